@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"odin-backend/internal/backfill"
+	"odin-backend/internal/config"
+	"odin-backend/internal/database"
+	"odin-backend/internal/mirror"
+	"odin-backend/internal/models"
+	"odin-backend/internal/worker"
+
+	"github.com/google/uuid"
+)
+
+const usage = `Usage: odin <command> [options]
+
+Commands:
+  admin backfill --what=fingerprints,risk,counters,techniques,matches
+                                                                Recompute derived fields for existing analyses
+  analysis ingest --log-dir=DIR [--name=NAME]                   Parse the logs of a past EMBA run into a new project
+  mirror import [--nvd=PATH] [--kev=FILE] [--epss=FILE] [--exploitdb=FILE]
+                                                                Load vulnerability datasets for offline mode
+`
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] + " " + os.Args[2] {
+	case "admin backfill":
+		runBackfill(os.Args[3:])
+	case "analysis ingest":
+		runIngest(os.Args[3:])
+	case "mirror import":
+		runMirrorImport(os.Args[3:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runBackfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	what := fs.String("what", "fingerprints,risk,counters,techniques,matches", "comma separated list of derived fields to recompute")
+	batchSize := fs.Int("batch-size", 500, "number of records processed per batch")
+	restart := fs.Bool("restart", false, "ignore saved progress and start from the beginning")
+	fs.Parse(args)
+
+	tasks, err := backfill.ParseTasks(*what)
+	if err != nil {
+		log.Fatalf("Invalid --what: %v", err)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Initialize(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	opts := backfill.Options{
+		Tasks:     tasks,
+		BatchSize: *batchSize,
+		Restart:   *restart,
+	}
+	err = backfill.Run(db, opts, func(state models.BackfillState) {
+		fmt.Printf("[%s] %d/%d processed (cursor %s)\n", state.Task, state.Processed, state.Total, state.Cursor)
+	})
+	if err != nil {
+		log.Fatalf("Backfill failed: %v", err)
+	}
+}
+
+func runIngest(args []string) {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	logDir := fs.String("log-dir", "", "EMBA log directory of the past run")
+	name := fs.String("name", "", "project name, defaults to the directory name")
+	layout := fs.String("layout", "", "parser layout, e.g. emba-1.x; detected when empty")
+	orgID := fs.String("org", models.DefaultOrgID, "organization the project belongs to")
+	fs.Parse(args)
+
+	if *logDir == "" {
+		log.Fatalf("--log-dir is required")
+	}
+	dir, err := filepath.Abs(*logDir)
+	if err != nil {
+		log.Fatalf("Invalid --log-dir: %v", err)
+	}
+	if *name == "" {
+		*name = filepath.Base(dir)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Initialize(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	project := &models.Project{
+		ID:                uuid.New().String(),
+		OrgID:             *orgID,
+		Name:              *name,
+		Status:            models.StatusPending,
+		Filename:          filepath.Base(dir),
+		FilePath:          dir,
+		Extractor:         "emba",
+		ParserLayout:      *layout,
+		IngestLogDir:      dir,
+		FirmwareInfo:      "{}",
+		ExtractionResults: "{}",
+	}
+	if err := db.Create(project).Error; err != nil {
+		log.Fatalf("Failed to create project: %v", err)
+	}
+
+	if err := worker.New(db, cfg).Ingest(project); err != nil {
+		project.Status = models.StatusFailed
+		db.Save(project)
+		log.Fatalf("Ingestion failed: %v", err)
+	}
+	fmt.Printf("Project %s: %d findings, %d CVEs, risk %s (layout %s, EMBA %s)\n",
+		project.ID, project.FindingCount, project.CVECount, project.RiskLevel, project.ParserLayout, project.EMBAVersion)
+}
+
+func runMirrorImport(args []string) {
+	fs := flag.NewFlagSet("mirror import", flag.ExitOnError)
+	paths := map[string]*string{
+		mirror.DatasetNVD:       fs.String("nvd", "", "NVD JSON 2.0 feed (nvdcve-2.0-*.json[.gz]) or a directory of them"),
+		mirror.DatasetKEV:       fs.String("kev", "", "CISA known_exploited_vulnerabilities.json"),
+		mirror.DatasetEPSS:      fs.String("epss", "", "FIRST epss_scores-YYYY-MM-DD.csv[.gz]"),
+		mirror.DatasetExploitDB: fs.String("exploitdb", "", "exploit-db files_exploits.csv"),
+	}
+	fs.Parse(args)
+
+	var datasets []string
+	for _, dataset := range mirror.Datasets {
+		if *paths[dataset] != "" {
+			datasets = append(datasets, dataset)
+		}
+	}
+	if len(datasets) == 0 {
+		log.Fatalf("At least one of --nvd, --kev, --epss or --exploitdb is required")
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Initialize(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	for _, dataset := range datasets {
+		count, err := mirror.Import(db, dataset, *paths[dataset])
+		if err != nil {
+			log.Fatalf("Import of %s from %s failed: %v", dataset, *paths[dataset], err)
+		}
+		fmt.Printf("[%s] %d records imported from %s\n", dataset, count, *paths[dataset])
+	}
+}
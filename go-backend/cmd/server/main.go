@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"odin-backend/internal/config"
 	"odin-backend/internal/database"
 	"odin-backend/internal/handlers"
 	"odin-backend/internal/middleware"
+	"odin-backend/internal/worker"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,12 +23,47 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	embedded := flag.Bool("embedded-worker", cfg.EmbeddedWorker, "run the analysis worker inside the server, without Redis")
+	flag.Parse()
+	cfg.EmbeddedWorker = *embedded
+
 	// Initialize database
 	db, err := database.Initialize(cfg.DatabasePath)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	if cfg.OfflineMode {
+		log.Println("Offline mode: online lookups disabled, enrichment uses the local mirrors")
+	}
+
+	// Single-binary mode for small labs: process jobs in this process
+	if cfg.EmbeddedWorker {
+		w := worker.New(db, cfg)
+		if err := w.Register(); err != nil {
+			log.Fatalf("Failed to register embedded worker: %v", err)
+		}
+		go w.RunJanitor()
+		go w.RunPasswordCracker()
+		go w.RunNVDEnrichment()
+		go w.RunEPSSEnrichment()
+		go w.RunCVEMonitoring()
+		go w.RunVulnDBSync()
+		go w.RunDefaultCredentialUpdates()
+		go w.RunOSINTRefresh()
+		go w.RunThreatFeeds()
+
+		// On SIGINT/SIGTERM stop the running analysis, requeue it and exit
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			w.Run(ctx)
+			stop()
+			log.Println("Embedded worker stopped, shutting down")
+			os.Exit(0)
+		}()
+		log.Println("Embedded worker started, polling the database for pending jobs")
+	}
+
 	// Initialize handlers
 	h := handlers.New(db, cfg)
 
@@ -34,6 +75,9 @@ func main() {
 	r.Use(middleware.Logger())
 	r.Use(middleware.ErrorHandler())
 
+	// Prometheus scrape endpoint
+	r.GET("/metrics", h.Metrics)
+
 	// API routes
 	api := r.Group("/api")
 	{
@@ -44,22 +88,90 @@ func main() {
 		firmware := api.Group("/firmware")
 		{
 			firmware.POST("/upload", h.UploadFirmware)
+			firmware.POST("/inspect", h.InspectFirmware)
 		}
 
 		// Analysis endpoints
 		analysis := api.Group("/analysis")
 		{
+			analysis.POST("/diff", h.CreateDiffScan)
 			analysis.GET("/:job_id/status", h.GetAnalysisStatus)
 			analysis.GET("/:job_id/results", h.GetAnalysisResults)
+			analysis.GET("/:job_id/hardware", h.GetHardwareInventory)
+			analysis.GET("/:job_id/sbom", h.GetSBOM)
+			analysis.GET("/:job_id/components/:component/cves", h.GetComponentCVEs)
+			analysis.GET("/:job_id/licenses", h.GetLicenses)
+			analysis.GET("/:job_id/licenses/copyleft", h.GetCopyleftReport)
+			analysis.GET("/:job_id/binaries", h.GetBinaryAnalysis)
+			analysis.GET("/:job_id/binaries/similar", h.FindSimilarBinaries)
+			analysis.GET("/:job_id/passwords", h.GetPasswordHashes)
+			analysis.GET("/:job_id/emulation", h.GetEmulation)
+			analysis.GET("/:job_id/keys", h.GetKeyMaterial)
+			analysis.GET("/:job_id/osint", h.GetOSINT)
+			analysis.GET("/:job_id/techniques", h.GetTechniques)
+			analysis.GET("/:job_id/vulnerabilities/prioritized", h.GetPrioritizedVulnerabilities)
+			analysis.PUT("/:job_id/deployment", h.UpdateDeployment)
+			analysis.PUT("/:job_id/cves/:finding_id/match", h.ReviewCVEMatch)
+			analysis.PUT("/:job_id/cves/:finding_id/severity", h.OverrideCVESeverity)
+			analysis.DELETE("/:job_id/cves/:finding_id/severity", h.ResetCVESeverity)
+			analysis.PUT("/:job_id/findings/:finding_id/severity", h.OverrideFindingSeverity)
+			analysis.DELETE("/:job_id/findings/:finding_id/severity", h.ResetFindingSeverity)
+			analysis.POST("/:job_id/findings/bulk", h.BulkUpdateFindings)
+			analysis.POST("/:job_id/findings/merge", h.MergeFindings)
+			analysis.DELETE("/:job_id/findings/:finding_id/merge", h.UnmergeFinding)
+			analysis.GET("/:job_id/labels", h.ListLabels)
+			analysis.PUT("/:job_id/findings/:finding_id/labels", h.SetFindingLabels)
+			analysis.POST("/:job_id/findings/:finding_id/labels", h.AddFindingLabels)
+			analysis.DELETE("/:job_id/findings/:finding_id/labels/:label", h.RemoveFindingLabel)
+			analysis.GET("/:job_id/files", h.GetProjectFiles)
+			analysis.GET("/:job_id/diff", h.GetDiffScan)
+			analysis.GET("/:job_id/fs", h.BrowseFilesystem)
+			analysis.GET("/:job_id/fs/file", h.GetFirmwareFile)
+			analysis.GET("/:job_id/findings/:finding_id/context", h.GetFindingContext)
+			analysis.GET("/:job_id/ocsf", h.ExportOCSF)
 			analysis.DELETE("/:job_id", h.DeleteAnalysis)
 		}
 
+		// Findings across all analyses
+		findings := api.Group("/findings")
+		{
+			findings.GET("", h.ListFindings)
+		}
+
+		// CVEs and the analyses they were found in
+		cves := api.Group("/cves")
+		{
+			cves.GET("", h.ListCVEs)
+			cves.GET("/:cve_id", h.GetCVE)
+		}
+
+		// Findings grouped by the CWE weakness they cite
+		weaknesses := api.Group("/cwe")
+		{
+			weaknesses.GET("", h.ListCWEs)
+			weaknesses.GET("/:cwe_id", h.GetCWE)
+		}
+
+		// Extracted files across all analyses
+		files := api.Group("/files")
+		{
+			files.GET("", h.SearchFiles)
+		}
+
+		// Binaries across all analyses
+		binaries := api.Group("/binaries")
+		{
+			binaries.GET("/similar", h.SearchSimilarBinaries)
+		}
+
 		// Projects endpoint for compatibility
 		projects := api.Group("/projects")
 		{
 			projects.GET("/", h.ListProjects)
 			projects.GET("/:project_id", h.GetProject)
 			projects.DELETE("/:project_id", h.DeleteProject)
+			projects.GET("/:project_id/freeze", h.GetFreezeStatus)
+			projects.POST("/:project_id/freeze", h.FreezeProject)
 		}
 
 		// EMBA specific endpoints
@@ -68,10 +180,89 @@ func main() {
 			emba.GET("/:job_id/results", h.GetEMBAReport)
 			emba.GET("/:job_id/report", h.GetEMBAReport)
 			emba.GET("/:job_id/logs", h.GetEMBALogs)
+			emba.GET("/:job_id/output", h.GetEMBAOutput)
+			emba.GET("/health", h.GetEMBAHealth)
 			emba.GET("/config", h.GetEMBAConfig)
 			emba.POST("/config", h.UpdateEMBAConfig)
 			emba.GET("/profiles", h.GetEMBAProfiles)
 		}
+
+		// Comparison of two analyses
+		compare := api.Group("/compare")
+		{
+			compare.GET("/files", h.CompareFiles)
+			compare.GET("/environment", h.CompareEnvironments)
+		}
+
+		// Result webhooks
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.GET("", h.ListWebhooks)
+			webhooks.POST("", h.CreateWebhook)
+			webhooks.PUT("/:id", h.UpdateWebhook)
+			webhooks.DELETE("/:id", h.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", h.ListWebhookDeliveries)
+		}
+
+		// CVE alert rules, notifying webhooks of new CVEs matching them
+		alertRules := api.Group("/alert-rules")
+		{
+			alertRules.GET("", h.ListAlertRules)
+			alertRules.POST("", h.CreateAlertRule)
+			alertRules.PUT("/:id", h.UpdateAlertRule)
+			alertRules.DELETE("/:id", h.DeleteAlertRule)
+		}
+
+		// CVE suppressions, the accepted risks
+		suppressions := api.Group("/suppressions")
+		{
+			suppressions.GET("", h.ListSuppressions)
+			suppressions.POST("", h.CreateSuppression)
+			suppressions.PUT("/:id", h.UpdateSuppression)
+			suppressions.DELETE("/:id", h.DeleteSuppression)
+		}
+
+		// YARA rule sets scanned against extracted firmware
+		yaraRules := api.Group("/yara/rulesets")
+		{
+			yaraRules.GET("", h.ListYaraRuleSets)
+			yaraRules.POST("", h.CreateYaraRuleSet)
+			yaraRules.GET("/:id", h.GetYaraRuleSet)
+			yaraRules.PUT("/:id", h.UpdateYaraRuleSet)
+			yaraRules.DELETE("/:id", h.DeleteYaraRuleSet)
+		}
+
+		// Administrative endpoints
+		admin := api.Group("/admin", middleware.RequireAdmin(cfg.AdminAPIToken))
+		{
+			admin.GET("/settings/:org_id", h.GetOrgSettings)
+			admin.PUT("/settings/:org_id", h.UpdateOrgSettings)
+			admin.GET("/audit", h.ListAuditLogs)
+			admin.GET("/workers", h.ListWorkers)
+			admin.POST("/workers/:worker_id/drain", h.DrainWorker)
+			admin.POST("/workers/:worker_id/resume", h.ResumeWorker)
+			admin.POST("/projects/:project_id/unfreeze", h.UnfreezeProject)
+			admin.GET("/failure-stats", h.GetFailureStats)
+			admin.GET("/slo", h.GetSLOReport)
+			admin.POST("/emba/install", h.InstallEMBA)
+			admin.GET("/emba/installs", h.ListEMBAInstalls)
+			admin.POST("/ingest", h.IngestLogs)
+			admin.GET("/backfill", h.GetBackfillStatus)
+			admin.POST("/backfill", h.StartBackfill)
+			admin.GET("/integrations", h.ListIntegrations)
+			admin.POST("/integrations", h.CreateIntegration)
+			admin.PUT("/integrations/:id", h.UpdateIntegration)
+			admin.DELETE("/integrations/:id", h.DeleteIntegration)
+			admin.GET("/osint/quotas", h.GetOSINTQuotas)
+			admin.GET("/mirrors", h.GetMirrorStatus)
+			admin.GET("/vulndb", h.GetVulnDBStatus)
+			admin.POST("/vulndb", h.StartVulnDBSync)
+			admin.GET("/threat-feeds", h.ListThreatFeeds)
+			admin.POST("/threat-feeds", h.CreateThreatFeed)
+			admin.PUT("/threat-feeds/:id", h.UpdateThreatFeed)
+			admin.DELETE("/threat-feeds/:id", h.DeleteThreatFeed)
+			admin.POST("/threat-feeds/:id/poll", h.PollThreatFeed)
+		}
 	}
 
 	// Start server
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"odin-backend/internal/config"
 	"odin-backend/internal/database"
 	"odin-backend/internal/worker"
-	"time"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
@@ -24,14 +26,49 @@ func main() {
 	// Initialize worker
 	w := worker.New(db, cfg)
 
+	// Announce this worker in the registry
+	if err := w.Register(); err != nil {
+		log.Fatalf("Failed to register worker: %v", err)
+	}
+
+	if cfg.OfflineMode {
+		log.Println("Offline mode: online lookups disabled, enrichment uses the local mirrors")
+	}
+
+	// Recover projects abandoned by crashed workers
+	go w.RunJanitor()
+
+	// Crack password hashes found in firmware, if enabled
+	go w.RunPasswordCracker()
+
+	// Fill CVE findings in with NVD's records and EPSS scores, if enabled
+	go w.RunNVDEnrichment()
+	go w.RunEPSSEnrichment()
+
+	// Match stored SBOMs against the CVEs NVD published since, if enabled
+	go w.RunCVEMonitoring()
+
+	// Keep the local vulnerability data current, if enabled
+	go w.RunVulnDBSync()
+
+	// Keep the default credentials dataset current, if a URL is set
+	go w.RunDefaultCredentialUpdates()
+
+	// Collect the OSINT of completed projects again, if an interval is set,
+	// and of those an exhausted provider quota deferred
+	go w.RunOSINTRefresh()
+
+	// Ingest the indicators of the threat feeds, if an interval is set
+	go w.RunThreatFeeds()
+
 	log.Println("Starting ODIN worker...")
 	log.Println("Worker will poll for pending analysis jobs every 10 seconds")
 
+	// Stop the running analysis and requeue it on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// Start worker polling loop
-	for {
-		if err := w.ProcessPendingJobs(); err != nil {
-			log.Printf("Error processing jobs: %v", err)
-		}
-		time.Sleep(10 * time.Second)
-	}
+	w.Run(ctx)
+	log.Println("ODIN worker stopped")
 }
@@ -0,0 +1,196 @@
+// Package attack maps findings to the adversary techniques they enable, in
+// MITRE ATT&CK for ICS (T0xxx) and MITRE EMB3D (TID-xxx), for the threat
+// models of the analyzed devices. The mapping is a fixed set of rules on a
+// finding's type, check, CWE and wording; a finding can map to several
+// techniques or to none.
+package attack
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// Frameworks of the techniques
+const (
+	FrameworkICS   = "attack-ics"
+	FrameworkEMB3D = "emb3d"
+)
+
+// Technique is an adversary technique of ATT&CK for ICS, or a threat of
+// EMB3D. Tactics are ATT&CK's tactics, or EMB3D's device property category.
+type Technique struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Framework string   `json:"framework"`
+	Tactics   []string `json:"tactics"`
+	URL       string   `json:"url"`
+}
+
+func ics(id, name string, tactics ...string) Technique {
+	return Technique{ID: id, Name: name, Framework: FrameworkICS, Tactics: tactics,
+		URL: "https://attack.mitre.org/techniques/" + id + "/"}
+}
+
+func emb3d(id, name, category string) Technique {
+	return Technique{ID: id, Name: name, Framework: FrameworkEMB3D, Tactics: []string{category},
+		URL: "https://emb3d.mitre.org/threats/" + id + ".html"}
+}
+
+// Techniques is the catalog the rules map to, by ID
+var Techniques = catalog(
+	ics("T0807", "Command-Line Interface", "Execution"),
+	ics("T0812", "Default Credentials", "Lateral Movement"),
+	ics("T0819", "Exploit Public-Facing Application", "Initial Access"),
+	ics("T0830", "Adversary-in-the-Middle", "Collection"),
+	ics("T0842", "Network Sniffing", "Discovery"),
+	ics("T0857", "System Firmware", "Persistence", "Inhibit Response Function"),
+	ics("T0866", "Exploitation of Remote Services", "Initial Access", "Lateral Movement"),
+	ics("T0886", "Remote Services", "Initial Access", "Lateral Movement"),
+	ics("T0890", "Exploitation for Privilege Escalation", "Privilege Escalation"),
+	ics("T0891", "Hardcoded Credentials", "Lateral Movement", "Persistence"),
+
+	emb3d("TID-115", "Firmware/Data Extraction via Hardware Interface", "Hardware"),
+	emb3d("TID-116", "Latent Privileged Access Port", "Hardware"),
+	emb3d("TID-119", "Latent Hardware Debug Port Allows Memory/Code Manipulation", "Hardware"),
+	emb3d("TID-201", "Inadequate Bootloader Protection and Verification", "System Software"),
+	emb3d("TID-210", "Device Vulnerabilities Unpatchable", "System Software"),
+	emb3d("TID-211", "Device Allows Unauthenticated Firmware Installation", "System Software"),
+	emb3d("TID-213", "Faulty FW/SW Update Integrity Verification", "System Software"),
+	emb3d("TID-311", "Default Credentials", "Application Software"),
+	emb3d("TID-327", "Out of Bounds Memory Access", "Application Software"),
+	emb3d("TID-328", "Hardcoded Credentials", "Application Software"),
+	emb3d("TID-329", "Improper Password Storage", "Application Software"),
+	emb3d("TID-408", "Unencrypted Sensitive Data Communication", "Networking"),
+)
+
+func catalog(techniques ...Technique) map[string]Technique {
+	byID := make(map[string]Technique, len(techniques))
+	for _, technique := range techniques {
+		byID[technique.ID] = technique
+	}
+	return byID
+}
+
+// rule maps the findings of one of types (any type when empty) that match
+// one of its checks, CWEs or keywords (any finding of the types when it has
+// none) and none of its exceptions to techniques. Keywords and exceptions
+// match the lowercased title and description.
+type rule struct {
+	types      []models.FindingType
+	checks     []string
+	cwes       []string
+	keywords   []string
+	except     []string
+	techniques []string
+}
+
+var rules = []rule{
+	// Credentials
+	{keywords: []string{"default credential"}, techniques: []string{"T0812", "TID-311"}},
+	{types: []models.FindingType{"snmp_community"}, techniques: []string{"T0812", "TID-311"}},
+	{types: []models.FindingType{models.FindingCredential, "credential_finding"}, except: []string{"default credential"}, techniques: []string{"T0891", "TID-328"}},
+	{cwes: []string{"CWE-798", "CWE-259", "CWE-321"}, techniques: []string{"T0891", "TID-328"}},
+	{types: []models.FindingType{models.FindingPrivateKey}, techniques: []string{"T0891", "T0830", "TID-328"}},
+	{checks: []string{"reused_private_key"}, techniques: []string{"T0891", "T0830", "TID-328"}},
+	{checks: []string{"weak_hash"}, cwes: []string{"CWE-916", "CWE-328"}, keywords: []string{"password hash"}, techniques: []string{"TID-329"}},
+
+	// Network services and communication
+	{checks: []string{"telnet"}, keywords: []string{"telnet"}, techniques: []string{"T0886", "TID-408"}},
+	{cwes: []string{"CWE-319"}, techniques: []string{"T0842", "TID-408"}},
+	{checks: []string{"weak_key", "self_signed_certificate"}, cwes: []string{"CWE-326", "CWE-327"}, techniques: []string{"T0830"}},
+	{types: []models.FindingType{"web_vulnerability"}, techniques: []string{"T0819"}},
+	{types: []models.FindingType{"hnap_vulnerability", "upnp_device", "vnc_vulnerability"}, techniques: []string{"T0866"}},
+	{types: []models.FindingType{"vnc_vulnerability"}, techniques: []string{"T0886"}},
+
+	// Boot chain and firmware updates
+	{keywords: []string{"without signature verification", "checksum verification disabled"}, techniques: []string{"T0857", "TID-201"}},
+	{keywords: []string{"unsigned firmware", "unsigned update", "firmware update without"}, techniques: []string{"T0857", "TID-211", "TID-213"}},
+
+	// Hardware access
+	{keywords: []string{"uart", "serial console", "bootdelay"}, techniques: []string{"TID-115", "TID-116"}},
+	{types: []models.FindingType{"hardware_interface"}, keywords: []string{"jtag", "debug interface"}, techniques: []string{"TID-119"}},
+
+	// Code weaknesses
+	{cwes: []string{"CWE-119", "CWE-120", "CWE-121", "CWE-122", "CWE-125", "CWE-787", "CWE-788", "CWE-415", "CWE-416"}, techniques: []string{"TID-327"}},
+	{cwes: []string{"CWE-77", "CWE-78"}, techniques: []string{"T0807"}},
+	{checks: []string{"setuid", "world_writable"}, techniques: []string{"T0890"}},
+	{types: []models.FindingType{"weak_permission"}, techniques: []string{"T0890"}},
+
+	// Support
+	{types: []models.FindingType{"eol", "kernel_eol"}, techniques: []string{"TID-210"}},
+}
+
+func (r rule) matches(finding *models.Finding, check, text string) bool {
+	if len(r.types) > 0 && !containsType(r.types, finding.Type) {
+		return false
+	}
+	for _, exception := range r.except {
+		if strings.Contains(text, exception) {
+			return false
+		}
+	}
+	if len(r.checks) == 0 && len(r.cwes) == 0 && len(r.keywords) == 0 {
+		return true
+	}
+	for _, c := range r.checks {
+		if c == check {
+			return true
+		}
+	}
+	for _, cwe := range r.cwes {
+		if cwe == finding.CWE {
+			return true
+		}
+	}
+	for _, keyword := range r.keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsType(types []models.FindingType, findingType models.FindingType) bool {
+	for _, t := range types {
+		if t == findingType {
+			return true
+		}
+	}
+	return false
+}
+
+// Map returns the IDs of the techniques a finding maps to, sorted
+func Map(finding *models.Finding) []string {
+	var metadata struct {
+		Check string `json:"check"`
+	}
+	if finding.FindingMetadata != "" {
+		json.Unmarshal([]byte(finding.FindingMetadata), &metadata)
+	}
+	text := strings.ToLower(finding.Title + "\n" + finding.Description)
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, r := range rules {
+		if !r.matches(finding, metadata.Check, text) {
+			continue
+		}
+		for _, id := range r.techniques {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Tag returns the techniques a finding maps to as stored on it, comma
+// separated
+func Tag(finding *models.Finding) string {
+	return strings.Join(Map(finding), ",")
+}
@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Record stores an audit log entry for an administrative change
+func Record(db *gorm.DB, actor, action, resource, resourceID string, details map[string]interface{}) error {
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit details: %w", err)
+	}
+
+	entry := models.AuditLog{
+		Actor:      actor,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Details:    string(encoded),
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to save audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"odin-backend/internal/attack"
+	"odin-backend/internal/models"
+	"odin-backend/internal/nvd"
+	"odin-backend/internal/risk"
+
+	"gorm.io/gorm"
+)
+
+// Supported backfill tasks
+const (
+	TaskFingerprints = "fingerprints"
+	TaskRisk         = "risk"
+	TaskCounters     = "counters"
+	TaskTechniques   = "techniques"
+	TaskMatches      = "matches"
+)
+
+// Task states recorded in models.BackfillState
+const (
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+)
+
+const defaultBatchSize = 500
+
+// ErrAlreadyRunning is returned when a backfill is started while another one is in progress
+var ErrAlreadyRunning = errors.New("a backfill is already running")
+
+var running sync.Mutex
+
+// Options controls which derived fields are recomputed and how
+type Options struct {
+	Tasks     []string
+	BatchSize int
+	Restart   bool // ignore saved cursors and start from the beginning
+}
+
+// ProgressFunc receives the task state after each processed batch
+type ProgressFunc func(state models.BackfillState)
+
+// ParseTasks splits and validates a comma separated task list
+func ParseTasks(what string) ([]string, error) {
+	var tasks []string
+	for _, task := range strings.Split(what, ",") {
+		task = strings.TrimSpace(task)
+		if task == "" {
+			continue
+		}
+		switch task {
+		case TaskFingerprints, TaskRisk, TaskCounters, TaskTechniques, TaskMatches:
+			tasks = append(tasks, task)
+		default:
+			return nil, fmt.Errorf("unknown backfill task %q", task)
+		}
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no backfill tasks given")
+	}
+	return tasks, nil
+}
+
+// Run recomputes derived fields for historical data in batches. Progress is
+// persisted after every batch so an interrupted run resumes where it stopped.
+func Run(db *gorm.DB, opts Options, progress ProgressFunc) error {
+	if !running.TryLock() {
+		return ErrAlreadyRunning
+	}
+	defer running.Unlock()
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	for _, task := range opts.Tasks {
+		if err := runTask(db, task, opts, progress); err != nil {
+			return fmt.Errorf("backfill %s failed: %w", task, err)
+		}
+	}
+	return nil
+}
+
+// IsRunning reports whether a backfill is currently in progress
+func IsRunning() bool {
+	if running.TryLock() {
+		running.Unlock()
+		return false
+	}
+	return true
+}
+
+// States returns the saved state of every backfill task
+func States(db *gorm.DB) ([]models.BackfillState, error) {
+	var states []models.BackfillState
+	if err := db.Order("task").Find(&states).Error; err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func runTask(db *gorm.DB, task string, opts Options, progress ProgressFunc) error {
+	state := models.BackfillState{Task: task}
+	if err := db.FirstOrCreate(&state, models.BackfillState{Task: task}).Error; err != nil {
+		return fmt.Errorf("failed to load backfill state: %w", err)
+	}
+
+	if opts.Restart || state.Status == StateCompleted {
+		state.Cursor = ""
+		state.Processed = 0
+		state.CompletedAt = nil
+	}
+	if state.Cursor == "" {
+		state.StartedAt = time.Now().UTC()
+	}
+	state.Status = StateRunning
+	state.Error = ""
+
+	total, err := countTask(db, task)
+	if err != nil {
+		return err
+	}
+	state.Total = int(total)
+	if err := db.Save(&state).Error; err != nil {
+		return fmt.Errorf("failed to save backfill state: %w", err)
+	}
+
+	log.Printf("Backfill %s: starting at cursor %q (%d/%d processed)", task, state.Cursor, state.Processed, state.Total)
+
+	for {
+		var processed int
+		var cursor string
+		var err error
+
+		switch task {
+		case TaskFingerprints:
+			processed, cursor, err = findingBatch(db, state.Cursor, opts.BatchSize, fingerprintColumn)
+		case TaskTechniques:
+			processed, cursor, err = findingBatch(db, state.Cursor, opts.BatchSize, techniquesColumn)
+		case TaskMatches:
+			processed, cursor, err = matchBatch(db, state.Cursor, opts.BatchSize)
+		default:
+			processed, cursor, err = projectBatch(db, task, state.Cursor, opts.BatchSize)
+		}
+
+		if err != nil {
+			state.Status = StateFailed
+			state.Error = err.Error()
+			db.Save(&state)
+			return err
+		}
+		if processed == 0 {
+			break
+		}
+
+		state.Cursor = cursor
+		state.Processed += processed
+		if err := db.Save(&state).Error; err != nil {
+			return fmt.Errorf("failed to save backfill state: %w", err)
+		}
+		if progress != nil {
+			progress(state)
+		}
+	}
+
+	now := time.Now().UTC()
+	state.Status = StateCompleted
+	state.CompletedAt = &now
+	if err := db.Save(&state).Error; err != nil {
+		return fmt.Errorf("failed to save backfill state: %w", err)
+	}
+	if progress != nil {
+		progress(state)
+	}
+
+	log.Printf("Backfill %s: completed, %d records processed", task, state.Processed)
+	return nil
+}
+
+func countTask(db *gorm.DB, task string) (int64, error) {
+	var total int64
+	var err error
+	switch task {
+	case TaskFingerprints:
+		err = db.Model(&models.Finding{}).Count(&total).Error
+	case TaskTechniques:
+		err = unfrozenFindings(db, db.Model(&models.Finding{})).Count(&total).Error
+	case TaskMatches:
+		err = db.Model(&models.CVEFinding{}).Count(&total).Error
+	default:
+		err = db.Model(&models.Project{}).Count(&total).Error
+	}
+	return total, err
+}
+
+// derivedColumn is a column of findings computed from their other fields
+type derivedColumn struct {
+	name    string
+	stored  func(*models.Finding) string
+	compute func(*models.Finding) string
+	// skipFrozen leaves the findings of frozen projects alone, for columns
+	// their content hash covers
+	skipFrozen bool
+}
+
+var (
+	fingerprintColumn = derivedColumn{
+		name:    "fingerprint",
+		stored:  func(f *models.Finding) string { return f.Fingerprint },
+		compute: (*models.Finding).ComputeFingerprint,
+	}
+	techniquesColumn = derivedColumn{
+		name:       "techniques",
+		stored:     func(f *models.Finding) string { return f.Techniques },
+		compute:    attack.Tag,
+		skipFrozen: true,
+	}
+)
+
+// unfrozenFindings restricts a query of findings to those of projects
+// that aren't frozen
+func unfrozenFindings(db, query *gorm.DB) *gorm.DB {
+	return query.Where("project_id IN (?)", db.Model(&models.Project{}).Select("id").Where("frozen_at IS NULL"))
+}
+
+// findingBatch recomputes a derived column for the next batch of findings
+// ordered by ID
+func findingBatch(db *gorm.DB, cursor string, batchSize int, column derivedColumn) (int, string, error) {
+	lastID := uint64(0)
+	if cursor != "" {
+		parsed, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid %s cursor %q: %w", column.name, cursor, err)
+		}
+		lastID = parsed
+	}
+
+	query := db.Where("id > ?", lastID)
+	if column.skipFrozen {
+		query = unfrozenFindings(db, query)
+	}
+	var findings []models.Finding
+	if err := query.Order("id").Limit(batchSize).Find(&findings).Error; err != nil {
+		return 0, "", fmt.Errorf("failed to load findings: %w", err)
+	}
+	if len(findings) == 0 {
+		return 0, cursor, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range findings {
+			finding := &findings[i]
+			value := column.compute(finding)
+			if value == column.stored(finding) {
+				continue
+			}
+			if err := tx.Model(&models.Finding{}).Where("id = ?", finding.ID).
+				Update(column.name, value).Error; err != nil {
+				return fmt.Errorf("failed to update finding %d: %w", finding.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	return len(findings), strconv.FormatUint(uint64(findings[len(findings)-1].ID), 10), nil
+}
+
+// matchBatch verifies the next batch of CVE findings ordered by ID against
+// the cached NVD records of their CVEs, for the findings enriched before
+// matches were verified. Findings of frozen projects and those already
+// verified or reviewed are left alone.
+func matchBatch(db *gorm.DB, cursor string, batchSize int) (int, string, error) {
+	lastID := uint64(0)
+	if cursor != "" {
+		parsed, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid matches cursor %q: %w", cursor, err)
+		}
+		lastID = parsed
+	}
+
+	var findings []models.CVEFinding
+	if err := db.Select("id", "project_id", "cve_id", "software_name", "software_version", "component_id", "match_status", "nvd_enriched_at").
+		Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&findings).Error; err != nil {
+		return 0, "", fmt.Errorf("failed to load CVE findings: %w", err)
+	}
+	if len(findings) == 0 {
+		return 0, cursor, nil
+	}
+
+	frozen := make(map[string]bool)
+	records := make(map[string]*nvd.CVE)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range findings {
+			finding := &findings[i]
+			if finding.MatchStatus != models.MatchUnverified || finding.NVDEnrichedAt == nil {
+				continue
+			}
+			isFrozen, ok := frozen[finding.ProjectID]
+			if !ok {
+				var project models.Project
+				if err := tx.Select("id", "frozen_at").First(&project, "id = ?", finding.ProjectID).Error; err != nil {
+					return fmt.Errorf("failed to load project %s: %w", finding.ProjectID, err)
+				}
+				isFrozen = project.Frozen()
+				frozen[finding.ProjectID] = isFrozen
+			}
+			if isFrozen {
+				continue
+			}
+
+			record, ok := records[finding.CVEID]
+			if !ok {
+				var cached models.NVDRecord
+				if err := tx.Limit(1).Find(&cached, "cve_id = ? AND found = ?", finding.CVEID, true).Error; err != nil {
+					return fmt.Errorf("failed to load NVD record of %s: %w", finding.CVEID, err)
+				}
+				if cached.CVEID != "" {
+					record = &nvd.CVE{}
+					if err := json.Unmarshal([]byte(cached.Data), record); err != nil {
+						return fmt.Errorf("failed to decode NVD record of %s: %w", finding.CVEID, err)
+					}
+				}
+				records[finding.CVEID] = record
+			}
+			if record == nil {
+				continue
+			}
+
+			component := models.SBOMComponent{Name: finding.SoftwareName, Version: finding.SoftwareVersion}
+			if finding.ComponentID != nil {
+				if err := tx.Limit(1).Find(&component, "id = ?", *finding.ComponentID).Error; err != nil {
+					return fmt.Errorf("failed to load component of CVE finding %d: %w", finding.ID, err)
+				}
+			}
+			status, reason := record.Verify(&component)
+			columns := map[string]interface{}{
+				"match_status": status,
+				"match_reason": reason,
+			}
+			if criteria, versions := record.Evidence(&component); criteria != "" {
+				columns["match_criteria"] = criteria
+				columns["match_version_range"] = versions
+			}
+			if err := tx.Model(&models.CVEFinding{}).Where("id = ?", finding.ID).UpdateColumns(columns).Error; err != nil {
+				return fmt.Errorf("failed to update CVE finding %d: %w", finding.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	return len(findings), strconv.FormatUint(uint64(findings[len(findings)-1].ID), 10), nil
+}
+
+// projectBatch recomputes risk levels or counters for the next batch of projects ordered by ID
+func projectBatch(db *gorm.DB, task, cursor string, batchSize int) (int, string, error) {
+	var projects []models.Project
+	if err := db.Where("id > ?", cursor).Order("id").Limit(batchSize).Find(&projects).Error; err != nil {
+		return 0, "", fmt.Errorf("failed to load projects: %w", err)
+	}
+	if len(projects) == 0 {
+		return 0, cursor, nil
+	}
+
+	for _, project := range projects {
+		// Frozen results must stay exactly as delivered
+		if project.Frozen() {
+			continue
+		}
+
+		counts, err := risk.CountProject(db, project.ID)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to count project %s: %w", project.ID, err)
+		}
+
+		updates := map[string]interface{}{}
+		if task == TaskRisk {
+			// Only completed projects have a meaningful risk level
+			if project.Status != models.StatusCompleted {
+				continue
+			}
+			updates["risk_level"] = risk.Level(counts)
+		} else {
+			risk.ApplyCounts(&project, counts)
+			updates["finding_count"] = project.FindingCount
+			updates["cve_count"] = project.CVECount
+			updates["critical_count"] = project.CriticalCount
+			updates["high_count"] = project.HighCount
+			updates["medium_count"] = project.MediumCount
+			updates["low_count"] = project.LowCount
+			updates["info_count"] = project.InfoCount
+		}
+
+		// UpdateColumns leaves UpdatedAt alone so backfills don't look like user edits
+		if err := db.Model(&models.Project{}).Where("id = ?", project.ID).UpdateColumns(updates).Error; err != nil {
+			return 0, "", fmt.Errorf("failed to update project %s: %w", project.ID, err)
+		}
+	}
+
+	return len(projects), projects[len(projects)-1].ID, nil
+}
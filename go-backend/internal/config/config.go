@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/base64"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +19,12 @@ type Config struct {
 	ServerHost string
 	ServerPort string
 
+	// Bearer token required for /api/admin endpoints
+	AdminAPIToken string
+
+	// Redis (job queue and cluster-wide coordination)
+	RedisURL string
+
 	// File Upload
 	UploadDir            string
 	WorkDir              string
@@ -30,24 +39,238 @@ type Config struct {
 	EMBAEnableLiveTesting bool
 	EMBAScanProfile     string
 	EMBAThreads         int
+	EMBAMaxConcurrent   int // cluster-wide limit on running EMBA processes, 0 disables
+	EMBATimeout         time.Duration // EMBA runs longer than this are killed, 0 disables
+	EMBAExcludedModules []string      // modules never run on this instance, e.g. S115 or L
+	EMBAPartialInterval time.Duration // how often finished modules are persisted during a run, 0 disables
+	EMBAIngestDir       string        // log directories of past runs the API may import from, defaults to EMBALogDir
+
+	// Images in no known container format whose entropy (bits per byte)
+	// reaches this are treated as encrypted and not analyzed, 0 disables
+	EncryptedEntropyThreshold float64
+
+	// Executables decrypting vendor encrypted images, tried after Odin's
+	// built-in decryptors on images that look encrypted; each run is
+	// limited to DecryptTimeout
+	DecryptorDir   string
+	DecryptTimeout time.Duration
+
+	// Where admin-triggered installs get EMBA from and the version they
+	// check out unless the request names one
+	EMBARepositoryURL string
+	EMBAPinnedVersion string
+
+	// How EMBA gets root: sudo, none (skip modules that need root),
+	// systemd-run or helper (EMBAPrivilegeHelper, e.g. a setuid wrapper)
+	EMBAPrivilegeMode   string
+	EMBAPrivilegeHelper string
+
+	// Resource limits so a scan can't starve services on the same host:
+	// cgroup limits through a systemd scope (systemd CPUQuota/MemoryMax
+	// syntax, e.g. "400%" and "16G") and CPU/IO scheduling priority
+	EMBACPUQuota     string
+	EMBAMemoryMax    string
+	EMBANice         int    // -20..19, 0 leaves the priority alone
+	EMBAIONiceClass  string // idle or best-effort, empty leaves it alone
+	EMBAIONiceLevel  int    // 0 (highest) to 7 for best-effort
+
+	// EMBA console output is streamed to a rotating log file next to the
+	// run's log directory; only its tail is stored with the results
+	EMBAOutputMaxSizeMB  int64
+	EMBAOutputMaxBackups int
+	EMBAStoredOutputKB   int // 0 keeps the output out of the database
+
+	// binwalk for extraction-only analyses when EMBA isn't available, and
+	// unblob for projects extracted with it instead of EMBA's extractor
+	BinwalkPath string
+	UnblobPath  string
+
+	// Run the job runner inside the API server, using the database as the
+	// queue and an in-process EMBA limit instead of Redis
+	EmbeddedWorker bool
+
+	// Default per-organization limit on concurrently running scans, 0 is unlimited
+	OrgMaxConcurrentScans int
+
+	// Resource-aware scheduling: jobs wait while the host is short on
+	// memory/disk; heavy jobs (emulation, HeavyScanProfiles) need more
+	MinFreeMemoryMB      int64
+	HeavyMinFreeMemoryMB int64
+	MinFreeDiskMB        int64
+	MaxLoadPerCPU        float64
+	HeavyScanProfiles    []string
+
+	// Stuck job recovery
+	JanitorInterval      time.Duration
+	StuckJobThreshold    time.Duration
+	StuckJobAction       string // requeue or fail
+	StuckJobMaxRequeues  int
+
+	// Job retries
+	JobMaxRetries      int
+	JobRetryBaseDelay  time.Duration
+	JobRetryMaxDelay   time.Duration
+	JobRetryableErrors []string // error substrings treated as transient, empty uses the built-in list
+
+	// Malware verdict engines
+	VerdictEngines []string // clamav, virustotal, sandbox
+	VerdictPolicy  string   // block, review, none
+	ClamAVPath     string
+	SandboxAPIURL  string
+	SandboxAPIKey  string
+
+	// Cracking of password hashes found in firmware: john or hashcat (empty
+	// disables), run in the background against PasswordWordlist
+	PasswordCracker      string
+	PasswordCrackerPath  string // defaults to the cracker's name
+	PasswordWordlist     string
+	PasswordCrackTimeout time.Duration // per project and algorithm
+
+	// Turnaround objectives, e.g. 95% of quick-scan.emba analyses complete
+	// within 1h, measured over the last SLOWindow
+	SLOObjectives []SLOObjective
+	SLOWindow     time.Duration
+
+	// Air-gapped operation: nothing is looked up on the internet. NVD,
+	// KEV, EPSS and exploit-db data come from the local mirrors imported
+	// with "odin mirror import"; the online OSINT providers and lookups are
+	// disabled. Services at configured URLs (sandbox, TAXII feeds, default
+	// credentials, webhooks) are still used, as they may be on the lab's
+	// own network.
+	OfflineMode bool
 
 	// External APIs
 	ShodanAPIKey     string
+	CensysAPIID      string
+	CensysAPISecret  string
 	VirusTotalAPIKey string
+
+	// Key encrypting the API keys stored in the integrations table, 32
+	// base64 encoded bytes; empty disables stored keys
+	IntegrationsKey string
+
+	// VirusTotal lookups per minute the key allows (the public API's 4)
+	VirusTotalRateLimit int
+
+	// How long the OSINT stage may search the external sources per analysis
+	OSINTTimeout time.Duration
+
+	// How long OSINT responses are cached (0 disables), per provider in
+	// OSINTCacheTTLs on top of OSINTCacheTTL
+	OSINTCacheTTL  time.Duration
+	OSINTCacheTTLs map[string]time.Duration
+
+	// Weights of the factors of OSINT confidence scores (specificity,
+	// reliability, corroboration) and how far each source is trusted
+	// (0-100), on top of the defaults
+	OSINTScoreWeights      map[string]float64
+	OSINTSourceReliability map[string]int
+
+	// Outbound calls allowed to each OSINT provider per UTC day and per
+	// minute, shared by its keys (absent: unlimited). Enrichment deferred
+	// by an exhausted quota resumes the next day.
+	OSINTDailyQuotas map[string]int
+	OSINTRateLimits  map[string]int
+
+	// How often the OSINT of completed projects is collected again (0
+	// disables the refresh)
+	OSINTRefreshInterval time.Duration
+
+	// OSINT providers enabled on this instance (shodan, censys, virustotal,
+	// endoflife); empty enables every configured one
+	OSINTProviders []string
+
+	// Lookup of the device model in the shipped vendor end-of-support table
+	// and of the SBOM components on endoflife.date during OSINT
+	EOLLookup bool
+
+	// Lookup of public exploits of the CVEs found (PoC-in-GitHub), on top
+	// of EMBA's exploit aggregation; ExploitLookupTimeout bounds it per analysis
+	ExploitLookup        bool
+	ExploitLookupTimeout time.Duration
+
+	// Lookup of the SBOM components of package ecosystems in the GitHub
+	// Advisory Database; GitHubToken raises GitHub's rate limit
+	GHSALookup        bool
+	GHSALookupTimeout time.Duration
+	GitHubToken       string
+
+	// Enrichment of the CVE findings of completed analyses with NVD's
+	// records in the background. NVDAPIKey raises NVD's rate limit; fetched
+	// records are cached for NVDCacheTTL.
+	NVDEnrichment bool
+	NVDAPIKey     string
+	NVDCacheTTL   time.Duration
+
+	// Default credentials dataset downloaded every
+	// DefaultCredentialsUpdateInterval on top of the shipped one (empty:
+	// the shipped one only)
+	DefaultCredentialsURL            string
+	DefaultCredentialsUpdateInterval time.Duration
+
+	// CWE dictionary in MITRE's CSV format replacing the shipped one
+	// (empty: the shipped one)
+	CWEDictionary string
+
+	// How often the TAXII collections of the threat feeds are polled for
+	// new STIX indicators (0 disables polling)
+	ThreatFeedPollInterval time.Duration
+
+	// Background lookup of the EPSS scores of the CVE findings, refreshed
+	// every EPSSRefreshInterval
+	EPSSEnrichment      bool
+	EPSSRefreshInterval time.Duration
+
+	// Matching of the SBOM components of completed analyses against the
+	// CVEs of NVD's modified feed every CVEMonitorInterval, adding the CVE
+	// findings they gained; offline against the imported NVD records
+	CVEMonitoring      bool
+	CVEMonitorInterval time.Duration
+	NVDModifiedFeedURL string
+
+	// Synchronization of the local vulnerability data every
+	// VulnDBSyncInterval (admins can start one any time): the datasets at
+	// the URLs, empty ones skipped, are downloaded into the mirror, and
+	// VulnDBEMBAUpdateCommand updates EMBA's CVE database when set
+	VulnDBSync              bool
+	VulnDBSyncInterval      time.Duration
+	VulnDBNVDURL            string
+	VulnDBKEVURL            string
+	VulnDBEPSSURL           string
+	VulnDBExploitDBURL      string
+	VulnDBEMBAUpdateCommand string
+	VulnDBEMBAUpdateTimeout time.Duration
+
+	// Scan the extracted filesystem with Odin's own secret rules after EMBA
+	SecretScan        bool
+	SecretScanTimeout time.Duration
+
+	// Compute ssdeep hashes of the extracted ELF binaries to find similar
+	// binaries across projects
+	FuzzyHash bool
+
+	// Scan the extracted filesystem with the enabled YARA rule sets of the
+	// project's organization, using the yara command line tool
+	YaraScan        bool
+	YaraPath        string
+	YaraScanTimeout time.Duration
 }
 
 func Load() (*Config, error) {
 	// Load .env file if it exists
 	_ = godotenv.Load()
 
+	var err error
 	cfg := &Config{
 		DatabasePath:        getEnv("DATABASE_PATH", "./odin.db"),
 		ServerHost:         getEnv("SERVER_HOST", "0.0.0.0"),
 		ServerPort:         getEnv("SERVER_PORT", "8080"),
+		AdminAPIToken:      getEnv("ADMIN_API_TOKEN", ""),
+		RedisURL:           getEnv("REDIS_URL", "localhost:6379"),
 		UploadDir:          getEnv("UPLOAD_DIR", "/tmp/odin/uploads"),
 		WorkDir:            getEnv("WORK_DIR", "/tmp/odin/work"),
 		MaxFileSize:        getEnvAsInt64("MAX_FILE_SIZE", 524288000), // 500MB
-		SupportedExtensions:   strings.Split(getEnv("SUPPORTED_EXTENSIONS", ".bin,.img,.hex,.rom,.fw"), ","),
+		SupportedExtensions:   strings.Split(getEnv("SUPPORTED_EXTENSIONS", ".bin,.img,.hex,.rom,.fw,.zip,.tar,.chk,.trx,.npk"), ","),
 		EMBAPath:             getEnv("EMBA_PATH", "../emba"),
 		EMBALogDir:           getEnv("EMBA_LOG_DIR", "/tmp/emba_logs"),
 		EMBAEnableEmulation:  getEnvAsBool("EMBA_ENABLE_EMULATION", false),
@@ -55,13 +278,310 @@ func Load() (*Config, error) {
 		EMBAEnableLiveTesting: getEnvAsBool("EMBA_ENABLE_LIVE_TESTING", false),
 		EMBAScanProfile:      getEnv("EMBA_SCAN_PROFILE", "default-scan.emba"),
 		EMBAThreads:          getEnvAsInt("EMBA_THREADS", 2),
+		EMBAMaxConcurrent:    getEnvAsInt("EMBA_MAX_CONCURRENT", 1),
+		EMBATimeout:          getEnvAsDuration("EMBA_TIMEOUT", 0),
+		EMBAPartialInterval:  getEnvAsDuration("EMBA_PARTIAL_INTERVAL", time.Minute),
+		EMBAIngestDir:        getEnv("EMBA_INGEST_DIR", ""),
+		EncryptedEntropyThreshold: getEnvAsFloat("ENCRYPTED_ENTROPY_THRESHOLD", 7.95),
+		DecryptorDir:         getEnv("DECRYPTOR_DIR", ""),
+		DecryptTimeout:       getEnvAsDuration("DECRYPT_TIMEOUT", 5*time.Minute),
+		EMBAExcludedModules:  splitNonEmpty(getEnv("EMBA_EXCLUDED_MODULES", "")),
+		EMBARepositoryURL:    getEnv("EMBA_REPOSITORY_URL", "https://github.com/e-m-b-a/emba.git"),
+		EMBAPinnedVersion:    getEnv("EMBA_PINNED_VERSION", ""),
+		EMBAPrivilegeMode:    getEnv("EMBA_PRIVILEGE_MODE", "sudo"),
+		EMBAPrivilegeHelper:  getEnv("EMBA_PRIVILEGE_HELPER", ""),
+		EMBACPUQuota:         getEnv("EMBA_CPU_QUOTA", ""),
+		EMBAMemoryMax:        getEnv("EMBA_MEMORY_MAX", ""),
+		EMBANice:             getEnvAsInt("EMBA_NICE", 0),
+		EMBAIONiceClass:      getEnv("EMBA_IONICE_CLASS", ""),
+		EMBAIONiceLevel:      getEnvAsInt("EMBA_IONICE_LEVEL", 4),
+		EMBAOutputMaxSizeMB:  getEnvAsInt64("EMBA_OUTPUT_MAX_SIZE_MB", 50),
+		EMBAOutputMaxBackups: getEnvAsInt("EMBA_OUTPUT_MAX_BACKUPS", 3),
+		EMBAStoredOutputKB:   getEnvAsInt("EMBA_STORED_OUTPUT_KB", 64),
+		BinwalkPath:          getEnv("BINWALK_PATH", "binwalk"),
+		UnblobPath:           getEnv("UNBLOB_PATH", "unblob"),
+		EmbeddedWorker:     getEnvAsBool("EMBEDDED_WORKER", false),
+		OrgMaxConcurrentScans: getEnvAsInt("ORG_MAX_CONCURRENT_SCANS", 0),
+		MinFreeMemoryMB:      getEnvAsInt64("MIN_FREE_MEMORY_MB", 2048),
+		HeavyMinFreeMemoryMB: getEnvAsInt64("HEAVY_MIN_FREE_MEMORY_MB", 16384),
+		MinFreeDiskMB:        getEnvAsInt64("MIN_FREE_DISK_MB", 10240),
+		MaxLoadPerCPU:        getEnvAsFloat("MAX_LOAD_PER_CPU", 1.5),
+		HeavyScanProfiles:    splitNonEmpty(getEnv("HEAVY_SCAN_PROFILES", "full-scan.emba,default-scan-emulation.emba")),
+		JanitorInterval:     getEnvAsDuration("JANITOR_INTERVAL", 5*time.Minute),
+		StuckJobThreshold:   getEnvAsDuration("STUCK_JOB_THRESHOLD", 30*time.Minute),
+		StuckJobAction:      getEnv("STUCK_JOB_ACTION", "requeue"),
+		StuckJobMaxRequeues: getEnvAsInt("STUCK_JOB_MAX_REQUEUES", 1),
+		JobMaxRetries:      getEnvAsInt("JOB_MAX_RETRIES", 3),
+		JobRetryBaseDelay:  getEnvAsDuration("JOB_RETRY_BASE_DELAY", time.Minute),
+		JobRetryMaxDelay:   getEnvAsDuration("JOB_RETRY_MAX_DELAY", 30*time.Minute),
+		JobRetryableErrors: splitNonEmpty(getEnv("JOB_RETRYABLE_ERRORS", "")),
+		VerdictEngines:     strings.Split(getEnv("VERDICT_ENGINES", ""), ","),
+		VerdictPolicy:      getEnv("VERDICT_POLICY", "review"),
+		ClamAVPath:         getEnv("CLAMAV_PATH", "clamscan"),
+		SandboxAPIURL:      getEnv("SANDBOX_API_URL", ""),
+		SandboxAPIKey:      getEnv("SANDBOX_API_KEY", ""),
+		PasswordCracker:      getEnv("PASSWORD_CRACKER", ""),
+		PasswordCrackerPath:  getEnv("PASSWORD_CRACKER_PATH", ""),
+		PasswordWordlist:     getEnv("PASSWORD_WORDLIST", ""),
+		PasswordCrackTimeout: getEnvAsDuration("PASSWORD_CRACK_TIMEOUT", 10*time.Minute),
+		OfflineMode:        getEnvAsBool("OFFLINE_MODE", false),
 		ShodanAPIKey:       getEnv("SHODAN_API_KEY", ""),
+		CensysAPIID:        getEnv("CENSYS_API_ID", ""),
+		CensysAPISecret:    getEnv("CENSYS_API_SECRET", ""),
 		VirusTotalAPIKey:   getEnv("VIRUSTOTAL_API_KEY", ""),
+		VirusTotalRateLimit: getEnvAsInt("VIRUSTOTAL_RATE_LIMIT", 4),
+		IntegrationsKey:     getEnv("INTEGRATIONS_KEY", ""),
+		OSINTTimeout:         getEnvAsDuration("OSINT_TIMEOUT", 2*time.Minute),
+		OSINTCacheTTL:        getEnvAsDuration("OSINT_CACHE_TTL", 24*time.Hour),
+		OSINTRefreshInterval: getEnvAsDuration("OSINT_REFRESH_INTERVAL", 0),
+		OSINTProviders:       splitNonEmpty(getEnv("OSINT_PROVIDERS", "")),
+		EOLLookup:            getEnvAsBool("EOL_LOOKUP", false),
+		ExploitLookup:        getEnvAsBool("EXPLOIT_LOOKUP", false),
+		ExploitLookupTimeout: getEnvAsDuration("EXPLOIT_LOOKUP_TIMEOUT", 2*time.Minute),
+		GHSALookup:           getEnvAsBool("GHSA_LOOKUP", false),
+		GHSALookupTimeout:    getEnvAsDuration("GHSA_LOOKUP_TIMEOUT", 2*time.Minute),
+		GitHubToken:          getEnv("GITHUB_TOKEN", ""),
+		NVDEnrichment:        getEnvAsBool("NVD_ENRICHMENT", false),
+		NVDAPIKey:            getEnv("NVD_API_KEY", ""),
+		NVDCacheTTL:          getEnvAsDuration("NVD_CACHE_TTL", 7*24*time.Hour),
+		DefaultCredentialsURL:            getEnv("DEFAULT_CREDENTIALS_URL", ""),
+		DefaultCredentialsUpdateInterval: getEnvAsDuration("DEFAULT_CREDENTIALS_UPDATE_INTERVAL", 24*time.Hour),
+		CWEDictionary:                    getEnv("CWE_DICTIONARY", ""),
+		ThreatFeedPollInterval:           getEnvAsDuration("THREAT_FEED_POLL_INTERVAL", time.Hour),
+		EPSSEnrichment:       getEnvAsBool("EPSS_ENRICHMENT", false),
+		EPSSRefreshInterval:  getEnvAsDuration("EPSS_REFRESH_INTERVAL", 24*time.Hour),
+		CVEMonitoring:        getEnvAsBool("CVE_MONITORING", false),
+		CVEMonitorInterval:   getEnvAsDuration("CVE_MONITOR_INTERVAL", 24*time.Hour),
+		NVDModifiedFeedURL:   getEnv("NVD_MODIFIED_FEED_URL", "https://nvd.nist.gov/feeds/json/cve/2.0/nvdcve-2.0-modified.json.gz"),
+		VulnDBSync:              getEnvAsBool("VULNDB_SYNC", false),
+		VulnDBSyncInterval:      getEnvAsDuration("VULNDB_SYNC_INTERVAL", 24*time.Hour),
+		VulnDBNVDURL:            getEnv("VULNDB_NVD_URL", "https://nvd.nist.gov/feeds/json/cve/2.0/nvdcve-2.0-modified.json.gz"),
+		VulnDBKEVURL:            getEnv("VULNDB_KEV_URL", "https://www.cisa.gov/sites/default/files/feeds/known_exploited_vulnerabilities.json"),
+		VulnDBEPSSURL:           getEnv("VULNDB_EPSS_URL", "https://epss.empiricalsecurity.com/epss_scores-current.csv.gz"),
+		VulnDBExploitDBURL:      getEnv("VULNDB_EXPLOITDB_URL", "https://gitlab.com/exploit-database/exploitdb/-/raw/main/files_exploits.csv"),
+		VulnDBEMBAUpdateCommand: getEnv("VULNDB_EMBA_UPDATE_COMMAND", ""),
+		VulnDBEMBAUpdateTimeout: getEnvAsDuration("VULNDB_EMBA_UPDATE_TIMEOUT", 30*time.Minute),
+		SecretScan:           getEnvAsBool("SECRET_SCAN", true),
+		SecretScanTimeout:    getEnvAsDuration("SECRET_SCAN_TIMEOUT", 15*time.Minute),
+		FuzzyHash:            getEnvAsBool("FUZZY_HASH", true),
+		YaraScan:             getEnvAsBool("YARA_SCAN", false),
+		YaraPath:             getEnv("YARA_PATH", "yara"),
+		YaraScanTimeout:      getEnvAsDuration("YARA_SCAN_TIMEOUT", 30*time.Minute),
+		SLOWindow:          getEnvAsDuration("SLO_WINDOW", 30*24*time.Hour),
+	}
+
+	cfg.SLOObjectives, err = parseSLOObjectives(getEnv("SLO_OBJECTIVES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SLO_OBJECTIVES: %w", err)
+	}
+
+	if cfg.IntegrationsKey != "" {
+		if err := validateIntegrationsKey(cfg.IntegrationsKey); err != nil {
+			return nil, fmt.Errorf("invalid INTEGRATIONS_KEY: %w", err)
+		}
+	}
+
+	cfg.OSINTCacheTTLs, err = parseProviderTTLs(getEnv("OSINT_CACHE_TTLS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OSINT_CACHE_TTLS: %w", err)
+	}
+	cfg.OSINTScoreWeights, err = parseScoreWeights(getEnv("OSINT_SCORE_WEIGHTS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OSINT_SCORE_WEIGHTS: %w", err)
+	}
+	cfg.OSINTSourceReliability, err = parseSourceReliability(getEnv("OSINT_SOURCE_RELIABILITY", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OSINT_SOURCE_RELIABILITY: %w", err)
+	}
+	cfg.OSINTDailyQuotas, err = parseProviderLimits(getEnv("OSINT_DAILY_QUOTAS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OSINT_DAILY_QUOTAS: %w", err)
+	}
+	cfg.OSINTRateLimits, err = parseProviderLimits(getEnv("OSINT_RATE_LIMITS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OSINT_RATE_LIMITS: %w", err)
+	}
+
+	switch cfg.EMBAPrivilegeMode {
+	case "sudo", "none", "systemd-run":
+	case "helper":
+		if cfg.EMBAPrivilegeHelper == "" {
+			return nil, fmt.Errorf("EMBA_PRIVILEGE_HELPER is required with EMBA_PRIVILEGE_MODE=helper")
+		}
+	default:
+		return nil, fmt.Errorf("invalid EMBA_PRIVILEGE_MODE %q: must be sudo, none, systemd-run or helper", cfg.EMBAPrivilegeMode)
+	}
+
+	if cfg.EMBANice < -20 || cfg.EMBANice > 19 {
+		return nil, fmt.Errorf("invalid EMBA_NICE %d: must be between -20 and 19", cfg.EMBANice)
+	}
+	switch cfg.EMBAIONiceClass {
+	case "", "idle", "best-effort":
+	default:
+		return nil, fmt.Errorf("invalid EMBA_IONICE_CLASS %q: must be idle or best-effort", cfg.EMBAIONiceClass)
+	}
+	if cfg.EMBAIONiceLevel < 0 || cfg.EMBAIONiceLevel > 7 {
+		return nil, fmt.Errorf("invalid EMBA_IONICE_LEVEL %d: must be between 0 and 7", cfg.EMBAIONiceLevel)
+	}
+
+	if cfg.EMBAIngestDir == "" {
+		cfg.EMBAIngestDir = cfg.EMBALogDir
+	}
+	if cfg.EncryptedEntropyThreshold < 0 || cfg.EncryptedEntropyThreshold > 8 {
+		return nil, fmt.Errorf("invalid ENCRYPTED_ENTROPY_THRESHOLD %g: must be between 0 and 8 bits per byte", cfg.EncryptedEntropyThreshold)
+	}
+
+	switch cfg.PasswordCracker {
+	case "":
+	case "john", "hashcat":
+		if cfg.PasswordWordlist == "" {
+			return nil, fmt.Errorf("PASSWORD_WORDLIST is required with PASSWORD_CRACKER=%s", cfg.PasswordCracker)
+		}
+		if cfg.PasswordCrackerPath == "" {
+			cfg.PasswordCrackerPath = cfg.PasswordCracker
+		}
+	default:
+		return nil, fmt.Errorf("invalid PASSWORD_CRACKER %q: must be john or hashcat", cfg.PasswordCracker)
 	}
 
 	return cfg, nil
 }
 
+// SLOObjective is a turnaround guarantee: Target of the analyses with
+// ScanProfile ("*" for all) complete within Threshold of their upload
+type SLOObjective struct {
+	Name        string
+	ScanProfile string
+	Target      float64
+	Threshold   time.Duration
+}
+
+// parseSLOObjectives parses a comma separated list of
+// "profile:percent:threshold" objectives, e.g. "quick-scan.emba:95:1h"
+func parseSLOObjectives(value string) ([]SLOObjective, error) {
+	var objectives []SLOObjective
+	for _, item := range splitNonEmpty(value) {
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%q: expected profile:percent:threshold", item)
+		}
+		percent, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("%q: percent must be a number in (0, 100]", item)
+		}
+		threshold, err := time.ParseDuration(parts[2])
+		if err != nil || threshold <= 0 {
+			return nil, fmt.Errorf("%q: threshold must be a positive duration", item)
+		}
+		name := strings.TrimSuffix(parts[0], ".emba")
+		if parts[0] == "*" {
+			name = "all"
+		}
+		objectives = append(objectives, SLOObjective{
+			Name:        fmt.Sprintf("%s-%s", name, parts[2]),
+			ScanProfile: parts[0],
+			Target:      percent / 100,
+			Threshold:   threshold,
+		})
+	}
+	return objectives, nil
+}
+
+// validateIntegrationsKey checks the key is 32 base64 encoded bytes, as
+// AES-256 needs
+func validateIntegrationsKey(value string) error {
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("not base64: %w", err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("must be 32 bytes, got %d", len(key))
+	}
+	return nil
+}
+
+// parseProviderTTLs parses a comma separated list of "provider:ttl" cache
+// lifetimes, e.g. "shodan:12h,virustotal:168h"
+func parseProviderTTLs(value string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, item := range splitNonEmpty(value) {
+		provider, ttl, ok := strings.Cut(item, ":")
+		if !ok || strings.TrimSpace(provider) == "" {
+			return nil, fmt.Errorf("%q: expected provider:ttl", item)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(ttl))
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("%q: ttl must be a duration, 0 to disable caching", item)
+		}
+		ttls[strings.ToLower(strings.TrimSpace(provider))] = duration
+	}
+	return ttls, nil
+}
+
+// parseScoreWeights parses a comma separated list of "factor:weight"
+// weights of the OSINT confidence score factors, e.g.
+// "specificity:0.5,reliability:0.3,corroboration:0.2". Factors left out
+// weigh nothing; empty keeps the defaults.
+func parseScoreWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	var total float64
+	for _, item := range splitNonEmpty(value) {
+		factor, weight, ok := strings.Cut(item, ":")
+		factor = strings.ToLower(strings.TrimSpace(factor))
+		switch factor {
+		case "specificity", "reliability", "corroboration":
+		default:
+			return nil, fmt.Errorf("%q: expected specificity, reliability or corroboration:weight", item)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if !ok || err != nil || w < 0 {
+			return nil, fmt.Errorf("%q: weight must be a non-negative number", item)
+		}
+		weights[factor] = w
+		total += w
+	}
+	if len(weights) > 0 && total == 0 {
+		return nil, fmt.Errorf("the weights can't all be 0")
+	}
+	return weights, nil
+}
+
+// parseSourceReliability parses a comma separated list of
+// "provider:reliability" trust levels from 0 to 100, e.g. "shodan:60"
+func parseSourceReliability(value string) (map[string]int, error) {
+	reliability := make(map[string]int)
+	for _, item := range splitNonEmpty(value) {
+		provider, level, ok := strings.Cut(item, ":")
+		if !ok || strings.TrimSpace(provider) == "" {
+			return nil, fmt.Errorf("%q: expected provider:reliability", item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(level))
+		if err != nil || n < 0 || n > 100 {
+			return nil, fmt.Errorf("%q: reliability must be between 0 and 100", item)
+		}
+		reliability[strings.ToLower(strings.TrimSpace(provider))] = n
+	}
+	return reliability, nil
+}
+
+// parseProviderLimits parses a comma separated list of "provider:limit"
+// call counts, e.g. "shodan:100,virustotal:500"
+func parseProviderLimits(value string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, item := range splitNonEmpty(value) {
+		provider, limit, ok := strings.Cut(item, ":")
+		if !ok || strings.TrimSpace(provider) == "" {
+			return nil, fmt.Errorf("%q: expected provider:limit", item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q: limit must be a non-negative number of calls", item)
+		}
+		limits[strings.ToLower(strings.TrimSpace(provider))] = n
+	}
+	return limits, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -95,3 +615,31 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func splitNonEmpty(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
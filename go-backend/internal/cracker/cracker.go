@@ -0,0 +1,74 @@
+package cracker
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+
+	"odin-backend/internal/config"
+)
+
+// Cracker tries password hashes against a wordlist with an external tool
+type Cracker interface {
+	Name() string
+	// Supports reports whether the tool handles a hash algorithm
+	Supports(algorithm string) bool
+	// Crack tries hashes of one algorithm against the wordlist and returns
+	// the passwords it found by hash
+	Crack(ctx context.Context, algorithm string, hashes []string) (map[string]string, error)
+}
+
+// New returns the cracker enabled in config, or nil when cracking is off
+func New(cfg *config.Config) Cracker {
+	switch cfg.PasswordCracker {
+	case "john":
+		return NewJohn(cfg.PasswordCrackerPath, cfg.PasswordWordlist)
+	case "hashcat":
+		return NewHashcat(cfg.PasswordCrackerPath, cfg.PasswordWordlist)
+	}
+	return nil
+}
+
+// writeHashes writes one hash per line to a temporary file in dir
+func writeHashes(dir string, hashes []string) (string, error) {
+	file, err := os.CreateTemp(dir, "hashes-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(strings.Join(hashes, "\n") + "\n"); err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// readCracked reads hash:password lines, as both tools write them, keeping
+// the hashes that were asked for
+func readCracked(path string, hashes []string) (map[string]string, error) {
+	wanted := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		wanted[hash] = true
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cracked := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// crypt(3) hashes contain no colon, passwords may
+		hash, password, ok := strings.Cut(scanner.Text(), ":")
+		if ok && wanted[hash] {
+			cracked[hash] = password
+		}
+	}
+	return cracked, scanner.Err()
+}
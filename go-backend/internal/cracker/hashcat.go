@@ -0,0 +1,88 @@
+package cracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// hashcatModes are hashcat's hash modes of the algorithms it supports
+var hashcatModes = map[string]int{
+	models.HashDES:         1500,
+	models.HashMD5Crypt:    500,
+	models.HashBcrypt:      3200,
+	models.HashSHA256Crypt: 7400,
+	models.HashSHA512Crypt: 1800,
+}
+
+// Hashcat cracks hashes with hashcat in straight (wordlist) mode
+type Hashcat struct {
+	binary   string
+	wordlist string
+}
+
+// NewHashcat creates a cracker running the given hashcat binary
+func NewHashcat(binary, wordlist string) *Hashcat {
+	if binary == "" {
+		binary = "hashcat"
+	}
+	return &Hashcat{binary: binary, wordlist: wordlist}
+}
+
+// Name returns the cracker name
+func (h *Hashcat) Name() string {
+	return "hashcat"
+}
+
+// Supports reports whether hashcat has a mode for the algorithm
+func (h *Hashcat) Supports(algorithm string) bool {
+	_, ok := hashcatModes[algorithm]
+	return ok
+}
+
+// Crack runs hashcat without its pot file and reads the cracked hashes
+// from an output file. hashcat exits 0 when all hashes were cracked and 1
+// when the wordlist was exhausted first.
+func (h *Hashcat) Crack(ctx context.Context, algorithm string, hashes []string) (map[string]string, error) {
+	dir, err := os.MkdirTemp("", "odin-hashcat-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	hashFile, err := writeHashes(dir, hashes)
+	if err != nil {
+		return nil, err
+	}
+	outFile := filepath.Join(dir, "cracked.txt")
+
+	cmd := exec.CommandContext(ctx, h.binary,
+		"--hash-type="+strconv.Itoa(hashcatModes[algorithm]),
+		"--attack-mode=0",
+		"--potfile-disable",
+		"--outfile="+outFile,
+		"--outfile-format=1,2",
+		"--quiet",
+		hashFile,
+		h.wordlist,
+	)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		var exitErr *exec.ExitError
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			return nil, fmt.Errorf("hashcat failed: %v: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	return readCracked(outFile, hashes)
+}
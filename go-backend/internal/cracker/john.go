@@ -0,0 +1,83 @@
+package cracker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// johnFormats are John the Ripper's format names of the hash algorithms;
+// its generic crypt format covers the rest through the system's crypt(3)
+var johnFormats = map[string]string{
+	models.HashDES:         "descrypt",
+	models.HashMD5Crypt:    "md5crypt",
+	models.HashBcrypt:      "bcrypt",
+	models.HashSHA256Crypt: "sha256crypt",
+	models.HashSHA512Crypt: "sha512crypt",
+	models.HashYescrypt:    "crypt",
+	models.HashCrypt:       "crypt",
+}
+
+// John cracks hashes with John the Ripper in wordlist mode
+type John struct {
+	binary   string
+	wordlist string
+}
+
+// NewJohn creates a cracker running the given john binary
+func NewJohn(binary, wordlist string) *John {
+	if binary == "" {
+		binary = "john"
+	}
+	return &John{binary: binary, wordlist: wordlist}
+}
+
+// Name returns the cracker name
+func (j *John) Name() string {
+	return "john"
+}
+
+// Supports reports whether john has a format for the algorithm
+func (j *John) Supports(algorithm string) bool {
+	_, ok := johnFormats[algorithm]
+	return ok
+}
+
+// Crack runs john with a pot file of its own, so passwords cracked for other
+// projects don't leak into this one's results and aren't skipped
+func (j *John) Crack(ctx context.Context, algorithm string, hashes []string) (map[string]string, error) {
+	dir, err := os.MkdirTemp("", "odin-john-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	hashFile, err := writeHashes(dir, hashes)
+	if err != nil {
+		return nil, err
+	}
+	pot := filepath.Join(dir, "john.pot")
+
+	cmd := exec.CommandContext(ctx, j.binary,
+		"--wordlist="+j.wordlist,
+		"--format="+johnFormats[algorithm],
+		"--pot="+pot,
+		"--session="+filepath.Join(dir, "session"),
+		"--nolog",
+		hashFile,
+	)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("john failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return readCracked(pot, hashes)
+}
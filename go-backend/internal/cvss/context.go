@@ -0,0 +1,138 @@
+package cvss
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reachability of a device, the CVSS attack vectors an attacker can use
+const (
+	ReachNetwork  = "network"
+	ReachAdjacent = "adjacent"
+	ReachLocal    = "local"
+	ReachPhysical = "physical"
+)
+
+// Exploit maturity of a CVE, from the exploits known of it
+const (
+	ExploitAttacked   = "attacked"   // exploited in the wild
+	ExploitFunctional = "functional" // a weaponized exploit, e.g. a Metasploit module
+	ExploitPoC        = "poc"        // a proof of concept
+	ExploitUnreported = "unreported" // no exploit known
+)
+
+// reachVectors maps reachabilities to attack vectors, least restrictive first
+var reachVectors = []struct{ reach, vector string }{
+	{ReachNetwork, "N"},
+	{ReachAdjacent, "A"},
+	{ReachLocal, "L"},
+	{ReachPhysical, "P"},
+}
+
+// exploitValues maps exploit maturities to the E metric of v3 and v4
+var exploitValues = map[string][2]string{
+	ExploitAttacked:   {"H", "A"},
+	ExploitFunctional: {"F", "P"},
+	ExploitPoC:        {"P", "P"},
+	ExploitUnreported: {"U", "U"},
+}
+
+// Context is what is known of where a device is deployed and of the
+// exploits of a CVE, which a vector is adjusted to. Empty fields leave
+// their metrics as they are.
+type Context struct {
+	Reachability string // network, adjacent, local or physical
+	Requirements string // confidentiality, integrity and availability requirements, e.g. CR:H/IR:M/AR:L
+	Exploit      string // attacked, functional, poc or unreported
+}
+
+// ValidateReachability returns an error unless a reachability is empty or
+// one of network, adjacent, local and physical
+func ValidateReachability(reach string) error {
+	if reach == "" {
+		return nil
+	}
+	for _, rv := range reachVectors {
+		if rv.reach == reach {
+			return nil
+		}
+	}
+	return fmt.Errorf("reachability must be network, adjacent, local or physical")
+}
+
+// ValidateRequirements returns an error unless security requirements are
+// empty or CR, IR and AR metrics, e.g. CR:H/IR:M/AR:L
+func ValidateRequirements(requirements string) error {
+	_, err := parseRequirements(requirements)
+	return err
+}
+
+func parseRequirements(requirements string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	if requirements == "" {
+		return parsed, nil
+	}
+	for _, part := range strings.Split(requirements, "/") {
+		name, value, ok := strings.Cut(part, ":")
+		if !ok || (name != "CR" && name != "IR" && name != "AR") || !v3Metrics.valid(name, value) {
+			return nil, fmt.Errorf("security requirements must be CR, IR and AR metrics rated H, M or L, e.g. CR:H/IR:M/AR:L")
+		}
+		parsed[name] = value
+	}
+	return parsed, nil
+}
+
+// Adjust returns a copy of the vector with the context's environmental and
+// exploit maturity metrics. A device reachable in a more restricted way
+// than the CVE's attack vector modifies the attack vector (MAV), never to a
+// less restricted one: a CVE needing local access stays local on a device
+// on the internet.
+func (v *Vector) Adjust(ctx Context) (*Vector, error) {
+	adjusted := v.Clone()
+
+	if ctx.Reachability != "" {
+		if err := ValidateReachability(ctx.Reachability); err != nil {
+			return nil, err
+		}
+		if restriction(ctx.Reachability) > strings.Index("NALP", v.Get("AV")) {
+			if err := adjusted.Set("MAV", reachVectors[restriction(ctx.Reachability)].vector); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	requirements, err := parseRequirements(ctx.Requirements)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range requirements {
+		if err := adjusted.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if ctx.Exploit != "" {
+		values, ok := exploitValues[ctx.Exploit]
+		if !ok {
+			return nil, fmt.Errorf("unknown exploit maturity %q", ctx.Exploit)
+		}
+		value := values[0]
+		if v.Version == Version40 {
+			value = values[1]
+		}
+		if err := adjusted.Set("E", value); err != nil {
+			return nil, err
+		}
+	}
+	return adjusted, nil
+}
+
+// restriction returns the index of a reachability in reachVectors
+func restriction(reach string) int {
+	for i, rv := range reachVectors {
+		if rv.reach == reach {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,319 @@
+// Package cvss parses CVSS v3.0, v3.1 and v4.0 vectors and scores v3
+// vectors: the base, temporal and environmental scores of the
+// specification, so a CVE's rating can be adjusted to where a device is
+// deployed
+package cvss
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Versions of the vectors Parse accepts
+const (
+	Version30 = "3.0"
+	Version31 = "3.1"
+	Version40 = "4.0"
+)
+
+// metricValues are the metrics of a version and the values they take, in
+// the specification's order
+type metricValues struct {
+	names  []string
+	values map[string]string // metric: values, one letter each unless comma separated
+	base   []string          // mandatory metrics
+}
+
+var v3Metrics = metricValues{
+	names: []string{"AV", "AC", "PR", "UI", "S", "C", "I", "A", "E", "RL", "RC",
+		"CR", "IR", "AR", "MAV", "MAC", "MPR", "MUI", "MS", "MC", "MI", "MA"},
+	values: map[string]string{
+		"AV": "NALP", "AC": "LH", "PR": "NLH", "UI": "NR", "S": "UC", "C": "HLN", "I": "HLN", "A": "HLN",
+		"E": "XHFPU", "RL": "XUWTO", "RC": "XCRU",
+		"CR": "XHML", "IR": "XHML", "AR": "XHML",
+		"MAV": "XNALP", "MAC": "XLH", "MPR": "XNLH", "MUI": "XNR", "MS": "XUC", "MC": "XHLN", "MI": "XHLN", "MA": "XHLN",
+	},
+	base: []string{"AV", "AC", "PR", "UI", "S", "C", "I", "A"},
+}
+
+var v4Metrics = metricValues{
+	names: []string{"AV", "AC", "AT", "PR", "UI", "VC", "VI", "VA", "SC", "SI", "SA", "E",
+		"CR", "IR", "AR", "MAV", "MAC", "MAT", "MPR", "MUI", "MVC", "MVI", "MVA", "MSC", "MSI", "MSA",
+		"S", "AU", "R", "V", "RE", "U"},
+	values: map[string]string{
+		"AV": "NALP", "AC": "LH", "AT": "NP", "PR": "NLH", "UI": "NPA",
+		"VC": "HLN", "VI": "HLN", "VA": "HLN", "SC": "HLN", "SI": "HLN", "SA": "HLN",
+		"E":  "XAPU",
+		"CR": "XHML", "IR": "XHML", "AR": "XHML",
+		"MAV": "XNALP", "MAC": "XLH", "MAT": "XNP", "MPR": "XNLH", "MUI": "XNPA",
+		"MVC": "XHLN", "MVI": "XHLN", "MVA": "XHLN", "MSC": "XHLN", "MSI": "XSHLN", "MSA": "XSHLN",
+		"S": "XNP", "AU": "XNY", "R": "XAUI", "V": "XDC", "RE": "XLMH", "U": "X,Clear,Green,Amber,Red",
+	},
+	base: []string{"AV", "AC", "AT", "PR", "UI", "VC", "VI", "VA", "SC", "SI", "SA"},
+}
+
+// Vector is a parsed CVSS vector
+type Vector struct {
+	Version string
+	metrics map[string]string
+}
+
+// Parse parses a CVSS v3.0, v3.1 or v4.0 vector string. A v3 vector without
+// its CVSS:3.x prefix, as some tools print it, is read as v3.1.
+func Parse(vector string) (*Vector, error) {
+	vector = strings.TrimSpace(vector)
+	version, rest := Version31, vector
+	if strings.HasPrefix(vector, "CVSS:") {
+		prefix, metrics, ok := strings.Cut(strings.TrimPrefix(vector, "CVSS:"), "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid CVSS vector %q", vector)
+		}
+		version, rest = prefix, metrics
+	}
+	spec, err := specOf(version)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vector{Version: version, metrics: make(map[string]string)}
+	for _, part := range strings.Split(rest, "/") {
+		name, value, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid CVSS metric %q", part)
+		}
+		if !spec.valid(name, value) {
+			return nil, fmt.Errorf("invalid CVSS %s metric %s:%s", version, name, value)
+		}
+		if _, dup := v.metrics[name]; dup {
+			return nil, fmt.Errorf("CVSS metric %s given twice", name)
+		}
+		v.metrics[name] = value
+	}
+	for _, name := range spec.base {
+		if _, ok := v.metrics[name]; !ok {
+			return nil, fmt.Errorf("CVSS %s vector lacks the %s metric", version, name)
+		}
+	}
+	return v, nil
+}
+
+func specOf(version string) (*metricValues, error) {
+	switch version {
+	case Version30, Version31:
+		return &v3Metrics, nil
+	case Version40:
+		return &v4Metrics, nil
+	}
+	return nil, fmt.Errorf("unsupported CVSS version %q", version)
+}
+
+func (m *metricValues) valid(name, value string) bool {
+	values, ok := m.values[name]
+	if !ok || value == "" {
+		return false
+	}
+	if strings.Contains(values, ",") {
+		for _, v := range strings.Split(values, ",") {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+	return len(value) == 1 && strings.Contains(values, value)
+}
+
+// Get returns the value of a metric, X (not defined) when the vector
+// doesn't set it
+func (v *Vector) Get(name string) string {
+	if value, ok := v.metrics[name]; ok {
+		return value
+	}
+	return "X"
+}
+
+// Set sets a metric, X removing it. It returns an error for metrics and
+// values the vector's version doesn't have.
+func (v *Vector) Set(name, value string) error {
+	spec, err := specOf(v.Version)
+	if err != nil {
+		return err
+	}
+	if !spec.valid(name, value) {
+		return fmt.Errorf("invalid CVSS %s metric %s:%s", v.Version, name, value)
+	}
+	if value == "X" {
+		delete(v.metrics, name)
+	} else {
+		v.metrics[name] = value
+	}
+	return nil
+}
+
+// Clone returns a copy of the vector
+func (v *Vector) Clone() *Vector {
+	clone := &Vector{Version: v.Version, metrics: make(map[string]string, len(v.metrics))}
+	for name, value := range v.metrics {
+		clone.metrics[name] = value
+	}
+	return clone
+}
+
+// String returns the vector with its prefix, its metrics in the
+// specification's order
+func (v *Vector) String() string {
+	spec, err := specOf(v.Version)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("CVSS:" + v.Version)
+	for _, name := range spec.names {
+		if value, ok := v.metrics[name]; ok {
+			b.WriteString("/" + name + ":" + value)
+		}
+	}
+	return b.String()
+}
+
+// Scorable reports whether the vector's scores can be computed: v3 vectors
+// can, v4 ones only be parsed and adjusted
+func (v *Vector) Scorable() bool {
+	return v.Version == Version30 || v.Version == Version31
+}
+
+// Severity returns the qualitative rating of a score: None, Low, Medium,
+// High or Critical
+func Severity(score float64) string {
+	switch {
+	case score == 0:
+		return "None"
+	case score < 4:
+		return "Low"
+	case score < 7:
+		return "Medium"
+	case score < 9:
+		return "High"
+	}
+	return "Critical"
+}
+
+// v3 metric weights
+var (
+	attackVector     = map[string]float64{"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2}
+	attackComplexity = map[string]float64{"L": 0.77, "H": 0.44}
+	userInteraction  = map[string]float64{"N": 0.85, "R": 0.62}
+	impactWeight     = map[string]float64{"H": 0.56, "L": 0.22, "N": 0}
+	requirement      = map[string]float64{"X": 1, "H": 1.5, "M": 1, "L": 0.5}
+	exploitMaturity  = map[string]float64{"X": 1, "H": 1, "F": 0.97, "P": 0.94, "U": 0.91}
+	remediationLevel = map[string]float64{"X": 1, "U": 1, "W": 0.97, "T": 0.96, "O": 0.95}
+	reportConfidence = map[string]float64{"X": 1, "C": 1, "R": 0.96, "U": 0.92}
+)
+
+func privilegesRequired(value string, scopeChanged bool) float64 {
+	switch value {
+	case "N":
+		return 0.85
+	case "L":
+		if scopeChanged {
+			return 0.68
+		}
+		return 0.62
+	}
+	if scopeChanged {
+		return 0.5
+	}
+	return 0.27
+}
+
+// BaseScore returns the v3 base score
+func (v *Vector) BaseScore() (float64, error) {
+	if !v.Scorable() {
+		return 0, fmt.Errorf("CVSS %s vectors can't be scored", v.Version)
+	}
+	changed := v.Get("S") == "C"
+	iss := 1 - (1-impactWeight[v.Get("C")])*(1-impactWeight[v.Get("I")])*(1-impactWeight[v.Get("A")])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	exploitability := 8.22 * attackVector[v.Get("AV")] * attackComplexity[v.Get("AC")] *
+		privilegesRequired(v.Get("PR"), changed) * userInteraction[v.Get("UI")]
+	if impact <= 0 {
+		return 0, nil
+	}
+	if changed {
+		return v.roundup(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return v.roundup(math.Min(impact+exploitability, 10)), nil
+}
+
+// TemporalScore returns the v3 temporal score: the base score weighed by
+// exploit code maturity, remediation level and report confidence
+func (v *Vector) TemporalScore() (float64, error) {
+	base, err := v.BaseScore()
+	if err != nil {
+		return 0, err
+	}
+	return v.roundup(base * v.temporalWeight()), nil
+}
+
+func (v *Vector) temporalWeight() float64 {
+	return exploitMaturity[v.Get("E")] * remediationLevel[v.Get("RL")] * reportConfidence[v.Get("RC")]
+}
+
+// EnvironmentalScore returns the v3 environmental score: the score of the
+// modified base metrics, weighed by the security requirements and the
+// temporal metrics. Without environmental metrics it is the temporal score,
+// but for v3.1 vectors changing the scope, whose modified impact v3.1
+// weighs differently than their impact.
+func (v *Vector) EnvironmentalScore() (float64, error) {
+	if !v.Scorable() {
+		return 0, fmt.Errorf("CVSS %s vectors can't be scored", v.Version)
+	}
+	changed := v.modified("S") == "C"
+	miss := math.Min(1-
+		(1-requirement[v.Get("CR")]*impactWeight[v.modified("C")])*
+			(1-requirement[v.Get("IR")]*impactWeight[v.modified("I")])*
+			(1-requirement[v.Get("AR")]*impactWeight[v.modified("A")]), 0.915)
+	impact := 6.42 * miss
+	if changed {
+		if v.Version == Version30 {
+			impact = 7.52*(miss-0.029) - 3.25*math.Pow(miss-0.02, 15)
+		} else {
+			impact = 7.52*(miss-0.029) - 3.25*math.Pow(miss*0.9731-0.02, 13)
+		}
+	}
+	exploitability := 8.22 * attackVector[v.modified("AV")] * attackComplexity[v.modified("AC")] *
+		privilegesRequired(v.modified("PR"), changed) * userInteraction[v.modified("UI")]
+	if impact <= 0 {
+		return 0, nil
+	}
+	if changed {
+		return v.roundup(v.roundup(math.Min(1.08*(impact+exploitability), 10)) * v.temporalWeight()), nil
+	}
+	return v.roundup(v.roundup(math.Min(impact+exploitability, 10)) * v.temporalWeight()), nil
+}
+
+// modified returns the modified value of a base metric, the base value
+// unless the vector modifies it
+func (v *Vector) modified(name string) string {
+	if value := v.Get("M" + name); value != "X" {
+		return value
+	}
+	return v.Get(name)
+}
+
+// roundup rounds a score up to one decimal, as v3.1 specifies it to avoid
+// floating point artifacts, or as v3.0 did
+func (v *Vector) roundup(score float64) float64 {
+	if v.Version == Version30 {
+		return math.Ceil(score*10) / 10
+	}
+	scaled := int(math.Round(score * 100000))
+	if scaled%10000 == 0 {
+		return float64(scaled) / 100000
+	}
+	return float64(scaled/10000+1) / 10
+}
@@ -0,0 +1,107 @@
+package cvss
+
+import "testing"
+
+// TestScores scores the vectors of FIRST's CVSS v3.1 examples document and
+// variations of them with temporal and environmental metrics; the expected
+// scores follow the equations of the v3.0 and v3.1 specifications
+func TestScores(t *testing.T) {
+	cases := []struct {
+		vector                        string
+		base, temporal, environmental float64
+	}{
+		// Base scores of the examples document
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N", 7.5, 7.5, 7.5}, // CVE-2014-0160
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8, 9.8, 9.8}, // CVE-2014-6271
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1, 6.1, 6.1}, // CVE-2013-1937
+		{"CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:C/C:L/I:L/A:N", 6.4, 6.4, 6.4}, // CVE-2013-0375
+		{"CVSS:3.1/AV:N/AC:H/PR:N/UI:R/S:U/C:L/I:N/A:N", 3.1, 3.1, 3.1}, // CVE-2014-3566
+		{"CVSS:3.1/AV:L/AC:L/PR:H/UI:N/S:U/C:L/I:L/A:L", 4.2, 4.2, 4.2}, // CVE-2009-0783
+		{"CVSS:3.1/AV:L/AC:L/PR:N/UI:R/S:U/C:H/I:H/A:H", 7.8, 7.8, 7.8}, // CVE-2015-1098
+		{"CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:H/A:N", 7.4, 7.4, 7.4}, // CVE-2014-0224
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:L/I:N/A:N", 5.8, 5.8, 5.8}, // CVE-2010-0467
+		{"CVSS:3.1/AV:P/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N", 4.6, 4.6, 4.6}, // CVE-2015-2890
+		{"CVSS:3.1/AV:L/AC:L/PR:H/UI:N/S:U/C:N/I:N/A:N", 0, 0, 0},
+
+		// Temporal metrics
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N/E:H/RL:O/RC:C", 7.5, 7.2, 7.2},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/E:P/RL:T/RC:R", 9.8, 8.5, 8.5},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N/E:U/RL:W/RC:U", 6.1, 5.0, 5.0},
+
+		// Environmental metrics
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/MAV:A/MPR:L/CR:L/IR:H/AR:M", 9.8, 9.8, 8.0},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N/MAV:L/MC:N/MI:N/MA:N", 7.5, 7.5, 0},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N/MC:H/IR:H", 6.1, 6.1, 8.6},
+		{"CVSS:3.0/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N/MC:H/IR:H", 6.1, 6.1, 8.6},
+		{"CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:C/C:H/I:H/A:H/MS:U", 9.9, 9.9, 8.8},
+
+		// v3.1 weighs the modified impact of a changed scope differently,
+		// so its environmental score differs from the base score even
+		// without environmental metrics
+		{"CVSS:3.0/AV:N/AC:L/PR:L/UI:N/S:C/C:H/I:H/A:H", 9.9, 9.9, 9.9}, // CVE-2012-1516
+		{"CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:C/C:H/I:H/A:H", 9.9, 9.9, 10.0},
+		{"CVSS:3.0/AV:L/AC:L/PR:N/UI:R/S:U/C:H/I:H/A:H/MS:C/MPR:L/CR:H", 7.8, 7.8, 8.2},
+		{"CVSS:3.1/AV:L/AC:L/PR:N/UI:R/S:U/C:H/I:H/A:H/MS:C/MPR:L/CR:H", 7.8, 7.8, 8.3},
+
+		// 5.0 × 0.92 is 4.6000000000000005 in floating point: v3.0's
+		// roundup made it 4.7, v3.1's rounds it to 4.6
+		{"CVSS:3.0/AV:N/AC:L/PR:N/UI:R/S:U/C:L/I:N/A:N/RC:U/CR:H", 4.3, 4.0, 4.7},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:U/C:L/I:N/A:N/RC:U/CR:H", 4.3, 4.0, 4.6},
+	}
+
+	for _, tc := range cases {
+		v, err := Parse(tc.vector)
+		if err != nil {
+			t.Errorf("%s: %v", tc.vector, err)
+			continue
+		}
+		base, err := v.BaseScore()
+		if err != nil {
+			t.Errorf("%s: %v", tc.vector, err)
+			continue
+		}
+		temporal, _ := v.TemporalScore()
+		environmental, _ := v.EnvironmentalScore()
+		if base != tc.base || temporal != tc.temporal || environmental != tc.environmental {
+			t.Errorf("%s: got %.1f/%.1f/%.1f, want %.1f/%.1f/%.1f", tc.vector,
+				base, temporal, environmental, tc.base, tc.temporal, tc.environmental)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	v, err := Parse("AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != Version31 {
+		t.Errorf("unprefixed vector read as %s, want 3.1", v.Version)
+	}
+	if err := v.Set("MAV", "L"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.String(), "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N/MAV:L"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	v4, err := Parse("CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N/U:Amber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v4.BaseScore(); err == nil {
+		t.Error("a v4.0 vector was scored")
+	}
+
+	for _, vector := range []string{
+		"CVSS:2.0/AV:N/AC:L/Au:N/C:P/I:P/A:P",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N/A:H",
+		"CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N/E",
+		"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N/U:Blue",
+	} {
+		if _, err := Parse(vector); err == nil {
+			t.Errorf("%s parsed without error", vector)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package cwe holds a dictionary of MITRE's Common Weakness Enumeration, to
+// name the weaknesses findings cite and roll them up into their parent
+// classes. Odin ships the weaknesses firmware analyses find; CWE_DICTIONARY
+// replaces them with MITRE's full Research Concepts view (1000.csv).
+package cwe
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed cwe.csv
+var shippedCSV string
+
+var (
+	shippedOnce sync.Once
+	shipped     *Dictionary
+)
+
+// researchView is the CWE view whose ChildOf relations make the hierarchy
+const researchView = "1000"
+
+// Weakness is an entry of the dictionary
+type Weakness struct {
+	ID          string   `json:"id"` // e.g. CWE-787
+	Name        string   `json:"name"`
+	Abstraction string   `json:"abstraction,omitempty"` // Pillar, Class, Base or Variant
+	Description string   `json:"description"`
+	Parents     []string `json:"parents"`
+}
+
+// Dictionary looks weaknesses up by ID
+type Dictionary struct {
+	weaknesses map[string]Weakness
+	children   map[string][]string
+}
+
+// Shipped returns the dictionary shipped with Odin
+func Shipped() *Dictionary {
+	shippedOnce.Do(func() {
+		var err error
+		shipped, err = Parse(strings.NewReader(shippedCSV))
+		if err != nil {
+			panic(fmt.Sprintf("shipped CWE dictionary doesn't parse: %v", err))
+		}
+	})
+	return shipped
+}
+
+// Load returns the dictionary in a CSV file in MITRE's format, or the
+// shipped one when path is empty
+func Load(path string) (*Dictionary, error) {
+	if path == "" {
+		return Shipped(), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Parse reads a CSV dictionary in MITRE's format, with a header naming its
+// columns: CWE-ID and Name, and optionally Weakness Abstraction, Description
+// and Related Weaknesses, whose ChildOf relations in the research view are
+// the parents
+func Parse(r io.Reader) (*Dictionary, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"cwe-id", "name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("no %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	dictionary := &Dictionary{
+		weaknesses: make(map[string]Weakness),
+		children:   make(map[string][]string),
+	}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		id := Normalize(field(record, "cwe-id"))
+		if id == "" {
+			continue
+		}
+		dictionary.weaknesses[id] = Weakness{
+			ID:          id,
+			Name:        field(record, "name"),
+			Abstraction: field(record, "weakness abstraction"),
+			Description: field(record, "description"),
+			Parents:     parents(field(record, "related weaknesses")),
+		}
+	}
+	for id, weakness := range dictionary.weaknesses {
+		for _, parent := range weakness.Parents {
+			dictionary.children[parent] = append(dictionary.children[parent], id)
+		}
+	}
+	for parent := range dictionary.children {
+		sort.Slice(dictionary.children[parent], func(i, j int) bool {
+			return less(dictionary.children[parent][i], dictionary.children[parent][j])
+		})
+	}
+	return dictionary, nil
+}
+
+// parents returns the ChildOf targets in the research view of a Related
+// Weaknesses field, e.g. ::NATURE:ChildOf:CWE ID:119:VIEW ID:1000::
+func parents(related string) []string {
+	parents := []string{}
+	for _, relation := range strings.Split(related, "::") {
+		parts := strings.Split(relation, ":")
+		fields := make(map[string]string)
+		for i := 0; i+1 < len(parts); i += 2 {
+			fields[parts[i]] = parts[i+1]
+		}
+		if fields["NATURE"] != "ChildOf" || fields["VIEW ID"] != researchView {
+			continue
+		}
+		if id := Normalize(fields["CWE ID"]); id != "" {
+			parents = append(parents, id)
+		}
+	}
+	return parents
+}
+
+var idPattern = regexp.MustCompile(`(?i)\bCWE[-_ ]?(\d+)\b`)
+
+// Normalize returns an ID as CWE-<number>, accepting the bare number and
+// other spellings such as cwe_787, or "" when it isn't one
+func Normalize(id string) string {
+	id = strings.TrimSpace(id)
+	if id != "" && strings.Trim(id, "0123456789") == "" {
+		return format(id)
+	}
+	if match := idPattern.FindStringSubmatch(id); match != nil && match[0] == id {
+		return format(match[1])
+	}
+	return ""
+}
+
+// Extract returns the first CWE ID a text mentions, as cwe_checker's
+// [CWE676] or as CWE-676, or ""
+func Extract(text string) string {
+	for _, match := range idPattern.FindAllStringSubmatch(text, -1) {
+		if id := format(match[1]); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func format(number string) string {
+	number = strings.TrimLeft(number, "0")
+	if number == "" {
+		return ""
+	}
+	return "CWE-" + number
+}
+
+// Lookup returns the weakness with an ID
+func (d *Dictionary) Lookup(id string) (Weakness, bool) {
+	weakness, ok := d.weaknesses[Normalize(id)]
+	return weakness, ok
+}
+
+// Len returns the number of weaknesses in the dictionary
+func (d *Dictionary) Len() int {
+	return len(d.weaknesses)
+}
+
+// Children returns the IDs of a weakness' direct children
+func (d *Dictionary) Children(id string) []string {
+	return d.children[Normalize(id)]
+}
+
+// Ancestors returns the IDs of a weakness' parents, their parents and so on
+// up to the pillars, nearest first
+func (d *Dictionary) Ancestors(id string) []string {
+	var ancestors []string
+	seen := map[string]bool{Normalize(id): true}
+	queue := []string{Normalize(id)}
+	for len(queue) > 0 {
+		weakness := d.weaknesses[queue[0]]
+		queue = queue[1:]
+		for _, parent := range weakness.Parents {
+			if seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			ancestors = append(ancestors, parent)
+			queue = append(queue, parent)
+		}
+	}
+	return ancestors
+}
+
+// Descendants returns the IDs of a weakness' children, their children and
+// so on
+func (d *Dictionary) Descendants(id string) []string {
+	var descendants []string
+	seen := map[string]bool{Normalize(id): true}
+	queue := []string{Normalize(id)}
+	for len(queue) > 0 {
+		for _, child := range d.children[queue[0]] {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			descendants = append(descendants, child)
+			queue = append(queue, child)
+		}
+		queue = queue[1:]
+	}
+	return descendants
+}
+
+// less orders CWE IDs by number
+func less(a, b string) bool {
+	var x, y int
+	fmt.Sscanf(a, "CWE-%d", &x)
+	fmt.Sscanf(b, "CWE-%d", &y)
+	return x < y
+}
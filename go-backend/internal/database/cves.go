@@ -0,0 +1,127 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/freeze"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// cveFindingColumns are the columns of the CVE data cve_findings used to
+// repeat for every project, now in the cves table
+var cveFindingColumns = []string{
+	"description", "references", "cvss_version", "exploitability_score", "impact_score",
+	"cwe_ids", "published_at", "last_modified_at", "epss_score", "epss_percentile", "epss_checked_at",
+}
+
+// frozenCVEColumns are the CVE data of a frozen project's finding as
+// cve_findings held it
+type frozenCVEColumns struct {
+	ID                  uint
+	CVEID               string
+	Description         string
+	References          string
+	CVSSVersion         string
+	ExploitabilityScore float64
+	ImpactScore         float64
+	CWEIDs              string
+	PublishedAt         *time.Time
+	LastModifiedAt      *time.Time
+	EPSSScore           float64
+	EPSSPercentile      float64
+	EPSSCheckedAt       *time.Time
+}
+
+// migrateCVEDictionary moves the CVE data of databases created before the
+// cves table there, a row per CVE, and drops it from cve_findings. Frozen
+// projects' findings keep theirs as CVE snapshots, so their results and
+// content hashes stay as they were frozen.
+func migrateCVEDictionary(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.CVEFinding{}, "description") {
+		return nil
+	}
+	log.Printf("Moving the CVE data of cve_findings to the shared cves table")
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Of the values projects have for a CVE, the latest NVD and EPSS
+		// data and any description and references
+		if err := tx.Exec("INSERT OR IGNORE INTO cves (id, description, `references`, cvss_version, exploitability_score, impact_score, " +
+			"cwe_ids, published_at, last_modified_at, nvd_enriched_at, epss_score, epss_percentile, epss_checked_at, created_at, updated_at) " +
+			"SELECT cve_id, MAX(COALESCE(description, '')), MAX(COALESCE(`references`, '')), MAX(COALESCE(cvss_version, '')), " +
+			"MAX(COALESCE(exploitability_score, 0)), MAX(COALESCE(impact_score, 0)), MAX(COALESCE(cwe_ids, '')), MAX(published_at), " +
+			"MAX(last_modified_at), MAX(nvd_enriched_at), MAX(COALESCE(epss_score, 0)), MAX(COALESCE(epss_percentile, 0)), " +
+			"MAX(epss_checked_at), MIN(created_at), CURRENT_TIMESTAMP FROM cve_findings WHERE cve_id <> '' GROUP BY cve_id").Error; err != nil {
+			return err
+		}
+
+		// Frozen projects keep the values their findings had
+		var frozen []models.Project
+		if err := tx.Select("id", "frozen_hash").Where("frozen_at IS NOT NULL").Find(&frozen).Error; err != nil {
+			return err
+		}
+		for _, project := range frozen {
+			var findings []frozenCVEColumns
+			if err := tx.Table("cve_findings").Where("project_id = ? AND cve_id <> ''", project.ID).Find(&findings).Error; err != nil {
+				return err
+			}
+			for _, finding := range findings {
+				snapshot, err := json.Marshal(models.CVE{
+					ID:                  finding.CVEID,
+					Description:         finding.Description,
+					References:          finding.References,
+					CVSSVersion:         finding.CVSSVersion,
+					ExploitabilityScore: finding.ExploitabilityScore,
+					ImpactScore:         finding.ImpactScore,
+					CWEIDs:              finding.CWEIDs,
+					PublishedAt:         finding.PublishedAt,
+					LastModifiedAt:      finding.LastModifiedAt,
+					EPSSScore:           finding.EPSSScore,
+					EPSSPercentile:      finding.EPSSPercentile,
+					EPSSCheckedAt:       finding.EPSSCheckedAt,
+				})
+				if err != nil {
+					return err
+				}
+				if err := tx.Table("cve_findings").Where("id = ?", finding.ID).
+					UpdateColumn("cve_snapshot", string(snapshot)).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, column := range cveFindingColumns {
+			if err := tx.Exec("DROP INDEX IF EXISTS idx_cve_findings_" + column).Error; err != nil {
+				return err
+			}
+			if !tx.Migrator().HasColumn(&models.CVEFinding{}, column) {
+				continue
+			}
+			if err := tx.Exec("ALTER TABLE cve_findings DROP COLUMN `" + column + "`").Error; err != nil {
+				return fmt.Errorf("failed to drop cve_findings.%s: %w", column, err)
+			}
+		}
+
+		// The snapshots hash as the columns did: the recorded hashes stand
+		for _, project := range frozen {
+			hash, err := freeze.ContentHash(tx, project.ID)
+			if err != nil {
+				return err
+			}
+			if hash != project.FrozenHash {
+				log.Printf("Warning: results of frozen project %s no longer match its content hash", project.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Give the dropped columns' space back
+	return db.Exec("VACUUM").Error
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"odin-backend/internal/models"
 	"os"
 	"path/filepath"
@@ -29,12 +30,48 @@ func Initialize(databasePath string) (*gorm.DB, error) {
 	err = db.AutoMigrate(
 		&models.Project{},
 		&models.Finding{},
+		&models.CVE{},
 		&models.CVEFinding{},
+		&models.CVESuppression{},
 		&models.OSINTResult{},
+		&models.EngineVerdict{},
+		&models.SBOMComponent{},
+		&models.BinaryAnalysis{},
+		&models.PasswordHash{},
+		&models.EmulationResult{},
+		&models.KeyMaterial{},
+		&models.BinaryHash{},
+		&models.FirmwareFile{},
+		&models.YaraRuleSet{},
+		&models.Worker{},
+		&models.OrgSettings{},
+		&models.AuditLog{},
+		&models.BackfillState{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.CVEAlertRule{},
+		&models.EMBAInstall{},
+		&models.NVDRecord{},
+		&models.KEVEntry{},
+		&models.EPSSRecord{},
+		&models.ExploitDBEntry{},
+		&models.MirrorImport{},
+		&models.CVEMonitorRun{},
+		&models.VulnDBSync{},
+		&models.OSINTCacheEntry{},
+		&models.OSINTUsage{},
+		&models.Integration{},
+		&models.ThreatFeed{},
+		&models.ThreatIndicator{},
+		&models.DefaultCredential{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := migrateCVEDictionary(db); err != nil {
+		return nil, fmt.Errorf("failed to move CVE data to the cves table: %w", err)
+	}
+
 	return db, nil
 }
@@ -0,0 +1,124 @@
+// Package decrypt decrypts firmware images vendors encrypt with known
+// schemes, so an image that would be rejected as encrypted is analyzed from
+// its decrypted payload instead. Odin ships decryptors for a few schemes;
+// operators add their own as executables in DECRYPTOR_DIR.
+package decrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/fwformat"
+)
+
+// headerSize is how much of an image decryptors see to recognize it
+const headerSize = 64 * 1024
+
+// ErrNotDecrypted is returned when no decryptor recognized the image or
+// none produced a payload Odin recognizes
+var ErrNotDecrypted = errors.New("no decryptor could decrypt the firmware")
+
+// Decryptor decrypts the images of one encryption scheme
+type Decryptor interface {
+	// Name identifies the decryptor in results and logs
+	Name() string
+
+	// Match reports whether the image starting with header looks like the
+	// scheme's; encrypted tells whether its entropy says it's encrypted
+	Match(header []byte, encrypted bool) bool
+
+	// Decrypt writes the decrypted image at in to out
+	Decrypt(ctx context.Context, in, out string) error
+}
+
+// Registry tries the built-in decryptors and the operator's plugins in turn
+type Registry struct {
+	decryptors []Decryptor
+}
+
+// New creates a registry of the built-in decryptors and the executables in
+// the configured DECRYPTOR_DIR
+func New(cfg *config.Config) *Registry {
+	r := &Registry{decryptors: []Decryptor{dlinkSHRS{}, &xorDecryptor{}}}
+	if cfg.DecryptorDir != "" {
+		plugins, err := loadPlugins(cfg.DecryptorDir, cfg.DecryptTimeout)
+		if err != nil {
+			log.Printf("Failed to load decryptor plugins from %s: %v", cfg.DecryptorDir, err)
+		}
+		r.decryptors = append(r.decryptors, plugins...)
+	}
+	return r
+}
+
+// Decrypt tries the decryptors that recognize the image until one writes a
+// payload in a known format to out, and returns that decryptor's name. It
+// returns ErrNotDecrypted when none did.
+func (r *Registry) Decrypt(ctx context.Context, in, out string, encrypted bool) (string, error) {
+	header, err := readHeader(in)
+	if err != nil {
+		return "", err
+	}
+
+	for _, decryptor := range r.decryptors {
+		if !decryptor.Match(header, encrypted) {
+			continue
+		}
+		if err := decryptor.Decrypt(ctx, in, out); err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			log.Printf("Decryptor %s failed on %s: %v", decryptor.Name(), in, err)
+			os.Remove(out)
+			continue
+		}
+		if plausible(out) {
+			return decryptor.Name(), nil
+		}
+		log.Printf("Decryptor %s produced no recognizable payload from %s", decryptor.Name(), in)
+		os.Remove(out)
+	}
+	return "", ErrNotDecrypted
+}
+
+// plausible reports whether a decrypted image holds a container Odin
+// recognizes, which a wrong key or scheme wouldn't produce
+func plausible(path string) bool {
+	header, err := readHeader(path)
+	if err != nil || len(header) == 0 {
+		return false
+	}
+	return recognizable(header)
+}
+
+// recognizable reports whether data starts with a known format or embeds a
+// distinctive one; a bare gzip magic is too short to count
+func recognizable(data []byte) bool {
+	if fwformat.Identify(data) != fwformat.Unknown {
+		return true
+	}
+	for _, embedded := range fwformat.ScanData(data, 10) {
+		if embedded.Format != fwformat.Gzip {
+			return true
+		}
+	}
+	return false
+}
+
+func readHeader(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return header[:n], nil
+}
@@ -0,0 +1,94 @@
+package decrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// D-Link's SHRS images (DIR-867, DIR-878, DIR-882, DIR-1760, DIR-3060 and
+// others) are AES-128-CBC encrypted with a key shared by the product line:
+// the header holds the plaintext and ciphertext sizes and the IV, and the
+// ciphertext starts after the header's signature block.
+const (
+	shrsMagic         = "SHRS"
+	shrsPayloadOffset = 0x6dc
+)
+
+var shrsKey, _ = hex.DecodeString("c05fbf1936c99429ce2a0781f08d6ad8")
+
+type dlinkSHRS struct{}
+
+func (dlinkSHRS) Name() string { return "dlink-shrs" }
+
+func (dlinkSHRS) Match(header []byte, encrypted bool) bool {
+	return len(header) >= shrsPayloadOffset && bytes.HasPrefix(header, []byte(shrsMagic))
+}
+
+func (dlinkSHRS) Decrypt(ctx context.Context, in, out string) error {
+	f, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, 0x1c)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return fmt.Errorf("truncated SHRS header: %w", err)
+	}
+	plainSize := int64(binary.BigEndian.Uint32(header[4:8]))
+	cipherSize := int64(binary.BigEndian.Uint32(header[8:12]))
+	iv := header[12:28]
+	if cipherSize == 0 || cipherSize%aes.BlockSize != 0 || plainSize > cipherSize {
+		return fmt.Errorf("invalid SHRS sizes: %d bytes of plaintext in %d of ciphertext", plainSize, cipherSize)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if shrsPayloadOffset+cipherSize > info.Size() {
+		return fmt.Errorf("SHRS image is truncated: %d bytes of ciphertext announced, %d present", cipherSize, info.Size()-shrsPayloadOffset)
+	}
+
+	block, err := aes.NewCipher(shrsKey)
+	if err != nil {
+		return err
+	}
+	decrypter := cipher.NewCBCDecrypter(block, iv)
+
+	dst, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	// Decrypt a megabyte at a time; CBC carries its state across calls
+	src := io.NewSectionReader(f, shrsPayloadOffset, cipherSize)
+	buf := make([]byte, 1<<20)
+	remaining := plainSize
+	for remaining > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(src, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		decrypter.CryptBlocks(buf[:n], buf[:n])
+		chunk := int64(n)
+		if chunk > remaining {
+			chunk = remaining
+		}
+		if _, err := dst.Write(buf[:chunk]); err != nil {
+			return err
+		}
+		remaining -= chunk
+	}
+	return dst.Close()
+}
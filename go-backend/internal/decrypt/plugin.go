@@ -0,0 +1,65 @@
+package decrypt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// plugin is an operator supplied decryptor: an executable called with the
+// encrypted image and the path to write the decrypted one to. It exits
+// non-zero for images it doesn't handle. Plugins only run on images that
+// look encrypted, since they can't tell Odin up front whether they apply.
+type plugin struct {
+	path    string
+	timeout time.Duration
+}
+
+// loadPlugins returns the executables of dir, by name
+func loadPlugins(dir string, timeout time.Duration) ([]Decryptor, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var plugins []Decryptor
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		plugins = append(plugins, &plugin{path: filepath.Join(dir, entry.Name()), timeout: timeout})
+	}
+	return plugins, nil
+}
+
+func (p *plugin) Name() string { return "plugin:" + filepath.Base(p.path) }
+
+func (p *plugin) Match(header []byte, encrypted bool) bool {
+	return encrypted
+}
+
+func (p *plugin) Decrypt(ctx context.Context, in, out string) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, p.path, in, out)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if info, err := os.Stat(out); err != nil || info.Size() == 0 {
+		return fmt.Errorf("wrote no decrypted image")
+	}
+	return nil
+}
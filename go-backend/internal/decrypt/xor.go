@@ -0,0 +1,147 @@
+package decrypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+// Several vendors obfuscate images by XORing them with a short repeating
+// key. Wherever the plaintext is zero or 0xff padding the key shows
+// through, so one of the most frequent key-sized blocks of the image is the
+// key or its complement; the one that turns the image into a known format wins.
+const (
+	maxXORKeyLength  = 64
+	minKeyRepeats    = 32 // occurrences of a block for it to be taken as the key
+	maxKeyCandidates = 4  // blocks tried per key length
+)
+
+type xorDecryptor struct{}
+
+func (*xorDecryptor) Name() string { return "xor" }
+
+func (*xorDecryptor) Match(header []byte, encrypted bool) bool {
+	return recoverXORKey(header) != nil
+}
+
+func (*xorDecryptor) Decrypt(ctx context.Context, in, out string) error {
+	header, err := readHeader(in)
+	if err != nil {
+		return err
+	}
+	key := recoverXORKey(header)
+	if key == nil {
+		return errors.New("no repeating XOR key found")
+	}
+
+	src, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	// The buffer is a multiple of every key length, so each read starts at
+	// the key's first byte
+	buf := make([]byte, len(key)<<14)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(src, buf)
+		xorBytes(buf[:n], key)
+		if _, werr := dst.Write(buf[:n]); werr != nil {
+			return werr
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return dst.Close()
+}
+
+// recoverXORKey returns the repeating key the image starting with header
+// was XORed with, or nil
+func recoverXORKey(header []byte) []byte {
+	for length := 1; length <= maxXORKeyLength && length*minKeyRepeats <= len(header); length++ {
+		counts := make(map[string]int)
+		for offset := 0; offset+length <= len(header); offset += length {
+			counts[string(header[offset:offset+length])]++
+		}
+		for _, block := range keyCandidates(counts) {
+			complement := make([]byte, length)
+			for i := range complement {
+				complement[i] = block[i] ^ 0xff
+			}
+			for _, key := range [][]byte{[]byte(block), complement} {
+				if isZero(key) {
+					continue
+				}
+				candidate := append([]byte(nil), header...)
+				xorBytes(candidate, key)
+				if recognizable(candidate) {
+					return key
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// keyCandidates returns the most frequent blocks, most frequent first. Runs
+// of other repeated bytes can outnumber the padding, so a few are tried. A
+// key made of a shorter one was tried with that length already.
+func keyCandidates(counts map[string]int) []string {
+	var blocks []string
+	for block, count := range counts {
+		if count >= minKeyRepeats && !repeats([]byte(block)) {
+			blocks = append(blocks, block)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if counts[blocks[i]] != counts[blocks[j]] {
+			return counts[blocks[i]] > counts[blocks[j]]
+		}
+		return blocks[i] < blocks[j]
+	})
+	if len(blocks) > maxKeyCandidates {
+		blocks = blocks[:maxKeyCandidates]
+	}
+	return blocks
+}
+
+// xorBytes XORs data, which starts at the key's first byte, with the key
+func xorBytes(data, key []byte) {
+	for i := range data {
+		data[i] ^= key[i%len(key)]
+	}
+}
+
+// repeats reports whether key is a shorter key repeated
+func repeats(key []byte) bool {
+	for period := 1; period < len(key); period++ {
+		if len(key)%period == 0 && bytes.Equal(key[period:], key[:len(key)-period]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isZero(key []byte) bool {
+	for _, b := range key {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+// Package defaultcreds holds the default credentials device vendors
+// document, shipped with Odin and optionally updated from a CSV dataset
+// such as SecLists' default-passwords.csv
+package defaultcreds
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"odin-backend/internal/models"
+)
+
+// SourceShipped is the source of the shipped dataset
+const SourceShipped = "shipped"
+
+//go:embed default-credentials.csv
+var shippedCSV string
+
+var (
+	shippedOnce sync.Once
+	shipped     []models.DefaultCredential
+)
+
+// Shipped returns the dataset shipped with Odin
+func Shipped() []models.DefaultCredential {
+	shippedOnce.Do(func() {
+		var err error
+		shipped, err = Parse(strings.NewReader(shippedCSV), SourceShipped)
+		if err != nil {
+			panic(fmt.Sprintf("shipped default credentials don't parse: %v", err))
+		}
+	})
+	return shipped
+}
+
+// Parse reads a CSV dataset with a header naming its columns: Vendor,
+// Username and Password, and optionally Model and Comments. Rows without a
+// vendor or with neither a username nor a password are skipped.
+func Parse(r io.Reader, source string) ([]models.DefaultCredential, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"vendor", "username", "password"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("no %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		value := strings.TrimSpace(record[i])
+		// SecLists marks blank fields as <blank> or <BLANK>
+		if strings.EqualFold(value, "<blank>") {
+			return ""
+		}
+		return value
+	}
+
+	var credentials []models.DefaultCredential
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		credential := models.DefaultCredential{
+			Vendor:   field(record, "vendor"),
+			Model:    field(record, "model"),
+			Username: field(record, "username"),
+			Password: field(record, "password"),
+			Comment:  field(record, "comments"),
+			Source:   source,
+		}
+		if credential.Vendor == "" || (credential.Username == "" && credential.Password == "") {
+			continue
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normalize(s string) string {
+	return nonAlphanumeric.ReplaceAllString(strings.ToLower(s), "")
+}
+
+// ForDevice returns the credentials documented for a device: those of its
+// model, and the vendor-wide ones. Vendor names match loosely, so
+// "NETGEAR, Inc." finds Netgear's.
+func ForDevice(credentials []models.DefaultCredential, manufacturer, model string) []models.DefaultCredential {
+	vendor := normalize(manufacturer)
+	device := normalize(model)
+	if vendor == "" {
+		return nil
+	}
+
+	var matches []models.DefaultCredential
+	seen := make(map[string]bool)
+	for _, credential := range credentials {
+		name := normalize(credential.Vendor)
+		if name == "" || !(strings.Contains(vendor, name) || strings.Contains(name, vendor)) {
+			continue
+		}
+		if credential.Model != "" {
+			m := normalize(credential.Model)
+			if device == "" || m == "" || !(strings.Contains(device, m) || strings.Contains(m, device)) {
+				continue
+			}
+		}
+		key := credential.Username + "\x00" + credential.Password
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		matches = append(matches, credential)
+	}
+	return matches
+}
+
+// Documenting returns the vendors documenting a username and password as
+// their default
+func Documenting(credentials []models.DefaultCredential, username, password string) []string {
+	var vendors []string
+	seen := make(map[string]bool)
+	for _, credential := range credentials {
+		if credential.Username == username && credential.Password == password && !seen[credential.Vendor] {
+			seen[credential.Vendor] = true
+			vendors = append(vendors, credential.Vendor)
+		}
+	}
+	return vendors
+}
+
+// Pair formats a credential as username:password, <blank> for empty fields
+func Pair(username, password string) string {
+	if username == "" {
+		username = "<blank>"
+	}
+	if password == "" {
+		password = "<blank>"
+	}
+	return username + ":" + password
+}
@@ -0,0 +1,168 @@
+package emba
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// aggregatorInfoKeys map F50 keys to the firmware info fields they fill
+var aggregatorInfoKeys = map[string]string{
+	"os_verified":    "os",
+	"os":             "os",
+	"architecture":   "architecture",
+	"arch":           "architecture",
+	"endianness":     "endianness",
+	"kernel_version": "kernel_version",
+	"entropy":        "entropy",
+}
+
+// aggregatorVersionKeys are the F50 rows listing detected software versions
+var aggregatorVersionKeys = map[string]bool{
+	"version_details": true,
+	"software":        true,
+	"version":         true,
+}
+
+// Aggregate is EMBA's F50 base aggregator output: its key/value records
+// and the software versions it lists
+type Aggregate struct {
+	Source   string             `json:"source"`
+	Info     map[string]string  `json:"info"`
+	Counts   map[string]int     `json:"counts"` // cve_high, exploits, ...
+	Versions []ComponentVersion `json:"versions"`
+}
+
+// ComponentVersion is a software component EMBA identified with its version
+type ComponentVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// parseAggregator reads the first F50 aggregator output the layout finds.
+// It returns nil when EMBA didn't write one.
+func parseAggregator(layout Layout, logDir string) (*Aggregate, error) {
+	for _, path := range layout.AggregatorFiles(logDir) {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+
+		aggregate := &Aggregate{Source: filepath.Base(path), Info: map[string]string{}, Counts: map[string]int{}}
+		if strings.HasSuffix(path, ".json") {
+			err = aggregate.readJSON(file)
+		} else {
+			err = aggregate.readCSV(file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return aggregate, nil
+	}
+	return nil, nil
+}
+
+// readCSV reads F50's "key;value;..." records
+func (a *Aggregate) readCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var values []string
+		for _, field := range record[1:] {
+			if field = strings.TrimSpace(field); field != "" && field != "NA" {
+				values = append(values, field)
+			}
+		}
+		a.add(record[0], values)
+	}
+}
+
+// readJSON reads an object of keys to a value or a list of values
+func (a *Aggregate) readJSON(r io.Reader) error {
+	var object map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&object); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch value := object[key].(type) {
+		case []interface{}:
+			for _, item := range value {
+				switch item := item.(type) {
+				case map[string]interface{}:
+					a.add(key, []string{jsonString(item["name"]), jsonString(item["version"])})
+				default:
+					a.add(key, []string{jsonString(item)})
+				}
+			}
+		default:
+			a.add(key, []string{jsonString(value)})
+		}
+	}
+	return nil
+}
+
+func (a *Aggregate) add(key string, values []string) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" || len(values) == 0 || values[0] == "" {
+		return
+	}
+
+	if field, ok := aggregatorInfoKeys[key]; ok {
+		a.Info[field] = values[0]
+		return
+	}
+	if aggregatorVersionKeys[key] {
+		if len(values) >= 2 && values[1] != "" {
+			a.Versions = append(a.Versions, ComponentVersion{Name: values[0], Version: values[1]})
+		}
+		return
+	}
+	if count, err := strconv.Atoi(values[0]); err == nil {
+		a.Counts[key] = count
+	}
+}
+
+func jsonString(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// apply adds the aggregate to the parsed results
+func (a *Aggregate) apply(results *ParsedResults) {
+	for field, value := range a.Info {
+		results.FileInfo[field] = value
+	}
+	if len(a.Versions) > 0 {
+		results.FileInfo["versions"] = a.Versions
+	}
+}
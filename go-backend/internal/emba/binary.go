@@ -0,0 +1,168 @@
+package emba
+
+import (
+	"log"
+	"math"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// binaryColumns locates the fields of S12's binary protection CSV, which
+// follows checksec's columns
+type binaryColumns struct {
+	file, relro, canary, nx, pie, rpath, runpath, symbols, fortify int
+}
+
+// newBinaryColumns maps a header row. ok is false when it doesn't name the
+// RELRO and NX columns.
+func newBinaryColumns(header []string) (columns binaryColumns, ok bool) {
+	columns = binaryColumns{file: -1, relro: -1, canary: -1, nx: -1, pie: -1, rpath: -1, runpath: -1, symbols: -1, fortify: -1}
+	set := func(field *int, i int) {
+		if *field == -1 {
+			*field = i
+		}
+	}
+
+	for i, name := range header {
+		name = strings.Trim(columnRegex.ReplaceAllString(strings.ToLower(name), "_"), "_")
+		switch {
+		case name == "file" || name == "binary" || name == "path" || strings.HasSuffix(name, "_path"):
+			set(&columns.file, i)
+		case strings.Contains(name, "relro"):
+			set(&columns.relro, i)
+		case strings.Contains(name, "canary"):
+			set(&columns.canary, i)
+		case name == "nx":
+			set(&columns.nx, i)
+		case name == "pie":
+			set(&columns.pie, i)
+		case name == "rpath":
+			set(&columns.rpath, i)
+		case name == "runpath":
+			set(&columns.runpath, i)
+		case strings.Contains(name, "symbols"):
+			set(&columns.symbols, i)
+		case name == "fortify" || name == "fortify_source":
+			set(&columns.fortify, i) // not the fortified/fortifiable counts
+		}
+	}
+
+	return columns, columns.file != -1 && columns.relro != -1 && columns.nx != -1
+}
+
+// parseBinaryProtectionCSV parses S12's s12_binary_protection.csv into one
+// record per binary
+func (s *Service) parseBinaryProtectionCSV(csvFile string) ([]models.BinaryAnalysis, error) {
+	records, err := readCSV(csvFile)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+
+	columns, ok := newBinaryColumns(records[0])
+	if !ok {
+		log.Printf("Skipping binary protection CSV %s: unrecognized header", csvFile)
+		return nil, nil
+	}
+
+	var binaries []models.BinaryAnalysis
+	for _, record := range records[1:] {
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		path := field(columns.file)
+		if path == "" || path == "NA" {
+			continue
+		}
+		relro := field(columns.relro)
+		binaries = append(binaries, models.BinaryAnalysis{
+			FilePath:    path,
+			RELRO:       protectionEnabled(relro),
+			FullRELRO:   strings.Contains(strings.ToLower(relro), "full"),
+			StackCanary: protectionEnabled(field(columns.canary)),
+			NX:          protectionEnabled(field(columns.nx)),
+			PIE:         protectionEnabled(field(columns.pie)),
+			Fortify:     protectionEnabled(field(columns.fortify)),
+			Stripped:    columns.symbols != -1 && !protectionEnabled(field(columns.symbols)),
+			RPath:       protectionEnabled(field(columns.rpath)) || protectionEnabled(field(columns.runpath)),
+		})
+	}
+	return binaries, nil
+}
+
+// protectionEnabled reads a checksec value such as "Full RELRO", "Canary
+// found", "NX disabled", "No PIE", "DSO" or "Yes"
+func protectionEnabled(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case value == "" || value == "na" || value == "n/a" || value == "-":
+		return false
+	case value == "no" || strings.HasPrefix(value, "no ") || value == "false":
+		return false
+	case strings.Contains(value, "disabled") || strings.Contains(value, "not found"):
+		return false
+	}
+	return true
+}
+
+// ProtectionCount is how many binaries lack a protection
+type ProtectionCount struct {
+	Missing int     `json:"missing"`
+	Percent float64 `json:"percent"`
+}
+
+// BinarySummary aggregates the protections of a firmware's binaries, e.g.
+// the share of binaries without NX
+type BinarySummary struct {
+	Total    int                        `json:"total"`
+	Missing  map[string]ProtectionCount `json:"missing"` // by protection: relro, full_relro, stack_canary, nx, pie, fortify
+	Stripped int                        `json:"stripped"`
+	RPath    int                        `json:"rpath"`
+}
+
+// binaryProtections are the protections BinarySummary counts
+var binaryProtections = []struct {
+	name    string
+	enabled func(*models.BinaryAnalysis) bool
+}{
+	{"relro", func(b *models.BinaryAnalysis) bool { return b.RELRO }},
+	{"full_relro", func(b *models.BinaryAnalysis) bool { return b.FullRELRO }},
+	{"stack_canary", func(b *models.BinaryAnalysis) bool { return b.StackCanary }},
+	{"nx", func(b *models.BinaryAnalysis) bool { return b.NX }},
+	{"pie", func(b *models.BinaryAnalysis) bool { return b.PIE }},
+	{"fortify", func(b *models.BinaryAnalysis) bool { return b.Fortify }},
+}
+
+// SummarizeBinaries counts the binaries lacking each protection
+func SummarizeBinaries(binaries []models.BinaryAnalysis) BinarySummary {
+	summary := BinarySummary{Total: len(binaries), Missing: make(map[string]ProtectionCount)}
+	for i := range binaries {
+		if binaries[i].Stripped {
+			summary.Stripped++
+		}
+		if binaries[i].RPath {
+			summary.RPath++
+		}
+	}
+	if len(binaries) == 0 {
+		return summary
+	}
+
+	for _, protection := range binaryProtections {
+		missing := 0
+		for i := range binaries {
+			if !protection.enabled(&binaries[i]) {
+				missing++
+			}
+		}
+		summary.Missing[protection.name] = ProtectionCount{
+			Missing: missing,
+			Percent: math.Round(float64(missing)*1000/float64(len(binaries))) / 10,
+		}
+	}
+	return summary
+}
@@ -0,0 +1,183 @@
+package emba
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// BootloaderInfo describes the bootloader and boot chain found in the firmware
+type BootloaderInfo struct {
+	Type                 string            `json:"type"`
+	Version              string            `json:"version"`
+	Environment          map[string]string `json:"environment"`
+	BootArgs             string            `json:"boot_args"`
+	SecureBoot           bool              `json:"secure_boot"`
+	SecureBootIndicators []string          `json:"secure_boot_indicators"`
+	SourceFiles          []string          `json:"source_files"`
+}
+
+var (
+	ubootVersionRegex = regexp.MustCompile(`U-Boot\s+(\d{4}\.\d{2}[\w.\-+]*)`)
+	ubootEnvRegex     = regexp.MustCompile(`\b(bootdelay|bootcmd|bootargs|baudrate|preboot|silent|verify|stdin|stdout|stderr|loadaddr|bootfile|serverip|ipaddr|ethaddr|autoload|bootm_size)=(.*)$`)
+	kernelCmdRegex    = regexp.MustCompile(`(?i)kernel command line:\s*(.+)$`)
+	serialConsoleRe   = regexp.MustCompile(`console=(tty(S|AMA|MSM|O|HS|MV|PS|SAC|LP|MT|UL)\w*)`)
+)
+
+// secureBootKeywords indicate signed images or verified boot in the boot chain
+var secureBootKeywords = []string{
+	"fit signature",
+	"verified boot",
+	"config_fit_signature",
+	"secure boot",
+	"secureboot",
+	"hab enabled",
+	"signature check ok",
+	"rsa-2048",
+	"rsa-4096",
+}
+
+// parseBootloader extracts structured U-Boot details from pre-module and
+// static module logs into FirmwareInfo and raises findings for risky settings
+func (s *Service) parseBootloader(logDir string, results *ParsedResults) error {
+	var files []string
+	for _, pattern := range []string{"P*", "S*"} {
+		matches, err := results.layout.ModuleLogs(logDir, pattern)
+		if err != nil {
+			return err
+		}
+		files = append(files, matches...)
+	}
+
+	info := &BootloaderInfo{Environment: make(map[string]string)}
+	indicators := make(map[string]bool)
+	sources := make(map[string]bool)
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Error reading bootloader source %s: %v", file, err)
+			continue
+		}
+
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			if matches := ubootVersionRegex.FindStringSubmatch(line); matches != nil && info.Version == "" {
+				info.Type = "u-boot"
+				info.Version = matches[1]
+				sources[file] = true
+			}
+			if matches := ubootEnvRegex.FindStringSubmatch(line); matches != nil {
+				info.Environment[matches[1]] = strings.TrimSpace(matches[2])
+				sources[file] = true
+			}
+			if matches := kernelCmdRegex.FindStringSubmatch(line); matches != nil && info.BootArgs == "" {
+				info.BootArgs = strings.TrimSpace(matches[1])
+				sources[file] = true
+			}
+
+			lower := strings.ToLower(line)
+			for _, keyword := range secureBootKeywords {
+				if strings.Contains(lower, keyword) {
+					indicators[keyword] = true
+					sources[file] = true
+				}
+			}
+		}
+	}
+
+	if bootargs, ok := info.Environment["bootargs"]; ok && info.BootArgs == "" {
+		info.BootArgs = bootargs
+	}
+	if info.Type == "" && len(info.Environment) > 0 {
+		info.Type = "u-boot"
+	}
+	if info.Type == "" && info.BootArgs == "" {
+		return nil
+	}
+
+	for indicator := range indicators {
+		info.SecureBootIndicators = append(info.SecureBootIndicators, indicator)
+	}
+	sort.Strings(info.SecureBootIndicators)
+	info.SecureBoot = len(info.SecureBootIndicators) > 0
+	for source := range sources {
+		info.SourceFiles = append(info.SourceFiles, source)
+	}
+	sort.Strings(info.SourceFiles)
+
+	results.FileInfo["bootloader"] = info
+	results.Findings = append(results.Findings, s.bootloaderFindings(info)...)
+
+	return nil
+}
+
+// bootloaderFindings flags dangerous bootloader settings
+func (s *Service) bootloaderFindings(info *BootloaderInfo) []models.Finding {
+	var findings []models.Finding
+	source := strings.Join(info.SourceFiles, ", ")
+
+	newFinding := func(title, description, severity, setting string) models.Finding {
+		return models.Finding{
+			Type:        models.FindingType("bootloader_config"),
+			Title:       title,
+			Description: description,
+			Severity:    models.RiskLevel(severity),
+			FilePath:    source,
+			Content:     setting,
+			FindingMetadata: encodeMetadata(map[string]interface{}{
+				"source":             "bootloader_analysis",
+				"bootloader":         info.Type,
+				"bootloader_version": info.Version,
+			}),
+		}
+	}
+
+	if value, ok := info.Environment["bootdelay"]; ok {
+		if delay, err := strconv.Atoi(value); err == nil && delay >= 0 {
+			findings = append(findings, newFinding(
+				"Bootloader autoboot can be interrupted",
+				fmt.Sprintf("bootdelay=%d allows interrupting autoboot over the console to reach the U-Boot shell, which can be used to dump flash or boot a modified kernel", delay),
+				"medium",
+				"bootdelay="+value,
+			))
+		}
+	}
+
+	if value, ok := info.Environment["verify"]; ok && (value == "n" || value == "no" || value == "0") {
+		findings = append(findings, newFinding(
+			"Kernel image checksum verification disabled",
+			"verify is disabled in the U-Boot environment, so kernel images are booted without integrity checks",
+			"high",
+			"verify="+value,
+		))
+	} else if info.Type != "" && !info.SecureBoot {
+		findings = append(findings, newFinding(
+			"Kernel loaded without signature verification",
+			"No secure-boot or FIT signature indicators were found in the boot chain, so the bootloader likely loads an unsigned kernel",
+			"high",
+			info.Environment["bootcmd"],
+		))
+	}
+
+	if matches := serialConsoleRe.FindStringSubmatch(info.BootArgs); matches != nil {
+		findings = append(findings, newFinding(
+			"UART console enabled",
+			fmt.Sprintf("Kernel boot arguments enable a serial console on %s, exposing a login or boot console on the UART header", matches[1]),
+			"medium",
+			info.BootArgs,
+		))
+	}
+
+	return findings
+}
@@ -0,0 +1,66 @@
+package emba
+
+import (
+	"encoding/json"
+
+	"odin-backend/internal/models"
+)
+
+// sourceConfidence rates the sources findings are parsed from. Structured
+// EMBA results are trusted; "line contains FOUND" matches are not.
+var sourceConfidence = map[string]models.Confidence{
+	"cwe_checker":         models.ConfidenceHigh,
+	"sbom":                models.ConfidenceHigh,
+	"emulation":           models.ConfidenceHigh,
+	"system_emulation":    models.ConfidenceHigh,
+	"network_scan":        models.ConfidenceHigh,
+	"snmp_check":          models.ConfidenceHigh,
+	"upnp_check":          models.ConfidenceHigh,
+	"vnc_check":           models.ConfidenceHigh,
+	"web_check":           models.ConfidenceHigh,
+	"kernel_analysis":     models.ConfidenceHigh,
+	"permission_check":    models.ConfidenceHigh,
+	"password_search":     models.ConfidenceHigh,
+	"password_cracking":   models.ConfidenceHigh,
+	"firmware_diff":       models.ConfidenceHigh,
+	"bootloader_analysis": models.ConfidenceMedium,
+	"hardware_inventory":  models.ConfidenceMedium,
+	"vulnerability_file":  models.ConfidenceLow,
+	"pre_analysis":        models.ConfidenceLow,
+	"static_analysis":     models.ConfidenceLow,
+	"finishing_analysis":  models.ConfidenceLow,
+}
+
+// annotateConfidence sets the confidence of every finding from its source.
+// It runs after annotateSeverity, whose severity source it takes into account.
+func annotateConfidence(results *ParsedResults) {
+	for i := range results.Findings {
+		finding := &results.Findings[i]
+
+		metadata := make(map[string]interface{})
+		if finding.FindingMetadata != "" {
+			json.Unmarshal([]byte(finding.FindingMetadata), &metadata)
+		}
+		finding.Confidence = findingConfidence(metadata)
+	}
+}
+
+func findingConfidence(metadata map[string]interface{}) models.Confidence {
+	switch metadata["severity_source"] {
+	case SeveritySourceEMBA:
+		return models.ConfidenceHigh // a row of an EMBA results CSV
+	case SeveritySourceCVSS:
+		return models.ConfidenceMedium // a scored log line
+	}
+
+	if source, ok := metadata["source"].(string); ok {
+		if confidence, ok := sourceConfidence[source]; ok {
+			return confidence
+		}
+	}
+	if metadata["category"] == "emba_json" {
+		return models.ConfidenceMedium
+	}
+	// Keyword matches in the grep log and module logs
+	return models.ConfidenceLow
+}
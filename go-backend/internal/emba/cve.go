@@ -0,0 +1,258 @@
+package emba
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+var (
+	cveIDRegex  = regexp.MustCompile(`(?i)^CVE-\d{4}-\d+$`)
+	columnRegex = regexp.MustCompile(`[^a-z0-9]+`)
+	edbIDRegex  = regexp.MustCompile(`^\d{3,6}$`)
+)
+
+// cveColumns locates the fields of a CVE CSV from its header, so columns
+// EMBA adds or reorders between releases don't shift the values read
+type cveColumns struct {
+	cve, software, version, binary, score, vector, source, description, kev int
+	exploits                                                                map[int]string // column to exploit source
+}
+
+// exploitColumns name the exploit sources of F20's exploit columns
+var exploitColumns = []struct{ keyword, source string }{
+	{"exploit_db", "exploit-db"},
+	{"edb", "exploit-db"},
+	{"metasploit", "metasploit"},
+	{"routersploit", "routersploit"},
+	{"trickest", "trickest"},
+	{"snyk", "snyk"},
+	{"packetstorm", "packetstorm"},
+	{"github", "github"},
+}
+
+// newCVEColumns maps a header row. ok is false when it doesn't name a CVE
+// column, i.e. the file has no header.
+func newCVEColumns(header []string) (columns cveColumns, ok bool) {
+	columns = cveColumns{cve: -1, software: -1, version: -1, binary: -1, score: -1, vector: -1, source: -1, description: -1, kev: -1, exploits: map[int]string{}}
+	set := func(field *int, i int) {
+		if *field == -1 {
+			*field = i
+		}
+	}
+
+	for i, name := range header {
+		name = strings.Trim(columnRegex.ReplaceAllString(strings.ToLower(name), "_"), "_")
+		switch {
+		case strings.Contains(name, "known_exploited") || name == "kev":
+			set(&columns.kev, i)
+		case strings.Contains(name, "local_exploit") || strings.Contains(name, "remote_exploit") || strings.Contains(name, "dos_exploit"):
+			// exploit type flags, not sources
+		case strings.Contains(name, "vector"):
+			set(&columns.vector, i)
+		case strings.Contains(name, "cvss") || strings.Contains(name, "score"):
+			set(&columns.score, i)
+		case name == "cve" || strings.HasPrefix(name, "cve_id") || strings.HasPrefix(name, "cve_identifier"):
+			set(&columns.cve, i)
+		case name == "binary" || strings.Contains(name, "binary_path") || name == "path":
+			set(&columns.binary, i)
+		case name == "product" || name == "software" || name == "component" || strings.HasSuffix(name, "_name"):
+			set(&columns.software, i)
+		case strings.Contains(name, "version"):
+			set(&columns.version, i)
+		case name == "source" || name == "database":
+			set(&columns.source, i)
+		case strings.Contains(name, "description") || name == "summary":
+			set(&columns.description, i)
+		default:
+			for _, exploit := range exploitColumns {
+				if strings.Contains(name, exploit.keyword) {
+					columns.exploits[i] = exploit.source
+					break
+				}
+			}
+		}
+	}
+
+	return columns, columns.cve != -1
+}
+
+// parseCVEFile parses CVE findings from EMBA CSV output such as F20's
+// f20_vul_aggregator.csv. Headerless files fall back to the positional
+// "cve,software,version,score,description" format.
+func (s *Service) parseCVEFile(csvFile string) ([]models.CVEFinding, error) {
+	records, err := readCSV(csvFile)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+
+	columns, ok := newCVEColumns(records[0])
+	if ok {
+		records = records[1:]
+	} else {
+		columns = cveColumns{cve: 0, software: 1, version: 2, binary: -1, score: 3, vector: -1, source: -1, description: 4, kev: -1}
+		if len(records[0]) > 0 && !cveIDRegex.MatchString(strings.TrimSpace(records[0][0])) {
+			records = records[1:] // unrecognized header
+		}
+	}
+
+	var cves []models.CVEFinding
+	for _, record := range records {
+		if cve, ok := s.cveFromRecord(record, columns); ok {
+			cves = append(cves, cve)
+		}
+	}
+	return cves, nil
+}
+
+// readCSV reads an EMBA CSV log, which separates fields with ';' or ','
+func readCSV(csvFile string) ([][]string, error) {
+	content, err := os.ReadFile(csvFile)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	if firstLine, _, _ := bytes.Cut(content, []byte("\n")); bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+// cveFromRecord builds a CVE finding from one CSV row
+func (s *Service) cveFromRecord(record []string, columns cveColumns) (models.CVEFinding, bool) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		value := strings.TrimSpace(record[i])
+		if value == "NA" || value == "-" {
+			return ""
+		}
+		return value
+	}
+
+	cveID := strings.ToUpper(field(columns.cve))
+	if !cveIDRegex.MatchString(cveID) {
+		return models.CVEFinding{}, false
+	}
+
+	cve := models.CVEFinding{
+		CVEID:           cveID,
+		SoftwareName:    field(columns.software),
+		SoftwareVersion: field(columns.version),
+		BinaryPath:      field(columns.binary),
+		CVSSVector:      field(columns.vector),
+		Source:          field(columns.source),
+		Description:     field(columns.description),
+	}
+	if cve.SoftwareName == "" {
+		cve.SoftwareName = cve.BinaryPath
+	}
+	if cve.Source == "" {
+		cve.Source = "NVD"
+	}
+
+	// The score column can hold the vector, or "7.5 (CVSS:3.1/...)"
+	rating := field(columns.score)
+	if score, err := strconv.ParseFloat(strings.Fields(rating + " x")[0], 64); err == nil {
+		cve.SeverityScore = score
+	}
+	if cve.CVSSVector == "" {
+		cve.CVSSVector = cvssVectorRegex.FindString(rating)
+	}
+	cve.SeverityLevel = models.RiskLevel(s.scoreToSeverity(cve.SeverityScore))
+
+	var sources []string
+	var refs exploitRefs
+	for i, source := range columns.exploits {
+		if flagSet(field(i)) && !containsSource(sources, source) {
+			sources = append(sources, source)
+		}
+		refs.add(source, field(i))
+	}
+	if len(sources) > 0 {
+		sort.Strings(sources)
+		data, _ := json.Marshal(sources)
+		cve.ExploitSources = string(data)
+		cve.ExploitAvailable = true
+	}
+	refs.apply(&cve)
+	cve.KnownExploited = flagSet(field(columns.kev))
+
+	return cve, true
+}
+
+// exploitRefs collects the exploits F20's exploit columns name. Besides
+// "yes" EMBA writes Exploit-DB IDs, Metasploit module paths and PoC URLs.
+type exploitRefs struct {
+	edb, metasploit, pocs []string
+}
+
+func (r *exploitRefs) add(source, value string) {
+	for _, token := range strings.FieldsFunc(value, func(c rune) bool { return c == ' ' || c == ',' || c == '|' }) {
+		switch {
+		case source == "exploit-db" && edbIDRegex.MatchString(token):
+			r.edb = appendUnique(r.edb, token)
+		case source == "metasploit" && strings.Contains(token, "/"):
+			module := strings.TrimSuffix(strings.TrimPrefix(token, "modules/"), ".rb")
+			r.metasploit = appendUnique(r.metasploit, module)
+		case strings.HasPrefix(token, "https://") || strings.HasPrefix(token, "http://"):
+			r.pocs = appendUnique(r.pocs, token)
+		}
+	}
+}
+
+// apply stores the references as JSON arrays on the CVE finding
+func (r *exploitRefs) apply(cve *models.CVEFinding) {
+	for _, list := range []struct {
+		values []string
+		field  *string
+	}{
+		{r.edb, &cve.ExploitDBIDs},
+		{r.metasploit, &cve.MetasploitModules},
+		{r.pocs, &cve.PoCURLs},
+	} {
+		if len(list.values) > 0 {
+			sort.Strings(list.values)
+			data, _ := json.Marshal(list.values)
+			*list.field = string(data)
+		}
+	}
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsSource(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// flagSet reports whether an F20 flag column is set; EMBA writes "yes",
+// exploit IDs or counts, and "no", "0" or nothing when unset
+func flagSet(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "no", "0", "false", "none", "n":
+		return false
+	}
+	return true
+}
+
+func containsSource(sources []string, source string) bool {
+	for _, s := range sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
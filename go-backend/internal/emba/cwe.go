@@ -0,0 +1,20 @@
+package emba
+
+import "odin-backend/internal/cwe"
+
+// annotateCWE links the findings that cite a weakness in their title,
+// description or content, such as those of S120's cwe_checker, to its CWE
+func annotateCWE(results *ParsedResults) {
+	for i := range results.Findings {
+		finding := &results.Findings[i]
+		if finding.CWE != "" {
+			continue
+		}
+		for _, text := range []string{finding.Title, finding.Description, finding.Content} {
+			if id := cwe.Extract(text); id != "" {
+				finding.CWE = id
+				break
+			}
+		}
+	}
+}
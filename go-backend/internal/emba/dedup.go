@@ -0,0 +1,29 @@
+package emba
+
+import "odin-backend/internal/models"
+
+// dedupFindings collapses findings the grep log, module logs and web report
+// raised for the same issue, i.e. with the same fingerprint. The most severe
+// one survives, counting the occurrences.
+func dedupFindings(findings []models.Finding) []models.Finding {
+	deduped := []models.Finding{}
+	index := make(map[string]int)
+	for _, finding := range findings {
+		finding.Fingerprint = finding.ComputeFingerprint()
+		finding.OccurrenceCount = 1
+
+		i, seen := index[finding.Fingerprint]
+		if !seen {
+			index[finding.Fingerprint] = len(deduped)
+			deduped = append(deduped, finding)
+			continue
+		}
+
+		occurrences := deduped[i].OccurrenceCount + 1
+		if finding.Severity.Rank() > deduped[i].Severity.Rank() {
+			deduped[i] = finding
+		}
+		deduped[i].OccurrenceCount = occurrences
+	}
+	return deduped
+}
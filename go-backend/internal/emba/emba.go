@@ -1,11 +1,12 @@
 package emba
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -13,18 +14,32 @@ import (
 	"time"
 
 	"odin-backend/internal/config"
+	"odin-backend/internal/cwe"
 	"odin-backend/internal/models"
 )
 
 type Service struct {
 	config *config.Config
+	health healthCache
 }
 
 type AnalysisResult struct {
 	Success      bool                   `json:"success"`
 	Error        string                 `json:"error,omitempty"`
 	LogDir       string                 `json:"log_dir"`
-	Stdout       string                 `json:"stdout,omitempty"`
+	Stdout       string                 `json:"stdout,omitempty"` // tail of the console output
+	ConsoleLog   string                 `json:"console_log,omitempty"`
+
+	// Modules the privilege mode kept EMBA from running
+	PrivilegeMode  string   `json:"privilege_mode"`
+	SkippedModules []string `json:"skipped_modules,omitempty"`
+
+	// Modules excluded by EMBA_EXCLUDED_MODULES or for this analysis
+	ExcludedModules []string `json:"excluded_modules,omitempty"`
+
+	// EMBA release that ran and the output layout its logs were parsed with
+	EMBAVersion  string `json:"emba_version"`
+	ParserLayout string `json:"parser_layout"`
 	AnalysisTime string                 `json:"analysis_time"`
 	Results      ParsedResults          `json:"results"`
 }
@@ -33,9 +48,19 @@ type ParsedResults struct {
 	Findings       []models.Finding       `json:"findings"`
 	CVEs           []models.CVEFinding    `json:"cves"`
 	OSINTResults   []models.OSINTResult   `json:"osint_results"`
+	Components     []models.SBOMComponent `json:"components"`
+	Binaries       []models.BinaryAnalysis `json:"binaries"`
+	PasswordHashes []models.PasswordHash   `json:"password_hashes"`
+	EmulationResults []models.EmulationResult `json:"emulation_results"`
+	KeyMaterials   []models.KeyMaterial   `json:"key_materials,omitempty"` // from Odin's key analysis, not EMBA
+	BinaryHashes   []models.BinaryHash    `json:"binary_hashes,omitempty"` // from Odin's fuzzy hashing, not EMBA
+	Files          []models.FirmwareFile  `json:"files,omitempty"`         // manifest of the extracted files
 	FileInfo       map[string]interface{} `json:"file_info"`
 	ExtractionInfo map[string]interface{} `json:"extraction_info"`
 	Summary        map[string]interface{} `json:"summary"`
+
+	// layout locates the logs of the EMBA release being parsed
+	layout Layout
 }
 
 // NewService creates a new EMBA service instance
@@ -59,8 +84,17 @@ func (s *Service) IsAvailable() bool {
 	return true
 }
 
-// AnalyzeFirmware runs EMBA analysis on firmware file using official EMBA parameters
-func (s *Service) AnalyzeFirmware(firmwarePath, jobID string) (*AnalysisResult, error) {
+// AnalyzeFirmware runs EMBA analysis on firmware file using official EMBA parameters.
+// Cancelling ctx, or exceeding EMBA_TIMEOUT, terminates EMBA and all of its
+// children and returns an error wrapping the context's error.
+func (s *Service) AnalyzeFirmware(ctx context.Context, firmwarePath, jobID string, opts AnalysisOptions) (*AnalysisResult, error) {
+	// Pick the parser layout for this EMBA release before it runs
+	version := embaVersion(s.config.EMBAPath)
+	layout, known := LayoutFor(version)
+	if !known {
+		log.Printf("EMBA version %q has no matching output layout, parsing job %s as %s", version, jobID, layout.Name())
+	}
+
 	if !s.IsAvailable() {
 		return nil, fmt.Errorf("EMBA is not available or not executable")
 	}
@@ -73,7 +107,14 @@ func (s *Service) AnalyzeFirmware(firmwarePath, jobID string) (*AnalysisResult,
 
 	// Build EMBA command according to official documentation with advanced features
 	embaScript := filepath.Join(s.config.EMBAPath, "emba")
-	scanProfile := filepath.Join(s.config.EMBAPath, "scan-profiles", s.config.EMBAScanProfile)
+	excluded, err := s.excludedModules(opts)
+	if err != nil {
+		return nil, err
+	}
+	scanProfile, err := s.restrictedProfile(filepath.Join(s.config.EMBAPath, "scan-profiles", s.config.EMBAScanProfile), jobID, excluded)
+	if err != nil {
+		return nil, err
+	}
 	
 	// Build command arguments dynamically based on configuration
 	args := []string{
@@ -86,8 +127,13 @@ func (s *Service) AnalyzeFirmware(firmwarePath, jobID string) (*AnalysisResult,
 		"-t", fmt.Sprintf("%d", s.config.EMBAThreads), // Thread count
 	}
 	
-	// Add optional advanced features
-	if s.config.EMBAEnableEmulation {
+	// Add optional advanced features. Emulation and live testing need root,
+	// and a platform EMBA can emulate.
+	emulatable, reason := opts.Platform.Emulatable()
+	if !emulatable && (s.config.EMBAEnableEmulation || s.config.EMBAEnableLiveTesting) {
+		log.Printf("Skipping emulation for job %s: %s", jobID, reason)
+	}
+	if s.config.EMBAEnableEmulation && !s.unprivileged() && emulatable {
 		args = append(args, "-E")        // Enable user-mode emulation (S115)
 	}
 	
@@ -95,34 +141,90 @@ func (s *Service) AnalyzeFirmware(firmwarePath, jobID string) (*AnalysisResult,
 		args = append(args, "-c")        // Enable CWE-checker (S120)
 	}
 	
-	if s.config.EMBAEnableLiveTesting {
+	if s.config.EMBAEnableLiveTesting && !s.unprivileged() && emulatable {
 		args = append(args, "-L")        // Enable live testing modules
 	}
+
+	// Run only the modules selected for this analysis
+	args = append(args, moduleArgs(opts.Modules)...)
+
+	if opts.DiffFirmware != "" {
+		args = append(args, "-o", opts.DiffFirmware) // Diff mode against the second image
+	}
 	
-	cmd := exec.Command("sudo", args...)
-	cmd.Dir = s.config.EMBAPath
+	if s.config.EMBATimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.EMBATimeout)
+		defer cancel()
+	}
+
+	cmd, err := s.command(ctx, args, jobID)
+	if err != nil {
+		return nil, err
+	}
 
 	log.Printf("Starting EMBA analysis for job %s", jobID)
-	log.Printf("Command: sudo %s", strings.Join(args, " "))
+	log.Printf("Command: %s", strings.Join(cmd.Args, " "))
+
+	// Stream the console output to disk rather than buffering a multi-hour
+	// run in memory; only its tail is kept for the results
+	consoleLog := ConsoleLogPath(s.config.EMBALogDir, jobID)
+	logWriter, err := newRotatingWriter(consoleLog, s.config.EMBAOutputMaxSizeMB*1024*1024, s.config.EMBAOutputMaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	defer logWriter.Close()
+
+	tail := newTailBuffer(s.config.EMBAStoredOutputKB * 1024)
+	output := io.MultiWriter(logWriter, tail)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	// Hand out the results of modules as they finish so a run that dies
+	// late doesn't lose everything
+	stopWatching := func() {}
+	if opts.OnModulesFinished != nil && s.config.EMBAPartialInterval > 0 {
+		stop, done := make(chan struct{}), make(chan struct{})
+		go s.watchModules(logDir, layout, opts.OnModulesFinished, stop, done)
+		stopWatching = func() {
+			close(stop)
+			<-done
+		}
+	}
 
 	// Run EMBA analysis
-	stdout, err := cmd.CombinedOutput()
-	stdoutStr := string(stdout)
+	err = cmd.Run()
+	stopWatching()
+	stdoutStr := tail.String()
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		log.Printf("EMBA analysis for job %s stopped: %v", jobID, ctxErr)
+		if ctxErr == context.DeadlineExceeded {
+			return nil, fmt.Errorf("EMBA analysis timed out: %w", ctxErr)
+		}
+		return nil, fmt.Errorf("EMBA analysis cancelled: %w", ctxErr)
+	}
 
 	if err != nil {
-		log.Printf("EMBA analysis failed for job %s: %v", jobID, err)
-		log.Printf("EMBA output: %s", stdoutStr)
+		log.Printf("EMBA analysis failed for job %s: %v (console output in %s)", jobID, err, consoleLog)
 		return &AnalysisResult{
 			Success:      false,
 			Error:        fmt.Sprintf("EMBA analysis failed: %v", err),
 			LogDir:       logDir,
 			Stdout:       stdoutStr,
+			ConsoleLog:   consoleLog,
 			AnalysisTime: time.Now().UTC().Format(time.RFC3339),
+
+			PrivilegeMode:   s.privilegeMode(),
+			SkippedModules:  s.skippedModules(),
+			ExcludedModules: excluded,
+			EMBAVersion:     version,
+			ParserLayout:    layout.Name(),
 		}, nil
 	}
 
 	// Parse EMBA results
-	results, err := s.parseEMBAResults(logDir, jobID)
+	results, err := s.parseEMBAResults(logDir, jobID, layout)
 	if err != nil {
 		log.Printf("Failed to parse EMBA results for job %s: %v", jobID, err)
 		// Don't fail completely, return partial results
@@ -135,22 +237,41 @@ func (s *Service) AnalyzeFirmware(firmwarePath, jobID string) (*AnalysisResult,
 
 	log.Printf("EMBA analysis completed for job %s", jobID)
 
+	if skipped := s.skippedModules(); len(skipped) > 0 {
+		results.Summary["skipped_modules"] = skipped
+	}
+	if len(excluded) > 0 {
+		results.Summary["excluded_modules"] = excluded
+	}
+
 	return &AnalysisResult{
 		Success:      true,
 		LogDir:       logDir,
 		Stdout:       stdoutStr,
+		ConsoleLog:   consoleLog,
 		AnalysisTime: time.Now().UTC().Format(time.RFC3339),
 		Results:      *results,
+
+		PrivilegeMode:   s.privilegeMode(),
+		SkippedModules:  s.skippedModules(),
+		ExcludedModules: excluded,
+		EMBAVersion:     version,
+		ParserLayout:    layout.Name(),
 	}, nil
 }
 
 // parseEMBAResults parses EMBA output files to extract structured results
 // Based on EMBA official documentation and output structure
-func (s *Service) parseEMBAResults(logDir, jobID string) (*ParsedResults, error) {
+func (s *Service) parseEMBAResults(logDir, jobID string, layout Layout) (*ParsedResults, error) {
 	results := &ParsedResults{
+		layout:         layout,
 		Findings:       []models.Finding{},
 		CVEs:          []models.CVEFinding{},
 		OSINTResults:  []models.OSINTResult{},
+		Components:    []models.SBOMComponent{},
+		Binaries:      []models.BinaryAnalysis{},
+		PasswordHashes: []models.PasswordHash{},
+		EmulationResults: []models.EmulationResult{},
 		FileInfo:      make(map[string]interface{}),
 		ExtractionInfo: make(map[string]interface{}),
 		Summary:       make(map[string]interface{}),
@@ -158,16 +279,26 @@ func (s *Service) parseEMBAResults(logDir, jobID string) (*ParsedResults, error)
 
 	// Look for EMBA specific output files
 	// EMBA creates structured output in specific directories
+
+	// The F50 aggregator is the authoritative source for versions and CVE
+	// and exploit counts; keyword heuristics over the module text logs are
+	// only a fallback for runs without it
+	aggregate, err := parseAggregator(layout, logDir)
+	if err != nil {
+		log.Printf("Failed to parse F50 aggregator output for job %s: %v", jobID, err)
+	}
+	if aggregate != nil {
+		aggregate.apply(results)
+	}
 	
 	// Parse grep-able log file (created with -g flag)
-	grepLogFile := filepath.Join(logDir, "fw_grep.log")
-	if _, err := os.Stat(grepLogFile); err == nil {
+	grepLogFile := layout.GrepLog(logDir)
+	if _, err := os.Stat(grepLogFile); err == nil && aggregate == nil {
 		s.parseGrepLog(grepLogFile, results)
 	}
 
 	// Parse CSV reports (EMBA generates CSV files for structured data)
-	csvPattern := filepath.Join(logDir, "**/*.csv")
-	csvFiles, err := filepath.Glob(csvPattern)
+	csvFiles, err := layout.CSVFiles(logDir)
 	if err == nil {
 		for _, csvFile := range csvFiles {
 			s.parseCSVReport(csvFile, results)
@@ -175,7 +306,9 @@ func (s *Service) parseEMBAResults(logDir, jobID string) (*ParsedResults, error)
 	}
 
 	// Parse text reports from specific EMBA modules
-	s.parseModuleReports(logDir, results)
+	if aggregate == nil {
+		s.parseModuleReports(logDir, results)
+	}
 
 	// Parse advanced module outputs if enabled
 	if s.config.EMBAEnableEmulation {
@@ -198,22 +331,57 @@ func (s *Service) parseEMBAResults(logDir, jobID string) (*ParsedResults, error)
 	
 	// Parse SBOM data (F15 module)
 	s.parseSBOMData(logDir, results)
+
+	// Complete and classify component licenses (F10 module)
+	s.parseLicenses(logDir, results)
+
+	// Files added, removed or changed between the images of a diff scan (D modules)
+	s.parseFirmwareDiff(logDir, results)
 	
 	// Parse advanced extraction modules
-	s.parseAdvancedExtractionModules(logDir, results)
+	s.parseAdvancedExtractionModules(logDir, results, aggregate == nil)
+
+	// Prefer EMBA-reported scores over keyword heuristics
+	s.annotateSeverity(results)
+	annotateConfidence(results)
+	annotateProvenance(logDir, results)
+	annotateCWE(results)
+	rawFindings := len(results.Findings)
+	results.Findings = dedupFindings(results.Findings)
 
 	// Generate summary based on parsed data
 	results.Summary = map[string]interface{}{
 		"total_findings":    len(results.Findings),
 		"total_cves":       len(results.CVEs),
 		"total_osint":      len(results.OSINTResults),
+		"total_components": len(results.Components),
 		"critical_count":   s.countBySeverity(results.Findings, results.CVEs, "critical"),
 		"high_count":       s.countBySeverity(results.Findings, results.CVEs, "high"),
 		"medium_count":     s.countBySeverity(results.Findings, results.CVEs, "medium"),
 		"low_count":        s.countBySeverity(results.Findings, results.CVEs, "low"),
+		"info_count":       s.countBySeverity(results.Findings, results.CVEs, "info"),
 		"analysis_time":    time.Now().UTC().Format(time.RFC3339),
-		"emba_version":     s.getEMBAVersion(),
+		"emba_version":     embaVersion(s.config.EMBAPath),
+		"parser_layout":    layout.Name(),
 		"log_directory":    logDir,
+		"result_source":    "text_heuristics",
+		"duplicate_findings": rawFindings - len(results.Findings),
+	}
+	if extraction, ok := results.ExtractionInfo["extraction"].(*Extraction); ok {
+		results.Summary["extraction_quality"] = extraction.Quality
+	}
+	if len(results.Binaries) > 0 {
+		results.Summary["binary_protection"] = SummarizeBinaries(results.Binaries)
+	}
+	if len(results.Components) > 0 {
+		results.Summary["licenses"] = SummarizeLicenses(results.Components)
+	}
+	if len(results.PasswordHashes) > 0 {
+		results.Summary["password_hashes"] = len(results.PasswordHashes)
+	}
+	if aggregate != nil {
+		results.Summary["result_source"] = "f50_aggregator"
+		results.Summary["aggregator"] = aggregate.Counts
 	}
 
 	return results, nil
@@ -227,7 +395,7 @@ func (s *Service) parseGrepLog(grepLogFile string, results *ParsedResults) error
 	}
 
 	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -244,7 +412,7 @@ func (s *Service) parseGrepLog(grepLogFile string, results *ParsedResults) error
 				Severity:        models.RiskLevel(s.determineSeverity(line)),
 				Type:            models.FindingType("security"),
 				FilePath:        s.extractLocation(line),
-				FindingMetadata: map[string]interface{}{"raw_line": line},
+				FindingMetadata: encodeMetadata(map[string]interface{}{"raw_line": line, "log_file": grepLogFile, "log_line": i + 1}),
 			}
 			results.Findings = append(results.Findings, finding)
 		}
@@ -256,7 +424,13 @@ func (s *Service) parseGrepLog(grepLogFile string, results *ParsedResults) error
 func (s *Service) parseCSVReport(csvFile string, results *ParsedResults) error {
 	filename := strings.ToLower(filepath.Base(csvFile))
 	
-	if strings.Contains(filename, "cve") {
+	if strings.Contains(filename, "binary_protection") {
+		binaries, err := s.parseBinaryProtectionCSV(csvFile)
+		if err != nil {
+			return err
+		}
+		results.Binaries = append(results.Binaries, binaries...)
+	} else if strings.Contains(filename, "cve") || strings.HasPrefix(filename, "f20_") {
 		cves, err := s.parseCVEFile(csvFile)
 		if err != nil {
 			return err
@@ -280,13 +454,11 @@ func (s *Service) parseModuleReports(logDir string, results *ParsedResults) erro
 		"S116_qemu_version_check.txt",
 		"S115_usermode_emulator.txt", 
 		"S120_cve_search.txt",
-		"S25_kernel_check.txt",
-		"S40_weak_perm_check.txt",
 	}
 
 	for _, moduleFile := range moduleFiles {
-		fullPath := filepath.Join(logDir, moduleFile)
-		if _, err := os.Stat(fullPath); err == nil {
+		paths, _ := results.layout.ModuleLogs(logDir, moduleFile)
+		for _, fullPath := range paths {
 			s.parseModuleFile(fullPath, results)
 		}
 	}
@@ -322,20 +494,6 @@ func (s *Service) parseWebReportData(webReportDir string, results *ParsedResults
 	return nil
 }
 
-// getEMBAVersion gets the EMBA version
-func (s *Service) getEMBAVersion() string {
-	embaScript := filepath.Join(s.config.EMBAPath, "emba")
-	cmd := exec.Command(embaScript, "-V")
-	cmd.Dir = s.config.EMBAPath
-	
-	output, err := cmd.Output()
-	if err != nil {
-		return "unknown"
-	}
-	
-	return strings.TrimSpace(string(output))
-}
-
 // parseModuleFile parses individual EMBA module output files
 func (s *Service) parseModuleFile(filePath string, results *ParsedResults) error {
 	content, err := os.ReadFile(filePath)
@@ -365,7 +523,7 @@ func (s *Service) parseModuleFile(filePath string, results *ParsedResults) error
 				Severity:        models.RiskLevel(s.determineSeverity(line)),
 				Type:            models.FindingType(s.getCategoryFromModule(moduleName)),
 				FilePath:        filePath,
-				FindingMetadata: map[string]interface{}{"module": moduleName, "raw_line": line},
+				FindingMetadata: encodeMetadata(map[string]interface{}{"module": moduleName, "raw_line": line}),
 			}
 			results.Findings = append(results.Findings, finding)
 		}
@@ -374,87 +532,6 @@ func (s *Service) parseModuleFile(filePath string, results *ParsedResults) error
 	return nil
 }
 
-// parseVulnerabilityCSV parses vulnerability findings from CSV files
-func (s *Service) parseVulnerabilityCSV(csvFile string) ([]models.Finding, error) {
-	var findings []models.Finding
-	
-	content, err := os.ReadFile(csvFile)
-	if err != nil {
-		return findings, err
-	}
-
-	lines := strings.Split(string(content), "\n")
-	if len(lines) < 2 {
-		return findings, nil
-	}
-
-	// Skip header line
-	for i := 1; i < len(lines); i++ {
-		line := strings.TrimSpace(lines[i])
-		if line == "" {
-			continue
-		}
-
-		fields := strings.Split(line, ",")
-		if len(fields) >= 3 {
-			finding := models.Finding{
-				Title:           s.cleanCSVField(fields[0]),
-				Description:     s.cleanCSVField(fields[1]),
-				Severity:        models.RiskLevel(s.normalizeSeverity(s.cleanCSVField(fields[2]))),
-				Type:            models.FindingType("vulnerability"),
-				FilePath:        csvFile,
-				FindingMetadata: map[string]interface{}{"csv_source": csvFile},
-			}
-			findings = append(findings, finding)
-		}
-	}
-
-	return findings, nil
-}
-
-// parseCVEFile parses CVE findings from EMBA CSV output
-func (s *Service) parseCVEFile(csvFile string) ([]models.CVEFinding, error) {
-	var cves []models.CVEFinding
-
-	content, err := os.ReadFile(csvFile)
-	if err != nil {
-		return nil, err
-	}
-
-	lines := strings.Split(string(content), "\n")
-	for i, line := range lines {
-		if i == 0 || strings.TrimSpace(line) == "" {
-			continue // Skip header and empty lines
-		}
-
-		parts := strings.Split(line, ",")
-		if len(parts) >= 4 {
-			score := 0.0
-			if len(parts) > 3 && parts[3] != "" {
-				if parsed, err := strconv.ParseFloat(strings.TrimSpace(parts[3]), 64); err == nil {
-					score = parsed
-				}
-			}
-
-			cve := models.CVEFinding{
-				CVEID:           strings.TrimSpace(parts[0]),
-				SoftwareName:    strings.TrimSpace(parts[1]),
-				SoftwareVersion: strings.TrimSpace(parts[2]),
-				SeverityScore:   score,
-				SeverityLevel:   models.RiskLevel(s.scoreToSeverity(score)),
-			}
-
-			if len(parts) > 4 {
-				cve.Description = strings.TrimSpace(parts[4])
-			}
-
-			cves = append(cves, cve)
-		}
-	}
-
-	return cves, nil
-}
-
 // parseVulnerabilityFile parses vulnerability findings from EMBA text output
 func (s *Service) parseVulnerabilityFile(vulnFile string) ([]models.Finding, error) {
 	var findings []models.Finding
@@ -481,7 +558,7 @@ func (s *Service) parseVulnerabilityFile(vulnFile string) ([]models.Finding, err
 				FilePath:        vulnFile,
 				LineNumber:      i + 1,
 				Content:         line,
-				FindingMetadata: map[string]interface{}{"source": "vulnerability_file"},
+				FindingMetadata: encodeMetadata(map[string]interface{}{"source": "vulnerability_file"}),
 			}
 			findings = append(findings, finding)
 		}
@@ -585,7 +662,7 @@ func (s *Service) mapJSONToFinding(jsonFinding map[string]interface{}) models.Fi
 	finding := models.Finding{
 		Type:            models.FindingType("security_issue"),
 		Severity:        models.RiskLevel("low"),
-		FindingMetadata: map[string]interface{}{"category": "emba_json"},
+		FindingMetadata: encodeMetadata(map[string]interface{}{"category": "emba_json"}),
 	}
 
 	if title, ok := jsonFinding["title"].(string); ok {
@@ -633,7 +710,8 @@ func (s *Service) extractLocation(line string) string {
 	words := strings.Fields(line)
 	for _, word := range words {
 		if strings.Contains(word, "/") && (strings.Contains(word, ".") || strings.HasPrefix(word, "/")) {
-			return word
+			// Drop the punctuation of the sentence, e.g. "in /etc/init.d/rcS: eval"
+			return strings.TrimRight(word, ":;,.")
 		}
 	}
 	return ""
@@ -682,6 +760,8 @@ func (s *Service) normalizeSeverity(severity string) string {
 		return "medium"
 	case "low", "l", "1", "2", "3", "4":
 		return "low"
+	case "info", "informational", "i", "0":
+		return "info"
 	default:
 		return "medium"
 	}
@@ -699,8 +779,7 @@ func (s *Service) parseScore(scoreStr string) float64 {
 // parseEmulationResults parses S115 user-mode emulation results
 func (s *Service) parseEmulationResults(logDir string, results *ParsedResults) error {
 	// Look for S115 emulation log files
-	emulationLogPattern := filepath.Join(logDir, "S115_*")
-	emulationFiles, err := filepath.Glob(emulationLogPattern)
+	emulationFiles, err := results.layout.ModuleLogs(logDir, "S115_*")
 	if err != nil {
 		return err
 	}
@@ -727,10 +806,10 @@ func (s *Service) parseEmulationResults(logDir string, results *ParsedResults) e
 					Description:     line,
 					Severity:        models.RiskLevel("low"),
 					FilePath:        emulationFile,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "emulation",
 						"module": "S115",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -743,8 +822,7 @@ func (s *Service) parseEmulationResults(logDir string, results *ParsedResults) e
 // parseCWECheckerResults parses S120 CWE-checker results
 func (s *Service) parseCWECheckerResults(logDir string, results *ParsedResults) error {
 	// Look for CWE-checker output files
-	cweLogPattern := filepath.Join(logDir, "S120_*")
-	cweFiles, err := filepath.Glob(cweLogPattern)
+	cweFiles, err := results.layout.ModuleLogs(logDir, "S120_*")
 	if err != nil {
 		return err
 	}
@@ -763,8 +841,8 @@ func (s *Service) parseCWECheckerResults(logDir string, results *ParsedResults)
 				continue
 			}
 
-			// Parse CWE findings
-			if strings.Contains(line, "CWE-") {
+			// Parse CWE findings, cited as [CWE676] by cwe_checker
+			if cweID := cwe.Extract(line); cweID != "" {
 				severity := "medium"
 				if strings.Contains(strings.ToLower(line), "high") {
 					severity = "high"
@@ -778,10 +856,11 @@ func (s *Service) parseCWECheckerResults(logDir string, results *ParsedResults)
 					Description:     line,
 					Severity:        models.RiskLevel(severity),
 					FilePath:        cweFile,
-					FindingMetadata: map[string]interface{}{
+					CWE:             cweID,
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "cwe_checker",
 						"module": "S120",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -804,110 +883,9 @@ func (s *Service) parseLiveTestingResults(logDir string, results *ParsedResults)
 	return nil
 }
 
-// parseSystemEmulationResults parses L10 system emulation results
-func (s *Service) parseSystemEmulationResults(logDir string, results *ParsedResults) error {
-	l10Pattern := filepath.Join(logDir, "L10_*")
-	l10Files, err := filepath.Glob(l10Pattern)
-	if err != nil {
-		return err
-	}
-
-	for _, l10File := range l10Files {
-		content, err := os.ReadFile(l10File)
-		if err != nil {
-			log.Printf("Error reading L10 file %s: %v", l10File, err)
-			continue
-		}
-
-		lines := strings.Split(string(content), "\n")
-		emulationData := map[string]interface{}{
-			"architecture": "",
-			"kernel":       "",
-			"init_process": "",
-			"network_ip":   "",
-			"services":     []string{},
-		}
-
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-
-			// Parse emulation setup information
-			if strings.Contains(strings.ToLower(line), "architecture") {
-				emulationData["architecture"] = s.extractValue(line, "architecture")
-			}
-			if strings.Contains(strings.ToLower(line), "kernel") && strings.Contains(line, "version") {
-				emulationData["kernel"] = s.extractValue(line, "kernel")
-			}
-			if strings.Contains(strings.ToLower(line), "init") && strings.Contains(line, "process") {
-				emulationData["init_process"] = s.extractValue(line, "init")
-			}
-			if strings.Contains(line, "IP:") || strings.Contains(line, "ip:") {
-				emulationData["network_ip"] = s.extractIPAddress(line)
-			}
-
-			// Parse emulation status and results
-			if strings.Contains(strings.ToLower(line), "emulation") && 
-			   (strings.Contains(strings.ToLower(line), "successful") || 
-			    strings.Contains(strings.ToLower(line), "started") ||
-			    strings.Contains(strings.ToLower(line), "running")) {
-				
-				finding := models.Finding{
-					Type:        models.FindingType("system_emulation"),
-					Title:       "System Emulation Status",
-					Description: line,
-					Severity:    models.RiskLevel("info"),
-					FilePath:    l10File,
-					FindingMetadata: map[string]interface{}{
-						"source":          "system_emulation",
-						"module":          "L10",
-						"emulation_data":  emulationData,
-					},
-				}
-				results.Findings = append(results.Findings, finding)
-			}
-
-			// Parse service detection
-			if strings.Contains(strings.ToLower(line), "service") && 
-			   (strings.Contains(strings.ToLower(line), "detected") ||
-			    strings.Contains(strings.ToLower(line), "running")) {
-				
-				serviceName := s.extractServiceName(line)
-				if serviceName != "" {
-					if services, ok := emulationData["services"].([]string); ok {
-						emulationData["services"] = append(services, serviceName)
-					}
-				}
-
-				finding := models.Finding{
-					Type:        models.FindingType("service_detection"),
-					Title:       fmt.Sprintf("Service Detected: %s", serviceName),
-					Description: line,
-					Severity:    models.RiskLevel("low"),
-					FilePath:    l10File,
-					FindingMetadata: map[string]interface{}{
-						"source":       "system_emulation",
-						"module":       "L10",
-						"service_name": serviceName,
-					},
-				}
-				results.Findings = append(results.Findings, finding)
-			}
-		}
-
-		// Store emulation summary
-		results.Summary["system_emulation"] = emulationData
-	}
-
-	return nil
-}
-
 // parseNetworkScanResults parses L15 Nmap scanning results
 func (s *Service) parseNetworkScanResults(logDir string, results *ParsedResults) error {
-	l15Pattern := filepath.Join(logDir, "L15_*")
-	l15Files, err := filepath.Glob(l15Pattern)
+	l15Files, err := results.layout.ModuleLogs(logDir, "L15_*")
 	if err != nil {
 		return err
 	}
@@ -950,11 +928,11 @@ func (s *Service) parseNetworkScanResults(logDir string, results *ParsedResults)
 						Description: line,
 						Severity:    models.RiskLevel(severity),
 						FilePath:    l15File,
-						FindingMetadata: map[string]interface{}{
+						FindingMetadata: encodeMetadata(map[string]interface{}{
 							"source":    "network_scan",
 							"module":    "L15",
 							"port_info": portInfo,
-						},
+						}),
 					}
 					results.Findings = append(results.Findings, finding)
 				}
@@ -968,12 +946,12 @@ func (s *Service) parseNetworkScanResults(logDir string, results *ParsedResults)
 					Type:        models.FindingType("service_version"),
 					Title:       "Service Version Detected",
 					Description: line,
-					Severity:    models.RiskLevel("info"),
+					Severity:    models.RiskInfo,
 					FilePath:    l15File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "network_scan",
 						"module": "L15",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -986,12 +964,12 @@ func (s *Service) parseNetworkScanResults(logDir string, results *ParsedResults)
 					Type:        models.FindingType("os_detection"),
 					Title:       "Operating System Detection",
 					Description: line,
-					Severity:    models.RiskLevel("info"),
+					Severity:    models.RiskInfo,
 					FilePath:    l15File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "network_scan",
 						"module": "L15",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1009,8 +987,7 @@ func (s *Service) parseNetworkScanResults(logDir string, results *ParsedResults)
 
 // parseSNMPCheckResults parses L20 SNMP check results
 func (s *Service) parseSNMPCheckResults(logDir string, results *ParsedResults) error {
-	l20Pattern := filepath.Join(logDir, "L20_*")
-	l20Files, err := filepath.Glob(l20Pattern)
+	l20Files, err := results.layout.ModuleLogs(logDir, "L20_*")
 	if err != nil {
 		return err
 	}
@@ -1046,10 +1023,10 @@ func (s *Service) parseSNMPCheckResults(logDir string, results *ParsedResults) e
 					Description: line,
 					Severity:    models.RiskLevel(severity),
 					FilePath:    l20File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "snmp_check",
 						"module": "L20",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1063,12 +1040,12 @@ func (s *Service) parseSNMPCheckResults(logDir string, results *ParsedResults) e
 					Type:        models.FindingType("snmp_info"),
 					Title:       "SNMP System Information",
 					Description: line,
-					Severity:    models.RiskLevel("info"),
+					Severity:    models.RiskInfo,
 					FilePath:    l20File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "snmp_check",
 						"module": "L20",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1080,8 +1057,7 @@ func (s *Service) parseSNMPCheckResults(logDir string, results *ParsedResults) e
 
 // parseUPnPHNAPResults parses L22 UPnP/HNAP check results
 func (s *Service) parseUPnPHNAPResults(logDir string, results *ParsedResults) error {
-	l22Pattern := filepath.Join(logDir, "L22_*")
-	l22Files, err := filepath.Glob(l22Pattern)
+	l22Files, err := results.layout.ModuleLogs(logDir, "L22_*")
 	if err != nil {
 		return err
 	}
@@ -1110,10 +1086,10 @@ func (s *Service) parseUPnPHNAPResults(logDir string, results *ParsedResults) er
 					Description: line,
 					Severity:    models.RiskLevel("medium"),
 					FilePath:    l22File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "upnp_check",
 						"module": "L22",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1129,10 +1105,10 @@ func (s *Service) parseUPnPHNAPResults(logDir string, results *ParsedResults) er
 					Description: line,
 					Severity:    models.RiskLevel("high"),
 					FilePath:    l22File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "upnp_check",
 						"module": "L22",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1144,8 +1120,7 @@ func (s *Service) parseUPnPHNAPResults(logDir string, results *ParsedResults) er
 
 // parseVNCCheckResults parses L23 VNC check results
 func (s *Service) parseVNCCheckResults(logDir string, results *ParsedResults) error {
-	l23Pattern := filepath.Join(logDir, "L23_*")
-	l23Files, err := filepath.Glob(l23Pattern)
+	l23Files, err := results.layout.ModuleLogs(logDir, "L23_*")
 	if err != nil {
 		return err
 	}
@@ -1181,10 +1156,10 @@ func (s *Service) parseVNCCheckResults(logDir string, results *ParsedResults) er
 					Description: line,
 					Severity:    models.RiskLevel(severity),
 					FilePath:    l23File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "vnc_check",
 						"module": "L23",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1196,8 +1171,7 @@ func (s *Service) parseVNCCheckResults(logDir string, results *ParsedResults) er
 
 // parseWebCheckResults parses L25 web application check results
 func (s *Service) parseWebCheckResults(logDir string, results *ParsedResults) error {
-	l25Pattern := filepath.Join(logDir, "L25_*")
-	l25Files, err := filepath.Glob(l25Pattern)
+	l25Files, err := results.layout.ModuleLogs(logDir, "L25_*")
 	if err != nil {
 		return err
 	}
@@ -1228,11 +1202,11 @@ func (s *Service) parseWebCheckResults(logDir string, results *ParsedResults) er
 					Description: line,
 					Severity:    models.RiskLevel(s.determineSeverity(line)),
 					FilePath:    l25File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "web_check",
 						"module": "L25",
 						"tool":   "nikto",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1249,11 +1223,11 @@ func (s *Service) parseWebCheckResults(logDir string, results *ParsedResults) er
 					Description: line,
 					Severity:    models.RiskLevel(s.determineSeverity(line)),
 					FilePath:    l25File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "web_check",
 						"module": "L25",
 						"tool":   "testssl",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1269,11 +1243,11 @@ func (s *Service) parseWebCheckResults(logDir string, results *ParsedResults) er
 					Description: line,
 					Severity:    models.RiskLevel(s.determineSeverity(line)),
 					FilePath:    l25File,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "web_check",
 						"module": "L25",
 						"tool":   "arachni",
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1283,106 +1257,56 @@ func (s *Service) parseWebCheckResults(logDir string, results *ParsedResults) er
 	return nil
 }
 
-// parseSBOMData parses F15 SBOM (Software Bill of Materials) data
-func (s *Service) parseSBOMData(logDir string, results *ParsedResults) error {
-	// Look for SBOM JSON files generated by F15
-	sbomFiles := []string{
-		filepath.Join(logDir, "sbom.json"),
-		filepath.Join(logDir, "f15_sbom.json"),
-		filepath.Join(logDir, "cyclonedx_sbom.json"),
-	}
-
-	for _, sbomFile := range sbomFiles {
-		if _, err := os.Stat(sbomFile); err != nil {
-			continue // File doesn't exist, skip
-		}
-
-		content, err := os.ReadFile(sbomFile)
-		if err != nil {
-			log.Printf("Error reading SBOM file %s: %v", sbomFile, err)
-			continue
-		}
-
-		var sbomData map[string]interface{}
-		if err := json.Unmarshal(content, &sbomData); err != nil {
-			log.Printf("Error parsing SBOM JSON %s: %v", sbomFile, err)
-			continue
-		}
-
-		// Extract components from SBOM
-		if components, ok := sbomData["components"].([]interface{}); ok {
-			for _, comp := range components {
-				if component, ok := comp.(map[string]interface{}); ok {
-					// Create findings for each software component
-					name := ""
-					version := ""
-					if n, ok := component["name"].(string); ok {
-						name = n
-					}
-					if v, ok := component["version"].(string); ok {
-						version = v
-					}
-
-					if name != "" {
-						finding := models.Finding{
-							Type:        models.FindingType("software_component"),
-							Title:       fmt.Sprintf("Software Component: %s", name),
-							Description: fmt.Sprintf("Component: %s, Version: %s", name, version),
-							Severity:    models.RiskLevel("low"),
-							FilePath:    sbomFile,
-							FindingMetadata: map[string]interface{}{
-								"source":    "sbom",
-								"module":    "F15",
-								"component": name,
-								"version":   version,
-							},
-						}
-						results.Findings = append(results.Findings, finding)
-					}
-				}
-			}
-		}
-
-		// Store SBOM data in summary
-		results.Summary["sbom_data"] = sbomData
-		break // Only process the first SBOM file found
-	}
-
-	return nil
-}
-
 // extractCWETitle extracts a meaningful title from CWE-checker output
 func (s *Service) extractCWETitle(line string) string {
 	// Extract CWE ID and description
-	cwePattern := `CWE-\d+`
-	re := regexp.MustCompile(cwePattern)
-	matches := re.FindStringSubmatch(line)
-	
-	if len(matches) > 0 {
-		return fmt.Sprintf("CWE Finding: %s", matches[0])
+	if cweID := cwe.Extract(line); cweID != "" {
+		return fmt.Sprintf("CWE Finding: %s", cweID)
 	}
 	
 	return "CWE Finding"
 }
 
 // parseAdvancedExtractionModules parses results from advanced extraction modules
-func (s *Service) parseAdvancedExtractionModules(logDir string, results *ParsedResults) error {
+func (s *Service) parseAdvancedExtractionModules(logDir string, results *ParsedResults, heuristics bool) error {
 	// Parse P modules (pre-modules for advanced extraction)
-	s.parsePreModules(logDir, results)
-	
-	// Parse S modules (static analysis modules)
-	s.parseStaticAnalysisModules(logDir, results)
+	if heuristics {
+		s.parsePreModules(logDir, results)
+	}
+
+	// Rate how well the image was unpacked
+	s.assessExtraction(logDir, results)
+
+	// Parse bootloader and boot chain details
+	s.parseBootloader(logDir, results)
+
+	// Inventory hardware peripherals from device trees and kernel configs
+	s.parseHardwareInventory(logDir, results)
+
+	// Parse the CPU architecture and operating system
+	s.parsePlatform(logDir, results)
+
+	// Parse kernel version, hardening checks and kernel CVEs
+	s.parseKernel(logDir, results)
+
+	// Parse weak file permissions
+	s.parseWeakPermissions(logDir, results)
+
+	// Collect password hashes of passwd and shadow files
+	s.parsePasswordHashes(logDir, results)
 	
-	// Parse F modules (finishing modules)
-	s.parseFinishingModules(logDir, results)
+	// Parse S and F module logs by keyword unless the aggregator covered them
+	if heuristics {
+		s.parseStaticAnalysisModules(logDir, results)
+		s.parseFinishingModules(logDir, results)
+	}
 	
 	return nil
 }
 
 // parsePreModules parses P module results (pre-analysis modules)
 func (s *Service) parsePreModules(logDir string, results *ParsedResults) error {
-	preModulePattern := filepath.Join(logDir, "P*")
-	preModuleFiles, err := filepath.Glob(preModulePattern)
+	preModuleFiles, err := results.layout.ModuleLogs(logDir, "P*")
 	if err != nil {
 		return err
 	}
@@ -1401,21 +1325,21 @@ func (s *Service) parsePreModules(logDir string, results *ParsedResults) error {
 				continue
 			}
 
-			// Parse firmware information from pre-modules
+			// Parse firmware information from pre-modules; bootloader
+			// details are handled separately by parseBootloader
 			if strings.Contains(strings.ToLower(line), "firmware") ||
-			   strings.Contains(strings.ToLower(line), "bootloader") ||
 			   strings.Contains(strings.ToLower(line), "kernel") {
 				
 				finding := models.Finding{
 					Type:        models.FindingType("firmware_info"),
 					Title:       "Firmware Information",
 					Description: line,
-					Severity:    models.RiskLevel("info"),
+					Severity:    models.RiskInfo,
 					FilePath:    preModuleFile,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "pre_analysis",
 						"module": filepath.Base(preModuleFile),
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1425,17 +1349,21 @@ func (s *Service) parsePreModules(logDir string, results *ParsedResults) error {
 	return nil
 }
 
+// structuredModuleRegex matches the logs of S modules with a parser of their
+// own: the kernel modules (S24-S26), weak permissions (S40) and the
+// password search (S107)
+var structuredModuleRegex = regexp.MustCompile(`(?i)^S(2[456]|40|107)_`)
+
 // parseStaticAnalysisModules parses additional S module results
 func (s *Service) parseStaticAnalysisModules(logDir string, results *ParsedResults) error {
-	staticModulePattern := filepath.Join(logDir, "S*")
-	staticModuleFiles, err := filepath.Glob(staticModulePattern)
+	staticModuleFiles, err := results.layout.ModuleLogs(logDir, "S*")
 	if err != nil {
 		return err
 	}
 
 	for _, staticModuleFile := range staticModuleFiles {
 		// Skip already processed modules
-		if strings.Contains(staticModuleFile, "S115") || strings.Contains(staticModuleFile, "S120") {
+		if strings.Contains(staticModuleFile, "S115") || strings.Contains(staticModuleFile, "S120") || structuredModuleRegex.MatchString(filepath.Base(staticModuleFile)) {
 			continue
 		}
 
@@ -1464,10 +1392,10 @@ func (s *Service) parseStaticAnalysisModules(logDir string, results *ParsedResul
 					Description: line,
 					Severity:    models.RiskLevel("medium"),
 					FilePath:    staticModuleFile,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "static_analysis",
 						"module": filepath.Base(staticModuleFile),
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1483,10 +1411,10 @@ func (s *Service) parseStaticAnalysisModules(logDir string, results *ParsedResul
 					Description: line,
 					Severity:    models.RiskLevel("low"),
 					FilePath:    staticModuleFile,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "static_analysis",
 						"module": filepath.Base(staticModuleFile),
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1498,8 +1426,7 @@ func (s *Service) parseStaticAnalysisModules(logDir string, results *ParsedResul
 
 // parseFinishingModules parses F module results (finishing modules)
 func (s *Service) parseFinishingModules(logDir string, results *ParsedResults) error {
-	finishingModulePattern := filepath.Join(logDir, "F*")
-	finishingModuleFiles, err := filepath.Glob(finishingModulePattern)
+	finishingModuleFiles, err := results.layout.ModuleLogs(logDir, "F*")
 	if err != nil {
 		return err
 	}
@@ -1532,12 +1459,12 @@ func (s *Service) parseFinishingModules(logDir string, results *ParsedResults) e
 					Type:        models.FindingType("analysis_summary"),
 					Title:       "Analysis Summary",
 					Description: line,
-					Severity:    models.RiskLevel("info"),
+					Severity:    models.RiskInfo,
 					FilePath:    finishingModuleFile,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "finishing_analysis",
 						"module": filepath.Base(finishingModuleFile),
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -1554,10 +1481,10 @@ func (s *Service) parseFinishingModules(logDir string, results *ParsedResults) e
 					Description: line,
 					Severity:    models.RiskLevel(severity),
 					FilePath:    finishingModuleFile,
-					FindingMetadata: map[string]interface{}{
+					FindingMetadata: encodeMetadata(map[string]interface{}{
 						"source": "finishing_analysis",
 						"module": filepath.Base(finishingModuleFile),
-					},
+					}),
 				}
 				results.Findings = append(results.Findings, finding)
 			}
@@ -0,0 +1,173 @@
+package emba
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+var (
+	emulationArchRegex   = regexp.MustCompile(`(?i)\barch(?:itecture)?\s*[:=]\s*([\w.-]+(?:\s+(?:little|big)[ -]endian)?)`)
+	emulationKernelRegex = regexp.MustCompile(`(?i)\b(?:kernel(?:\s+version)?\s*[:=]|using kernel)\s*(\S+)`)
+	emulationInitRegex   = regexp.MustCompile(`(?i)\b(?:init(?:\s+(?:process|file|binary))?\s*[:=]|with init(?: file)?)\s*(/\S+)`)
+	emulationIPLineRegex = regexp.MustCompile(`(?i)\bip(?:\s+address)?\b|running on`)
+	ipv4Regex            = regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)
+	// "Service detected: httpd running on port 80" or nmap's "80/tcp open http"
+	emulationServiceRegex = regexp.MustCompile(`(?i)service detected:\s*([\w.-]+)(?:.*?\bport\s+(\d+)(?:/(tcp|udp))?)?`)
+	emulationPortRegex    = regexp.MustCompile(`^(\d+)/(tcp|udp)\s+open\s+(\S+)`)
+	// the status lines of the emulation itself, and the reachability checks
+	// of emulator_online_results.log ("ICMP ok: 1")
+	emulationSuccessRegex = regexp.MustCompile(`(?i)emulat\w*\b.*\b(?:successful(?:ly)?|succeeded|is running|running on)\b|\b(?:icmp|tcp) ok:\s*[1-9]`)
+	emulationFailureRegex = regexp.MustCompile(`(?i)emulat\w*\b.*\b(?:failed|not successful|unsuccessful|no working)\b`)
+)
+
+// parseSystemEmulationResults parses L10 system emulation results into an
+// EmulationResult per log: the emulated environment, the addresses the
+// firmware took and the services that came up
+func (s *Service) parseSystemEmulationResults(logDir string, results *ParsedResults) error {
+	l10Files, err := results.layout.ModuleLogs(logDir, "L10_*")
+	if err != nil {
+		return err
+	}
+
+	for _, l10File := range l10Files {
+		content, err := os.ReadFile(l10File)
+		if err != nil {
+			log.Printf("Error reading L10 file %s: %v", l10File, err)
+			continue
+		}
+
+		emulation := models.EmulationResult{}
+		var addresses []string
+		var services []models.EmulatedService
+		failed := false
+
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+			if line == "" {
+				continue
+			}
+
+			if matches := emulationArchRegex.FindStringSubmatch(line); matches != nil && emulation.Architecture == "" {
+				emulation.Architecture = matches[1]
+			}
+			if matches := emulationKernelRegex.FindStringSubmatch(line); matches != nil && emulation.Kernel == "" {
+				emulation.Kernel = matches[1]
+			}
+			if matches := emulationInitRegex.FindStringSubmatch(line); matches != nil && emulation.InitProcess == "" {
+				emulation.InitProcess = matches[1]
+			}
+			if emulationIPLineRegex.MatchString(line) {
+				for _, ip := range ipv4Regex.FindAllString(line, -1) {
+					if !strings.HasPrefix(ip, "0.") && !strings.HasPrefix(ip, "127.") && !strings.HasPrefix(ip, "255.") {
+						addresses = appendUnique(addresses, ip)
+					}
+				}
+			}
+
+			switch {
+			case emulationFailureRegex.MatchString(line):
+				failed = true
+				emulation.Detail = line
+			case emulationSuccessRegex.MatchString(line):
+				emulation.Success = true
+				emulation.Detail = line
+			}
+
+			service, ok := emulatedService(line)
+			if !ok || containsService(services, service) {
+				continue
+			}
+			services = append(services, service)
+
+			results.Findings = append(results.Findings, models.Finding{
+				Type:        models.FindingType("service_detection"),
+				Title:       fmt.Sprintf("Service Detected: %s", service.Name),
+				Description: line,
+				Severity:    models.RiskLow,
+				FilePath:    l10File,
+				FindingMetadata: encodeMetadata(map[string]interface{}{
+					"source":       "system_emulation",
+					"module":       "L10",
+					"service_name": service.Name,
+					"port":         service.Port,
+				}),
+			})
+		}
+
+		// A service answering means the firmware booted whatever EMBA logged
+		if len(services) > 0 {
+			emulation.Success = true
+		}
+		if emulation.Detail == "" && !failed && !emulation.Success {
+			continue // not an emulation log, e.g. EMBA's module header only
+		}
+		if addresses != nil {
+			data, _ := json.Marshal(addresses)
+			emulation.IPAddresses = string(data)
+		}
+		if services != nil {
+			data, _ := json.Marshal(services)
+			emulation.Services = string(data)
+		}
+		results.EmulationResults = append(results.EmulationResults, emulation)
+
+		title := "System emulation failed"
+		if emulation.Success {
+			title = "System emulation successful"
+		}
+		results.Findings = append(results.Findings, models.Finding{
+			Type:        models.FindingType("system_emulation"),
+			Title:       title,
+			Description: emulation.Detail,
+			Severity:    models.RiskInfo,
+			FilePath:    l10File,
+			FindingMetadata: encodeMetadata(map[string]interface{}{
+				"source":       "system_emulation",
+				"module":       "L10",
+				"architecture": emulation.Architecture,
+				"services":     len(services),
+			}),
+		})
+	}
+
+	return nil
+}
+
+// emulatedService reads the service a line reports as up, if any
+func emulatedService(line string) (models.EmulatedService, bool) {
+	if matches := emulationPortRegex.FindStringSubmatch(line); matches != nil {
+		port, _ := strconv.Atoi(matches[1])
+		return models.EmulatedService{Name: matches[3], Port: port, Protocol: matches[2]}, true
+	}
+	if matches := emulationServiceRegex.FindStringSubmatch(line); matches != nil {
+		service := models.EmulatedService{Name: matches[1], Protocol: strings.ToLower(matches[3])}
+		service.Port, _ = strconv.Atoi(matches[2])
+		if service.Port > 0 && service.Protocol == "" {
+			service.Protocol = "tcp"
+		}
+		return service, true
+	}
+	return models.EmulatedService{}, false
+}
+
+// containsService reports whether a service was seen already: on the same
+// port, or by name when the port is unknown, as EMBA and nmap name them
+// differently (httpd, http)
+func containsService(services []models.EmulatedService, service models.EmulatedService) bool {
+	for _, s := range services {
+		if service.Port > 0 && s.Port == service.Port && s.Protocol == service.Protocol {
+			return true
+		}
+		if service.Port == 0 && s.Name == service.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package emba
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	backendversion "odin-backend/internal/version"
+)
+
+// ParserVersion identifies the result parsing logic. Bump it whenever a
+// parser change alters the findings produced from the same EMBA output.
+const ParserVersion = "16"
+
+// feedPaths are EMBA's external vulnerability data sources, relative to the
+// EMBA directory; their modification times date the snapshot a run used
+var feedPaths = map[string]string{
+	"nvd":              "external/nvd-json-data-feeds",
+	"epss":             "external/EPSS-data",
+	"known_exploited":  "config/known_exploited_vulnerabilities.csv",
+	"metasploit":       "config/msf_cve-db.txt",
+	"exploit_database": "external/exploit-database",
+}
+
+var profileModulesRegex = regexp.MustCompile(`^\s*(?:export\s+)?(SELECT_MODULES|MODULE_BLACKLIST)\+?=\(?\s*(.*?)\s*\)?\s*$`)
+
+// Environment fingerprints everything besides the firmware that determines
+// an analysis' results
+type Environment struct {
+	EMBAVersion     string            `json:"emba_version"`
+	EMBACommit      string            `json:"emba_commit,omitempty"`
+	ScanProfile     string            `json:"scan_profile"`
+	ProfileHash     string            `json:"profile_hash"`
+	Modules         []string          `json:"modules,omitempty"`          // SELECT_MODULES of the profile
+	ExcludedModules []string          `json:"excluded_modules,omitempty"` // MODULE_BLACKLIST of the profile
+	Options         map[string]string `json:"options"`
+	FeedSnapshots   map[string]string `json:"feed_snapshots"`
+	ParserVersion   string            `json:"parser_version"`
+	ParserLayout    string            `json:"parser_layout"`
+	BackendVersion  string            `json:"backend_version"`
+}
+
+// EnvironmentDifference is one field that differs between two environments
+type EnvironmentDifference struct {
+	Field  string `json:"field"`
+	Base   string `json:"base"`
+	Target string `json:"target"`
+}
+
+// Environment captures the current analysis environment
+func (s *Service) Environment() *Environment {
+	version := embaVersion(s.config.EMBAPath)
+	layout, _ := LayoutFor(version)
+	env := &Environment{
+		EMBAVersion: version,
+		EMBACommit:  embaCommit(s.config.EMBAPath),
+		ScanProfile: s.config.EMBAScanProfile,
+		Options: map[string]string{
+			"threads":      strconv.Itoa(s.config.EMBAThreads),
+			"emulation":    strconv.FormatBool(s.config.EMBAEnableEmulation),
+			"cwe_check":    strconv.FormatBool(s.config.EMBAEnableCWECheck),
+			"live_testing": strconv.FormatBool(s.config.EMBAEnableLiveTesting),
+			"privilege":    s.privilegeMode(),
+		},
+		FeedSnapshots:  make(map[string]string),
+		ParserVersion:  ParserVersion,
+		ParserLayout:   layout.Name(),
+		BackendVersion: backendversion.Version,
+	}
+
+	profilePath := filepath.Join(s.config.EMBAPath, "scan-profiles", s.config.EMBAScanProfile)
+	if content, err := os.ReadFile(profilePath); err == nil {
+		env.ProfileHash = fmt.Sprintf("%x", sha256.Sum256(content))
+		env.Modules, env.ExcludedModules = profileModules(string(content))
+	}
+	env.ExcludedModules = append(env.ExcludedModules, s.skippedModules()...)
+	env.ExcludedModules = append(env.ExcludedModules, s.config.EMBAExcludedModules...)
+
+	for name, rel := range feedPaths {
+		if info, err := os.Stat(filepath.Join(s.config.EMBAPath, rel)); err == nil {
+			env.FeedSnapshots[name] = info.ModTime().UTC().Format(time.RFC3339)
+		}
+	}
+
+	return env
+}
+
+// Hash identifies the environment; runs with equal hashes used the same setup
+func (e *Environment) Hash() string {
+	encoded, _ := json.Marshal(e)
+	return fmt.Sprintf("%x", sha256.Sum256(encoded))
+}
+
+// Diff lists the fields that differ between two environments
+func (e *Environment) Diff(target *Environment) []EnvironmentDifference {
+	var diffs []EnvironmentDifference
+	add := func(field, base, other string) {
+		if base != other {
+			diffs = append(diffs, EnvironmentDifference{Field: field, Base: base, Target: other})
+		}
+	}
+
+	add("emba_version", e.EMBAVersion, target.EMBAVersion)
+	add("emba_commit", e.EMBACommit, target.EMBACommit)
+	add("scan_profile", e.ScanProfile, target.ScanProfile)
+	add("profile_hash", e.ProfileHash, target.ProfileHash)
+	add("modules", strings.Join(e.Modules, ","), strings.Join(target.Modules, ","))
+	add("excluded_modules", strings.Join(e.ExcludedModules, ","), strings.Join(target.ExcludedModules, ","))
+	for _, key := range unionKeys(e.Options, target.Options) {
+		add("options."+key, e.Options[key], target.Options[key])
+	}
+	for _, key := range unionKeys(e.FeedSnapshots, target.FeedSnapshots) {
+		add("feed_snapshots."+key, e.FeedSnapshots[key], target.FeedSnapshots[key])
+	}
+	add("parser_version", e.ParserVersion, target.ParserVersion)
+	add("parser_layout", e.ParserLayout, target.ParserLayout)
+	add("backend_version", e.BackendVersion, target.BackendVersion)
+	return diffs
+}
+
+// embaVersion reads the version EMBA ships in config/VERSION.txt
+func embaVersion(embaPath string) string {
+	content, err := os.ReadFile(filepath.Join(embaPath, "config", "VERSION.txt"))
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// embaCommit returns the git commit of the EMBA checkout, if it is one
+func embaCommit(embaPath string) string {
+	// Don't report the commit of a repository EMBA merely sits in
+	if _, err := os.Stat(filepath.Join(embaPath, ".git")); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", "-C", embaPath, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// profileModules extracts the selected and blacklisted modules of a scan profile
+func profileModules(profile string) (selected, excluded []string) {
+	scanner := bufio.NewScanner(strings.NewReader(profile))
+	for scanner.Scan() {
+		matches := profileModulesRegex.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			continue
+		}
+		for _, module := range strings.Fields(matches[2]) {
+			module = strings.Trim(module, `"'`)
+			if module == "" {
+				continue
+			}
+			if matches[1] == "SELECT_MODULES" {
+				selected = append(selected, module)
+			} else {
+				excluded = append(excluded, module)
+			}
+		}
+	}
+	sort.Strings(selected)
+	sort.Strings(excluded)
+	return selected, excluded
+}
+
+func unionKeys(a, b map[string]string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]string{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,145 @@
+package emba
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// encryptedEntropy is the entropy (bits per byte) above which an image that
+// yields no files is considered encrypted rather than just unsupported
+const encryptedEntropy = 7.9
+
+// partialExtractionFiles is how many files an extraction needs to count as
+// more than a container with a few opaque blobs
+const partialExtractionFiles = 10
+
+// Extraction describes how well EMBA unpacked the image
+type Extraction struct {
+	Quality              models.ExtractionQuality `json:"quality"`
+	Entropy              *float64                 `json:"entropy,omitempty"`     // bits per byte, when EMBA reported it
+	ExtractedFiles       int                      `json:"extracted_files"`       // -1 when unknown
+	EncryptionIndicators []string                 `json:"encryption_indicators"` // pre-module lines reporting encryption
+}
+
+var (
+	entropyRegex        = regexp.MustCompile(`(?i)\bentropy\b[^0-9\n]{0,40}?(\d(?:\.\d+)?)\b`)
+	extractedFilesRegex = regexp.MustCompile(`(?i)\b(?:found|extracted)\s+(\d+)\s+(?:unique\s+)?files\b`)
+	encryptedRegex      = regexp.MustCompile(`(?i)\bencrypted\b`)
+	notEncryptedRegex   = regexp.MustCompile(`(?i)\b(not|un)\s?encrypted\b`)
+)
+
+// assessExtraction rates how well EMBA unpacked the image from the entropy
+// and extraction results of the pre-modules (P*) and the extracted firmware
+// tree, and records it in ExtractionInfo["extraction"]
+func (s *Service) assessExtraction(logDir string, results *ParsedResults) error {
+	entropy := -1.0
+	if value, ok := results.FileInfo["entropy"].(string); ok {
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			entropy = parsed
+		}
+	}
+	files := -1
+	indicators := []string{}
+
+	preModuleFiles, err := results.layout.ModuleLogs(logDir, "P*")
+	if err != nil {
+		return err
+	}
+	for _, file := range preModuleFiles {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Error reading pre-module file %s: %v", file, err)
+			continue
+		}
+
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+			if line == "" {
+				continue
+			}
+
+			if matches := entropyRegex.FindStringSubmatch(line); matches != nil && entropy < 0 {
+				if value, err := strconv.ParseFloat(matches[1], 64); err == nil && value <= 8 {
+					entropy = value
+				}
+			}
+			if matches := extractedFilesRegex.FindStringSubmatch(line); matches != nil {
+				if count, err := strconv.Atoi(matches[1]); err == nil && count > files {
+					files = count
+				}
+			}
+			if (strings.HasPrefix(line, "[+]") || strings.HasPrefix(line, "[!]")) &&
+				encryptedRegex.MatchString(line) && !notEncryptedRegex.MatchString(line) {
+				indicators = append(indicators, line)
+			}
+		}
+	}
+
+	// The logs don't always say; count what landed in the firmware tree
+	if files < 0 {
+		files = countExtractedFiles(filepath.Join(logDir, "firmware"))
+	}
+
+	extraction := &Extraction{
+		Quality:              extractionQuality(entropy, files, len(indicators) > 0),
+		ExtractedFiles:       files,
+		EncryptionIndicators: indicators,
+	}
+	if entropy >= 0 {
+		extraction.Entropy = &entropy
+	}
+	results.ExtractionInfo["extraction"] = extraction
+	return nil
+}
+
+// RateExtraction describes an extraction done outside EMBA (unblob, binwalk)
+// that unpacked the given number of files
+func RateExtraction(files int) *Extraction {
+	return &Extraction{
+		Quality:              extractionQuality(-1, files, false),
+		ExtractedFiles:       files,
+		EncryptionIndicators: []string{},
+	}
+}
+
+// extractionQuality rates an extraction. files is -1 when unknown.
+func extractionQuality(entropy float64, files int, encryptionReported bool) models.ExtractionQuality {
+	encrypted := encryptionReported || entropy >= encryptedEntropy
+	switch {
+	case files < 0:
+		if encryptionReported {
+			return models.ExtractionEncrypted
+		}
+		return models.ExtractionUnknown
+	case files == 0 && encrypted:
+		return models.ExtractionEncrypted
+	case files == 0:
+		return models.ExtractionFailed
+	case files < partialExtractionFiles:
+		return models.ExtractionPartial
+	}
+	return models.ExtractionGood
+}
+
+// countExtractedFiles counts the regular files below dir, or returns -1
+// when there is no such directory
+func countExtractedFiles(dir string) int {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return -1
+	}
+	count := 0
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
@@ -0,0 +1,123 @@
+package emba
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// FindingFirmwareDiff is the type of the findings of EMBA's diff mode: a
+// file added, removed or changed between the two firmware images
+const FindingFirmwareDiff models.FindingType = "firmware_diff"
+
+// Changes between the two firmware images of a diff scan
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+var (
+	// diff -rq output of the extracted file systems
+	diffFilesRegex  = regexp.MustCompile(`^Files (\S+) and (\S+) differ$`)
+	diffOnlyInRegex = regexp.MustCompile(`^Only in (\S+?):\s+(.+)$`)
+	// EMBA's own summary lines, e.g. "[+] Changed file: /bin/busybox"
+	diffChangeRegex = regexp.MustCompile(`(?i)\b(added|new|removed|deleted|changed|modified)\s+(?:file|binary)\s*:\s*(\S+)`)
+)
+
+// diffChangeNames maps the words EMBA uses to the recorded change
+var diffChangeNames = map[string]string{
+	"added":    DiffAdded,
+	"new":      DiffAdded,
+	"removed":  DiffRemoved,
+	"deleted":  DiffRemoved,
+	"changed":  DiffChanged,
+	"modified": DiffChanged,
+}
+
+// parseFirmwareDiff parses the output of EMBA's diff mode (-o, D modules)
+// into a finding per file added, removed or changed in the second image
+func (s *Service) parseFirmwareDiff(logDir string, results *ParsedResults) error {
+	files, err := results.layout.ModuleLogs(logDir, "D*_firmware_diffing*")
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Error reading firmware diff %s: %v", file, err)
+			continue
+		}
+
+		// The roots of the two extracted images, learned from the files
+		// diff reports as differing; "Only in" lines are placed by them
+		var baseRoot, targetRoot string
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+
+			var change, filePath string
+			if matches := diffFilesRegex.FindStringSubmatch(line); matches != nil {
+				change = DiffChanged
+				filePath, baseRoot, targetRoot = splitDiffRoots(matches[1], matches[2])
+			} else if matches := diffOnlyInRegex.FindStringSubmatch(line); matches != nil {
+				dir := matches[1]
+				switch {
+				case targetRoot != "" && strings.HasPrefix(dir, targetRoot):
+					change, filePath = DiffAdded, path.Join("/", strings.TrimPrefix(dir, targetRoot), matches[2])
+				case baseRoot != "" && strings.HasPrefix(dir, baseRoot):
+					change, filePath = DiffRemoved, path.Join("/", strings.TrimPrefix(dir, baseRoot), matches[2])
+				default:
+					continue // neither image is known yet
+				}
+			} else if matches := diffChangeRegex.FindStringSubmatch(line); matches != nil {
+				change, filePath = diffChangeNames[strings.ToLower(matches[1])], matches[2]
+			} else {
+				continue
+			}
+
+			key := change + " " + filePath
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			results.Findings = append(results.Findings, models.Finding{
+				Type:        FindingFirmwareDiff,
+				Title:       fmt.Sprintf("File %s: %s", change, filePath),
+				Description: line,
+				Severity:    models.RiskInfo,
+				FilePath:    filePath,
+				FindingMetadata: encodeMetadata(map[string]interface{}{
+					"source":   "firmware_diff",
+					"change":   change,
+					"log_file": file,
+				}),
+			})
+		}
+	}
+
+	return nil
+}
+
+// splitDiffRoots splits the paths of a file in both extracted images into
+// the path within the firmware and the two image roots
+func splitDiffRoots(base, target string) (filePath, baseRoot, targetRoot string) {
+	a, b := strings.Split(base, "/"), strings.Split(target, "/")
+	n := 0
+	for n < len(a)-1 && n < len(b)-1 && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	// diff only compares files of the same name
+	if n == 0 {
+		n = 1
+	}
+	filePath = "/" + strings.Join(a[len(a)-n:], "/")
+	return filePath, strings.Join(a[:len(a)-n], "/"), strings.Join(b[:len(b)-n], "/")
+}
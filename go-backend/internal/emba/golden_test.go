@@ -0,0 +1,106 @@
+package emba
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"odin-backend/internal/config"
+)
+
+// Run with -update to rewrite the golden files after an intended parser change
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestGolden parses every log directory in testdata/golden/<case>/logs and
+// compares the results with testdata/golden/<case>/expected.json. Add cases
+// by copying the log directory of a real scan.
+func TestGolden(t *testing.T) {
+	cases, err := filepath.Glob(filepath.Join("testdata", "golden", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no golden cases in testdata/golden")
+	}
+
+	service := New(&config.Config{})
+	for _, dir := range cases {
+		dir := dir
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			logDir, err := filepath.Abs(filepath.Join(dir, "logs"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := service.ParseLogDir(logDir, "")
+			if err != nil {
+				t.Fatalf("ParseLogDir: %v", err)
+			}
+
+			got := goldenJSON(t, logDir, result)
+			expectedFile := filepath.Join(dir, "expected.json")
+			if *update {
+				if err := os.WriteFile(expectedFile, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(expectedFile)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, expected) {
+				t.Errorf("parser output differs from %s (run go test -update if intended):\n%s", expectedFile, lineDiff(string(expected), string(got)))
+			}
+		})
+	}
+}
+
+// goldenJSON renders the parts of a result that don't depend on when and
+// where the test runs
+func goldenJSON(t *testing.T, logDir string, result *AnalysisResult) []byte {
+	t.Helper()
+
+	results := result.Results
+	summary := make(map[string]interface{})
+	for key, value := range results.Summary {
+		if key != "analysis_time" && key != "log_directory" {
+			summary[key] = value
+		}
+	}
+	results.Summary = summary
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"emba_version":  result.EMBAVersion,
+		"parser_layout": result.ParserLayout,
+		"results":       results,
+	}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.ReplaceAll(data, []byte(logDir), []byte("$LOGDIR"))
+	return append(data, '\n')
+}
+
+// lineDiff lists the lines that differ between two outputs
+func lineDiff(expected, got string) string {
+	expectedLines, gotLines := strings.Split(expected, "\n"), strings.Split(got, "\n")
+	var diff strings.Builder
+	for i := 0; i < len(expectedLines) || i < len(gotLines); i++ {
+		var e, g string
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if e != g {
+			diff.WriteString("- " + e + "\n+ " + g + "\n")
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,355 @@
+package emba
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// Peripheral categories in the hardware inventory
+const (
+	PeripheralUART     = "uart"
+	PeripheralJTAG     = "jtag"
+	PeripheralSPI      = "spi"
+	PeripheralSPIFlash = "spi_flash"
+	PeripheralNAND     = "nand_flash"
+	PeripheralRadio    = "radio"
+	PeripheralUSB      = "usb"
+	PeripheralI2C      = "i2c"
+	PeripheralEthernet = "ethernet"
+)
+
+// Peripheral is a hardware interface found in a device tree or kernel config
+type Peripheral struct {
+	Category   string `json:"category"`
+	Name       string `json:"name"`
+	Compatible string `json:"compatible,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Status     string `json:"status"` // okay, disabled or built-in for kernel config options
+	Source     string `json:"source"`
+}
+
+// HardwareInventory lists the peripherals described by the firmware
+type HardwareInventory struct {
+	Peripherals   []Peripheral   `json:"peripherals"`
+	Counts        map[string]int `json:"counts"`
+	DeviceTrees   []string       `json:"device_trees"`
+	KernelConfigs []string       `json:"kernel_configs"`
+}
+
+const (
+	fdtMagic        = 0xd00dfeed
+	fdtBeginNode    = 0x1
+	fdtEndNode      = 0x2
+	fdtProp         = 0x3
+	fdtNop          = 0x4
+	fdtEnd          = 0x9
+	maxHardwareFile = 16 << 20
+)
+
+var kernelConfigRegex = regexp.MustCompile(`^(CONFIG_[A-Z0-9_]+)=([ym])$`)
+
+// peripheralRules classify device tree nodes by node name or compatible string
+var peripheralRules = []struct {
+	category string
+	keywords []string
+}{
+	{PeripheralJTAG, []string{"jtag", "coresight", "swd", "debug-port"}},
+	{PeripheralSPIFlash, []string{"jedec,spi-nor", "spi-nor", "m25p", "mx25", "w25q", "s25fl", "spi-flash"}},
+	{PeripheralNAND, []string{"nand"}},
+	{PeripheralRadio, []string{"wifi", "wlan", "bluetooth", "ath9k", "ath10k", "ath11k", "mt76", "brcmfmac", "bcm43", "qca,", "modem", "zigbee", "lora", "802154", "nfc"}},
+	{PeripheralUART, []string{"serial", "uart", "ns16550", "pl011"}},
+	{PeripheralSPI, []string{"spi"}},
+	{PeripheralUSB, []string{"usb", "ehci", "ohci", "xhci", "dwc2", "dwc3"}},
+	{PeripheralI2C, []string{"i2c"}},
+	{PeripheralEthernet, []string{"ethernet", "gmac", "stmmac", "mdio"}},
+}
+
+// kernelConfigPeripherals maps kernel options to the peripheral they enable
+var kernelConfigPeripherals = map[string]string{
+	"CONFIG_SERIAL_8250":         PeripheralUART,
+	"CONFIG_SERIAL_AMBA_PL011":   PeripheralUART,
+	"CONFIG_SERIAL_CORE":         PeripheralUART,
+	"CONFIG_MTD_SPI_NOR":         PeripheralSPIFlash,
+	"CONFIG_MTD_M25P80":          PeripheralSPIFlash,
+	"CONFIG_MTD_NAND":            PeripheralNAND,
+	"CONFIG_MTD_RAW_NAND":        PeripheralNAND,
+	"CONFIG_SPI":                 PeripheralSPI,
+	"CONFIG_CFG80211":            PeripheralRadio,
+	"CONFIG_MAC80211":            PeripheralRadio,
+	"CONFIG_BT":                  PeripheralRadio,
+	"CONFIG_ATH9K":               PeripheralRadio,
+	"CONFIG_ATH10K":              PeripheralRadio,
+	"CONFIG_IEEE802154":          PeripheralRadio,
+	"CONFIG_USB":                 PeripheralUSB,
+	"CONFIG_USB_GADGET":          PeripheralUSB,
+	"CONFIG_I2C":                 PeripheralI2C,
+	"CONFIG_CORESIGHT":           PeripheralJTAG,
+	"CONFIG_ARM_AMBA_JTAG":       PeripheralJTAG,
+	"CONFIG_JTAG":                PeripheralJTAG,
+	"CONFIG_KGDB_SERIAL_CONSOLE": PeripheralUART,
+}
+
+// parseHardwareInventory builds a peripheral inventory from device tree blobs
+// and kernel configs in the extracted firmware
+func (s *Service) parseHardwareInventory(logDir string, results *ParsedResults) error {
+	root := filepath.Join(logDir, "firmware")
+	if _, err := os.Stat(root); err != nil {
+		root = logDir
+	}
+
+	inventory := &HardwareInventory{Counts: make(map[string]int)}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxHardwareFile || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		name := strings.ToLower(d.Name())
+		switch {
+		case strings.HasSuffix(name, ".dtb"), strings.HasSuffix(name, ".dtbo"):
+			peripherals, err := parseDeviceTreeBlob(path)
+			if err != nil {
+				log.Printf("Error parsing device tree %s: %v", path, err)
+				return nil
+			}
+			inventory.DeviceTrees = append(inventory.DeviceTrees, path)
+			inventory.Peripherals = append(inventory.Peripherals, peripherals...)
+		case name == ".config" || strings.HasPrefix(name, "config-") || name == "kconfig" || strings.HasSuffix(name, "_defconfig"):
+			peripherals, ok := parseKernelConfig(path)
+			if !ok {
+				return nil
+			}
+			inventory.KernelConfigs = append(inventory.KernelConfigs, path)
+			inventory.Peripherals = append(inventory.Peripherals, peripherals...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(inventory.DeviceTrees) == 0 && len(inventory.KernelConfigs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(inventory.Peripherals, func(i, j int) bool {
+		return inventory.Peripherals[i].Category < inventory.Peripherals[j].Category
+	})
+	for _, peripheral := range inventory.Peripherals {
+		if peripheral.Status != "disabled" {
+			inventory.Counts[peripheral.Category]++
+		}
+	}
+
+	results.FileInfo["hardware"] = inventory
+	results.Findings = append(results.Findings, s.hardwareFindings(inventory)...)
+
+	return nil
+}
+
+// hardwareFindings flags debug interfaces left enabled in the device tree
+func (s *Service) hardwareFindings(inventory *HardwareInventory) []models.Finding {
+	var findings []models.Finding
+	for _, peripheral := range inventory.Peripherals {
+		// Only device tree nodes; a driver built into the kernel doesn't mean the port is wired up
+		if peripheral.Category != PeripheralJTAG || peripheral.Status == "disabled" || peripheral.Compatible == "" {
+			continue
+		}
+		findings = append(findings, models.Finding{
+			Type:        models.FindingType("hardware_interface"),
+			Title:       "Hardware debug interface enabled",
+			Description: fmt.Sprintf("%s (%s) is enabled, which may give an attacker with physical access debug control over the SoC", peripheral.Name, peripheral.Compatible),
+			Severity:    models.RiskLevel("medium"),
+			FilePath:    peripheral.Source,
+			Content:     peripheral.Name,
+			FindingMetadata: encodeMetadata(map[string]interface{}{
+				"source":   "hardware_inventory",
+				"category": peripheral.Category,
+				"address":  peripheral.Address,
+			}),
+		})
+	}
+	return findings
+}
+
+// parseDeviceTreeBlob walks the structure block of a flattened device tree
+// and classifies its nodes as peripherals
+func parseDeviceTreeBlob(path string) ([]Peripheral, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 40 || binary.BigEndian.Uint32(data[0:4]) != fdtMagic {
+		return nil, fmt.Errorf("not a flattened device tree")
+	}
+
+	structOffset := binary.BigEndian.Uint32(data[8:12])
+	stringsOffset := binary.BigEndian.Uint32(data[12:16])
+	if int(structOffset) >= len(data) || int(stringsOffset) >= len(data) {
+		return nil, fmt.Errorf("corrupt device tree header")
+	}
+
+	type node struct {
+		name       string
+		compatible string
+		status     string
+	}
+
+	var peripherals []Peripheral
+	var stack []*node
+	offset := int(structOffset)
+
+	readString := func(at int) string {
+		if at < 0 || at >= len(data) {
+			return ""
+		}
+		end := bytes.IndexByte(data[at:], 0)
+		if end < 0 {
+			return ""
+		}
+		return string(data[at : at+end])
+	}
+	align := func(n int) int { return (n + 3) &^ 3 }
+
+	for offset+4 <= len(data) {
+		token := binary.BigEndian.Uint32(data[offset : offset+4])
+		offset += 4
+
+		switch token {
+		case fdtBeginNode:
+			name := readString(offset)
+			offset = align(offset + len(name) + 1)
+			stack = append(stack, &node{name: name, status: "okay"})
+		case fdtEndNode:
+			if len(stack) == 0 {
+				return peripherals, nil
+			}
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if category := classifyPeripheral(current.name, current.compatible); category != "" {
+				name, address := current.name, ""
+				if i := strings.IndexByte(name, '@'); i >= 0 {
+					name, address = name[:i], name[i+1:]
+				}
+				peripherals = append(peripherals, Peripheral{
+					Category:   category,
+					Name:       name,
+					Compatible: current.compatible,
+					Address:    address,
+					Status:     current.status,
+					Source:     path,
+				})
+			}
+		case fdtProp:
+			if offset+8 > len(data) {
+				return peripherals, nil
+			}
+			length := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+			nameOffset := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+			offset += 8
+			if length < 0 || offset+length > len(data) {
+				return peripherals, nil
+			}
+			value := data[offset : offset+length]
+			offset = align(offset + length)
+
+			if len(stack) == 0 {
+				continue
+			}
+			current := stack[len(stack)-1]
+			switch readString(int(stringsOffset) + nameOffset) {
+			case "compatible":
+				// A list of NUL separated strings, most specific first
+				current.compatible = strings.Join(strings.FieldsFunc(string(value), func(r rune) bool { return r == 0 }), ",")
+			case "status":
+				current.status = strings.TrimRight(string(value), "\x00")
+			}
+		case fdtNop:
+		case fdtEnd:
+			return peripherals, nil
+		default:
+			return peripherals, fmt.Errorf("unexpected device tree token 0x%x", token)
+		}
+	}
+
+	return peripherals, nil
+}
+
+// classifyPeripheral maps a device tree node to a peripheral category, or ""
+func classifyPeripheral(nodeName, compatible string) string {
+	name := strings.ToLower(nodeName)
+	if i := strings.IndexByte(name, '@'); i >= 0 {
+		name = name[:i]
+	}
+	compatible = strings.ToLower(compatible)
+	if compatible == "" && name != "flash" {
+		return ""
+	}
+
+	for _, rule := range peripheralRules {
+		for _, keyword := range rule.keywords {
+			if strings.Contains(compatible, keyword) || strings.Contains(name, keyword) {
+				return rule.category
+			}
+		}
+	}
+	if name == "flash" {
+		return PeripheralSPIFlash
+	}
+	return ""
+}
+
+// parseKernelConfig reads enabled peripheral drivers from a kernel .config.
+// It returns false when the file doesn't look like a kernel config.
+func parseKernelConfig(path string) ([]Peripheral, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	var peripherals []Peripheral
+	isConfig := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "# Linux/") || strings.HasPrefix(line, "# Automatically generated file") {
+			isConfig = true
+		}
+		matches := kernelConfigRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		isConfig = true
+		if category, ok := kernelConfigPeripherals[matches[1]]; ok {
+			status := "built-in"
+			if matches[2] == "m" {
+				status = "module"
+			}
+			peripherals = append(peripherals, Peripheral{
+				Category: category,
+				Name:     matches[1],
+				Status:   status,
+				Source:   path,
+			})
+		}
+	}
+
+	return peripherals, isConfig
+}
@@ -0,0 +1,213 @@
+package emba
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// dependencyCheckTimeout bounds EMBA's own dependency checker
+	dependencyCheckTimeout = 5 * time.Minute
+	// toolVersionTimeout bounds each external tool's version query
+	toolVersionTimeout = 10 * time.Second
+	// maxDependencyOutput is how much of the checker's output is reported
+	maxDependencyOutput = 16 * 1024
+)
+
+var (
+	ansiRegex        = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	notOKRegex       = regexp.MustCompile(`^\s*(.+?)\s+-\s+not ok`)
+	toolVersionRegex = regexp.MustCompile(`v?(\d+\.\d+(?:\.\d+)?[\w.+~-]*)`)
+)
+
+// tool is an external program EMBA relies on
+type tool struct {
+	name        string
+	commands    []string // alternatives, the first one found is used
+	versionArgs []string
+	required    func(*Service) bool
+}
+
+func always(*Service) bool { return true }
+
+var tools = []tool{
+	{name: "binwalk", commands: []string{"binwalk"}, versionArgs: []string{"--help"}, required: always},
+	{name: "unblob", commands: []string{"unblob"}, versionArgs: []string{"--version"}},
+	{name: "qemu", commands: []string{"qemu-mips-static", "qemu-arm-static", "qemu-system-mips"}, versionArgs: []string{"--version"},
+		required: func(s *Service) bool { return s.config.EMBAEnableEmulation }},
+	{name: "cwe_checker", commands: []string{"cwe_checker"}, versionArgs: []string{"--version"},
+		required: func(s *Service) bool { return s.config.EMBAEnableCWECheck }},
+	{name: "docker", commands: []string{"docker"}, versionArgs: []string{"--version"}},
+	{name: "sudo", commands: []string{"sudo"}, versionArgs: []string{"--version"},
+		required: func(s *Service) bool { return s.privilegeMode() == PrivilegeSudo }},
+	{name: "systemd-run", commands: []string{"systemd-run"}, versionArgs: []string{"--version"},
+		required: func(s *Service) bool { return s.privilegeMode() == PrivilegeSystemdRun || s.hasCgroupLimits() }},
+}
+
+// ToolStatus reports whether an external tool is installed and its version
+type ToolStatus struct {
+	Name      string `json:"name"`
+	Required  bool   `json:"required"`
+	Available bool   `json:"available"`
+	Path      string `json:"path,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+// DependencyCheck is the outcome of EMBA's dependency checker (emba -d)
+type DependencyCheck struct {
+	Passed  bool     `json:"passed"`
+	Missing []string `json:"missing"`
+	Error   string   `json:"error,omitempty"`
+	Output  string   `json:"output,omitempty"` // tail of the checker's output
+
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Health describes whether this host can run EMBA analyses
+type Health struct {
+	Healthy         bool             `json:"healthy"`
+	ScriptFound     bool             `json:"script_found"`
+	EMBAVersion     string           `json:"emba_version"`
+	PrivilegeMode   string           `json:"privilege_mode"`
+	Tools           []ToolStatus     `json:"tools"`
+	Missing         []string         `json:"missing"` // required tools and failed dependency checks
+	DependencyCheck *DependencyCheck `json:"dependency_check,omitempty"`
+	CheckedAt       time.Time        `json:"checked_at"`
+}
+
+// healthCache keeps the last health report and dependency check, which are
+// too slow to repeat per request
+type healthCache struct {
+	mu              sync.Mutex
+	health          *Health
+	dependencyCheck *DependencyCheck
+}
+
+// Health checks the EMBA installation and external tools. With
+// dependencyCheck it also runs EMBA's dependency checker, which takes
+// minutes; otherwise the last checker result is reported.
+func (s *Service) Health(ctx context.Context, dependencyCheck bool) *Health {
+	health := &Health{
+		ScriptFound:   s.IsAvailable(),
+		EMBAVersion:   embaVersion(s.config.EMBAPath),
+		PrivilegeMode: s.privilegeMode(),
+		Missing:       []string{},
+		CheckedAt:     time.Now().UTC(),
+	}
+	if !health.ScriptFound {
+		health.Missing = append(health.Missing, "emba")
+	}
+
+	for _, t := range tools {
+		status := s.checkTool(ctx, t)
+		health.Tools = append(health.Tools, status)
+		if status.Required && !status.Available {
+			health.Missing = append(health.Missing, status.Name)
+		}
+	}
+	cveSearch := filepath.Join(s.config.EMBAPath, "external", "cve-search")
+	if _, err := os.Stat(cveSearch); err == nil {
+		health.Tools = append(health.Tools, ToolStatus{Name: "cve-search", Available: true, Path: cveSearch})
+	} else {
+		health.Tools = append(health.Tools, ToolStatus{Name: "cve-search"})
+	}
+
+	var check *DependencyCheck
+	if dependencyCheck && health.ScriptFound {
+		check = s.runDependencyCheck(ctx)
+	}
+
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	if check != nil {
+		s.health.dependencyCheck = check
+	}
+	if health.DependencyCheck = s.health.dependencyCheck; health.DependencyCheck != nil {
+		health.Missing = append(health.Missing, health.DependencyCheck.Missing...)
+	}
+	health.Healthy = len(health.Missing) == 0 &&
+		(health.DependencyCheck == nil || health.DependencyCheck.Passed)
+	s.health.health = health
+	return health
+}
+
+// CachedHealth returns the last health report if it is younger than maxAge,
+// otherwise it runs the quick checks
+func (s *Service) CachedHealth(ctx context.Context, maxAge time.Duration) *Health {
+	s.health.mu.Lock()
+	health := s.health.health
+	s.health.mu.Unlock()
+	if health != nil && time.Since(health.CheckedAt) < maxAge {
+		return health
+	}
+	return s.Health(ctx, false)
+}
+
+func (s *Service) checkTool(ctx context.Context, t tool) ToolStatus {
+	status := ToolStatus{Name: t.name, Required: t.required != nil && t.required(s)}
+	for _, command := range t.commands {
+		path, err := exec.LookPath(command)
+		if err != nil {
+			continue
+		}
+		status.Available = true
+		status.Path = path
+		status.Version = toolVersion(ctx, path, t.versionArgs)
+		break
+	}
+	return status
+}
+
+// toolVersion returns the first version number a tool prints
+func toolVersion(ctx context.Context, path string, args []string) string {
+	ctx, cancel := context.WithTimeout(ctx, toolVersionTimeout)
+	defer cancel()
+	output, _ := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	for _, line := range strings.Split(string(output), "\n") {
+		if match := toolVersionRegex.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// runDependencyCheck runs "emba -d 2", which checks the host's dependencies
+// and reports each one as "ok" or "not ok"
+func (s *Service) runDependencyCheck(ctx context.Context) *DependencyCheck {
+	ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+	defer cancel()
+
+	check := &DependencyCheck{Missing: []string{}, CheckedAt: time.Now().UTC()}
+	cmd, err := s.command(ctx, []string{filepath.Join(s.config.EMBAPath, "emba"), "-d", "2"}, "dependency-check")
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	output, runErr := cmd.CombinedOutput()
+	clean := ansiRegex.ReplaceAllString(string(output), "")
+
+	for _, line := range strings.Split(clean, "\n") {
+		if match := notOKRegex.FindStringSubmatch(line); match != nil {
+			check.Missing = append(check.Missing, strings.TrimSpace(match[1]))
+		}
+	}
+	if len(clean) > maxDependencyOutput {
+		clean = clean[len(clean)-maxDependencyOutput:]
+	}
+	check.Output = clean
+
+	switch {
+	case ctx.Err() != nil:
+		check.Error = "dependency check timed out"
+	case runErr != nil && len(check.Missing) == 0:
+		check.Error = runErr.Error()
+	}
+	check.Passed = check.Error == "" && len(check.Missing) == 0
+	return check
+}
@@ -1,10 +1,20 @@
 package emba
 
 import (
+	"encoding/json"
 	"regexp"
 	"strings"
 )
 
+// encodeMetadata serializes finding metadata into the JSON text stored on models.Finding
+func encodeMetadata(metadata map[string]interface{}) string {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
 // Helper methods for Service struct
 func (s *Service) extractValue(line, prefix string) string {
 	if strings.Contains(line, prefix) {
@@ -0,0 +1,95 @@
+package emba
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// embaLogVersionRegex finds the version EMBA prints at the start of emba.log
+var embaLogVersionRegex = regexp.MustCompile(`(?i)\bEMBA\b[^\n]*?\bversion\b[^\d\n]*(\d+\.\d+(?:\.\d+)?[\w.\-]*)`)
+
+// ParseLogDir parses the log directory of an EMBA run that already
+// finished, e.g. a past scan or one from another system, without running
+// EMBA. layoutName picks the output layout; empty detects it.
+func (s *Service) ParseLogDir(logDir, layoutName string) (*AnalysisResult, error) {
+	info, err := os.Stat(logDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", logDir)
+	}
+
+	version := logDirVersion(logDir)
+	var layout Layout
+	switch {
+	case layoutName != "":
+		if layout = LayoutByName(layoutName); layout == nil {
+			return nil, fmt.Errorf("unknown parser layout %q", layoutName)
+		}
+	case version != "":
+		layout, _ = LayoutFor(version)
+	default:
+		layout = DetectLayout(logDir)
+	}
+
+	results, err := s.parseEMBAResults(logDir, filepath.Base(logDir), layout)
+	if err != nil {
+		return nil, err
+	}
+	// The summary describes the installed EMBA, not the one that wrote the logs
+	if version == "" {
+		version = "unknown"
+	}
+	results.Summary["emba_version"] = version
+
+	return &AnalysisResult{
+		Success:      true,
+		LogDir:       logDir,
+		AnalysisTime: time.Now().UTC().Format(time.RFC3339),
+		Results:      *results,
+		EMBAVersion:  version,
+		ParserLayout: layout.Name(),
+	}, nil
+}
+
+// DetectLayout guesses the output layout of a log directory whose EMBA
+// version is unknown: only EMBA 1.0 and later write csv_logs/ and SBOM/
+// and lower case module logs
+func DetectLayout(logDir string) Layout {
+	for _, dir := range []string{"csv_logs", "SBOM"} {
+		if info, err := os.Stat(filepath.Join(logDir, dir)); err == nil && info.IsDir() {
+			return currentLayout{}
+		}
+	}
+	if files, _ := moduleLogs(logDir, []string{"[a-z][0-9]*_*"}); len(files) > 0 {
+		return currentLayout{}
+	}
+	if files, _ := moduleLogs(logDir, []string{"[A-Z][0-9]*_*"}); len(files) > 0 {
+		return legacyLayout{}
+	}
+	return layouts[len(layouts)-1]
+}
+
+// logDirVersion returns the EMBA version emba.log names, or ""
+func logDirVersion(logDir string) string {
+	file, err := os.Open(filepath.Join(logDir, "emba.log"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	head, err := io.ReadAll(io.LimitReader(file, 64*1024))
+	if err != nil {
+		return ""
+	}
+	matches := embaLogVersionRegex.FindSubmatch([]byte(ansiRegex.ReplaceAllString(string(head), "")))
+	if matches == nil {
+		return ""
+	}
+	return string(matches[1])
+}
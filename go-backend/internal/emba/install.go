@@ -0,0 +1,135 @@
+package emba
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Installer modes, mapped to EMBA's installer.sh flags
+const (
+	InstallModeDefault = "default" // -d: dependencies for running EMBA with its docker image
+	InstallModeHost    = "host"    // -F: all dependencies on the host, no docker
+)
+
+var installModeFlags = map[string]string{
+	InstallModeDefault: "-d",
+	InstallModeHost:    "-F",
+}
+
+// Installation steps reported to the progress callback
+const (
+	InstallStepClone     = "clone"
+	InstallStepFetch     = "fetch"
+	InstallStepCheckout  = "checkout"
+	InstallStepInstaller = "installer"
+)
+
+const (
+	// installTimeout bounds a whole installation; the installer downloads gigabytes
+	installTimeout = 3 * time.Hour
+	// installOutputKB is how much installer output progress reports carry
+	installOutputKB = 16
+)
+
+// gitRefRegex matches tags, branches and commits, keeping options out of git's argv
+var gitRefRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// InstallProgress receives the current step and the tail of its output
+type InstallProgress func(step, output string)
+
+// ValidateInstall checks an installation request before it is queued
+func ValidateInstall(ref, mode string) error {
+	if !gitRefRegex.MatchString(ref) || strings.Contains(ref, "..") {
+		return fmt.Errorf("invalid EMBA version %q", ref)
+	}
+	if _, ok := installModeFlags[mode]; !ok {
+		return fmt.Errorf("invalid install mode %q: must be %s or %s", mode, InstallModeDefault, InstallModeHost)
+	}
+	return nil
+}
+
+// Install clones EMBA into EMBA_PATH, or updates an existing checkout,
+// checks out the pinned ref and runs EMBA's installer in the given mode.
+// The installer runs with the configured privilege mode since it installs
+// system packages.
+func (s *Service) Install(ctx context.Context, ref, mode string, progress InstallProgress) error {
+	if err := ValidateInstall(ref, mode); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, installTimeout)
+	defer cancel()
+
+	path := s.config.EMBAPath
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		if err := s.installStep(ctx, InstallStepFetch, progress, "git", "-C", path, "fetch", "--tags", "--force", "origin"); err != nil {
+			return err
+		}
+	} else {
+		if entries, err := os.ReadDir(path); err == nil && len(entries) > 0 {
+			return fmt.Errorf("%s exists but is not a git checkout of EMBA", path)
+		}
+		if err := s.installStep(ctx, InstallStepClone, progress, "git", "clone", s.config.EMBARepositoryURL, path); err != nil {
+			return err
+		}
+	}
+
+	if err := s.installStep(ctx, InstallStepCheckout, progress, "git", "-C", path, "checkout", "--force", "--detach", ref); err != nil {
+		return err
+	}
+
+	cmd, err := s.command(ctx, []string{"./installer.sh", installModeFlags[mode]}, "install")
+	if err != nil {
+		return err
+	}
+	// The installer asks for confirmation before installing packages
+	cmd.Stdin = strings.NewReader(strings.Repeat("y\n", 100))
+	return runInstallCommand(ctx, cmd, InstallStepInstaller, progress)
+}
+
+func (s *Service) installStep(ctx context.Context, step string, progress InstallProgress, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	return runInstallCommand(ctx, cmd, step, progress)
+}
+
+// runInstallCommand runs one installation step, reporting its output as it arrives
+func runInstallCommand(ctx context.Context, cmd *exec.Cmd, step string, progress InstallProgress) error {
+	tail := newTailBuffer(installOutputKB * 1024)
+	var output io.Writer = tail
+	if progress != nil {
+		progress(step, "")
+		output = progressWriter{tail: tail, report: func() { progress(step, tail.String()) }}
+	}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("EMBA installation %s step stopped: %w", step, ctx.Err())
+		}
+		return fmt.Errorf("EMBA installation %s step failed: %w", step, err)
+	}
+	if progress != nil {
+		progress(step, tail.String())
+	}
+	return nil
+}
+
+// progressWriter reports the output tail after every write
+type progressWriter struct {
+	tail   *tailBuffer
+	report func()
+}
+
+func (w progressWriter) Write(p []byte) (int, error) {
+	n, err := w.tail.Write(p)
+	w.report()
+	return n, err
+}
@@ -0,0 +1,272 @@
+package emba
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+)
+
+// KernelInfo describes the Linux kernel of the firmware from EMBA's kernel
+// modules: S24 identifies the kernel binary, S25 checks its configuration
+// against kernel-hardening-checker and S26 verifies kernel CVEs against the
+// sources and config
+type KernelInfo struct {
+	Version      string              `json:"version"`
+	EOL          bool                `json:"eol"`
+	EOLDate      string              `json:"eol_date,omitempty"` // end of life of the kernel's stable branch
+	Checks       []KernelConfigCheck `json:"hardening_checks"`
+	FailedChecks int                 `json:"failed_checks"`
+	CVEs         []string            `json:"cves"`          // kernel CVEs S26 looked at
+	VerifiedCVEs []string            `json:"verified_cves"` // those confirmed by the sources or config
+	SourceFiles  []string            `json:"source_files"`
+}
+
+// KernelConfigCheck is one kernel hardening recommendation
+type KernelConfigCheck struct {
+	Option  string `json:"option"` // e.g. CONFIG_STRICT_DEVMEM
+	Type    string `json:"type"`   // kconfig, cmdline or sysctl
+	Desired string `json:"desired"`
+	Reason  string `json:"reason,omitempty"` // e.g. self_protection
+	Passed  bool   `json:"passed"`
+	Detail  string `json:"detail,omitempty"` // why it failed, e.g. is not set
+	Line    int    `json:"-"`
+}
+
+var (
+	kernelVersionRegex = regexp.MustCompile(`(?i)(?:kernel|linux)\s+version[:\s]+v?(\d+\.\d+(?:\.\d+)?)`)
+	// kernel-hardening-checker output, e.g.
+	// CONFIG_BUG |kconfig| y |defconfig | self_protection | OK
+	kernelCheckRegex    = regexp.MustCompile(`^\s*([^\s|]+)\s*\|\s*(kconfig|cmdline|sysctl)\s*\|\s*([^|]*?)\s*\|\s*([^|]*?)\s*\|\s*([^|]*?)\s*\|\s*(OK|FAIL)(?::\s*"?([^"]*)"?)?`)
+	insecureOptionRegex = regexp.MustCompile(`(?i)insecure kernel setting.*?\b(CONFIG_[A-Z0-9_]+)=(\S+)`)
+	kernelCVERegex      = regexp.MustCompile(`CVE-\d{4}-\d{4,}`)
+	unverifiedRegex     = regexp.MustCompile(`(?i)\b(not verified|unverified)\b`)
+)
+
+// kernelEOL lists the end of life of Linux stable branches that were
+// maintained as long-term releases, from kernel.org. Other branches are
+// only maintained until the next release.
+var kernelEOL = []struct {
+	series string
+	eol    string
+}{
+	{"2.6", "2016-03-12"},
+	{"3.0", "2013-10-22"},
+	{"3.2", "2018-06-01"},
+	{"3.4", "2016-10-26"},
+	{"3.10", "2017-11-04"},
+	{"3.12", "2017-05-09"},
+	{"3.14", "2016-09-11"},
+	{"3.16", "2020-06-11"},
+	{"3.18", "2017-02-08"},
+	{"4.1", "2018-05-28"},
+	{"4.4", "2022-02-03"},
+	{"4.9", "2023-01-07"},
+	{"4.14", "2024-01-10"},
+	{"4.19", "2024-12-05"},
+	{"5.4", "2025-12-31"},
+	{"5.10", "2026-12-31"},
+	{"5.15", "2026-12-31"},
+	{"6.1", "2027-12-31"},
+	{"6.6", "2026-12-31"},
+	{"6.12", "2026-12-31"},
+}
+
+// KernelEOL reports whether a kernel version's stable branch reached its
+// end of life by now, and the date when it's known. Versions newer than
+// every long-term branch count as maintained.
+func KernelEOL(version string, now time.Time) (bool, string) {
+	v, ok := ParseVersion(version)
+	if !ok {
+		return false, ""
+	}
+
+	newest := Version{}
+	for _, branch := range kernelEOL {
+		b, _ := ParseVersion(branch.series)
+		if b.Major == v.Major && b.Minor == v.Minor {
+			eol, err := time.Parse("2006-01-02", branch.eol)
+			return err == nil && !now.Before(eol), branch.eol
+		}
+		if b.Major > newest.Major || b.Major == newest.Major && b.Minor > newest.Minor {
+			newest = b
+		}
+	}
+	return v.Major < newest.Major || v.Major == newest.Major && v.Minor < newest.Minor, ""
+}
+
+// parseKernel extracts the kernel version, hardening checks and verified
+// kernel CVEs from the S24, S25 and S26 logs into FirmwareInfo and raises
+// findings for an end-of-life kernel and missing hardening
+func (s *Service) parseKernel(logDir string, results *ParsedResults) error {
+	info := &KernelInfo{Checks: []KernelConfigCheck{}, CVEs: []string{}, VerifiedCVEs: []string{}}
+	cves := make(map[string]bool)
+	verified := make(map[string]bool)
+	checked := make(map[string]bool)
+	var checksFile, versionFile string
+	versionLine := 0
+
+	for _, pattern := range []string{"S24_*", "S25_*", "S26_*"} {
+		files, err := results.layout.ModuleLogs(logDir, pattern)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				log.Printf("Error reading kernel log %s: %v", file, err)
+				continue
+			}
+
+			used := false
+			for i, line := range strings.Split(string(content), "\n") {
+				line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+				if line == "" {
+					continue
+				}
+
+				if matches := kernelVersionRegex.FindStringSubmatch(line); matches != nil && info.Version == "" {
+					info.Version = matches[1]
+					versionFile, versionLine, used = file, i+1, true
+				}
+				if matches := kernelCheckRegex.FindStringSubmatch(line); matches != nil && !checked[matches[1]] {
+					checked[matches[1]] = true
+					info.Checks = append(info.Checks, KernelConfigCheck{
+						Option:  matches[1],
+						Type:    matches[2],
+						Desired: matches[3],
+						Reason:  matches[5],
+						Passed:  matches[6] == "OK",
+						Detail:  strings.TrimSpace(matches[7]),
+						Line:    i + 1,
+					})
+					checksFile, used = file, true
+				} else if matches := insecureOptionRegex.FindStringSubmatch(line); matches != nil && !checked[matches[1]] {
+					// S25's own summary of an option the checker may have listed already
+					checked[matches[1]] = true
+					info.Checks = append(info.Checks, KernelConfigCheck{
+						Option: matches[1],
+						Type:   "kconfig",
+						Passed: false,
+						Detail: "set to " + matches[2],
+						Line:   i + 1,
+					})
+					checksFile, used = file, true
+				}
+				if strings.EqualFold(moduleID(filepath.Base(file)), "S26") {
+					for _, cve := range kernelCVERegex.FindAllString(line, -1) {
+						cves[cve] = true
+						if strings.Contains(strings.ToLower(line), "verified") && !unverifiedRegex.MatchString(line) {
+							verified[cve] = true
+						}
+						used = true
+					}
+				}
+			}
+			if used {
+				info.SourceFiles = append(info.SourceFiles, file)
+			}
+		}
+	}
+
+	// F50 reports the kernel version when the kernel logs don't name it
+	if version, ok := results.FileInfo["kernel_version"].(string); ok && info.Version == "" {
+		info.Version = version
+	}
+	if info.Version == "" && len(info.Checks) == 0 && len(cves) == 0 {
+		return nil
+	}
+
+	info.EOL, info.EOLDate = KernelEOL(info.Version, time.Now())
+	for _, check := range info.Checks {
+		if !check.Passed {
+			info.FailedChecks++
+		}
+	}
+	for cve := range cves {
+		info.CVEs = append(info.CVEs, cve)
+	}
+	sort.Strings(info.CVEs)
+	for cve := range verified {
+		info.VerifiedCVEs = append(info.VerifiedCVEs, cve)
+	}
+	sort.Strings(info.VerifiedCVEs)
+
+	results.FileInfo["kernel"] = info
+	results.Findings = append(results.Findings, kernelFindings(info, versionFile, versionLine, checksFile)...)
+
+	return nil
+}
+
+// kernelFindings flags an end-of-life kernel, located at the log line naming
+// its version, and failed hardening checks
+func kernelFindings(info *KernelInfo, versionFile string, versionLine int, checksFile string) []models.Finding {
+	var findings []models.Finding
+
+	if info.EOL {
+		description := fmt.Sprintf("Linux %s belongs to a stable branch that no longer receives security fixes", info.Version)
+		if info.EOLDate != "" {
+			description = fmt.Sprintf("Linux %s belongs to a stable branch that stopped receiving security fixes on %s", info.Version, info.EOLDate)
+		}
+		metadata := map[string]interface{}{
+			"source":         "kernel_analysis",
+			"kernel_version": info.Version,
+			"eol_date":       info.EOLDate,
+		}
+		// F50 names the version when no kernel log does
+		if versionFile != "" {
+			metadata["log_file"] = versionFile
+			metadata["log_line"] = versionLine
+		}
+		findings = append(findings, models.Finding{
+			Type:            models.FindingType("kernel_eol"),
+			Title:           fmt.Sprintf("End-of-life Linux kernel %s", info.Version),
+			Description:     description,
+			Severity:        models.RiskHigh,
+			FilePath:        versionFile,
+			Content:         info.Version,
+			FindingMetadata: encodeMetadata(metadata),
+		})
+	}
+
+	var failed []string
+	firstLine := 0
+	for _, check := range info.Checks {
+		if check.Passed {
+			continue
+		}
+		failed = append(failed, check.Option)
+		if firstLine == 0 {
+			firstLine = check.Line
+		}
+	}
+	if len(failed) > 0 {
+		severity := models.RiskLow
+		if len(failed) > 10 {
+			severity = models.RiskMedium
+		}
+		findings = append(findings, models.Finding{
+			Type:        models.FindingType("kernel_config"),
+			Title:       "Kernel configuration lacks hardening",
+			Description: fmt.Sprintf("%d of %d kernel hardening checks failed", len(failed), len(info.Checks)),
+			Severity:    severity,
+			FilePath:    checksFile,
+			Content:     strings.Join(failed, "\n"),
+			FindingMetadata: encodeMetadata(map[string]interface{}{
+				"source":         "kernel_analysis",
+				"kernel_version": info.Version,
+				"failed_checks":  failed,
+				"log_file":       checksFile,
+				"log_line":       firstLine,
+			}),
+		})
+	}
+
+	return findings
+}
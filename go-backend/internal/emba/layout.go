@@ -0,0 +1,173 @@
+package emba
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is an EMBA release number
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string
+}
+
+var (
+	versionRegex   = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+	moduleLogRegex = regexp.MustCompile(`^[A-Za-z]\d+_`)
+)
+
+// ParseVersion extracts the release number from EMBA's version string,
+// e.g. "1.5.2" or "2.0.0b-4c1a2f". ok is false when there is none.
+func ParseVersion(raw string) (version Version, ok bool) {
+	version.Raw = raw
+	match := versionRegex.FindStringSubmatch(raw)
+	if match == nil {
+		return version, false
+	}
+	version.Major, _ = strconv.Atoi(match[1])
+	version.Minor, _ = strconv.Atoi(match[2])
+	version.Patch, _ = strconv.Atoi(match[3])
+	return version, true
+}
+
+// Layout locates the output of an EMBA release in a run's log directory.
+// EMBA renames and moves its logs between releases; each layout covers a
+// range of versions so the parsers don't silently miss results.
+type Layout interface {
+	Name() string
+	Supports(version Version) bool
+	// ModuleLogs returns the module log files matching a pattern in EMBA's
+	// original naming, e.g. "S115_*" or "S25_kernel_check.txt"
+	ModuleLogs(logDir, pattern string) ([]string, error)
+	// CSVFiles returns the CSV logs written with -g
+	CSVFiles(logDir string) ([]string, error)
+	GrepLog(logDir string) string
+	SBOMFiles(logDir string) []string
+	// AggregatorFiles returns the candidate F50 aggregator outputs, preferred first
+	AggregatorFiles(logDir string) []string
+}
+
+// layouts are tried in order; the last one is the fallback for versions
+// none of them claims
+var layouts = []Layout{legacyLayout{}, currentLayout{}}
+
+// LayoutFor returns the layout for an EMBA version string, and whether one
+// claimed it rather than falling back to the newest
+func LayoutFor(raw string) (Layout, bool) {
+	if version, ok := ParseVersion(raw); ok {
+		for _, layout := range layouts {
+			if layout.Supports(version) {
+				return layout, true
+			}
+		}
+	}
+	return layouts[len(layouts)-1], false
+}
+
+// LayoutByName returns a layout by its name, or nil
+func LayoutByName(name string) Layout {
+	for _, layout := range layouts {
+		if layout.Name() == name {
+			return layout
+		}
+	}
+	return nil
+}
+
+// legacyLayout is EMBA before 1.0: upper case module logs next to each
+// other in the log directory, CSV logs one level below
+type legacyLayout struct{}
+
+func (legacyLayout) Name() string { return "emba-0.x" }
+
+func (legacyLayout) Supports(version Version) bool { return version.Major == 0 }
+
+func (legacyLayout) ModuleLogs(logDir, pattern string) ([]string, error) {
+	return moduleLogs(logDir, []string{pattern})
+}
+
+func (legacyLayout) CSVFiles(logDir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(logDir, "*", "*.csv"))
+}
+
+func (legacyLayout) GrepLog(logDir string) string {
+	return filepath.Join(logDir, "fw_grep.log")
+}
+
+func (legacyLayout) SBOMFiles(logDir string) []string {
+	return []string{
+		filepath.Join(logDir, "sbom.json"),
+		filepath.Join(logDir, "f15_sbom.json"),
+		filepath.Join(logDir, "cyclonedx_sbom.json"),
+	}
+}
+
+func (legacyLayout) AggregatorFiles(logDir string) []string {
+	return []string{filepath.Join(logDir, "csv_logs", "f50_base_aggregator.csv")}
+}
+
+// currentLayout is EMBA 1.0 and later: lower case module logs, CSV logs in
+// csv_logs and the CycloneDX SBOM in SBOM/
+type currentLayout struct{}
+
+func (currentLayout) Name() string { return "emba-1.x" }
+
+func (currentLayout) Supports(version Version) bool { return version.Major >= 1 }
+
+func (currentLayout) ModuleLogs(logDir, pattern string) ([]string, error) {
+	return moduleLogs(logDir, []string{pattern, strings.ToLower(pattern)})
+}
+
+func (currentLayout) CSVFiles(logDir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(logDir, "csv_logs", "*.csv"))
+	if err != nil || len(files) > 0 {
+		return files, err
+	}
+	return legacyLayout{}.CSVFiles(logDir)
+}
+
+func (currentLayout) GrepLog(logDir string) string {
+	return filepath.Join(logDir, "fw_grep.log")
+}
+
+func (currentLayout) SBOMFiles(logDir string) []string {
+	return []string{filepath.Join(logDir, "SBOM", "EMBA_cyclonedx_sbom.json")}
+}
+
+func (currentLayout) AggregatorFiles(logDir string) []string {
+	return []string{
+		filepath.Join(logDir, "json_logs", "f50_base_aggregator.json"),
+		filepath.Join(logDir, "csv_logs", "f50_base_aggregator.csv"),
+	}
+}
+
+// moduleLogs globs the patterns in the log directory, keeping only module
+// log files: "S*" must not pick up directories such as SBOM
+func moduleLogs(logDir string, patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(logDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if seen[match] || !moduleLogRegex.MatchString(filepath.Base(match)) {
+				continue
+			}
+			if info, err := os.Stat(match); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			seen[match] = true
+			files = append(files, match)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
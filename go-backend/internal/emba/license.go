@@ -0,0 +1,243 @@
+package emba
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+var (
+	// F10's license summary, e.g.
+	// [+] Binary: busybox / Version: 1.24.1 / License: GPL-2.0-only
+	licenseLineRegex = regexp.MustCompile(`(?i)binary:\s*([^/]+?)\s*/\s*version:\s*([^/]*?)\s*/\s*license:\s*(.+?)\s*$`)
+	// GPL family names that aren't SPDX IDs, e.g. GPLv2, GPL-2.0+ or LGPL 2.1
+	gplAliasRegex   = regexp.MustCompile(`(?i)^(a|l)?gpl[\s-]*v?(\d)(?:\.(\d))?(\+|-or-later|-only)?$`)
+	licenseOrRegex  = regexp.MustCompile(`(?i)\s+OR\s+`)
+	licenseAndRegex = regexp.MustCompile(`(?i)\s+AND\s+`)
+)
+
+// licenseAliases maps other common names of licenses to SPDX IDs
+var licenseAliases = map[string]string{
+	"mit license":   "MIT",
+	"apache 2.0":    "Apache-2.0",
+	"apache-2":      "Apache-2.0",
+	"apache2":       "Apache-2.0",
+	"asl 2.0":       "Apache-2.0",
+	"bsd-3":         "BSD-3-Clause",
+	"bsd-2":         "BSD-2-Clause",
+	"mpl-2":         "MPL-2.0",
+	"public domain": "LicenseRef-public-domain",
+}
+
+// licenseCategoryPrefixes classify SPDX IDs by prefix, most specific first
+var licenseCategoryPrefixes = []struct {
+	prefix   string
+	category models.LicenseCategory
+}{
+	{"LGPL-", models.LicenseWeakCopyleft},
+	{"GPL-", models.LicenseStrongCopyleft},
+	{"AGPL-", models.LicenseStrongCopyleft},
+	{"EUPL-", models.LicenseStrongCopyleft},
+	{"OSL-", models.LicenseStrongCopyleft},
+	{"SSPL-", models.LicenseStrongCopyleft},
+	{"MPL-", models.LicenseWeakCopyleft},
+	{"EPL-", models.LicenseWeakCopyleft},
+	{"CDDL-", models.LicenseWeakCopyleft},
+	{"CPL-", models.LicenseWeakCopyleft},
+	{"MIT", models.LicensePermissive},
+	{"BSD-", models.LicensePermissive},
+	{"0BSD", models.LicensePermissive},
+	{"Apache-", models.LicensePermissive},
+	{"ISC", models.LicensePermissive},
+	{"Zlib", models.LicensePermissive},
+	{"OpenSSL", models.LicensePermissive},
+	{"curl", models.LicensePermissive},
+	{"PSF-", models.LicensePermissive},
+	{"Python-", models.LicensePermissive},
+	{"BSL-1.0", models.LicensePermissive},
+	{"Unlicense", models.LicensePermissive},
+	{"CC0-", models.LicensePermissive},
+	{"X11", models.LicensePermissive},
+	{"Libpng", models.LicensePermissive},
+	{"IJG", models.LicensePermissive},
+	{"LicenseRef-public-domain", models.LicensePermissive},
+}
+
+// licenseRank orders categories by how much they oblige
+var licenseRank = map[models.LicenseCategory]int{
+	models.LicenseUnknown:        0,
+	models.LicensePermissive:     1,
+	models.LicenseWeakCopyleft:   2,
+	models.LicenseStrongCopyleft: 3,
+}
+
+// NormalizeLicense turns common license names into SPDX IDs, e.g. GPLv2+
+// into GPL-2.0-or-later. Unrecognized names are returned as they are.
+func NormalizeLicense(name string) string {
+	name = strings.TrimSpace(name)
+	if matches := gplAliasRegex.FindStringSubmatch(name); matches != nil {
+		minor := matches[3]
+		if minor == "" {
+			minor = "0"
+		}
+		suffix := "-only"
+		if matches[4] == "+" || strings.EqualFold(matches[4], "-or-later") {
+			suffix = "-or-later"
+		}
+		return strings.ToUpper(matches[1]) + "GPL-" + matches[2] + "." + minor + suffix
+	}
+	if id, ok := licenseAliases[strings.ToLower(name)]; ok {
+		return id
+	}
+	return name
+}
+
+// ClassifyLicense returns the category of an SPDX license expression. Of
+// alternatives (OR) the least obliging applies, of combined licenses (AND)
+// the most obliging; exceptions (WITH) don't change the category.
+func ClassifyLicense(expression string) models.LicenseCategory {
+	expression = strings.NewReplacer("(", " ", ")", " ").Replace(expression)
+
+	best := models.LicenseUnknown
+	for _, alternative := range licenseOrRegex.Split(expression, -1) {
+		worst := models.LicenseUnknown
+		for _, term := range licenseAndRegex.Split(alternative, -1) {
+			id, _, _ := strings.Cut(strings.TrimSpace(term), " WITH ")
+			if category := licenseCategory(strings.TrimSpace(id)); licenseRank[category] > licenseRank[worst] {
+				worst = category
+			}
+		}
+		if worst != models.LicenseUnknown && (best == models.LicenseUnknown || licenseRank[worst] < licenseRank[best]) {
+			best = worst
+		}
+	}
+	return best
+}
+
+func licenseCategory(id string) models.LicenseCategory {
+	id = NormalizeLicense(id)
+	for _, rule := range licenseCategoryPrefixes {
+		if strings.HasPrefix(strings.ToLower(id), strings.ToLower(rule.prefix)) {
+			return rule.category
+		}
+	}
+	return models.LicenseUnknown
+}
+
+// parseLicenses adds the licenses F10 identified to the SBOM components
+// that have none, adds the binaries it names that the SBOM lacks, and sets
+// every component's combined license and category
+func (s *Service) parseLicenses(logDir string, results *ParsedResults) error {
+	files, err := results.layout.ModuleLogs(logDir, "F10_*")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Error reading license summary %s: %v", file, err)
+			continue
+		}
+
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+			matches := licenseLineRegex.FindStringSubmatch(line)
+			if matches == nil {
+				continue
+			}
+			name, version, license := matches[1], matches[2], NormalizeLicense(matches[3])
+			if version == "NA" {
+				version = ""
+			}
+			if license == "" || strings.EqualFold(license, "NA") || strings.EqualFold(license, "unknown") {
+				continue
+			}
+			data, _ := json.Marshal([]string{license})
+
+			matched := false
+			for i := range results.Components {
+				component := &results.Components[i]
+				if normalizeComponentName(component.Name) != normalizeComponentName(name) ||
+					(version != "" && component.Version != "" && component.Version != version) {
+					continue
+				}
+				matched = true
+				if component.Licenses == "" {
+					component.Licenses = string(data)
+				}
+			}
+			if !matched {
+				results.Components = append(results.Components, models.SBOMComponent{
+					Type:     "application",
+					Name:     name,
+					Version:  version,
+					Licenses: string(data),
+				})
+			}
+		}
+	}
+
+	for i := range results.Components {
+		component := &results.Components[i]
+		component.License = combineLicenses(component.Licenses)
+		component.LicenseCategory = ClassifyLicense(component.License)
+	}
+	return nil
+}
+
+// combineLicenses joins a component's licenses into one SPDX expression;
+// CycloneDX lists every license that applies
+func combineLicenses(licenses string) string {
+	var names []string
+	if licenses == "" || json.Unmarshal([]byte(licenses), &names) != nil {
+		return ""
+	}
+	for i, name := range names {
+		names[i] = NormalizeLicense(name)
+		if len(names) > 1 && strings.Contains(strings.ToUpper(names[i]), " OR ") {
+			names[i] = "(" + names[i] + ")"
+		}
+	}
+	return strings.Join(names, " AND ")
+}
+
+// LicenseSummary counts an analysis' components per license category and
+// per license
+type LicenseSummary struct {
+	Total      int                            `json:"total_components"`
+	Copyleft   int                            `json:"copyleft_components"`
+	Unknown    int                            `json:"unknown_license"`
+	Categories map[models.LicenseCategory]int `json:"categories"`
+	Licenses   map[string]int                 `json:"licenses"`
+}
+
+// SummarizeLicenses summarizes the licenses of SBOM components
+func SummarizeLicenses(components []models.SBOMComponent) LicenseSummary {
+	summary := LicenseSummary{
+		Total:      len(components),
+		Categories: make(map[models.LicenseCategory]int),
+		Licenses:   make(map[string]int),
+	}
+	for _, component := range components {
+		category := component.LicenseCategory
+		if category == "" {
+			category = models.LicenseUnknown
+		}
+		summary.Categories[category]++
+		if category.Copyleft() {
+			summary.Copyleft++
+		}
+		if category == models.LicenseUnknown {
+			summary.Unknown++
+		}
+		if component.License != "" {
+			summary.Licenses[component.License]++
+		}
+	}
+	return summary
+}
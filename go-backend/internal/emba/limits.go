@@ -0,0 +1,80 @@
+package emba
+
+import "strconv"
+
+// ioniceClasses maps EMBA_IONICE_CLASS to ionice's numeric classes
+var ioniceClasses = map[string]string{
+	"best-effort": "2",
+	"idle":        "3",
+}
+
+// hasCgroupLimits reports whether EMBA runs with CPU or memory limits
+func (s *Service) hasCgroupLimits() bool {
+	return s.config.EMBACPUQuota != "" || s.config.EMBAMemoryMax != ""
+}
+
+// limitedCommand wraps the EMBA command line in a systemd scope carrying
+// the cgroup limits and in nice/ionice for the scheduling priority. All of
+// them exec the next command, so EMBA keeps the process group it is
+// killed through.
+func (s *Service) limitedCommand(args []string) []string {
+	var prefix []string
+
+	if s.hasCgroupLimits() {
+		prefix = append(prefix, "systemd-run", "--scope", "--quiet", "--collect")
+		if s.unprivileged() {
+			// Without root only the user's own systemd instance can create scopes
+			prefix = append(prefix, "--user")
+		}
+		for _, property := range s.cgroupProperties() {
+			prefix = append(prefix, "--property="+property)
+		}
+	}
+	if s.config.EMBANice != 0 {
+		prefix = append(prefix, "nice", "-n", strconv.Itoa(s.config.EMBANice))
+	}
+	if class, ok := ioniceClasses[s.config.EMBAIONiceClass]; ok {
+		prefix = append(prefix, "ionice", "-c", class)
+		if s.config.EMBAIONiceClass == "best-effort" {
+			prefix = append(prefix, "-n", strconv.Itoa(s.config.EMBAIONiceLevel))
+		}
+	}
+
+	if len(prefix) == 0 {
+		return args
+	}
+	return append(prefix, args...)
+}
+
+// cgroupProperties returns the systemd unit properties for the CPU and
+// memory limits
+func (s *Service) cgroupProperties() []string {
+	var properties []string
+	if s.config.EMBACPUQuota != "" {
+		properties = append(properties, "CPUQuota="+s.config.EMBACPUQuota)
+	}
+	if s.config.EMBAMemoryMax != "" {
+		// Otherwise the scope swaps past the limit and still drags the host down
+		properties = append(properties, "MemoryMax="+s.config.EMBAMemoryMax, "MemorySwapMax=0")
+	}
+	return properties
+}
+
+// unitLimitArgs returns systemd-run arguments applying all limits to a
+// transient service unit
+func (s *Service) unitLimitArgs() []string {
+	var args []string
+	for _, property := range s.cgroupProperties() {
+		args = append(args, "--property="+property)
+	}
+	if s.config.EMBANice != 0 {
+		args = append(args, "--nice="+strconv.Itoa(s.config.EMBANice))
+	}
+	if s.config.EMBAIONiceClass != "" {
+		args = append(args, "--property=IOSchedulingClass="+s.config.EMBAIONiceClass)
+		if s.config.EMBAIONiceClass == "best-effort" {
+			args = append(args, "--property=IOSchedulingPriority="+strconv.Itoa(s.config.EMBAIONiceLevel))
+		}
+	}
+	return args
+}
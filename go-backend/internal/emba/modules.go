@@ -0,0 +1,134 @@
+package emba
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// moduleRegex matches EMBA module names accepted by -m: a module group
+// (e.g. "S" for all static modules), a module number ("S09") or a full
+// module name ("S09_firmware_base_version_check")
+var moduleRegex = regexp.MustCompile(`^[PSLFQDX](\d{2,3}(_[A-Za-z0-9_]+)?)?$`)
+
+// maxSelectedModules bounds the -m arguments of a single analysis
+const maxSelectedModules = 64
+
+// AnalysisOptions are per-analysis settings on top of the service configuration
+type AnalysisOptions struct {
+	// Modules restricts EMBA to these modules; empty runs the whole profile
+	Modules []string
+
+	// ExcludedModules are kept from running on top of EMBA_EXCLUDED_MODULES
+	ExcludedModules []string
+
+	// DiffFirmware runs EMBA's diff mode (-o): the firmware analyzed is
+	// compared with this second image instead of being scanned
+	DiffFirmware string
+
+	// Platform is what is known of the firmware's architecture and OS
+	// before EMBA runs; emulation is skipped for platforms it can't emulate
+	Platform Platform
+
+	// OnModulesFinished receives the results of modules as EMBA finishes
+	// them, every EMBA_PARTIAL_INTERVAL while it runs
+	OnModulesFinished func(*PartialResults)
+}
+
+// ParseModules validates a comma or space separated module selection and
+// returns the normalized module names
+func ParseModules(value string) ([]string, error) {
+	var modules []string
+	seen := make(map[string]bool)
+	for _, module := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		// Full module names keep their case, groups and numbers are upper case
+		name := strings.ToUpper(module[:1]) + module[1:]
+		if i := strings.IndexByte(name, '_'); i < 0 {
+			name = strings.ToUpper(name)
+		}
+		if !moduleRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid EMBA module %q", module)
+		}
+		if !seen[name] {
+			seen[name] = true
+			modules = append(modules, name)
+		}
+	}
+	if len(modules) > maxSelectedModules {
+		return nil, fmt.Errorf("at most %d modules can be selected", maxSelectedModules)
+	}
+	return modules, nil
+}
+
+// moduleArgs builds EMBA's -m arguments. EMBA identifies modules by their
+// lower case number, e.g. "-m s09".
+func moduleArgs(modules []string) []string {
+	var args []string
+	for _, module := range modules {
+		if i := strings.IndexByte(module, '_'); i > 0 {
+			module = module[:i]
+		}
+		args = append(args, "-m", strings.ToLower(module))
+	}
+	return args
+}
+
+// excludedModules combines the instance-wide and per-analysis exclusions
+func (s *Service) excludedModules(opts AnalysisOptions) ([]string, error) {
+	configured, err := ParseModules(strings.Join(s.config.EMBAExcludedModules, ","))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMBA_EXCLUDED_MODULES: %w", err)
+	}
+
+	var excluded []string
+	seen := make(map[string]bool)
+	for _, module := range append(configured, opts.ExcludedModules...) {
+		if !seen[module] {
+			seen[module] = true
+			excluded = append(excluded, module)
+		}
+	}
+	return excluded, nil
+}
+
+// blacklistNames expands module groups and numbers to the full module names
+// EMBA's MODULE_BLACKLIST matches against, e.g. "S115" to
+// "S115_usermode_emulator" and "L" to every live testing module
+func (s *Service) blacklistNames(modules []string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	modulesDir := filepath.Join(s.config.EMBAPath, "modules")
+	for _, module := range modules {
+		var pattern string
+		switch {
+		case strings.IndexByte(module, '_') > 0:
+			add(module)
+			continue
+		case len(module) == 1:
+			pattern = module + "[0-9]*_*.sh"
+		default:
+			pattern = module + "_*.sh"
+		}
+
+		matches, _ := filepath.Glob(filepath.Join(modulesDir, pattern))
+		if len(matches) == 0 {
+			// Unknown to this EMBA checkout; keep it so the exclusion stays visible
+			add(module)
+			continue
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			add(strings.TrimSuffix(filepath.Base(match), ".sh"))
+		}
+	}
+	return names
+}
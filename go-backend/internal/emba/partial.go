@@ -0,0 +1,133 @@
+package emba
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+)
+
+// moduleFinishedRegex matches the line EMBA ends a module log with, e.g.
+// "[*] 2024-05-02 - S25_kernel_check finished"
+var moduleFinishedRegex = regexp.MustCompile(`(?i)\b[a-z]\d+_\w+\s+finished\b`)
+
+// PartialResults are the results of the modules EMBA finished since the
+// previous call during a run
+type PartialResults struct {
+	Modules  []string // module log names, e.g. "s25_kernel_check"
+	Findings []models.Finding
+	CVEs     []models.CVEFinding
+}
+
+// watchModules parses module logs as EMBA finishes them and hands their
+// results to handle until stop is closed. done is closed on return.
+func (s *Service) watchModules(logDir string, layout Layout, handle func(*PartialResults), stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	finished := make(map[string]bool)
+	ticker := time.NewTicker(s.config.EMBAPartialInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if partial := s.finishedModules(logDir, layout, finished); partial != nil {
+			handle(partial)
+		}
+	}
+}
+
+// finishedModules parses the module logs that finished and aren't in seen
+// yet, adding them to it. It returns nil when none did.
+func (s *Service) finishedModules(logDir string, layout Layout, seen map[string]bool) *PartialResults {
+	files, err := layout.ModuleLogs(logDir, "*")
+	if err != nil {
+		log.Printf("Failed to list module logs in %s: %v", logDir, err)
+		return nil
+	}
+
+	results := &ParsedResults{layout: layout, FileInfo: make(map[string]interface{})}
+	var modules []string
+	var kernel, permissions, passwords bool
+	for _, file := range files {
+		module := strings.ToLower(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+		if seen[module] || !moduleFinished(file) {
+			continue
+		}
+		seen[module] = true
+		modules = append(modules, module)
+
+		switch {
+		case strings.HasPrefix(module, "s40_"):
+			permissions = true
+		case strings.HasPrefix(module, "s107_"):
+			passwords = true
+		case structuredModuleRegex.MatchString(module):
+			kernel = true
+		default:
+			s.parseModuleFile(file, results)
+		}
+		if strings.HasPrefix(module, "f20_") {
+			s.parseModuleCSVs(logDir, layout, "f20_", results)
+		}
+	}
+	if kernel {
+		s.parseKernel(logDir, results)
+	}
+	if permissions {
+		s.parseWeakPermissions(logDir, results)
+	}
+	if passwords {
+		s.parsePasswordHashes(logDir, results)
+	}
+	if len(modules) == 0 {
+		return nil
+	}
+
+	s.annotateSeverity(results)
+	annotateConfidence(results)
+	annotateProvenance(logDir, results)
+	annotateCWE(results)
+	results.Findings = dedupFindings(results.Findings)
+	return &PartialResults{Modules: modules, Findings: results.Findings, CVEs: results.CVEs}
+}
+
+// parseModuleCSVs parses the CSV logs a module wrote
+func (s *Service) parseModuleCSVs(logDir string, layout Layout, prefix string, results *ParsedResults) {
+	csvFiles, err := layout.CSVFiles(logDir)
+	if err != nil {
+		return
+	}
+	for _, csvFile := range csvFiles {
+		if strings.HasPrefix(strings.ToLower(filepath.Base(csvFile)), prefix) {
+			s.parseCSVReport(csvFile, results)
+		}
+	}
+}
+
+// moduleFinished reports whether EMBA wrote a module's closing line
+func moduleFinished(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	const tailSize = 4096
+	if info, err := file.Stat(); err == nil && info.Size() > tailSize {
+		file.Seek(-tailSize, io.SeekEnd)
+	}
+	tail, err := io.ReadAll(file)
+	if err != nil {
+		return false
+	}
+	lines := strings.Split(strings.TrimSpace(string(tail)), "\n")
+	return moduleFinishedRegex.MatchString(ansiRegex.ReplaceAllString(lines[len(lines)-1], ""))
+}
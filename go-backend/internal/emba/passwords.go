@@ -0,0 +1,275 @@
+package emba
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+var (
+	// user:hash entries of passwd and shadow files, e.g.
+	// root:$1$3Fz8kLwq$Yq2d9u0Lr5VxUe2k3x9C3/:18000:0:99999:7:::
+	passwordEntryRegex = regexp.MustCompile(`(?:^|[\s'"])([A-Za-z_][\w.-]{0,31}):([^:\s'"]*)(:\d*:)?`)
+	cryptHashRegex     = regexp.MustCompile(`^\$(\w+)\$[^\s:]+$`)
+	desHashRegex       = regexp.MustCompile(`^[./0-9A-Za-z]{13}$`)
+)
+
+// HashAlgorithm returns the algorithm of a password field in crypt(3)
+// format, or "" when it isn't a usable hash (e.g. "x" or a locked "!").
+// entry tells whether the field came from a full passwd/shadow entry, the
+// only context in which an empty field or a bare DES hash can be trusted.
+func HashAlgorithm(hash string, entry bool) string {
+	if hash == "" {
+		if entry {
+			return models.HashNone
+		}
+		return ""
+	}
+	if matches := cryptHashRegex.FindStringSubmatch(hash); matches != nil {
+		switch matches[1] {
+		case "1":
+			return models.HashMD5Crypt
+		case "2a", "2b", "2x", "2y":
+			return models.HashBcrypt
+		case "5":
+			return models.HashSHA256Crypt
+		case "6":
+			return models.HashSHA512Crypt
+		case "y":
+			return models.HashYescrypt
+		}
+		return models.HashCrypt
+	}
+	if entry && desHashRegex.MatchString(hash) {
+		return models.HashDES
+	}
+	return ""
+}
+
+// passwordSource is where a hash was found, for the finding's provenance
+type passwordSource struct {
+	logFile string
+	logLine int
+}
+
+// passwordKey identifies a hash of an account in a file
+func passwordKey(path, username, hash string) string {
+	return path + "\x00" + username + "\x00" + hash
+}
+
+// parsePasswordHashes collects the password hashes of passwd and shadow
+// files EMBA's password checks found (S45's log, S107's log and CSV) into
+// PasswordHashes and raises a finding per file
+func (s *Service) parsePasswordHashes(logDir string, results *ParsedResults) error {
+	sources := make(map[string]passwordSource)
+	add := func(path, username, hash string, entry bool, source passwordSource) {
+		algorithm := HashAlgorithm(hash, entry)
+		key := passwordKey(path, username, hash)
+		if _, ok := sources[key]; ok || algorithm == "" {
+			return
+		}
+		sources[key] = source
+
+		status := models.CrackPending
+		if algorithm == models.HashNone {
+			status = ""
+		}
+		results.PasswordHashes = append(results.PasswordHashes, models.PasswordHash{
+			FilePath:    path,
+			Username:    username,
+			Hash:        hash,
+			Algorithm:   algorithm,
+			CrackStatus: status,
+		})
+	}
+
+	// S107 writes its results to a CSV of path and hash
+	if csvFiles, err := results.layout.CSVFiles(logDir); err == nil {
+		for _, csvFile := range csvFiles {
+			if !strings.HasPrefix(strings.ToLower(filepath.Base(csvFile)), "s107_") {
+				continue
+			}
+			records, err := readCSV(csvFile)
+			if err != nil {
+				log.Printf("Error reading password CSV %s: %v", csvFile, err)
+				continue
+			}
+			pathColumn, hashColumn := 0, 1
+			for i, record := range records {
+				if len(record) <= pathColumn || len(record) <= hashColumn {
+					continue
+				}
+				path, value := strings.TrimSpace(record[pathColumn]), strings.TrimSpace(record[hashColumn])
+				if i == 0 && !strings.HasPrefix(path, "/") {
+					// Header row, e.g. PW_PATH;PW_HASH
+					for j, name := range record {
+						name = strings.ToLower(name)
+						if strings.Contains(name, "path") {
+							pathColumn = j
+						} else if strings.Contains(name, "hash") {
+							hashColumn = j
+						}
+					}
+					continue
+				}
+
+				source := passwordSource{logFile: csvFile, logLine: i + 1}
+				if entries := passwordEntryRegex.FindAllStringSubmatch(value, -1); entries != nil {
+					for _, entry := range entries {
+						add(path, entry[1], entry[2], entry[3] != "", source)
+					}
+				} else {
+					add(path, "", value, false, source)
+				}
+			}
+		}
+	}
+
+	// The text logs list each file followed by its entries
+	for _, pattern := range []string{"S45_*", "S107_*"} {
+		files, err := results.layout.ModuleLogs(logDir, pattern)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				log.Printf("Error reading password log %s: %v", file, err)
+				continue
+			}
+
+			currentPath := ""
+			for i, line := range strings.Split(string(content), "\n") {
+				line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+				if line == "" {
+					continue
+				}
+
+				entries := passwordEntryRegex.FindAllStringSubmatch(line, -1)
+				// Hashes contain slashes; look for the file outside of them
+				path := permissionPathRegex.FindString(passwordEntryRegex.ReplaceAllString(line, " "))
+				if path != "" && (len(entries) > 0 || strings.HasPrefix(line, "[")) {
+					currentPath = path
+				}
+				if currentPath == "" {
+					continue
+				}
+				for _, entry := range entries {
+					add(currentPath, entry[1], entry[2], entry[3] != "", passwordSource{logFile: file, logLine: i + 1})
+				}
+			}
+		}
+	}
+
+	results.Findings = append(results.Findings, passwordFindings(results.PasswordHashes, sources)...)
+	return nil
+}
+
+// passwordFindings raises a finding per file with password hashes, which
+// every device running the firmware shares and anyone can crack offline,
+// and one per account that needs no password at all
+func passwordFindings(hashes []models.PasswordHash, sources map[string]passwordSource) []models.Finding {
+	byPath := make(map[string][]models.PasswordHash)
+	var paths []string
+	for _, hash := range hashes {
+		if _, ok := byPath[hash.FilePath]; !ok {
+			paths = append(paths, hash.FilePath)
+		}
+		byPath[hash.FilePath] = append(byPath[hash.FilePath], hash)
+	}
+
+	var findings []models.Finding
+	for _, path := range paths {
+		metadata := func(hash models.PasswordHash, extra map[string]interface{}) string {
+			source := sources[passwordKey(hash.FilePath, hash.Username, hash.Hash)]
+			fields := map[string]interface{}{
+				"source":   "password_search",
+				"module":   moduleID(filepath.Base(source.logFile)),
+				"log_file": source.logFile,
+				"log_line": source.logLine,
+			}
+			for key, value := range extra {
+				fields[key] = value
+			}
+			return encodeMetadata(fields)
+		}
+
+		var accounts, weak []string
+		var first *models.PasswordHash
+		algorithms := make(map[string]bool)
+		for i, hash := range byPath[path] {
+			if hash.Algorithm == models.HashNone {
+				findings = append(findings, models.Finding{
+					Type:            models.FindingCredential,
+					Title:           fmt.Sprintf("Account %s has no password", hash.Username),
+					Description:     fmt.Sprintf("The password field of %s in %s is empty, so the account may log in without a password", hash.Username, path),
+					Severity:        models.RiskCritical,
+					FilePath:        path,
+					Content:         hash.Username,
+					FindingMetadata: metadata(hash, map[string]interface{}{"username": hash.Username}),
+				})
+				continue
+			}
+			if first == nil {
+				first = &byPath[path][i]
+			}
+			accounts = append(accounts, fmt.Sprintf("%s (%s)", hash.Username, hash.Algorithm))
+			algorithms[hash.Algorithm] = true
+			if hash.Algorithm == models.HashDES || hash.Algorithm == models.HashMD5Crypt {
+				weak = append(weak, hash.Username)
+			}
+		}
+		if len(accounts) == 0 {
+			continue
+		}
+
+		var algorithmList []string
+		for algorithm := range algorithms {
+			algorithmList = append(algorithmList, algorithm)
+		}
+		sort.Strings(algorithmList)
+
+		severity := models.RiskMedium
+		description := fmt.Sprintf("%s contains password hashes for %s. Every device running this firmware shares them and they can be cracked offline", path, strings.Join(accounts, ", "))
+		if len(weak) > 0 {
+			severity = models.RiskHigh
+			description += fmt.Sprintf("; the DES and MD5 crypt hashes (%s) are fast to brute force", strings.Join(weak, ", "))
+		}
+		findings = append(findings, models.Finding{
+			Type:            models.FindingCredential,
+			Title:           "Password hashes in " + path,
+			Description:     description,
+			Severity:        severity,
+			FilePath:        path,
+			Content:         strings.Join(accounts, "\n"),
+			FindingMetadata: metadata(*first, map[string]interface{}{"algorithms": algorithmList, "accounts": len(accounts)}),
+		})
+	}
+	return findings
+}
+
+// CrackedPasswordFinding reports a password cracked from its hash as a
+// default credential of every device running the firmware
+func CrackedPasswordFinding(hash *models.PasswordHash, crackerName string) models.Finding {
+	return models.Finding{
+		Type:        models.FindingCredential,
+		Title:       fmt.Sprintf("Default credentials for %s", hash.Username),
+		Description: fmt.Sprintf("The password of %s in %s is %q, found in the wordlist. Every device running this firmware accepts it", hash.Username, hash.FilePath, hash.CrackedPassword),
+		Severity:    models.RiskCritical,
+		FilePath:    hash.FilePath,
+		Content:     hash.Username + ":" + hash.CrackedPassword,
+		Confidence:  sourceConfidence["password_cracking"],
+		FindingMetadata: encodeMetadata(map[string]interface{}{
+			"source":           "password_cracking",
+			"cracker":          crackerName,
+			"algorithm":        hash.Algorithm,
+			"password_hash_id": hash.ID,
+		}),
+	}
+}
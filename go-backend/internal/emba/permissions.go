@@ -0,0 +1,246 @@
+package emba
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// Permission issues of a file, recorded comma separated in a finding's
+// PermissionIssues
+const (
+	PermissionWorldWritable = "world_writable"
+	PermissionSetuid        = "setuid"
+	PermissionSetgid        = "setgid"
+	PermissionNoStickyBit   = "no_sticky_bit"
+	PermissionWeakShadow    = "weak_shadow"
+	PermissionWeakInit      = "weak_init_script"
+	PermissionWeak          = "weak_permission"
+)
+
+// permissionReasons explain why each issue is risky
+var permissionReasons = map[string]string{
+	PermissionWorldWritable: "it is world-writable, so any local user or compromised service can modify it",
+	PermissionSetuid:        "it is setuid and runs with its owner's privileges, so a flaw in it can be used to escalate privileges",
+	PermissionSetgid:        "it is setgid and runs with its group's privileges",
+	PermissionNoStickyBit:   "the directory lacks the sticky bit, so users can delete or replace each other's files in it",
+	PermissionWeakShadow:    "the password hashes in it are readable by other users",
+	PermissionWeakInit:      "startup scripts run as root at boot, so whoever can modify it gains root",
+	PermissionWeak:          "its permissions are weaker than recommended",
+}
+
+// permissionSections map the headings of S40's result lists, e.g.
+// "[+] Found 3 setuid files:", to the issue they report
+var permissionSections = []struct{ keyword, issue string }{
+	{"world writable", PermissionWorldWritable},
+	{"world-writable", PermissionWorldWritable},
+	{"setuid", PermissionSetuid},
+	{"suid", PermissionSetuid},
+	{"setgid", PermissionSetgid},
+	{"sgid", PermissionSetgid},
+	{"shadow", PermissionWeakShadow},
+	{"rc file", PermissionWeakInit},
+	{"init", PermissionWeakInit},
+	{"weak", PermissionWeak},
+}
+
+var (
+	permissionModeRegex  = regexp.MustCompile(`(?:^|[\s(])([-dlcbps])([rwxsStT-]{9})(?:[\s)]|$)`)
+	permissionOwnerRegex = regexp.MustCompile(`[-dlcbps][rwxsStT-]{9}\s+(?:\d+\s+)?([\w.-]+)[\s:]+([\w.-]+)`)
+	permissionPathRegex  = regexp.MustCompile(`(/[^\s()'"]*)`)
+)
+
+// filePermission is one file S40 reported
+type filePermission struct {
+	path, mode, owner string
+	directory         bool
+	issues            map[string]bool
+	logFile           string
+	logLine           int
+}
+
+// parseWeakPermissions turns S40's weak permission results into one finding
+// per file with its mode, owner and the reasons it is risky
+func (s *Service) parseWeakPermissions(logDir string, results *ParsedResults) error {
+	files, err := results.layout.ModuleLogs(logDir, "S40_*")
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]*filePermission)
+	var order []string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Error reading weak permission log %s: %v", file, err)
+			continue
+		}
+
+		section, sectionDirectory := "", false
+		for i, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+			if line == "" {
+				continue
+			}
+
+			var path, issue string
+			directory := sectionDirectory
+			switch {
+			case strings.HasPrefix(line, "[+]"):
+				// A result heading either names a file itself or starts a list
+				head, rest, _ := strings.Cut(line, ":")
+				issue = permissionIssue(head)
+				directory = strings.Contains(strings.ToLower(head), "director")
+				if path = permissionPathRegex.FindString(rest); path == "" {
+					section, sectionDirectory = issue, directory
+					continue
+				}
+				section = ""
+			case strings.HasPrefix(line, "["):
+				section = ""
+				continue
+			case section != "":
+				path, issue = permissionPathRegex.FindString(line), section
+			}
+			if path == "" || issue == "" {
+				continue
+			}
+
+			entry, ok := entries[path]
+			if !ok {
+				entry = &filePermission{path: path, issues: make(map[string]bool), logFile: file, logLine: i + 1}
+				entries[path] = entry
+				order = append(order, path)
+			}
+			entry.issues[issue] = true
+			entry.directory = entry.directory || directory
+			if matches := permissionModeRegex.FindStringSubmatch(line); matches != nil && entry.mode == "" {
+				entry.mode = matches[1] + matches[2]
+				entry.directory = entry.directory || matches[1] == "d"
+			}
+			if matches := permissionOwnerRegex.FindStringSubmatch(line); matches != nil && entry.owner == "" {
+				entry.owner = matches[1] + ":" + matches[2]
+			}
+		}
+	}
+
+	for _, path := range order {
+		results.Findings = append(results.Findings, permissionFinding(entries[path]))
+	}
+	return nil
+}
+
+// permissionIssue returns the issue a heading reports, or ""
+func permissionIssue(heading string) string {
+	lower := strings.ToLower(heading)
+	for _, section := range permissionSections {
+		if strings.Contains(lower, section.keyword) {
+			return section.issue
+		}
+	}
+	return ""
+}
+
+// modeIssues derives issues from a symbolic mode such as -rwsr-xr-x
+func modeIssues(mode string) []string {
+	if len(mode) != 10 {
+		return nil
+	}
+	var issues []string
+	if mode[8] == 'w' {
+		issues = append(issues, PermissionWorldWritable)
+		if mode[0] == 'd' && mode[9] != 't' && mode[9] != 'T' {
+			issues = append(issues, PermissionNoStickyBit)
+		}
+	}
+	if mode[3] == 's' || mode[3] == 'S' {
+		issues = append(issues, PermissionSetuid)
+	}
+	if mode[6] == 's' || mode[6] == 'S' {
+		issues = append(issues, PermissionSetgid)
+	}
+	return issues
+}
+
+// permissionFinding builds the finding for one file
+func permissionFinding(entry *filePermission) models.Finding {
+	for _, issue := range modeIssues(entry.mode) {
+		entry.issues[issue] = true
+	}
+	// The generic issue only matters when nothing more specific is known
+	if len(entry.issues) > 1 {
+		delete(entry.issues, PermissionWeak)
+	}
+
+	var issues []string
+	for issue := range entry.issues {
+		issues = append(issues, issue)
+	}
+	sort.Strings(issues)
+
+	severity := models.RiskLow
+	switch {
+	case entry.issues[PermissionWeakShadow],
+		entry.issues[PermissionSetuid] && entry.issues[PermissionWorldWritable],
+		entry.issues[PermissionWeakInit] && entry.issues[PermissionWorldWritable]:
+		severity = models.RiskHigh
+	case entry.issues[PermissionWorldWritable], entry.issues[PermissionSetuid], entry.issues[PermissionWeakInit]:
+		severity = models.RiskMedium
+	}
+
+	kind := "file"
+	if entry.directory {
+		kind = "directory"
+	}
+	var title string
+	switch {
+	case entry.issues[PermissionWeakShadow]:
+		title = "Password hashes readable by other users"
+	case entry.issues[PermissionSetuid]:
+		title = "Setuid " + kind
+	case entry.issues[PermissionWorldWritable]:
+		title = "World-writable " + kind
+	case entry.issues[PermissionSetgid]:
+		title = "Setgid " + kind
+	case entry.issues[PermissionWeakInit]:
+		title = "Startup script with weak permissions"
+	default:
+		title = "Weak file permissions"
+	}
+
+	reasons := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		reasons = append(reasons, permissionReasons[issue])
+	}
+	description := fmt.Sprintf("%s is risky because %s", entry.path, strings.Join(reasons, "; "))
+	if entry.mode != "" {
+		description += fmt.Sprintf(" (mode %s", entry.mode)
+		if entry.owner != "" {
+			description += ", owner " + entry.owner
+		}
+		description += ")"
+	}
+
+	return models.Finding{
+		Type:             models.FindingType("weak_permission"),
+		Title:            title,
+		Description:      description,
+		Severity:         severity,
+		FilePath:         entry.path,
+		FileMode:         entry.mode,
+		FileOwner:        entry.owner,
+		PermissionIssues: strings.Join(issues, ","),
+		FindingMetadata: encodeMetadata(map[string]interface{}{
+			"source":   "permission_check",
+			"module":   "S40",
+			"issues":   issues,
+			"log_file": entry.logFile,
+			"log_line": entry.logLine,
+		}),
+	}
+}
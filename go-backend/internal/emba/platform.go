@@ -0,0 +1,167 @@
+package emba
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"odin-backend/internal/fwformat"
+)
+
+var (
+	// P99's "Detected architecture and endianness of the firmware: MIPS / EB"
+	archEndianRegex = regexp.MustCompile(`(?i)architecture and endianness of the firmware:\s*([\w.-]+)\s*/\s*(E[BL]|big|little)\b`)
+	archRegex       = regexp.MustCompile(`(?i)(?:firmware architecture|detected architecture|architecture detected)\s*:\s*([\w.-]+)`)
+	osRegex         = regexp.MustCompile(`(?i)operating system (?:detected|detection|identified)(?:\s*\(verified\))?\s*:\s*([\w.-]+)`)
+)
+
+// Platform is the CPU architecture and operating system a firmware runs
+type Platform struct {
+	Architecture string `json:"architecture,omitempty"` // e.g. mipsel, arm, x86_64
+	OSFamily     string `json:"os_family,omitempty"`    // e.g. linux, vxworks
+}
+
+// architectures maps the names EMBA, file headers and image configs use for
+// an architecture to Odin's, which are QEMU's where it has one
+var architectures = map[string]string{
+	"arm": "arm", "arm32": "arm", "armel": "arm", "armhf": "arm", "armv7": "arm", "armv5": "arm",
+	"arm64": "arm64", "aarch64": "arm64", "armv8": "arm64",
+	"mips": "mips", "mips32": "mips", "mipsel": "mipsel",
+	"mips64": "mips64", "mips64el": "mips64el", "mips64le": "mips64el",
+	"ppc": "powerpc", "powerpc": "powerpc", "ppc64": "powerpc64", "powerpc64": "powerpc64", "ppc64le": "powerpc64",
+	"x86": "x86", "i386": "x86", "i686": "x86", "386": "x86", "intel80386": "x86",
+	"x86_64": "x86_64", "x86-64": "x86_64", "amd64": "x86_64", "x64": "x86_64",
+	"riscv": "riscv", "riscv32": "riscv", "riscv64": "riscv64",
+	"nios2": "nios2", "sparc": "sparc", "sparc64": "sparc64", "sh": "sh4", "sh4": "sh4",
+	"xtensa": "xtensa", "arc": "arc", "avr": "avr", "msp430": "msp430", "m68k": "m68k",
+	"arm-cortex-m": "arm-cortex-m", "microblaze": "microblaze",
+}
+
+// osFamilies maps the operating system names EMBA and image headers use
+var osFamilies = map[string]string{
+	"linux": "linux", "android": "android", "vxworks": "vxworks", "freertos": "freertos",
+	"openrtos": "freertos", "zephyr": "zephyr", "threadx": "threadx", "qnx": "qnx",
+	"ecos": "ecos", "rtems": "rtems", "windows": "windows", "wince": "windows",
+	"freebsd": "bsd", "netbsd": "bsd", "openbsd": "bsd", "bsd": "bsd",
+	"bare-metal": "bare-metal", "none": "bare-metal", "u-boot": "bare-metal", "arm-trusted-firmware": "bare-metal",
+}
+
+// NormalizeArchitecture returns Odin's name of an architecture, with the
+// byte order for MIPS where the name doesn't give it. Unknown names are kept
+// in lower case.
+func NormalizeArchitecture(arch, endianness string) string {
+	arch = strings.ToLower(strings.TrimSpace(arch))
+	if arch == "" || arch == "unknown" || arch == "na" {
+		return ""
+	}
+	normalized, ok := architectures[arch]
+	if !ok {
+		return arch
+	}
+	little := strings.EqualFold(endianness, "EL") || strings.EqualFold(endianness, "little")
+	if little && (normalized == "mips" || normalized == "mips64") {
+		normalized += "el"
+	}
+	return normalized
+}
+
+// NormalizeOS returns Odin's name of an operating system family
+func NormalizeOS(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "unknown" || name == "na" {
+		return ""
+	}
+	if family, ok := osFamilies[name]; ok {
+		return family
+	}
+	return name
+}
+
+// emulatedArchitectures are those EMBA's emulation modules run binaries of
+var emulatedArchitectures = map[string]bool{
+	"arm": true, "arm64": true, "mips": true, "mipsel": true, "mips64": true, "mips64el": true,
+	"powerpc": true, "x86": true, "x86_64": true, "nios2": true, "riscv64": true,
+}
+
+// Emulatable reports whether EMBA's emulation can run the firmware, and
+// why not. An unknown platform is tried.
+func (p Platform) Emulatable() (bool, string) {
+	if p.OSFamily != "" && p.OSFamily != "linux" && p.OSFamily != "android" {
+		return false, "emulation needs a Linux firmware, this one runs " + p.OSFamily
+	}
+	if p.Architecture != "" && !emulatedArchitectures[p.Architecture] {
+		return false, "EMBA can't emulate " + p.Architecture + " binaries"
+	}
+	return true, ""
+}
+
+// parsePlatform reads the architecture and operating system P99 and S03
+// detected where the F50 aggregator didn't report them
+func (s *Service) parsePlatform(logDir string, results *ParsedResults) error {
+	arch, _ := results.FileInfo["architecture"].(string)
+	endianness, _ := results.FileInfo["endianness"].(string)
+	osName, _ := results.FileInfo["os"].(string)
+	if arch != "" && osName != "" {
+		return nil
+	}
+
+	for _, pattern := range []string{"P99_*", "S03_*"} {
+		files, err := results.layout.ModuleLogs(logDir, pattern)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				log.Printf("Error reading platform log %s: %v", file, err)
+				continue
+			}
+			for _, line := range strings.Split(string(content), "\n") {
+				line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+				if matches := archEndianRegex.FindStringSubmatch(line); matches != nil && arch == "" {
+					arch, endianness = matches[1], matches[2]
+				} else if matches := archRegex.FindStringSubmatch(line); matches != nil && arch == "" {
+					arch = matches[1]
+				}
+				if matches := osRegex.FindStringSubmatch(line); matches != nil && osName == "" {
+					osName = matches[1]
+				}
+			}
+		}
+	}
+
+	if arch != "" {
+		results.FileInfo["architecture"] = arch
+	}
+	if endianness != "" {
+		results.FileInfo["endianness"] = endianness
+	}
+	if osName != "" {
+		results.FileInfo["os"] = osName
+	}
+	return nil
+}
+
+// PlatformOf returns the platform EMBA reported in the firmware info
+func PlatformOf(fileInfo map[string]interface{}) Platform {
+	arch, _ := fileInfo["architecture"].(string)
+	endianness, _ := fileInfo["endianness"].(string)
+	osName, _ := fileInfo["os"].(string)
+	return Platform{Architecture: NormalizeArchitecture(arch, endianness), OSFamily: NormalizeOS(osName)}
+}
+
+// HeaderPlatform returns what an image's header hints at of its platform
+func HeaderPlatform(info fwformat.Info) Platform {
+	platform := Platform{
+		Architecture: NormalizeArchitecture(info.Architecture, info.Endianness),
+		OSFamily:     NormalizeOS(info.Details["os"]),
+	}
+	switch info.Format {
+	case fwformat.AndroidBoot, fwformat.AndroidSparse:
+		platform.OSFamily = "android"
+	case fwformat.IntelHex:
+		platform.OSFamily = "bare-metal"
+	}
+	return platform
+}
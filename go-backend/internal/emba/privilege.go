@@ -0,0 +1,132 @@
+package emba
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Privilege modes EMBA can be started with (EMBA_PRIVILEGE_MODE)
+const (
+	PrivilegeSudo       = "sudo"        // sudo emba ... (default)
+	PrivilegeNone       = "none"        // run EMBA as the worker user, skipping modules that need root
+	PrivilegeSystemdRun = "systemd-run" // run EMBA as a transient systemd unit
+	PrivilegeHelper     = "helper"      // hand the command line to a dedicated setuid helper
+)
+
+// rootOnlyModules need root to mount filesystems or run emulation and are
+// skipped when EMBA runs unprivileged
+var rootOnlyModules = []string{
+	"P14_ext_mounter",
+	"P19_bsd_ufs_mounter",
+	"S115_usermode_emulator",
+	"S116_qemu_version_detection",
+	"L10_system_emulation",
+}
+
+// privilegeMode returns the configured mode, defaulting to sudo
+func (s *Service) privilegeMode() string {
+	if s.config.EMBAPrivilegeMode == "" {
+		return PrivilegeSudo
+	}
+	return s.config.EMBAPrivilegeMode
+}
+
+// unprivileged reports whether EMBA runs without root
+func (s *Service) unprivileged() bool {
+	return s.privilegeMode() == PrivilegeNone
+}
+
+// skippedModules lists the modules the privilege mode keeps EMBA from running
+func (s *Service) skippedModules() []string {
+	if !s.unprivileged() {
+		return nil
+	}
+	return rootOnlyModules
+}
+
+// restrictedProfile writes a copy of the scan profile that blacklists the
+// modules the privilege mode can't run and the excluded ones. It returns the
+// profile unchanged when nothing needs to be skipped.
+func (s *Service) restrictedProfile(profile, jobID string, excluded []string) (string, error) {
+	skipped := s.skippedModules()
+	if len(skipped) == 0 && len(excluded) == 0 {
+		return profile, nil
+	}
+
+	content, err := os.ReadFile(profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read scan profile: %w", err)
+	}
+
+	restricted := string(content)
+	if len(skipped) > 0 {
+		restricted += "\n# Added by Odin: modules that need root\n" + blacklistLine(skipped)
+	}
+	if len(excluded) > 0 {
+		restricted += "\n# Added by Odin: excluded modules\n" + blacklistLine(s.blacklistNames(excluded))
+	}
+
+	// Kept out of the job's log directory, which EMBA expects to be empty
+	path := filepath.Join(s.config.EMBALogDir, jobID+".profile.emba")
+	if err := os.WriteFile(path, []byte(restricted), 0644); err != nil {
+		return "", fmt.Errorf("failed to write scan profile: %w", err)
+	}
+	return path, nil
+}
+
+func blacklistLine(modules []string) string {
+	quoted := make([]string, len(modules))
+	for i, module := range modules {
+		quoted[i] = `"` + module + `"`
+	}
+	return "export MODULE_BLACKLIST+=( " + strings.Join(quoted, " ") + " )\n"
+}
+
+// command wraps the EMBA command line for the configured privilege mode
+func (s *Service) command(ctx context.Context, args []string, jobID string) (*exec.Cmd, error) {
+	mode := s.privilegeMode()
+	if mode != PrivilegeSystemdRun {
+		args = s.limitedCommand(args)
+	}
+
+	var cmd *exec.Cmd
+	switch mode {
+	case PrivilegeSudo:
+		cmd = exec.CommandContext(ctx, "sudo", args...)
+	case PrivilegeNone:
+		cmd = exec.CommandContext(ctx, args[0], args[1:]...)
+	case PrivilegeSystemdRun:
+		unit := "odin-emba-" + jobID
+		systemdArgs := []string{
+			"--unit=" + unit,
+			"--pipe", "--wait", "--collect", "--quiet",
+			"--property=WorkingDirectory=" + s.config.EMBAPath,
+		}
+		systemdArgs = append(systemdArgs, s.unitLimitArgs()...)
+		cmd = exec.CommandContext(ctx, "systemd-run", append(systemdArgs, args...)...)
+		killProcessGroupOnCancel(cmd)
+		// The unit runs outside our process group; stop it through systemd
+		stopGroup := cmd.Cancel
+		cmd.Cancel = func() error {
+			exec.Command("systemctl", "stop", unit).Run()
+			return stopGroup()
+		}
+		cmd.Dir = s.config.EMBAPath
+		return cmd, nil
+	case PrivilegeHelper:
+		if s.config.EMBAPrivilegeHelper == "" {
+			return nil, fmt.Errorf("EMBA_PRIVILEGE_HELPER must be set for the helper privilege mode")
+		}
+		cmd = exec.CommandContext(ctx, s.config.EMBAPrivilegeHelper, args...)
+	default:
+		return nil, fmt.Errorf("unknown EMBA privilege mode %q", mode)
+	}
+
+	cmd.Dir = s.config.EMBAPath
+	killProcessGroupOnCancel(cmd)
+	return cmd, nil
+}
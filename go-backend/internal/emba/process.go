@@ -0,0 +1,29 @@
+package emba
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killGracePeriod is how long EMBA gets to clean up (unmount, stop emulation)
+// after SIGTERM before its process group is killed
+const killGracePeriod = 30 * time.Second
+
+// killProcessGroupOnCancel runs the command in its own process group and,
+// when its context is cancelled, signals the whole group: sudo relays SIGTERM
+// to EMBA, and anything still running after the grace period is killed, so
+// EMBA's children aren't orphaned. An unprivileged worker can only signal sudo
+// itself, so it relies on sudo forwarding the SIGTERM.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		err := syscall.Kill(-pgid, syscall.SIGTERM)
+		time.AfterFunc(killGracePeriod, func() {
+			syscall.Kill(-pgid, syscall.SIGKILL)
+		})
+		return err
+	}
+	cmd.WaitDelay = killGracePeriod + 5*time.Second
+}
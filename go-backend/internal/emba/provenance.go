@@ -0,0 +1,118 @@
+package emba
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+var moduleIDRegex = regexp.MustCompile(`^([A-Za-z])(\d+)_`)
+
+// annotateProvenance records on every finding the EMBA module, the log file
+// relative to the run's log directory and the line it was parsed from
+func annotateProvenance(logDir string, results *ParsedResults) {
+	logs := make(map[string][]string)
+	for i := range results.Findings {
+		finding := &results.Findings[i]
+
+		metadata := make(map[string]interface{})
+		if finding.FindingMetadata != "" {
+			json.Unmarshal([]byte(finding.FindingMetadata), &metadata)
+		}
+
+		source := provenanceFile(logDir, finding, metadata)
+		if source == "" {
+			continue
+		}
+		relative, err := filepath.Rel(logDir, source)
+		if err != nil {
+			continue
+		}
+		finding.SourceFile = filepath.ToSlash(relative)
+
+		finding.Module = moduleID(filepath.Base(source))
+		if module, ok := metadata["module"].(string); ok && finding.Module == "" {
+			finding.Module = moduleID(module)
+		}
+		// Grep log lines start with the module that logged them
+		if raw, ok := metadata["raw_line"].(string); ok && finding.Module == "" {
+			finding.Module = moduleID(raw)
+		}
+
+		switch {
+		case metadata["log_line"] != nil:
+			if line, ok := metadata["log_line"].(float64); ok {
+				finding.SourceLine = int(line)
+			}
+		case finding.LineNumber > 0 && finding.FilePath == source:
+			finding.SourceLine = finding.LineNumber
+		default:
+			if _, ok := logs[source]; !ok {
+				logs[source] = readLogLines(source)
+			}
+			finding.SourceLine = findLogLine(logs[source], finding, metadata)
+		}
+	}
+}
+
+// provenanceFile returns the log file a finding was parsed from, if it lies
+// in the log directory
+func provenanceFile(logDir string, finding *models.Finding, metadata map[string]interface{}) string {
+	candidates := []string{}
+	if logFile, ok := metadata["log_file"].(string); ok {
+		candidates = append(candidates, logFile)
+	}
+	// Findings built from several logs list them comma separated
+	candidates = append(candidates, strings.Split(finding.FilePath, ", ")...)
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		relative, err := filepath.Rel(logDir, candidate)
+		if err != nil || relative == "." || strings.HasPrefix(relative, "..") {
+			continue
+		}
+		return candidate
+	}
+	return ""
+}
+
+// moduleID returns the EMBA module ID, e.g. "S25", of a module log name
+func moduleID(name string) string {
+	matches := moduleIDRegex.FindStringSubmatch(name)
+	if matches == nil {
+		return ""
+	}
+	return strings.ToUpper(matches[1]) + matches[2]
+}
+
+func readLogLines(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(ansiRegex.ReplaceAllString(string(content), ""), "\n")
+}
+
+// findLogLine returns the 1-based number of the first log line containing the
+// text the finding was parsed from, or 0
+func findLogLine(lines []string, finding *models.Finding, metadata map[string]interface{}) int {
+	raw, _ := metadata["raw_line"].(string)
+	for _, text := range []string{raw, finding.Content, finding.Description} {
+		text = strings.TrimSpace(ansiRegex.ReplaceAllString(text, ""))
+		if text == "" {
+			continue
+		}
+		for i, line := range lines {
+			if strings.Contains(line, text) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+package emba
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// cycloneDXComponent is the part of a CycloneDX component Odin keeps
+type cycloneDXComponent struct {
+	BOMRef   string `json:"bom-ref"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	PURL     string `json:"purl"`
+	CPE      string `json:"cpe"`
+	Licenses []struct {
+		License struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"license"`
+		Expression string `json:"expression"`
+	} `json:"licenses"`
+	Supplier *struct {
+		Name string `json:"name"`
+	} `json:"supplier"`
+	Publisher  string               `json:"publisher"`
+	Components []cycloneDXComponent `json:"components"` // nested, e.g. files of a package
+}
+
+// parseSBOMData parses the software components of F15's CycloneDX SBOM
+func (s *Service) parseSBOMData(logDir string, results *ParsedResults) error {
+	for _, sbomFile := range results.layout.SBOMFiles(logDir) {
+		content, err := os.ReadFile(sbomFile)
+		if err != nil {
+			continue // File doesn't exist, skip
+		}
+
+		var sbom struct {
+			BOMFormat  string               `json:"bomFormat"`
+			Components []cycloneDXComponent `json:"components"`
+		}
+		if err := json.Unmarshal(content, &sbom); err != nil {
+			log.Printf("Error parsing SBOM JSON %s: %v", sbomFile, err)
+			continue
+		}
+
+		results.Components = appendComponents(results.Components, sbom.Components)
+		results.FileInfo["sbom"] = map[string]interface{}{
+			"file":       sbomFile,
+			"format":     sbom.BOMFormat,
+			"components": len(results.Components),
+		}
+		break // Only process the first SBOM file found
+	}
+
+	return nil
+}
+
+// appendComponents flattens nested CycloneDX components
+func appendComponents(components []models.SBOMComponent, cdx []cycloneDXComponent) []models.SBOMComponent {
+	for _, c := range cdx {
+		if c.Name != "" {
+			var licenses []string
+			for _, license := range c.Licenses {
+				switch {
+				case license.License.ID != "":
+					licenses = append(licenses, license.License.ID)
+				case license.License.Name != "":
+					licenses = append(licenses, license.License.Name)
+				case license.Expression != "":
+					licenses = append(licenses, license.Expression)
+				}
+			}
+			component := models.SBOMComponent{
+				BOMRef:   c.BOMRef,
+				Type:     c.Type,
+				Name:     c.Name,
+				Version:  c.Version,
+				PURL:     c.PURL,
+				CPE:      c.CPE,
+				Supplier: c.Publisher,
+			}
+			if c.Supplier != nil && c.Supplier.Name != "" {
+				component.Supplier = c.Supplier.Name
+			}
+			if len(licenses) > 0 {
+				data, _ := json.Marshal(licenses)
+				component.Licenses = string(data)
+			}
+			components = append(components, component)
+		}
+		components = appendComponents(components, c.Components)
+	}
+	return components
+}
+
+var componentNameRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// MatchComponent finds the SBOM component a CVE finding's software belongs
+// to. It returns the component's index, or -1, and the evidence: the CPE
+// or purl naming the product at the CVE's version, or a name and version
+// (name_version) or name-only match. Stronger evidence wins.
+func MatchComponent(components []models.SBOMComponent, cve *models.CVEFinding) (int, string) {
+	software := normalizeComponentName(cve.SoftwareName)
+	if software == "" {
+		return -1, ""
+	}
+
+	best, evidence, rank := -1, "", 0
+	consider := func(i int, match string, r int) {
+		if r > rank {
+			best, evidence, rank = i, match, r
+		}
+	}
+	for i := range components {
+		component := &components[i]
+		versionMatches := cve.SoftwareVersion != "" && component.Version == cve.SoftwareVersion
+
+		if product, version := cpeProduct(component.CPE); product == software && version == cve.SoftwareVersion && versionMatches {
+			consider(i, "cpe", 4)
+		}
+		if name, version := purlName(component.PURL); name == software && version == cve.SoftwareVersion && versionMatches {
+			consider(i, "purl", 3)
+		}
+		if normalizeComponentName(component.Name) == software {
+			if versionMatches {
+				consider(i, "name_version", 2)
+			} else if cve.SoftwareVersion == "" || component.Version == "" {
+				consider(i, "name", 1)
+			}
+		}
+	}
+	return best, evidence
+}
+
+// MatchEvidence returns the CPE or purl of a component MatchComponent
+// matched a CVE finding to and the version it matched, or "" for a match
+// by name only
+func MatchEvidence(component *models.SBOMComponent, match string, cve *models.CVEFinding) (string, string) {
+	switch match {
+	case "cpe":
+		return component.CPE, "=" + cve.SoftwareVersion
+	case "purl":
+		return component.PURL, "=" + cve.SoftwareVersion
+	case "name_version":
+		return "", "=" + cve.SoftwareVersion
+	}
+	return "", ""
+}
+
+func normalizeComponentName(name string) string {
+	return componentNameRegex.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// cpeProduct returns the product and version of a CPE 2.3 name
+func cpeProduct(cpe string) (string, string) {
+	parts := strings.Split(cpe, ":")
+	if len(parts) < 6 || parts[0] != "cpe" {
+		return "", ""
+	}
+	return normalizeComponentName(parts[4]), parts[5]
+}
+
+// purlName returns the name and version of a package URL,
+// e.g. pkg:generic/busybox@1.36.1
+func purlName(purl string) (string, string) {
+	if !strings.HasPrefix(purl, "pkg:") {
+		return "", ""
+	}
+	purl, _, _ = strings.Cut(purl, "?")
+	path, version, _ := strings.Cut(purl, "@")
+	name := path[strings.LastIndex(path, "/")+1:]
+	return normalizeComponentName(name), version
+}
@@ -0,0 +1,189 @@
+package emba
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// Where a finding's severity came from, recorded as severity_source in its metadata
+const (
+	SeveritySourceEMBA      = "emba_severity" // severity column of an EMBA CSV
+	SeveritySourceCVSS      = "emba_cvss"     // CVSS score reported by EMBA
+	SeveritySourceHeuristic = "heuristic"     // keyword based classification by the parser
+)
+
+var (
+	inlineCVSSRegex   = regexp.MustCompile(`(?i)\bcvss(?:v?[23](?:\.[01])?)?(?:\s+base)?(?:\s+score)?\s*[:=]?\s*\(?\s*(\d{1,2}(?:\.\d)?)\b`)
+	cvssVectorRegex   = regexp.MustCompile(`CVSS:(?:[23]\.[01]|4\.0)/[A-Za-z:/]+|AV:[NALP]/AC:[LHM]/[A-Za-z:/]+`)
+	severityColumns   = []string{"severity", "criticality", "risk"}
+	cvssScoreColumns  = []string{"cvss", "cvss_score", "cvss3", "cvss_v3", "cvssv3", "cvss2", "cvss_v2", "score"}
+	cvssVectorColumns = []string{"cvss_vector", "vector", "cvss3_vector", "cvss_v3_vector"}
+)
+
+// annotateSeverity prefers CVSS scores EMBA printed alongside a finding over
+// the keyword heuristic, and records the severity source on every finding
+func (s *Service) annotateSeverity(results *ParsedResults) {
+	for i := range results.Findings {
+		finding := &results.Findings[i]
+
+		metadata := make(map[string]interface{})
+		if finding.FindingMetadata != "" {
+			json.Unmarshal([]byte(finding.FindingMetadata), &metadata)
+		}
+		if _, ok := metadata["severity_source"]; ok {
+			continue
+		}
+
+		text := finding.Description
+		if raw, ok := metadata["raw_line"].(string); ok && raw != "" {
+			text = raw
+		}
+
+		if score, raw, ok := inlineCVSS(text); ok {
+			metadata["emba_cvss"] = raw
+			if vector := cvssVectorRegex.FindString(text); vector != "" {
+				metadata["emba_cvss_vector"] = vector
+			}
+			metadata["heuristic_severity"] = finding.Severity
+			metadata["severity_source"] = SeveritySourceCVSS
+			finding.Severity = models.RiskLevel(s.scoreToSeverity(score))
+		} else {
+			metadata["severity_source"] = SeveritySourceHeuristic
+		}
+
+		finding.FindingMetadata = encodeMetadata(metadata)
+	}
+}
+
+// inlineCVSS finds a "CVSS: 9.8" style score in a log line
+func inlineCVSS(text string) (float64, string, bool) {
+	matches := inlineCVSSRegex.FindStringSubmatch(text)
+	if len(matches) < 2 {
+		return 0, "", false
+	}
+	score, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil || score < 0 || score > 10 {
+		return 0, "", false
+	}
+	return score, matches[1], true
+}
+
+// parseVulnerabilityCSV parses vulnerability findings from CSV files. Columns
+// are located by header name; files without a recognizable header fall back
+// to title, description, severity order.
+func (s *Service) parseVulnerabilityCSV(csvFile string) ([]models.Finding, error) {
+	var findings []models.Finding
+
+	content, err := os.ReadFile(csvFile)
+	if err != nil {
+		return findings, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(string(content)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.Comma = detectCSVDelimiter(string(content))
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return findings, err
+	}
+	if len(records) < 2 {
+		return findings, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	column := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				if value := s.cleanCSVField(record[i]); value != "" {
+					return value
+				}
+			}
+		}
+		return ""
+	}
+
+	_, hasTitle := columns["title"]
+	_, hasName := columns["name"]
+	positional := !hasTitle && !hasName
+
+	// Skip header line
+	for _, record := range records[1:] {
+		if len(record) < 3 {
+			continue
+		}
+
+		title := column(record, "title", "name")
+		description := column(record, "description", "details", "message")
+		severityValue := column(record, severityColumns...)
+		scoreValue := column(record, cvssScoreColumns...)
+		vector := column(record, cvssVectorColumns...)
+		if positional {
+			title = s.cleanCSVField(record[0])
+			description = s.cleanCSVField(record[1])
+			if severityValue == "" && scoreValue == "" {
+				severityValue = s.cleanCSVField(record[2])
+			}
+		}
+
+		metadata := map[string]interface{}{"csv_source": csvFile}
+		severity := ""
+
+		// EMBA's own scores win over anything we'd infer
+		if scoreValue != "" {
+			metadata["emba_cvss"] = scoreValue
+			if score, err := strconv.ParseFloat(scoreValue, 64); err == nil && score >= 0 && score <= 10 {
+				severity = s.scoreToSeverity(score)
+				metadata["severity_source"] = SeveritySourceCVSS
+			}
+		}
+		if vector != "" {
+			metadata["emba_cvss_vector"] = vector
+		}
+		if severityValue != "" {
+			metadata["emba_severity"] = severityValue
+			if severity == "" {
+				severity = s.normalizeSeverity(severityValue)
+				metadata["severity_source"] = SeveritySourceEMBA
+			}
+		}
+		if severity == "" {
+			severity = s.determineSeverity(title + " " + description)
+			metadata["severity_source"] = SeveritySourceHeuristic
+		}
+
+		finding := models.Finding{
+			Title:           title,
+			Description:     description,
+			Severity:        models.RiskLevel(severity),
+			Type:            models.FindingType("vulnerability"),
+			FilePath:        csvFile,
+			FindingMetadata: encodeMetadata(metadata),
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+// detectCSVDelimiter returns ';' for EMBA's semicolon separated CSVs and ',' otherwise
+func detectCSVDelimiter(content string) rune {
+	header := content
+	if i := strings.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		return ';'
+	}
+	return ','
+}
@@ -0,0 +1,102 @@
+// Package epss fetches FIRST's Exploit Prediction Scoring System scores:
+// the probability a CVE is exploited in the wild within the next 30 days
+package epss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/config"
+)
+
+const (
+	apiURL = "https://api.first.org/data/v1/epss"
+
+	// BatchSize is the number of CVEs asked for per request
+	BatchSize = 100
+)
+
+// Client fetches EPSS scores
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// Source looks EPSS scores up: the API, or the imported mirror of FIRST's
+// daily CSV in offline mode
+type Source interface {
+	// Scores returns the current scores of up to BatchSize CVEs by CVE ID.
+	// CVEs not scored are missing from it.
+	Scores(ctx context.Context, cveIDs []string) (map[string]Score, error)
+}
+
+// New returns an EPSS client, or nil when EPSS_ENRICHMENT is off or in
+// offline mode
+func New(cfg *config.Config) *Client {
+	if !cfg.EPSSEnrichment || cfg.OfflineMode {
+		return nil
+	}
+	return &Client{
+		baseURL: apiURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Score is a CVE's EPSS score on a day
+type Score struct {
+	CVE        string  `json:"cve"`
+	EPSS       float64 `json:"epss"`       // probability of exploitation, 0-1
+	Percentile float64 `json:"percentile"` // share of CVEs scored lower, 0-1
+	Date       string  `json:"date"`
+}
+
+// Scores returns the current scores of up to BatchSize CVEs by CVE ID. CVEs
+// EPSS doesn't score yet are missing from it.
+func (c *Client) Scores(ctx context.Context, cveIDs []string) (map[string]Score, error) {
+	if len(cveIDs) > BatchSize {
+		return nil, fmt.Errorf("at most %d CVEs can be looked up at once", BatchSize)
+	}
+	params := url.Values{"cve": {strings.Join(cveIDs, ",")}, "limit": {strconv.Itoa(BatchSize)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("EPSS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("EPSS returned status %d", resp.StatusCode)
+	}
+
+	// The API sends the scores as strings
+	var body struct {
+		Data []struct {
+			CVE        string `json:"cve"`
+			EPSS       string `json:"epss"`
+			Percentile string `json:"percentile"`
+			Date       string `json:"date"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode EPSS response: %w", err)
+	}
+
+	scores := make(map[string]Score, len(body.Data))
+	for _, entry := range body.Data {
+		probability, err := strconv.ParseFloat(entry.EPSS, 64)
+		if err != nil {
+			continue
+		}
+		percentile, _ := strconv.ParseFloat(entry.Percentile, 64)
+		scores[entry.CVE] = Score{CVE: entry.CVE, EPSS: probability, Percentile: percentile, Date: entry.Date}
+	}
+	return scores, nil
+}
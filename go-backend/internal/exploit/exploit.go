@@ -0,0 +1,136 @@
+package exploit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+)
+
+// pocInGitHubURL serves PoC-in-GitHub's per-CVE lists of proof of concept
+// repositories, e.g. 2016/CVE-2016-5195.json
+const pocInGitHubURL = "https://raw.githubusercontent.com/nomi-sec/PoC-in-GitHub/master/"
+
+// Lookup finds public exploits of CVEs online in addition to those EMBA's
+// exploit aggregation reported
+type Lookup struct {
+	baseURL string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// New returns the online exploit lookup, or nil when EXPLOIT_LOOKUP is off
+// or in offline mode, where the imported exploit-db index is searched
+// instead
+func New(cfg *config.Config) *Lookup {
+	if !cfg.ExploitLookup || cfg.OfflineMode {
+		return nil
+	}
+	return &Lookup{
+		baseURL: pocInGitHubURL,
+		timeout: cfg.ExploitLookupTimeout,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Enrich adds the PoC repositories PoC-in-GitHub lists to the CVE findings
+// and marks those CVEs as having an exploit. Lookups stop at the timeout;
+// the CVEs not looked up by then keep what EMBA reported.
+func (l *Lookup) Enrich(cves []models.CVEFinding) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	found := make(map[string][]string)
+	for i := range cves {
+		cve := &cves[i]
+		pocs, ok := found[cve.CVEID]
+		if !ok {
+			var err error
+			pocs, err = l.pocs(ctx, cve.CVEID)
+			if ctx.Err() != nil {
+				log.Printf("Exploit lookup stopped after %s, %d of %d CVE findings checked", l.timeout, i, len(cves))
+				return
+			}
+			if err != nil {
+				log.Printf("Exploit lookup for %s failed: %v", cve.CVEID, err)
+			}
+			found[cve.CVEID] = pocs
+		}
+		if len(pocs) == 0 {
+			continue
+		}
+
+		cve.PoCURLs = Merge(cve.PoCURLs, pocs...)
+		cve.ExploitSources = Merge(cve.ExploitSources, "github")
+		cve.ExploitAvailable = true
+	}
+}
+
+// pocs returns the repositories PoC-in-GitHub lists for a CVE
+func (l *Lookup) pocs(ctx context.Context, cveID string) ([]string, error) {
+	parts := strings.SplitN(cveID, "-", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid CVE ID %q", cveID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.baseURL+parts[1]+"/"+cveID+".json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("PoC-in-GitHub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("PoC-in-GitHub returned status %d", resp.StatusCode)
+	}
+
+	var repositories []struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&repositories); err != nil {
+		return nil, fmt.Errorf("failed to decode PoC-in-GitHub response: %w", err)
+	}
+
+	var urls []string
+	for _, repository := range repositories {
+		if repository.HTMLURL != "" {
+			urls = append(urls, repository.HTMLURL)
+		}
+	}
+	return urls, nil
+}
+
+// Merge adds values to a JSON array of strings, keeping it sorted and
+// free of duplicates
+func Merge(list string, values ...string) string {
+	var merged []string
+	if list != "" {
+		_ = json.Unmarshal([]byte(list), &merged)
+	}
+	seen := make(map[string]bool, len(merged))
+	for _, value := range merged {
+		seen[value] = true
+	}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			merged = append(merged, value)
+		}
+	}
+	sort.Strings(merged)
+	data, _ := json.Marshal(merged)
+	return string(data)
+}
@@ -0,0 +1,656 @@
+package extract
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/fwformat"
+)
+
+const (
+	maxBootImageSize    = 256 << 20 // boot images are tens of MiB
+	maxAndroidEntrySize = 16 << 30  // a partition image in an OTA zip
+	avbFooterSize       = 64
+	avbHeaderSize       = 256
+)
+
+// ErrNotAndroid is returned for images that aren't Android boot images,
+// sparse images or OTA zips
+var ErrNotAndroid = errors.New("not an Android boot image, sparse image or OTA package")
+
+// AndroidInfo is the Android specific metadata found while unpacking an
+// image, stored with the project's firmware info
+type AndroidInfo struct {
+	ImageType  string     `json:"image_type"` // boot, sparse or ota
+	Boot       *BootImage `json:"boot,omitempty"`
+	Build      string     `json:"build,omitempty"`  // post-build fingerprint of an OTA
+	Device     string     `json:"device,omitempty"` // device an OTA is for
+	Partitions []string   `json:"partitions,omitempty"`
+	AVB        []AVBImage `json:"avb,omitempty"`
+	DMVerity   bool       `json:"dm_verity"`
+	Verity     []string   `json:"verity_partitions,omitempty"`
+	Unhandled  []string   `json:"unhandled,omitempty"` // parts left for EMBA to unpack
+}
+
+// BootImage is the header of an Android boot image
+type BootImage struct {
+	HeaderVersion uint32 `json:"header_version"`
+	Name          string `json:"name,omitempty"`
+	Cmdline       string `json:"cmdline,omitempty"`
+	OSVersion     string `json:"os_version,omitempty"`
+	PatchLevel    string `json:"patch_level,omitempty"`
+	KernelSize    uint32 `json:"kernel_size"`
+	RamdiskSize   uint32 `json:"ramdisk_size"`
+}
+
+// AVBImage is the Android Verified Boot metadata of an image, from a
+// vbmeta partition or the footer of a signed partition
+type AVBImage struct {
+	Image                string   `json:"image"`
+	Algorithm            string   `json:"algorithm"`
+	RollbackIndex        uint64   `json:"rollback_index"`
+	Release              string   `json:"release,omitempty"`
+	HashtreeDisabled     bool     `json:"hashtree_disabled"`
+	VerificationDisabled bool     `json:"verification_disabled"`
+	Hashtree             []string `json:"hashtree_partitions,omitempty"` // verified by dm-verity
+	Hash                 []string `json:"hash_partitions,omitempty"`
+	Chained              []string `json:"chained_partitions,omitempty"`
+}
+
+var avbAlgorithms = []string{
+	"NONE", "SHA256_RSA2048", "SHA256_RSA4096", "SHA256_RSA8192",
+	"SHA512_RSA2048", "SHA512_RSA4096", "SHA512_RSA8192",
+}
+
+// IsAndroid reports whether the firmware is an Android boot image, sparse
+// image or OTA zip, which Odin unpacks itself before EMBA sees them
+func IsAndroid(firmwarePath, firmwareType string) bool {
+	switch firmwareType {
+	case fwformat.AndroidBoot, fwformat.AndroidSparse:
+		return true
+	case fwformat.Zip:
+		return isOTA(firmwarePath)
+	}
+	return false
+}
+
+func isOTA(path string) bool {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return false
+	}
+	defer archive.Close()
+	for _, entry := range archive.File {
+		switch entry.Name {
+		case "META-INF/com/android/metadata", "META-INF/com/google/android/update-binary", "payload.bin":
+			return true
+		}
+	}
+	return false
+}
+
+// Android unpacks an Android image into dir: the kernel and ramdisk of boot
+// images, the filesystem of sparse (and raw ext4) partition images and the
+// partition images of OTA zips. Parts Odin can't unpack, like the payload
+// of A/B OTAs, are copied into dir for EMBA.
+func (e *Extractor) Android(ctx context.Context, firmwarePath, dir string) (*AndroidInfo, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+	format, err := fwformat.IdentifyFile(firmwarePath)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AndroidInfo{}
+	switch format {
+	case fwformat.AndroidBoot:
+		info.ImageType = "boot"
+		err = e.unpackAndroidImage(ctx, firmwarePath, "boot", dir, info)
+	case fwformat.AndroidSparse:
+		info.ImageType = "sparse"
+		err = e.unpackAndroidImage(ctx, firmwarePath, "system", dir, info)
+	case fwformat.Zip:
+		info.ImageType = "ota"
+		err = e.unpackOTA(ctx, firmwarePath, dir, info)
+	default:
+		return nil, ErrNotAndroid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hasFiles(dir) {
+		return nil, ErrNothingExtracted
+	}
+	for _, avb := range info.AVB {
+		if len(avb.Hashtree) > 0 && !avb.HashtreeDisabled && !avb.VerificationDisabled {
+			info.DMVerity = true
+			info.Verity = appendUnique(info.Verity, avb.Hashtree...)
+		}
+	}
+	return info, nil
+}
+
+// unpackAndroidImage unpacks one partition image into dir/name
+func (e *Extractor) unpackAndroidImage(ctx context.Context, path, name, dir string, info *AndroidInfo) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	header, err := readHeader(path, 4096)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte("ANDROID!")):
+		if err := unpackBoot(path, filepath.Join(dir, name), info); err != nil {
+			return err
+		}
+	case bytes.HasPrefix(header, []byte{0x3a, 0xff, 0x26, 0xed}):
+		raw := filepath.Join(dir, name+".raw.img")
+		if err := unsparse(path, raw); err != nil {
+			return fmt.Errorf("failed to convert sparse image %s: %w", name, err)
+		}
+		return e.unpackAndroidImage(ctx, raw, name, dir, info)
+	case bytes.HasPrefix(header, []byte("AVB0")):
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if avb, ok := parseVBMeta(data, name); ok {
+			info.AVB = append(info.AVB, avb)
+		}
+		return nil
+	case isExt4(header):
+		if err := e.dumpExt4(ctx, path, filepath.Join(dir, name)); err != nil {
+			// The image stays for EMBA's extractor
+			info.Unhandled = append(info.Unhandled, fmt.Sprintf("%s: %v", name, err))
+		} else if strings.HasPrefix(path, dir) {
+			os.Remove(path)
+		}
+	case len(header) >= 1028 && binary.LittleEndian.Uint32(header[1024:]) == 0xe0f5e1e2:
+		info.Unhandled = append(info.Unhandled, name+": EROFS filesystem")
+	}
+
+	if avb, ok := readAVBFooter(path, name); ok {
+		info.AVB = append(info.AVB, avb)
+	}
+	return nil
+}
+
+// unpackBoot writes the kernel and ramdisk of a boot image below dir and
+// unpacks the ramdisk when it's a (gzip compressed) cpio archive
+func unpackBoot(path, dir string, info *AndroidInfo) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if stat.Size() > maxBootImageSize {
+		return fmt.Errorf("boot image is larger than %d MiB", maxBootImageSize>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) < 1632 {
+		return errors.New("truncated boot image header")
+	}
+
+	le := binary.LittleEndian
+	boot := &BootImage{
+		HeaderVersion: le.Uint32(data[40:]),
+		KernelSize:    le.Uint32(data[8:]),
+	}
+	var pageSize uint32
+	var osVersion uint32
+	if boot.HeaderVersion >= 3 {
+		// v3 and v4 moved the second stage and addresses to vendor_boot
+		boot.RamdiskSize = le.Uint32(data[12:])
+		osVersion = le.Uint32(data[16:])
+		boot.Cmdline = cString(data[44 : 44+1536])
+		pageSize = 4096
+	} else {
+		boot.RamdiskSize = le.Uint32(data[16:])
+		pageSize = le.Uint32(data[36:])
+		osVersion = le.Uint32(data[44:])
+		boot.Name = cString(data[48:64])
+		boot.Cmdline = strings.TrimSpace(cString(data[64:576]) + " " + cString(data[608:1632]))
+	}
+	if pageSize == 0 || pageSize > 65536 {
+		return fmt.Errorf("invalid boot image page size %d", pageSize)
+	}
+	if osVersion != 0 {
+		version := osVersion >> 11
+		boot.OSVersion = fmt.Sprintf("%d.%d.%d", version>>14, version>>7&0x7f, version&0x7f)
+		if level := osVersion & 0x7ff; level != 0 {
+			boot.PatchLevel = fmt.Sprintf("%04d-%02d", 2000+level>>4, level&0xf)
+		}
+	}
+	if info.Boot == nil {
+		info.Boot = boot
+	}
+	if strings.Contains(boot.Cmdline, "dm=") || strings.Contains(boot.Cmdline, "veritymode") {
+		info.DMVerity = true
+	}
+
+	kernelOffset := uint64(pageSize)
+	ramdiskOffset := kernelOffset + roundUp(uint64(boot.KernelSize), uint64(pageSize))
+	if kernelOffset+uint64(boot.KernelSize) > uint64(len(data)) || ramdiskOffset+uint64(boot.RamdiskSize) > uint64(len(data)) {
+		return errors.New("boot image is truncated")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if boot.KernelSize > 0 {
+		kernel := data[kernelOffset : kernelOffset+uint64(boot.KernelSize)]
+		if err := os.WriteFile(filepath.Join(dir, "kernel"), kernel, 0644); err != nil {
+			return err
+		}
+	}
+	if boot.RamdiskSize == 0 {
+		return nil
+	}
+
+	ramdisk := data[ramdiskOffset : ramdiskOffset+uint64(boot.RamdiskSize)]
+	archive := ramdisk
+	if bytes.HasPrefix(ramdisk, gzipMagic) {
+		if inflated, err := gunzip(ramdisk); err == nil {
+			archive = inflated
+		}
+	}
+	if archive = findCPIO(archive); archive == nil {
+		// lz4 and other compressions are left to EMBA
+		info.Unhandled = append(info.Unhandled, filepath.Base(dir)+": compressed ramdisk")
+		return os.WriteFile(filepath.Join(dir, "ramdisk"), ramdisk, 0644)
+	}
+	root := filepath.Join(dir, "ramdisk")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	if _, err := writeCPIO(archive, root); err != nil {
+		return err
+	}
+	if mounts := fstabVerity(root); len(mounts) > 0 {
+		info.DMVerity = true
+		info.Verity = appendUnique(info.Verity, mounts...)
+	}
+	return nil
+}
+
+// fstabVerity returns the partitions the fstabs of a ramdisk verify with
+// dm-verity, flagged verify (Android 7 to 9) or avb (10 and later)
+func fstabVerity(root string) []string {
+	var mounts []string
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() || !strings.HasPrefix(d.Name(), "fstab") {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 5 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			for _, flag := range strings.Split(fields[4], ",") {
+				if flag == "verify" || flag == "avb" || strings.HasPrefix(flag, "avb=") {
+					// Named like the partitions of vbmeta; "/" is system-as-root
+					partition := strings.TrimPrefix(fields[1], "/")
+					if partition == "" {
+						partition = "system"
+					}
+					mounts = appendUnique(mounts, partition)
+					break
+				}
+			}
+		}
+		return nil
+	})
+	return mounts
+}
+
+// unsparse converts an Android sparse image into the raw image it describes
+func unsparse(path, out string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+
+	header := make([]byte, 28)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return err
+	}
+	le := binary.LittleEndian
+	fileHeaderSize := int64(le.Uint16(header[8:]))
+	chunkHeaderSize := int64(le.Uint16(header[10:]))
+	blockSize := int64(le.Uint32(header[12:]))
+	totalBlocks := int64(le.Uint32(header[16:]))
+	totalChunks := le.Uint32(header[20:])
+	if fileHeaderSize < 28 || chunkHeaderSize < 12 || blockSize == 0 || blockSize%4 != 0 {
+		return errors.New("invalid sparse image header")
+	}
+	if _, err := reader.Discard(int(fileHeaderSize - 28)); err != nil {
+		return err
+	}
+
+	raw, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer raw.Close()
+
+	chunk := make([]byte, chunkHeaderSize)
+	var block int64
+	for i := uint32(0); i < totalChunks; i++ {
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return fmt.Errorf("chunk %d: %w", i, err)
+		}
+		blocks := int64(le.Uint32(chunk[4:]))
+		dataSize := int64(le.Uint32(chunk[8:])) - chunkHeaderSize
+		if block+blocks > totalBlocks || dataSize < 0 {
+			return fmt.Errorf("chunk %d exceeds the image", i)
+		}
+		if _, err := raw.Seek(block*blockSize, io.SeekStart); err != nil {
+			return err
+		}
+
+		switch le.Uint16(chunk[0:]) {
+		case 0xcac1: // raw
+			if dataSize != blocks*blockSize {
+				return fmt.Errorf("chunk %d: raw data size mismatch", i)
+			}
+			if _, err := io.CopyN(raw, reader, dataSize); err != nil {
+				return err
+			}
+		case 0xcac2: // fill
+			pattern := make([]byte, 4)
+			if _, err := io.ReadFull(reader, pattern); err != nil {
+				return err
+			}
+			if !bytes.Equal(pattern, make([]byte, 4)) {
+				filled := bytes.Repeat(pattern, int(blockSize/4))
+				for b := int64(0); b < blocks; b++ {
+					if _, err := raw.Write(filled); err != nil {
+						return err
+					}
+				}
+			}
+			if _, err := reader.Discard(int(dataSize - 4)); err != nil {
+				return err
+			}
+		case 0xcac3, 0xcac4: // don't care, crc32
+			if _, err := reader.Discard(int(dataSize)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("chunk %d: unknown type", i)
+		}
+		block += blocks
+	}
+	return raw.Truncate(totalBlocks * blockSize)
+}
+
+// dumpExt4 copies the files of an ext4 image into dir with debugfs, which
+// needs neither root nor a loop mount
+func (e *Extractor) dumpExt4(ctx context.Context, image, dir string) error {
+	debugfs, err := exec.LookPath("debugfs")
+	if err != nil {
+		return errors.New("debugfs is not installed")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, debugfs, "-R", fmt.Sprintf("rdump / %s", dir), image)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("debugfs failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if !hasFiles(dir) {
+		return ErrNothingExtracted
+	}
+	return nil
+}
+
+// unpackOTA extracts the partition images of an OTA zip and unpacks them.
+// Entries are written under their base name only, so none escapes dir.
+func (e *Extractor) unpackOTA(ctx context.Context, path, dir string, info *AndroidInfo) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	images := filepath.Join(dir, "images")
+	if err := os.MkdirAll(images, 0755); err != nil {
+		return err
+	}
+	for _, entry := range archive.File {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := filepath.Base(entry.Name)
+		switch {
+		case entry.Name == "META-INF/com/android/metadata":
+			content, err := readZipEntry(entry, 1<<20)
+			if err != nil {
+				return err
+			}
+			for _, line := range strings.Split(string(content), "\n") {
+				key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+				switch key {
+				case "post-build":
+					info.Build = value
+				case "pre-device":
+					info.Device = value
+				}
+			}
+		case strings.HasSuffix(name, ".img"):
+			target := filepath.Join(images, name)
+			if err := extractZipEntry(entry, target); err != nil {
+				return err
+			}
+			partition := strings.TrimSuffix(name, ".img")
+			info.Partitions = append(info.Partitions, partition)
+			if err := e.unpackAndroidImage(ctx, target, partition, dir, info); err != nil {
+				return fmt.Errorf("failed to unpack %s: %w", name, err)
+			}
+		case name == "payload.bin", strings.Contains(name, ".new.dat"), strings.HasSuffix(name, ".transfer.list"):
+			// A/B payloads and block based OTA data need tools Odin doesn't
+			// ship; EMBA's extractor may handle them
+			if err := extractZipEntry(entry, filepath.Join(images, name)); err != nil {
+				return err
+			}
+			info.Unhandled = append(info.Unhandled, name)
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(entry *zip.File, target string) error {
+	if entry.UncompressedSize64 > maxAndroidEntrySize {
+		return fmt.Errorf("%s is too large to extract", entry.Name)
+	}
+	r, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	// The size in the directory may lie
+	if n, err := io.CopyN(out, r, maxAndroidEntrySize+1); err != nil && err != io.EOF {
+		return err
+	} else if n > maxAndroidEntrySize {
+		return fmt.Errorf("%s is too large to extract", entry.Name)
+	}
+	return nil
+}
+
+func readZipEntry(entry *zip.File, limit int64) ([]byte, error) {
+	r, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, limit))
+}
+
+// readAVBFooter reads the vbmeta a signed partition carries at its end
+func readAVBFooter(path, name string) (AVBImage, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return AVBImage{}, false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.Size() < avbFooterSize {
+		return AVBImage{}, false
+	}
+	footer := make([]byte, avbFooterSize)
+	if _, err := f.ReadAt(footer, stat.Size()-avbFooterSize); err != nil || !bytes.HasPrefix(footer, []byte("AVBf")) {
+		return AVBImage{}, false
+	}
+	offset := int64(binary.BigEndian.Uint64(footer[20:]))
+	size := int64(binary.BigEndian.Uint64(footer[28:]))
+	if offset < 0 || size < avbHeaderSize || size > 1<<20 || offset+size > stat.Size() {
+		return AVBImage{}, false
+	}
+	vbmeta := make([]byte, size)
+	if _, err := f.ReadAt(vbmeta, offset); err != nil {
+		return AVBImage{}, false
+	}
+	return parseVBMeta(vbmeta, name)
+}
+
+// parseVBMeta parses a vbmeta image: its header and the partitions its
+// descriptors verify
+func parseVBMeta(data []byte, name string) (AVBImage, bool) {
+	if len(data) < avbHeaderSize || !bytes.HasPrefix(data, []byte("AVB0")) {
+		return AVBImage{}, false
+	}
+	be := binary.BigEndian
+	avb := AVBImage{
+		Image:         name,
+		Algorithm:     "unknown",
+		RollbackIndex: be.Uint64(data[112:]),
+		Release:       cString(data[128:176]),
+	}
+	if algorithm := be.Uint32(data[28:]); int(algorithm) < len(avbAlgorithms) {
+		avb.Algorithm = avbAlgorithms[algorithm]
+	}
+	flags := be.Uint32(data[120:])
+	avb.HashtreeDisabled = flags&1 != 0
+	avb.VerificationDisabled = flags&2 != 0
+
+	auxStart := avbHeaderSize + be.Uint64(data[12:])
+	start := auxStart + be.Uint64(data[96:])
+	end := start + be.Uint64(data[104:])
+	if end > uint64(len(data)) || start > end {
+		return avb, true
+	}
+	descriptors := data[start:end]
+	for len(descriptors) >= 16 {
+		tag := be.Uint64(descriptors[0:])
+		size := be.Uint64(descriptors[8:])
+		if size > uint64(len(descriptors)-16) {
+			break
+		}
+		descriptor := descriptors[:16+size]
+		switch tag {
+		case 1: // hashtree
+			if partition, ok := descriptorName(descriptor, 104, 180); ok {
+				avb.Hashtree = append(avb.Hashtree, partition)
+			}
+		case 2: // hash
+			if partition, ok := descriptorName(descriptor, 56, 132); ok {
+				avb.Hash = append(avb.Hash, partition)
+			}
+		case 4: // chain partition
+			if partition, ok := descriptorName(descriptor, 20, 92); ok {
+				avb.Chained = append(avb.Chained, partition)
+			}
+		}
+		descriptors = descriptors[16+size:]
+	}
+	return avb, true
+}
+
+// descriptorName reads the partition name of a descriptor, whose length is
+// at lengthOffset and which starts at nameOffset
+func descriptorName(descriptor []byte, lengthOffset, nameOffset int) (string, bool) {
+	if len(descriptor) < nameOffset {
+		return "", false
+	}
+	length := int(binary.BigEndian.Uint32(descriptor[lengthOffset:]))
+	if length > len(descriptor)-nameOffset {
+		return "", false
+	}
+	return string(descriptor[nameOffset : nameOffset+length]), true
+}
+
+func isExt4(header []byte) bool {
+	return len(header) >= 1082 && binary.LittleEndian.Uint16(header[1080:]) == 0xef53
+}
+
+func readHeader(path string, size int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	header := make([]byte, size)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return header[:n], nil
+}
+
+func cString(field []byte) string {
+	if i := bytes.IndexByte(field, 0); i >= 0 {
+		field = field[:i]
+	}
+	return strings.TrimSpace(string(field))
+}
+
+func roundUp(n, to uint64) uint64 {
+	return (n + to - 1) / to * to
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
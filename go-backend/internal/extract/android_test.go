@@ -0,0 +1,67 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestUnsparse converts testdata/android/system.sparse.img, 8 blocks of 64
+// bytes: 2 raw, 2 filled with deadbeef, 1 don't care, 1 filled with zeros,
+// 1 raw, a crc32 chunk and a trailing don't care block
+func TestUnsparse(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "system.raw.img")
+	if err := unsparse(filepath.Join("testdata", "android", "system.sparse.img"), out); err != nil {
+		t.Fatalf("unsparse: %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []byte
+	for i := 0; i < 128; i++ {
+		want = append(want, byte(i))
+	}
+	want = append(want, bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 32)...)
+	want = append(want, make([]byte, 128)...)
+	want = append(want, []byte("tail block")...)
+	want = append(want, bytes.Repeat([]byte("."), 54)...)
+	want = append(want, make([]byte, 64)...)
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("raw image differs:\ngot  %x\nwant %x", got, want)
+	}
+}
+
+func TestUnsparseInvalid(t *testing.T) {
+	for _, image := range []string{"overflow.sparse.img", "short.sparse.img"} {
+		out := filepath.Join(t.TempDir(), "raw.img")
+		if err := unsparse(filepath.Join("testdata", "android", image), out); err == nil {
+			t.Errorf("%s: converted without error", image)
+		}
+	}
+}
+
+// TestAndroidSparse unpacks a sparse image whose raw image holds no
+// filesystem Odin knows, which is kept in the directory for EMBA
+func TestAndroidSparse(t *testing.T) {
+	dir := t.TempDir()
+	e := &Extractor{}
+	info, err := e.Android(context.Background(), filepath.Join("testdata", "android", "system.sparse.img"), dir)
+	if err != nil {
+		t.Fatalf("Android: %v", err)
+	}
+	if info.ImageType != "sparse" {
+		t.Errorf("image type %q, want sparse", info.ImageType)
+	}
+	stat, err := os.Stat(filepath.Join(dir, "system.raw.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != 8*64 {
+		t.Errorf("raw image size %d, want %d", stat.Size(), 8*64)
+	}
+}
@@ -0,0 +1,116 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+)
+
+// checkSource marks the findings of Odin's own checks in their metadata
+const checkSource = "extraction_check"
+
+// Largest file the content checks read
+const maxCheckedFileSize = 1 << 20
+
+// Check runs Odin's lightweight checks over an extracted filesystem:
+// accounts without a password or with a weak hash, telnet daemons and risky
+// file permissions. Keys and other secrets are left to the secret scanner.
+// Paths are reported relative to root.
+func Check(root string) ([]models.Finding, error) {
+	var findings []models.Finding
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel := "/" + filepath.ToSlash(strings.TrimPrefix(p, root+string(filepath.Separator)))
+		name := d.Name()
+
+		if info.Mode()&(fs.ModeSetuid|fs.ModeSetgid) != 0 {
+			findings = append(findings, newFinding(models.FindingConfigIssue, models.RiskLow, rel,
+				fmt.Sprintf("Setuid/setgid file: %s", rel),
+				fmt.Sprintf("%s runs with the privileges of its owner (mode %s)", rel, info.Mode()),
+				map[string]interface{}{"check": "setuid"}))
+		}
+		if info.Mode().Perm()&0002 != 0 && !strings.Contains(rel, "/tmp/") {
+			findings = append(findings, newFinding(models.FindingConfigIssue, models.RiskMedium, rel,
+				fmt.Sprintf("World-writable file: %s", rel),
+				fmt.Sprintf("Any user can modify %s (mode %s)", rel, info.Mode()),
+				map[string]interface{}{"check": "world_writable"}))
+		}
+		if name == "telnetd" || name == "utelnetd" {
+			findings = append(findings, newFinding(models.FindingService, models.RiskMedium, rel,
+				"Telnet daemon present",
+				fmt.Sprintf("%s serves unencrypted remote logins", rel),
+				map[string]interface{}{"check": "telnet", "service_name": name}))
+		}
+
+		if info.Size() > maxCheckedFileSize {
+			return nil
+		}
+		if name == "passwd" || name == "shadow" {
+			findings = append(findings, checkAccounts(p, rel)...)
+		}
+		return nil
+	})
+	return findings, err
+}
+
+// checkAccounts reports accounts in a passwd or shadow file that need no
+// password or use a hash that is quickly cracked
+func checkAccounts(path, rel string) []models.Finding {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var findings []models.Finding
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) < 2 || fields[0] == "" || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		account, hash := fields[0], fields[1]
+
+		switch emba.HashAlgorithm(hash, true) {
+		case models.HashNone:
+			findings = append(findings, newFinding(models.FindingCredential, models.RiskCritical, rel,
+				fmt.Sprintf("Account %s has no password", account),
+				fmt.Sprintf("%s lets %s log in without a password", rel, account),
+				map[string]interface{}{"check": "empty_password", "account": account}))
+		case models.HashDES, models.HashMD5Crypt:
+			algorithm := emba.HashAlgorithm(hash, true)
+			findings = append(findings, newFinding(models.FindingCredential, models.RiskHigh, rel,
+				fmt.Sprintf("Weak password hash for account %s", account),
+				fmt.Sprintf("%s stores the password of %s as %s, which is quickly cracked", rel, account, algorithm),
+				map[string]interface{}{"check": "weak_hash", "account": account, "algorithm": algorithm}))
+		}
+	}
+	return findings
+}
+
+func newFinding(findingType models.FindingType, severity models.RiskLevel, rel, title, description string, metadata map[string]interface{}) models.Finding {
+	metadata["source"] = checkSource
+	encoded, _ := json.Marshal(metadata)
+	finding := models.Finding{
+		Type:            findingType,
+		Title:           title,
+		Description:     description,
+		Severity:        severity,
+		FilePath:        rel,
+		FindingMetadata: string(encoded),
+		Confidence:      models.ConfidenceMedium,
+		OccurrenceCount: 1,
+	}
+	finding.Fingerprint = finding.ComputeFingerprint()
+	return finding
+}
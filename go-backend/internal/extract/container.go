@@ -0,0 +1,393 @@
+package extract
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/fwformat"
+)
+
+// Largest manifest, index or config blob read from an image
+const maxImageMetadataSize = 4 << 20
+
+// whiteoutPrefix marks files a layer deletes from the layers below it
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// ContainerInfo describes a container image, stored with the project's
+// firmware info
+type ContainerInfo struct {
+	Format       string            `json:"format"` // docker or oci
+	RepoTags     []string          `json:"repo_tags,omitempty"`
+	Architecture string            `json:"architecture,omitempty"`
+	OS           string            `json:"os,omitempty"`
+	Created      string            `json:"created,omitempty"`
+	User         string            `json:"user"`
+	Entrypoint   []string          `json:"entrypoint,omitempty"`
+	Cmd          []string          `json:"cmd,omitempty"`
+	ExposedPorts []string          `json:"exposed_ports,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Layers       int               `json:"layers"`
+}
+
+// imageConfig is the part of an image config blob Odin reads
+type imageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Created      string `json:"created"`
+	Config       struct {
+		User         string              `json:"User"`
+		Entrypoint   []string            `json:"Entrypoint"`
+		Cmd          []string            `json:"Cmd"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Labels       map[string]string   `json:"Labels"`
+	} `json:"config"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+// IsContainerImage reports whether the firmware is a docker save tarball or
+// an OCI image layout, plain or gzip compressed
+func IsContainerImage(firmwarePath, firmwareType string) bool {
+	if firmwareType != fwformat.Tar && firmwareType != fwformat.Gzip {
+		return false
+	}
+	found := false
+	walkTar(firmwarePath, func(header *tar.Header, _ io.Reader) error {
+		switch path.Clean(header.Name) {
+		case "manifest.json", "oci-layout":
+			found = true
+			return errStopWalk
+		}
+		return nil
+	})
+	return found
+}
+
+var errStopWalk = errors.New("stop")
+
+// walkTar calls fn for every entry of a (gzip compressed) tar file
+func walkTar(file string, fn func(*tar.Header, io.Reader) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := maybeGunzip(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(header, archive); err != nil {
+			if err == errStopWalk {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// maybeGunzip decompresses r if it starts with a gzip header
+func maybeGunzip(r *bufio.Reader) (io.Reader, error) {
+	magic, err := r.Peek(2)
+	if err != nil || !bytes.Equal(magic, gzipMagic[:2]) {
+		return r, nil
+	}
+	return gzip.NewReader(r)
+}
+
+// ContainerImage unpacks the layers of a docker save tarball or OCI image
+// layout into dir, applying each layer's whiteouts, and writes the image
+// config to dir/.odin-image-config.json where the secret scanner finds
+// credentials passed as environment variables
+func (e *Extractor) ContainerImage(ctx context.Context, firmwarePath, dir string) (*ContainerInfo, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+	// The image's blobs are unpacked next to the root filesystem, not into it
+	blobs, err := os.MkdirTemp(filepath.Dir(dir), "image-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(blobs)
+
+	err = walkTar(firmwarePath, func(header *tar.Header, r io.Reader) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+		name := path.Clean("/" + header.Name)
+		target := filepath.Join(blobs, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, r)
+		out.Close()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image archive: %w", err)
+	}
+
+	info, layers, config, err := readImageManifest(blobs)
+	if err != nil {
+		return nil, err
+	}
+	for _, layer := range layers {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err := applyLayer(filepath.Join(blobs, filepath.FromSlash(path.Clean("/"+layer))), dir); err != nil {
+			return nil, fmt.Errorf("failed to apply layer %s: %w", layer, err)
+		}
+	}
+	info.Layers = len(layers)
+	if !hasFiles(dir) {
+		return nil, ErrNothingExtracted
+	}
+
+	if config != "" {
+		content, err := readLimited(filepath.Join(blobs, filepath.FromSlash(path.Clean("/"+config))))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image config: %w", err)
+		}
+		var parsed imageConfig
+		if err := json.Unmarshal(content, &parsed); err != nil {
+			return nil, fmt.Errorf("invalid image config: %w", err)
+		}
+		info.Architecture = parsed.Architecture
+		info.OS = parsed.OS
+		info.Created = parsed.Created
+		info.User = parsed.Config.User
+		info.Entrypoint = parsed.Config.Entrypoint
+		info.Cmd = parsed.Config.Cmd
+		info.Labels = parsed.Config.Labels
+		for port := range parsed.Config.ExposedPorts {
+			info.ExposedPorts = append(info.ExposedPorts, port)
+		}
+		sort.Strings(info.ExposedPorts)
+		if err := os.WriteFile(filepath.Join(dir, ".odin-image-config.json"), content, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// readImageManifest finds the layers, in order, and the config of the image
+// in an unpacked docker save tarball or OCI layout
+func readImageManifest(blobs string) (*ContainerInfo, []string, string, error) {
+	// docker save: manifest.json lists the layers of each tagged image
+	if content, err := readLimited(filepath.Join(blobs, "manifest.json")); err == nil {
+		var manifest []struct {
+			Config   string   `json:"Config"`
+			RepoTags []string `json:"RepoTags"`
+			Layers   []string `json:"Layers"`
+		}
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, nil, "", fmt.Errorf("invalid manifest.json: %w", err)
+		}
+		if len(manifest) == 0 {
+			return nil, nil, "", errors.New("manifest.json lists no image")
+		}
+		if len(manifest) > 1 {
+			log.Printf("Image archive holds %d images, analyzing the first", len(manifest))
+		}
+		return &ContainerInfo{Format: "docker", RepoTags: manifest[0].RepoTags}, manifest[0].Layers, manifest[0].Config, nil
+	}
+
+	// OCI layout: index.json points at a manifest, or at an index of the
+	// manifests of several platforms
+	content, err := readLimited(filepath.Join(blobs, "index.json"))
+	if err != nil {
+		return nil, nil, "", errors.New("neither manifest.json nor index.json found in the image archive")
+	}
+	info := &ContainerInfo{Format: "oci"}
+	for depth := 0; depth < 4; depth++ {
+		var document struct {
+			MediaType string          `json:"mediaType"`
+			Manifests []ociDescriptor `json:"manifests"`
+			Config    ociDescriptor   `json:"config"`
+			Layers    []ociDescriptor `json:"layers"`
+		}
+		if err := json.Unmarshal(content, &document); err != nil {
+			return nil, nil, "", fmt.Errorf("invalid OCI index or manifest: %w", err)
+		}
+		if len(document.Layers) > 0 {
+			layers := make([]string, 0, len(document.Layers))
+			for _, layer := range document.Layers {
+				if strings.Contains(layer.MediaType, "zstd") {
+					return nil, nil, "", errors.New("zstd compressed layers are not supported")
+				}
+				layers = append(layers, blobPath(layer.Digest))
+			}
+			return info, layers, blobPath(document.Config.Digest), nil
+		}
+		if len(document.Manifests) == 0 {
+			return nil, nil, "", errors.New("OCI index lists no manifest")
+		}
+		chosen := pickManifest(document.Manifests)
+		// The ref name annotation is the closest thing to a tag
+		if name := chosen.Annotations["org.opencontainers.image.ref.name"]; name != "" && len(info.RepoTags) == 0 {
+			info.RepoTags = []string{name}
+		}
+		if content, err = readLimited(filepath.Join(blobs, filepath.FromSlash(blobPath(chosen.Digest)))); err != nil {
+			return nil, nil, "", fmt.Errorf("manifest %s is missing: %w", chosen.Digest, err)
+		}
+	}
+	return nil, nil, "", errors.New("OCI index nests too deep")
+}
+
+// pickManifest prefers linux/amd64, then linux/arm64, then the first one:
+// firmware images are mostly analyzed for the platform the scanners know best
+func pickManifest(manifests []ociDescriptor) ociDescriptor {
+	for _, arch := range []string{"amd64", "arm64"} {
+		for _, manifest := range manifests {
+			if manifest.Platform != nil && manifest.Platform.OS == "linux" && manifest.Platform.Architecture == arch {
+				return manifest
+			}
+		}
+	}
+	return manifests[0]
+}
+
+// blobPath maps a digest to its file in an OCI layout
+func blobPath(digest string) string {
+	algorithm, hash, _ := strings.Cut(digest, ":")
+	return path.Join("blobs", algorithm, hash)
+}
+
+func readLimited(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, maxImageMetadataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxImageMetadataSize {
+		return nil, fmt.Errorf("%s is too large", filepath.Base(file))
+	}
+	return content, nil
+}
+
+// applyLayer unpacks a layer tarball onto dir. Like writeCPIO it keeps
+// entries inside dir and skips entries below symlinks.
+func applyLayer(layer, dir string) error {
+	return walkTar(layer, func(header *tar.Header, r io.Reader) error {
+		cleaned := path.Clean("/" + header.Name)
+		if cleaned == "/" {
+			return nil
+		}
+		target := filepath.Join(dir, filepath.FromSlash(cleaned))
+		if err := checkParents(dir, filepath.Dir(target)); err != nil {
+			log.Printf("Skipping layer entry %s: %v", header.Name, err)
+			return nil
+		}
+
+		// Whiteouts delete what lower layers put there
+		base := path.Base(cleaned)
+		if base == whiteoutOpaque {
+			entries, _ := os.ReadDir(filepath.Dir(target))
+			for _, entry := range entries {
+				os.RemoveAll(filepath.Join(filepath.Dir(target), entry.Name()))
+			}
+			return nil
+		}
+		if strings.HasPrefix(base, whiteoutPrefix) {
+			return os.RemoveAll(filepath.Join(filepath.Dir(target), strings.TrimPrefix(base, whiteoutPrefix)))
+		}
+
+		mode := os.FileMode(header.Mode)
+		perm := mode & 0777
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+				os.RemoveAll(target)
+			}
+			if err := os.MkdirAll(target, perm|0700); err != nil {
+				return err
+			}
+			return os.Chmod(target, perm|0700|setuidBits(mode)|stickyBit(mode))
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.RemoveAll(target)
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, perm|0600)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, r); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+			// Keep setuid and setgid bits for the checks
+			return os.Chmod(target, perm|0600|setuidBits(mode))
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.RemoveAll(target)
+			return os.Symlink(header.Linkname, target)
+		case tar.TypeLink:
+			// Hard links point at an earlier entry of the layer or a lower one
+			source := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+header.Linkname)))
+			if err := checkParents(dir, filepath.Dir(source)); err != nil {
+				return nil
+			}
+			info, err := os.Lstat(source)
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.RemoveAll(target)
+			return os.Link(source, target)
+		}
+		// Device nodes and FIFOs aren't needed to inspect the filesystem
+		return nil
+	})
+}
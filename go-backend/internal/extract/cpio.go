@@ -0,0 +1,250 @@
+package extract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cpioHeaderSize = 110
+	cpioTrailer    = "TRAILER!!!"
+)
+
+// ErrNoFilesystem is returned when an image holds no filesystem the own
+// unpacker understands
+var ErrNoFilesystem = errors.New("no cpio archive found in the firmware; install binwalk to unpack other formats")
+
+var (
+	cpioMagics = [][]byte{[]byte("070701"), []byte("070702")}
+	gzipMagic  = []byte{0x1f, 0x8b, 0x08}
+)
+
+// maxCPIOArchives bounds the archives unpacked from one image
+const maxCPIOArchives = 4
+
+// unpackCPIO extracts the first newc cpio archive in the image into dir.
+// Archives are found at any offset (e.g. an initramfs behind a kernel) and
+// may be gzip compressed. The uncompressed archives following the first one,
+// such as the second slot of an A/B image, go into dir-1, dir-2, ...
+func unpackCPIO(firmwarePath, dir string) error {
+	data, err := os.ReadFile(firmwarePath)
+	if err != nil {
+		return err
+	}
+
+	archive := findCPIO(data)
+	if archive == nil {
+		// A compressed archive: try every gzip stream in the image
+		for offset := bytes.Index(data, gzipMagic); offset >= 0; {
+			if inflated, err := gunzip(data[offset:]); err == nil {
+				if archive = findCPIO(inflated); archive != nil {
+					break
+				}
+			}
+			next := bytes.Index(data[offset+1:], gzipMagic)
+			if next < 0 {
+				break
+			}
+			offset += next + 1
+		}
+	}
+	if archive == nil {
+		return ErrNoFilesystem
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	end, err := writeCPIO(archive, dir)
+	if err != nil {
+		return err
+	}
+
+	for n := 1; n < maxCPIOArchives; n++ {
+		archive = findCPIO(archive[end:])
+		if archive == nil {
+			break
+		}
+		next := fmt.Sprintf("%s-%d", dir, n)
+		if err := os.MkdirAll(next, 0755); err != nil {
+			return err
+		}
+		if end, err = writeCPIO(archive, next); err != nil {
+			log.Printf("Failed to unpack cpio archive %d of %s: %v", n+1, firmwarePath, err)
+			break
+		}
+	}
+	return nil
+}
+
+func findCPIO(data []byte) []byte {
+	best := -1
+	for _, magic := range cpioMagics {
+		if i := bytes.Index(data, magic); i >= 0 && (best < 0 || i < best) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	return data[best:]
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	reader.Multistream(false)
+	return io.ReadAll(reader)
+}
+
+// writeCPIO writes the entries of a newc archive below dir and returns the
+// archive's length. Entries can't escape it: names are cleaned and entries
+// below a symlink are skipped.
+func writeCPIO(archive []byte, dir string) (int, error) {
+	offset := 0
+	for {
+		if offset+cpioHeaderSize > len(archive) {
+			return 0, fmt.Errorf("truncated cpio archive")
+		}
+		header := archive[offset : offset+cpioHeaderSize]
+		if !bytes.Equal(header[:6], cpioMagics[0]) && !bytes.Equal(header[:6], cpioMagics[1]) {
+			return 0, fmt.Errorf("invalid cpio header at offset %d", offset)
+		}
+		field := func(i int) (int, error) {
+			value, err := strconv.ParseUint(string(header[6+8*i:14+8*i]), 16, 32)
+			return int(value), err
+		}
+		mode, err1 := field(1)
+		fileSize, err2 := field(6)
+		nameSize, err3 := field(11)
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return 0, fmt.Errorf("invalid cpio header at offset %d: %w", offset, err)
+		}
+
+		nameStart := offset + cpioHeaderSize
+		dataStart := align4(nameStart + nameSize)
+		dataEnd := dataStart + fileSize
+		if nameSize == 0 || dataEnd > len(archive) {
+			return 0, fmt.Errorf("truncated cpio archive")
+		}
+		name := strings.TrimRight(string(archive[nameStart:nameStart+nameSize]), "\x00")
+		if name == cpioTrailer {
+			return align4(dataEnd), nil
+		}
+
+		if err := writeEntry(dir, name, os.FileMode(mode), archive[dataStart:dataEnd]); err != nil {
+			return 0, err
+		}
+		offset = align4(dataEnd)
+	}
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// cpio file types in the mode's high bits
+const (
+	cpioTypeMask    = 0170000
+	cpioTypeDir     = 0040000
+	cpioTypeRegular = 0100000
+	cpioTypeSymlink = 0120000
+)
+
+func writeEntry(dir, name string, mode os.FileMode, data []byte) error {
+	cleaned := path.Clean("/" + name)
+	if cleaned == "/" {
+		return nil
+	}
+	target := filepath.Join(dir, filepath.FromSlash(cleaned))
+	if err := checkParents(dir, filepath.Dir(target)); err != nil {
+		log.Printf("Skipping cpio entry %s: %v", name, err)
+		return nil
+	}
+
+	perm := mode & 0777
+	switch mode & cpioTypeMask {
+	case cpioTypeDir:
+		if err := os.MkdirAll(target, perm|0700); err != nil {
+			return err
+		}
+		// MkdirAll's mode is subject to the umask
+		return os.Chmod(target, perm|0700|setuidBits(mode)|stickyBit(mode))
+	case cpioTypeRegular:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		os.Remove(target) // a later entry replaces an earlier one
+		if err := os.WriteFile(target, data, perm|0600); err != nil {
+			return err
+		}
+		// Keep setuid and setgid bits for the checks
+		return os.Chmod(target, perm|0600|setuidBits(mode))
+	case cpioTypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		os.Remove(target)
+		return os.Symlink(string(data), target)
+	}
+	// Device nodes, FIFOs and sockets aren't needed to inspect the filesystem
+	return nil
+}
+
+// setuidBits maps the raw setuid/setgid bits of a cpio mode to Go's
+func setuidBits(mode os.FileMode) os.FileMode {
+	var bits os.FileMode
+	if mode&04000 != 0 {
+		bits |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		bits |= os.ModeSetgid
+	}
+	return bits
+}
+
+// stickyBit maps the raw sticky bit of a cpio mode to Go's
+func stickyBit(mode os.FileMode) os.FileMode {
+	if mode&01000 != 0 {
+		return os.ModeSticky
+	}
+	return 0
+}
+
+// checkParents refuses to write below a symlink an earlier entry created,
+// which could point anywhere on the host
+func checkParents(root, dir string) error {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("cpio entry outside the extraction directory")
+	}
+	current := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." || part == "" {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("cpio entry %s is below a symlink", dir)
+		}
+	}
+	return nil
+}
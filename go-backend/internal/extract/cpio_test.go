@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestUnpackCPIO unpacks the images in testdata/cpio and checks the tree
+// they leave: regular files by content, symlinks by target ("-> target")
+// and directories as "dir"
+func TestUnpackCPIO(t *testing.T) {
+	cases := []struct {
+		image string
+		want  map[string]string
+	}{
+		{
+			image: "rootfs.cpio",
+			want: map[string]string{
+				"etc":         "dir",
+				"etc/passwd":  "root:x:0:0:root:/root:/bin/sh\n",
+				"bin/busybox": "\x7fELF busybox",
+				"bin/sh":      "-> busybox",
+				"tmp":         "dir",
+				"escape":      "cleaned into the root\n",
+				"link":        "-> ../outside",
+			},
+		},
+		{
+			image: "initramfs.bin",
+			want:  map[string]string{"init": "#!/bin/sh\n"},
+		},
+		{
+			image: "ab.bin",
+			want:  map[string]string{"version": "slot a\n"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.image, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "rootfs")
+			if err := unpackCPIO(filepath.Join("testdata", "cpio", tc.image), dir); err != nil {
+				t.Fatalf("unpackCPIO: %v", err)
+			}
+			if _, err := os.Lstat(filepath.Join(dir, "..", "outside")); !os.IsNotExist(err) {
+				t.Errorf("an entry was written through a symlink out of the extraction directory")
+			}
+			got := readTree(t, dir)
+			for name, want := range tc.want {
+				if got[name] != want {
+					t.Errorf("%s: got %q, want %q", name, got[name], want)
+				}
+			}
+			for name := range got {
+				if _, ok := tc.want[name]; !ok && got[name] != "dir" {
+					t.Errorf("unexpected entry %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpackCPIOKeepsModes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rootfs")
+	if err := unpackCPIO(filepath.Join("testdata", "cpio", "rootfs.cpio"), dir); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "bin", "busybox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSetuid == 0 || info.Mode().Perm() != 0755 {
+		t.Errorf("bin/busybox mode %v, want setuid 0755", info.Mode())
+	}
+	info, err = os.Stat(filepath.Join(dir, "tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSticky == 0 {
+		t.Errorf("tmp mode %v, want sticky", info.Mode())
+	}
+}
+
+func TestUnpackCPIOSecondSlot(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rootfs")
+	if err := unpackCPIO(filepath.Join("testdata", "cpio", "ab.bin"), dir); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir+"-1", "version"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "slot b\n" {
+		t.Errorf("second slot: got %q", content)
+	}
+}
+
+func TestUnpackCPIONoArchive(t *testing.T) {
+	err := unpackCPIO(filepath.Join("testdata", "cpio", "none.bin"), t.TempDir())
+	if !errors.Is(err, ErrNoFilesystem) {
+		t.Errorf("got %v, want ErrNoFilesystem", err)
+	}
+}
+
+// readTree lists the entries below dir by slash separated relative path
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	tree := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = "-> " + target
+		case info.IsDir():
+			tree[rel] = "dir"
+		default:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = string(content)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
@@ -0,0 +1,128 @@
+// Package extract unpacks firmware without EMBA, so an instance whose EMBA
+// installation is missing or broken still inventories and checks the
+// filesystem of uploaded images
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/config"
+)
+
+// Extraction methods
+const (
+	MethodEMBA      = "emba" // EMBA's own extractor, the default
+	MethodUnblob    = "unblob"
+	MethodBinwalk   = "binwalk"
+	MethodCPIO      = "cpio"      // Odin's own unpacker
+	MethodAndroid   = "android"   // Odin's unpacker for Android boot, sparse and OTA images
+	MethodMCU       = "mcu"       // bare-metal images, converted rather than unpacked
+	MethodContainer = "container" // Odin's unpacker for docker and OCI image layers
+	MethodNone      = "none"      // monolithic RTOS images with nothing to unpack
+)
+
+// ErrNothingExtracted is returned when an extractor ran but unpacked no files
+var ErrNothingExtracted = errors.New("no files were extracted from the firmware")
+
+// Selectable reports whether a project can be analyzed with the extraction
+// method; binwalk and cpio are only used when EMBA isn't available
+func Selectable(method string) bool {
+	return method == MethodEMBA || method == MethodUnblob
+}
+
+// Extractor unpacks firmware with unblob or binwalk, or with Odin's own
+// cpio unpacker where binwalk isn't installed or finds nothing
+type Extractor struct {
+	binwalk string
+	unblob  string
+}
+
+// New creates an extractor running the configured binwalk and unblob
+func New(cfg *config.Config) *Extractor {
+	return &Extractor{binwalk: cfg.BinwalkPath, unblob: cfg.UnblobPath}
+}
+
+// Extract unpacks the firmware into dir and returns the method that
+// extracted files
+func (e *Extractor) Extract(ctx context.Context, firmwarePath, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+
+	var binwalkErr error
+	if _, err := exec.LookPath(e.binwalk); err == nil {
+		binwalkErr = e.runBinwalk(ctx, firmwarePath, dir)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if binwalkErr == nil && hasFiles(dir) {
+			return MethodBinwalk, nil
+		}
+	}
+
+	if err := unpackCPIO(firmwarePath, filepath.Join(dir, "cpio-root")); err != nil {
+		if binwalkErr != nil {
+			return "", fmt.Errorf("binwalk failed (%v) and %w", binwalkErr, err)
+		}
+		return "", err
+	}
+	return MethodCPIO, nil
+}
+
+// Unblob unpacks the firmware into dir with unblob
+func (e *Extractor) Unblob(ctx context.Context, firmwarePath, dir string) error {
+	if _, err := exec.LookPath(e.unblob); err != nil {
+		return fmt.Errorf("unblob is not installed: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+
+	// The report lands next to the extracted files, not among them
+	report := filepath.Join(filepath.Dir(dir), "unblob_report.json")
+	cmd := exec.CommandContext(ctx, e.unblob, "--extract-dir", dir, "--report", report, firmwarePath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("unblob failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if !hasFiles(dir) {
+		return ErrNothingExtracted
+	}
+	return nil
+}
+
+// runBinwalk extracts recursively (matryoshka mode) into dir
+func (e *Extractor) runBinwalk(ctx context.Context, firmwarePath, dir string) error {
+	cmd := exec.CommandContext(ctx, e.binwalk, "-e", "-M", "-q", "-C", dir, firmwarePath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("binwalk failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// hasFiles reports whether dir holds any regular file
+func hasFiles(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
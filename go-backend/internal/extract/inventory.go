@@ -0,0 +1,95 @@
+package extract
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"odin-backend/internal/fwformat"
+)
+
+var elfMagic = []byte{0x7f, 'E', 'L', 'F'}
+
+// Inventory summarizes the files of an extracted filesystem
+type Inventory struct {
+	Files         int   `json:"files"`
+	Directories   int   `json:"directories"`
+	Symlinks      int   `json:"symlinks"`
+	TotalSize     int64 `json:"total_size"`
+	Executables   int   `json:"elf_executables"`
+	Setuid        int   `json:"setuid_files"`
+	WorldWritable int   `json:"world_writable_files"`
+
+	// Architecture and byte order most of the ELF executables are built for
+	Architecture string `json:"architecture,omitempty"`
+	Endianness   string `json:"endianness,omitempty"`
+}
+
+// TakeInventory counts the files below root
+func TakeInventory(root string) (Inventory, error) {
+	var inventory Inventory
+	architectures := make(map[[2]string]int) // architecture, byte order
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		switch {
+		case d.IsDir():
+			inventory.Directories++
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			inventory.Symlinks++
+			return nil
+		case !d.Type().IsRegular():
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		inventory.Files++
+		inventory.TotalSize += info.Size()
+		if info.Mode()&(fs.ModeSetuid|fs.ModeSetgid) != 0 {
+			inventory.Setuid++
+		}
+		if info.Mode().Perm()&0002 != 0 {
+			inventory.WorldWritable++
+		}
+		if header, ok := elfHeader(p); ok {
+			inventory.Executables++
+			if header.Architecture != "" {
+				architectures[[2]string{header.Architecture, header.Endianness}]++
+			}
+		}
+		return nil
+	})
+
+	most := 0
+	for arch, count := range architectures {
+		if count > most || count == most && arch[0] < inventory.Architecture {
+			most = count
+			inventory.Architecture, inventory.Endianness = arch[0], arch[1]
+		}
+	}
+	return inventory, err
+}
+
+// elfHeader describes the file's ELF header, if it starts with one
+func elfHeader(path string) (fwformat.Info, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return fwformat.Info{}, false
+	}
+	defer file.Close()
+	header := make([]byte, 64)
+	n, _ := file.Read(header)
+	if !bytes.HasPrefix(header[:n], elfMagic) {
+		return fwformat.Info{}, false
+	}
+	return fwformat.Describe(header[:n]), true
+}
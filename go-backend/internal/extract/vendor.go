@@ -0,0 +1,448 @@
+package extract
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"odin-backend/internal/fwformat"
+)
+
+// ErrChecksumMismatch is returned for vendor containers whose checksums
+// don't match their contents: the upload is truncated or corrupt
+var ErrChecksumMismatch = errors.New("firmware container checksum mismatch")
+
+// maxContainerDepth bounds containers wrapped in containers (a Netgear CHK
+// usually holds a Broadcom TRX)
+const maxContainerDepth = 4
+
+// VendorContainer describes the vendor wrapper Odin stripped from an image
+type VendorContainer struct {
+	Format     string              `json:"format"`
+	Size       int64               `json:"size"` // bytes of the image the header covers
+	HeaderSize int64               `json:"header_size"`
+	Details    map[string]string   `json:"details,omitempty"`
+	Checksums  []ContainerChecksum `json:"checksums,omitempty"`
+	Parts      []ContainerPart     `json:"parts,omitempty"`
+	Encrypted  bool                `json:"encrypted,omitempty"`
+	Inner      *VendorContainer    `json:"inner,omitempty"` // the container this one wraps
+}
+
+// ContainerChecksum is a checksum of the header compared with the contents
+type ContainerChecksum struct {
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Valid    bool   `json:"valid"`
+}
+
+// ContainerPart is a section of the container's payload
+type ContainerPart struct {
+	Name   string `json:"name"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	Format string `json:"format,omitempty"`
+}
+
+// IsVendorContainer reports whether Unwrap handles the firmware format
+func IsVendorContainer(firmwareType string) bool {
+	switch firmwareType {
+	case fwformat.CHK, fwformat.TRX, fwformat.NPK, fwformat.SHRS, fwformat.EncryptedImg:
+		return true
+	}
+	return false
+}
+
+// Unwrap strips the vendor containers of an image, checking their
+// checksums, and writes the payload they wrap into dir. It returns the
+// payload's path and the containers, or no path when the image isn't in a
+// vendor container or its payload is encrypted.
+func Unwrap(firmwarePath, dir string) (string, *VendorContainer, error) {
+	var outer *VendorContainer
+	link := &outer
+	path := ""
+	for depth := 0; depth < maxContainerDepth; depth++ {
+		input := firmwarePath
+		if path != "" {
+			input = path
+		}
+		format, err := fwformat.IdentifyFile(input)
+		if err != nil {
+			return "", nil, err
+		}
+		if !IsVendorContainer(format) {
+			break
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", nil, fmt.Errorf("failed to create payload directory: %w", err)
+		}
+		payload := filepath.Join(dir, fmt.Sprintf("payload%d.bin", depth))
+		container, written, err := unwrapFile(format, input, payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid %s container: %w", format, err)
+		}
+		*link = container
+		link = &container.Inner
+		for _, checksum := range container.Checksums {
+			if !checksum.Valid {
+				return "", outer, fmt.Errorf("%w: %s %s checksum is %s, the header says %s", ErrChecksumMismatch, format, checksum.Name, checksum.Actual, checksum.Expected)
+			}
+		}
+		if container.Encrypted {
+			return "", outer, nil
+		}
+		if !written {
+			break
+		}
+		path = payload
+	}
+	return path, outer, nil
+}
+
+// unwrapFile parses the container of the image at in and writes the
+// payload it wraps, if any, to out
+func unwrapFile(format, in, out string) (*VendorContainer, bool, error) {
+	f, err := os.Open(in)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	dst, err := os.Create(out)
+	if err != nil {
+		return nil, false, err
+	}
+	defer dst.Close()
+
+	var container *VendorContainer
+	switch format {
+	case fwformat.CHK:
+		container, err = unwrapCHK(f, info.Size(), dst)
+	case fwformat.TRX:
+		container, err = unwrapTRX(f, info.Size(), dst)
+	case fwformat.NPK:
+		container, err = unwrapNPK(f, info.Size(), dst)
+	case fwformat.SHRS:
+		container, err = describeSHRS(f, info.Size())
+	default:
+		container, err = describeEncryptedImg(f, info.Size())
+	}
+	if err == nil {
+		err = dst.Close()
+	}
+	written := false
+	if stat, statErr := os.Stat(out); statErr == nil && stat.Size() > 0 {
+		written = true
+	} else {
+		os.Remove(out)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	identifyParts(f, container.Parts)
+	return container, written, nil
+}
+
+// Netgear's CHK header: magic, header length, region and version, the
+// checksums of the kernel, rootfs, both and the header, the kernel and
+// rootfs lengths, then the board ID up to the header length
+func unwrapCHK(f *os.File, size int64, out io.Writer) (*VendorContainer, error) {
+	fixed := make([]byte, 40)
+	if _, err := io.ReadFull(f, fixed); err != nil {
+		return nil, fmt.Errorf("truncated header: %w", err)
+	}
+	headerLen := int64(binary.BigEndian.Uint32(fixed[4:8]))
+	kernelLen := int64(binary.BigEndian.Uint32(fixed[24:28]))
+	rootfsLen := int64(binary.BigEndian.Uint32(fixed[28:32]))
+	if headerLen < 40 || headerLen > 4096 || headerLen+kernelLen+rootfsLen > size {
+		return nil, fmt.Errorf("header of %d bytes announces %d bytes of kernel and %d of rootfs in a %d byte image", headerLen, kernelLen, rootfsLen, size)
+	}
+	header := make([]byte, headerLen)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("truncated header: %w", err)
+	}
+
+	container := &VendorContainer{
+		Format:     fwformat.CHK,
+		Size:       headerLen + kernelLen + rootfsLen,
+		HeaderSize: headerLen,
+		Details: map[string]string{
+			"region":  fmt.Sprint(header[8]),
+			"version": fmt.Sprintf("V%d.%d.%d.%d_%d.%d.%d", header[9], header[10], header[11], header[12], header[13], header[14], header[15]),
+		},
+	}
+	if board := cString(header[40:]); board != "" {
+		container.Details["board_id"] = board
+	}
+	container.Parts = append(container.Parts, ContainerPart{Name: "kernel", Offset: headerLen, Size: kernelLen})
+	if rootfsLen > 0 {
+		container.Parts = append(container.Parts, ContainerPart{Name: "rootfs", Offset: headerLen + kernelLen, Size: rootfsLen})
+	}
+
+	// The header checksum covers the header with its own field zeroed
+	headerSum := newNetgearChecksum()
+	zeroed := append([]byte(nil), header...)
+	copy(zeroed[36:40], make([]byte, 4))
+	headerSum.Write(zeroed)
+
+	kernelSum, rootfsSum, imageSum := newNetgearChecksum(), newNetgearChecksum(), newNetgearChecksum()
+	if err := copySection(f, headerLen, kernelLen, out, kernelSum, imageSum); err != nil {
+		return nil, err
+	}
+	if err := copySection(f, headerLen+kernelLen, rootfsLen, out, rootfsSum, imageSum); err != nil {
+		return nil, err
+	}
+	container.Checksums = append(container.Checksums, checksum("header", binary.BigEndian.Uint32(header[36:40]), headerSum.Sum32()))
+	if kernelLen > 0 {
+		container.Checksums = append(container.Checksums, checksum("kernel", binary.BigEndian.Uint32(header[16:20]), kernelSum.Sum32()))
+	}
+	if rootfsLen > 0 {
+		container.Checksums = append(container.Checksums, checksum("rootfs", binary.BigEndian.Uint32(header[20:24]), rootfsSum.Sum32()))
+	}
+	container.Checksums = append(container.Checksums, checksum("image", binary.BigEndian.Uint32(header[32:36]), imageSum.Sum32()))
+	return container, nil
+}
+
+// Broadcom's TRX header: magic, image length, a CRC32 of everything after
+// it, flags and version, then the offsets of up to three (version 1) or four
+// (version 2) partitions
+func unwrapTRX(f *os.File, size int64, out io.Writer) (*VendorContainer, error) {
+	header := make([]byte, 32)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("truncated header: %w", err)
+	}
+	length := int64(binary.LittleEndian.Uint32(header[4:8]))
+	version := binary.LittleEndian.Uint16(header[14:16])
+	headerSize, partitions := int64(28), 3
+	if version >= 2 {
+		headerSize, partitions = 32, 4
+	}
+	if length < headerSize || length > size {
+		return nil, fmt.Errorf("header announces %d bytes in a %d byte image", length, size)
+	}
+
+	var offsets []int64
+	for i := 0; i < partitions; i++ {
+		offset := int64(binary.LittleEndian.Uint32(header[16+4*i : 20+4*i]))
+		if offset == 0 {
+			continue
+		}
+		if offset < headerSize || offset >= length {
+			return nil, fmt.Errorf("partition %d at offset %d is outside the %d byte image", i, offset, length)
+		}
+		offsets = append(offsets, offset)
+	}
+	if len(offsets) == 0 {
+		offsets = append(offsets, headerSize)
+	}
+
+	container := &VendorContainer{
+		Format:     fwformat.TRX,
+		Size:       length,
+		HeaderSize: headerSize,
+		Details: map[string]string{
+			"version": fmt.Sprint(version),
+			"flags":   fmt.Sprintf("0x%04x", binary.LittleEndian.Uint16(header[12:14])),
+		},
+	}
+	for i, offset := range offsets {
+		end := length
+		if i+1 < len(offsets) {
+			end = offsets[i+1]
+		}
+		container.Parts = append(container.Parts, ContainerPart{Name: fmt.Sprintf("partition%d", i), Offset: offset, Size: end - offset})
+	}
+
+	// The CRC covers the image from the flags on, without the final inversion
+	crc := crc32.NewIEEE()
+	crc.Write(header[12:headerSize])
+	if err := copySection(f, headerSize, offsets[0]-headerSize, crc); err != nil {
+		return nil, err
+	}
+	if err := copySection(f, offsets[0], length-offsets[0], out, crc); err != nil {
+		return nil, err
+	}
+	container.Checksums = append(container.Checksums, checksum("crc32", binary.LittleEndian.Uint32(header[8:12]), ^crc.Sum32()))
+	return container, nil
+}
+
+// npkParts names the common part types of MikroTik's NPK packages
+var npkParts = map[uint16]string{
+	1: "part_info", 2: "description", 3: "dependencies", 4: "file_container",
+	7: "install_script", 8: "uninstall_script", 9: "signature", 16: "architecture",
+	18: "package_info", 21: "squashfs", 22: "zero_padding", 23: "digest",
+}
+
+// MikroTik's NPK packages: magic and payload size, then parts of a type, a
+// size and their data. RouterOS 6 and later keep the files in a SquashFS
+// part, older versions in a file container.
+func unwrapNPK(f *os.File, size int64, out io.Writer) (*VendorContainer, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("truncated header: %w", err)
+	}
+	length := int64(binary.LittleEndian.Uint32(header[4:8])) + 8
+	if length > size {
+		return nil, fmt.Errorf("header announces %d bytes in a %d byte image", length, size)
+	}
+
+	container := &VendorContainer{Format: fwformat.NPK, Size: length, HeaderSize: 8, Details: map[string]string{}}
+	var payload *ContainerPart
+	for offset := int64(8); offset+6 <= length; {
+		partHeader := make([]byte, 6)
+		if _, err := f.ReadAt(partHeader, offset); err != nil {
+			return nil, fmt.Errorf("truncated part header at offset %d: %w", offset, err)
+		}
+		partType := binary.LittleEndian.Uint16(partHeader[0:2])
+		partSize := int64(binary.LittleEndian.Uint32(partHeader[2:6]))
+		if offset+6+partSize > length {
+			return nil, fmt.Errorf("part at offset %d runs past the end of the package", offset)
+		}
+		name, ok := npkParts[partType]
+		if !ok {
+			name = fmt.Sprintf("type%d", partType)
+		}
+		part := ContainerPart{Name: name, Offset: offset + 6, Size: partSize}
+
+		switch partType {
+		case 1:
+			// Package name, then revision, release candidate, minor and major
+			info := make([]byte, 20)
+			if partSize >= 20 {
+				if _, err := f.ReadAt(info, part.Offset); err == nil {
+					container.Details["name"] = cString(info[:16])
+					container.Details["version"] = fmt.Sprintf("%d.%d.%d", info[19], info[18], info[16])
+				}
+			}
+		case 16:
+			if partSize <= 64 {
+				arch := make([]byte, partSize)
+				if _, err := f.ReadAt(arch, part.Offset); err == nil {
+					container.Details["architecture"] = cString(arch)
+				}
+			}
+		case 4, 21:
+			if payload == nil || partType == 21 {
+				payload = &part
+			}
+		}
+		container.Parts = append(container.Parts, part)
+		offset += 6 + partSize
+	}
+	if len(container.Details) == 0 {
+		container.Details = nil
+	}
+
+	if payload != nil {
+		if err := copySection(f, payload.Offset, payload.Size, out); err != nil {
+			return nil, err
+		}
+	}
+	return container, nil
+}
+
+// D-Link's SHRS header holds the plaintext and ciphertext sizes and the IV;
+// the ciphertext follows the signature block. Odin's decryptor decrypts it.
+func describeSHRS(f *os.File, size int64) (*VendorContainer, error) {
+	const payloadOffset = 0x6dc
+	header := make([]byte, 0x1c)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("truncated header: %w", err)
+	}
+	plainSize := int64(binary.BigEndian.Uint32(header[4:8]))
+	cipherSize := int64(binary.BigEndian.Uint32(header[8:12]))
+	if payloadOffset+cipherSize > size {
+		return nil, fmt.Errorf("header announces %d bytes of ciphertext, %d present", cipherSize, size-payloadOffset)
+	}
+	return &VendorContainer{
+		Format:     fwformat.SHRS,
+		Size:       payloadOffset + cipherSize,
+		HeaderSize: payloadOffset,
+		Details: map[string]string{
+			"decrypted_size": fmt.Sprint(plainSize),
+			"iv":             fmt.Sprintf("%x", header[12:28]),
+			"cipher":         "aes-128-cbc",
+		},
+		Parts:     []ContainerPart{{Name: "ciphertext", Offset: payloadOffset, Size: cipherSize}},
+		Encrypted: true,
+	}, nil
+}
+
+// D-Link's encrpted_img images (DIR-X series) hold the ciphertext's size
+// after the magic; the key is device specific
+func describeEncryptedImg(f *os.File, size int64) (*VendorContainer, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("truncated header: %w", err)
+	}
+	cipherSize := int64(binary.BigEndian.Uint32(header[12:16]))
+	if 16+cipherSize > size {
+		return nil, fmt.Errorf("header announces %d bytes of ciphertext, %d present", cipherSize, size-16)
+	}
+	return &VendorContainer{
+		Format:     fwformat.EncryptedImg,
+		Size:       16 + cipherSize,
+		HeaderSize: 16,
+		Parts:      []ContainerPart{{Name: "ciphertext", Offset: 16, Size: cipherSize}},
+		Encrypted:  true,
+	}, nil
+}
+
+// copySection copies size bytes of f from offset to the writers
+func copySection(f *os.File, offset, size int64, writers ...io.Writer) error {
+	_, err := io.Copy(io.MultiWriter(writers...), io.NewSectionReader(f, offset, size))
+	return err
+}
+
+// identifyParts sets the format of the parts Identify recognizes
+func identifyParts(f *os.File, parts []ContainerPart) {
+	for i := range parts {
+		head := make([]byte, 64*1024)
+		if parts[i].Size < int64(len(head)) {
+			head = head[:parts[i].Size]
+		}
+		n, _ := f.ReadAt(head, parts[i].Offset)
+		if format := fwformat.Identify(head[:n]); n > 0 && format != fwformat.Unknown {
+			parts[i].Format = format
+		}
+	}
+}
+
+func checksum(name string, expected, actual uint32) ContainerChecksum {
+	return ContainerChecksum{
+		Name:     name,
+		Expected: fmt.Sprintf("%08x", expected),
+		Actual:   fmt.Sprintf("%08x", actual),
+		Valid:    expected == actual,
+	}
+}
+
+// netgearChecksum is the Fletcher-like checksum of Netgear's CHK images
+type netgearChecksum struct {
+	c0, c1 uint32
+}
+
+func newNetgearChecksum() *netgearChecksum { return &netgearChecksum{} }
+
+func (n *netgearChecksum) Write(p []byte) (int, error) {
+	for _, b := range p {
+		n.c0 += uint32(b)
+		n.c1 += n.c0
+	}
+	return len(p), nil
+}
+
+func (n *netgearChecksum) Sum32() uint32 {
+	fold := func(c uint32) uint32 {
+		b := (c & 0xffff) + (c >> 16)
+		return ((b >> 16) + b) & 0xffff
+	}
+	return fold(n.c1)<<16 | fold(n.c0)
+}
@@ -0,0 +1,128 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"odin-backend/internal/fwformat"
+)
+
+// The checksums of the fixtures in testdata/vendor were computed the way
+// OpenWrt's mkchkimg and trx tools compute them
+
+// TestUnwrapCHK unwraps a Netgear CHK image holding a Broadcom TRX with a
+// kernel and a SquashFS partition, followed by a 16 byte rootfs
+func TestUnwrapCHK(t *testing.T) {
+	dir := t.TempDir()
+	path, container, err := Unwrap(filepath.Join("testdata", "vendor", "netgear.chk"), dir)
+	if err != nil {
+		t.Fatalf("Unwrap: %v", err)
+	}
+
+	want := &VendorContainer{
+		Format:     fwformat.CHK,
+		Size:       58 + 124 + 16,
+		HeaderSize: 58,
+		Details: map[string]string{
+			"region":   "1",
+			"version":  "V1.0.2.94_1.0.1",
+			"board_id": "U12H332T20_NETGEAR",
+		},
+		Checksums: []ContainerChecksum{
+			{Name: "header", Expected: "2b3f0bc8", Actual: "2b3f0bc8", Valid: true},
+			{Name: "kernel", Expected: "e44f0e71", Actual: "e44f0e71", Valid: true},
+			{Name: "rootfs", Expected: "2b900520", Actual: "2b900520", Valid: true},
+			{Name: "image", Expected: "f6f01391", Actual: "f6f01391", Valid: true},
+		},
+		Parts: []ContainerPart{
+			{Name: "kernel", Offset: 58, Size: 124, Format: fwformat.TRX},
+			{Name: "rootfs", Offset: 182, Size: 16},
+		},
+		Inner: &VendorContainer{
+			Format:     fwformat.TRX,
+			Size:       124,
+			HeaderSize: 28,
+			Details:    map[string]string{"version": "1", "flags": "0x0000"},
+			Checksums:  []ContainerChecksum{{Name: "crc32", Expected: "5d12966a", Actual: "5d12966a", Valid: true}},
+			Parts: []ContainerPart{
+				{Name: "partition0", Offset: 28, Size: 32},
+				{Name: "partition1", Offset: 60, Size: 64, Format: fwformat.SquashFS},
+			},
+		},
+	}
+	if !reflect.DeepEqual(container, want) {
+		t.Errorf("got %+v, want %+v", container, want)
+		if container != nil {
+			t.Errorf("inner: got %+v, want %+v", container.Inner, want.Inner)
+		}
+	}
+
+	if path != filepath.Join(dir, "payload1.bin") {
+		t.Fatalf("payload path %s", path)
+	}
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(payload, bytes.Repeat([]byte("K"), 32)) || !bytes.HasPrefix(payload[32:], []byte("hsqs")) || len(payload) != 96 {
+		t.Errorf("payload %q is not the TRX's partitions", payload)
+	}
+}
+
+func TestUnwrapChecksumMismatch(t *testing.T) {
+	path, container, err := Unwrap(filepath.Join("testdata", "vendor", "corrupt.chk"), t.TempDir())
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("got %v, want ErrChecksumMismatch", err)
+	}
+	if path != "" {
+		t.Errorf("corrupt image unwrapped to %s", path)
+	}
+	if container == nil || container.Format != fwformat.CHK {
+		t.Fatalf("got container %+v, want the CHK header", container)
+	}
+	for _, checksum := range container.Checksums {
+		// The flipped byte is in the kernel, which the image checksum covers too
+		wantValid := checksum.Name == "header" || checksum.Name == "rootfs"
+		if checksum.Valid != wantValid {
+			t.Errorf("%s checksum valid %v, want %v", checksum.Name, checksum.Valid, wantValid)
+		}
+	}
+}
+
+// TestUnwrapNPK unwraps a RouterOS 6 package, whose files are in its
+// SquashFS part rather than the older file container
+func TestUnwrapNPK(t *testing.T) {
+	dir := t.TempDir()
+	path, container, err := Unwrap(filepath.Join("testdata", "vendor", "routeros.npk"), dir)
+	if err != nil {
+		t.Fatalf("Unwrap: %v", err)
+	}
+
+	want := &VendorContainer{
+		Format:     fwformat.NPK,
+		Size:       99,
+		HeaderSize: 8,
+		Details:    map[string]string{"name": "routeros", "version": "6.15.5", "architecture": "mipsbe"},
+		Parts: []ContainerPart{
+			{Name: "part_info", Offset: 14, Size: 20},
+			{Name: "architecture", Offset: 40, Size: 6},
+			{Name: "file_container", Offset: 52, Size: 9},
+			{Name: "squashfs", Offset: 67, Size: 32, Format: fwformat.SquashFS},
+		},
+	}
+	if !reflect.DeepEqual(container, want) {
+		t.Errorf("got %+v, want %+v", container, want)
+	}
+
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(payload, []byte("hsqs")) || len(payload) != 32 {
+		t.Errorf("payload %q is not the SquashFS part", payload)
+	}
+}
@@ -0,0 +1,207 @@
+package firmwarefs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinkHops bounds symlink resolution, as the kernel's ELOOP does
+const maxSymlinkHops = 40
+
+// ErrEscapes is returned for paths whose symlinks lead outside the rootfs
+var ErrEscapes = errors.New("path leads outside the extracted filesystem")
+
+// Entry types
+const (
+	TypeDir     = "dir"
+	TypeFile    = "file"
+	TypeSymlink = "symlink"
+	TypeOther   = "other" // device nodes, FIFOs and sockets
+)
+
+// Entry is a file or directory of an extracted filesystem
+type Entry struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"` // inside the firmware, e.g. /etc/init.d
+	Type   string `json:"type"`
+	Size   int64  `json:"size"`
+	Mode   string `json:"mode"`             // e.g. -rwsr-xr-x
+	Target string `json:"target,omitempty"` // of symlinks
+}
+
+// rootFSDirs are directories found at the top of a Linux root filesystem
+var rootFSDirs = []string{"bin", "sbin", "usr", "lib"}
+
+// RootFS returns the root filesystem inside an extracted firmware tree: the
+// shallowest directory holding etc and one of bin, sbin, usr or lib (e.g.
+// "_firmware.bin.extracted/squashfs-root"), or root itself
+func RootFS(root string) string {
+	best, bestDepth := root, -1
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		depth := strings.Count(p, string(filepath.Separator))
+		if bestDepth >= 0 && depth >= bestDepth {
+			return filepath.SkipDir
+		}
+		if isRootFS(p) {
+			best, bestDepth = p, depth
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return best
+}
+
+func isRootFS(dir string) bool {
+	if info, err := os.Lstat(filepath.Join(dir, "etc")); err != nil || !info.IsDir() {
+		return false
+	}
+	for _, name := range rootFSDirs {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve maps a path inside the firmware to the file below rootfs,
+// following symlinks as if rootfs were the root directory: absolute targets
+// start over at rootfs and nothing resolves above it
+func Resolve(rootfs, firmwarePath string) (string, error) {
+	remaining := strings.Split(strings.Trim(path.Clean("/"+firmwarePath), "/"), "/")
+	current := "/"
+	hops := 0
+	for len(remaining) > 0 {
+		part := remaining[0]
+		remaining = remaining[1:]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			current = path.Dir(current)
+			continue
+		}
+
+		next := path.Join(current, part)
+		info, err := os.Lstat(filepath.Join(rootfs, filepath.FromSlash(next)))
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return "", fmt.Errorf("too many levels of symbolic links in %s", firmwarePath)
+		}
+		target, err := os.Readlink(filepath.Join(rootfs, filepath.FromSlash(next)))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			current = "/"
+		}
+		remaining = append(strings.Split(target, "/"), remaining...)
+	}
+
+	resolved := filepath.Join(rootfs, filepath.FromSlash(current))
+	if rel, err := filepath.Rel(rootfs, resolved); err != nil || strings.HasPrefix(rel, "..") {
+		return "", ErrEscapes
+	}
+	return resolved, nil
+}
+
+// List returns the entries of a directory of the firmware, sorted by name.
+// Symlinks are listed, not followed.
+func List(dir, firmwarePath string) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue // removed meanwhile
+		}
+		entry := Entry{
+			Name: dirEntry.Name(),
+			Path: path.Join("/", firmwarePath, dirEntry.Name()),
+			Size: info.Size(),
+			Mode: ModeString(info.Mode()),
+		}
+		switch {
+		case info.IsDir():
+			entry.Type = TypeDir
+		case info.Mode()&fs.ModeSymlink != 0:
+			entry.Type = TypeSymlink
+			entry.Target, _ = os.Readlink(filepath.Join(dir, dirEntry.Name()))
+		case info.Mode().IsRegular():
+			entry.Type = TypeFile
+		default:
+			entry.Type = TypeOther
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ModeString formats a file mode the way ls does, e.g. -rwsr-xr-x
+func ModeString(mode fs.FileMode) string {
+	kind := byte('-')
+	switch {
+	case mode.IsDir():
+		kind = 'd'
+	case mode&fs.ModeSymlink != 0:
+		kind = 'l'
+	case mode&fs.ModeCharDevice != 0:
+		kind = 'c'
+	case mode&fs.ModeDevice != 0:
+		kind = 'b'
+	case mode&fs.ModeNamedPipe != 0:
+		kind = 'p'
+	case mode&fs.ModeSocket != 0:
+		kind = 's'
+	}
+
+	const rwx = "rwxrwxrwx"
+	buf := []byte{kind}
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			buf = append(buf, rwx[i])
+		} else {
+			buf = append(buf, '-')
+		}
+	}
+	special := []struct {
+		bit   fs.FileMode
+		index int
+		set   byte // with the execute bit
+		unset byte // without it
+	}{
+		{fs.ModeSetuid, 3, 's', 'S'},
+		{fs.ModeSetgid, 6, 's', 'S'},
+		{fs.ModeSticky, 9, 't', 'T'},
+	}
+	for _, s := range special {
+		if mode&s.bit == 0 {
+			continue
+		}
+		if buf[s.index] == 'x' {
+			buf[s.index] = s.set
+		} else {
+			buf[s.index] = s.unset
+		}
+	}
+	return string(buf)
+}
@@ -0,0 +1,80 @@
+// Package firmwarefs locates files in the firmware trees EMBA extracts into a
+// project's log directory
+package firmwarefs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// ErrNotExtracted is returned for projects without an EMBA log directory
+var ErrNotExtracted = errors.New("no extracted firmware available")
+
+// Root returns the directory holding a project's extracted firmware
+func Root(project *models.Project) (string, error) {
+	logDir, _ := project.ExtractionData()["emba_log_dir"].(string)
+	if logDir == "" {
+		return "", ErrNotExtracted
+	}
+	if _, err := os.Stat(logDir); err != nil {
+		return "", ErrNotExtracted
+	}
+
+	root := filepath.Join(logDir, "firmware")
+	if _, err := os.Stat(root); err != nil {
+		root = logDir
+	}
+	return root, nil
+}
+
+// CleanPath normalizes a path inside the firmware, e.g. "etc/config/../passwd"
+// to "/etc/passwd", rejecting paths that escape the root
+func CleanPath(p string) (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("path must not contain '..'")
+		}
+	}
+	cleaned := path.Clean("/" + p)
+	if cleaned == "/" {
+		return "", fmt.Errorf("path must name a file")
+	}
+	return cleaned, nil
+}
+
+// Find locates a file by its path inside the firmware's root filesystem.
+// Extracted filesystems are nested below their container (e.g.
+// "fw_extract/squashfs-root/etc/passwd"), so the shallowest regular file whose
+// path ends in the requested one wins. It returns "" if there's no such file.
+func Find(root, firmwarePath string) (string, error) {
+	suffix := filepath.FromSlash(firmwarePath)
+
+	var best string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel := string(filepath.Separator) + strings.TrimPrefix(p, root+string(filepath.Separator))
+		if !strings.HasSuffix(rel, suffix) {
+			return nil
+		}
+		if best == "" || len(p) < len(best) {
+			best = p
+		}
+		return nil
+	})
+	return best, err
+}
@@ -0,0 +1,109 @@
+package firmwarefs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/fwformat"
+	"odin-backend/internal/models"
+)
+
+// File types of manifest entries besides container formats
+const (
+	FileTypeELF    = "elf"
+	FileTypeScript = "script"
+	FileTypeText   = "text"
+	FileTypeData   = "data"
+)
+
+// sniffLength is how much of a file decides its MIME and file type
+const sniffLength = 512
+
+// Manifest lists every regular file below root with its size, SHA-256, MIME
+// type and file type. Paths are relative to root.
+func Manifest(ctx context.Context, root string) ([]models.FirmwareFile, error) {
+	files := []models.FirmwareFile{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		file, err := describeFile(p)
+		if err != nil {
+			return nil
+		}
+		file.Path = "/" + filepath.ToSlash(strings.TrimPrefix(p, root+string(filepath.Separator)))
+		file.Name = d.Name()
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
+
+// describeFile hashes a file and determines its type from its first bytes
+func describeFile(path string) (models.FirmwareFile, error) {
+	var file models.FirmwareFile
+	f, err := os.Open(path)
+	if err != nil {
+		return file, err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return file, err
+	}
+	head = head[:n]
+
+	hash := sha256.New()
+	hash.Write(head)
+	rest, err := io.Copy(hash, f)
+	if err != nil {
+		return file, err
+	}
+
+	file.Size = int64(n) + rest
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	file.MIMEType, file.FileType = sniffType(head)
+	return file, nil
+}
+
+// sniffType returns the MIME type and file type of content starting with head
+func sniffType(head []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x7f, 'E', 'L', 'F'}):
+		return "application/x-elf", FileTypeELF
+	case bytes.HasPrefix(head, []byte("#!")):
+		interpreter := head
+		if i := bytes.IndexByte(interpreter, '\n'); i >= 0 {
+			interpreter = interpreter[:i]
+		}
+		if bytes.Contains(interpreter, []byte("sh")) {
+			return "text/x-shellscript", FileTypeScript
+		}
+		return "text/plain; charset=utf-8", FileTypeScript
+	}
+
+	mimeType := http.DetectContentType(head)
+	if format := fwformat.Identify(head); format != fwformat.Unknown && format != fwformat.IntelHex {
+		return mimeType, format
+	}
+	if strings.HasPrefix(mimeType, "text/") {
+		return mimeType, FileTypeText
+	}
+	return mimeType, FileTypeData
+}
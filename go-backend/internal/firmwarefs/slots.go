@@ -0,0 +1,184 @@
+package firmwarefs
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// minSlotOverlap is the share of the smaller root filesystem's paths both
+// must have for two root filesystems to be copies of the same firmware
+const minSlotOverlap = 0.8
+
+// Slot is one copy of the firmware in an image with an A/B update layout
+type Slot struct {
+	Name  string `json:"name"` // a, b, ...
+	Root  string `json:"root"` // the copy's root filesystem in the extracted tree
+	Files int    `json:"files"`
+}
+
+// SlotLayout describes the copies of an A/B image and how far they differ
+type SlotLayout struct {
+	Slots          []Slot `json:"slots"`
+	IdenticalFiles int    `json:"identical_files"` // same path and contents in every slot
+	DifferentFiles int    `json:"different_files"` // in every slot, with different contents
+}
+
+// rootDirs are the top-level directories of a root filesystem, besides etc
+var rootDirs = []string{"bin", "sbin", "usr", "lib"}
+
+// Slots finds the copies of the firmware in a manifest: root filesystems
+// that aren't inside another one and share most of their paths. It returns
+// nil unless there are at least two.
+func Slots(files []models.FirmwareFile) *SlotLayout {
+	// Directories with an etc and a bin, sbin, usr or lib are root filesystems
+	dirs := make(map[string]map[string]bool)
+	for _, file := range files {
+		parts := strings.Split(strings.TrimPrefix(file.Path, "/"), "/")
+		for i := 0; i+1 < len(parts); i++ {
+			dir := "/" + strings.Join(parts[:i], "/")
+			if dirs[dir] == nil {
+				dirs[dir] = make(map[string]bool)
+			}
+			dirs[dir][parts[i]] = true
+		}
+	}
+	var roots []string
+	for dir, children := range dirs {
+		if !children["etc"] {
+			continue
+		}
+		for _, name := range rootDirs {
+			if children[name] {
+				roots = append(roots, dir)
+				break
+			}
+		}
+	}
+	sort.Strings(roots)
+	roots = outermost(roots)
+	if len(roots) < 2 {
+		return nil
+	}
+
+	// Hashes of each root's files by their path within the root
+	contents := make([]map[string]string, len(roots))
+	for i := range contents {
+		contents[i] = make(map[string]string)
+	}
+	for _, file := range files {
+		for i, root := range roots {
+			if rel, ok := within(file.Path, root); ok {
+				contents[i][rel] = file.SHA256
+				break
+			}
+		}
+	}
+
+	// Copies of the first root are the slots; other root filesystems (a
+	// recovery image, an overlay) aren't
+	slots := []int{0}
+	for i := 1; i < len(roots); i++ {
+		if overlap(contents[0], contents[i]) >= minSlotOverlap {
+			slots = append(slots, i)
+		}
+	}
+	if len(slots) < 2 {
+		return nil
+	}
+
+	layout := &SlotLayout{}
+	for n, i := range slots {
+		layout.Slots = append(layout.Slots, Slot{Name: string(rune('a' + n)), Root: roots[i], Files: len(contents[i])})
+	}
+	for rel, sum := range contents[slots[0]] {
+		identical, everywhere := true, true
+		for _, i := range slots[1:] {
+			other, ok := contents[i][rel]
+			if !ok {
+				everywhere = false
+				break
+			}
+			if other != sum {
+				identical = false
+			}
+		}
+		switch {
+		case everywhere && identical:
+			layout.IdenticalFiles++
+		case everywhere:
+			layout.DifferentFiles++
+		}
+	}
+	return layout
+}
+
+// SlotOf returns the slot a path in the extracted tree or one of EMBA's
+// absolute paths into it belongs to, and the path within the slot
+func (l *SlotLayout) SlotOf(filePath string) (string, string) {
+	filePath = "/" + strings.TrimPrefix(filePath, "/")
+	for _, slot := range l.Slots {
+		marker := slot.Root + "/"
+		if i := strings.Index(filePath, marker); i >= 0 {
+			return slot.Name, "/" + filePath[i+len(marker):]
+		}
+	}
+	return "", ""
+}
+
+// Unslotted replaces the slot roots in s, a title or description naming a
+// file of a slot, with the path within the slot
+func (l *SlotLayout) Unslotted(s string) string {
+	for _, slot := range l.Slots {
+		s = strings.ReplaceAll(s, slot.Root+"/", "/")
+	}
+	return s
+}
+
+// outermost drops the roots inside another one of the sorted roots
+func outermost(roots []string) []string {
+	var kept []string
+	for _, root := range roots {
+		nested := false
+		for _, outer := range kept {
+			if _, ok := within(root, outer); ok {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			kept = append(kept, root)
+		}
+	}
+	return kept
+}
+
+// within returns p relative to root if it is below it
+func within(p, root string) (string, bool) {
+	if root == "/" {
+		return p, true
+	}
+	if strings.HasPrefix(p, root+"/") {
+		return path.Clean(strings.TrimPrefix(p, root)), true
+	}
+	return "", false
+}
+
+// overlap is the share of the smaller set's paths the other has as well
+func overlap(a, b map[string]string) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) == 0 {
+		return 0
+	}
+	shared := 0
+	for rel := range a {
+		if _, ok := b[rel]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a))
+}
@@ -0,0 +1,107 @@
+package freeze
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrFrozen is returned when a change is attempted on a frozen project
+var ErrFrozen = errors.New("project results are frozen")
+
+// snapshot is the canonical form of a project's result set that gets hashed
+type snapshot struct {
+	ProjectID    string               `json:"project_id"`
+	RiskLevel    models.RiskLevel     `json:"risk_level"`
+	Disposition  models.Disposition   `json:"disposition"`
+	FirmwareInfo string               `json:"firmware_info"`
+	Findings     []models.Finding     `json:"findings"`
+	CVEs         []models.CVEFinding  `json:"cves"`
+	OSINT        []models.OSINTResult `json:"osint"`
+}
+
+// ContentHash returns a sha256 over the project's findings, CVEs, OSINT
+// results and risk assessment. It changes whenever any delivered result does.
+// The CVEs' shared data is hashed as frozen projects show it, from their
+// CVE snapshots.
+func ContentHash(db *gorm.DB, projectID string) (string, error) {
+	var project models.Project
+	if err := db.First(&project, "id = ?", projectID).Error; err != nil {
+		return "", err
+	}
+
+	snap := snapshot{
+		ProjectID:    project.ID,
+		RiskLevel:    project.RiskLevel,
+		Disposition:  project.Disposition,
+		FirmwareInfo: project.FirmwareInfo,
+	}
+	if err := db.Where("project_id = ?", projectID).Order("id").Find(&snap.Findings).Error; err != nil {
+		return "", fmt.Errorf("failed to load findings: %w", err)
+	}
+	if err := db.Where("project_id = ?", projectID).Order("id").Find(&snap.CVEs).Error; err != nil {
+		return "", fmt.Errorf("failed to load CVE findings: %w", err)
+	}
+	if err := db.Where("project_id = ?", projectID).Order("id").Find(&snap.OSINT).Error; err != nil {
+		return "", fmt.Errorf("failed to load OSINT results: %w", err)
+	}
+
+	// Fingerprints are derived bookkeeping that backfills may recompute
+	for i := range snap.Findings {
+		snap.Findings[i].Fingerprint = ""
+	}
+
+	encoded, err := json.Marshal(snap)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result set: %w", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(encoded)), nil
+}
+
+// SnapshotCVEs stores the shared CVE records of a project's CVE findings
+// on them, which they show from then on: enriching the records doesn't
+// change the results of a frozen project
+func SnapshotCVEs(db *gorm.DB, projectID string) error {
+	var findings []models.CVEFinding
+	if err := db.Preload("CVE").Select("id", "cve_id").Where("project_id = ?", projectID).Find(&findings).Error; err != nil {
+		return fmt.Errorf("failed to load CVE findings: %w", err)
+	}
+	for _, finding := range findings {
+		if finding.CVE == nil {
+			continue
+		}
+		snapshot, err := json.Marshal(finding.CVE)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", finding.CVEID, err)
+		}
+		if err := db.Model(&models.CVEFinding{}).Where("id = ?", finding.ID).
+			UpdateColumn("cve_snapshot", string(snapshot)).Error; err != nil {
+			return fmt.Errorf("failed to snapshot %s: %w", finding.CVEID, err)
+		}
+	}
+	return nil
+}
+
+// ReleaseCVEs drops the CVE snapshots of an unfrozen project's findings,
+// which show the shared records again
+func ReleaseCVEs(db *gorm.DB, projectID string) error {
+	return db.Model(&models.CVEFinding{}).Where("project_id = ? AND cve_snapshot <> ''", projectID).
+		UpdateColumn("cve_snapshot", "").Error
+}
+
+// Check returns ErrFrozen if the project's results are locked
+func Check(db *gorm.DB, projectID string) error {
+	var project models.Project
+	if err := db.Select("id", "frozen_at").First(&project, "id = ?", projectID).Error; err != nil {
+		return err
+	}
+	if project.Frozen() {
+		return ErrFrozen
+	}
+	return nil
+}
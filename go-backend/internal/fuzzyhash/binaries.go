@@ -0,0 +1,60 @@
+package fuzzyhash
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+const (
+	// ssdeep hashes of smaller files match too much to mean anything
+	minHashedFileSize = 4 << 10
+	maxHashedFileSize = 64 << 20
+)
+
+var elfMagic = []byte{0x7f, 'E', 'L', 'F'}
+
+// HashBinaries returns the SHA-256 and ssdeep hashes of every ELF binary
+// below root, with paths relative to root
+func HashBinaries(ctx context.Context, root string) ([]models.BinaryHash, error) {
+	hashes := []models.BinaryHash{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() < minHashedFileSize || info.Size() > maxHashedFileSize {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil || !bytes.HasPrefix(content, elfMagic) {
+			return nil
+		}
+
+		hash := Hash(content)
+		blockSize, _ := BlockSize(hash)
+		sum := sha256.Sum256(content)
+		hashes = append(hashes, models.BinaryHash{
+			FilePath:  "/" + filepath.ToSlash(strings.TrimPrefix(p, root+string(filepath.Separator))),
+			Size:      info.Size(),
+			SHA256:    hex.EncodeToString(sum[:]),
+			SSDeep:    hash,
+			BlockSize: blockSize,
+		})
+		return nil
+	})
+	return hashes, err
+}
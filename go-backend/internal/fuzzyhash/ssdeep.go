@@ -0,0 +1,261 @@
+// Package fuzzyhash computes and compares ssdeep (context triggered
+// piecewise) hashes, which stay similar when a file changes only in parts:
+// the same library rebuilt for another product of a vendor hashes close to
+// the original where its SHA-256 shares nothing
+package fuzzyhash
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	rollingWindow = 7
+	minBlockSize  = 3
+	hashPrime     = 0x01000193
+	hashInit      = 0x28021967
+	spamsumLength = 64
+	b64           = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+)
+
+// ErrInvalidHash is returned for strings that aren't ssdeep hashes
+var ErrInvalidHash = errors.New("invalid ssdeep hash")
+
+// rollingHash is the Adler-32 style hash over the last rollingWindow bytes
+// that decides where a piece of the input ends
+type rollingHash struct {
+	window     [rollingWindow]uint32
+	h1, h2, h3 uint32
+	n          int
+}
+
+func (r *rollingHash) roll(c byte) uint32 {
+	r.h2 -= r.h1
+	r.h2 += rollingWindow * uint32(c)
+	r.h1 += uint32(c)
+	r.h1 -= r.window[r.n%rollingWindow]
+	r.window[r.n%rollingWindow] = uint32(c)
+	r.n++
+	r.h3 <<= 5
+	r.h3 ^= uint32(c)
+	return r.h1 + r.h2 + r.h3
+}
+
+func (r *rollingHash) sum() uint32 {
+	return r.h1 + r.h2 + r.h3
+}
+
+// Hash returns the ssdeep hash of data, "blocksize:hash:hash2" as the
+// ssdeep tool prints it
+func Hash(data []byte) string {
+	blockSize := uint32(minBlockSize)
+	for uint64(blockSize)*spamsumLength < uint64(len(data)) {
+		blockSize *= 2
+	}
+
+	for {
+		var roll rollingHash
+		h, h2 := uint32(hashInit), uint32(hashInit)
+		var sig, sig2 []byte
+		// Once a signature is full, the last character takes in the rest
+		// of the input. ssdeep keeps it from the last piece boundary when
+		// the input ends on one.
+		var last, last2 byte
+
+		for _, c := range data {
+			h = (h * hashPrime) ^ uint32(c)
+			h2 = (h2 * hashPrime) ^ uint32(c)
+			r := roll.roll(c)
+
+			if r%blockSize == blockSize-1 {
+				if len(sig) < spamsumLength-1 {
+					sig = append(sig, b64[h%64])
+					h = hashInit
+				} else {
+					last = b64[h%64]
+				}
+			}
+			if r%(blockSize*2) == blockSize*2-1 {
+				if len(sig2) < spamsumLength/2-1 {
+					sig2 = append(sig2, b64[h2%64])
+					h2 = hashInit
+				} else {
+					last2 = b64[h2%64]
+				}
+			}
+		}
+
+		// Too few pieces to compare anything: retry with smaller ones
+		if blockSize > minBlockSize && len(sig) < spamsumLength/2 {
+			blockSize /= 2
+			continue
+		}
+
+		if roll.sum() != 0 {
+			last, last2 = b64[h%64], b64[h2%64]
+		}
+		if last != 0 {
+			sig = append(sig, last)
+		}
+		if last2 != 0 {
+			sig2 = append(sig2, last2)
+		}
+		return fmt.Sprintf("%d:%s:%s", blockSize, sig, sig2)
+	}
+}
+
+// parsed is an ssdeep hash split into its parts
+type parsed struct {
+	blockSize uint64
+	sig, sig2 string
+}
+
+func parse(hash string) (parsed, error) {
+	parts := strings.SplitN(hash, ":", 3)
+	if len(parts) != 3 {
+		return parsed{}, ErrInvalidHash
+	}
+	blockSize, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil || blockSize < minBlockSize {
+		return parsed{}, ErrInvalidHash
+	}
+	// ssdeep may append ,"filename"
+	sig2, _, _ := strings.Cut(parts[2], ",")
+	return parsed{blockSize: blockSize, sig: parts[1], sig2: sig2}, nil
+}
+
+// BlockSize returns the block size of an ssdeep hash. Only hashes whose
+// block sizes are equal or differ by a factor of two can be compared.
+func BlockSize(hash string) (uint64, error) {
+	p, err := parse(hash)
+	return p.blockSize, err
+}
+
+// Compare returns how similar the inputs of two ssdeep hashes are, from 0
+// (nothing in common) to 100 (identical or nearly so)
+func Compare(a, b string) (int, error) {
+	pa, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+
+	if pa.blockSize != pb.blockSize && pa.blockSize != pb.blockSize*2 && pb.blockSize != pa.blockSize*2 {
+		return 0, nil
+	}
+
+	// Long runs of one character (padding) would make unrelated files match
+	a1, a2 := collapseRuns(pa.sig), collapseRuns(pa.sig2)
+	b1, b2 := collapseRuns(pb.sig), collapseRuns(pb.sig2)
+
+	switch {
+	case pa.blockSize == pb.blockSize:
+		if a1 == b1 && a2 == b2 {
+			return 100, nil
+		}
+		score := scoreStrings(a1, b1, pa.blockSize)
+		if score2 := scoreStrings(a2, b2, pa.blockSize*2); score2 > score {
+			score = score2
+		}
+		return score, nil
+	case pa.blockSize == pb.blockSize*2:
+		return scoreStrings(a1, b2, pa.blockSize), nil
+	default:
+		return scoreStrings(a2, b1, pb.blockSize), nil
+	}
+}
+
+// collapseRuns shortens runs of more than three identical characters to three
+func collapseRuns(s string) string {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if i >= 3 && s[i] == s[i-1] && s[i] == s[i-2] && s[i] == s[i-3] {
+			continue
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// scoreStrings scores two signatures of the same block size by their
+// weighted edit distance
+func scoreStrings(s1, s2 string, blockSize uint64) int {
+	if len(s1) > spamsumLength || len(s2) > spamsumLength {
+		return 0
+	}
+	// Signatures must share a whole rolling window to count as related
+	if !commonSubstring(s1, s2) {
+		return 0
+	}
+
+	score := editDistance(s1, s2)
+	score = score * spamsumLength / (len(s1) + len(s2))
+	score = 100 * score / spamsumLength
+	if score >= 100 {
+		return 0
+	}
+	score = 100 - score
+
+	// Small block sizes can't be trusted to mean much: cap their score by
+	// how much input the signatures actually cover
+	if blockSize >= (99+rollingWindow)/rollingWindow*minBlockSize {
+		return score
+	}
+	shorter := len(s1)
+	if len(s2) < shorter {
+		shorter = len(s2)
+	}
+	if limit := int(blockSize/minBlockSize) * shorter; score > limit {
+		score = limit
+	}
+	return score
+}
+
+func commonSubstring(s1, s2 string) bool {
+	if len(s1) < rollingWindow || len(s2) < rollingWindow {
+		return false
+	}
+	for i := 0; i+rollingWindow <= len(s1); i++ {
+		if strings.Contains(s2, s1[i:i+rollingWindow]) {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance is the Levenshtein distance with substitutions costing two,
+// as ssdeep weighs them
+func editDistance(s1, s2 string) int {
+	prev := make([]int, len(s2)+1)
+	curr := make([]int, len(s2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s1); i++ {
+		curr[0] = i
+		for j := 1; j <= len(s2); j++ {
+			cost := 2
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(s2)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,133 @@
+package fuzzyhash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// The expected hashes and scores were computed with a port of ssdeep
+// 2.14's fuzzy.c (fuzzy_hash_buf and fuzzy_compare) over the same inputs
+
+// pseudoRandom returns n bytes of a linear congruential generator, so the
+// inputs need no fixture files
+func pseudoRandom(n int, seed uint32) []byte {
+	out := make([]byte, n)
+	for i := range out {
+		seed = seed*1664525 + 1013904223
+		out[i] = byte(seed >> 24)
+	}
+	return out
+}
+
+func TestHash(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "3::"},
+		{"zeros", make([]byte, 4096), "3::"},
+		{
+			"repeated text",
+			bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog. "), 40),
+			"12:Fg66666666666666666666666666666666666666G:F1",
+		},
+		{"random 1000", pseudoRandom(1000, 1), "24:ofpwiCb4fQARTZ1DnCmjr4ngjEBa8d7hM0IIZ+mLRJ:PiCEfQUVBAhI7Tu"},
+		{
+			"random 64k",
+			pseudoRandom(65536, 2),
+			"1536:h7K4vKzgzBQC4eprfpx4wL8pi4dJSSy4/khDFSXIcI:c43d9T74tpi4dJSc/khDFOIj",
+		},
+		{
+			// A full signature on input ending in a rolling window of zeros
+			// keeps the character of its last piece boundary
+			"zero tail",
+			append(pseudoRandom(1500, 5), make([]byte, 7)...),
+			"24:gXMWUbUWa5IlqivK0/IneIr1kTDyl4FIKDhv9lk1+j35nVUCz7cqK56sbk9nyacj:FWUAWa5GqivF/sMql4FI69lk1+j35nVy",
+		},
+	}
+
+	for _, tc := range cases {
+		if got := Hash(tc.data); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	const (
+		original = "192:LmxmXI+PQ80GxfHTUVeISgVEqw/fqtvTEciXtnO9ZWd/:qeIiR9IpD4CdTEc61O9Yt"
+		// 16 bytes in the middle inverted
+		patched = "192:LmxmXI+PQ80GxfHTUVeISgVcqw/fqtvTEciXtnO9ZWd/:qeIiR9IpL4CdTEc61O9Yt"
+		// 2 KiB appended
+		appended = "192:LmxmXI+PQ80GxfHTUVeISgVEqw/fqtvTEciXtnO9ZWdeu3/B:qeIiR9IpD4CdTEc61O9Ycu35"
+		// The first half, hashed at half the block size
+		half      = "96:R7kyTmxyNpj5l4pUqmvPC4I180hsT2Wl56RIhRTgLVCoefhDGS0tjV5B:LmxmXI+PQ80GxfHTUVeISgV/"
+		unrelated = "192:CxdzEtXTS4BCmGsF+xTdTX8c+R/LMbc9PJuTTFWcm:CQbGsQxdL8c+RNUTTDm"
+	)
+
+	if got := Hash(pseudoRandom(8192, 3)); got != original {
+		t.Fatalf("Hash: got %q, want %q", got, original)
+	}
+
+	cases := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"identical", original, original, 100},
+		{"file name suffix", original, original + `,"firmware.bin"`, 100},
+		{"patched", original, patched, 99},
+		{"appended", original, appended, 97},
+		{"half block size", original, half, 72},
+		{"double block size", half, original, 72},
+		{"unrelated", original, unrelated, 0},
+		{"incompatible block sizes", "3:ABCDEFGHIJ:ABCDE", "12:ABCDEFGHIJ:ABCDE", 0},
+		{"no common window", "192:ABCDEFabcdef:ABCDEF", "192:ABCDEFxbcdef:ABCDEF", 0},
+	}
+
+	for _, tc := range cases {
+		got, err := Compare(tc.a, tc.b)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCompareInvalid(t *testing.T) {
+	for _, hash := range []string{"", "deadbeef", "abc:def:ghi", "2:ABC:DEF", "-3:ABC:DEF"} {
+		if _, err := Compare(hash, "3::"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("%q: got %v, want ErrInvalidHash", hash, err)
+		}
+		if _, err := Compare("3::", hash); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("%q as second hash: got %v, want ErrInvalidHash", hash, err)
+		}
+	}
+}
+
+func TestBlockSize(t *testing.T) {
+	got, err := BlockSize(`1536:h7K4vKzgzBQC4e:c43d9T74,"a.bin"`)
+	if err != nil || got != 1536 {
+		t.Errorf("got %d, %v, want 1536", got, err)
+	}
+}
+
+func TestCollapseRuns(t *testing.T) {
+	cases := map[string]string{
+		"":           "",
+		"AAA":        "AAA",
+		"AAAA":       "AAA",
+		"xAAAAAAAAy": "xAAAy",
+		"AAAABBBBAA": "AAABBBAA",
+	}
+	for in, want := range cases {
+		if got := collapseRuns(in); got != want {
+			t.Errorf("collapseRuns(%q): got %q, want %q", in, got, want)
+		}
+	}
+}
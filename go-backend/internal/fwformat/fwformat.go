@@ -0,0 +1,367 @@
+// Package fwformat identifies firmware images by their container signature
+package fwformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Container formats recognized by Identify
+const (
+	Unknown       = "unknown"
+	UImage        = "uimage"
+	FIT           = "fit"
+	TRX           = "trx"
+	CHK           = "chk"
+	NPK           = "npk"
+	SHRS          = "shrs"
+	EncryptedImg  = "encrpted_img"
+	SquashFS      = "squashfs"
+	UBI           = "ubi"
+	JFFS2         = "jffs2"
+	CPIO          = "cpio"
+	Ext           = "ext"
+	ISO9660       = "iso9660"
+	AndroidBoot   = "android_boot"
+	AndroidSparse = "android_sparse"
+	ELF           = "elf"
+	IntelHex      = "intel_hex"
+	Gzip          = "gzip"
+	XZ            = "xz"
+	Bzip2         = "bzip2"
+	LZMA          = "lzma"
+	Zip           = "zip"
+	UEFI          = "uefi"
+	Tar           = "tar"
+)
+
+// headerSize is how much of an image Identify needs to see
+const headerSize = 64 * 1024
+
+type signature struct {
+	format string
+	offset int
+	magic  []byte
+}
+
+// signatures are checked in order; more specific ones come first
+var signatures = []signature{
+	{UImage, 0, []byte{0x27, 0x05, 0x19, 0x56}},
+	{FIT, 0, []byte{0xd0, 0x0d, 0xfe, 0xed}},
+	{TRX, 0, []byte("HDR0")},
+	{CHK, 0, []byte("*#$^")},
+	{NPK, 0, []byte{0x1e, 0xf1, 0xd0, 0xba}},
+	{SHRS, 0, []byte("SHRS")},
+	{EncryptedImg, 0, []byte("encrpted_img")},
+	{SquashFS, 0, []byte("hsqs")},
+	{SquashFS, 0, []byte("sqsh")},
+	{UBI, 0, []byte("UBI#")},
+	{JFFS2, 0, []byte{0x85, 0x19}},
+	{JFFS2, 0, []byte{0x19, 0x85}},
+	{CPIO, 0, []byte("070701")},
+	{CPIO, 0, []byte("070702")},
+	{AndroidBoot, 0, []byte("ANDROID!")},
+	{AndroidSparse, 0, []byte{0x3a, 0xff, 0x26, 0xed}},
+	{ELF, 0, []byte{0x7f, 'E', 'L', 'F'}},
+	{Gzip, 0, []byte{0x1f, 0x8b}},
+	{XZ, 0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{Bzip2, 0, []byte("BZh")},
+	{Zip, 0, []byte("PK\x03\x04")},
+	{LZMA, 0, []byte{0x5d, 0x00, 0x00}},
+	{UEFI, 40, []byte("_FVH")},
+	{Tar, 257, []byte("ustar")},
+	{Ext, 1080, []byte{0x53, 0xef}},
+	{ISO9660, 32769, []byte("CD001")},
+}
+
+// Encrypted reports whether images of the format are vendor encrypted
+// (D-Link's SHRS and encrpted_img containers), so they can only be
+// analyzed once decrypted
+func Encrypted(format string) bool {
+	return format == SHRS || format == EncryptedImg
+}
+
+// Identify returns the container format of an image from its first bytes
+func Identify(header []byte) string {
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if len(header) >= end && bytes.Equal(header[sig.offset:end], sig.magic) {
+			return sig.format
+		}
+	}
+	if isIntelHex(header) {
+		return IntelHex
+	}
+	return Unknown
+}
+
+// IdentifyFile reads the start of a file and identifies its format
+func IdentifyFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Unknown, err
+	}
+	defer f.Close()
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Unknown, err
+	}
+	return Identify(header[:n]), nil
+}
+
+// isIntelHex checks for a ":LLAAAATT...CC" record of hex digits whose length
+// matches its byte count
+func isIntelHex(header []byte) bool {
+	line := header
+	if i := bytes.IndexAny(line, "\r\n"); i >= 0 {
+		line = line[:i]
+	}
+	if len(line) < 11 || line[0] != ':' || len(line)%2 == 0 {
+		return false
+	}
+	record := make([]byte, (len(line)-1)/2)
+	if _, err := hex.Decode(record, line[1:]); err != nil {
+		return false
+	}
+	return len(record) == 5+int(record[0])
+}
+
+// Entropy returns the Shannon entropy of a file in bits per byte, from 0
+// for constant data to 8 for random data. Encrypted and compressed images
+// come close to 8.
+func Entropy(path string) (float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var counts [256]int64
+	var total int64
+	buf := make([]byte, 1<<20)
+	for {
+		n, err := f.Read(buf)
+		for _, b := range buf[:n] {
+			counts[b]++
+		}
+		total += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy, nil
+}
+
+// Byte orders of Info
+const (
+	LittleEndian = "little"
+	BigEndian    = "big"
+)
+
+// Info is what an image's header tells about it before any extraction: the
+// container format, the byte order and CPU architecture it hints at and
+// format specific details such as the compression or image name
+type Info struct {
+	Format       string            `json:"format"`
+	Endianness   string            `json:"endianness,omitempty"`
+	Architecture string            `json:"architecture,omitempty"`
+	Details      map[string]string `json:"details,omitempty"`
+}
+
+// Describe identifies the format of an image from its first bytes and reads
+// the hints its header holds
+func Describe(header []byte) Info {
+	info := Info{Format: Identify(header), Details: map[string]string{}}
+	switch info.Format {
+	case UImage:
+		describeUImage(header, &info)
+	case TRX:
+		// Broadcom's header is little-endian, as are its MIPS targets
+		info.Endianness = LittleEndian
+		if len(header) >= 16 {
+			info.Details["version"] = fmt.Sprint(binary.LittleEndian.Uint16(header[14:16]))
+		}
+	case CHK:
+		describeCHK(header, &info)
+	case SquashFS:
+		describeSquashFS(header, &info)
+	case UBI:
+		info.Endianness = BigEndian
+	case JFFS2:
+		if header[0] == 0x85 {
+			info.Endianness = LittleEndian
+		} else {
+			info.Endianness = BigEndian
+		}
+	case FIT:
+		info.Endianness = BigEndian
+	case ELF:
+		describeELF(header, &info)
+	case IntelHex:
+		info.Details["hint"] = "microcontroller image"
+	}
+	if len(info.Details) == 0 {
+		info.Details = nil
+	}
+	return info
+}
+
+// DescribeFile reads the start of a file and describes it
+func DescribeFile(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{Format: Unknown}, err
+	}
+	defer f.Close()
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Info{Format: Unknown}, err
+	}
+	return Describe(header[:n]), nil
+}
+
+// uImageArchitectures maps the ih_arch codes of U-Boot's image header
+var uImageArchitectures = map[byte]string{
+	1: "alpha", 2: "arm", 3: "x86", 4: "ia64", 5: "mips", 6: "mips64",
+	7: "powerpc", 8: "s390", 9: "sh", 10: "sparc", 11: "sparc64", 12: "m68k",
+	14: "microblaze", 15: "nios2", 16: "blackfin", 17: "avr32", 20: "nds32",
+	21: "openrisc", 22: "arm64", 23: "arc", 24: "x86_64", 25: "xtensa", 26: "riscv",
+}
+
+// uImageOSes maps the common ih_os codes of U-Boot's image header
+var uImageOSes = map[byte]string{
+	1: "openbsd", 2: "netbsd", 3: "freebsd", 5: "linux", 14: "vxworks",
+	16: "qnx", 17: "u-boot", 18: "rtems", 24: "openrtos", 25: "arm-trusted-firmware",
+}
+
+// uImageCompressions maps the ih_comp codes of U-Boot's image header
+var uImageCompressions = map[byte]string{
+	0: "none", 1: "gzip", 2: "bzip2", 3: "lzma", 4: "lzo", 5: "lz4", 6: "zstd",
+}
+
+// uImageTypes maps the common ih_type codes of U-Boot's image header
+var uImageTypes = map[byte]string{
+	1: "standalone", 2: "kernel", 3: "ramdisk", 4: "multi", 5: "firmware",
+	6: "script", 7: "filesystem", 8: "flat_dt",
+}
+
+func describeUImage(header []byte, info *Info) {
+	if len(header) < 64 {
+		return
+	}
+	info.Architecture = uImageArchitectures[header[29]]
+	if name, ok := uImageOSes[header[28]]; ok {
+		info.Details["os"] = name
+	}
+	if compression, ok := uImageCompressions[header[31]]; ok {
+		info.Details["compression"] = compression
+	}
+	if imageType, ok := uImageTypes[header[30]]; ok {
+		info.Details["image_type"] = imageType
+	}
+	if name := cString(header[32:64]); name != "" {
+		info.Details["name"] = name
+	}
+}
+
+// describeCHK reads the board ID of a Netgear CHK header, which follows the
+// fixed 40-byte part and runs to the end of the header
+func describeCHK(header []byte, info *Info) {
+	info.Endianness = BigEndian
+	if len(header) < 40 {
+		return
+	}
+	headerLen := int(binary.BigEndian.Uint32(header[4:8]))
+	if headerLen > 40 && headerLen <= len(header) {
+		if board := cString(header[40:headerLen]); board != "" {
+			info.Details["board_id"] = board
+		}
+	}
+}
+
+// squashFSCompressions maps the compression IDs of a SquashFS 4 superblock
+var squashFSCompressions = map[uint16]string{
+	1: "gzip", 2: "lzma", 3: "lzo", 4: "xz", 5: "lz4", 6: "zstd",
+}
+
+func describeSquashFS(header []byte, info *Info) {
+	var order binary.ByteOrder = binary.LittleEndian
+	info.Endianness = LittleEndian
+	if bytes.HasPrefix(header, []byte("sqsh")) {
+		order = binary.BigEndian
+		info.Endianness = BigEndian
+	}
+	if len(header) < 32 {
+		return
+	}
+	major := order.Uint16(header[28:30])
+	info.Details["version"] = fmt.Sprintf("%d.%d", major, order.Uint16(header[30:32]))
+	if major >= 4 {
+		if compression, ok := squashFSCompressions[order.Uint16(header[20:22])]; ok {
+			info.Details["compression"] = compression
+		}
+	}
+}
+
+// elfMachines maps the common e_machine values of ELF headers
+var elfMachines = map[uint16]string{
+	2: "sparc", 3: "x86", 8: "mips", 20: "powerpc", 21: "powerpc64", 40: "arm",
+	42: "sh", 43: "sparc64", 62: "x86_64", 83: "avr", 94: "xtensa",
+	105: "msp430", 183: "arm64", 195: "arc", 243: "riscv",
+}
+
+func describeELF(header []byte, info *Info) {
+	if len(header) < 20 {
+		return
+	}
+	var order binary.ByteOrder
+	switch header[5] {
+	case 1:
+		order = binary.LittleEndian
+		info.Endianness = LittleEndian
+	case 2:
+		order = binary.BigEndian
+		info.Endianness = BigEndian
+	default:
+		return
+	}
+	switch header[4] {
+	case 1:
+		info.Details["class"] = "32-bit"
+	case 2:
+		info.Details["class"] = "64-bit"
+	}
+	info.Architecture = elfMachines[order.Uint16(header[18:20])]
+}
+
+// cString returns the text of a NUL padded header field
+func cString(field []byte) string {
+	if i := bytes.IndexByte(field, 0); i >= 0 {
+		field = field[:i]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(string(field), ""))
+}
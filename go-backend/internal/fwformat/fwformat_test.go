@@ -0,0 +1,123 @@
+package fwformat
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestDescribeFile describes the header fixtures in testdata
+func TestDescribeFile(t *testing.T) {
+	cases := []struct {
+		file string
+		want Info
+	}{
+		{
+			file: "uimage.bin",
+			want: Info{
+				Format:       UImage,
+				Architecture: "arm",
+				Details: map[string]string{
+					"os":          "linux",
+					"compression": "gzip",
+					"image_type":  "kernel",
+					"name":        "Linux-4.14.180",
+				},
+			},
+		},
+		{
+			file: "netgear.chk",
+			want: Info{
+				Format:     CHK,
+				Endianness: BigEndian,
+				Details:    map[string]string{"board_id": "U12H332T20_NETGEAR"},
+			},
+		},
+		{
+			file: "broadcom.trx",
+			want: Info{Format: TRX, Endianness: LittleEndian, Details: map[string]string{"version": "1"}},
+		},
+		{
+			file: "rootfs.squashfs",
+			want: Info{
+				Format:     SquashFS,
+				Endianness: LittleEndian,
+				Details:    map[string]string{"version": "4.0", "compression": "xz"},
+			},
+		},
+		{
+			// SquashFS 3 has no compression ID in its superblock
+			file: "legacy.squashfs",
+			want: Info{Format: SquashFS, Endianness: BigEndian, Details: map[string]string{"version": "3.1"}},
+		},
+		{
+			file: "busybox-arm.elf",
+			want: Info{
+				Format:       ELF,
+				Endianness:   LittleEndian,
+				Architecture: "arm",
+				Details:      map[string]string{"class": "32-bit"},
+			},
+		},
+		{
+			file: "init-mips64.elf",
+			want: Info{
+				Format:       ELF,
+				Endianness:   BigEndian,
+				Architecture: "mips",
+				Details:      map[string]string{"class": "64-bit"},
+			},
+		},
+		{
+			file: "firmware.hex",
+			want: Info{Format: IntelHex, Details: map[string]string{"hint": "microcontroller image"}},
+		},
+		{
+			file: "random.bin",
+			want: Info{Format: Unknown},
+		},
+	}
+
+	for _, tc := range cases {
+		got, err := DescribeFile(filepath.Join("testdata", tc.file))
+		if err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.file, got, tc.want)
+		}
+	}
+}
+
+// TestDescribeTruncated checks that headers cut short of their fields are
+// identified without reading past their end
+func TestDescribeTruncated(t *testing.T) {
+	cases := map[string]string{
+		"'\x05\x19V":      UImage,
+		"*#$^\x00\x00":    CHK,
+		"HDR0":            TRX,
+		"hsqs\x00\x00":    SquashFS,
+		"\x7fELF\x01\x01": ELF,
+	}
+	for header, want := range cases {
+		if got := Describe([]byte(header)); got.Format != want {
+			t.Errorf("%q: got %s, want %s", header, got.Format, want)
+		}
+	}
+}
+
+func TestIdentifyIntelHex(t *testing.T) {
+	cases := map[string]bool{
+		":00000001FF\n": true,
+		// The byte count says 16 data bytes but the record holds 15
+		":10010000214601360121470136007EFE09D21901\n": false,
+		":0000000GFF\n": false,
+		"00000001FF\n":  false,
+	}
+	for header, want := range cases {
+		if got := Identify([]byte(header)) == IntelHex; got != want {
+			t.Errorf("%q: got %v, want %v", header, got, want)
+		}
+	}
+}
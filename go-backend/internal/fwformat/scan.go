@@ -0,0 +1,114 @@
+package fwformat
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+)
+
+// scanChunkSize is how much of an image Scan searches at a time; each chunk
+// is read with headerSize more so a header found near its end is whole
+const scanChunkSize = 4 << 20
+
+// Embedded is a container format found inside an image
+type Embedded struct {
+	Offset int64 `json:"offset"`
+	Info
+}
+
+// embeddedSignatures are the signatures distinctive enough to search for
+// anywhere in an image. Two- and three-byte magics would match all over
+// compressed data; gzip is kept with its deflate method byte. Every member
+// of a tar archive has a header of its own, so tar is left out.
+var embeddedSignatures = func() []signature {
+	var sigs []signature
+	for _, sig := range signatures {
+		if len(sig.magic) >= 4 && sig.format != Tar {
+			sigs = append(sigs, sig)
+		}
+	}
+	return append(sigs, signature{Gzip, 0, []byte{0x1f, 0x8b, 0x08}})
+}()
+
+// Scan searches a whole image for embedded containers, the way binwalk's
+// signature scan does, and returns up to limit of them by offset; the
+// image's own header is found at offset 0 too. It reports whether it
+// stopped at the limit.
+func Scan(path string, limit int) ([]Embedded, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	var found []Embedded
+	buf := make([]byte, scanChunkSize+headerSize)
+	for pos := int64(0); ; pos += scanChunkSize {
+		n, err := f.ReadAt(buf, pos)
+		if err != nil && err != io.EOF {
+			return nil, false, err
+		}
+		if n == 0 {
+			break
+		}
+		for _, embedded := range ScanData(buf[:n], limit-len(found)) {
+			embedded.Offset += pos
+			found = append(found, embedded)
+		}
+		if len(found) == limit {
+			return found, true, nil
+		}
+		if n <= scanChunkSize {
+			break
+		}
+	}
+	return found, false, nil
+}
+
+// ScanData searches data for embedded containers like Scan does, up to
+// limit of them. Only headers within its first 4 MiB are found.
+func ScanData(data []byte, limit int) []Embedded {
+	var found []Embedded
+	hits := scanChunk(data)
+	sort.Ints(hits)
+	for _, start := range hits {
+		info := Describe(data[start:])
+		if info.Format == Unknown {
+			continue
+		}
+		found = append(found, Embedded{Offset: int64(start), Info: info})
+		if len(found) == limit {
+			break
+		}
+	}
+	return found
+}
+
+// scanChunk returns where the embedded signatures' headers start in the
+// first scanChunkSize bytes of chunk
+func scanChunk(chunk []byte) []int {
+	seen := make(map[int]bool)
+	var hits []int
+	for _, sig := range embeddedSignatures {
+		for from := 0; ; {
+			i := bytes.Index(chunk[from:], sig.magic)
+			if i < 0 {
+				break
+			}
+			i += from
+			from = i + 1
+			start := i - sig.offset
+			if start >= scanChunkSize {
+				break
+			}
+			// A header before the chunk was seen with the previous one
+			if start < 0 || seen[start] {
+				continue
+			}
+			seen[start] = true
+			hits = append(hits, start)
+		}
+	}
+	return hits
+}
@@ -0,0 +1,267 @@
+// Package ghsa looks the SBOM components of package ecosystems (npm, PyPI,
+// Go, ...) up in the GitHub Advisory Database by their purl. EMBA matches
+// CVEs by CPE, which packages bundled in firmware web UIs rarely have.
+package ghsa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+)
+
+const (
+	apiURL = "https://api.github.com/advisories"
+
+	// Source is the vulnerability database of the CVE findings of advisories
+	Source = "GHSA"
+)
+
+// ecosystems maps purl types to the GitHub Advisory Database's ecosystems
+var ecosystems = map[string]string{
+	"npm":      "npm",
+	"pypi":     "pip",
+	"gem":      "rubygems",
+	"maven":    "maven",
+	"golang":   "go",
+	"cargo":    "rust",
+	"composer": "composer",
+	"nuget":    "nuget",
+	"pub":      "pub",
+	"hex":      "erlang",
+	"swift":    "swift",
+}
+
+// severities maps advisory severities to risk levels
+var severities = map[string]models.RiskLevel{
+	"critical": models.RiskCritical,
+	"high":     models.RiskHigh,
+	"moderate": models.RiskMedium,
+	"medium":   models.RiskMedium,
+	"low":      models.RiskLow,
+}
+
+// Lookup finds the advisories affecting SBOM components
+type Lookup struct {
+	token   string
+	baseURL string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// New returns the advisory lookup, or nil when GHSA_LOOKUP is off or in
+// offline mode
+func New(cfg *config.Config) *Lookup {
+	if !cfg.GHSALookup || cfg.OfflineMode {
+		return nil
+	}
+	return &Lookup{
+		token:   cfg.GitHubToken,
+		baseURL: apiURL,
+		timeout: cfg.GHSALookupTimeout,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Advisory is a reviewed advisory of the GitHub Advisory Database
+type Advisory struct {
+	GHSAID      string   `json:"ghsa_id"`
+	CVEID       string   `json:"cve_id"`
+	HTMLURL     string   `json:"html_url"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Severity    string   `json:"severity"`
+	References  []string `json:"references"`
+	PublishedAt string   `json:"published_at"`
+	UpdatedAt   string   `json:"updated_at"`
+	CVSS        struct {
+		VectorString string  `json:"vector_string"`
+		Score        float64 `json:"score"`
+	} `json:"cvss"`
+	CWEs []struct {
+		CWEID string `json:"cwe_id"`
+	} `json:"cwes"`
+	Vulnerabilities []struct {
+		VulnerableVersionRange string `json:"vulnerable_version_range"`
+		FirstPatchedVersion    string `json:"first_patched_version"`
+	} `json:"vulnerabilities"`
+}
+
+// Find returns a CVE finding, named by its CVE or else its GHSA ID, for every
+// advisory affecting the version of a component with a purl of an
+// ecosystem GitHub tracks. Advisories of CVEs already in known are
+// skipped. Lookups stop at the timeout or the first failure; the findings
+// of the components looked up until then are returned.
+func (l *Lookup) Find(components []models.SBOMComponent, known []models.CVEFinding) []models.CVEFinding {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	seen := make(map[string]bool, len(known))
+	for _, cve := range known {
+		seen[cve.CVEID] = true
+	}
+
+	var findings []models.CVEFinding
+	looked := make(map[string]bool)
+	for i, component := range components {
+		ecosystem, name, version, ok := parsePURL(component.PURL)
+		if !ok || version == "" || looked[ecosystem+name+version] {
+			continue
+		}
+		looked[ecosystem+name+version] = true
+
+		advisories, err := l.advisories(ctx, ecosystem, name, version)
+		if ctx.Err() != nil {
+			log.Printf("GitHub advisory lookup stopped after %s, %d of %d components checked", l.timeout, i, len(components))
+			break
+		}
+		if err != nil {
+			log.Printf("GitHub advisory lookup for %s failed: %v", component.PURL, err)
+			break
+		}
+		for _, advisory := range advisories {
+			finding := toFinding(component, advisory)
+			if seen[finding.CVEID] {
+				continue
+			}
+			seen[finding.CVEID] = true
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// advisories returns the reviewed advisories affecting a package version
+func (l *Lookup) advisories(ctx context.Context, ecosystem, name, version string) ([]Advisory, error) {
+	params := url.Values{
+		"ecosystem": {ecosystem},
+		"affects":   {name + "@" + version},
+		"type":      {"reviewed"},
+		"per_page":  {"100"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if l.token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned status %d", resp.StatusCode)
+	}
+
+	var advisories []Advisory
+	if err := json.NewDecoder(resp.Body).Decode(&advisories); err != nil {
+		return nil, fmt.Errorf("failed to decode GitHub response: %w", err)
+	}
+	return advisories, nil
+}
+
+// parsePURL returns the advisory ecosystem, package name and version of a
+// purl, the name the way the ecosystem spells it: @scope/name for npm,
+// group:artifact for Maven, the module path for Go
+func parsePURL(purl string) (string, string, string, bool) {
+	rest, ok := strings.CutPrefix(purl, "pkg:")
+	if !ok {
+		return "", "", "", false
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	typ, path, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", "", "", false
+	}
+	ecosystem, ok := ecosystems[strings.ToLower(typ)]
+	if !ok {
+		return "", "", "", false
+	}
+	path, version, _ := strings.Cut(path, "@")
+
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		segments = append(segments, segment)
+	}
+	if unescaped, err := url.PathUnescape(version); err == nil {
+		version = unescaped
+	}
+
+	name := strings.Join(segments, "/")
+	if ecosystem == "maven" && len(segments) == 2 {
+		name = segments[0] + ":" + segments[1]
+	}
+	if ecosystem == "pip" {
+		name = strings.ToLower(name)
+	}
+	return ecosystem, name, version, name != ""
+}
+
+// toFinding converts an advisory of a component into a CVE finding
+func toFinding(component models.SBOMComponent, advisory Advisory) models.CVEFinding {
+	id := advisory.CVEID
+	if id == "" {
+		id = advisory.GHSAID
+	}
+
+	level, ok := severities[strings.ToLower(advisory.Severity)]
+	if !ok {
+		level = models.RiskMedium
+	}
+	description := advisory.Summary
+	if description == "" {
+		description = advisory.Description
+	}
+
+	references := []string{advisory.HTMLURL}
+	for _, reference := range advisory.References {
+		if reference != advisory.HTMLURL {
+			references = append(references, reference)
+		}
+	}
+	encoded, _ := json.Marshal(references)
+
+	var cwes []string
+	for _, cwe := range advisory.CWEs {
+		if cwe.CWEID != "" {
+			cwes = append(cwes, cwe.CWEID)
+		}
+	}
+	sort.Strings(cwes)
+
+	finding := models.CVEFinding{
+		CVEID:           id,
+		SoftwareName:    component.Name,
+		SoftwareVersion: component.Version,
+		Description:     description,
+		SeverityScore:   advisory.CVSS.Score,
+		SeverityLevel:   level,
+		CVSSVector:      advisory.CVSS.VectorString,
+		Source:          Source,
+		References:      string(encoded),
+		CWEIDs:          strings.Join(cwes, ","),
+	}
+	if published, err := time.Parse(time.RFC3339, advisory.PublishedAt); err == nil {
+		finding.PublishedAt = &published
+	}
+	if updated, err := time.Parse(time.RFC3339, advisory.UpdatedAt); err == nil {
+		finding.LastModifiedAt = &updated
+	}
+	return finding
+}
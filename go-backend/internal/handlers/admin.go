@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/backfill"
+	"odin-backend/internal/models"
+	"odin-backend/internal/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartBackfill starts recomputing derived fields for historical data in the background
+func (h *Handler) StartBackfill(c *gin.Context) {
+	var request struct {
+		What      []string `json:"what"`
+		BatchSize int      `json:"batch_size"`
+		Restart   bool     `json:"restart"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if len(request.What) == 0 {
+		request.What = []string{backfill.TaskFingerprints, backfill.TaskRisk, backfill.TaskCounters, backfill.TaskTechniques, backfill.TaskMatches}
+	}
+	tasks, err := backfill.ParseTasks(strings.Join(request.What, ","))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid backfill tasks",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if backfill.IsRunning() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Backfill already running",
+			"message": backfill.ErrAlreadyRunning.Error(),
+		})
+		return
+	}
+
+	opts := backfill.Options{
+		Tasks:     tasks,
+		BatchSize: request.BatchSize,
+		Restart:   request.Restart,
+	}
+	go func() {
+		if err := backfill.Run(h.db, opts, nil); err != nil {
+			log.Printf("Backfill failed: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Backfill started",
+		"tasks":   tasks,
+	})
+}
+
+// GetBackfillStatus returns the progress of every backfill task
+func (h *Handler) GetBackfillStatus(c *gin.Context) {
+	states, err := backfill.States(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks": states,
+	})
+}
+
+// GetOrgSettings returns the effective upload settings of an organization
+func (h *Handler) GetOrgSettings(c *gin.Context) {
+	orgSettings, err := settings.Load(h.db, h.config, c.Param("org_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, orgSettingsResponse(orgSettings))
+}
+
+// UpdateOrgSettings changes the supported extensions, size limit and scan cap of an organization
+func (h *Handler) UpdateOrgSettings(c *gin.Context) {
+	var request struct {
+		SupportedExtensions []string `json:"supported_extensions"`
+		MaxFileSize         *int64   `json:"max_file_size"`
+		MaxConcurrentScans  *int     `json:"max_concurrent_scans"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	orgID := c.Param("org_id")
+	current, err := settings.Load(h.db, h.config, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	previous := orgSettingsResponse(current)
+
+	if request.SupportedExtensions != nil {
+		extensions, err := settings.NormalizeExtensions(request.SupportedExtensions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid supported_extensions",
+				"message": err.Error(),
+			})
+			return
+		}
+		current.SupportedExtensions = strings.Join(extensions, ",")
+	}
+	if request.MaxFileSize != nil {
+		if err := settings.ValidateMaxFileSize(*request.MaxFileSize); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid max_file_size",
+				"message": err.Error(),
+			})
+			return
+		}
+		current.MaxFileSize = *request.MaxFileSize
+	}
+	if request.MaxConcurrentScans != nil {
+		if err := settings.ValidateMaxConcurrentScans(*request.MaxConcurrentScans); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid max_concurrent_scans",
+				"message": err.Error(),
+			})
+			return
+		}
+		current.MaxConcurrentScans = *request.MaxConcurrentScans
+	}
+
+	actor := requestActor(c)
+	current.UpdatedBy = actor
+	if err := h.db.Save(&current).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save settings",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	updated := orgSettingsResponse(current)
+	if err := audit.Record(h.db, actor, "settings.update", "org_settings", orgID, map[string]interface{}{
+		"before": previous,
+		"after":  updated,
+	}); err != nil {
+		log.Printf("Failed to audit settings change for org %s: %v", orgID, err)
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// ListAuditLogs returns recent audit log entries, optionally filtered by action or resource
+func (h *Handler) ListAuditLogs(c *gin.Context) {
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	query := h.db.Order("created_at DESC").Limit(limit)
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if resource := c.Query("resource"); resource != "" {
+		query = query.Where("resource = ?", resource)
+	}
+	if resourceID := c.Query("resource_id"); resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+
+	var entries []models.AuditLog
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+func orgSettingsResponse(s models.OrgSettings) gin.H {
+	return gin.H{
+		"org_id":               s.OrgID,
+		"supported_extensions": s.Extensions(),
+		"max_file_size":        s.MaxFileSize,
+		"max_concurrent_scans": s.MaxConcurrentScans,
+		"updated_by":           s.UpdatedBy,
+		"updated_at":           s.UpdatedAt,
+	}
+}
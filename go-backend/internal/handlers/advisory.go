@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Upload advisories are given once a firmware type failed often enough
+const (
+	advisoryMinAttempts = 5
+	advisoryFailureRate = 0.5
+)
+
+// failureStat counts finished analyses of one firmware type and extractor
+type failureStat struct {
+	FirmwareType string  `json:"firmware_type"`
+	Extractor    string  `json:"extractor"`
+	Attempts     int     `json:"attempts"`
+	Failed       int     `json:"failed"`
+	FailureRate  float64 `json:"failure_rate"`
+}
+
+// failureStats aggregates finished analyses by firmware type and extractor,
+// optionally for a single firmware type
+func failureStats(db *gorm.DB, firmwareType string) ([]failureStat, error) {
+	var rows []struct {
+		FirmwareType string
+		Extractor    string
+		Attempts     int
+		Failed       int
+	}
+	query := db.Model(&models.Project{}).
+		Select("firmware_type, extractor, COUNT(*) AS attempts, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS failed", models.StatusFailed).
+		Where("status IN ?", []models.ProjectStatus{models.StatusCompleted, models.StatusFailed}).
+		Where("firmware_type <> ''")
+	if firmwareType != "" {
+		query = query.Where("firmware_type = ?", firmwareType)
+	}
+	if err := query.Group("firmware_type, extractor").Order("firmware_type, extractor").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make([]failureStat, 0, len(rows))
+	for _, row := range rows {
+		stat := failureStat{
+			FirmwareType: row.FirmwareType,
+			Extractor:    row.Extractor,
+			Attempts:     row.Attempts,
+			Failed:       row.Failed,
+		}
+		if row.Attempts > 0 {
+			stat.FailureRate = float64(row.Failed) / float64(row.Attempts)
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// uploadAdvisory warns about firmware types that usually fail with the
+// extractor they'd be analyzed with, or returns nil
+func (h *Handler) uploadAdvisory(firmwareType, extractor string) gin.H {
+	stats, err := failureStats(h.db, firmwareType)
+	if err != nil {
+		return nil
+	}
+
+	for _, stat := range stats {
+		if stat.Extractor != extractor || stat.Attempts < advisoryMinAttempts || stat.FailureRate < advisoryFailureRate {
+			continue
+		}
+		return gin.H{
+			"level":         "warning",
+			"firmware_type": stat.FirmwareType,
+			"extractor":     stat.Extractor,
+			"failed":        stat.Failed,
+			"attempts":      stat.Attempts,
+			"message": fmt.Sprintf("Images with this signature (%s) failed in %d/%d prior attempts with %s - consider another extractor or a quick-triage module selection",
+				stat.FirmwareType, stat.Failed, stat.Attempts, stat.Extractor),
+		}
+	}
+	return nil
+}
+
+// GetFailureStats returns analysis failure rates by firmware type and extractor
+func (h *Handler) GetFailureStats(c *gin.Context) {
+	stats, err := failureStats(h.db, c.Query("firmware_type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats":        stats,
+		"min_attempts": advisoryMinAttempts,
+		"warn_at_rate": advisoryFailureRate,
+	})
+}
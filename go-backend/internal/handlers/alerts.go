@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/models"
+	"odin-backend/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type alertRuleRequest struct {
+	Name             *string  `json:"name"`
+	Enabled          *bool    `json:"enabled"`
+	WebhookID        *uint    `json:"webhook_id"`
+	MinCVSS          *float64 `json:"min_cvss"`
+	KnownExploited   *bool    `json:"known_exploited"`
+	ExploitAvailable *bool    `json:"exploit_available"`
+	ProjectID        *string  `json:"project_id"`
+	Fleets           []string `json:"fleets"`
+}
+
+// ListAlertRules returns the CVE alert rules of the requesting organization
+func (h *Handler) ListAlertRules(c *gin.Context) {
+	var rules []models.CVEAlertRule
+	if err := h.db.Where("org_id = ?", requestOrgID(c)).Order("id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response := make([]gin.H, 0, len(rules))
+	for _, rule := range rules {
+		response = append(response, alertRuleResponse(rule))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alert_rules": response,
+		"total":       len(rules),
+	})
+}
+
+// CreateAlertRule adds a rule alerting a webhook subscription of the new
+// CVEs of monitored projects meeting its conditions
+func (h *Handler) CreateAlertRule(c *gin.Context) {
+	actor := requestActor(c)
+	rule := models.CVEAlertRule{
+		OrgID:     requestOrgID(c),
+		Enabled:   true,
+		CreatedBy: actor,
+		UpdatedBy: actor,
+	}
+	if !h.bindAlertRule(c, &rule) {
+		return
+	}
+
+	if err := h.db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create alert rule",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.auditAlertRule(c, "alert_rule.create", rule)
+
+	c.JSON(http.StatusCreated, alertRuleResponse(rule))
+}
+
+// UpdateAlertRule changes the conditions, scope or subscription of a rule
+func (h *Handler) UpdateAlertRule(c *gin.Context) {
+	rule, ok := h.findAlertRule(c)
+	if !ok {
+		return
+	}
+	if !h.bindAlertRule(c, &rule) {
+		return
+	}
+	rule.UpdatedBy = requestActor(c)
+
+	if err := h.db.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update alert rule",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.auditAlertRule(c, "alert_rule.update", rule)
+
+	c.JSON(http.StatusOK, alertRuleResponse(rule))
+}
+
+// DeleteAlertRule removes a rule
+func (h *Handler) DeleteAlertRule(c *gin.Context) {
+	rule, ok := h.findAlertRule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete alert rule",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.auditAlertRule(c, "alert_rule.delete", rule)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alert rule deleted successfully",
+	})
+}
+
+// findAlertRule loads the rule in the URL, scoped to the requesting organization
+func (h *Handler) findAlertRule(c *gin.Context) (models.CVEAlertRule, bool) {
+	var rule models.CVEAlertRule
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid alert rule ID",
+			"message": err.Error(),
+		})
+		return rule, false
+	}
+
+	if err := h.db.Where("org_id = ?", requestOrgID(c)).First(&rule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Alert rule not found",
+				"message": "No CVE alert rule with this ID",
+			})
+			return rule, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return rule, false
+	}
+
+	return rule, true
+}
+
+// bindAlertRule applies the fields present in the request body and
+// validates the result, the subscription and project being the organization's
+func (h *Handler) bindAlertRule(c *gin.Context, rule *models.CVEAlertRule) bool {
+	var request alertRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return false
+	}
+	invalid := func(message string) bool {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid alert rule",
+			"message": message,
+		})
+		return false
+	}
+
+	if request.Name != nil {
+		rule.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.Enabled != nil {
+		rule.Enabled = *request.Enabled
+	}
+	if request.WebhookID != nil {
+		rule.WebhookID = *request.WebhookID
+	}
+	if request.MinCVSS != nil {
+		rule.MinCVSS = *request.MinCVSS
+	}
+	if request.KnownExploited != nil {
+		rule.KnownExploited = *request.KnownExploited
+	}
+	if request.ExploitAvailable != nil {
+		rule.ExploitAvailable = *request.ExploitAvailable
+	}
+	if request.ProjectID != nil {
+		rule.ProjectID = strings.TrimSpace(*request.ProjectID)
+	}
+	if request.Fleets != nil {
+		rule.Fleets = joinList(request.Fleets)
+	}
+
+	if err := webhook.ValidateAlertRule(rule); err != nil {
+		return invalid(err.Error())
+	}
+
+	exists := func(model interface{}, query string, args ...interface{}) (bool, bool) {
+		var count int64
+		if err := h.db.Model(model).Where(query, args...).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Database error",
+				"message": err.Error(),
+			})
+			return false, false
+		}
+		return count > 0, true
+	}
+	found, ok := exists(&models.WebhookSubscription{}, "id = ? AND org_id = ?", rule.WebhookID, rule.OrgID)
+	if !ok {
+		return false
+	}
+	if !found {
+		return invalid("webhook_id must be a webhook subscription of the organization")
+	}
+	if rule.ProjectID != "" {
+		found, ok := exists(&models.Project{}, "id = ? AND org_id = ?", rule.ProjectID, rule.OrgID)
+		if !ok {
+			return false
+		}
+		if !found {
+			return invalid("project_id must be a project of the organization")
+		}
+	}
+	return true
+}
+
+// auditAlertRule records who changed a rule and how
+func (h *Handler) auditAlertRule(c *gin.Context, action string, rule models.CVEAlertRule) {
+	id := strconv.FormatUint(uint64(rule.ID), 10)
+	if err := audit.Record(h.db, requestActor(c), action, "cve_alert_rule", id, map[string]interface{}{
+		"name":              rule.Name,
+		"enabled":           rule.Enabled,
+		"webhook_id":        rule.WebhookID,
+		"min_cvss":          rule.MinCVSS,
+		"known_exploited":   rule.KnownExploited,
+		"exploit_available": rule.ExploitAvailable,
+		"project_id":        rule.ProjectID,
+		"fleets":            rule.Fleets,
+	}); err != nil {
+		log.Printf("Failed to audit change of CVE alert rule %s: %v", id, err)
+	}
+}
+
+func alertRuleResponse(rule models.CVEAlertRule) gin.H {
+	return gin.H{
+		"id":                rule.ID,
+		"org_id":            rule.OrgID,
+		"name":              rule.Name,
+		"enabled":           rule.Enabled,
+		"webhook_id":        rule.WebhookID,
+		"min_cvss":          rule.MinCVSS,
+		"known_exploited":   rule.KnownExploited,
+		"exploit_available": rule.ExploitAvailable,
+		"project_id":        rule.ProjectID,
+		"fleets":            splitList(rule.Fleets),
+		"alerts":            rule.Alerts,
+		"last_alerted_at":   rule.LastAlertedAt,
+		"created_by":        rule.CreatedBy,
+		"updated_by":        rule.UpdatedBy,
+		"created_at":        rule.CreatedAt,
+		"updated_at":        rule.UpdatedAt,
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/attack"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// techniqueSummary is the findings of an analysis mapped to a technique
+type techniqueSummary struct {
+	attack.Technique
+	Findings   int            `json:"findings"`
+	BySeverity map[string]int `json:"by_severity"`
+	FindingIDs []uint         `json:"finding_ids"`
+}
+
+// GetTechniques summarizes an analysis' findings by the ATT&CK for ICS and
+// EMB3D techniques they map to, for threat models. ?framework=attack-ics or
+// emb3d keeps one framework.
+func (h *Handler) GetTechniques(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var findings []models.Finding
+	if err := h.db.Select("id, severity, techniques").
+		Where("project_id = ? AND partial = ? AND techniques != '' AND merged_into_id IS NULL", project.ID, false).
+		Order("id").Find(&findings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	framework := c.Query("framework")
+	summaries := make(map[string]*techniqueSummary)
+	tactics := make(map[string]int)
+	mapped := 0
+	for _, finding := range findings {
+		counted := false
+		for _, id := range strings.Split(finding.Techniques, ",") {
+			technique, ok := attack.Techniques[id]
+			if !ok || (framework != "" && technique.Framework != framework) {
+				continue
+			}
+			summary, ok := summaries[id]
+			if !ok {
+				summary = &techniqueSummary{Technique: technique, BySeverity: make(map[string]int)}
+				summaries[id] = summary
+				for _, tactic := range technique.Tactics {
+					tactics[tactic]++
+				}
+			}
+			summary.Findings++
+			summary.BySeverity[string(finding.Severity)]++
+			summary.FindingIDs = append(summary.FindingIDs, finding.ID)
+			counted = true
+		}
+		if counted {
+			mapped++
+		}
+	}
+
+	techniques := make([]*techniqueSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		techniques = append(techniques, summary)
+	}
+	sort.Slice(techniques, func(i, j int) bool {
+		if techniques[i].Framework != techniques[j].Framework {
+			return techniques[i].Framework < techniques[j].Framework
+		}
+		return techniques[i].ID < techniques[j].ID
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":          project.ID,
+		"techniques":      techniques,
+		"total":           len(techniques),
+		"tactics":         tactics,
+		"mapped_findings": mapped,
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// binaryProtectionColumns maps the protections ?missing= accepts to columns
+var binaryProtectionColumns = map[string]string{
+	"relro":        "relro",
+	"full_relro":   "full_relro",
+	"stack_canary": "stack_canary",
+	"nx":           "nx",
+	"pie":          "pie",
+	"fortify":      "fortify",
+}
+
+// GetBinaryAnalysis returns the exploit mitigations of an analysis' binaries
+// with the share of binaries lacking each. ?missing=nx lists only the
+// binaries without NX; the summary always covers all of them.
+func (h *Handler) GetBinaryAnalysis(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var binaries []models.BinaryAnalysis
+	if err := h.db.Where("project_id = ?", project.ID).Order("file_path").Find(&binaries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	summary := emba.SummarizeBinaries(binaries)
+
+	if missing := c.Query("missing"); missing != "" {
+		column, ok := binaryProtectionColumns[missing]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid protection",
+				"message": "missing must be one of relro, full_relro, stack_canary, nx, pie or fortify",
+			})
+			return
+		}
+		binaries = nil
+		if err := h.db.Where("project_id = ? AND "+column+" = ?", project.ID, false).Order("file_path").Find(&binaries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Database error",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if binaries == nil {
+		binaries = []models.BinaryAnalysis{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":   jobID,
+		"summary":  summary,
+		"binaries": binaries,
+	})
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"odin-backend/internal/diff"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/firmwarefs"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxCompareFileSize caps the files diffed by CompareFiles
+const maxCompareFileSize = 1 << 20
+
+// Comparison outcomes of CompareFiles
+const (
+	compareIdentical = "identical"
+	compareModified  = "modified"
+	compareAdded     = "added"
+	compareRemoved   = "removed"
+	compareBinary    = "binary"
+	compareTooLarge  = "too_large"
+)
+
+// comparedFile is one side of a file comparison
+type comparedFile struct {
+	Present bool   `json:"present"`
+	Size    int64  `json:"size,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+
+	content []byte
+}
+
+// CompareFiles returns a unified diff of a file extracted from two analyses,
+// e.g. a configuration file shipped in two releases of the same device
+func (h *Handler) CompareFiles(c *gin.Context) {
+	baseID, targetID := c.Query("base"), c.Query("target")
+	if baseID == "" || targetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing parameters",
+			"message": "base and target analysis IDs are required",
+		})
+		return
+	}
+	filePath, err := firmwarefs.CleanPath(c.Query("path"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid path",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	base, ok := h.loadComparedProject(c, baseID)
+	if !ok {
+		return
+	}
+	target, ok := h.loadComparedProject(c, targetID)
+	if !ok {
+		return
+	}
+
+	var files [2]*comparedFile
+	for i, project := range []*models.Project{base, target} {
+		file, err := readComparedFile(project, filePath)
+		if err == firmwarefs.ErrNotExtracted {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Extracted firmware not available",
+				"message": fmt.Sprintf("Analysis job %s has no extracted firmware", project.ID),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to read file",
+				"message": err.Error(),
+			})
+			return
+		}
+		files[i] = file
+	}
+	baseFile, targetFile := files[0], files[1]
+
+	if !baseFile.Present && !targetFile.Present {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "File not found",
+			"message": fmt.Sprintf("%s was not found in either firmware", filePath),
+		})
+		return
+	}
+
+	response := gin.H{
+		"base":        base.ID,
+		"target":      target.ID,
+		"path":        filePath,
+		"same_device": base.DeviceModel == target.DeviceModel && base.Manufacturer == target.Manufacturer,
+		"base_file":   baseFile,
+		"target_file": targetFile,
+	}
+
+	status := compareModified
+	switch {
+	case baseFile.Present && targetFile.Present && baseFile.SHA256 == targetFile.SHA256:
+		status = compareIdentical
+	case baseFile.Size > maxCompareFileSize || targetFile.Size > maxCompareFileSize:
+		status = compareTooLarge
+	case !isText(baseFile.content) || !isText(targetFile.content):
+		status = compareBinary
+	default:
+		if !baseFile.Present {
+			status = compareAdded
+		} else if !targetFile.Present {
+			status = compareRemoved
+		}
+		response["diff"] = diff.Unified("base"+filePath, "target"+filePath,
+			string(baseFile.content), string(targetFile.content), diff.DefaultContext)
+	}
+	response["status"] = status
+
+	c.JSON(http.StatusOK, response)
+}
+
+// loadComparedProject loads a completed analysis for CompareFiles
+func (h *Handler) loadComparedProject(c *gin.Context, id string) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": fmt.Sprintf("Analysis job %s not found", id),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	if project.Status != models.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Analysis not completed",
+			"message": fmt.Sprintf("Analysis job %s has not completed", id),
+		})
+		return nil, false
+	}
+	return &project, true
+}
+
+// readComparedFile reads a file from a project's extracted firmware. Files
+// over the size cap are only hashed.
+func readComparedFile(project *models.Project, filePath string) (*comparedFile, error) {
+	root, err := firmwarefs.Root(project)
+	if err != nil {
+		return nil, err
+	}
+	found, err := firmwarefs.Find(root, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if found == "" {
+		return &comparedFile{}, nil
+	}
+
+	f, err := os.Open(found)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	file := &comparedFile{Present: true, Size: info.Size()}
+
+	hasher := sha256.New()
+	if info.Size() > maxCompareFileSize {
+		if _, err := io.Copy(hasher, f); err != nil {
+			return nil, err
+		}
+	} else {
+		if file.content, err = io.ReadAll(f); err != nil {
+			return nil, err
+		}
+		hasher.Write(file.content)
+	}
+	file.SHA256 = fmt.Sprintf("%x", hasher.Sum(nil))
+	return file, nil
+}
+
+// isText reports whether content looks like a text file
+func isText(content []byte) bool {
+	sample := content
+	if len(sample) > 8192 {
+		sample = sample[:8192]
+	}
+	return !bytes.ContainsRune(sample, 0) && utf8.Valid(content)
+}
+
+// CompareEnvironments explains why two analyses, typically of identical
+// firmware, produced different results: it lists the differences between
+// their recorded analysis environments alongside the differences in results
+func (h *Handler) CompareEnvironments(c *gin.Context) {
+	baseID, targetID := c.Query("base"), c.Query("target")
+	if baseID == "" || targetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing parameters",
+			"message": "base and target analysis IDs are required",
+		})
+		return
+	}
+
+	base, ok := h.loadComparedProject(c, baseID)
+	if !ok {
+		return
+	}
+	target, ok := h.loadComparedProject(c, targetID)
+	if !ok {
+		return
+	}
+
+	baseEnv, targetEnv := decodeEnvironment(base), decodeEnvironment(target)
+	var differences []emba.EnvironmentDifference
+	if baseEnv != nil && targetEnv != nil {
+		differences = baseEnv.Diff(targetEnv)
+	}
+
+	results, err := h.compareResults(base, target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base":                    base.ID,
+		"target":                  target.ID,
+		"same_firmware":           base.FileHash != "" && base.FileHash == target.FileHash,
+		"same_environment":        baseEnv != nil && targetEnv != nil && len(differences) == 0,
+		"base_environment":        baseEnv,
+		"target_environment":      targetEnv,
+		"environment_differences": differences,
+		"result_differences":      results,
+		"explanation":             explainDrift(baseEnv, targetEnv, differences, results),
+	})
+}
+
+// resultDifferences summarizes how two analyses' results differ
+type resultDifferences struct {
+	BaseRiskLevel      models.RiskLevel `json:"base_risk_level"`
+	TargetRiskLevel    models.RiskLevel `json:"target_risk_level"`
+	FindingsOnlyBase   []string         `json:"findings_only_in_base"`
+	FindingsOnlyTarget []string         `json:"findings_only_in_target"`
+	CVEsOnlyBase       []string         `json:"cves_only_in_base"`
+	CVEsOnlyTarget     []string         `json:"cves_only_in_target"`
+}
+
+func (r *resultDifferences) empty() bool {
+	return r.BaseRiskLevel == r.TargetRiskLevel && len(r.FindingsOnlyBase) == 0 &&
+		len(r.FindingsOnlyTarget) == 0 && len(r.CVEsOnlyBase) == 0 && len(r.CVEsOnlyTarget) == 0
+}
+
+// compareResults matches findings by fingerprint and CVEs by ID and component
+func (h *Handler) compareResults(base, target *models.Project) (*resultDifferences, error) {
+	titles := func(projectID string) (map[string]string, error) {
+		var findings []models.Finding
+		if err := h.db.Select("fingerprint", "title").Where("project_id = ?", projectID).Find(&findings).Error; err != nil {
+			return nil, err
+		}
+		result := make(map[string]string, len(findings))
+		for _, f := range findings {
+			result[f.Fingerprint] = f.Title
+		}
+		return result, nil
+	}
+	cves := func(projectID string) (map[string]string, error) {
+		var found []models.CVEFinding
+		if err := h.db.Select("cve_id", "software_name").Where("project_id = ?", projectID).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		result := make(map[string]string, len(found))
+		for _, cve := range found {
+			result[cve.CVEID+"|"+cve.SoftwareName] = fmt.Sprintf("%s (%s)", cve.CVEID, cve.SoftwareName)
+		}
+		return result, nil
+	}
+
+	baseFindings, err := titles(base.ID)
+	if err != nil {
+		return nil, err
+	}
+	targetFindings, err := titles(target.ID)
+	if err != nil {
+		return nil, err
+	}
+	baseCVEs, err := cves(base.ID)
+	if err != nil {
+		return nil, err
+	}
+	targetCVEs, err := cves(target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resultDifferences{
+		BaseRiskLevel:      base.RiskLevel,
+		TargetRiskLevel:    target.RiskLevel,
+		FindingsOnlyBase:   onlyIn(baseFindings, targetFindings),
+		FindingsOnlyTarget: onlyIn(targetFindings, baseFindings),
+		CVEsOnlyBase:       onlyIn(baseCVEs, targetCVEs),
+		CVEsOnlyTarget:     onlyIn(targetCVEs, baseCVEs),
+	}, nil
+}
+
+// onlyIn returns the sorted labels of keys in a but not in b
+func onlyIn(a, b map[string]string) []string {
+	labels := []string{}
+	for key, label := range a {
+		if _, ok := b[key]; !ok {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func decodeEnvironment(project *models.Project) *emba.Environment {
+	if project.Environment == "" {
+		return nil
+	}
+	var env emba.Environment
+	if err := json.Unmarshal([]byte(project.Environment), &env); err != nil {
+		return nil
+	}
+	return &env
+}
+
+// explainDrift turns environment differences into likely causes of result differences
+func explainDrift(base, target *emba.Environment, differences []emba.EnvironmentDifference, results *resultDifferences) []string {
+	explanation := []string{}
+	if base == nil || target == nil {
+		explanation = append(explanation, "The environment of at least one analysis wasn't recorded (analyzed before environment tracking), so drift can't be determined")
+		return explanation
+	}
+
+	for _, d := range differences {
+		switch {
+		case d.Field == "emba_version" || d.Field == "emba_commit":
+			explanation = append(explanation, fmt.Sprintf("EMBA changed from %s to %s; module output and CVE matching may differ", orNone(d.Base), orNone(d.Target)))
+		case d.Field == "scan_profile" || d.Field == "profile_hash":
+			explanation = append(explanation, fmt.Sprintf("The scan profile differs (%s: %s vs %s)", d.Field, orNone(d.Base), orNone(d.Target)))
+		case d.Field == "modules" || d.Field == "excluded_modules":
+			explanation = append(explanation, fmt.Sprintf("Different EMBA modules were run (%s: %s vs %s)", d.Field, orNone(d.Base), orNone(d.Target)))
+		case strings.HasPrefix(d.Field, "options."):
+			explanation = append(explanation, fmt.Sprintf("EMBA option %s changed from %s to %s", strings.TrimPrefix(d.Field, "options."), orNone(d.Base), orNone(d.Target)))
+		case strings.HasPrefix(d.Field, "feed_snapshots."):
+			explanation = append(explanation, fmt.Sprintf("The %s feed snapshot changed from %s to %s; CVE and exploit matches depend on it", strings.TrimPrefix(d.Field, "feed_snapshots."), orNone(d.Base), orNone(d.Target)))
+		case d.Field == "parser_version":
+			explanation = append(explanation, fmt.Sprintf("Odin's result parser changed from version %s to %s", orNone(d.Base), orNone(d.Target)))
+		case d.Field == "backend_version":
+			explanation = append(explanation, fmt.Sprintf("The Odin backend was upgraded from %s to %s", orNone(d.Base), orNone(d.Target)))
+		}
+	}
+
+	if len(differences) == 0 && !results.empty() {
+		explanation = append(explanation, "Both analyses ran in the same environment; the differences come from nondeterministic EMBA modules such as emulation or timeouts")
+	}
+	return explanation
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
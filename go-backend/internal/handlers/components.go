@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// componentCVEs rolls the CVE findings of a software component of an
+// analysis up, as remediation upgrades a component rather than fixing
+// one CVE at a time
+type componentCVEs struct {
+	Name            string                   `json:"name"`
+	Version         string                   `json:"version"`
+	ComponentID     *uint                    `json:"component_id,omitempty"`
+	CVEs            int                      `json:"cves"`
+	SeverityCounts  map[models.RiskLevel]int `json:"severity_counts"`
+	HighestScore    float64                  `json:"highest_score"`
+	HighestSeverity models.RiskLevel         `json:"highest_severity"`
+	ExploitableCVEs int                      `json:"exploitable_cves"`
+	KnownExploited  int                      `json:"known_exploited"`
+}
+
+// GetComponentCVEs returns the CVE findings of a software component of an
+// analysis, matched by name case-insensitively and narrowed to a version
+// with ?version, with their roll-up. Suppressed and rejected CVEs are left
+// out unless ?include_suppressed=true and ?include_rejected=true.
+func (h *Handler) GetComponentCVEs(c *gin.Context) {
+	jobID := c.Param("job_id")
+	name := strings.TrimSpace(c.Param("component"))
+	version := strings.TrimSpace(c.Query("version"))
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.db.Preload("CVE").Where("project_id = ? AND LOWER(software_name) = ?", project.ID, strings.ToLower(name))
+	if version != "" {
+		query = query.Where("software_version = ?", version)
+	}
+	var cves []models.CVEFinding
+	if err := query.Order("severity_score DESC, cve_id").Find(&cves).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// A component of the SBOM without CVEs isn't an error
+	if len(cves) == 0 {
+		components := h.db.Model(&models.SBOMComponent{}).Where("project_id = ? AND LOWER(name) = ?", project.ID, strings.ToLower(name))
+		if version != "" {
+			components = components.Where("version = ?", version)
+		}
+		var count int64
+		if err := components.Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Database error",
+				"message": err.Error(),
+			})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Component not found",
+				"message": "No software component or CVE finding with this name in the analysis",
+			})
+			return
+		}
+	}
+	cves = filterHidden(c, cves)
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":     jobID,
+		"component":  name,
+		"version":    version,
+		"versions":   groupCVEsByComponent(cves),
+		"cves":       cves,
+		"total_cves": len(cves),
+	})
+}
+
+// groupCVEsByComponent rolls CVE findings up by software name and version,
+// the components with the most severe CVEs first
+func groupCVEsByComponent(cves []models.CVEFinding) []componentCVEs {
+	groups := []componentCVEs{}
+	index := make(map[[2]string]int)
+	for _, cve := range cves {
+		key := [2]string{strings.ToLower(cve.SoftwareName), cve.SoftwareVersion}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, componentCVEs{
+				Name:           cve.SoftwareName,
+				Version:        cve.SoftwareVersion,
+				SeverityCounts: make(map[models.RiskLevel]int),
+			})
+		}
+		group := &groups[i]
+		if group.ComponentID == nil {
+			group.ComponentID = cve.ComponentID
+		}
+		group.CVEs++
+		group.SeverityCounts[cve.SeverityLevel]++
+		if cve.SeverityScore > group.HighestScore {
+			group.HighestScore = cve.SeverityScore
+		}
+		if cve.SeverityLevel.Rank() > group.HighestSeverity.Rank() {
+			group.HighestSeverity = cve.SeverityLevel
+		}
+		if cve.Exploitable() {
+			group.ExploitableCVEs++
+		}
+		if cve.KnownExploited {
+			group.KnownExploited++
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := &groups[i], &groups[j]
+		if a.HighestSeverity != b.HighestSeverity {
+			return a.HighestSeverity.Rank() > b.HighestSeverity.Rank()
+		}
+		if a.CVEs != b.CVEs {
+			return a.CVEs > b.CVEs
+		}
+		if a.HighestScore != b.HighestScore {
+			return a.HighestScore > b.HighestScore
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return groups
+}
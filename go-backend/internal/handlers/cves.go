@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/cwe"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// cveSummary is a CVE found in the organization's analyses: its shared
+// record and what its findings the search matched add up to
+type cveSummary struct {
+	models.CVE
+	SeverityScore    float64          `json:"severity_score"` // highest of its findings
+	SeverityLevel    models.RiskLevel `json:"severity_level"`
+	KnownExploited   bool             `json:"known_exploited"`
+	ExploitAvailable bool             `json:"exploit_available"`
+	Software         []string         `json:"software"`
+	ProjectCount     int              `json:"project_count"`
+	FindingCount     int              `json:"finding_count"`
+	projects         map[string]bool
+	software         map[string]bool
+}
+
+// cveSortColumns are the orders ?sort accepts, most severe, most likely
+// exploited or most widespread first
+var cveSortColumns = map[string]string{
+	"severity": "severity_score DESC",
+	"epss":     "MAX(COALESCE(cves.epss_score, 0)) DESC, severity_score DESC",
+	"projects": "COUNT(DISTINCT cve_findings.project_id) DESC, severity_score DESC",
+}
+
+// ListCVEs searches the CVEs found in the organization's analyses, one
+// entry per CVE rather than per project. Filters, matched by at least one
+// of the CVE's findings: severity (comma separated), software (part of the
+// name, any case), kev=true, exploitable=true, min_cvss, cwe, fleet and
+// project_id. The findings of accepted risks are left out unless
+// ?include_suppressed=true. ?sort=severity (default), epss or projects;
+// paged with limit and offset.
+func (h *Handler) ListCVEs(c *gin.Context) {
+	limit, offset := pageParams(c)
+	order, ok := cveSortColumns[c.DefaultQuery("sort", "severity")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid sort",
+			"message": "sort must be severity, epss or projects",
+		})
+		return
+	}
+	query, err := h.searchCVEFindings(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid filter",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var total int64
+	if err := query.Distinct("cve_findings.cve_id").Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var rows []struct {
+		CVEID         string
+		SeverityScore float64
+	}
+	if err := query.Select("cve_findings.cve_id, MAX(cve_findings.severity_score) AS severity_score").
+		Group("cve_findings.cve_id").Order(order + ", cve_findings.cve_id").
+		Limit(limit).Offset(offset).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.CVEID)
+	}
+
+	var findings []models.CVEFinding
+	var records []models.CVE
+	if len(ids) > 0 {
+		if err := query.Select("cve_findings.*").Where("cve_findings.cve_id IN ?", ids).Find(&findings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Database error",
+				"message": err.Error(),
+			})
+			return
+		}
+		if err := h.db.Where("id IN ?", ids).Find(&records).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Database error",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	summaries := make(map[string]*cveSummary, len(ids))
+	for _, id := range ids {
+		summaries[id] = &cveSummary{CVE: models.CVE{ID: id}, Software: []string{},
+			projects: make(map[string]bool), software: make(map[string]bool)}
+	}
+	for _, record := range records {
+		summaries[record.ID].CVE = record
+	}
+	for _, finding := range findings {
+		summary := summaries[finding.CVEID]
+		if finding.SeverityScore > summary.SeverityScore {
+			summary.SeverityScore = finding.SeverityScore
+		}
+		if finding.SeverityLevel.Rank() > summary.SeverityLevel.Rank() {
+			summary.SeverityLevel = finding.SeverityLevel
+		}
+		summary.KnownExploited = summary.KnownExploited || finding.KnownExploited
+		summary.ExploitAvailable = summary.ExploitAvailable || finding.ExploitAvailable
+		if name := strings.ToLower(finding.SoftwareName); !summary.software[name] {
+			summary.software[name] = true
+			summary.Software = append(summary.Software, finding.SoftwareName)
+		}
+		summary.projects[finding.ProjectID] = true
+		summary.FindingCount++
+	}
+
+	list := make([]*cveSummary, 0, len(ids))
+	for _, id := range ids {
+		summary := summaries[id]
+		summary.ProjectCount = len(summary.projects)
+		sort.Strings(summary.Software)
+		list = append(list, summary)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cves":  list,
+		"count": len(list),
+		"total": total,
+	})
+}
+
+// searchCVEFindings returns the CVE findings of the organization's analyses
+// the request's filters match, joined with their projects and CVE records
+func (h *Handler) searchCVEFindings(c *gin.Context) (*gorm.DB, error) {
+	query := h.db.Model(&models.CVEFinding{}).
+		Joins("JOIN projects ON projects.id = cve_findings.project_id").
+		Joins("LEFT JOIN cves ON cves.id = cve_findings.cve_id").
+		Where("projects.org_id = ? AND cve_findings.partial = ?", requestOrgID(c), false)
+	for param, column := range map[string]string{
+		"project_id": "cve_findings.project_id",
+		"fleet":      "projects.fleet",
+	} {
+		if value := c.Query(param); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if severity := c.Query("severity"); severity != "" {
+		var levels []models.RiskLevel
+		for _, level := range strings.Split(severity, ",") {
+			level := models.RiskLevel(strings.ToLower(strings.TrimSpace(level)))
+			if level.Rank() == 0 {
+				return nil, fmt.Errorf("unknown severity %q", level)
+			}
+			levels = append(levels, level)
+		}
+		query = query.Where("cve_findings.severity_level IN ?", levels)
+	}
+	if software := strings.TrimSpace(c.Query("software")); software != "" {
+		query = query.Where("LOWER(cve_findings.software_name) LIKE ?", "%"+strings.ToLower(software)+"%")
+	}
+	if c.Query("kev") == "true" {
+		query = query.Where("cve_findings.known_exploited = ?", true)
+	}
+	if c.Query("exploitable") == "true" {
+		query = query.Where("cve_findings.exploit_available = ? OR cve_findings.known_exploited = ?", true, true)
+	}
+	if value := c.Query("min_cvss"); value != "" {
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil || score < 0 || score > 10 {
+			return nil, fmt.Errorf("min_cvss must be a score from 0 to 10")
+		}
+		query = query.Where("cve_findings.severity_score >= ?", score)
+	}
+	if weakness := c.Query("cwe"); weakness != "" {
+		// cwe_ids is comma separated
+		query = query.Where("',' || cves.cwe_ids || ',' LIKE ?", "%,"+cwe.Normalize(weakness)+",%")
+	}
+	if value := c.Query("match_status"); value != "" {
+		status, err := parseMatchStatus(value)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("cve_findings.match_status = ?", status)
+	}
+	query = excludeHidden(c, query)
+	return query.Session(&gorm.Session{}), nil
+}
+
+// affectedProject is an analysis of the organization a CVE was found in,
+// with the software components it affects there
+type affectedProject struct {
+	ID           string               `json:"id"`
+	Name         string               `json:"name"`
+	Fleet        string               `json:"fleet"`
+	DeviceModel  string               `json:"device_model"`
+	Manufacturer string               `json:"manufacturer"`
+	Status       models.ProjectStatus `json:"status"`
+	RiskLevel    models.RiskLevel     `json:"risk_level"`
+	Frozen       bool                 `json:"frozen"`
+	CompletedAt  *time.Time           `json:"completed_at,omitempty"`
+	Components   []affectedComponent  `json:"components"`
+}
+
+// affectedComponent is a software component a CVE was found in: the CVE
+// finding, and the SBOM component it is linked to, if any
+type affectedComponent struct {
+	FindingID         uint               `json:"finding_id"`
+	SoftwareName      string             `json:"software_name"`
+	SoftwareVersion   string             `json:"software_version"`
+	BinaryPath        string             `json:"binary_path,omitempty"`
+	SeverityScore     float64            `json:"severity_score"`
+	SeverityLevel     models.RiskLevel   `json:"severity_level"`
+	AdjustedScore     float64            `json:"adjusted_score,omitempty"`
+	AdjustedSeverity  models.RiskLevel   `json:"adjusted_severity,omitempty"`
+	ExploitAvailable  bool               `json:"exploit_available"`
+	KnownExploited    bool               `json:"known_exploited"`
+	MonitoredAt       *time.Time         `json:"monitored_at,omitempty"`
+	SuppressionID     *uint              `json:"suppression_id,omitempty"`
+	MatchStatus       models.MatchStatus `json:"match_status,omitempty"`
+	MatchReason       string             `json:"match_reason,omitempty"`
+	ComponentID       *uint              `json:"component_id,omitempty"`
+	ComponentMatch    string             `json:"component_match,omitempty"`
+	MatchCriteria     string             `json:"match_criteria,omitempty"`
+	MatchVersionRange string             `json:"match_version_range,omitempty"`
+	PURL              string             `json:"purl,omitempty"`
+	CPE               string             `json:"cpe,omitempty"`
+}
+
+// GetCVE returns the shared record of a CVE and every analysis of the
+// organization it was found in, with the components it affects there, to
+// answer which firmware contains a newly published CVE. ?fleet narrows the
+// analyses; the findings of accepted risks are left out unless
+// ?include_suppressed=true.
+func (h *Handler) GetCVE(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("cve_id"))
+	if strings.HasPrefix(strings.ToUpper(id), "CVE-") {
+		id = strings.ToUpper(id)
+	}
+
+	var record models.CVE
+	if err := h.db.Limit(1).Find(&record, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.db.Model(&models.CVEFinding{}).
+		Joins("JOIN projects ON projects.id = cve_findings.project_id").
+		Where("cve_findings.cve_id = ? AND cve_findings.partial = ? AND projects.org_id = ?", id, false, requestOrgID(c))
+	if fleet := c.Query("fleet"); fleet != "" {
+		query = query.Where("projects.fleet = ?", fleet)
+	}
+	query = excludeHidden(c, query)
+	var findings []models.CVEFinding
+	if err := query.Select("cve_findings.*").Order("cve_findings.project_id, cve_findings.id").Find(&findings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if record.ID == "" && len(findings) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "CVE not found",
+			"message": "No analysis found the CVE",
+		})
+		return
+	}
+	if record.ID == "" {
+		record.ID = id
+	}
+
+	projectIDs := make([]string, 0)
+	var componentIDs []uint
+	seen := make(map[string]bool)
+	for _, finding := range findings {
+		if !seen[finding.ProjectID] {
+			seen[finding.ProjectID] = true
+			projectIDs = append(projectIDs, finding.ProjectID)
+		}
+		if finding.ComponentID != nil {
+			componentIDs = append(componentIDs, *finding.ComponentID)
+		}
+	}
+
+	var projects []models.Project
+	if err := h.db.Where("id IN ?", projectIDs).Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	components := make(map[uint]models.SBOMComponent)
+	if len(componentIDs) > 0 {
+		var list []models.SBOMComponent
+		if err := h.db.Where("id IN ?", componentIDs).Find(&list).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Database error",
+				"message": err.Error(),
+			})
+			return
+		}
+		for _, component := range list {
+			components[component.ID] = component
+		}
+	}
+
+	affected := make(map[string]*affectedProject, len(projects))
+	for _, project := range projects {
+		affected[project.ID] = &affectedProject{
+			ID:           project.ID,
+			Name:         project.Name,
+			Fleet:        project.Fleet,
+			DeviceModel:  project.DeviceModel,
+			Manufacturer: project.Manufacturer,
+			Status:       project.Status,
+			RiskLevel:    project.RiskLevel,
+			Frozen:       project.FrozenAt != nil,
+			CompletedAt:  project.CompletedAt,
+			Components:   []affectedComponent{},
+		}
+	}
+
+	knownExploited, exploitAvailable := false, false
+	for _, finding := range findings {
+		project, ok := affected[finding.ProjectID]
+		if !ok {
+			continue
+		}
+		component := affectedComponent{
+			FindingID:         finding.ID,
+			SoftwareName:      finding.SoftwareName,
+			SoftwareVersion:   finding.SoftwareVersion,
+			BinaryPath:        finding.BinaryPath,
+			SeverityScore:     finding.SeverityScore,
+			SeverityLevel:     finding.SeverityLevel,
+			AdjustedScore:     finding.AdjustedScore,
+			AdjustedSeverity:  finding.AdjustedSeverity,
+			ExploitAvailable:  finding.ExploitAvailable,
+			KnownExploited:    finding.KnownExploited,
+			MonitoredAt:       finding.MonitoredAt,
+			SuppressionID:     finding.SuppressionID,
+			MatchStatus:       finding.MatchStatus,
+			MatchReason:       finding.MatchReason,
+			ComponentID:       finding.ComponentID,
+			ComponentMatch:    finding.ComponentMatch,
+			MatchCriteria:     finding.MatchCriteria,
+			MatchVersionRange: finding.MatchVersionRange,
+		}
+		if finding.ComponentID != nil {
+			sbom := components[*finding.ComponentID]
+			component.PURL = sbom.PURL
+			component.CPE = sbom.CPE
+		}
+		project.Components = append(project.Components, component)
+		knownExploited = knownExploited || finding.KnownExploited
+		exploitAvailable = exploitAvailable || finding.ExploitAvailable
+	}
+
+	list := make([]*affectedProject, 0, len(affected))
+	for _, project := range affected {
+		list = append(list, project)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"cve":               record,
+		"known_exploited":   knownExploited,
+		"exploit_available": exploitAvailable,
+		"projects":          list,
+		"project_count":     len(list),
+		"finding_count":     len(findings),
+	})
+}
+
+// excludeHidden leaves out the CVE findings of accepted risks and those
+// analysts rejected as false positives, unless the request asks for them
+// with ?include_suppressed=true and ?include_rejected=true or
+// ?match_status=rejected
+func excludeHidden(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if c.Query("include_suppressed") != "true" {
+		query = query.Where("cve_findings.suppression_id IS NULL")
+	}
+	if c.Query("include_rejected") != "true" && c.Query("match_status") != string(models.MatchRejected) {
+		query = query.Where("cve_findings.match_status <> ?", models.MatchRejected)
+	}
+	return query
+}
+
+// parseMatchStatus reads a ?match_status filter, unverified standing for
+// the findings not checked yet
+func parseMatchStatus(value string) (models.MatchStatus, error) {
+	switch status := models.MatchStatus(strings.ToLower(value)); status {
+	case models.MatchMatched, models.MatchPotential, models.MatchConfirmed, models.MatchRejected:
+		return status, nil
+	case "unverified":
+		return models.MatchUnverified, nil
+	}
+	return "", fmt.Errorf("match_status must be matched, potential, confirmed, rejected or unverified")
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"odin-backend/internal/cwe"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// cweGroup is the findings of the organization citing a weakness
+type cweGroup struct {
+	cwe.Weakness
+	Findings   int            `json:"findings"`
+	Projects   int            `json:"projects"`
+	BySeverity map[string]int `json:"by_severity"`
+	projects   map[string]bool
+}
+
+// ListCWEs groups the findings of the organization's analyses by the
+// weakness they cite, with the weakness' name and parents from the CWE
+// dictionary. With ?rollup=true a finding also counts towards every
+// ancestor of its weakness, for reports by weakness class up to the
+// pillars. ?project_id, ?fleet and ?severity narrow the findings.
+func (h *Handler) ListCWEs(c *gin.Context) {
+	var rows []struct {
+		CWE       string
+		ProjectID string
+		Severity  string
+		Count     int
+	}
+	if err := h.cweFindings(c).Select("findings.cwe, findings.project_id, findings.severity, COUNT(*) AS count").
+		Group("findings.cwe, findings.project_id, findings.severity").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rollup := c.Query("rollup") == "true"
+	groups := make(map[string]*cweGroup)
+	for _, row := range rows {
+		ids := []string{row.CWE}
+		if rollup {
+			ids = append(ids, h.cwe.Ancestors(row.CWE)...)
+		}
+		for _, id := range ids {
+			group, ok := groups[id]
+			if !ok {
+				group = &cweGroup{Weakness: h.weakness(id), BySeverity: make(map[string]int), projects: make(map[string]bool)}
+				groups[id] = group
+			}
+			group.Findings += row.Count
+			group.BySeverity[row.Severity] += row.Count
+			group.projects[row.ProjectID] = true
+		}
+	}
+
+	list := make([]*cweGroup, 0, len(groups))
+	for _, group := range groups {
+		group.Projects = len(group.projects)
+		list = append(list, group)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Findings != list[j].Findings {
+			return list[i].Findings > list[j].Findings
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"weaknesses": list,
+		"total":      len(list),
+		"rollup":     rollup,
+	})
+}
+
+// GetCWE returns a weakness from the CWE dictionary with its ancestors and
+// children, and the findings of the organization citing it or one of its
+// descendants. ?project_id, ?fleet and ?severity narrow the findings.
+func (h *Handler) GetCWE(c *gin.Context) {
+	id := cwe.Normalize(c.Param("cwe_id"))
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid CWE ID",
+			"message": "Use a CWE ID such as CWE-787 or 787",
+		})
+		return
+	}
+
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 1000 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	ids := append([]string{id}, h.cwe.Descendants(id)...)
+	query := h.cweFindings(c).Where("findings.cwe IN ?", ids)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if _, known := h.cwe.Lookup(id); !known && total == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "CWE not found",
+			"message": "The weakness is neither in the CWE dictionary nor cited by a finding",
+		})
+		return
+	}
+
+	var findings []models.Finding
+	if err := query.Select("findings.*").Order("findings.created_at DESC, findings.id").
+		Limit(limit).Offset(offset).Find(&findings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ancestors := []cwe.Weakness{}
+	for _, ancestor := range h.cwe.Ancestors(id) {
+		ancestors = append(ancestors, h.weakness(ancestor))
+	}
+	children := []cwe.Weakness{}
+	for _, child := range h.cwe.Children(id) {
+		children = append(children, h.weakness(child))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"weakness":  h.weakness(id),
+		"ancestors": ancestors,
+		"children":  children,
+		"findings":  findings,
+		"count":     len(findings),
+		"total":     total,
+	})
+}
+
+// cweFindings selects the completed findings of the organization's analyses
+// that cite a weakness, but for merged duplicates, narrowed by the request's
+// filters
+func (h *Handler) cweFindings(c *gin.Context) *gorm.DB {
+	query := h.db.Model(&models.Finding{}).
+		Joins("JOIN projects ON projects.id = findings.project_id").
+		Where("projects.org_id = ? AND findings.cwe != '' AND findings.partial = ? AND findings.merged_into_id IS NULL", requestOrgID(c), false)
+	for param, column := range map[string]string{
+		"project_id": "findings.project_id",
+		"severity":   "findings.severity",
+		"fleet":      "projects.fleet",
+	} {
+		if value := c.Query(param); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	return query
+}
+
+// weakness returns a weakness from the CWE dictionary, or one with only
+// its ID when the dictionary doesn't have it
+func (h *Handler) weakness(id string) cwe.Weakness {
+	if weakness, ok := h.cwe.Lookup(id); ok {
+		return weakness
+	}
+	return cwe.Weakness{ID: id, Parents: []string{}}
+}
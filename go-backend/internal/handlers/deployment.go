@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/cvss"
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type deploymentRequest struct {
+	Reachability         *string `json:"reachability"`
+	SecurityRequirements *string `json:"security_requirements"`
+}
+
+// UpdateDeployment sets where an analysis' device is deployed, how it can
+// be reached and its security requirements, and adjusts the scores of its
+// CVEs to it: a CVE exploitable over the network scores lower on a device
+// only reachable locally.
+func (h *Handler) UpdateDeployment(c *gin.Context) {
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", c.Param("job_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if rejectFrozen(c, &project) {
+		return
+	}
+
+	var request deploymentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+	if request.Reachability != nil {
+		project.Reachability = strings.ToLower(strings.TrimSpace(*request.Reachability))
+	}
+	if request.SecurityRequirements != nil {
+		project.SecurityRequirements = strings.ToUpper(strings.TrimSpace(*request.SecurityRequirements))
+	}
+	if err := validateDeployment(project.Reachability, project.SecurityRequirements); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid deployment context",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Project{}).Where("id = ?", project.ID).UpdateColumns(map[string]interface{}{
+			"reachability":          project.Reachability,
+			"security_requirements": project.SecurityRequirements,
+		}).Error; err != nil {
+			return err
+		}
+		return risk.Rescore(tx, project.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update deployment context",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := audit.Record(h.db, requestActor(c), "project.deployment", "project", project.ID, map[string]interface{}{
+		"reachability":          project.Reachability,
+		"security_requirements": project.SecurityRequirements,
+	}); err != nil {
+		log.Printf("Failed to audit deployment context of project %s: %v", project.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":                project.ID,
+		"reachability":          project.Reachability,
+		"security_requirements": project.SecurityRequirements,
+	})
+}
+
+// validateDeployment checks a deployment context: a reachability of
+// network, adjacent, local or physical and CVSS security requirements such
+// as CR:H/IR:M/AR:L, either of them empty when unknown
+func validateDeployment(reachability, requirements string) error {
+	if err := cvss.ValidateReachability(reachability); err != nil {
+		return err
+	}
+	return cvss.ValidateRequirements(requirements)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateDiffScan queues a differential EMBA scan (diff mode) of the
+// firmware of two analyses of the same device: base_job_id's image, usually
+// the older one, against target_job_id's
+func (h *Handler) CreateDiffScan(c *gin.Context) {
+	var request struct {
+		BaseJobID   string `json:"base_job_id" binding:"required"`
+		TargetJobID string `json:"target_job_id" binding:"required"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+	if request.BaseJobID == request.TargetJobID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid diff scan",
+			"message": "base_job_id and target_job_id must name two analyses",
+		})
+		return
+	}
+
+	base, ok := h.loadDiffedProject(c, request.BaseJobID)
+	if !ok {
+		return
+	}
+	target, ok := h.loadDiffedProject(c, request.TargetJobID)
+	if !ok {
+		return
+	}
+
+	if base.DeviceModel != "" && target.DeviceModel != "" && base.DeviceModel != target.DeviceModel {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid diff scan",
+			"message": fmt.Sprintf("The firmware belongs to different devices (%s, %s)", base.DeviceModel, target.DeviceModel),
+		})
+		return
+	}
+
+	name := request.Name
+	if name == "" {
+		name = fmt.Sprintf("Diff %s → %s", base.Name, target.Name)
+	}
+	project := &models.Project{
+		ID:                 uuid.New().String(),
+		OrgID:              target.OrgID,
+		Name:               name,
+		Description:        request.Description,
+		Status:             models.StatusPending,
+		Filename:           target.Filename,
+		FilePath:           target.FilePath,
+		FileSize:           target.FileSize,
+		DeviceName:         target.DeviceName,
+		DeviceModel:        target.DeviceModel,
+		DeviceVersion:      target.DeviceVersion,
+		Manufacturer:       target.Manufacturer,
+		Fleet:              target.Fleet,
+		FirmwareType:       target.FirmwareType,
+		FirmwareEndianness: target.FirmwareEndianness,
+		FirmwareArch:       target.FirmwareArch,
+		Architecture:       target.Architecture,
+		OSFamily:           target.OSFamily,
+		FormatDetails:      target.FormatDetails,
+		Extractor:          "emba",
+		ScanProfile:        h.config.EMBAScanProfile,
+		DiffBaseID:         base.ID,
+		DiffTargetID:       target.ID,
+		FirmwareInfo:       "{}",
+		ExtractionResults:  "{}",
+	}
+	if err := h.db.Create(project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create project",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := audit.Record(h.db, requestActor(c), "analysis.diff", "project", project.ID, map[string]interface{}{
+		"base_job_id":   base.ID,
+		"target_job_id": target.ID,
+	}); err != nil {
+		log.Printf("Failed to audit diff scan %s: %v", project.ID, err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":        project.ID,
+		"status":        project.Status,
+		"base_job_id":   base.ID,
+		"target_job_id": target.ID,
+		"message":       "Diff scan queued",
+	})
+}
+
+// loadDiffedProject loads an analysis for CreateDiffScan. Diff mode needs
+// its uploaded image; imported logs and diff scans have none of their own.
+func (h *Handler) loadDiffedProject(c *gin.Context, id string) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": fmt.Sprintf("Analysis job %s not found", id),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	if _, err := os.Stat(project.FilePath); project.IngestLogDir != "" || project.DiffBaseID != "" || err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid diff scan",
+			"message": fmt.Sprintf("Analysis job %s has no uploaded firmware to compare", id),
+		})
+		return nil, false
+	}
+	return &project, true
+}
+
+// GetDiffScan returns the files a diff scan found added, removed or changed
+// in the target firmware compared with the base
+func (h *Handler) GetDiffScan(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if project.DiffBaseID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Not a diff scan",
+			"message": "Analysis " + jobID + " is not a diff scan",
+		})
+		return
+	}
+
+	var findings []models.Finding
+	if err := h.db.Where("project_id = ? AND type = ?", project.ID, emba.FindingFirmwareDiff).
+		Order("file_path").Find(&findings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	changes := map[string][]models.Finding{
+		emba.DiffAdded:   {},
+		emba.DiffRemoved: {},
+		emba.DiffChanged: {},
+	}
+	for _, finding := range findings {
+		var metadata struct {
+			Change string `json:"change"`
+		}
+		json.Unmarshal([]byte(finding.FindingMetadata), &metadata)
+		if _, ok := changes[metadata.Change]; ok {
+			changes[metadata.Change] = append(changes[metadata.Change], finding)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":        jobID,
+		"status":        project.Status,
+		"base_job_id":   project.DiffBaseID,
+		"target_job_id": project.DiffTargetID,
+		"added":         changes[emba.DiffAdded],
+		"removed":       changes[emba.DiffRemoved],
+		"changed":       changes[emba.DiffChanged],
+		"summary": gin.H{
+			"added":   len(changes[emba.DiffAdded]),
+			"removed": len(changes[emba.DiffRemoved]),
+			"changed": len(changes[emba.DiffChanged]),
+		},
+	})
+}
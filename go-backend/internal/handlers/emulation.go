@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetEmulation returns the outcome of an analysis' system emulation: the
+// architecture, kernel and init process the firmware was booted with, the
+// addresses it took and the services that came up
+func (h *Handler) GetEmulation(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	emulations := []models.EmulationResult{}
+	if err := h.db.Where("project_id = ?", project.ID).Order("id").Find(&emulations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Emulation only runs with live testing; say so rather than return nothing
+	if len(emulations) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"job_id":   jobID,
+			"emulated": false,
+			"message":  "No system emulation results; emulation runs with EMBA_ENABLE_LIVE_TESTING",
+		})
+		return
+	}
+
+	success := false
+	for _, emulation := range emulations {
+		success = success || emulation.Success
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":     jobID,
+		"emulated":   true,
+		"success":    success,
+		"emulations": emulations,
+	})
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// manifestEntry is a manifest file with the project it was extracted from
+type manifestEntry struct {
+	models.FirmwareFile
+	ProjectName string `json:"project_name"`
+}
+
+// GetProjectFiles returns the manifest of the files extracted from an
+// analysis' firmware, filtered like SearchFiles
+func (h *Handler) GetProjectFiles(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := filterFiles(c, h.db.Model(&models.FirmwareFile{}).Where("project_id = ?", project.ID)).Session(&gorm.Session{})
+	limit, offset := pageParams(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	files := []models.FirmwareFile{}
+	if err := query.Order("path").Limit(limit).Offset(offset).Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id": jobID,
+		"files":  files,
+		"count":  len(files),
+		"total":  total,
+	})
+}
+
+// SearchFiles finds files across the manifests of all analyses, e.g.
+// ?name=libssl.so.1.0.0 for the projects shipping that library. Filters:
+// name (* matches anything), path (prefix), sha256, mime_type and file_type;
+// at least one is required.
+func (h *Handler) SearchFiles(c *gin.Context) {
+	if c.Query("name") == "" && c.Query("path") == "" && c.Query("sha256") == "" &&
+		c.Query("mime_type") == "" && c.Query("file_type") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing filter",
+			"message": "Search by name, path, sha256, mime_type or file_type",
+		})
+		return
+	}
+
+	query := filterFiles(c, h.db.Model(&models.FirmwareFile{})).Session(&gorm.Session{})
+	limit, offset := pageParams(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	var projectIDs []string
+	if err := query.Distinct().Pluck("project_id", &projectIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	entries := []manifestEntry{}
+	if err := query.Select("firmware_files.*, projects.name AS project_name").
+		Joins("JOIN projects ON projects.id = firmware_files.project_id").
+		Order("firmware_files.project_id, firmware_files.path").
+		Limit(limit).Offset(offset).Scan(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files":       entries,
+		"count":       len(entries),
+		"total":       total,
+		"project_ids": projectIDs,
+		"projects":    len(projectIDs),
+	})
+}
+
+// filterFiles applies the file filters of the request to a manifest query
+func filterFiles(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if name := c.Query("name"); name != "" {
+		if strings.Contains(name, "*") {
+			query = query.Where("firmware_files.name LIKE ?", strings.ReplaceAll(name, "*", "%"))
+		} else {
+			query = query.Where("firmware_files.name = ?", name)
+		}
+	}
+	if path := c.Query("path"); path != "" {
+		query = query.Where("firmware_files.path LIKE ?", path+"%")
+	}
+	for param, column := range map[string]string{
+		"sha256":    "firmware_files.sha256",
+		"mime_type": "firmware_files.mime_type",
+		"file_type": "firmware_files.file_type",
+	} {
+		if value := c.Query(param); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	return query
+}
+
+// pageParams reads ?limit= (default 100, up to 1000) and ?offset=
+func pageParams(c *gin.Context) (int, int) {
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 1000 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+	return limit, offset
+}
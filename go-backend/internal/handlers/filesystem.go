@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"odin-backend/internal/firmwarefs"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Limits of GetFirmwareFile: text and base64 previews are truncated,
+// hex dumps are paged and raw downloads of larger files refused
+const (
+	maxFilePreview    = 1 << 20
+	defaultHexLength  = 4096
+	maxHexLength      = 64 << 10
+	maxFileDownload   = 100 << 20
+	binarySniffLength = 8000
+)
+
+// Content modes of GetFirmwareFile
+const (
+	fileModeText   = "text"
+	fileModeHex    = "hex"
+	fileModeBase64 = "base64"
+	fileModeRaw    = "raw"
+)
+
+// firmwareFile is a file of the extracted filesystem located by
+// resolveFirmwarePath
+type firmwareFile struct {
+	Path     string // inside the firmware, cleaned
+	Resolved string // on the host, below RootFS
+	Root     string // extraction tree of the analysis
+	RootFS   string
+}
+
+// BrowseFilesystem lists a directory (?path=/etc/init.d, / by default) of
+// the root filesystem extracted from an analysis' firmware. Symlinks are
+// resolved inside the extracted filesystem, never on the host.
+func (h *Handler) BrowseFilesystem(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	file, ok := h.resolveFirmwarePath(c, jobID, c.DefaultQuery("path", "/"))
+	if !ok {
+		return
+	}
+	if info, err := os.Stat(file.Resolved); err != nil || !info.IsDir() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Not a directory",
+			"message": fmt.Sprintf("%s is not a directory", file.Path),
+		})
+		return
+	}
+
+	entries, err := firmwarefs.List(file.Resolved, file.Path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to list directory",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Where the rootfs was found, e.g. "_firmware.bin.extracted/squashfs-root"
+	rootfsDir, _ := filepath.Rel(file.Root, file.RootFS)
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":  jobID,
+		"path":    file.Path,
+		"rootfs":  filepath.ToSlash(rootfsDir),
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// GetFirmwareFile returns a file of the extracted filesystem (?path=) as
+// text, a hex dump (paged with offset and length), base64 or the raw bytes
+// (?mode=text|hex|base64|raw, text by default)
+func (h *Handler) GetFirmwareFile(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	mode := c.DefaultQuery("mode", fileModeText)
+	switch mode {
+	case fileModeText, fileModeHex, fileModeBase64, fileModeRaw:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid mode",
+			"message": "mode must be text, hex, base64 or raw",
+		})
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid offset",
+			"message": "offset must be a non-negative number of bytes",
+		})
+		return
+	}
+	length, err := strconv.Atoi(c.DefaultQuery("length", strconv.Itoa(defaultHexLength)))
+	if err != nil || length <= 0 || length > maxHexLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid length",
+			"message": fmt.Sprintf("length must be between 1 and %d bytes", maxHexLength),
+		})
+		return
+	}
+
+	file, ok := h.resolveFirmwarePath(c, jobID, c.Query("path"))
+	if !ok {
+		return
+	}
+	info, err := os.Stat(file.Resolved)
+	if err != nil || !info.Mode().IsRegular() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Not a file",
+			"message": fmt.Sprintf("%s is not a regular file", file.Path),
+		})
+		return
+	}
+
+	f, err := os.Open(file.Resolved)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read file",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+
+	if mode == fileModeRaw {
+		if info.Size() > maxFileDownload {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "File too large",
+				"message": fmt.Sprintf("%s is %d bytes; raw downloads are limited to %d bytes, use mode=hex to page through it", file.Path, info.Size(), maxFileDownload),
+			})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(file.Path)))
+		c.DataFromReader(http.StatusOK, info.Size(), "application/octet-stream", f, nil)
+		return
+	}
+
+	response := gin.H{
+		"job_id": jobID,
+		"path":   file.Path,
+		"mode":   mode,
+		"size":   info.Size(),
+	}
+
+	if mode == fileModeHex {
+		data := make([]byte, length)
+		n, err := f.ReadAt(data, offset)
+		if err != nil && err != io.EOF {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to read file",
+				"message": err.Error(),
+			})
+			return
+		}
+		response["offset"] = offset
+		response["length"] = n
+		response["content"] = hexDump(data[:n], offset)
+		response["truncated"] = offset+int64(n) < info.Size()
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, maxFilePreview))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read file",
+			"message": err.Error(),
+		})
+		return
+	}
+	response["truncated"] = int64(len(data)) < info.Size()
+
+	if mode == fileModeBase64 {
+		response["content"] = base64.StdEncoding.EncodeToString(data)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if isBinary(data) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":   "Binary file",
+			"message": fmt.Sprintf("%s is not a text file, use mode=hex or mode=base64", file.Path),
+		})
+		return
+	}
+	response["content"] = string(data)
+	c.JSON(http.StatusOK, response)
+}
+
+// resolveFirmwarePath locates a path (/ or a file path inside the firmware)
+// of an analysis' extracted root filesystem, writing the error response
+// when it can't
+func (h *Handler) resolveFirmwarePath(c *gin.Context, jobID, firmwarePath string) (*firmwareFile, bool) {
+	if firmwarePath != "/" {
+		cleaned, err := firmwarefs.CleanPath(firmwarePath)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid path",
+				"message": err.Error(),
+			})
+			return nil, false
+		}
+		firmwarePath = cleaned
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+
+	root, err := firmwarefs.Root(&project)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Extracted firmware not available",
+			"message": fmt.Sprintf("Analysis job %s has no extracted firmware", jobID),
+		})
+		return nil, false
+	}
+	rootfs := firmwarefs.RootFS(root)
+
+	resolved, err := firmwarefs.Resolve(rootfs, firmwarePath)
+	if os.IsNotExist(err) && firmwarePath != "/" {
+		// Finding paths may be relative to the extraction tree or even
+		// absolute on the host: look the file up by that path's suffix
+		if found, findErr := firmwarefs.Find(root, strings.TrimPrefix(firmwarePath, root)); findErr == nil && found != "" {
+			resolved, err = found, nil
+		}
+	}
+	if os.IsNotExist(err) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Path not found",
+			"message": fmt.Sprintf("%s does not exist in the extracted firmware", firmwarePath),
+		})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid path",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return &firmwareFile{Path: firmwarePath, Resolved: resolved, Root: root, RootFS: rootfs}, true
+}
+
+// isBinary reports whether data looks like anything but text: a NUL byte or
+// invalid UTF-8 near the start
+func isBinary(data []byte) bool {
+	sniff := data
+	if len(sniff) > binarySniffLength {
+		sniff = sniff[:binarySniffLength]
+		// Don't count a multi-byte character cut in half
+		for i := 0; i < utf8.UTFMax && len(sniff) > 0 && !utf8.Valid(sniff); i++ {
+			sniff = sniff[:len(sniff)-1]
+		}
+	}
+	return bytes.IndexByte(sniff, 0) >= 0 || !utf8.Valid(sniff)
+}
+
+// hexDump formats data like hexdump -C, with offsets counted from the start
+// of the file
+func hexDump(data []byte, offset int64) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(data); i += 16 {
+		end := i + 16
+		if end > len(data) {
+			end = len(data)
+		}
+		line := data[i:end]
+
+		fmt.Fprintf(&buf, "%08x  ", offset+int64(i))
+		for j := 0; j < 16; j++ {
+			if j < len(line) {
+				buf.WriteString(hex.EncodeToString(line[j : j+1]))
+				buf.WriteByte(' ')
+			} else {
+				buf.WriteString("   ")
+			}
+			if j == 7 {
+				buf.WriteByte(' ')
+			}
+		}
+		buf.WriteString(" |")
+		for _, b := range line {
+			if b >= 0x20 && b < 0x7f {
+				buf.WriteByte(b)
+			} else {
+				buf.WriteByte('.')
+			}
+		}
+		buf.WriteString("|\n")
+	}
+	return buf.String()
+}
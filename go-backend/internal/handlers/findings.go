@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/cwe"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListFindings searches findings across all analyses. Filters: type,
+// severity, module, project_id, triage_status, assignee, label, cwe,
+// technique (ATT&CK for ICS or EMB3D), slot (a or b of A/B images) and
+// permission, the last matching one of a weak permission finding's issues,
+// e.g. ?permission=setuid lists every setuid file found in any firmware.
+// Findings merged into another are left out unless ?include_merged=true.
+func (h *Handler) ListFindings(c *gin.Context) {
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 1000 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	query := h.db.Model(&models.Finding{}).Where("partial = ?", false)
+	if c.Query("include_merged") != "true" {
+		query = query.Where("merged_into_id IS NULL")
+	}
+	for param, column := range map[string]string{
+		"type":       "type",
+		"severity":   "severity",
+		"module":     "module",
+		"project_id": "project_id",
+		"assignee":   "assignee",
+	} {
+		if value := c.Query(param); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if status := c.Query("triage_status"); status != "" {
+		triage, err := parseTriageStatus(status)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid triage status",
+				"message": err.Error(),
+			})
+			return
+		}
+		query = query.Where("triage_status = ?", triage)
+	}
+	if weakness := c.Query("cwe"); weakness != "" {
+		query = query.Where("cwe = ?", cwe.Normalize(weakness))
+	}
+	if technique := c.Query("technique"); technique != "" {
+		// techniques is comma separated
+		query = query.Where("',' || techniques || ',' LIKE ?", "%,"+strings.ToUpper(technique)+",%")
+	}
+	if permission := c.Query("permission"); permission != "" {
+		// permission_issues is comma separated
+		query = query.Where("',' || permission_issues || ',' LIKE ?", "%,"+permission+",%")
+	}
+	if label := c.Query("label"); label != "" {
+		// labels is comma separated
+		query = query.Where("',' || labels || ',' LIKE ?", "%,"+normalizeLabel(label)+",%")
+	}
+	if slot := c.Query("slot"); slot != "" {
+		// slot is comma separated for findings both copies of an A/B image have
+		query = query.Where("',' || slot || ',' LIKE ?", "%,"+slot+",%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var findings []models.Finding
+	if err := query.Order("created_at DESC, id").Limit(limit).Offset(offset).Find(&findings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"findings": findings,
+		"count":    len(findings),
+		"total":    total,
+	})
+}
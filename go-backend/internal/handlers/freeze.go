@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/freeze"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FreezeProject locks a completed project's results and records their content hash
+func (h *Handler) FreezeProject(c *gin.Context) {
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+
+	if project.Frozen() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Project already frozen",
+			"message": "Project results were frozen by " + project.FrozenBy,
+		})
+		return
+	}
+	if project.Status != models.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Project not completed",
+			"message": "Only completed analyses can be frozen",
+		})
+		return
+	}
+
+	// The CVEs' shared data is kept as it is now, and hashed with the results
+	now := time.Now().UTC()
+	actor := requestActor(c)
+	var hash string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := freeze.SnapshotCVEs(tx, project.ID); err != nil {
+			return err
+		}
+		var err error
+		hash, err = freeze.ContentHash(tx, project.ID)
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&models.Project{}).
+			Where("id = ? AND frozen_at IS NULL", project.ID).
+			UpdateColumns(map[string]interface{}{
+				"frozen_at":   now,
+				"frozen_by":   actor,
+				"frozen_hash": hash,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return freeze.ErrFrozen
+		}
+		return nil
+	})
+	if errors.Is(err, freeze.ErrFrozen) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Project already frozen",
+			"message": "Project was frozen concurrently",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to freeze project",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := audit.Record(h.db, actor, "project.freeze", "project", project.ID, map[string]interface{}{
+		"content_hash": hash,
+	}); err != nil {
+		log.Printf("Failed to audit freeze of project %s: %v", project.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project_id":   project.ID,
+		"frozen_at":    now,
+		"frozen_by":    actor,
+		"content_hash": hash,
+	})
+}
+
+// GetFreezeStatus reports whether a project is frozen and whether its results
+// still match the content hash recorded at freeze time
+func (h *Handler) GetFreezeStatus(c *gin.Context) {
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+
+	if !project.Frozen() {
+		c.JSON(http.StatusOK, gin.H{
+			"project_id": project.ID,
+			"frozen":     false,
+		})
+		return
+	}
+
+	current, err := freeze.ContentHash(h.db, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to hash results",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project_id":   project.ID,
+		"frozen":       true,
+		"frozen_at":    project.FrozenAt,
+		"frozen_by":    project.FrozenBy,
+		"content_hash": project.FrozenHash,
+		"current_hash": current,
+		"intact":       current == project.FrozenHash,
+	})
+}
+
+// UnfreezeProject unlocks a frozen project's results (admin only)
+func (h *Handler) UnfreezeProject(c *gin.Context) {
+	project, ok := h.findProject(c)
+	if !ok {
+		return
+	}
+
+	if !project.Frozen() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Project not frozen",
+			"message": "Project results are not frozen",
+		})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Project{}).Where("id = ?", project.ID).
+			UpdateColumns(map[string]interface{}{
+				"frozen_at":   nil,
+				"frozen_by":   "",
+				"frozen_hash": "",
+			}).Error; err != nil {
+			return err
+		}
+		return freeze.ReleaseCVEs(tx, project.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to unfreeze project",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := audit.Record(h.db, requestActor(c), "project.unfreeze", "project", project.ID, map[string]interface{}{
+		"frozen_at":    project.FrozenAt,
+		"frozen_by":    project.FrozenBy,
+		"content_hash": project.FrozenHash,
+	}); err != nil {
+		log.Printf("Failed to audit unfreeze of project %s: %v", project.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project_id": project.ID,
+		"frozen":     false,
+	})
+}
+
+// findProject loads the project named by the project_id URL parameter
+func (h *Handler) findProject(c *gin.Context) (models.Project, bool) {
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", c.Param("project_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Project not found",
+				"message": "No project with this ID",
+			})
+			return project, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return project, false
+	}
+	return project, true
+}
+
+// rejectFrozen responds with 409 and returns true if the project's results are locked
+func rejectFrozen(c *gin.Context, project *models.Project) bool {
+	if !project.Frozen() {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "Project frozen",
+		"message": freeze.ErrFrozen.Error() + "; an admin must unfreeze the project first",
+	})
+	return true
+}
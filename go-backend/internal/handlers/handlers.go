@@ -14,7 +14,14 @@ import (
 	"time"
 
 	"odin-backend/internal/config"
+	"odin-backend/internal/cwe"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/extract"
+	"odin-backend/internal/fwformat"
 	"odin-backend/internal/models"
+	"odin-backend/internal/osint"
+	"odin-backend/internal/settings"
+	"odin-backend/internal/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -24,24 +31,61 @@ import (
 type Handler struct {
 	db     *gorm.DB
 	config *config.Config
+	emba   *emba.Service
+	cwe    *cwe.Dictionary
 }
 
 func New(db *gorm.DB, cfg *config.Config) *Handler {
+	dictionary, err := cwe.Load(cfg.CWEDictionary)
+	if err != nil {
+		log.Printf("Failed to load CWE dictionary %s, using the shipped one: %v", cfg.CWEDictionary, err)
+		dictionary = cwe.Shipped()
+	}
 	return &Handler{
 		db:     db,
 		config: cfg,
+		emba:   emba.New(cfg),
+		cwe:    dictionary,
 	}
 }
 
 // HealthCheck returns the health status of the API
 func (h *Handler) HealthCheck(c *gin.Context) {
+	status := "healthy"
+	embaHealth := h.emba.CachedHealth(c.Request.Context(), embaHealthMaxAge)
+	if !embaHealth.Healthy {
+		status = "degraded"
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":    "healthy",
+		"status":    status,
 		"timestamp": time.Now().UTC(),
-		"version":   "1.0.0",
+		"version":   version.Version,
+		"emba": gin.H{
+			"healthy":    embaHealth.Healthy,
+			"version":    embaHealth.EMBAVersion,
+			"missing":    embaHealth.Missing,
+			"checked_at": embaHealth.CheckedAt,
+		},
 	})
 }
 
+// embaHealthMaxAge is how long the health check reuses EMBA's last health report
+const embaHealthMaxAge = 5 * time.Minute
+
+// GetEMBAHealth checks the EMBA installation and its external tools; with
+// check_dependencies=true it also runs EMBA's dependency checker
+func (h *Handler) GetEMBAHealth(c *gin.Context) {
+	checkDependencies, _ := strconv.ParseBool(c.Query("check_dependencies"))
+	health := h.emba.Health(c.Request.Context(), checkDependencies)
+
+	status := http.StatusOK
+	if !health.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
+
 // UploadFirmware handles firmware file upload and starts analysis
 func (h *Handler) UploadFirmware(c *gin.Context) {
 	// Log request details for debugging
@@ -75,10 +119,22 @@ func (h *Handler) UploadFirmware(c *gin.Context) {
 	}
 	defer file.Close()
 
+	// Load the organization's upload settings
+	orgID := requestOrgID(c)
+	uploadSettings, err := settings.Load(h.db, h.config, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load upload settings",
+			"message": err.Error(),
+		})
+		return
+	}
+	supportedExtensions := uploadSettings.Extensions()
+
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	validExt := false
-	for _, supportedExt := range h.config.SupportedExtensions {
+	for _, supportedExt := range supportedExtensions {
 		if ext == supportedExt {
 			validExt = true
 			break
@@ -87,16 +143,16 @@ func (h *Handler) UploadFirmware(c *gin.Context) {
 	if !validExt {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Unsupported file type",
-			"message": fmt.Sprintf("Supported extensions: %s", strings.Join(h.config.SupportedExtensions, ", ")),
+			"message": fmt.Sprintf("Supported extensions: %s", strings.Join(supportedExtensions, ", ")),
 		})
 		return
 	}
 
 	// Validate file size
-	if header.Size > h.config.MaxFileSize {
+	if header.Size > uploadSettings.MaxFileSize {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "File too large",
-			"message": fmt.Sprintf("Maximum file size: %d bytes", h.config.MaxFileSize),
+			"message": fmt.Sprintf("Maximum file size: %d bytes", uploadSettings.MaxFileSize),
 		})
 		return
 	}
@@ -141,6 +197,103 @@ func (h *Handler) UploadFirmware(c *gin.Context) {
 
 	fileHash := fmt.Sprintf("%x", hasher.Sum(nil))
 
+	// Optional EMBA module selection, e.g. "S09,S25,F20" for a quick CVE pass
+	modules, err := emba.ParseModules(c.Request.FormValue("modules"))
+	if err != nil {
+		dst.Close()
+		os.Remove(filePath)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid module selection",
+			"message": err.Error(),
+		})
+		return
+	}
+	selectedModules := strings.Join(modules, ",")
+
+	// Optional modules to skip for this project, e.g. "S115,L" on hardware
+	// where emulation is flaky
+	excluded, err := emba.ParseModules(c.Request.FormValue("exclude_modules"))
+	if err != nil {
+		dst.Close()
+		os.Remove(filePath)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid module exclusion",
+			"message": err.Error(),
+		})
+		return
+	}
+	excludedModules := strings.Join(excluded, ",")
+
+	// Optional extraction backend: EMBA's own extractor, or unblob for
+	// formats it misses
+	extractor := c.Request.FormValue("extractor")
+	if extractor == "" {
+		extractor = extract.MethodEMBA
+	}
+	if !extract.Selectable(extractor) {
+		dst.Close()
+		os.Remove(filePath)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid extractor",
+			"message": fmt.Sprintf("Unknown extractor %q, use %s or %s", extractor, extract.MethodEMBA, extract.MethodUnblob),
+		})
+		return
+	}
+
+	// Optional deployment context the CVEs' adjusted scores are computed for
+	reachability := strings.ToLower(strings.TrimSpace(c.Request.FormValue("reachability")))
+	securityRequirements := strings.ToUpper(strings.TrimSpace(c.Request.FormValue("security_requirements")))
+	if err := validateDeployment(reachability, securityRequirements); err != nil {
+		dst.Close()
+		os.Remove(filePath)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid deployment context",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Optional OSINT providers to run, e.g. "endoflife" to keep a
+	// confidential image's hashes and certificates off third-party services
+	osintProviders := strings.Join(osint.ParseSelection(c.Request.FormValue("osint_providers")), ",")
+	// and whether to query them again instead of using cached responses
+	osintRefresh, _ := strconv.ParseBool(c.Request.FormValue("osint_refresh"))
+
+	// Attach to an in-flight analysis of the same firmware, profile, module
+	// selection and extractor instead of analyzing it twice
+	if existing, err := h.findInFlightDuplicate(orgID, fileHash, h.config.EMBAScanProfile, selectedModules, excludedModules, extractor); err != nil {
+		log.Printf("Failed to check for duplicate analysis of %s: %v", fileHash, err)
+	} else if existing != nil {
+		dst.Close()
+		os.Remove(filePath)
+		log.Printf("Upload of %s attached to in-flight job %s", fileHash, existing.ID)
+		c.JSON(http.StatusOK, gin.H{
+			"job_id":       existing.ID,
+			"project_id":   existing.ID,
+			"status":       existing.Status,
+			"message":      "Identical firmware is already being analyzed with this profile; attached to the existing job",
+			"deduplicated": true,
+			"filename":     header.Filename,
+			"file_size":    header.Size,
+			"file_hash":    fileHash,
+		})
+		return
+	}
+
+	// Identify the container format to warn about types that usually fail,
+	// and note what its header says before any heavy analysis starts
+	format, err := fwformat.DescribeFile(filePath)
+	if err != nil {
+		log.Printf("Failed to identify firmware format of %s: %v", filePath, err)
+	}
+	firmwareType := format.Format
+	platform := emba.HeaderPlatform(format)
+	formatDetails := ""
+	if format.Details != nil {
+		encoded, _ := json.Marshal(format.Details)
+		formatDetails = string(encoded)
+	}
+
 	// Get project metadata from form
 	projectName := c.Request.FormValue("project_name")
 	if projectName == "" {
@@ -150,6 +303,7 @@ func (h *Handler) UploadFirmware(c *gin.Context) {
 	// Create project record
 	project := &models.Project{
 		ID:          jobID,
+		OrgID:       orgID,
 		Name:        projectName,
 		Description: c.Request.FormValue("description"),
 		Status:      models.StatusPending,
@@ -161,6 +315,21 @@ func (h *Handler) UploadFirmware(c *gin.Context) {
 		DeviceModel: c.Request.FormValue("device_model"),
 		DeviceVersion: c.Request.FormValue("device_version"),
 		Manufacturer: c.Request.FormValue("manufacturer"),
+		Fleet:       c.Request.FormValue("fleet"),
+		Reachability: reachability,
+		SecurityRequirements: securityRequirements,
+		FirmwareType: firmwareType,
+		FirmwareEndianness: format.Endianness,
+		FirmwareArch: format.Architecture,
+		FormatDetails: formatDetails,
+		Architecture: platform.Architecture,
+		OSFamily:    platform.OSFamily,
+		Extractor:   extractor,
+		ScanProfile: h.config.EMBAScanProfile,
+		Modules:     selectedModules,
+		ExcludedModules: excludedModules,
+		OSINTProviders: osintProviders,
+		OSINTRefresh: osintRefresh,
 		FirmwareInfo: "{}",
 		ExtractionResults: "{}",
 	}
@@ -176,23 +345,27 @@ func (h *Handler) UploadFirmware(c *gin.Context) {
 		return
 	}
 
-	// Start analysis directly (simplified without queue)
-	// In a production system, this would be handled by a background worker
-	// For now, we'll mark the project as ready for analysis
+	// The project stays pending; workers pick it up from the database queue
 
-	// Update project status
-	project.Status = models.StatusUploading
-	h.db.Save(project)
+	response := gin.H{
+		"job_id":        jobID,
+		"project_id":    jobID,
+		"status":        "QUEUED",
+		"message":       "Firmware uploaded successfully, analysis queued",
+		"filename":      header.Filename,
+		"file_size":     header.Size,
+		"file_hash":     fileHash,
+		"modules":       modules,
+		"excluded_modules": excluded,
+		"firmware_type": firmwareType,
+		"format":        format,
+		"extractor":     extractor,
+	}
+	if advisory := h.uploadAdvisory(firmwareType, project.Extractor); advisory != nil {
+		response["advisory"] = advisory
+	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"job_id":     jobID,
-		"project_id": jobID,
-		"status":     "QUEUED",
-		"message":    "Firmware uploaded successfully, analysis queued",
-		"filename":   header.Filename,
-		"file_size":  header.Size,
-		"file_hash":  fileHash,
-	})
+	c.JSON(http.StatusAccepted, response)
 }
 
 // GetAnalysisStatus returns the current status of an analysis job
@@ -215,23 +388,60 @@ func (h *Handler) GetAnalysisStatus(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	extraction := project.ExtractionData()
+	statusMessage, _ := extraction["status_message"].(string)
+
+	response := gin.H{
 		"job_id":       jobID,
 		"project_id":   project.ID,
 		"status":       project.Status,
+		"message":      statusMessage,
 		"risk_level":   project.RiskLevel,
 		"created_at":   project.CreatedAt,
 		"updated_at":   project.UpdatedAt,
 		"completed_at": project.CompletedAt,
-	})
+	}
+
+	// Why a queued job hasn't started yet (resource checks)
+	if project.Status == models.StatusPending {
+		if scheduling, ok := extraction["scheduling"]; ok {
+			response["scheduling"] = scheduling
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetAnalysisResults returns the complete analysis results
 func (h *Handler) GetAnalysisResults(c *gin.Context) {
 	jobID := c.Param("job_id")
 
+	// ?min_confidence=medium hides low-confidence keyword matches
+	minConfidence := models.Confidence(c.Query("min_confidence"))
+	if minConfidence != "" && minConfidence.Rank() == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid confidence",
+			"message": "min_confidence must be low, medium or high",
+		})
+		return
+	}
+
+	// ?exploitable=true keeps the CVEs with a public or known exploit,
+	// ?include_suppressed=true keeps those of accepted risks
+	exploitable := c.Query("exploitable") == "true"
+
+	// ?group_by=component rolls the CVEs up by software component instead
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != "component" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid grouping",
+			"message": "group_by must be component",
+		})
+		return
+	}
+
 	var project models.Project
-	if err := h.db.Preload("Findings").Preload("CVEFindings").Preload("OSINTResults").
+	if err := h.db.Preload("Findings").Preload("CVEFindings.CVE").Preload("OSINTResults", currentOSINT).Preload("EngineVerdicts").
 		First(&project, "id = ?", jobID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -246,14 +456,43 @@ func (h *Handler) GetAnalysisResults(c *gin.Context) {
 		})
 		return
 	}
+	mergedFindings := len(project.Findings)
+	project.Findings = filterMerged(c, project.Findings)
+	mergedFindings -= len(project.Findings)
+	project.Findings = filterConfidence(project.Findings, minConfidence)
+	project.Findings = filterLabel(project.Findings, c.Query("label"))
+	matches := make(map[models.MatchStatus]int)
+	suppressedCVEs := 0
+	for _, cve := range project.CVEFindings {
+		matches[cve.MatchStatus]++
+		if cve.SuppressionID != nil {
+			suppressedCVEs++
+		}
+	}
+	project.CVEFindings = filterHidden(c, project.CVEFindings)
+	exploitableCVEs := filterExploitable(project.CVEFindings)
+	if exploitable {
+		project.CVEFindings = exploitableCVEs
+	}
 
 	if project.Status != models.StatusCompleted {
-		c.JSON(http.StatusAccepted, gin.H{
+		response := gin.H{
 			"job_id":     jobID,
 			"status":     project.Status,
 			"message":    "Analysis not yet completed",
 			"progress":   h.getProgressMessage(project.Status),
-		})
+		}
+		// Results of the modules EMBA already finished
+		if project.FinishedModules != "" {
+			response["finished_modules"] = strings.Split(project.FinishedModules, ",")
+			response["findings"] = project.Findings
+			if groupBy == "component" {
+				response["cve_components"] = groupCVEsByComponent(project.CVEFindings)
+			} else {
+				response["cve_findings"] = project.CVEFindings
+			}
+		}
+		c.JSON(http.StatusAccepted, response)
 		return
 	}
 
@@ -268,6 +507,7 @@ func (h *Handler) GetAnalysisResults(c *gin.Context) {
 
 	// Count findings by severity
 	severityCounts := map[models.RiskLevel]int{
+		models.RiskInfo:     0,
 		models.RiskLow:      0,
 		models.RiskMedium:   0,
 		models.RiskHigh:     0,
@@ -283,8 +523,34 @@ func (h *Handler) GetAnalysisResults(c *gin.Context) {
 	}
 
 	summary["severity_counts"] = severityCounts
+	summary["exploitable_cves"] = len(exploitableCVEs)
+	summary["suppressed_cves"] = suppressedCVEs
+	summary["potential_cves"] = matches[models.MatchPotential]
+	summary["rejected_cves"] = matches[models.MatchRejected]
+	summary["merged_findings"] = mergedFindings
+
+	if hardware := firmwareInfoSection(&project, "hardware"); hardware != nil {
+		summary["hardware_peripherals"] = hardware["counts"]
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	// Modules EMBA didn't run because of its privilege mode
+	if skipped, ok := project.ExtractionData()["skipped_modules"]; ok && skipped != nil {
+		summary["skipped_modules"] = skipped
+	}
+	// Modules excluded for the instance or the project
+	if excluded, ok := project.ExtractionData()["excluded_modules"]; ok && excluded != nil {
+		summary["excluded_modules"] = excluded
+	}
+
+	// The grouped view replaces the CVE rows, which the component's CVEs endpoint lists
+	var components []componentCVEs
+	if groupBy == "component" {
+		components = groupCVEsByComponent(project.CVEFindings)
+		summary["vulnerable_components"] = len(components)
+		project.CVEFindings = nil
+	}
+
+	response := gin.H{
 		"job_id":            jobID,
 		"project":           project,
 		"findings":          project.Findings,
@@ -293,7 +559,82 @@ func (h *Handler) GetAnalysisResults(c *gin.Context) {
 		"summary":           summary,
 		"extraction_results": project.ExtractionResults,
 		"firmware_info":     project.FirmwareInfo,
-	})
+	}
+	if components != nil {
+		response["cve_components"] = components
+		delete(response, "cve_findings")
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// filterConfidence keeps the findings at or above a confidence level
+func filterConfidence(findings []models.Finding, min models.Confidence) []models.Finding {
+	if min == "" {
+		return findings
+	}
+	filtered := []models.Finding{}
+	for _, finding := range findings {
+		if finding.Confidence.Rank() >= min.Rank() {
+			filtered = append(filtered, finding)
+		}
+	}
+	return filtered
+}
+
+// filterLabel keeps the findings with a label
+func filterLabel(findings []models.Finding, label string) []models.Finding {
+	if label == "" {
+		return findings
+	}
+	label = normalizeLabel(label)
+	filtered := []models.Finding{}
+	for _, finding := range findings {
+		if containsString(splitList(finding.Labels), label) {
+			filtered = append(filtered, finding)
+		}
+	}
+	return filtered
+}
+
+// filterExploitable keeps the CVE findings that have a public exploit or
+// are known to be exploited
+func filterExploitable(cves []models.CVEFinding) []models.CVEFinding {
+	filtered := []models.CVEFinding{}
+	for _, cve := range cves {
+		if cve.Exploitable() {
+			filtered = append(filtered, cve)
+		}
+	}
+	return filtered
+}
+
+// filterHidden drops the CVE findings of accepted risks and those analysts
+// rejected as false positives, unless the request asks for them with
+// ?include_suppressed=true and ?include_rejected=true
+func filterHidden(c *gin.Context, cves []models.CVEFinding) []models.CVEFinding {
+	suppressed, rejected := c.Query("include_suppressed") == "true", c.Query("include_rejected") == "true"
+	filtered := []models.CVEFinding{}
+	for _, cve := range cves {
+		if (cve.SuppressionID == nil || suppressed) && (cve.MatchStatus != models.MatchRejected || rejected) {
+			filtered = append(filtered, cve)
+		}
+	}
+	return filtered
+}
+
+// filterMerged drops the findings merged into another as duplicates,
+// unless the request asks for them with ?include_merged=true
+func filterMerged(c *gin.Context, findings []models.Finding) []models.Finding {
+	if c.Query("include_merged") == "true" {
+		return findings
+	}
+	filtered := []models.Finding{}
+	for _, finding := range findings {
+		if finding.MergedIntoID == nil {
+			filtered = append(filtered, finding)
+		}
+	}
+	return filtered
 }
 
 // DeleteAnalysis deletes an analysis job and its results
@@ -316,10 +657,21 @@ func (h *Handler) DeleteAnalysis(c *gin.Context) {
 		return
 	}
 
-	// Delete uploaded file
-	if err := os.Remove(project.FilePath); err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Warning: Failed to delete file %s: %v\n", project.FilePath, err)
+	if rejectFrozen(c, &project) {
+		return
+	}
+
+	// Delete uploaded file; a diff scan's belongs to the analysis it compared
+	if project.DiffBaseID == "" {
+		if err := os.Remove(project.FilePath); err != nil {
+			// Log error but don't fail the request
+			fmt.Printf("Warning: Failed to delete file %s: %v\n", project.FilePath, err)
+		}
+		if project.EncryptedFilePath != "" {
+			if err := os.Remove(project.EncryptedFilePath); err != nil {
+				fmt.Printf("Warning: Failed to delete file %s: %v\n", project.EncryptedFilePath, err)
+			}
+		}
 	}
 
 	// Delete project (cascade will delete related records)
@@ -356,7 +708,15 @@ func (h *Handler) ListProjects(c *gin.Context) {
 		}
 	}
 
-	if err := h.db.Limit(limit).Offset(offset).Order("created_at DESC").Find(&projects).Error; err != nil {
+	query := h.db.Model(&models.Project{})
+	if architecture := c.Query("architecture"); architecture != "" {
+		query = query.Where("architecture = ?", emba.NormalizeArchitecture(architecture, ""))
+	}
+	if osFamily := c.Query("os_family"); osFamily != "" {
+		query = query.Where("os_family = ?", emba.NormalizeOS(osFamily))
+	}
+
+	if err := query.Limit(limit).Offset(offset).Order("created_at DESC").Find(&projects).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Database error",
 			"message": err.Error(),
@@ -375,7 +735,7 @@ func (h *Handler) GetProject(c *gin.Context) {
 	projectID := c.Param("project_id")
 
 	var project models.Project
-	if err := h.db.Preload("Findings").Preload("CVEFindings").Preload("OSINTResults").
+	if err := h.db.Preload("Findings").Preload("CVEFindings.CVE").Preload("OSINTResults", currentOSINT).Preload("EngineVerdicts").
 		First(&project, "id = ?", projectID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -390,6 +750,8 @@ func (h *Handler) GetProject(c *gin.Context) {
 		})
 		return
 	}
+	project.Findings = filterMerged(c, project.Findings)
+	project.CVEFindings = filterHidden(c, project.CVEFindings)
 
 	c.JSON(http.StatusOK, project)
 }
@@ -569,6 +931,91 @@ func (h *Handler) GetEMBALogs(c *gin.Context) {
 	})
 }
 
+const (
+	defaultOutputTail = 64 << 10
+	maxOutputTail     = 1 << 20 // larger tails are cut to this
+)
+
+// GetEMBAOutput returns the tail of an analysis' EMBA console output. The
+// output is streamed to disk as EMBA runs, so this works for live analyses.
+func (h *Handler) GetEMBAOutput(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tailBytes := int64(defaultOutputTail)
+	if value := c.Query("tail"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid tail",
+				"message": "tail must be a positive number of bytes",
+			})
+			return
+		}
+		if n > maxOutputTail {
+			n = maxOutputTail
+		}
+		tailBytes = n
+	}
+
+	consoleLog := emba.ConsoleLogPath(h.config.EMBALogDir, fmt.Sprintf("job_%s", project.ID))
+	file, err := os.Open(consoleLog)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "EMBA output not found",
+			"message": "EMBA has not produced any output for this analysis",
+		})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read EMBA output",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	offset := info.Size() - tailBytes
+	if offset < 0 {
+		offset = 0
+	}
+	output := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(output, offset); err != nil && err != io.EOF {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to read EMBA output",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":    jobID,
+		"status":    project.Status,
+		"size":      info.Size(),
+		"offset":    offset,
+		"truncated": offset > 0,
+		"output":    string(output),
+	})
+}
+
 // GetEMBAProfiles returns available EMBA scan profiles
 func (h *Handler) GetEMBAProfiles(c *gin.Context) {
 	profilesDir := filepath.Join(h.config.EMBAPath, "scan-profiles")
@@ -639,6 +1086,7 @@ func (h *Handler) GetEMBAProfiles(c *gin.Context) {
 		"profiles": profiles,
 		"total":    len(profiles),
 		"profiles_dir": profilesDir,
+		"excluded_modules": h.config.EMBAExcludedModules,
 	})
 }
 
@@ -753,3 +1201,40 @@ func (h *Handler) getProgressMessage(status models.ProjectStatus) string {
 		return "Processing..."
 	}
 }
+
+// findInFlightDuplicate returns a queued or running project of the organization
+// analyzing the same firmware with the same profile, modules and extractor, or nil
+func (h *Handler) findInFlightDuplicate(orgID, fileHash, profile, modules, excluded, extractor string) (*models.Project, error) {
+	var project models.Project
+	err := h.db.Where("org_id = ? AND file_hash = ? AND scan_profile = ? AND modules = ? AND excluded_modules = ? AND extractor = ?", orgID, fileHash, profile, modules, excluded, extractor).
+		Where("status IN ?", []models.ProjectStatus{models.StatusPending, models.StatusExtracting, models.StatusAnalyzing}).
+		Order("created_at").
+		First(&project).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// requestOrgID returns the organization a request acts for, taken from the
+// X-Odin-Org header or the org_id form field
+func requestOrgID(c *gin.Context) string {
+	if orgID := c.GetHeader("X-Odin-Org"); orgID != "" {
+		return orgID
+	}
+	if orgID := c.Request.FormValue("org_id"); orgID != "" {
+		return orgID
+	}
+	return models.DefaultOrgID
+}
+
+// requestActor returns the user named in the X-Odin-User header for audit logging
+func requestActor(c *gin.Context) string {
+	if actor := c.GetHeader("X-Odin-User"); actor != "" {
+		return actor
+	}
+	return "unknown"
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetHardwareInventory returns the peripherals (UARTs, JTAG, flash, radios)
+// found in the firmware's device trees and kernel configs
+func (h *Handler) GetHardwareInventory(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	hardware := firmwareInfoSection(&project, "hardware")
+	if hardware == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"job_id":      jobID,
+			"peripherals": []interface{}{},
+			"message":     "No device trees or kernel configs found in the firmware",
+		})
+		return
+	}
+
+	hardware["job_id"] = jobID
+	c.JSON(http.StatusOK, hardware)
+}
+
+// firmwareInfoSection decodes one top-level section of a project's firmware info
+func firmwareInfoSection(project *models.Project, key string) map[string]interface{} {
+	var info map[string]interface{}
+	if err := json.Unmarshal([]byte(project.FirmwareInfo), &info); err != nil {
+		return nil
+	}
+	section, _ := info[key].(map[string]interface{})
+	return section
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// IngestLogs creates a project from the log directory of a past EMBA run.
+// A worker parses the logs without running EMBA.
+func (h *Handler) IngestLogs(c *gin.Context) {
+	var request struct {
+		LogDir       string `json:"log_dir" binding:"required"` // relative to EMBA_INGEST_DIR
+		Name         string `json:"name"`
+		Description  string `json:"description"`
+		ParserLayout string `json:"parser_layout"` // detected when empty
+		DeviceName   string `json:"device_name"`
+		DeviceModel  string `json:"device_model"`
+		Manufacturer string `json:"manufacturer"`
+		Fleet        string `json:"fleet"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if request.ParserLayout != "" && emba.LayoutByName(request.ParserLayout) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid parser layout",
+			"message": "Unknown parser layout " + request.ParserLayout,
+		})
+		return
+	}
+
+	// Only directories below EMBA_INGEST_DIR can be imported
+	root, err := filepath.Abs(h.config.EMBAIngestDir)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Invalid ingest directory",
+			"message": err.Error(),
+		})
+		return
+	}
+	logDir := filepath.Join(root, filepath.Clean("/"+request.LogDir))
+	if relative, err := filepath.Rel(root, logDir); err != nil || relative == "." || strings.HasPrefix(relative, "..") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid log directory",
+			"message": "log_dir must name a directory below EMBA_INGEST_DIR",
+		})
+		return
+	}
+	if info, err := os.Stat(logDir); err != nil || !info.IsDir() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid log directory",
+			"message": "No log directory " + request.LogDir,
+		})
+		return
+	}
+
+	name := request.Name
+	if name == "" {
+		name = filepath.Base(logDir)
+	}
+	project := &models.Project{
+		ID:                uuid.New().String(),
+		OrgID:             requestOrgID(c),
+		Name:              name,
+		Description:       request.Description,
+		Status:            models.StatusPending,
+		Filename:          filepath.Base(logDir),
+		FilePath:          logDir,
+		DeviceName:        request.DeviceName,
+		DeviceModel:       request.DeviceModel,
+		Manufacturer:      request.Manufacturer,
+		Fleet:             request.Fleet,
+		Extractor:         "emba",
+		ParserLayout:      request.ParserLayout,
+		IngestLogDir:      logDir,
+		FirmwareInfo:      "{}",
+		ExtractionResults: "{}",
+	}
+	if err := h.db.Create(project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create project",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := audit.Record(h.db, requestActor(c), "analysis.ingest", "project", project.ID, map[string]interface{}{
+		"log_dir":       logDir,
+		"parser_layout": request.ParserLayout,
+	}); err != nil {
+		log.Printf("Failed to audit log ingestion %s: %v", project.ID, err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":  project.ID,
+		"status":  project.Status,
+		"message": "EMBA logs queued for parsing",
+	})
+}
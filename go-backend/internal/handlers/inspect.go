@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/inspect"
+	"odin-backend/internal/models"
+	"odin-backend/internal/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InspectFirmware takes a quick look at an uploaded image without queueing
+// an analysis: its format, version strings, entropy profile and embedded
+// containers. The image isn't kept. Projects that already analyzed the same
+// image are listed, so a batch of candidates can be triaged.
+func (h *Handler) InspectFirmware(c *gin.Context) {
+	if err := c.Request.ParseMultipartForm(h.config.MaxFileSize); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to parse form",
+			"message": err.Error(),
+		})
+		return
+	}
+	file, header, err := c.Request.FormFile("firmware_file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "No firmware file provided",
+			"message": "Please provide a firmware file",
+		})
+		return
+	}
+	defer file.Close()
+
+	orgID := requestOrgID(c)
+	uploadSettings, err := settings.Load(h.db, h.config, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load upload settings",
+			"message": err.Error(),
+		})
+		return
+	}
+	supportedExtensions := uploadSettings.Extensions()
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if !containsString(supportedExtensions, ext) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported file type",
+			"message": fmt.Sprintf("Supported extensions: %s", strings.Join(supportedExtensions, ", ")),
+		})
+		return
+	}
+	if header.Size > uploadSettings.MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "File too large",
+			"message": fmt.Sprintf("Maximum file size: %d bytes", uploadSettings.MaxFileSize),
+		})
+		return
+	}
+
+	if err := os.MkdirAll(h.config.UploadDir, 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create upload directory",
+			"message": err.Error(),
+		})
+		return
+	}
+	tmp, err := os.CreateTemp(h.config.UploadDir, "inspect-*"+ext)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save file",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, file)
+	tmp.Close()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save file",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	report, err := inspect.Inspect(tmp.Name())
+	if err != nil {
+		log.Printf("Failed to inspect %s: %v", header.Filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to inspect firmware",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var analyzed []models.Project
+	if err := h.db.Select("id", "name", "status", "risk_level", "created_at").
+		Where("org_id = ? AND file_hash = ?", orgID, report.SHA256).
+		Order("created_at DESC").Find(&analyzed).Error; err != nil {
+		log.Printf("Failed to look up projects of %s: %v", report.SHA256, err)
+	}
+	projects := make([]gin.H, 0, len(analyzed))
+	for _, project := range analyzed {
+		projects = append(projects, gin.H{
+			"job_id":     project.ID,
+			"name":       project.Name,
+			"status":     project.Status,
+			"risk_level": project.RiskLevel,
+			"created_at": project.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filename": header.Filename,
+		"report":   report,
+		"projects": projects,
+	})
+}
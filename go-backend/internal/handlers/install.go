@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InstallEMBA queues an installation or update of EMBA on worker hosts. A
+// worker on each host carries it out between analyses.
+func (h *Handler) InstallEMBA(c *gin.Context) {
+	var request struct {
+		Version   string   `json:"version"`
+		Mode      string   `json:"mode"`
+		Hostnames []string `json:"hostnames"` // defaults to every host with an online worker
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if request.Version == "" {
+		request.Version = h.config.EMBAPinnedVersion
+	}
+	if request.Mode == "" {
+		request.Mode = emba.InstallModeDefault
+	}
+	if request.Version == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing EMBA version",
+			"message": "Give a version or set EMBA_PINNED_VERSION",
+		})
+		return
+	}
+	if err := emba.ValidateInstall(request.Version, request.Mode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid installation request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	onlineHosts, err := h.onlineWorkerHosts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	hostnames := request.Hostnames
+	if len(hostnames) == 0 {
+		hostnames = onlineHosts
+	}
+	if len(hostnames) == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "No online workers",
+			"message": "EMBA is installed by a worker on each host; start one first",
+		})
+		return
+	}
+	for _, hostname := range hostnames {
+		if !containsString(onlineHosts, hostname) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Unknown host",
+				"message": "No online worker on " + hostname,
+			})
+			return
+		}
+	}
+
+	actor := requestActor(c)
+	var installs []models.EMBAInstall
+	for _, hostname := range hostnames {
+		install := models.EMBAInstall{
+			ID:          uuid.New().String(),
+			Hostname:    hostname,
+			Version:     request.Version,
+			Mode:        request.Mode,
+			Status:      models.InstallPending,
+			RequestedBy: actor,
+		}
+		if err := h.db.Create(&install).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to queue installation",
+				"message": err.Error(),
+			})
+			return
+		}
+		installs = append(installs, install)
+
+		if err := audit.Record(h.db, actor, "emba.install", "emba_install", install.ID, map[string]interface{}{
+			"hostname": hostname,
+			"version":  request.Version,
+			"mode":     request.Mode,
+		}); err != nil {
+			log.Printf("Failed to audit EMBA installation %s: %v", install.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "EMBA installation queued; workers start it once their current analysis finishes",
+		"installs": installs,
+	})
+}
+
+// ListEMBAInstalls returns recent EMBA installations with their progress
+func (h *Handler) ListEMBAInstalls(c *gin.Context) {
+	var installs []models.EMBAInstall
+	if err := h.db.Order("created_at DESC").Limit(100).Find(&installs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"installs": installs,
+	})
+}
+
+// onlineWorkerHosts returns the hosts that have a worker sending heartbeats
+func (h *Handler) onlineWorkerHosts() ([]string, error) {
+	var hostnames []string
+	err := h.db.Model(&models.Worker{}).
+		Where("last_heartbeat_at > ?", time.Now().Add(-models.WorkerOfflineAfter)).
+		Distinct().Pluck("hostname", &hostnames).Error
+	sort.Strings(hostnames)
+	return hostnames, err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/integrations"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type integrationRequest struct {
+	Provider *string `json:"provider"`
+	Name     *string `json:"name"`
+	KeyID    *string `json:"key_id"`
+	Secret   *string `json:"secret"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+// ListIntegrations returns the stored API keys of the OSINT providers,
+// without their secrets, optionally of one ?provider
+func (h *Handler) ListIntegrations(c *gin.Context) {
+	query := h.db.Order("provider, id")
+	if provider := c.Query("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+
+	var stored []models.Integration
+	if err := query.Find(&stored).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"integrations": stored,
+		"total":        len(stored),
+	})
+}
+
+// CreateIntegration stores an API key of an OSINT provider. The provider
+// uses it from its next request on, alongside its other enabled keys.
+func (h *Handler) CreateIntegration(c *gin.Context) {
+	keys, ok := h.keyring(c)
+	if !ok {
+		return
+	}
+
+	integration := models.Integration{Enabled: true, CreatedBy: requestActor(c)}
+	if !h.bindIntegration(c, keys, &integration) {
+		return
+	}
+	if err := h.db.Create(&integration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create integration",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.auditIntegration(c, "integration.create", integration)
+	c.JSON(http.StatusCreated, integration)
+}
+
+// UpdateIntegration renames, enables or disables a stored key, or rotates
+// it by replacing its secret
+func (h *Handler) UpdateIntegration(c *gin.Context) {
+	keys, ok := h.keyring(c)
+	if !ok {
+		return
+	}
+	integration, ok := h.findIntegration(c)
+	if !ok {
+		return
+	}
+	if !h.bindIntegration(c, keys, &integration) {
+		return
+	}
+	if err := h.db.Save(&integration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update integration",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.auditIntegration(c, "integration.update", integration)
+	c.JSON(http.StatusOK, integration)
+}
+
+// DeleteIntegration removes a stored key
+func (h *Handler) DeleteIntegration(c *gin.Context) {
+	integration, ok := h.findIntegration(c)
+	if !ok {
+		return
+	}
+	if err := h.db.Delete(&integration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete integration",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.auditIntegration(c, "integration.delete", integration)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Integration deleted successfully",
+	})
+}
+
+// keyring returns the keyring stored secrets are sealed with, or answers
+// that keys can't be stored without INTEGRATIONS_KEY
+func (h *Handler) keyring(c *gin.Context) (*integrations.Keyring, bool) {
+	keys := integrations.New(h.db, h.config)
+	if keys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Integrations not configured",
+			"message": "Set INTEGRATIONS_KEY to store API keys",
+		})
+		return nil, false
+	}
+	return keys, true
+}
+
+// findIntegration loads the stored key in the URL
+func (h *Handler) findIntegration(c *gin.Context) (models.Integration, bool) {
+	var integration models.Integration
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid integration ID",
+			"message": err.Error(),
+		})
+		return integration, false
+	}
+
+	if err := h.db.First(&integration, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Integration not found",
+				"message": "No stored API key with this ID",
+			})
+			return integration, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return integration, false
+	}
+
+	return integration, true
+}
+
+// bindIntegration applies the fields present in the request body, sealing
+// a new secret, and validates the result. The provider of a stored key
+// can't change.
+func (h *Handler) bindIntegration(c *gin.Context, keys *integrations.Keyring, integration *models.Integration) bool {
+	var request integrationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	invalid := func(message string) bool {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid integration",
+			"message": message,
+		})
+		return false
+	}
+
+	if integration.ID == 0 {
+		if request.Provider == nil {
+			return invalid("provider is required")
+		}
+		integration.Provider = strings.ToLower(strings.TrimSpace(*request.Provider))
+	} else if request.Provider != nil && strings.ToLower(strings.TrimSpace(*request.Provider)) != integration.Provider {
+		return invalid("the provider of a stored key can't change")
+	}
+	hasKeyID, known := integrations.Providers[integration.Provider]
+	if !known {
+		var names []string
+		for name := range integrations.Providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return invalid(fmt.Sprintf("unknown provider %q, use one of %s", integration.Provider, strings.Join(names, ", ")))
+	}
+
+	if request.Name != nil {
+		integration.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.KeyID != nil {
+		integration.KeyID = strings.TrimSpace(*request.KeyID)
+	}
+	if request.Enabled != nil {
+		integration.Enabled = *request.Enabled
+	}
+	if request.Secret != nil {
+		secret := strings.TrimSpace(*request.Secret)
+		if secret == "" {
+			return invalid("secret can't be empty")
+		}
+		sealed, err := keys.Seal(secret)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to encrypt secret",
+				"message": err.Error(),
+			})
+			return false
+		}
+		integration.Secret = sealed
+		integration.SecretHint = integrations.Hint(secret)
+		// A rotated key starts without the old one's failure
+		integration.LastError = ""
+		integration.LastErrorAt = nil
+	}
+
+	if integration.Secret == "" {
+		return invalid("secret is required")
+	}
+	if hasKeyID && integration.KeyID == "" {
+		return invalid(fmt.Sprintf("key_id is required for %s", integration.Provider))
+	}
+	return true
+}
+
+// auditIntegration records a change of a stored key, never its secret
+func (h *Handler) auditIntegration(c *gin.Context, action string, integration models.Integration) {
+	id := strconv.FormatUint(uint64(integration.ID), 10)
+	if err := audit.Record(h.db, requestActor(c), action, "integration", id, map[string]interface{}{
+		"provider":    integration.Provider,
+		"name":        integration.Name,
+		"key_id":      integration.KeyID,
+		"secret_hint": integration.SecretHint,
+		"enabled":     integration.Enabled,
+	}); err != nil {
+		log.Printf("Failed to audit change of integration %s: %v", id, err)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// keyMaterialEntry is a key or certificate with the other analyses whose
+// firmware holds the same private key
+type keyMaterialEntry struct {
+	models.KeyMaterial
+	SharedWith []string `json:"shared_with,omitempty"`
+}
+
+// GetKeyMaterial returns the keys and certificates found in an analysis'
+// firmware. ?kind=private_key|public_key|certificate filters them.
+func (h *Handler) GetKeyMaterial(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.db.Where("project_id = ?", project.ID)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	keys := []models.KeyMaterial{}
+	if err := query.Order("kind, file_path").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	entries := make([]keyMaterialEntry, 0, len(keys))
+	counts := map[string]int{}
+	reused := 0
+	for _, key := range keys {
+		entry := keyMaterialEntry{KeyMaterial: key}
+		if key.Kind == models.KeyKindPrivateKey && key.Fingerprint != "" {
+			if err := h.db.Model(&models.KeyMaterial{}).
+				Where("kind = ? AND fingerprint = ? AND project_id <> ?", models.KeyKindPrivateKey, key.Fingerprint, project.ID).
+				Distinct().Pluck("project_id", &entry.SharedWith).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Database error",
+					"message": err.Error(),
+				})
+				return
+			}
+			if len(entry.SharedWith) > 0 {
+				reused++
+			}
+		}
+		counts[key.Kind]++
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":        jobID,
+		"key_materials": entries,
+		"count":         len(entries),
+		"by_kind":       counts,
+		"reused_keys":   reused,
+	})
+}
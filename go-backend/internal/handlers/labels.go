@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxLabels      = 20 // per finding
+	maxLabelLength = 64
+)
+
+type findingLabelsRequest struct {
+	Labels []string `json:"labels"`
+}
+
+// SetFindingLabels replaces the labels of a finding
+func (h *Handler) SetFindingLabels(c *gin.Context) {
+	h.changeFindingLabels(c, func(_, requested []string) []string {
+		return requested
+	})
+}
+
+// AddFindingLabels adds labels to a finding, keeping those it has
+func (h *Handler) AddFindingLabels(c *gin.Context) {
+	h.changeFindingLabels(c, func(current, requested []string) []string {
+		return append(current, requested...)
+	})
+}
+
+// RemoveFindingLabel removes a label from a finding
+func (h *Handler) RemoveFindingLabel(c *gin.Context) {
+	project, finding, ok := h.findFinding(c)
+	if !ok || !triageable(c, &project) {
+		return
+	}
+
+	label := normalizeLabel(c.Param("label"))
+	current := splitList(finding.Labels)
+	labels := []string{}
+	for _, existing := range current {
+		if existing != label {
+			labels = append(labels, existing)
+		}
+	}
+	if len(labels) == len(current) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Label not found",
+			"message": "The finding doesn't have this label",
+		})
+		return
+	}
+	h.saveFindingLabels(c, &project, &finding, labels)
+}
+
+// ListLabels returns the labels of an analysis' findings with the number of
+// findings having each
+func (h *Handler) ListLabels(c *gin.Context) {
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", c.Param("job_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var values []string
+	if err := h.db.Model(&models.Finding{}).Where("project_id = ? AND labels != ''", project.ID).
+		Pluck("labels", &values).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	counts := make(map[string]int)
+	for _, value := range values {
+		for _, label := range splitList(value) {
+			counts[label]++
+		}
+	}
+	type labelCount struct {
+		Label    string `json:"label"`
+		Findings int    `json:"findings"`
+	}
+	labels := make([]labelCount, 0, len(counts))
+	for label, count := range counts {
+		labels = append(labels, labelCount{label, count})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Label < labels[j].Label })
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id": project.ID,
+		"labels": labels,
+		"total":  len(labels),
+	})
+}
+
+// changeFindingLabels sets the labels of the finding in the URL to those
+// change returns from its current and the requested ones
+func (h *Handler) changeFindingLabels(c *gin.Context, change func(current, requested []string) []string) {
+	project, finding, ok := h.findFinding(c)
+	if !ok || !triageable(c, &project) {
+		return
+	}
+
+	var request findingLabelsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+	requested := make([]string, 0, len(request.Labels))
+	for _, label := range request.Labels {
+		label = normalizeLabel(label)
+		if err := validateLabel(label); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid label",
+				"message": err.Error(),
+			})
+			return
+		}
+		requested = append(requested, label)
+	}
+
+	labels := uniqueLabels(change(splitList(finding.Labels), requested))
+	if len(labels) > maxLabels {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid label",
+			"message": fmt.Sprintf("a finding can have at most %d labels", maxLabels),
+		})
+		return
+	}
+	h.saveFindingLabels(c, &project, &finding, labels)
+}
+
+// saveFindingLabels stores a finding's labels, records the change in the
+// audit log and responds with the finding
+func (h *Handler) saveFindingLabels(c *gin.Context, project *models.Project, finding *models.Finding, labels []string) {
+	previous := splitList(finding.Labels)
+	finding.Labels = strings.Join(labels, ",")
+	if err := h.db.Model(&models.Finding{}).Where("id = ?", finding.ID).
+		UpdateColumn("labels", finding.Labels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update labels",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	id := strconv.FormatUint(uint64(finding.ID), 10)
+	if err := audit.Record(h.db, requestActor(c), "finding.labels", "finding", id, map[string]interface{}{
+		"project_id": project.ID,
+		"from":       previous,
+		"to":         labels,
+	}); err != nil {
+		log.Printf("Failed to audit labels of finding %s: %v", id, err)
+	}
+
+	c.JSON(http.StatusOK, finding)
+}
+
+// normalizeLabel trims a label and lowercases it: labels match in any case
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
+
+// validateLabel checks a normalized label, which can't hold the comma
+// separating the labels stored
+func validateLabel(label string) error {
+	switch {
+	case label == "":
+		return fmt.Errorf("labels can't be empty")
+	case utf8.RuneCountInString(label) > maxLabelLength:
+		return fmt.Errorf("label %q is longer than %d characters", label, maxLabelLength)
+	case strings.Contains(label, ","):
+		return fmt.Errorf("label %q can't contain a comma", label)
+	}
+	return nil
+}
+
+// uniqueLabels drops repeated labels, keeping their order
+func uniqueLabels(labels []string) []string {
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, label := range labels {
+		if !seen[label] {
+			seen[label] = true
+			unique = append(unique, label)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// copyleftObligations summarize what shipping a copyleft component requires
+var copyleftObligations = map[models.LicenseCategory]string{
+	models.LicenseStrongCopyleft: "Provide the complete corresponding source code of the component and of works combined with it under the same license, with the license text and copyright notices",
+	models.LicenseWeakCopyleft:   "Provide the source code of the component including any changes to it, with the license text and copyright notices; LGPL libraries must remain replaceable by the user",
+}
+
+// GetLicenses returns the license summary of an analysis' components with
+// each component's license. ?category=permissive lists only that category.
+func (h *Handler) GetLicenses(c *gin.Context) {
+	project, ok := h.licenseProject(c)
+	if !ok {
+		return
+	}
+
+	var components []models.SBOMComponent
+	if err := h.db.Where("project_id = ?", project.ID).Order("name, version").Find(&components).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	summary := emba.SummarizeLicenses(components)
+
+	if category := c.Query("category"); category != "" {
+		var filtered []models.SBOMComponent
+		for _, component := range components {
+			if string(component.LicenseCategory) == category {
+				filtered = append(filtered, component)
+			}
+		}
+		components = filtered
+	}
+	if components == nil {
+		components = []models.SBOMComponent{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":     project.ID,
+		"summary":    summary,
+		"components": components,
+	})
+}
+
+// GetCopyleftReport lists the components under copyleft licenses with what
+// distributing them obliges, as JSON or, with ?format=csv, a spreadsheet
+// for the legal review
+func (h *Handler) GetCopyleftReport(c *gin.Context) {
+	project, ok := h.licenseProject(c)
+	if !ok {
+		return
+	}
+
+	var components []models.SBOMComponent
+	if err := h.db.Where("project_id = ? AND license_category IN ?", project.ID,
+		[]models.LicenseCategory{models.LicenseStrongCopyleft, models.LicenseWeakCopyleft}).
+		Order("license_category, name, version").Find(&components).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	type entry struct {
+		Name       string                 `json:"name"`
+		Version    string                 `json:"version"`
+		License    string                 `json:"license"`
+		Category   models.LicenseCategory `json:"license_category"`
+		Supplier   string                 `json:"supplier"`
+		PURL       string                 `json:"purl"`
+		Obligation string                 `json:"obligation"`
+	}
+	entries := make([]entry, 0, len(components))
+	for _, component := range components {
+		entries = append(entries, entry{
+			Name:       component.Name,
+			Version:    component.Version,
+			License:    component.License,
+			Category:   component.LicenseCategory,
+			Supplier:   component.Supplier,
+			PURL:       component.PURL,
+			Obligation: copyleftObligations[component.LicenseCategory],
+		})
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{
+			"job_id":     project.ID,
+			"firmware":   project.Filename,
+			"count":      len(entries),
+			"components": entries,
+		})
+	case "csv":
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", "attachment; filename=copyleft-"+project.ID+".csv")
+		writer := csv.NewWriter(c.Writer)
+		writer.Write([]string{"name", "version", "license", "license_category", "supplier", "purl", "obligation"})
+		for _, e := range entries {
+			writer.Write([]string{e.Name, e.Version, e.License, string(e.Category), e.Supplier, e.PURL, e.Obligation})
+		}
+		writer.Flush()
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid format",
+			"message": "format must be json or csv",
+		})
+	}
+}
+
+// licenseProject loads the project of a license request, answering it
+// with an error if that fails
+func (h *Handler) licenseProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", c.Param("job_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return &project, true
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type matchReviewRequest struct {
+	Status string `json:"status" binding:"required"` // confirmed, rejected, or potential to reopen it
+	Note   string `json:"note"`
+}
+
+// ReviewCVEMatch records an analyst's decision on whether a CVE finding's
+// component is affected: confirmed, or rejected as a false positive, which
+// hides the finding from the default views and leaves it out of the risk
+// rating. potential reopens the decision.
+func (h *Handler) ReviewCVEMatch(c *gin.Context) {
+	project, finding, ok := h.findCVEFinding(c)
+	if !ok {
+		return
+	}
+	if rejectFrozen(c, &project) {
+		return
+	}
+
+	var request matchReviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+	status := models.MatchStatus(strings.ToLower(strings.TrimSpace(request.Status)))
+	if !status.Reviewed() && status != models.MatchPotential {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid match status",
+			"message": "status must be confirmed, rejected or potential",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	finding.MatchStatus = status
+	finding.MatchNote = strings.TrimSpace(request.Note)
+	finding.ReviewedBy = requestActor(c)
+	finding.ReviewedAt = &now
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CVEFinding{}).Where("id = ?", finding.ID).UpdateColumns(map[string]interface{}{
+			"match_status": finding.MatchStatus,
+			"match_note":   finding.MatchNote,
+			"reviewed_by":  finding.ReviewedBy,
+			"reviewed_at":  finding.ReviewedAt,
+		}).Error; err != nil {
+			return err
+		}
+		return risk.Recount(tx, project.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to review CVE match",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	id := strconv.FormatUint(uint64(finding.ID), 10)
+	if err := audit.Record(h.db, finding.ReviewedBy, "cve_match.review", "cve_finding", id, map[string]interface{}{
+		"project_id":   project.ID,
+		"cve_id":       finding.CVEID,
+		"match_status": finding.MatchStatus,
+		"match_reason": finding.MatchReason,
+		"note":         finding.MatchNote,
+	}); err != nil {
+		log.Printf("Failed to audit review of CVE finding %s: %v", id, err)
+	}
+
+	c.JSON(http.StatusOK, finding)
+}
+
+// findCVEFinding loads the analysis and the CVE finding in the URL, its
+// canonical data filled in
+func (h *Handler) findCVEFinding(c *gin.Context) (models.Project, models.CVEFinding, bool) {
+	var project models.Project
+	var finding models.CVEFinding
+
+	if err := h.db.First(&project, "id = ?", c.Param("job_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return project, finding, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return project, finding, false
+	}
+
+	if err := h.db.Preload("CVE").First(&finding, "id = ? AND project_id = ?", c.Param("finding_id"), project.ID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "CVE finding not found",
+				"message": "No CVE finding with this ID in the analysis",
+			})
+			return project, finding, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return project, finding, false
+	}
+
+	return project, finding, true
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type mergeFindingsRequest struct {
+	CanonicalID uint   `json:"canonical_id" binding:"required"`
+	FindingIDs  []uint `json:"finding_ids" binding:"required"`
+	Reason      string `json:"reason"`
+}
+
+// MergeFindings merges duplicate findings of a completed analysis into a
+// canonical one, which keeps its severity and counts their occurrences.
+// The duplicates stay, linked to it by merged_into_id, but are left out of
+// the counts and the default views; the findings merged into them move to
+// the canonical one.
+func (h *Handler) MergeFindings(c *gin.Context) {
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", c.Param("job_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if !triageable(c, &project) {
+		return
+	}
+
+	var request mergeFindingsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+	request.Reason = strings.TrimSpace(request.Reason)
+	invalid := func(message string) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid merge",
+			"message": message,
+		})
+	}
+
+	var canonical models.Finding
+	if err := h.db.First(&canonical, "id = ? AND project_id = ? AND partial = ?", request.CanonicalID, project.ID, false).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			invalid("canonical_id must be a finding of the analysis")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if canonical.MergedIntoID != nil {
+		invalid(fmt.Sprintf("finding %d is merged into finding %d", canonical.ID, *canonical.MergedIntoID))
+		return
+	}
+
+	ids := make(map[uint]bool)
+	unique := []uint{}
+	for _, id := range request.FindingIDs {
+		if id == canonical.ID {
+			invalid("finding_ids can't include canonical_id")
+			return
+		}
+		if !ids[id] {
+			ids[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		invalid("finding_ids is empty")
+		return
+	}
+	var merged []models.Finding
+	if err := h.db.Where("id IN ? AND project_id = ? AND partial = ?", unique, project.ID, false).
+		Order("id").Find(&merged).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if len(merged) != len(unique) {
+		invalid("finding_ids must be findings of the analysis")
+		return
+	}
+	for _, finding := range merged {
+		if finding.MergedIntoID != nil {
+			invalid(fmt.Sprintf("finding %d is already merged into finding %d", finding.ID, *finding.MergedIntoID))
+			return
+		}
+	}
+
+	actor := requestActor(c)
+	now := time.Now().UTC()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		occurrences := 0
+		for i := range merged {
+			finding := &merged[i]
+			occurrences += finding.OccurrenceCount
+
+			// The findings merged into this one move to the canonical
+			// finding, their occurrences with them
+			var children []models.Finding
+			if err := tx.Select("id", "occurrence_count").Where("merged_into_id = ?", finding.ID).Find(&children).Error; err != nil {
+				return err
+			}
+			own := finding.OccurrenceCount
+			for _, child := range children {
+				own -= child.OccurrenceCount
+			}
+			if own < 1 {
+				own = 1
+			}
+			if len(children) > 0 {
+				if err := tx.Model(&models.Finding{}).Where("merged_into_id = ?", finding.ID).
+					UpdateColumn("merged_into_id", canonical.ID).Error; err != nil {
+					return err
+				}
+			}
+
+			finding.MergedIntoID = &canonical.ID
+			finding.MergedBy = actor
+			finding.MergedAt = &now
+			finding.OccurrenceCount = own
+			if err := tx.Model(&models.Finding{}).Where("id = ?", finding.ID).UpdateColumns(map[string]interface{}{
+				"merged_into_id":   finding.MergedIntoID,
+				"merged_by":        finding.MergedBy,
+				"merged_at":        finding.MergedAt,
+				"occurrence_count": finding.OccurrenceCount,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		canonical.OccurrenceCount += occurrences
+		if err := tx.Model(&models.Finding{}).Where("id = ?", canonical.ID).
+			UpdateColumn("occurrence_count", canonical.OccurrenceCount).Error; err != nil {
+			return err
+		}
+		return risk.Recount(tx, project.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to merge findings",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	mergedIDs := make([]uint, 0, len(merged))
+	for _, finding := range merged {
+		mergedIDs = append(mergedIDs, finding.ID)
+	}
+	id := strconv.FormatUint(uint64(canonical.ID), 10)
+	if err := audit.Record(h.db, actor, "finding.merge", "finding", id, map[string]interface{}{
+		"project_id":  project.ID,
+		"finding_ids": mergedIDs,
+		"reason":      request.Reason,
+	}); err != nil {
+		log.Printf("Failed to audit merge into finding %s: %v", id, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":    project.ID,
+		"canonical": canonical,
+		"merged":    merged,
+	})
+}
+
+// UnmergeFinding restores a finding merged into another: it counts again,
+// and the canonical finding no longer counts its occurrences
+func (h *Handler) UnmergeFinding(c *gin.Context) {
+	project, finding, ok := h.findFinding(c)
+	if !ok || !triageable(c, &project) {
+		return
+	}
+	if finding.MergedIntoID == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Finding not merged",
+			"message": "The finding isn't merged into another",
+		})
+		return
+	}
+
+	canonicalID := *finding.MergedIntoID
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Finding{}).Where("id = ?", finding.ID).UpdateColumns(map[string]interface{}{
+			"merged_into_id": nil,
+			"merged_by":      "",
+			"merged_at":      nil,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Finding{}).Where("id = ?", canonicalID).
+			UpdateColumn("occurrence_count", gorm.Expr("MAX(occurrence_count - ?, 1)", finding.OccurrenceCount)).Error; err != nil {
+			return err
+		}
+		return risk.Recount(tx, project.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to unmerge finding",
+			"message": err.Error(),
+		})
+		return
+	}
+	finding.MergedIntoID, finding.MergedBy, finding.MergedAt = nil, "", nil
+
+	id := strconv.FormatUint(uint64(finding.ID), 10)
+	if err := audit.Record(h.db, requestActor(c), "finding.unmerge", "finding", id, map[string]interface{}{
+		"project_id":   project.ID,
+		"canonical_id": canonicalID,
+	}); err != nil {
+		log.Printf("Failed to audit unmerge of finding %s: %v", id, err)
+	}
+
+	c.JSON(http.StatusOK, finding)
+}
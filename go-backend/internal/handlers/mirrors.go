@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"odin-backend/internal/mirror"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMirrorStatus reports whether this instance runs offline and the last
+// import of every local mirror dataset; a dataset never imported has no
+// imported_at
+func (h *Handler) GetMirrorStatus(c *gin.Context) {
+	imports, err := mirror.Status(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	imported := make(map[string]models.MirrorImport, len(imports))
+	for _, record := range imports {
+		imported[record.Dataset] = record
+	}
+	datasets := make([]gin.H, 0, len(mirror.Datasets))
+	for _, dataset := range mirror.Datasets {
+		status := gin.H{"dataset": dataset, "records": 0, "imported_at": nil}
+		if record, ok := imported[dataset]; ok {
+			status["records"] = record.Records
+			status["source"] = record.Source
+			status["imported_at"] = record.ImportedAt
+		}
+		datasets = append(datasets, status)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offline_mode": h.config.OfflineMode,
+		"datasets":     datasets,
+	})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/ocsf"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportOCSF returns an analysis' findings and CVEs as OCSF Vulnerability
+// Finding events, as a JSON array or, with ?format=ndjson, one event per line
+// for bulk loading into a data lake
+func (h *Handler) ExportOCSF(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.Preload("Findings", "merged_into_id IS NULL").Preload("CVEFindings.CVE").First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if project.Status != models.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Analysis not completed",
+			"message": "Findings can only be exported once the analysis has completed",
+		})
+		return
+	}
+
+	events := ocsf.Events(&project)
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, events)
+	case "ndjson":
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(c.Writer)
+		for i := range events {
+			if err := encoder.Encode(&events[i]); err != nil {
+				return
+			}
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid format",
+			"message": "format must be json or ndjson",
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/osint"
+	"odin-backend/internal/osint/providers"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// currentOSINT keeps a project's latest OSINT collection, and the results
+// from before collections were dated
+func currentOSINT(db *gorm.DB) *gorm.DB {
+	return db.Where("collected_at IS NULL OR collected_at = (SELECT osint_collected_at FROM projects WHERE projects.id = osint_results.project_id)")
+}
+
+// osintCollection is one collection of a project's OSINT
+type osintCollection struct {
+	CollectedAt *time.Time     `json:"collected_at"`
+	Results     int            `json:"results"`
+	Exposure    map[string]int `json:"exposure"`
+}
+
+// GetOSINT returns an analysis' latest OSINT collection, or the one of
+// ?collected_at (RFC 3339), and the history of its collections with their
+// exposure per source
+func (h *Handler) GetOSINT(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var all []models.OSINTResult
+	if err := h.db.Where("project_id = ?", project.ID).Order("collected_at, source, confidence_score DESC").Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	selected := project.OSINTCollectedAt
+	if value := c.Query("collected_at"); value != "" {
+		collectedAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid collected_at",
+				"message": "collected_at must be an RFC 3339 time",
+			})
+			return
+		}
+		selected = &collectedAt
+	}
+	sameCollection := func(a, b *time.Time) bool {
+		return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+	}
+
+	var collections []osintCollection
+	byCollection := make(map[int][]models.OSINTResult)
+	results := []models.OSINTResult{}
+	for _, result := range all {
+		i := len(collections) - 1
+		if i < 0 || !sameCollection(collections[i].CollectedAt, result.CollectedAt) {
+			collections = append(collections, osintCollection{CollectedAt: result.CollectedAt})
+			i++
+		}
+		collections[i].Results++
+		byCollection[i] = append(byCollection[i], result)
+		// Undated results are the analysis' own, shown until a refresh
+		if sameCollection(result.CollectedAt, selected) || result.CollectedAt == nil && c.Query("collected_at") == "" {
+			results = append(results, result)
+		}
+	}
+	for i := range collections {
+		collections[i].Exposure = osint.Exposure(byCollection[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":       project.ID,
+		"collected_at": selected,
+		"results":      results,
+		"total":        len(results),
+		"collections":  collections,
+	})
+}
+
+// GetOSINTQuotas reports today's budget of the OSINT providers: calls made,
+// the daily quota and what remains of it, the rate limit, and when the
+// quotas reset; and the projects whose OSINT waits for a reset
+func (h *Handler) GetOSINTQuotas(c *gin.Context) {
+	quotas, err := osint.Quotas(h.db, h.config, providers.New(h.db, h.config).Names())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var deferred int64
+	if err := h.db.Model(&models.Project{}).Where("osint_deferred_until IS NOT NULL").Count(&deferred).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"quotas":            quotas,
+		"resets_at":         osint.ResetsAt(),
+		"deferred_projects": deferred,
+	})
+}
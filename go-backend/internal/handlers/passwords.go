@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetPasswordHashes returns the password hashes found in an analysis'
+// firmware with the outcome of cracking them. ?status=cracked lists only
+// the default credentials.
+func (h *Handler) GetPasswordHashes(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.db.Where("project_id = ?", project.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("crack_status = ?", status)
+	}
+	hashes := []models.PasswordHash{}
+	if err := query.Order("file_path, username").Find(&hashes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	cracked := 0
+	for _, hash := range hashes {
+		if hash.CrackStatus == models.CrackCracked {
+			cracked++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":          jobID,
+		"password_hashes": hashes,
+		"count":           len(hashes),
+		"cracked":         cracked,
+	})
+}
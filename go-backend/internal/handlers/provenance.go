@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultContextLines = 5
+	maxContextLines     = 50
+)
+
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// LogLine is one line of an EMBA log
+type LogLine struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+}
+
+// GetFindingContext returns the EMBA log lines around the one a finding was
+// parsed from, so analysts can verify it without opening the logs
+func (h *Handler) GetFindingContext(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var finding models.Finding
+	if err := h.db.First(&finding, "id = ? AND project_id = ?", c.Param("finding_id"), jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Finding not found",
+				"message": "No finding with this ID in the analysis",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if finding.SourceFile == "" || finding.SourceLine == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "No provenance",
+			"message": "The finding wasn't parsed from a known EMBA log line",
+		})
+		return
+	}
+
+	radius, err := strconv.Atoi(c.DefaultQuery("lines", strconv.Itoa(defaultContextLines)))
+	if err != nil || radius < 0 || radius > maxContextLines {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid lines",
+			"message": "lines must be between 0 and " + strconv.Itoa(maxContextLines),
+		})
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// SourceFile comes from the database, but keep it inside the log directory
+	logDir := h.analysisLogDir(&project)
+	path := filepath.Join(logDir, filepath.FromSlash(finding.SourceFile))
+	if relative, err := filepath.Rel(logDir, path); err != nil || strings.HasPrefix(relative, "..") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid source file",
+			"message": "The finding's source file is outside the analysis log directory",
+		})
+		return
+	}
+
+	lines, err := readLogContext(path, finding.SourceLine, radius)
+	if err != nil {
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "Log unavailable",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"finding_id":  finding.ID,
+		"module":      finding.Module,
+		"source_file": finding.SourceFile,
+		"source_line": finding.SourceLine,
+		"lines":       lines,
+	})
+}
+
+// analysisLogDir returns the EMBA log directory of an analysis
+func (h *Handler) analysisLogDir(project *models.Project) string {
+	if logDir, ok := project.ExtractionData()["emba_log_dir"].(string); ok && logDir != "" {
+		return logDir
+	}
+	return filepath.Join(h.config.EMBALogDir, "job_"+project.ID)
+}
+
+// readLogContext reads the lines within radius of line, stripping EMBA's
+// terminal colors
+func readLogContext(path string, line, radius int) ([]LogLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []LogLine{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for number := 1; scanner.Scan() && number <= line+radius; number++ {
+		if number >= line-radius {
+			lines = append(lines, LogLine{Number: number, Text: ansiEscapeRegex.ReplaceAllString(scanner.Text(), "")})
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSBOM returns the software components of an analysis with the CVE
+// findings linked to each of them
+func (h *Handler) GetSBOM(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var components []models.SBOMComponent
+	if err := h.db.Preload("CVEFindings.CVE").Where("project_id = ?", project.ID).Order("name, version").Find(&components).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var unlinked int64
+	if err := h.db.Model(&models.CVEFinding{}).Where("project_id = ? AND component_id IS NULL", project.ID).Count(&unlinked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	vulnerable := 0
+	for _, component := range components {
+		if len(component.CVEFindings) > 0 {
+			vulnerable++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":                jobID,
+		"total_components":      len(components),
+		"vulnerable_components": vulnerable,
+		"unlinked_cves":         unlinked,
+		"components":            components,
+	})
+}
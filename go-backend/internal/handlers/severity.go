@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type severityOverrideRequest struct {
+	Severity string `json:"severity" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+// OverrideFindingSeverity sets an analyst's severity on a finding of a
+// completed analysis, keeping the one the parser assigned, and rates the
+// project again
+func (h *Handler) OverrideFindingSeverity(c *gin.Context) {
+	project, finding, ok := h.findFinding(c)
+	if !ok || !triageable(c, &project) {
+		return
+	}
+	severity, reason, ok := bindSeverityOverride(c)
+	if !ok {
+		return
+	}
+
+	previous := finding.Severity
+	if finding.SeverityOverrideAt == nil {
+		finding.OriginalSeverity = finding.Severity
+	}
+	now := time.Now().UTC()
+	finding.Severity = severity
+	finding.SeverityReason = reason
+	finding.SeverityOverrideBy = requestActor(c)
+	finding.SeverityOverrideAt = &now
+	if !h.saveSeverity(c, &models.Finding{}, finding.ID, project.ID, map[string]interface{}{
+		"severity":             finding.Severity,
+		"original_severity":    finding.OriginalSeverity,
+		"severity_reason":      finding.SeverityReason,
+		"severity_override_by": finding.SeverityOverrideBy,
+		"severity_override_at": finding.SeverityOverrideAt,
+	}) {
+		return
+	}
+	h.auditSeverity(c, "finding.severity_override", "finding", finding.ID, project.ID, previous, finding.Severity, finding.OriginalSeverity, reason)
+
+	c.JSON(http.StatusOK, finding)
+}
+
+// ResetFindingSeverity drops the analyst's severity of a finding, restoring
+// the parser's
+func (h *Handler) ResetFindingSeverity(c *gin.Context) {
+	project, finding, ok := h.findFinding(c)
+	if !ok || !triageable(c, &project) {
+		return
+	}
+	if finding.SeverityOverrideAt == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "No severity override",
+			"message": "The finding has the severity the parser assigned",
+		})
+		return
+	}
+
+	previous := finding.Severity
+	finding.Severity = finding.OriginalSeverity
+	finding.OriginalSeverity, finding.SeverityReason, finding.SeverityOverrideBy, finding.SeverityOverrideAt = "", "", "", nil
+	if !h.saveSeverity(c, &models.Finding{}, finding.ID, project.ID, map[string]interface{}{
+		"severity":             finding.Severity,
+		"original_severity":    "",
+		"severity_reason":      "",
+		"severity_override_by": "",
+		"severity_override_at": nil,
+	}) {
+		return
+	}
+	h.auditSeverity(c, "finding.severity_reset", "finding", finding.ID, project.ID, previous, finding.Severity, finding.Severity, "")
+
+	c.JSON(http.StatusOK, finding)
+}
+
+// OverrideCVESeverity sets an analyst's severity on a CVE finding of a
+// completed analysis, keeping the one EMBA or NVD assigned, and rates the
+// project again
+func (h *Handler) OverrideCVESeverity(c *gin.Context) {
+	project, finding, ok := h.findCVEFinding(c)
+	if !ok || !triageable(c, &project) {
+		return
+	}
+	severity, reason, ok := bindSeverityOverride(c)
+	if !ok {
+		return
+	}
+
+	previous := finding.SeverityLevel
+	if finding.SeverityOverrideAt == nil {
+		finding.OriginalSeverity = finding.SeverityLevel
+	}
+	now := time.Now().UTC()
+	finding.SeverityLevel = severity
+	finding.SeverityReason = reason
+	finding.SeverityOverrideBy = requestActor(c)
+	finding.SeverityOverrideAt = &now
+	if !h.saveSeverity(c, &models.CVEFinding{}, finding.ID, project.ID, map[string]interface{}{
+		"severity_level":       finding.SeverityLevel,
+		"original_severity":    finding.OriginalSeverity,
+		"severity_reason":      finding.SeverityReason,
+		"severity_override_by": finding.SeverityOverrideBy,
+		"severity_override_at": finding.SeverityOverrideAt,
+	}) {
+		return
+	}
+	h.auditSeverity(c, "cve_finding.severity_override", "cve_finding", finding.ID, project.ID, previous, finding.SeverityLevel, finding.OriginalSeverity, reason)
+
+	c.JSON(http.StatusOK, finding)
+}
+
+// ResetCVESeverity drops the analyst's severity of a CVE finding,
+// restoring the one EMBA or NVD assigned
+func (h *Handler) ResetCVESeverity(c *gin.Context) {
+	project, finding, ok := h.findCVEFinding(c)
+	if !ok || !triageable(c, &project) {
+		return
+	}
+	if finding.SeverityOverrideAt == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "No severity override",
+			"message": "The CVE finding has the severity EMBA or NVD assigned",
+		})
+		return
+	}
+
+	previous := finding.SeverityLevel
+	finding.SeverityLevel = finding.OriginalSeverity
+	finding.OriginalSeverity, finding.SeverityReason, finding.SeverityOverrideBy, finding.SeverityOverrideAt = "", "", "", nil
+	if !h.saveSeverity(c, &models.CVEFinding{}, finding.ID, project.ID, map[string]interface{}{
+		"severity_level":       finding.SeverityLevel,
+		"original_severity":    "",
+		"severity_reason":      "",
+		"severity_override_by": "",
+		"severity_override_at": nil,
+	}) {
+		return
+	}
+	h.auditSeverity(c, "cve_finding.severity_reset", "cve_finding", finding.ID, project.ID, previous, finding.SeverityLevel, finding.SeverityLevel, "")
+
+	c.JSON(http.StatusOK, finding)
+}
+
+// findFinding loads the analysis and the finding in the URL
+func (h *Handler) findFinding(c *gin.Context) (models.Project, models.Finding, bool) {
+	var project models.Project
+	var finding models.Finding
+
+	if err := h.db.First(&project, "id = ?", c.Param("job_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return project, finding, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return project, finding, false
+	}
+
+	if err := h.db.First(&finding, "id = ? AND project_id = ?", c.Param("finding_id"), project.ID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Finding not found",
+				"message": "No finding with this ID in the analysis",
+			})
+			return project, finding, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return project, finding, false
+	}
+
+	return project, finding, true
+}
+
+// triageable rejects severity and triage changes to frozen projects and to
+// analyses still running, whose final results replace the partial ones
+func triageable(c *gin.Context, project *models.Project) bool {
+	if rejectFrozen(c, project) {
+		return false
+	}
+	if project.Status != models.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Analysis not completed",
+			"message": "Findings can be triaged once the analysis completed",
+		})
+		return false
+	}
+	return true
+}
+
+// bindSeverityOverride reads the severity and the reason for it from the request body
+func bindSeverityOverride(c *gin.Context) (models.RiskLevel, string, bool) {
+	var request severityOverrideRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return "", "", false
+	}
+	severity := models.RiskLevel(strings.ToLower(strings.TrimSpace(request.Severity)))
+	if severity.Rank() == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid severity",
+			"message": "severity must be info, low, medium, high or critical",
+		})
+		return "", "", false
+	}
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid severity override",
+			"message": "reason is required",
+		})
+		return "", "", false
+	}
+	return severity, reason, true
+}
+
+// saveSeverity updates the severity columns of a finding or CVE finding
+// and recounts the project's risk level with it
+func (h *Handler) saveSeverity(c *gin.Context, model interface{}, id uint, projectID string, columns map[string]interface{}) bool {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(model).Where("id = ?", id).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+		return risk.Recount(tx, projectID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update severity",
+			"message": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// auditSeverity records who changed a severity, from what to what and why
+func (h *Handler) auditSeverity(c *gin.Context, action, resource string, id uint, projectID string, from, to, original models.RiskLevel, reason string) {
+	resourceID := strconv.FormatUint(uint64(id), 10)
+	if err := audit.Record(h.db, requestActor(c), action, resource, resourceID, map[string]interface{}{
+		"project_id":        projectID,
+		"from":              from,
+		"to":                to,
+		"original_severity": original,
+		"reason":            reason,
+	}); err != nil {
+		log.Printf("Failed to audit severity change of %s %s: %v", resource, resourceID, err)
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"odin-backend/internal/fuzzyhash"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMinSimilarity = 50
+	defaultSimilarLimit  = 20
+	maxSimilarLimit      = 200
+)
+
+// similarBinary is a binary of another project that resembles the one
+// searched for
+type similarBinary struct {
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	FilePath    string `json:"file_path"`
+	SHA256      string `json:"sha256"`
+	SSDeep      string `json:"ssdeep"`
+	Score       int    `json:"score"`     // ssdeep similarity, 0-100
+	Identical   bool   `json:"identical"` // same SHA-256
+}
+
+// FindSimilarBinaries returns, for the binaries of an analysis (or only the
+// one in ?path=), the binaries of other projects with a similar ssdeep hash.
+// ?min_score= (default 50) and ?limit= (matches per binary, default 20)
+// narrow the results.
+func (h *Handler) FindSimilarBinaries(c *gin.Context) {
+	jobID := c.Param("job_id")
+	minScore, limit, ok := similarityParams(c)
+	if !ok {
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	query := h.db.Where("project_id = ?", project.ID)
+	if path := c.Query("path"); path != "" {
+		query = query.Where("file_path = ?", path)
+	}
+	var hashes []models.BinaryHash
+	if err := query.Order("file_path").Find(&hashes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	binaries := make([]gin.H, 0, len(hashes))
+	for _, hash := range hashes {
+		matches, err := h.similarBinaries(hash.SSDeep, hash.SHA256, project.ID, minScore, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Database error",
+				"message": err.Error(),
+			})
+			return
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		binaries = append(binaries, gin.H{
+			"file_path": hash.FilePath,
+			"sha256":    hash.SHA256,
+			"ssdeep":    hash.SSDeep,
+			"similar":   matches,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":    jobID,
+		"min_score": minScore,
+		"binaries":  binaries,
+		"total":     len(binaries),
+	})
+}
+
+// SearchSimilarBinaries returns the binaries of all projects similar to an
+// ssdeep hash (?ssdeep=) or identical to a SHA-256 (?sha256=)
+func (h *Handler) SearchSimilarBinaries(c *gin.Context) {
+	minScore, limit, ok := similarityParams(c)
+	if !ok {
+		return
+	}
+	ssdeep, sha256 := c.Query("ssdeep"), c.Query("sha256")
+	if ssdeep == "" && sha256 == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Missing hash",
+			"message": "ssdeep or sha256 is required",
+		})
+		return
+	}
+	if ssdeep != "" {
+		if _, err := fuzzyhash.BlockSize(ssdeep); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid hash",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	matches, err := h.similarBinaries(ssdeep, sha256, "", minScore, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"min_score": minScore,
+		"similar":   matches,
+		"total":     len(matches),
+	})
+}
+
+// similarBinaries compares an ssdeep hash with the stored hashes of
+// compatible block size, outside excludeProject, best matches first
+func (h *Handler) similarBinaries(ssdeep, sha256, excludeProject string, minScore, limit int) ([]similarBinary, error) {
+	query := h.db.Model(&models.BinaryHash{})
+	if excludeProject != "" {
+		query = query.Where("project_id <> ?", excludeProject)
+	}
+	blockSize, err := fuzzyhash.BlockSize(ssdeep)
+	switch {
+	case err == nil && sha256 != "":
+		query = query.Where("block_size IN ? OR sha256 = ?", []uint64{blockSize / 2, blockSize, blockSize * 2}, sha256)
+	case err == nil:
+		query = query.Where("block_size IN ?", []uint64{blockSize / 2, blockSize, blockSize * 2})
+	default:
+		query = query.Where("sha256 = ?", sha256)
+	}
+
+	var candidates []models.BinaryHash
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	matches := []similarBinary{}
+	projectIDs := make(map[string]bool)
+	for _, candidate := range candidates {
+		identical := sha256 != "" && candidate.SHA256 == sha256
+		score := 0
+		if identical {
+			score = 100
+		} else if ssdeep != "" {
+			score, _ = fuzzyhash.Compare(ssdeep, candidate.SSDeep)
+		}
+		if score < minScore {
+			continue
+		}
+		matches = append(matches, similarBinary{
+			ProjectID: candidate.ProjectID,
+			FilePath:  candidate.FilePath,
+			SHA256:    candidate.SHA256,
+			SSDeep:    candidate.SSDeep,
+			Score:     score,
+			Identical: identical,
+		})
+		projectIDs[candidate.ProjectID] = true
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ProjectID < matches[j].ProjectID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	ids := make([]string, 0, len(projectIDs))
+	for id := range projectIDs {
+		ids = append(ids, id)
+	}
+	var projects []models.Project
+	if err := h.db.Select("id, name").Where("id IN ?", ids).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(projects))
+	for _, project := range projects {
+		names[project.ID] = project.Name
+	}
+	for i := range matches {
+		matches[i].ProjectName = names[matches[i].ProjectID]
+	}
+	return matches, nil
+}
+
+// similarityParams reads ?min_score= and ?limit=, writing the error response
+// when they're invalid
+func similarityParams(c *gin.Context) (int, int, bool) {
+	minScore, err := strconv.Atoi(c.DefaultQuery("min_score", strconv.Itoa(defaultMinSimilarity)))
+	if err != nil || minScore < 1 || minScore > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid min_score",
+			"message": "min_score must be between 1 and 100",
+		})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSimilarLimit)))
+	if err != nil || limit < 1 || limit > maxSimilarLimit {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid limit",
+			"message": "limit must be between 1 and 200",
+		})
+		return 0, 0, false
+	}
+	return minScore, limit, true
+}
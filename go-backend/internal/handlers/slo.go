@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/slo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSLOReport measures analysis turnaround against the configured objectives
+func (h *Handler) GetSLOReport(c *gin.Context) {
+	reports, err := slo.Measure(h.db, h.config.SLOObjectives, h.config.SLOWindow, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to measure SLOs",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window":     h.config.SLOWindow.String(),
+		"objectives": reports,
+	})
+}
+
+// Metrics exposes analysis and SLO gauges in the Prometheus text format
+func (h *Handler) Metrics(c *gin.Context) {
+	var b strings.Builder
+
+	var statusCounts []struct {
+		Status models.ProjectStatus
+		Count  int64
+	}
+	if err := h.db.Model(&models.Project{}).Select("status, COUNT(*) AS count").Group("status").Scan(&statusCounts).Error; err != nil {
+		c.String(http.StatusInternalServerError, "# failed to count analyses: %v\n", err)
+		return
+	}
+	b.WriteString("# HELP odin_analyses Analyses by status.\n# TYPE odin_analyses gauge\n")
+	for _, row := range statusCounts {
+		fmt.Fprintf(&b, "odin_analyses{status=%q} %d\n", row.Status, row.Count)
+	}
+
+	reports, err := slo.Measure(h.db, h.config.SLOObjectives, h.config.SLOWindow, time.Now())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "# failed to measure SLOs: %v\n", err)
+		return
+	}
+	gauges := []struct {
+		name, help string
+		value      func(slo.Report) float64
+	}{
+		{"odin_slo_target_ratio", "Share of analyses that must complete within the threshold.", func(r slo.Report) float64 { return r.Target }},
+		{"odin_slo_threshold_seconds", "Turnaround threshold of the objective.", func(r slo.Report) float64 { return r.ThresholdSeconds }},
+		{"odin_slo_attainment_ratio", "Share of analyses in the window that completed within the threshold.", func(r slo.Report) float64 { return r.Attainment }},
+		{"odin_slo_error_budget_remaining_ratio", "Share of the allowed breaches left in the window.", func(r slo.Report) float64 { return r.ErrorBudgetRemaining }},
+		{"odin_slo_analyses", "Analyses in the window counted against the objective.", func(r slo.Report) float64 { return float64(r.Total) }},
+		{"odin_slo_breaches", "Analyses in the window that missed the threshold.", func(r slo.Report) float64 { return float64(r.Breached) }},
+		{"odin_slo_turnaround_p95_seconds", "95th percentile turnaround of completed analyses in the window.", func(r slo.Report) float64 { return r.P95Turnaround }},
+	}
+	for _, gauge := range gauges {
+		if len(reports) == 0 {
+			break
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", gauge.name, gauge.help, gauge.name)
+		for _, report := range reports {
+			fmt.Fprintf(&b, "%s{objective=%q,scan_profile=%q} %g\n", gauge.name, report.Name, report.ScanProfile, gauge.value(report))
+		}
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
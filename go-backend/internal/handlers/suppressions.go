@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/models"
+	"odin-backend/internal/suppress"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var cveIDRegex = regexp.MustCompile(`^CVE-\d{4}-\d{4,}$`)
+
+type suppressionRequest struct {
+	ProjectID     *string    `json:"project_id"`
+	CVEID         *string    `json:"cve_id"`
+	Component     *string    `json:"component"`
+	Justification *string    `json:"justification"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	NeverExpires  bool       `json:"never_expires"` // clears expires_at on update
+}
+
+// ListSuppressions returns the CVE suppression rules of the requesting
+// organization. ?project_id narrows them to the rules covering a project,
+// its own and the global ones, ?cve_id to a CVE and ?active=true to the
+// rules in force.
+func (h *Handler) ListSuppressions(c *gin.Context) {
+	query := h.db.Where("org_id = ?", requestOrgID(c))
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = '' OR project_id = ?", projectID)
+	}
+	if cveID := strings.TrimSpace(c.Query("cve_id")); cveID != "" {
+		query = query.Where("cve_id = ?", strings.ToUpper(cveID))
+	}
+	if c.Query("active") == "true" {
+		query = query.Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC())
+	}
+
+	var rules []models.CVESuppression
+	if err := query.Order("id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suppressions": rules,
+		"total":        len(rules),
+	})
+}
+
+// CreateSuppression accepts the risk of a CVE, in one project or, without a
+// project_id, in every project of the organization, and suppresses the
+// CVE findings it matches
+func (h *Handler) CreateSuppression(c *gin.Context) {
+	actor := requestActor(c)
+	rule := models.CVESuppression{
+		OrgID:     requestOrgID(c),
+		CreatedBy: actor,
+		UpdatedBy: actor,
+	}
+	if !h.bindSuppression(c, &rule) {
+		return
+	}
+
+	if err := h.db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create suppression",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.auditSuppression(c, "suppression.create", rule)
+	h.applySuppression(&rule, nil)
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateSuppression changes the scope, justification or expiry of a rule
+func (h *Handler) UpdateSuppression(c *gin.Context) {
+	rule, ok := h.findSuppression(c)
+	if !ok {
+		return
+	}
+	previous, err := suppress.Projects(h.db, &rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if !h.bindSuppression(c, &rule) {
+		return
+	}
+	rule.UpdatedBy = requestActor(c)
+
+	if err := h.db.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update suppression",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.auditSuppression(c, "suppression.update", rule)
+	h.applySuppression(&rule, previous)
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteSuppression removes a rule, which lifts the suppression of the CVE
+// findings it matched
+func (h *Handler) DeleteSuppression(c *gin.Context) {
+	rule, ok := h.findSuppression(c)
+	if !ok {
+		return
+	}
+	previous, err := suppress.Projects(h.db, &rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.db.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete suppression",
+			"message": err.Error(),
+		})
+		return
+	}
+	h.auditSuppression(c, "suppression.delete", rule)
+	for _, projectID := range previous {
+		if err := suppress.Apply(h.db, projectID); err != nil {
+			log.Printf("Failed to lift CVE suppression %d in project %s: %v", rule.ID, projectID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Suppression deleted successfully",
+	})
+}
+
+// findSuppression loads the rule in the URL, scoped to the requesting organization
+func (h *Handler) findSuppression(c *gin.Context) (models.CVESuppression, bool) {
+	var rule models.CVESuppression
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid suppression ID",
+			"message": err.Error(),
+		})
+		return rule, false
+	}
+
+	if err := h.db.Where("org_id = ?", requestOrgID(c)).First(&rule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Suppression not found",
+				"message": "No CVE suppression with this ID",
+			})
+			return rule, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return rule, false
+	}
+
+	return rule, true
+}
+
+// bindSuppression applies the fields present in the request body and validates the result
+func (h *Handler) bindSuppression(c *gin.Context, rule *models.CVESuppression) bool {
+	var request suppressionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return false
+	}
+	invalid := func(message string) bool {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid suppression",
+			"message": message,
+		})
+		return false
+	}
+
+	if request.ProjectID != nil {
+		rule.ProjectID = strings.TrimSpace(*request.ProjectID)
+	}
+	if request.CVEID != nil {
+		rule.CVEID = strings.ToUpper(strings.TrimSpace(*request.CVEID))
+	}
+	if request.Component != nil {
+		rule.Component = strings.TrimSpace(*request.Component)
+	}
+	if request.Justification != nil {
+		rule.Justification = strings.TrimSpace(*request.Justification)
+	}
+	if request.ExpiresAt != nil {
+		expires := request.ExpiresAt.UTC()
+		if !expires.After(time.Now().UTC()) {
+			return invalid("expires_at must be in the future")
+		}
+		rule.ExpiresAt = &expires
+	} else if request.NeverExpires {
+		rule.ExpiresAt = nil
+	}
+
+	if !cveIDRegex.MatchString(rule.CVEID) {
+		return invalid("cve_id must be a CVE ID, e.g. CVE-2021-44228")
+	}
+	if rule.Justification == "" {
+		return invalid("justification is required")
+	}
+	if rule.ProjectID != "" {
+		var count int64
+		if err := h.db.Model(&models.Project{}).Where("id = ? AND org_id = ?", rule.ProjectID, rule.OrgID).
+			Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Database error",
+				"message": err.Error(),
+			})
+			return false
+		}
+		if count == 0 {
+			return invalid("project_id must be a project of the organization")
+		}
+	}
+	return true
+}
+
+// applySuppression applies the rules again to the projects a rule covers
+// now and those it covered before a change. The rule is saved by then, so a
+// failure is only logged; saving the rule again retries it.
+func (h *Handler) applySuppression(rule *models.CVESuppression, previous []string) {
+	current, err := suppress.Projects(h.db, rule)
+	if err != nil {
+		log.Printf("Failed to apply CVE suppression %d: %v", rule.ID, err)
+		return
+	}
+	seen := make(map[string]bool)
+	for _, projectID := range append(previous, current...) {
+		if seen[projectID] {
+			continue
+		}
+		seen[projectID] = true
+		if err := suppress.Apply(h.db, projectID); err != nil {
+			log.Printf("Failed to apply CVE suppression %d to project %s: %v", rule.ID, projectID, err)
+		}
+	}
+}
+
+// auditSuppression records who changed a rule and how
+func (h *Handler) auditSuppression(c *gin.Context, action string, rule models.CVESuppression) {
+	id := strconv.FormatUint(uint64(rule.ID), 10)
+	if err := audit.Record(h.db, requestActor(c), action, "cve_suppression", id, map[string]interface{}{
+		"project_id":    rule.ProjectID,
+		"cve_id":        rule.CVEID,
+		"component":     rule.Component,
+		"justification": rule.Justification,
+		"expires_at":    rule.ExpiresAt,
+	}); err != nil {
+		log.Printf("Failed to audit change of CVE suppression %s: %v", id, err)
+	}
+}
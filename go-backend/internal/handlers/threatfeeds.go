@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type threatFeedRequest struct {
+	Name         *string `json:"name"`
+	APIRoot      *string `json:"api_root"`
+	CollectionID *string `json:"collection_id"`
+	Username     *string `json:"username"`
+	Password     *string `json:"password"`
+	Enabled      *bool   `json:"enabled"`
+}
+
+// ListThreatFeeds returns the TAXII threat feeds, without their passwords
+func (h *Handler) ListThreatFeeds(c *gin.Context) {
+	var feeds []models.ThreatFeed
+	if err := h.db.Order("id").Find(&feeds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"threat_feeds": feeds,
+		"total":        len(feeds),
+	})
+}
+
+// CreateThreatFeed subscribes to a TAXII collection. The worker polls it
+// within a minute.
+func (h *Handler) CreateThreatFeed(c *gin.Context) {
+	feed := models.ThreatFeed{Enabled: true, CreatedBy: requestActor(c)}
+	if !h.bindThreatFeed(c, &feed) {
+		return
+	}
+	if err := h.db.Create(&feed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create threat feed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.auditThreatFeed(c, "threat_feed.create", feed)
+	c.JSON(http.StatusCreated, feed)
+}
+
+// UpdateThreatFeed changes a feed's collection or credentials, or enables
+// or disables it. Indicators of a disabled feed no longer match.
+func (h *Handler) UpdateThreatFeed(c *gin.Context) {
+	feed, ok := h.findThreatFeed(c)
+	if !ok {
+		return
+	}
+	collection := feed.APIRoot + "|" + feed.CollectionID
+	if !h.bindThreatFeed(c, &feed) {
+		return
+	}
+	// Another collection is polled from its start
+	if feed.APIRoot+"|"+feed.CollectionID != collection {
+		feed.AddedAfter = ""
+		feed.LastPolledAt = nil
+	}
+	if err := h.db.Save(&feed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update threat feed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.auditThreatFeed(c, "threat_feed.update", feed)
+	c.JSON(http.StatusOK, feed)
+}
+
+// DeleteThreatFeed unsubscribes from a feed and removes its indicators.
+// The findings its indicators raised stay.
+func (h *Handler) DeleteThreatFeed(c *gin.Context) {
+	feed, ok := h.findThreatFeed(c)
+	if !ok {
+		return
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("feed_id = ?", feed.ID).Delete(&models.ThreatIndicator{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&feed).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete threat feed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.auditThreatFeed(c, "threat_feed.delete", feed)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Threat feed deleted successfully",
+	})
+}
+
+// PollThreatFeed makes the worker poll a feed within a minute instead of
+// at its next interval
+func (h *Handler) PollThreatFeed(c *gin.Context) {
+	feed, ok := h.findThreatFeed(c)
+	if !ok {
+		return
+	}
+	if !feed.Enabled {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Threat feed disabled",
+			"message": "Enable the feed to poll it",
+		})
+		return
+	}
+	if err := h.db.Model(&feed).Update("last_polled_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.auditThreatFeed(c, "threat_feed.poll", feed)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Threat feed will be polled within a minute",
+	})
+}
+
+// findThreatFeed loads the feed in the URL
+func (h *Handler) findThreatFeed(c *gin.Context) (models.ThreatFeed, bool) {
+	var feed models.ThreatFeed
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid threat feed ID",
+			"message": err.Error(),
+		})
+		return feed, false
+	}
+
+	if err := h.db.First(&feed, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Threat feed not found",
+				"message": "No threat feed with this ID",
+			})
+			return feed, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return feed, false
+	}
+
+	return feed, true
+}
+
+// bindThreatFeed applies the fields present in the request body, sealing a
+// new password, and validates the result. An empty password removes it.
+func (h *Handler) bindThreatFeed(c *gin.Context, feed *models.ThreatFeed) bool {
+	var request threatFeedRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	invalid := func(message string) bool {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid threat feed",
+			"message": message,
+		})
+		return false
+	}
+
+	if request.Name != nil {
+		feed.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.APIRoot != nil {
+		feed.APIRoot = strings.TrimSpace(*request.APIRoot)
+	}
+	if request.CollectionID != nil {
+		feed.CollectionID = strings.TrimSpace(*request.CollectionID)
+	}
+	if request.Username != nil {
+		feed.Username = strings.TrimSpace(*request.Username)
+	}
+	if request.Enabled != nil {
+		feed.Enabled = *request.Enabled
+	}
+	if request.Password != nil {
+		feed.Password = ""
+		if *request.Password != "" {
+			// A password is stored encrypted like the OSINT API keys
+			keys, ok := h.keyring(c)
+			if !ok {
+				return false
+			}
+			sealed, err := keys.Seal(*request.Password)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to encrypt password",
+					"message": err.Error(),
+				})
+				return false
+			}
+			feed.Password = sealed
+		}
+	}
+
+	if feed.Name == "" {
+		return invalid("name is required")
+	}
+	if feed.CollectionID == "" {
+		return invalid("collection_id is required")
+	}
+	if root, err := url.Parse(feed.APIRoot); err != nil || (root.Scheme != "https" && root.Scheme != "http") || root.Host == "" {
+		return invalid("api_root must be the http(s) URL of a TAXII 2.1 API root")
+	}
+	return true
+}
+
+// auditThreatFeed records a change of a feed, never its password
+func (h *Handler) auditThreatFeed(c *gin.Context, action string, feed models.ThreatFeed) {
+	id := strconv.FormatUint(uint64(feed.ID), 10)
+	if err := audit.Record(h.db, requestActor(c), action, "threat_feed", id, map[string]interface{}{
+		"name":          feed.Name,
+		"api_root":      feed.APIRoot,
+		"collection_id": feed.CollectionID,
+		"username":      feed.Username,
+		"has_password":  feed.Password != "",
+		"enabled":       feed.Enabled,
+	}); err != nil {
+		log.Printf("Failed to audit change of threat feed %s: %v", id, err)
+	}
+}
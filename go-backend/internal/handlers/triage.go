@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/cwe"
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findingFilter selects findings of an analysis for a bulk change. All
+// criteria set must match; All selects every finding when none is set.
+type findingFilter struct {
+	All          bool     `json:"all"`
+	IDs          []uint   `json:"ids"`
+	Type         string   `json:"type"`
+	Severity     []string `json:"severity"`
+	Module       string   `json:"module"`
+	Confidence   string   `json:"confidence"`
+	CWE          string   `json:"cwe"`
+	Title        string   `json:"title"` // part of the title, any case
+	TriageStatus *string  `json:"triage_status"`
+	Assignee     *string  `json:"assignee"` // "" for unassigned
+	Label        string   `json:"label"`
+}
+
+// findingChanges are the changes a bulk request applies, those set only
+type findingChanges struct {
+	TriageStatus *string `json:"triage_status"`
+	Severity     *string `json:"severity"`
+	Assignee     *string `json:"assignee"` // "" unassigns
+}
+
+type bulkFindingsRequest struct {
+	Filter findingFilter  `json:"filter"`
+	Set    findingChanges `json:"set"`
+	Reason string         `json:"reason"` // required with a severity
+	DryRun bool           `json:"dry_run"`
+}
+
+// BulkUpdateFindings applies triage status, severity and assignee changes
+// to the findings of a completed analysis a filter matches, e.g. every
+// informational version_detection finding marked reviewed, in one
+// transaction. A severity is an analyst's override, as for a single
+// finding, and rates the project again. dry_run only counts the findings.
+func (h *Handler) BulkUpdateFindings(c *gin.Context) {
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", c.Param("job_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if !triageable(c, &project) {
+		return
+	}
+
+	var request bulkFindingsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return
+	}
+	request.Reason = strings.TrimSpace(request.Reason)
+	columns, err := request.columns(requestActor(c), time.Now().UTC())
+	if err == nil {
+		err = request.Filter.validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid bulk update",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var matched int64
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		query := request.Filter.apply(tx.Model(&models.Finding{}).Where("project_id = ? AND partial = ?", project.ID, false))
+		if err := query.Count(&matched).Error; err != nil {
+			return err
+		}
+		if request.DryRun || matched == 0 {
+			return nil
+		}
+		if err := request.Filter.apply(tx.Model(&models.Finding{}).Where("project_id = ? AND partial = ?", project.ID, false)).
+			UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+		if request.Set.Severity == nil {
+			return nil
+		}
+		return risk.Recount(tx, project.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update findings",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if !request.DryRun && matched > 0 {
+		if err := audit.Record(h.db, requestActor(c), "finding.bulk_update", "project", project.ID, map[string]interface{}{
+			"filter":   request.Filter,
+			"set":      request.Set,
+			"reason":   request.Reason,
+			"findings": matched,
+		}); err != nil {
+			log.Printf("Failed to audit bulk update of findings of project %s: %v", project.ID, err)
+		}
+	}
+
+	updated := matched
+	if request.DryRun {
+		updated = 0
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":  project.ID,
+		"matched": matched,
+		"updated": updated,
+		"dry_run": request.DryRun,
+	})
+}
+
+// columns validates the changes and returns the columns they set
+func (r *bulkFindingsRequest) columns(actor string, now time.Time) (map[string]interface{}, error) {
+	columns := map[string]interface{}{}
+	if r.Set.TriageStatus != nil {
+		status, err := parseTriageStatus(*r.Set.TriageStatus)
+		if err != nil {
+			return nil, err
+		}
+		columns["triage_status"] = status
+	}
+	if r.Set.Assignee != nil {
+		columns["assignee"] = strings.TrimSpace(*r.Set.Assignee)
+	}
+	if len(columns) > 0 {
+		columns["triaged_by"] = actor
+		columns["triaged_at"] = now
+	}
+	if r.Set.Severity != nil {
+		severity := models.RiskLevel(strings.ToLower(strings.TrimSpace(*r.Set.Severity)))
+		if severity.Rank() == 0 {
+			return nil, fmt.Errorf("set.severity must be info, low, medium, high or critical")
+		}
+		if r.Reason == "" {
+			return nil, fmt.Errorf("reason is required to override severities")
+		}
+		// The parser's severity is kept on the first override
+		columns["original_severity"] = gorm.Expr("CASE WHEN severity_override_at IS NULL THEN severity ELSE original_severity END")
+		columns["severity"] = severity
+		columns["severity_reason"] = r.Reason
+		columns["severity_override_by"] = actor
+		columns["severity_override_at"] = now
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("set needs triage_status, severity or assignee")
+	}
+	return columns, nil
+}
+
+// validate checks the filter's values and that it selects something on
+// purpose: a filter without criteria needs all
+func (f *findingFilter) validate() error {
+	for _, severity := range f.Severity {
+		if models.RiskLevel(strings.ToLower(severity)).Rank() == 0 {
+			return fmt.Errorf("unknown filter.severity %q", severity)
+		}
+	}
+	if f.Confidence != "" && models.Confidence(strings.ToLower(f.Confidence)).Rank() == 0 {
+		return fmt.Errorf("filter.confidence must be low, medium or high")
+	}
+	if f.TriageStatus != nil {
+		if _, err := parseTriageStatus(*f.TriageStatus); err != nil {
+			return fmt.Errorf("filter.%w", err)
+		}
+	}
+	if !f.All && len(f.IDs) == 0 && f.Type == "" && len(f.Severity) == 0 && f.Module == "" && f.Confidence == "" &&
+		f.CWE == "" && f.Title == "" && f.TriageStatus == nil && f.Assignee == nil && f.Label == "" {
+		return fmt.Errorf("filter has no criteria; set filter.all to change every finding of the analysis")
+	}
+	return nil
+}
+
+// apply narrows a findings query to the filter
+func (f *findingFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.IDs) > 0 {
+		query = query.Where("id IN ?", f.IDs)
+	}
+	if f.Type != "" {
+		query = query.Where("type = ?", f.Type)
+	}
+	if len(f.Severity) > 0 {
+		severities := make([]string, 0, len(f.Severity))
+		for _, severity := range f.Severity {
+			severities = append(severities, strings.ToLower(severity))
+		}
+		query = query.Where("severity IN ?", severities)
+	}
+	if f.Module != "" {
+		query = query.Where("module = ?", f.Module)
+	}
+	if f.Confidence != "" {
+		query = query.Where("confidence = ?", strings.ToLower(f.Confidence))
+	}
+	if f.CWE != "" {
+		query = query.Where("cwe = ?", cwe.Normalize(f.CWE))
+	}
+	if f.Title != "" {
+		query = query.Where("LOWER(title) LIKE ?", "%"+strings.ToLower(f.Title)+"%")
+	}
+	if f.TriageStatus != nil {
+		status, _ := parseTriageStatus(*f.TriageStatus)
+		query = query.Where("triage_status = ?", status)
+	}
+	if f.Assignee != nil {
+		query = query.Where("assignee = ?", strings.TrimSpace(*f.Assignee))
+	}
+	if f.Label != "" {
+		query = query.Where("',' || labels || ',' LIKE ?", "%,"+normalizeLabel(f.Label)+",%")
+	}
+	return query
+}
+
+// parseTriageStatus reads a triage status; open is the status of findings
+// not triaged yet
+func parseTriageStatus(value string) (models.TriageStatus, error) {
+	switch status := models.TriageStatus(strings.ToLower(strings.TrimSpace(value))); status {
+	case "open":
+		return models.TriageOpen, nil
+	case models.TriageInProgress, models.TriageReviewed, models.TriageResolved:
+		return status, nil
+	}
+	return "", fmt.Errorf("triage_status must be open, in_progress, reviewed or resolved")
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/vulndb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recentSyncs is how many syncs the status lists
+const recentSyncs = 10
+
+// GetVulnDBStatus returns the state of the local vulnerability data: the
+// last sync and the last successful one, the records stored and last import
+// of every dataset, and the recent syncs with their failures
+func (h *Handler) GetVulnDBStatus(c *gin.Context) {
+	status, err := vulndb.GetStatus(h.db, h.config, recentSyncs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scheduled":       h.config.VulnDBSync,
+		"interval":        h.config.VulnDBSyncInterval.String(),
+		"emba_update":     h.config.VulnDBEMBAUpdateCommand != "",
+		"running":         status.Running,
+		"last_sync":       status.LastSync,
+		"last_success":    status.LastSuccess,
+		"datasets":        status.Datasets,
+		"recent_syncs":    status.RecentSyncs,
+		"recent_failures": status.RecentFailures,
+	})
+}
+
+// StartVulnDBSync starts synchronizing the local vulnerability data in the
+// background: every dataset with a URL and EMBA's CVE database, or those
+// the request names
+func (h *Handler) StartVulnDBSync(c *gin.Context) {
+	var request struct {
+		Datasets []string `json:"datasets"` // nvd, kev, epss, exploitdb, emba
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request format",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	targets, err := vulndb.ParseTargets(request.Datasets)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid datasets",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	actor := requestActor(c)
+	sync, err := vulndb.Start(h.db, h.config, actor, targets)
+	if errors.Is(err, vulndb.ErrAlreadyRunning) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Sync already running",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to start sync",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	id := strconv.FormatUint(uint64(sync.ID), 10)
+	if err := audit.Record(h.db, actor, "vulndb.sync", "vulndb_sync", id, map[string]interface{}{
+		"datasets": targets,
+	}); err != nil {
+		log.Printf("Failed to audit vulnerability database sync %s: %v", id, err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Vulnerability database sync started",
+		"sync":    sync,
+	})
+}
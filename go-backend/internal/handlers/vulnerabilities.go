@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// prioritizedCVE is a CVE finding with its fix priority
+type prioritizedCVE struct {
+	models.CVEFinding
+	Priority float64 `json:"priority"`
+}
+
+// GetPrioritizedVulnerabilities returns an analysis' CVE findings ordered by
+// fix priority, EPSS × CVSS × exploit availability (see
+// CVEFinding.Priority), so what is likely to be exploited comes first.
+// CVEs of equal priority are ordered by CVSS score. ?limit bounds the list;
+// suppressed CVEs are left out unless ?include_suppressed=true.
+func (h *Handler) GetPrioritizedVulnerabilities(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Job not found",
+				"message": "Analysis job not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var cves []models.CVEFinding
+	if err := h.db.Preload("CVE").Where("project_id = ? AND partial = ?", project.ID, false).Find(&cves).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	cves = filterHidden(c, cves)
+
+	vulnerabilities := make([]prioritizedCVE, 0, len(cves))
+	unscored := 0
+	for _, cve := range cves {
+		if cve.EPSSCheckedAt == nil {
+			unscored++
+		}
+		vulnerabilities = append(vulnerabilities, prioritizedCVE{CVEFinding: cve, Priority: cve.Priority()})
+	}
+	sort.SliceStable(vulnerabilities, func(i, j int) bool {
+		a, b := vulnerabilities[i], vulnerabilities[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.SeverityScore != b.SeverityScore {
+			return a.SeverityScore > b.SeverityScore
+		}
+		return a.CVEID < b.CVEID
+	})
+
+	total := len(vulnerabilities)
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed < total {
+			vulnerabilities = vulnerabilities[:parsed]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":          jobID,
+		"vulnerabilities": vulnerabilities,
+		"count":           len(vulnerabilities),
+		"total":           total,
+		// Not scored by EPSS yet; their priority is 0 until they are
+		"epss_pending": unscored,
+	})
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type webhookRequest struct {
+	Name            *string           `json:"name"`
+	URL             *string           `json:"url"`
+	Secret          *string           `json:"secret"`
+	Enabled         *bool             `json:"enabled"`
+	EventTypes      []string          `json:"event_types"`
+	MinSeverity     *models.RiskLevel `json:"min_severity"`
+	FindingTypes    []string          `json:"finding_types"`
+	Fleets          []string          `json:"fleets"`
+	PayloadTemplate *string           `json:"payload_template"`
+}
+
+// ListWebhooks returns the webhook subscriptions of the requesting organization
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	var subs []models.WebhookSubscription
+	if err := h.db.Where("org_id = ?", requestOrgID(c)).Order("id").Find(&subs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response := make([]gin.H, 0, len(subs))
+	for _, sub := range subs {
+		response = append(response, webhookResponse(sub))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": response,
+		"total":    len(subs),
+	})
+}
+
+// CreateWebhook adds a webhook subscription with optional filters
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	sub := models.WebhookSubscription{
+		OrgID:   requestOrgID(c),
+		Enabled: true,
+	}
+	if !h.bindWebhook(c, &sub) {
+		return
+	}
+
+	if err := h.db.Create(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create webhook",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhookResponse(sub))
+}
+
+// UpdateWebhook changes the target, filters or template of a subscription
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	sub, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	if !h.bindWebhook(c, &sub) {
+		return
+	}
+
+	if err := h.db.Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update webhook",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhookResponse(sub))
+}
+
+// DeleteWebhook removes a subscription and its delivery history
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	sub, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	// Alert rules would go silent
+	var rules int64
+	if err := h.db.Model(&models.CVEAlertRule{}).Where("webhook_id = ?", sub.ID).Count(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+	if rules > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Webhook in use",
+			"message": "Delete or move the alert rules notifying this webhook first",
+		})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&sub).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete webhook",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// ListWebhookDeliveries returns the most recent delivery attempts of a subscription
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	sub, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := h.db.Where("subscription_id = ?", sub.ID).Order("created_at DESC").Limit(100).Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
+
+// findWebhook loads the subscription in the URL, scoped to the requesting organization
+func (h *Handler) findWebhook(c *gin.Context) (models.WebhookSubscription, bool) {
+	var sub models.WebhookSubscription
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook ID",
+			"message": err.Error(),
+		})
+		return sub, false
+	}
+
+	if err := h.db.Where("org_id = ?", requestOrgID(c)).First(&sub, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Webhook not found",
+				"message": "No webhook subscription with this ID",
+			})
+			return sub, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return sub, false
+	}
+
+	return sub, true
+}
+
+// bindWebhook applies the fields present in the request body and validates the result
+func (h *Handler) bindWebhook(c *gin.Context, sub *models.WebhookSubscription) bool {
+	var request webhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	if request.Name != nil {
+		sub.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.URL != nil {
+		sub.URL = strings.TrimSpace(*request.URL)
+	}
+	if request.Secret != nil {
+		sub.Secret = *request.Secret
+	}
+	if request.Enabled != nil {
+		sub.Enabled = *request.Enabled
+	}
+	if request.EventTypes != nil {
+		sub.EventTypes = joinList(request.EventTypes)
+	}
+	if request.MinSeverity != nil {
+		sub.MinSeverity = *request.MinSeverity
+	}
+	if request.FindingTypes != nil {
+		sub.FindingTypes = joinList(request.FindingTypes)
+	}
+	if request.Fleets != nil {
+		sub.Fleets = joinList(request.Fleets)
+	}
+	if request.PayloadTemplate != nil {
+		sub.PayloadTemplate = *request.PayloadTemplate
+	}
+
+	if err := webhook.ValidateSubscription(sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook",
+			"message": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func webhookResponse(sub models.WebhookSubscription) gin.H {
+	return gin.H{
+		"id":               sub.ID,
+		"org_id":           sub.OrgID,
+		"name":             sub.Name,
+		"url":              sub.URL,
+		"has_secret":       sub.Secret != "",
+		"enabled":          sub.Enabled,
+		"event_types":      splitList(sub.EventTypes),
+		"min_severity":     sub.MinSeverity,
+		"finding_types":    splitList(sub.FindingTypes),
+		"fleets":           splitList(sub.Fleets),
+		"payload_template": sub.PayloadTemplate,
+		"created_at":       sub.CreatedAt,
+		"updated_at":       sub.UpdatedAt,
+	}
+}
+
+func joinList(items []string) string {
+	var cleaned []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			cleaned = append(cleaned, item)
+		}
+	}
+	return strings.Join(cleaned, ",")
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
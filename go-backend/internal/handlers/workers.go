@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"odin-backend/internal/audit"
+	"odin-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListWorkers returns registered workers with their current job and liveness
+func (h *Handler) ListWorkers(c *gin.Context) {
+	var workers []models.Worker
+	if err := h.db.Order("hostname, id").Find(&workers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var response []gin.H
+	online := 0
+	for i := range workers {
+		worker := &workers[i]
+		if worker.Online() {
+			online++
+		}
+		response = append(response, gin.H{
+			"id":                 worker.ID,
+			"hostname":           worker.Hostname,
+			"pid":                worker.PID,
+			"version":            worker.Version,
+			"current_project_id": worker.CurrentProjectID,
+			"load":               worker.Load,
+			"draining":           worker.Draining,
+			"online":             worker.Online(),
+			"started_at":         worker.StartedAt,
+			"last_heartbeat_at":  worker.LastHeartbeatAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workers": response,
+		"total":   len(workers),
+		"online":  online,
+	})
+}
+
+// DrainWorker stops a worker from picking up new jobs once its current job finishes
+func (h *Handler) DrainWorker(c *gin.Context) {
+	h.setWorkerDraining(c, true)
+}
+
+// ResumeWorker lets a drained worker pick up new jobs again
+func (h *Handler) ResumeWorker(c *gin.Context) {
+	h.setWorkerDraining(c, false)
+}
+
+func (h *Handler) setWorkerDraining(c *gin.Context, draining bool) {
+	workerID := c.Param("worker_id")
+
+	var worker models.Worker
+	if err := h.db.First(&worker, "id = ?", workerID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Worker not found",
+				"message": "No worker registered with this ID",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.db.Model(&worker).Update("draining", draining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update worker",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	action := "worker.resume"
+	if draining {
+		action = "worker.drain"
+	}
+	if err := audit.Record(h.db, requestActor(c), action, "worker", workerID, map[string]interface{}{
+		"current_project_id": worker.CurrentProjectID,
+	}); err != nil {
+		log.Printf("Failed to audit %s for worker %s: %v", action, workerID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"worker_id": workerID,
+		"draining":  draining,
+	})
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/yara"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Largest rule set accepted
+const maxYaraRuleSetSize = 5 << 20
+
+type yaraRuleSetRequest struct {
+	Name        *string           `json:"name" form:"name"`
+	Description *string           `json:"description" form:"description"`
+	Source      *string           `json:"source" form:"source"`
+	Tags        []string          `json:"tags" form:"tags"`
+	Severity    *models.RiskLevel `json:"severity" form:"severity"`
+	Enabled     *bool             `json:"enabled" form:"enabled"`
+}
+
+// ListYaraRuleSets returns the YARA rule sets of the requesting organization
+// without their rules. ?tag= and ?enabled=true|false filter them.
+func (h *Handler) ListYaraRuleSets(c *gin.Context) {
+	query := h.db.Where("org_id = ?", requestOrgID(c))
+	if enabled := c.Query("enabled"); enabled != "" {
+		query = query.Where("enabled = ?", enabled == "true")
+	}
+
+	var sets []models.YaraRuleSet
+	if err := query.Order("id").Find(&sets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tag := c.Query("tag")
+	response := make([]gin.H, 0, len(sets))
+	for _, set := range sets {
+		if tag != "" && !containsString(splitList(set.Tags), tag) {
+			continue
+		}
+		entry := yaraRuleSetResponse(set)
+		delete(entry, "source")
+		response = append(response, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rule_sets": response,
+		"total":     len(response),
+	})
+}
+
+// GetYaraRuleSet returns a rule set with its rules
+func (h *Handler) GetYaraRuleSet(c *gin.Context) {
+	set, ok := h.findYaraRuleSet(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, yaraRuleSetResponse(set))
+}
+
+// CreateYaraRuleSet uploads a rule set, either as JSON with the rules in
+// source or as a form with the rules in a rules_file upload. Sets are
+// enabled unless the request says otherwise.
+func (h *Handler) CreateYaraRuleSet(c *gin.Context) {
+	set := models.YaraRuleSet{
+		OrgID:    requestOrgID(c),
+		Severity: models.RiskHigh,
+		Enabled:  true,
+	}
+	if !h.bindYaraRuleSet(c, &set) {
+		return
+	}
+
+	if err := h.db.Create(&set).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create rule set",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, yaraRuleSetResponse(set))
+}
+
+// UpdateYaraRuleSet replaces the rules of a set or changes its name, tags,
+// severity or whether it's enabled
+func (h *Handler) UpdateYaraRuleSet(c *gin.Context) {
+	set, ok := h.findYaraRuleSet(c)
+	if !ok {
+		return
+	}
+	if !h.bindYaraRuleSet(c, &set) {
+		return
+	}
+
+	if err := h.db.Save(&set).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update rule set",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, yaraRuleSetResponse(set))
+}
+
+// DeleteYaraRuleSet removes a rule set. Findings of its past matches stay.
+func (h *Handler) DeleteYaraRuleSet(c *gin.Context) {
+	set, ok := h.findYaraRuleSet(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(&set).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete rule set",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rule set deleted successfully",
+	})
+}
+
+// findYaraRuleSet loads the rule set in the URL, scoped to the requesting organization
+func (h *Handler) findYaraRuleSet(c *gin.Context) (models.YaraRuleSet, bool) {
+	var set models.YaraRuleSet
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid rule set ID",
+			"message": err.Error(),
+		})
+		return set, false
+	}
+
+	if err := h.db.Where("org_id = ?", requestOrgID(c)).First(&set, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Rule set not found",
+				"message": "No YARA rule set with this ID",
+			})
+			return set, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Database error",
+			"message": err.Error(),
+		})
+		return set, false
+	}
+
+	return set, true
+}
+
+// bindYaraRuleSet applies the fields present in the request to the set and
+// checks that its rules compile
+func (h *Handler) bindYaraRuleSet(c *gin.Context, set *models.YaraRuleSet) bool {
+	var request yaraRuleSetRequest
+	if err := c.ShouldBind(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"message": err.Error(),
+		})
+		return false
+	}
+	if file, err := c.FormFile("rules_file"); err == nil {
+		if file.Size > maxYaraRuleSetSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "Rule set too large",
+				"message": "Rule sets are limited to 5 MiB",
+			})
+			return false
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to read rules file",
+				"message": err.Error(),
+			})
+			return false
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to read rules file",
+				"message": err.Error(),
+			})
+			return false
+		}
+		source := string(content)
+		request.Source = &source
+		if request.Name == nil {
+			name := strings.TrimSuffix(strings.TrimSuffix(file.Filename, ".yar"), ".yara")
+			request.Name = &name
+		}
+	}
+
+	if request.Name != nil {
+		set.Name = strings.TrimSpace(*request.Name)
+	}
+	if request.Description != nil {
+		set.Description = *request.Description
+	}
+	if request.Source != nil {
+		set.Source = *request.Source
+	}
+	if request.Tags != nil {
+		set.Tags = joinList(request.Tags)
+	}
+	if request.Severity != nil {
+		set.Severity = *request.Severity
+	}
+	if request.Enabled != nil {
+		set.Enabled = *request.Enabled
+	}
+
+	if set.Name == "" || strings.TrimSpace(set.Source) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid rule set",
+			"message": "name and source (or a rules_file upload) are required",
+		})
+		return false
+	}
+	if len(set.Source) > maxYaraRuleSetSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "Rule set too large",
+			"message": "Rule sets are limited to 5 MiB",
+		})
+		return false
+	}
+	switch set.Severity {
+	case models.RiskLow, models.RiskMedium, models.RiskHigh, models.RiskCritical:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid rule set",
+			"message": "severity must be low, medium, high or critical",
+		})
+		return false
+	}
+
+	// Rules that don't compile would fail the scan of every analysis. Without
+	// yara on this host they're checked when the first scan runs.
+	if request.Source != nil {
+		if err := yara.Validate(c.Request.Context(), h.config.YaraPath, set.Source); err != nil && err != yara.ErrUnavailable {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid YARA rules",
+				"message": err.Error(),
+			})
+			return false
+		}
+	}
+	return true
+}
+
+func yaraRuleSetResponse(set models.YaraRuleSet) gin.H {
+	return gin.H{
+		"id":          set.ID,
+		"org_id":      set.OrgID,
+		"name":        set.Name,
+		"description": set.Description,
+		"source":      set.Source,
+		"tags":        splitList(set.Tags),
+		"severity":    set.Severity,
+		"enabled":     set.Enabled,
+		"created_at":  set.CreatedAt,
+		"updated_at":  set.UpdatedAt,
+	}
+}
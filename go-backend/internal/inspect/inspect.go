@@ -0,0 +1,279 @@
+// Package inspect takes a quick look at a firmware image without analyzing
+// it: its format, the version strings it embeds, how its entropy runs and
+// which containers it holds. It reads the image a few times from start to
+// end and runs no external tools, so a report takes seconds, which is what
+// triaging a batch of candidate images needs.
+package inspect
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"odin-backend/internal/fwformat"
+	"odin-backend/internal/rtos"
+)
+
+const (
+	maxBlocks       = 256 // points of the entropy profile
+	minBlockSize    = 64 << 10
+	highEntropy     = 7.5 // bits per byte of compressed or encrypted data
+	maxVersions     = 50
+	maxEmbedded     = 200
+	maxEntries      = 500
+	minStringLength = 6
+	maxStringLength = 256 // longer runs are cut; versions are short
+)
+
+// Report is what an inspection found
+type Report struct {
+	Size              int64               `json:"size"`
+	SHA256            string              `json:"sha256"`
+	Format            fwformat.Info       `json:"format"`
+	Entropy           EntropyProfile      `json:"entropy"`
+	Versions          []string            `json:"versions"`
+	RTOS              *rtos.Detection     `json:"rtos,omitempty"`
+	Embedded          []fwformat.Embedded `json:"embedded"`
+	EmbeddedTruncated bool                `json:"embedded_truncated,omitempty"`
+	Entries           []Entry             `json:"entries,omitempty"` // members of zip and tar archives
+	EntriesTruncated  bool                `json:"entries_truncated,omitempty"`
+}
+
+// EntropyProfile is the entropy of an image overall and block by block
+type EntropyProfile struct {
+	Overall     float64   `json:"overall"`
+	BlockSize   int64     `json:"block_size"`
+	Blocks      []float64 `json:"blocks"`
+	HighRegions []Region  `json:"high_regions,omitempty"` // likely compressed or encrypted
+}
+
+// Region is a span of an image
+type Region struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// Entry is a member of an archive
+type Entry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Dir  bool   `json:"dir,omitempty"`
+}
+
+// versionPatterns find the version banners firmware is commonly built with
+var versionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`Linux version \d+\.\d+[\w.+-]*`),
+	regexp.MustCompile(`BusyBox v\d+\.\d+(?:\.\d+)?`),
+	regexp.MustCompile(`U-Boot \d{4}\.\d{2}[\w.-]*`),
+	regexp.MustCompile(`OpenWrt [\w.-]+`),
+	regexp.MustCompile(`OpenSSL \d+\.\d+\.\d+\w*`),
+	regexp.MustCompile(`dropbear_\d+\.\d+`),
+	regexp.MustCompile(`(?i)\b(?:firmware|version|ver|fw)[ _:=-]*v?\d+(?:\.\d+){1,3}[\w-]*`),
+}
+
+// Inspect reports on the image at path
+func Inspect(path string) (*Report, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Size: info.Size(), Versions: []string{}}
+
+	if report.Format, err = fwformat.DescribeFile(path); err != nil {
+		return nil, err
+	}
+	if err := scanImage(path, report); err != nil {
+		return nil, err
+	}
+	if report.Embedded, report.EmbeddedTruncated, err = fwformat.Scan(path, maxEmbedded); err != nil {
+		return nil, err
+	}
+	if report.Embedded == nil {
+		report.Embedded = []fwformat.Embedded{}
+	}
+	if report.RTOS, err = rtos.Detect(path); err != nil {
+		return nil, err
+	}
+
+	switch report.Format.Format {
+	case fwformat.Zip:
+		report.Entries, report.EntriesTruncated, err = zipEntries(path)
+	case fwformat.Tar:
+		report.Entries, report.EntriesTruncated, err = tarEntries(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s archive: %w", report.Format.Format, err)
+	}
+	return report, nil
+}
+
+// scanImage reads the image once for its hash, entropy profile and
+// version strings
+func scanImage(path string, report *Report) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	blockSize := int64(minBlockSize)
+	if perBlock := (report.Size + maxBlocks - 1) / maxBlocks; perBlock > blockSize {
+		blockSize = (perBlock + 4095) &^ 4095
+	}
+	report.Entropy.BlockSize = blockSize
+	report.Entropy.Blocks = []float64{}
+
+	hasher := sha256.New()
+	var total, block [256]int64
+	var inBlock int64
+	seen := make(map[string]bool)
+	var text []byte
+	endString := func() {
+		if len(text) >= minStringLength && len(report.Versions) < maxVersions {
+			for _, pattern := range versionPatterns {
+				if version := pattern.Find(text); version != nil && !seen[string(version)] {
+					seen[string(version)] = true
+					report.Versions = append(report.Versions, string(version))
+					break
+				}
+			}
+		}
+		text = text[:0]
+	}
+
+	buf := make([]byte, 1<<20)
+	for {
+		n, err := f.Read(buf)
+		hasher.Write(buf[:n])
+		for _, b := range buf[:n] {
+			block[b]++
+			inBlock++
+			if inBlock == blockSize {
+				report.Entropy.Blocks = append(report.Entropy.Blocks, round(entropy(&block, inBlock)))
+				addCounts(&total, &block)
+				inBlock = 0
+			}
+			if b >= 0x20 && b < 0x7f {
+				if len(text) < maxStringLength {
+					text = append(text, b)
+				}
+			} else if len(text) > 0 {
+				endString()
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	endString()
+	if inBlock > 0 {
+		report.Entropy.Blocks = append(report.Entropy.Blocks, round(entropy(&block, inBlock)))
+		addCounts(&total, &block)
+	}
+	report.Entropy.Overall = round(entropy(&total, report.Size))
+	report.Entropy.HighRegions = highRegions(report.Entropy.Blocks, blockSize, report.Size)
+	report.SHA256 = fmt.Sprintf("%x", hasher.Sum(nil))
+	return nil
+}
+
+// highRegions joins the runs of high entropy blocks
+func highRegions(blocks []float64, blockSize, size int64) []Region {
+	var regions []Region
+	for i := 0; i < len(blocks); i++ {
+		if blocks[i] < highEntropy {
+			continue
+		}
+		start := i
+		for i < len(blocks) && blocks[i] >= highEntropy {
+			i++
+		}
+		offset := int64(start) * blockSize
+		end := int64(i) * blockSize
+		if end > size {
+			end = size
+		}
+		regions = append(regions, Region{Offset: offset, Size: end - offset})
+	}
+	return regions
+}
+
+// entropy returns the Shannon entropy of counted bytes in bits per byte
+func entropy(counts *[256]int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	e := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(total)
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
+
+// addCounts adds a block's counts to the total and clears them
+func addCounts(total, block *[256]int64) {
+	for i, count := range block {
+		total[i] += count
+		block[i] = 0
+	}
+}
+
+func round(e float64) float64 {
+	return math.Round(e*100) / 100
+}
+
+func zipEntries(path string) ([]Entry, bool, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.Close()
+
+	var entries []Entry
+	for _, f := range r.File {
+		if len(entries) == maxEntries {
+			return entries, true, nil
+		}
+		entries = append(entries, Entry{Name: f.Name, Size: int64(f.UncompressedSize64), Dir: f.FileInfo().IsDir()})
+	}
+	return entries, false, nil
+}
+
+func tarEntries(path string) ([]Entry, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries, false, nil
+		}
+		if err != nil {
+			return entries, false, err
+		}
+		if len(entries) == maxEntries {
+			return entries, true, nil
+		}
+		entries = append(entries, Entry{
+			Name: strings.TrimPrefix(header.Name, "./"),
+			Size: header.Size,
+			Dir:  header.Typeflag == tar.TypeDir,
+		})
+	}
+}
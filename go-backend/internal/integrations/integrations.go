@@ -0,0 +1,169 @@
+// Package integrations keeps the API keys of the OSINT providers in the
+// database, encrypted with INTEGRATIONS_KEY, so they can be added, rotated
+// and revoked without redeploying. A provider with several enabled keys
+// spreads its requests over them, least recently used first.
+package integrations
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Providers lists the providers keys can be stored for, and whether their
+// keys have a public ID besides the secret
+var Providers = map[string]bool{
+	"shodan":     false,
+	"censys":     true, // API ID and secret
+	"virustotal": false,
+}
+
+// ErrNoKey is returned when a provider has neither a stored key nor one in
+// the environment
+var ErrNoKey = errors.New("no API key configured")
+
+// Key is an API credential of a provider. ID is 0 for the one from the
+// environment.
+type Key struct {
+	ID     uint
+	KeyID  string
+	Secret string
+}
+
+// seal encrypts a secret with AES-256-GCM, the nonce prepended, base64
+// encoded
+func seal(key []byte, secret string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a secret seal encrypted
+func open(key []byte, sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("sealed secret too short")
+	}
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Hint returns the last 4 characters of a secret, to tell keys apart
+// without showing them
+func Hint(secret string) string {
+	if len(secret) <= 8 {
+		return ""
+	}
+	return "..." + secret[len(secret)-4:]
+}
+
+// Keyring hands out the stored keys of the providers
+type Keyring struct {
+	db  *gorm.DB
+	key []byte
+}
+
+// New returns the keyring, or nil when INTEGRATIONS_KEY is empty
+func New(db *gorm.DB, cfg *config.Config) *Keyring {
+	if cfg.IntegrationsKey == "" {
+		return nil
+	}
+	// Load checked it's 32 base64 encoded bytes
+	key, _ := base64.StdEncoding.DecodeString(cfg.IntegrationsKey)
+	return &Keyring{db: db, key: key}
+}
+
+// Seal encrypts a secret to store
+func (k *Keyring) Seal(secret string) (string, error) {
+	return seal(k.key, secret)
+}
+
+// Open decrypts a secret Seal encrypted. A nil keyring can't.
+func (k *Keyring) Open(sealed string) (string, error) {
+	if k == nil {
+		return "", errors.New("INTEGRATIONS_KEY is not set")
+	}
+	return open(k.key, sealed)
+}
+
+// Key returns the provider's enabled stored key used least recently and
+// counts the use, or fallback, the key from the environment, when none is
+// stored. A nil keyring always returns fallback.
+func (k *Keyring) Key(provider string, fallback Key) (Key, error) {
+	if k != nil {
+		var stored models.Integration
+		err := k.db.Where("provider = ? AND enabled = ?", provider, true).
+			Order("last_used_at IS NOT NULL, last_used_at, id").First(&stored).Error
+		if err == nil {
+			secret, err := open(k.key, stored.Secret)
+			if err != nil {
+				return Key{}, fmt.Errorf("%s key %d: %w", provider, stored.ID, err)
+			}
+			now := time.Now().UTC()
+			if err := k.db.Model(&stored).Updates(map[string]interface{}{
+				"usage_count":  gorm.Expr("usage_count + 1"),
+				"last_used_at": now,
+			}).Error; err != nil {
+				log.Printf("Failed to count use of %s key %d: %v", provider, stored.ID, err)
+			}
+			return Key{ID: stored.ID, KeyID: stored.KeyID, Secret: secret}, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return Key{}, fmt.Errorf("failed to load %s keys: %w", provider, err)
+		}
+	}
+	if fallback.Secret == "" {
+		return Key{}, ErrNoKey
+	}
+	return fallback, nil
+}
+
+// Failed records the error a provider answered a stored key with, so a
+// revoked or exhausted key shows in the integrations list
+func (k *Keyring) Failed(key Key, failure error) {
+	if k == nil || key.ID == 0 {
+		return
+	}
+	if err := k.db.Model(&models.Integration{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+		"last_error":    failure.Error(),
+		"last_error_at": time.Now().UTC(),
+	}).Error; err != nil {
+		log.Printf("Failed to record error of key %d: %v", key.ID, err)
+	}
+}
@@ -0,0 +1,106 @@
+package mcu
+
+import "encoding/binary"
+
+// espChips maps the chip ID of an ESP32 application image header
+var espChips = map[uint16]string{
+	0x0000: "esp32",
+	0x0002: "esp32-s2",
+	0x0005: "esp32-c3",
+	0x0009: "esp32-s3",
+	0x000c: "esp32-c2",
+	0x000d: "esp32-c6",
+	0x0010: "esp32-h2",
+}
+
+// detectRaw detects the architecture of a flat flash image from what
+// sits at its start: an ESP application header, a Cortex-M vector table or
+// the jump table of an AVR
+func detectRaw(data []byte, base uint32, info *Info) {
+	info.Architecture = ArchUnknown
+	if len(data) < 24 {
+		return
+	}
+	le := binary.LittleEndian
+
+	// ESP image: magic, segment count, SPI mode, size/frequency, entry point
+	if data[0] == 0xe9 && data[1] > 0 && data[1] <= 16 {
+		info.EntryPoint = uint64(le.Uint32(data[4:]))
+		// ESP32 and later carry an extended header; WP pin 0xee means unused
+		if chip, ok := espChips[le.Uint16(data[12:])]; ok && data[8] == 0xee {
+			info.Chip = chip
+			info.Architecture = ArchXtensa
+			if chip == "esp32-c3" || chip == "esp32-c2" || chip == "esp32-c6" || chip == "esp32-h2" {
+				info.Architecture = ArchRISCV
+			}
+		} else {
+			info.Chip = "esp8266"
+			info.Architecture = ArchXtensa
+		}
+		return
+	}
+
+	// Flash of most Cortex-M parts is also mapped at 0, and images for
+	// bootloader offsets are often linked there
+	if sp, ok := cortexMVectors(data, base, base+uint32(len(data))); ok {
+		info.Architecture = ArchCortexM
+		info.InitialSP = uint64(sp)
+		if info.EntryPoint == 0 {
+			info.EntryPoint = uint64(le.Uint32(data[4:]) &^ 1)
+		}
+		return
+	}
+
+	if base == 0 && avrVectors(data) {
+		info.Architecture = ArchAVR
+	}
+}
+
+// cortexMVectors checks for a Cortex-M vector table at the start of data:
+// the initial stack pointer in SRAM followed by a Thumb reset handler
+// inside the image. It returns the initial stack pointer.
+func cortexMVectors(data []byte, start, end uint32) (uint32, bool) {
+	if len(data) < 16 {
+		return 0, false
+	}
+	le := binary.LittleEndian
+	sp, reset := le.Uint32(data[0:]), le.Uint32(data[4:])
+	if sp < 0x10000000 || sp >= 0x40000000 || sp%4 != 0 {
+		return 0, false
+	}
+	if reset&1 == 0 {
+		return 0, false
+	}
+	handler := reset &^ 1
+	if !(handler >= start && handler < end) && !(start >= 0x08000000 && handler < end-start) {
+		return 0, false
+	}
+	// NMI and HardFault handlers are Thumb too, or unset
+	for _, offset := range []int{8, 12} {
+		if vector := le.Uint32(data[offset:]); vector != 0 && vector&1 == 0 {
+			return 0, false
+		}
+	}
+	return sp, true
+}
+
+// avrVectors checks for the interrupt vector table of an AVR: the first
+// entries are all jmp (0x940c) or all rjmp instructions
+func avrVectors(data []byte) bool {
+	const entries = 4
+	if len(data) < entries*4 {
+		return false
+	}
+	le := binary.LittleEndian
+	jmp, rjmp := true, true
+	for i := 0; i < entries; i++ {
+		if le.Uint16(data[i*4:]) != 0x940c {
+			jmp = false
+		}
+		// rjmp tables have one word per vector
+		if le.Uint16(data[i*2:])&0xf000 != 0xc000 {
+			rjmp = false
+		}
+	}
+	return jmp || rjmp
+}
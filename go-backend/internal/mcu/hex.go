@@ -0,0 +1,114 @@
+package mcu
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Largest address span a HEX file may cover; MCU flash is a few MiB at most
+const maxImageSpan = 64 << 20
+
+// ErrInvalidHex is returned for HEX files with malformed records
+var ErrInvalidHex = errors.New("invalid Intel HEX file")
+
+type hexSegment struct {
+	address uint32
+	data    []byte
+}
+
+// ParseIntelHex converts an Intel HEX file into the flash image it
+// describes. Gaps between records are filled with 0xff, the value of erased
+// flash. It returns the image, the address of its first byte and the start
+// address record (0 if there is none).
+func ParseIntelHex(r io.Reader) ([]byte, uint32, uint32, error) {
+	var segments []hexSegment
+	var upper, start uint32
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if text[0] != ':' || len(text)%2 == 0 {
+			return nil, 0, 0, fmt.Errorf("%w: line %d", ErrInvalidHex, line)
+		}
+		record := make([]byte, (len(text)-1)/2)
+		if _, err := hex.Decode(record, text[1:]); err != nil || len(record) < 5 || len(record) != 5+int(record[0]) {
+			return nil, 0, 0, fmt.Errorf("%w: line %d", ErrInvalidHex, line)
+		}
+		var sum byte
+		for _, b := range record {
+			sum += b
+		}
+		if sum != 0 {
+			return nil, 0, 0, fmt.Errorf("%w: checksum mismatch on line %d", ErrInvalidHex, line)
+		}
+
+		data := record[4 : len(record)-1]
+		offset := uint32(record[1])<<8 | uint32(record[2])
+		switch record[3] {
+		case 0x00: // data
+			segments = append(segments, hexSegment{address: upper + offset, data: data})
+		case 0x01: // end of file
+			return assemble(segments, start)
+		case 0x02: // extended segment address
+			if len(data) != 2 {
+				return nil, 0, 0, fmt.Errorf("%w: line %d", ErrInvalidHex, line)
+			}
+			upper = (uint32(data[0])<<8 | uint32(data[1])) << 4
+		case 0x04: // extended linear address
+			if len(data) != 2 {
+				return nil, 0, 0, fmt.Errorf("%w: line %d", ErrInvalidHex, line)
+			}
+			upper = (uint32(data[0])<<8 | uint32(data[1])) << 16
+		case 0x03, 0x05: // start segment / linear address
+			if len(data) != 4 {
+				return nil, 0, 0, fmt.Errorf("%w: line %d", ErrInvalidHex, line)
+			}
+			start = uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+			if record[3] == 0x03 {
+				// CS:IP
+				start = (start>>16)<<4 + start&0xffff
+			}
+		default:
+			return nil, 0, 0, fmt.Errorf("%w: unknown record type %02x on line %d", ErrInvalidHex, record[3], line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+	// Some tools omit the end of file record
+	return assemble(segments, start)
+}
+
+func assemble(segments []hexSegment, start uint32) ([]byte, uint32, uint32, error) {
+	if len(segments) == 0 {
+		return nil, 0, 0, fmt.Errorf("%w: no data records", ErrInvalidHex)
+	}
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].address < segments[j].address })
+
+	base := segments[0].address
+	var end uint64
+	for _, segment := range segments {
+		if e := uint64(segment.address) + uint64(len(segment.data)); e > end {
+			end = e
+		}
+	}
+	if end-uint64(base) > maxImageSpan {
+		return nil, 0, 0, fmt.Errorf("%w: records span more than %d MiB", ErrInvalidHex, maxImageSpan>>20)
+	}
+
+	image := bytes.Repeat([]byte{0xff}, int(end-uint64(base)))
+	for _, segment := range segments {
+		copy(image[segment.address-base:], segment.data)
+	}
+	return image, base, start, nil
+}
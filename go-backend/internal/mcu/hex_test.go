@@ -0,0 +1,76 @@
+package mcu
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIntelHex(t *testing.T) {
+	cases := []struct {
+		file  string
+		image []byte
+		base  uint32
+		start uint32
+	}{
+		{
+			// Extended linear addresses, a gap filled as erased flash and
+			// a start linear address
+			file: "stm32.hex",
+			image: append(append(
+				[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+				bytes.Repeat([]byte{0xff}, 16)...), "Odin"...),
+			base:  0x08000000,
+			start: 0x08000131,
+		},
+		{
+			// Extended segment addresses, records out of order, a CS:IP
+			// start address and no end of file record
+			file:  "segment.hex",
+			image: []byte{1, 2, 3, 4, 0xaa, 0xbb},
+			base:  0x10000,
+			start: 0x10010,
+		},
+	}
+
+	for _, tc := range cases {
+		f, err := os.Open(filepath.Join("testdata", tc.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		image, base, start, err := ParseIntelHex(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		if !bytes.Equal(image, tc.image) {
+			t.Errorf("%s: image %x, want %x", tc.file, image, tc.image)
+		}
+		if base != tc.base || start != tc.start {
+			t.Errorf("%s: base %#x start %#x, want %#x and %#x", tc.file, base, start, tc.base, tc.start)
+		}
+	}
+}
+
+func TestParseIntelHexInvalid(t *testing.T) {
+	for _, file := range []string{"checksum.hex", "unknown.hex", "empty.hex", "span.hex"} {
+		f, err := os.Open(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, err = ParseIntelHex(f)
+		f.Close()
+		if !errors.Is(err, ErrInvalidHex) {
+			t.Errorf("%s: got %v, want ErrInvalidHex", file, err)
+		}
+	}
+
+	for _, text := range []string{":0000000", "10000000FF\n", ":0400000001020304\n"} {
+		if _, _, _, err := ParseIntelHex(bytes.NewBufferString(text)); !errors.Is(err, ErrInvalidHex) {
+			t.Errorf("%q: got %v, want ErrInvalidHex", text, err)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package mcu analyzes bare-metal microcontroller firmware: Intel HEX files
+// and ELF images without an operating system. They hold no filesystem for
+// EMBA's modules to look into, so Odin converts them into a flat image,
+// detects the architecture and pulls out their strings and symbols instead.
+package mcu
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"os"
+	"sort"
+
+	"odin-backend/internal/fwformat"
+)
+
+// Formats of MCU images
+const (
+	FormatHex = fwformat.IntelHex
+	FormatELF = fwformat.ELF
+)
+
+// Architectures detected
+const (
+	ArchCortexM = "arm-cortex-m"
+	ArchARM     = "arm"
+	ArchXtensa  = "xtensa"
+	ArchRISCV   = "riscv"
+	ArchAVR     = "avr"
+	ArchMSP430  = "msp430"
+	ArchUnknown = "unknown"
+)
+
+const (
+	minStringLength = 6
+	maxStrings      = 100000
+)
+
+// Info describes an MCU image, stored with the project's firmware info
+type Info struct {
+	Format       string `json:"format"` // intel_hex or elf
+	Architecture string `json:"architecture"`
+	Chip         string `json:"chip,omitempty"` // e.g. esp32-s3, from the image header
+	BaseAddress  uint64 `json:"base_address"`
+	EntryPoint   uint64 `json:"entry_point"`
+	ImageSize    int    `json:"image_size"`
+	InitialSP    uint64 `json:"initial_sp,omitempty"` // Cortex-M vector table
+	Strings      int    `json:"strings"`
+	Symbols      int    `json:"symbols"`
+}
+
+// Image is an analyzed MCU image
+type Image struct {
+	Info    Info
+	Data    []byte   // flash contents; the ELF file itself for ELF images
+	Strings []string // printable strings of at least six characters
+	Symbols []string // function and object names of ELF images
+}
+
+// IsMCU reports whether the firmware is an Intel HEX file or a bare-metal
+// ELF image
+func IsMCU(path, firmwareType string) bool {
+	switch firmwareType {
+	case FormatHex:
+		return true
+	case FormatELF:
+		f, err := elf.Open(path)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		return isBareMetal(f)
+	}
+	return false
+}
+
+// isBareMetal tells ELF images for an MCU from Linux executables: they have
+// no interpreter, no dynamic section and no ABI note, and target an
+// architecture microcontrollers use
+func isBareMetal(f *elf.File) bool {
+	switch f.Machine {
+	case elf.EM_ARM, elf.EM_XTENSA, elf.EM_RISCV, elf.EM_AVR, elf.EM_MSP430:
+	default:
+		return false
+	}
+	if f.Type != elf.ET_EXEC {
+		return false
+	}
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP || prog.Type == elf.PT_DYNAMIC {
+			return false
+		}
+	}
+	return f.Section(".note.ABI-tag") == nil && f.Section(".interp") == nil
+}
+
+// Analyze reads an Intel HEX or bare-metal ELF image
+func Analyze(path, firmwareType string) (*Image, error) {
+	var image *Image
+	var err error
+	switch firmwareType {
+	case FormatHex:
+		image, err = analyzeHex(path)
+	case FormatELF:
+		image, err = analyzeELF(path)
+	default:
+		return nil, fmt.Errorf("%s is not an MCU image format", firmwareType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	image.Strings = extractStrings(image.Data)
+	image.Info.Format = firmwareType
+	image.Info.ImageSize = len(image.Data)
+	image.Info.Strings = len(image.Strings)
+	image.Info.Symbols = len(image.Symbols)
+	return image, nil
+}
+
+func analyzeHex(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, base, start, err := ParseIntelHex(f)
+	if err != nil {
+		return nil, err
+	}
+	image := &Image{Data: data, Info: Info{BaseAddress: uint64(base), EntryPoint: uint64(start)}}
+	detectRaw(data, base, &image.Info)
+	return image, nil
+}
+
+func analyzeELF(path string) (*Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	image := &Image{Data: data, Info: Info{EntryPoint: f.Entry, Architecture: ArchUnknown}}
+	symbols, _ := f.Symbols()
+	names := make(map[string]bool)
+	for _, symbol := range symbols {
+		switch elf.ST_TYPE(symbol.Info) {
+		case elf.STT_FUNC, elf.STT_OBJECT:
+			if symbol.Name != "" && !names[symbol.Name] {
+				names[symbol.Name] = true
+				image.Symbols = append(image.Symbols, symbol.Name)
+			}
+		}
+	}
+	sort.Strings(image.Symbols)
+
+	// The lowest loaded segment is where the vector table of a Cortex-M sits
+	var first *elf.Prog
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_LOAD && prog.Filesz > 0 && (first == nil || prog.Paddr < first.Paddr) {
+			first = prog
+		}
+	}
+	if first != nil {
+		image.Info.BaseAddress = first.Paddr
+	}
+
+	switch f.Machine {
+	case elf.EM_ARM:
+		image.Info.Architecture = ArchARM
+		if first != nil {
+			segment := make([]byte, 16)
+			if n, _ := first.ReadAt(segment, 0); n == len(segment) {
+				if sp, ok := cortexMVectors(segment, uint32(first.Paddr), uint32(first.Paddr+first.Memsz)); ok {
+					image.Info.Architecture = ArchCortexM
+					image.Info.InitialSP = uint64(sp)
+				}
+			}
+		}
+		if names["Reset_Handler"] {
+			image.Info.Architecture = ArchCortexM
+		}
+	case elf.EM_XTENSA:
+		image.Info.Architecture = ArchXtensa
+	case elf.EM_RISCV:
+		image.Info.Architecture = ArchRISCV
+	case elf.EM_AVR:
+		image.Info.Architecture = ArchAVR
+	case elf.EM_MSP430:
+		image.Info.Architecture = ArchMSP430
+	}
+	// ESP-IDF applications start the second core from call_start_cpu0
+	if (f.Machine == elf.EM_XTENSA || f.Machine == elf.EM_RISCV) && (names["call_start_cpu0"] || names["app_main"]) {
+		image.Info.Chip = "esp32"
+	}
+	return image, nil
+}
+
+// extractStrings returns the runs of printable ASCII in data
+func extractStrings(data []byte) []string {
+	var found []string
+	begin := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && (data[i] >= 0x20 && data[i] < 0x7f || data[i] == '\t') {
+			if begin < 0 {
+				begin = i
+			}
+			continue
+		}
+		if begin >= 0 && i-begin >= minStringLength {
+			found = append(found, string(data[begin:i]))
+			if len(found) == maxStrings {
+				break
+			}
+		}
+		begin = -1
+	}
+	return found
+}
@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,7 +17,7 @@ func CORS() gin.HandlerFunc {
 		origin := c.Request.Header.Get("Origin")
 		c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Odin-User, X-Odin-Org")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -74,3 +76,30 @@ func ErrorHandler() gin.HandlerFunc {
 		}
 	}
 }
+
+// RequireAdmin middleware restricts a route group to callers presenting the
+// admin API token as a bearer token. An empty token leaves the routes open,
+// which is only intended for local development.
+func RequireAdmin(token string) gin.HandlerFunc {
+	if token == "" {
+		log.Println("Warning: ADMIN_API_TOKEN is not set, admin endpoints are unauthenticated")
+	}
+
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "A valid admin API token is required",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
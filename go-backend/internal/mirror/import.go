@@ -0,0 +1,381 @@
+package mirror
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/nvd"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// importBatch is the number of records inserted per statement
+const importBatch = 500
+
+var cveIDRegex = regexp.MustCompile(`^CVE-\d{4}-\d{4,}$`)
+
+// Import loads a dataset from a file, or for NVD from a file or a directory
+// of yearly feeds, and records the import. Files may be gzipped. NVD
+// records are added to the ones imported or fetched before; the other
+// datasets are snapshots and replace the previous import. It returns the
+// number of records imported.
+func Import(db *gorm.DB, dataset, path string) (int, error) {
+	var (
+		count int
+		err   error
+	)
+	switch dataset {
+	case DatasetNVD:
+		count, err = importNVD(db, path)
+	case DatasetKEV:
+		count, err = importFile(db, path, importKEV)
+	case DatasetEPSS:
+		count, err = importFile(db, path, importEPSS)
+	case DatasetExploitDB:
+		count, err = importFile(db, path, importExploitDB)
+	default:
+		return 0, fmt.Errorf("unknown dataset %q", dataset)
+	}
+	if err != nil {
+		return count, err
+	}
+
+	source, _ := filepath.Abs(path)
+	record := models.MirrorImport{Dataset: dataset, Source: source, Records: count, ImportedAt: time.Now().UTC()}
+	if err := db.Save(&record).Error; err != nil {
+		return count, fmt.Errorf("failed to record import: %w", err)
+	}
+	return count, nil
+}
+
+// open opens a file, decompressing it when it is gzipped
+func open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
+
+// importFile imports a snapshot dataset from a file in a transaction
+func importFile(db *gorm.DB, path string, load func(tx *gorm.DB, r io.Reader) (int, error)) (int, error) {
+	r, err := open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var count int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		count, err = load(tx, r)
+		return err
+	})
+	return count, err
+}
+
+// importNVD adds the CVEs of NVD JSON 2.0 feeds to the NVD records, and
+// has the findings of those CVEs enriched again
+func importNVD(db *gorm.DB, path string) (int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.json", "*.json.gz"} {
+			matches, _ := filepath.Glob(filepath.Join(path, pattern))
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+		if len(files) == 0 {
+			return 0, fmt.Errorf("no NVD feeds (*.json, *.json.gz) in %s", path)
+		}
+	}
+
+	count := 0
+	for _, file := range files {
+		n, err := importNVDFeed(db, file)
+		count += n
+		if err != nil {
+			return count, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return count, nil
+}
+
+func importNVDFeed(db *gorm.DB, path string) (int, error) {
+	r, err := open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	now := time.Now().UTC()
+	count := 0
+	var batch []models.NVDRecord
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		ids := make([]string, 0, len(batch))
+		for _, record := range batch {
+			ids = append(ids, record.CVEID)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&batch).Error; err != nil {
+				return fmt.Errorf("failed to store NVD records: %w", err)
+			}
+			return tx.Model(&models.CVEFinding{}).Where("cve_id IN ? AND nvd_enriched_at IS NOT NULL", ids).
+				UpdateColumn("nvd_enriched_at", nil).Error
+		})
+		count += len(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	err = nvd.ReadFeed(r, func(cve *nvd.CVE) error {
+		data, _ := json.Marshal(cve)
+		batch = append(batch, models.NVDRecord{CVEID: cve.ID, Found: true, Data: string(data), FetchedAt: now})
+		if len(batch) < importBatch {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+// importKEV replaces the KEV mirror with CISA's
+// known_exploited_vulnerabilities.json catalog
+func importKEV(tx *gorm.DB, r io.Reader) (int, error) {
+	var catalog struct {
+		Vulnerabilities []struct {
+			CVEID                      string `json:"cveID"`
+			VendorProject              string `json:"vendorProject"`
+			Product                    string `json:"product"`
+			VulnerabilityName          string `json:"vulnerabilityName"`
+			DateAdded                  string `json:"dateAdded"`
+			KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.NewDecoder(r).Decode(&catalog); err != nil {
+		return 0, fmt.Errorf("failed to decode KEV catalog: %w", err)
+	}
+	if len(catalog.Vulnerabilities) == 0 {
+		return 0, errors.New("KEV catalog lists no vulnerabilities")
+	}
+
+	entries := make([]models.KEVEntry, 0, len(catalog.Vulnerabilities))
+	seen := make(map[string]bool)
+	for _, v := range catalog.Vulnerabilities {
+		if !cveIDRegex.MatchString(v.CVEID) || seen[v.CVEID] {
+			continue
+		}
+		seen[v.CVEID] = true
+		entries = append(entries, models.KEVEntry{
+			CVEID:             v.CVEID,
+			VendorProject:     v.VendorProject,
+			Product:           v.Product,
+			VulnerabilityName: v.VulnerabilityName,
+			DateAdded:         v.DateAdded,
+			RansomwareUse:     v.KnownRansomwareCampaignUse,
+		})
+	}
+
+	if err := tx.Where("1 = 1").Delete(&models.KEVEntry{}).Error; err != nil {
+		return 0, fmt.Errorf("failed to clear KEV mirror: %w", err)
+	}
+	if err := tx.CreateInBatches(entries, importBatch).Error; err != nil {
+		return 0, fmt.Errorf("failed to store KEV entries: %w", err)
+	}
+	return len(entries), nil
+}
+
+// importEPSS replaces the EPSS mirror with FIRST's daily
+// epss_scores-YYYY-MM-DD.csv, whose first line comments the model version
+// and score date, and has every CVE scored again from it
+func importEPSS(tx *gorm.DB, r io.Reader) (int, error) {
+	reader := bufio.NewReader(r)
+	var date string
+	if first, err := reader.Peek(1); err == nil && first[0] == '#' {
+		line, _ := reader.ReadString('\n')
+		for _, field := range strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "#")), ",") {
+			if value, ok := strings.CutPrefix(field, "score_date:"); ok && len(value) >= 10 {
+				date = value[:10]
+			}
+		}
+	}
+
+	rows := csv.NewReader(reader)
+	header, err := rows.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read EPSS header: %w", err)
+	}
+	columns := columnIndex(header)
+	cveCol, okCVE := columns["cve"]
+	epssCol, okEPSS := columns["epss"]
+	percentileCol, okPercentile := columns["percentile"]
+	if !okCVE || !okEPSS || !okPercentile {
+		return 0, errors.New("EPSS CSV needs cve, epss and percentile columns")
+	}
+
+	if err := tx.Where("1 = 1").Delete(&models.EPSSRecord{}).Error; err != nil {
+		return 0, fmt.Errorf("failed to clear EPSS mirror: %w", err)
+	}
+	count := 0
+	batch := make([]models.EPSSRecord, 0, importBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&batch).Error; err != nil {
+			return fmt.Errorf("failed to store EPSS scores: %w", err)
+		}
+		count += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	for line := 2; ; line++ {
+		row, err := rows.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		score, err1 := strconv.ParseFloat(row[epssCol], 64)
+		percentile, err2 := strconv.ParseFloat(row[percentileCol], 64)
+		if err1 != nil || err2 != nil || !cveIDRegex.MatchString(row[cveCol]) {
+			return count, fmt.Errorf("line %d: invalid EPSS score %q", line, strings.Join(row, ","))
+		}
+		batch = append(batch, models.EPSSRecord{CVEID: row[cveCol], EPSS: score, Percentile: percentile, Date: date})
+		if len(batch) == importBatch {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return count, err
+	}
+	if count == 0 {
+		return 0, errors.New("EPSS CSV has no scores")
+	}
+
+	if err := tx.Model(&models.CVE{}).Where("epss_checked_at IS NOT NULL").
+		UpdateColumn("epss_checked_at", nil).Error; err != nil {
+		return count, fmt.Errorf("failed to reset EPSS scores: %w", err)
+	}
+	return count, nil
+}
+
+// importExploitDB replaces the exploit-db mirror with exploit-db's
+// files_exploits.csv index, keeping the exploits whose codes name CVEs
+func importExploitDB(tx *gorm.DB, r io.Reader) (int, error) {
+	rows := csv.NewReader(r)
+	rows.FieldsPerRecord = -1
+	rows.LazyQuotes = true
+	header, err := rows.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read exploit-db header: %w", err)
+	}
+	columns := columnIndex(header)
+	idCol, okID := columns["id"]
+	codesCol, okCodes := columns["codes"]
+	if !okID || !okCodes {
+		return 0, errors.New("exploit-db index needs id and codes columns")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	if err := tx.Where("1 = 1").Delete(&models.ExploitDBEntry{}).Error; err != nil {
+		return 0, fmt.Errorf("failed to clear exploit-db mirror: %w", err)
+	}
+	exploits := 0
+	batch := make([]models.ExploitDBEntry, 0, importBatch)
+	for line := 2; ; line++ {
+		row, err := rows.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return exploits, fmt.Errorf("line %d: %w", line, err)
+		}
+		if idCol >= len(row) || codesCol >= len(row) {
+			continue
+		}
+
+		found := false
+		for _, code := range strings.Split(row[codesCol], ";") {
+			code = strings.TrimSpace(code)
+			if !cveIDRegex.MatchString(code) {
+				continue
+			}
+			found = true
+			batch = append(batch, models.ExploitDBEntry{
+				ExploitID:   strings.TrimSpace(row[idCol]),
+				CVEID:       code,
+				Description: field(row, "description"),
+				Type:        field(row, "type"),
+				Platform:    field(row, "platform"),
+				Verified:    field(row, "verified") == "1",
+			})
+		}
+		if found {
+			exploits++
+		}
+		if len(batch) >= importBatch {
+			if err := tx.Create(&batch).Error; err != nil {
+				return exploits, fmt.Errorf("failed to store exploits: %w", err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := tx.Create(&batch).Error; err != nil {
+			return exploits, fmt.Errorf("failed to store exploits: %w", err)
+		}
+	}
+	return exploits, nil
+}
+
+// columnIndex maps the lowercase names of a CSV header to their columns
+func columnIndex(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns
+}
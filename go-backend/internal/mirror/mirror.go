@@ -0,0 +1,130 @@
+// Package mirror keeps local copies of the vulnerability datasets Odin
+// otherwise looks up online (NVD's JSON feeds, CISA KEV, FIRST's EPSS CSV
+// and the exploit-db index), imported from files with "odin mirror
+// import", and looks CVEs up in them in offline mode.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/epss"
+	"odin-backend/internal/exploit"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Datasets that can be imported
+const (
+	DatasetNVD       = "nvd"
+	DatasetKEV       = "kev"
+	DatasetEPSS      = "epss"
+	DatasetExploitDB = "exploitdb"
+)
+
+// Datasets lists the datasets in the order they are imported
+var Datasets = []string{DatasetNVD, DatasetKEV, DatasetEPSS, DatasetExploitDB}
+
+// lookupChunk bounds the CVE IDs of one lookup query
+const lookupChunk = 500
+
+// exploitDBURL is the page of an exploit-db exploit by its ID
+const exploitDBURL = "https://www.exploit-db.com/exploits/"
+
+// Mirror looks CVEs up in the imported datasets
+type Mirror struct {
+	db *gorm.DB
+}
+
+// New returns the mirror lookups, or nil unless OFFLINE_MODE is on: online
+// the datasets are looked up at their source
+func New(db *gorm.DB, cfg *config.Config) *Mirror {
+	if !cfg.OfflineMode {
+		return nil
+	}
+	return &Mirror{db: db}
+}
+
+// Scores returns the imported EPSS scores of CVEs by CVE ID, making the
+// mirror an epss.Source
+func (m *Mirror) Scores(ctx context.Context, cveIDs []string) (map[string]epss.Score, error) {
+	var records []models.EPSSRecord
+	if err := m.db.WithContext(ctx).Where("cve_id IN ?", cveIDs).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up EPSS mirror: %w", err)
+	}
+	scores := make(map[string]epss.Score, len(records))
+	for _, record := range records {
+		scores[record.CVEID] = epss.Score{CVE: record.CVEID, EPSS: record.EPSS, Percentile: record.Percentile, Date: record.Date}
+	}
+	return scores, nil
+}
+
+var _ epss.Source = (*Mirror)(nil)
+
+// Enrich marks the CVE findings listed in the KEV mirror as known exploited
+// and adds the exploits the exploit-db mirror has of them, as the online
+// exploit lookup does. A failed lookup leaves what EMBA reported.
+func (m *Mirror) Enrich(cves []models.CVEFinding) error {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, cve := range cves {
+		if strings.HasPrefix(cve.CVEID, "CVE-") && !seen[cve.CVEID] {
+			seen[cve.CVEID] = true
+			ids = append(ids, cve.CVEID)
+		}
+	}
+
+	known := make(map[string]bool)
+	exploits := make(map[string][]string)
+	for start := 0; start < len(ids); start += lookupChunk {
+		chunk := ids[start:min(start+lookupChunk, len(ids))]
+
+		var listed []string
+		if err := m.db.Model(&models.KEVEntry{}).Where("cve_id IN ?", chunk).Pluck("cve_id", &listed).Error; err != nil {
+			return fmt.Errorf("failed to look up KEV mirror: %w", err)
+		}
+		for _, id := range listed {
+			known[id] = true
+		}
+
+		var entries []models.ExploitDBEntry
+		if err := m.db.Where("cve_id IN ?", chunk).Order("exploit_id").Find(&entries).Error; err != nil {
+			return fmt.Errorf("failed to look up exploit-db mirror: %w", err)
+		}
+		for _, entry := range entries {
+			exploits[entry.CVEID] = append(exploits[entry.CVEID], entry.ExploitID)
+		}
+	}
+
+	for i := range cves {
+		cve := &cves[i]
+		if known[cve.CVEID] {
+			cve.KnownExploited = true
+		}
+		found := exploits[cve.CVEID]
+		if len(found) == 0 {
+			continue
+		}
+		urls := make([]string, 0, len(found))
+		for _, id := range found {
+			urls = append(urls, exploitDBURL+id)
+		}
+		cve.ExploitDBIDs = exploit.Merge(cve.ExploitDBIDs, found...)
+		cve.PoCURLs = exploit.Merge(cve.PoCURLs, urls...)
+		cve.ExploitSources = exploit.Merge(cve.ExploitSources, "exploit-db")
+		cve.ExploitAvailable = true
+	}
+	return nil
+}
+
+// Status returns the last import of every dataset imported so far
+func Status(db *gorm.DB) ([]models.MirrorImport, error) {
+	var imports []models.MirrorImport
+	if err := db.Order("dataset").Find(&imports).Error; err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
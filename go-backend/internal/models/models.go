@@ -1,10 +1,15 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ProjectStatus represents the status of a firmware analysis project
@@ -24,12 +29,149 @@ const (
 type RiskLevel string
 
 const (
+	RiskInfo     RiskLevel = "info" // informational, e.g. emulation and scan summaries
 	RiskLow      RiskLevel = "low"
 	RiskMedium   RiskLevel = "medium"
 	RiskHigh     RiskLevel = "high"
 	RiskCritical RiskLevel = "critical"
 )
 
+// Rank orders risk levels from least to most severe
+func (r RiskLevel) Rank() int {
+	switch r {
+	case RiskInfo:
+		return 1
+	case RiskLow:
+		return 2
+	case RiskMedium:
+		return 3
+	case RiskHigh:
+		return 4
+	case RiskCritical:
+		return 5
+	default:
+		return 0
+	}
+}
+
+// Confidence is how much the parser trusts a finding, based on where it
+// came from: structured EMBA output or keyword matching on log lines
+type Confidence string
+
+const (
+	ConfidenceLow    Confidence = "low"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceHigh   Confidence = "high"
+)
+
+// Rank orders confidence levels from least to most confident
+func (c Confidence) Rank() int {
+	switch c {
+	case ConfidenceLow:
+		return 1
+	case ConfidenceMedium:
+		return 2
+	case ConfidenceHigh:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// MatchStatus is whether a CVE finding's component is affected by the CVE,
+// as NVD's applicability statements tell or an analyst decided
+type MatchStatus string
+
+const (
+	MatchUnverified MatchStatus = ""          // not checked yet
+	MatchMatched    MatchStatus = "matched"   // the component's version is in a vulnerable range
+	MatchPotential  MatchStatus = "potential" // uncertain, for an analyst to confirm or reject
+	MatchConfirmed  MatchStatus = "confirmed" // confirmed by an analyst
+	MatchRejected   MatchStatus = "rejected"  // a false positive, rejected by an analyst
+)
+
+// Reviewed reports whether an analyst decided the match
+func (m MatchStatus) Reviewed() bool {
+	return m == MatchConfirmed || m == MatchRejected
+}
+
+// TriageStatus is where an analyst's triage of a finding stands
+type TriageStatus string
+
+const (
+	TriageOpen       TriageStatus = "" // not triaged yet
+	TriageInProgress TriageStatus = "in_progress"
+	TriageReviewed   TriageStatus = "reviewed"
+	TriageResolved   TriageStatus = "resolved"
+)
+
+// ExtractionQuality is how well EMBA could unpack a firmware image
+type ExtractionQuality string
+
+const (
+	ExtractionGood      ExtractionQuality = "good"
+	ExtractionPartial   ExtractionQuality = "partial"   // only a few files came out
+	ExtractionFailed    ExtractionQuality = "failed"    // nothing came out
+	ExtractionEncrypted ExtractionQuality = "encrypted" // nothing came out of a high entropy image
+	ExtractionUnknown   ExtractionQuality = "unknown"
+)
+
+// CrackStatus is the outcome of trying a password hash against the wordlist
+type CrackStatus string
+
+const (
+	CrackPending     CrackStatus = "pending"
+	CrackRunning     CrackStatus = "running"
+	CrackCracked     CrackStatus = "cracked"
+	CrackNotCracked  CrackStatus = "not_cracked"
+	CrackUnsupported CrackStatus = "unsupported" // the cracker can't handle the algorithm
+	CrackFailed      CrackStatus = "failed"
+)
+
+// Password hash algorithms, from the crypt(3) format of the hash
+const (
+	HashDES         = "des"
+	HashMD5Crypt    = "md5crypt"
+	HashBcrypt      = "bcrypt"
+	HashSHA256Crypt = "sha256crypt"
+	HashSHA512Crypt = "sha512crypt"
+	HashYescrypt    = "yescrypt"
+	HashCrypt       = "crypt" // another $id$ format
+	HashNone        = "none"  // empty password field, no password needed
+)
+
+// LicenseCategory groups licenses by what distributing the firmware obliges
+// the vendor to do
+type LicenseCategory string
+
+const (
+	LicenseStrongCopyleft LicenseCategory = "strong_copyleft" // GPL, AGPL: source of the component and works combined with it
+	LicenseWeakCopyleft   LicenseCategory = "weak_copyleft"   // LGPL, MPL, EPL: source of the component and changes to it
+	LicensePermissive     LicenseCategory = "permissive"      // MIT, BSD, Apache: attribution
+	LicenseUnknown        LicenseCategory = "unknown"
+)
+
+// Copyleft reports whether the license requires releasing source code
+func (c LicenseCategory) Copyleft() bool {
+	return c == LicenseStrongCopyleft || c == LicenseWeakCopyleft
+}
+
+// Opaque reports whether EMBA saw nothing inside the image, so an empty
+// result says nothing about the firmware's security
+func (q ExtractionQuality) Opaque() bool {
+	return q == ExtractionFailed || q == ExtractionEncrypted
+}
+
+// Disposition is the aggregate malware verdict for an uploaded firmware
+type Disposition string
+
+const (
+	DispositionUnknown    Disposition = "unknown"
+	DispositionClean      Disposition = "clean"
+	DispositionSuspicious Disposition = "suspicious"
+	DispositionMalicious  Disposition = "malicious"
+)
+
 // FindingType represents the type of finding
 type FindingType string
 
@@ -44,9 +186,13 @@ const (
 	FindingSecurityIssue FindingType = "security_issue"
 )
 
+// DefaultOrgID is used for projects and settings when no organization is given
+const DefaultOrgID = "default"
+
 // Project represents a firmware analysis project
 type Project struct {
 	ID          string        `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	OrgID       string        `gorm:"default:default;index" json:"org_id"`
 	Name        string        `gorm:"not null" json:"name"`
 	Description string        `json:"description"`
 	Status      ProjectStatus `gorm:"default:pending" json:"status"`
@@ -56,27 +202,154 @@ type Project struct {
 	Filename string `gorm:"not null" json:"filename"`
 	FilePath string `gorm:"not null" json:"file_path"`
 	FileSize int64  `json:"file_size"`
-	FileHash string `json:"file_hash"`
+	FileHash string `gorm:"index" json:"file_hash"`
+
+	// Container format detected at upload (uimage, squashfs, trx, ...) and
+	// the extraction backend analyzing it; failure statistics are kept per pair
+	FirmwareType string `gorm:"index" json:"firmware_type"`
+	Extractor    string `gorm:"default:emba" json:"extractor"`
+
+	// What the container's header hints at: byte order, CPU architecture
+	// and format details such as the compression or image name (JSON)
+	FirmwareEndianness string `json:"firmware_endianness"`
+	FirmwareArch       string `gorm:"index" json:"firmware_arch"`
+	FormatDetails      string `gorm:"type:text" json:"format_details"`
+
+	// Encrypted uploads a decryptor could decrypt are analyzed from the
+	// decrypted image (FilePath); the upload stays at EncryptedFilePath
+	Decryptor         string `json:"decryptor,omitempty"`
+	EncryptedFilePath string `json:"encrypted_file_path,omitempty"`
+
+	// CPU architecture (e.g. mipsel, arm64) and operating system family
+	// (linux, vxworks, ...) the firmware runs: hinted at by the header on
+	// upload, then set from what the analysis found. Emulation is chosen
+	// by them.
+	Architecture string `gorm:"index" json:"architecture"`
+	OSFamily     string `gorm:"index" json:"os_family"`
+
+	// Analyzed without EMBA, which wasn't available: the filesystem was
+	// extracted (binwalk or Odin's own unpacker), inventoried and checked
+	ExtractionOnly bool `gorm:"default:false;index" json:"extraction_only"`
+
+	// EMBA scan profile the analysis runs with
+	ScanProfile string `json:"scan_profile"`
+
+	// EMBA release detected when the analysis started and the output layout
+	// its logs were parsed with
+	EMBAVersion  string `gorm:"index" json:"emba_version"`
+	ParserLayout string `json:"parser_layout"`
+
+	// EMBA modules selected for this analysis (-m), comma separated; empty
+	// runs every module of the scan profile
+	Modules string `json:"modules"`
+
+	// EMBA modules kept from running for this project, on top of the
+	// instance's EMBA_EXCLUDED_MODULES (comma separated)
+	ExcludedModules string `json:"excluded_modules"`
+
+	// OSINT providers run for this project (comma separated); empty runs
+	// every one enabled on the instance, "none" none
+	OSINTProviders string `json:"osint_providers"`
+
+	// Query the OSINT providers again instead of using cached responses
+	OSINTRefresh bool `gorm:"default:false" json:"osint_refresh"`
+
+	// Latest OSINT collection, the one the results show
+	OSINTCollectedAt *time.Time `gorm:"index" json:"osint_collected_at"`
+
+	// When the OSINT is collected again because a provider's daily quota
+	// ran out during the last collection
+	OSINTDeferredUntil *time.Time `gorm:"index" json:"osint_deferred_until,omitempty"`
+
+	// Log directory of a past EMBA run the results are parsed from instead
+	// of analyzing the firmware
+	IngestLogDir string `json:"ingest_log_dir,omitempty"`
+
+	// Diff scans (EMBA's -o) compare the firmware of two analyses of the same
+	// device instead of scanning one: the base image against the target's
+	DiffBaseID   string `gorm:"index" json:"diff_base_id,omitempty"`
+	DiffTargetID string `gorm:"index" json:"diff_target_id,omitempty"`
+
+	// Module logs EMBA has finished so far, comma separated; their findings
+	// are saved as partial results while the analysis runs
+	FinishedModules string `gorm:"type:text" json:"finished_modules"`
+
+	// Malware verdict aggregated across antivirus/threat-intel engines
+	Disposition Disposition `gorm:"default:unknown" json:"disposition"`
+	NeedsReview bool        `gorm:"default:false" json:"needs_review"`
 
 	// Device metadata
+	Fleet         string `gorm:"index" json:"fleet"` // product line / device fleet the firmware belongs to
 	DeviceName    string `json:"device_name"`
 	DeviceModel   string `json:"device_model"`
 	DeviceVersion string `json:"device_version"`
 	Manufacturer  string `json:"manufacturer"`
 
+	// Where the device is deployed, which its CVEs' adjusted CVSS scores
+	// are computed for: how it can be reached (network, adjacent, local or
+	// physical) and its security requirements, e.g. CR:H/IR:M/AR:L
+	Reachability         string `json:"reachability,omitempty"`
+	SecurityRequirements string `json:"security_requirements,omitempty"`
+
+	// How well the image could be unpacked; details in ExtractionResults
+	ExtractionQuality ExtractionQuality `gorm:"index" json:"extraction_quality"`
+
+	// Kernel from EMBA's kernel modules (S24-S26); details in FirmwareInfo
+	KernelVersion      string `gorm:"index" json:"kernel_version"`
+	KernelEOL          bool   `gorm:"default:false;index" json:"kernel_eol"`
+	KernelEOLDate      string `json:"kernel_eol_date"`
+	KernelFailedChecks int    `gorm:"default:0" json:"kernel_failed_checks"`
+	KernelVerifiedCVEs int    `gorm:"default:0" json:"kernel_verified_cves"`
+
+	// RTOS of monolithic images, from its banner; details in FirmwareInfo
+	RTOS        string `gorm:"index" json:"rtos,omitempty"`
+	RTOSVersion string `json:"rtos_version,omitempty"`
+
 	// Analysis results (JSON fields)
 	FirmwareInfo      string `gorm:"type:text" json:"firmware_info"`
 	ExtractionResults string `gorm:"type:text" json:"extraction_results"`
 
+	// Fingerprint of the analysis environment (EMBA version, profile, modules,
+	// feed snapshots, parser version) the results were produced with
+	Environment     string `gorm:"type:text" json:"environment"`
+	EnvironmentHash string `gorm:"index" json:"environment_hash"`
+
+	// Derived counters, recomputed on completion and by backfill
+	FindingCount  int `gorm:"default:0" json:"finding_count"`
+	CVECount      int `gorm:"default:0" json:"cve_count"`
+	CriticalCount int `gorm:"default:0" json:"critical_count"`
+	HighCount     int `gorm:"default:0" json:"high_count"`
+	MediumCount   int `gorm:"default:0" json:"medium_count"`
+	LowCount      int `gorm:"default:0" json:"low_count"`
+	InfoCount     int `gorm:"default:0" json:"info_count"`
+
+	// Frozen results are locked against changes once an assessment is delivered
+	FrozenAt   *time.Time `json:"frozen_at"`
+	FrozenBy   string     `json:"frozen_by"`
+	FrozenHash string     `json:"frozen_hash"` // content hash of the result set at freeze time
+
+	// Retries after transient failures
+	RetryCount  int        `gorm:"default:0" json:"retry_count"`
+	NextRetryAt *time.Time `json:"next_retry_at"`
+
 	// Timestamps
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at"` // refreshed by the worker processing the project
 
 	// Relationships
 	Findings     []Finding     `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"findings,omitempty"`
 	CVEFindings  []CVEFinding  `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"cve_findings,omitempty"`
 	OSINTResults []OSINTResult `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"osint_results,omitempty"`
+	EngineVerdicts []EngineVerdict `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"engine_verdicts,omitempty"`
+	SBOMComponents []SBOMComponent `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"sbom_components,omitempty"`
+	BinaryAnalyses []BinaryAnalysis `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"binary_analyses,omitempty"`
+	PasswordHashes []PasswordHash   `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"password_hashes,omitempty"`
+	EmulationResults []EmulationResult `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"emulation_results,omitempty"`
+	KeyMaterials     []KeyMaterial     `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"key_materials,omitempty"`
+	BinaryHashes     []BinaryHash      `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"binary_hashes,omitempty"`
+	Files            []FirmwareFile    `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"files,omitempty"`
 }
 
 // BeforeCreate generates UUID for new projects
@@ -87,6 +360,29 @@ func (p *Project) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Frozen reports whether the project's results are locked
+func (p *Project) Frozen() bool {
+	return p.FrozenAt != nil
+}
+
+// ExtractionData decodes the project's extraction results JSON
+func (p *Project) ExtractionData() map[string]interface{} {
+	data := make(map[string]interface{})
+	if p.ExtractionResults != "" {
+		_ = json.Unmarshal([]byte(p.ExtractionResults), &data)
+	}
+	return data
+}
+
+// SetExtractionData encodes data as the project's extraction results JSON
+func (p *Project) SetExtractionData(data map[string]interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	p.ExtractionResults = string(encoded)
+}
+
 // Finding represents a security finding from analysis
 type Finding struct {
 	ID        uint        `gorm:"primaryKey" json:"id"`
@@ -100,38 +396,319 @@ type Finding struct {
 	FilePath   string `json:"file_path"`
 	LineNumber int    `json:"line_number"`
 
+	// File permissions, for weak permission findings (S40)
+	FileMode         string `json:"file_mode,omitempty"`                      // e.g. -rwsr-xr-x
+	FileOwner        string `json:"file_owner,omitempty"`                     // user:group
+	PermissionIssues string `gorm:"index" json:"permission_issues,omitempty"` // e.g. setuid,world_writable
+
 	// Finding data
 	Content         string `json:"content"`
 	Context         string `json:"context"`
 	FindingMetadata string `gorm:"type:text" json:"finding_metadata"`
 
+	// Fingerprint identifies the same underlying issue across parser sources
+	Fingerprint string `gorm:"index" json:"fingerprint"`
+
+	// Provenance: the EMBA module, log file (relative to the run's log
+	// directory) and line the finding was parsed from
+	Module     string `gorm:"index" json:"module"` // e.g. S25
+	SourceFile string `json:"source_file"`
+	SourceLine int    `json:"source_line"`
+
+	// Weakness the finding cites, e.g. CWE-787 for an out-of-bounds write
+	// cwe_checker (S120) reported
+	CWE string `gorm:"index" json:"cwe,omitempty"`
+
+	// ATT&CK for ICS and EMB3D techniques the finding enables, comma
+	// separated (e.g. T0812,TID-311)
+	Techniques string `gorm:"index" json:"techniques,omitempty"`
+
+	// Confidence in the finding, set by the parser from its source
+	Confidence Confidence `gorm:"default:medium;index" json:"confidence"`
+
+	// How often the parser found the issue across EMBA's outputs before
+	// collapsing the duplicates into this record
+	OccurrenceCount int `gorm:"default:1" json:"occurrence_count"`
+
+	// Slots of an A/B image the finding was found in, comma separated (a,b)
+	// when both copies have it
+	Slot string `gorm:"index" json:"slot,omitempty"`
+
+	// Partial findings were saved per finished module while EMBA was still
+	// running; the final results replace them
+	Partial bool `gorm:"default:false;index" json:"partial"`
+
+	// Severity override: an analyst's rating replaces the parser's, kept in
+	// OriginalSeverity, with who changed it, when and why
+	OriginalSeverity   RiskLevel  `json:"original_severity,omitempty"`
+	SeverityReason     string     `gorm:"type:text" json:"severity_reason,omitempty"`
+	SeverityOverrideBy string     `json:"severity_override_by,omitempty"`
+	SeverityOverrideAt *time.Time `json:"severity_override_at,omitempty"`
+
+	// Triage: where an analyst's review stands, who the finding is
+	// assigned to and who triaged it last
+	TriageStatus TriageStatus `gorm:"default:'';index" json:"triage_status,omitempty"`
+	Assignee     string       `gorm:"index" json:"assignee,omitempty"`
+	TriagedBy    string       `json:"triaged_by,omitempty"`
+	TriagedAt    *time.Time   `json:"triaged_at,omitempty"`
+
+	// Free-form labels of the analysts' workflow, e.g. report-chapter-3 or
+	// needs-retest, comma separated
+	Labels string `gorm:"index" json:"labels,omitempty"`
+
+	// Canonical finding an analyst merged this one into as a duplicate,
+	// which counts its occurrences; merged findings are left out of the
+	// project's counts and the default views
+	MergedIntoID *uint      `gorm:"index" json:"merged_into_id,omitempty"`
+	MergedBy     string     `json:"merged_by,omitempty"`
+	MergedAt     *time.Time `json:"merged_at,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 
 	// Relationships
 	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
 }
 
+// BeforeCreate fills in the fingerprint for new findings
+func (f *Finding) BeforeCreate(tx *gorm.DB) error {
+	if f.Fingerprint == "" {
+		f.Fingerprint = f.ComputeFingerprint()
+	}
+	return nil
+}
+
+// ComputeFingerprint hashes the normalized title, file path and source module.
+// Paths into the run's log directory are taken relative to it, so the same
+// finding has the same fingerprint in every run.
+func (f *Finding) ComputeFingerprint() string {
+	module := ""
+	if f.FindingMetadata != "" {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(f.FindingMetadata), &metadata); err == nil {
+			if m, ok := metadata["module"].(string); ok {
+				module = m
+			}
+		}
+	}
+
+	location := f.FilePath
+	if i := strings.Index(location, "/"+f.SourceFile); f.SourceFile != "" && i > 0 {
+		location = strings.ReplaceAll(location, location[:i+1], "")
+	}
+
+	title := strings.Join(strings.Fields(strings.ToLower(f.Title)), " ")
+	sum := sha256.Sum256([]byte(strings.Join([]string{title, location, module}, "|")))
+	return fmt.Sprintf("%x", sum)
+}
+
 // CVEFinding represents a CVE vulnerability finding
 type CVEFinding struct {
 	ID        uint   `gorm:"primaryKey" json:"id"`
 	ProjectID string `gorm:"not null;index" json:"project_id"`
 
-	CVEID           string `gorm:"not null" json:"cve_id"`
+	CVEID           string `gorm:"not null;index" json:"cve_id"`
 	SoftwareName    string `gorm:"not null" json:"software_name"`
 	SoftwareVersion string `json:"software_version"`
 
-	// CVE details
-	Description   string    `json:"description"`
+	// Fields tagged gorm:"-" are the CVE's canonical data, stored once in the
+	// shared cves table: set by the parsers before the finding is saved,
+	// which adds what the table lacks, and filled in from the table when CVE
+	// is preloaded. Frozen projects' findings are filled in from the
+	// CVESnapshot taken when they were frozen instead.
+
+	// CVE details. The score is the CVE's rating in this project: what EMBA
+	// reported, or NVD's once enriched; frozen projects keep theirs.
+	Description   string    `gorm:"-" json:"description"`
 	SeverityScore float64   `json:"severity_score"`
 	SeverityLevel RiskLevel `json:"severity_level"`
+	CVSSVector    string    `json:"cvss_vector"`
+	Source        string    `json:"source"` // vulnerability database, e.g. NVD
+
+	// The score adjusted to the project: the CVSS vector's environmental
+	// score for the device's deployment and the exploits known, and the
+	// vector it was computed from. Zero for vectors that can't be scored.
+	AdjustedScore    float64   `json:"adjusted_score,omitempty"`
+	AdjustedSeverity RiskLevel `json:"adjusted_severity,omitempty"`
+	AdjustedVector   string    `json:"adjusted_vector,omitempty"`
+
+	// Binary the vulnerable version was detected in
+	BinaryPath string `json:"binary_path"`
+
+	// Exploitation
+	ExploitAvailable bool   `gorm:"default:false" json:"exploit_available"`
+	ExploitSources   string `gorm:"type:text" json:"exploit_sources"` // JSON array, e.g. ["exploit-db","metasploit"]
+	KnownExploited   bool   `gorm:"default:false" json:"known_exploited"` // listed in CISA KEV
+
+	// Known exploits (JSON arrays): Exploit-DB IDs, Metasploit module paths
+	// and proof of concept repositories, e.g. from PoC-in-GitHub
+	ExploitDBIDs      string `gorm:"type:text" json:"exploit_db_ids,omitempty"`
+	MetasploitModules string `gorm:"type:text" json:"metasploit_modules,omitempty"`
+	PoCURLs           string `gorm:"type:text" json:"poc_urls,omitempty"`
+
+	// Saved while EMBA was still running, see Finding.Partial
+	Partial bool `gorm:"default:false;index" json:"partial"`
+
+	// SBOM component the vulnerable version belongs to and how it was
+	// matched: cpe, purl, name_version or name
+	ComponentID    *uint  `gorm:"index" json:"component_id,omitempty"`
+	ComponentMatch string `json:"component_match,omitempty"`
+
+	// Evidence of the match: the CPE of NVD's applicability statement that
+	// includes the component's version, or the component's CPE or purl
+	// naming the version, and the versions it covers, e.g. >=1.20.0 <1.36.1
+	// or =1.35.0
+	MatchCriteria     string `json:"match_criteria,omitempty"`
+	MatchVersionRange string `json:"match_version_range,omitempty"`
 
 	// References (JSON array)
-	References string `gorm:"type:text" json:"references"`
+	References string `gorm:"-" json:"references"`
+
+	// NVD's record of the CVE, filled in in the background once the
+	// analysis completed: the CVSS v3 subscores, weaknesses and dates.
+	// NVDEnrichedAt is nil until NVD's score was applied to this finding.
+	CVSSVersion         string     `gorm:"-" json:"cvss_version,omitempty"` // 3.1 or 3.0
+	ExploitabilityScore float64    `gorm:"-" json:"exploitability_score,omitempty"`
+	ImpactScore         float64    `gorm:"-" json:"impact_score,omitempty"`
+	CWEIDs              string     `gorm:"-" json:"cwe_ids,omitempty"` // comma separated, e.g. CWE-787,CWE-121
+	PublishedAt         *time.Time `gorm:"-" json:"published_at,omitempty"`
+	LastModifiedAt      *time.Time `gorm:"-" json:"last_modified_at,omitempty"`
+	CVSSV4Vector        string     `gorm:"-" json:"cvss_v4_vector,omitempty"`
+	CVSSV4Score         float64    `gorm:"-" json:"cvss_v4_score,omitempty"`
+	NVDEnrichedAt       *time.Time `gorm:"index" json:"nvd_enriched_at,omitempty"`
+
+	// EPSS: the probability the CVE is exploited in the wild within 30 days
+	// and its percentile among all CVEs, refreshed daily in the background.
+	// EPSSCheckedAt is nil until the first lookup.
+	EPSSScore      float64    `gorm:"-" json:"epss_score,omitempty"`
+	EPSSPercentile float64    `gorm:"-" json:"epss_percentile,omitempty"`
+	EPSSCheckedAt  *time.Time `gorm:"-" json:"epss_checked_at,omitempty"`
+
+	// When the CVE monitor added the finding to the completed analysis, for
+	// a CVE published or matched after it; nil for the analysis' own
+	MonitoredAt *time.Time `gorm:"index" json:"monitored_at,omitempty"`
+
+	// Rule suppressing the CVE as an accepted risk: hidden from the default
+	// views and left out of the risk rating
+	SuppressionID *uint `gorm:"index" json:"suppression_id,omitempty"`
+
+	// Whether the component is affected, checked against NVD's
+	// applicability statements once enriched, and why: version_in_range,
+	// platform_condition, version_out_of_range, no_version,
+	// product_not_listed or no_applicability_data. Rejected findings are
+	// false positives, hidden from the default views and left out of the
+	// risk rating.
+	MatchStatus MatchStatus `gorm:"default:'';index" json:"match_status,omitempty"`
+	MatchReason string      `json:"match_reason,omitempty"`
+	MatchNote   string      `gorm:"type:text" json:"match_note,omitempty"` // the analyst's reasoning
+	ReviewedBy  string      `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time  `json:"reviewed_at,omitempty"`
+
+	// Severity override, see Finding: the rating EMBA or NVD assigned is
+	// kept in OriginalSeverity, where NVD's enrichment updates it
+	OriginalSeverity   RiskLevel  `json:"original_severity,omitempty"`
+	SeverityReason     string     `gorm:"type:text" json:"severity_reason,omitempty"`
+	SeverityOverrideBy string     `json:"severity_override_by,omitempty"`
+	SeverityOverrideAt *time.Time `json:"severity_override_at,omitempty"`
+
+	// The CVE's record as of the project's freeze (JSON), so enriching the
+	// shared record doesn't change frozen results
+	CVESnapshot string `gorm:"type:text" json:"-"`
 
 	CreatedAt time.Time `json:"created_at"`
 
 	// Relationships
 	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
+	CVE     *CVE    `gorm:"foreignKey:CVEID;references:ID;constraint:-" json:"-"`
+}
+
+// BeforeCreate adds the finding's canonical data to the shared cves
+// table: a new CVE is inserted, a known one only gets the fields it lacks
+func (c *CVEFinding) BeforeCreate(tx *gorm.DB) error {
+	if c.CVEID == "" {
+		return nil
+	}
+	record := c.Record()
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "description"}, Value: gorm.Expr("CASE WHEN cves.description = '' THEN excluded.description ELSE cves.description END")},
+			{Column: clause.Column{Name: "references"}, Value: gorm.Expr("CASE WHEN cves.`references` = '' THEN excluded.`references` ELSE cves.`references` END")},
+			{Column: clause.Column{Name: "cwe_ids"}, Value: gorm.Expr("CASE WHEN cves.cwe_ids = '' THEN excluded.cwe_ids ELSE cves.cwe_ids END")},
+			{Column: clause.Column{Name: "published_at"}, Value: gorm.Expr("COALESCE(cves.published_at, excluded.published_at)")},
+			{Column: clause.Column{Name: "last_modified_at"}, Value: gorm.Expr("COALESCE(cves.last_modified_at, excluded.last_modified_at)")},
+		},
+	}).Create(&record).Error
+}
+
+// AfterFind fills the canonical data in from the snapshot of a frozen
+// project's finding, or else from the preloaded CVE
+func (c *CVEFinding) AfterFind(tx *gorm.DB) error {
+	if c.CVESnapshot != "" {
+		var record CVE
+		if err := json.Unmarshal([]byte(c.CVESnapshot), &record); err != nil {
+			return fmt.Errorf("failed to decode CVE snapshot of finding %d: %w", c.ID, err)
+		}
+		c.Fill(&record)
+		return nil
+	}
+	if c.CVE != nil {
+		c.Fill(c.CVE)
+	}
+	return nil
+}
+
+// Fill sets the canonical data of the finding from the CVE's record
+func (c *CVEFinding) Fill(record *CVE) {
+	c.Description = record.Description
+	c.References = record.References
+	c.CVSSVersion = record.CVSSVersion
+	c.ExploitabilityScore = record.ExploitabilityScore
+	c.ImpactScore = record.ImpactScore
+	c.CWEIDs = record.CWEIDs
+	c.PublishedAt = record.PublishedAt
+	c.LastModifiedAt = record.LastModifiedAt
+	c.CVSSV4Vector = record.CVSSV4Vector
+	c.CVSSV4Score = record.CVSSV4Score
+	c.EPSSScore = record.EPSSScore
+	c.EPSSPercentile = record.EPSSPercentile
+	c.EPSSCheckedAt = record.EPSSCheckedAt
+}
+
+// Record returns the canonical data the finding carries as a CVE record
+func (c *CVEFinding) Record() CVE {
+	return CVE{
+		ID:                  c.CVEID,
+		Description:         c.Description,
+		References:          c.References,
+		CVSSVersion:         c.CVSSVersion,
+		ExploitabilityScore: c.ExploitabilityScore,
+		ImpactScore:         c.ImpactScore,
+		CWEIDs:              c.CWEIDs,
+		PublishedAt:         c.PublishedAt,
+		LastModifiedAt:      c.LastModifiedAt,
+		CVSSV4Vector:        c.CVSSV4Vector,
+		CVSSV4Score:         c.CVSSV4Score,
+	}
+}
+
+// Exploitable reports whether the CVE has a public exploit or is known to
+// be exploited in the wild
+func (c *CVEFinding) Exploitable() bool {
+	return c.ExploitAvailable || c.KnownExploited
+}
+
+// Priority ranks the CVE for fixing: EPSS × CVSS × exploit availability,
+// which weighs a public exploit twice and exploitation in the wild (CISA
+// KEV) three times. Between 0 and 30.
+func (c *CVEFinding) Priority() float64 {
+	weight := 1.0
+	switch {
+	case c.KnownExploited:
+		weight = 3
+	case c.ExploitAvailable:
+		weight = 2
+	}
+	return c.EPSSScore * c.SeverityScore * weight
 }
 
 // OSINTResult represents OSINT intelligence data
@@ -148,11 +725,632 @@ type OSINTResult struct {
 	URL         string `json:"url"`
 	Data        string `gorm:"type:text" json:"data"`
 
-	// Relevance scoring
-	ConfidenceScore int `gorm:"default:0" json:"confidence_score"` // 0-100
+	// Relevance scoring: ConfidenceScore (0-100) combines how specific the
+	// query that found the result is, the reliability of its source and its
+	// corroboration by other sources finding the same entity (e.g.
+	// certificate:<fingerprint>); ScoreBreakdown records how (JSON)
+	ConfidenceScore int    `gorm:"default:0" json:"confidence_score"`
+	Specificity     int    `gorm:"default:0" json:"specificity"`
+	Entity          string `gorm:"index" json:"entity,omitempty"`
+	ScoreBreakdown  string `gorm:"type:text" json:"score_breakdown,omitempty"`
+
+	// Collection the result belongs to: the analysis' OSINT stage or a
+	// scheduled refresh. Earlier collections are kept.
+	CollectedAt *time.Time `gorm:"index" json:"collected_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+// SBOMComponent is a software component of the firmware from EMBA's
+// CycloneDX SBOM (F15)
+type SBOMComponent struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID string `gorm:"not null;index" json:"project_id"`
+
+	BOMRef   string `json:"bom_ref"`
+	Type     string `json:"type"` // library, application, operating-system, firmware, ...
+	Name     string `gorm:"not null;index" json:"name"`
+	Version  string `json:"version"`
+	PURL     string `gorm:"index" json:"purl"`
+	CPE      string `gorm:"index" json:"cpe"`
+	Licenses string `gorm:"type:text" json:"licenses"` // JSON array of SPDX IDs or names
+	License  string `gorm:"index" json:"license"`      // SPDX expression of all of them, e.g. GPL-2.0-only AND MIT
+	Supplier string `json:"supplier"`
+
+	LicenseCategory LicenseCategory `gorm:"index" json:"license_category"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Project     Project      `gorm:"foreignKey:ProjectID" json:"-"`
+	CVEFindings []CVEFinding `gorm:"foreignKey:ComponentID;constraint:OnDelete:SET NULL" json:"cve_findings,omitempty"`
+}
+
+// BinaryAnalysis records the exploit mitigations of one binary of the
+// firmware from EMBA's binary protection check (S12)
+type BinaryAnalysis struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID string `gorm:"not null;index" json:"project_id"`
+	FilePath  string `gorm:"not null;index" json:"file_path"`
+
+	RELRO       bool `json:"relro"`      // partial or full
+	FullRELRO   bool `json:"full_relro"` // GOT is read-only too
+	StackCanary bool `json:"stack_canary"`
+	NX          bool `json:"nx"`
+	PIE         bool `json:"pie"`
+	Fortify     bool `json:"fortify"`
+	Stripped    bool `json:"stripped"`
+	RPath       bool `json:"rpath"` // RPATH or RUNPATH set
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+// EmulationResult is the outcome of EMBA's system emulation (L10): the
+// environment the firmware was booted in and the services that came up
+type EmulationResult struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID string `gorm:"not null;index" json:"project_id"`
+
+	Success      bool   `gorm:"default:false" json:"success"`
+	Architecture string `json:"architecture"`
+	Kernel       string `json:"kernel"`                        // kernel EMBA booted the firmware with
+	InitProcess  string `json:"init_process"`                  // e.g. /sbin/init
+	IPAddresses  string `gorm:"type:text" json:"ip_addresses"` // JSON array
+	Services     string `gorm:"type:text" json:"services"`     // JSON array of {name, port, protocol}
+	Detail       string `json:"detail"`                        // the last status line EMBA logged
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+// FirmwareFile is an entry of the manifest of the files extracted from a
+// project's firmware, kept so files can be searched across projects without
+// extracting anything again
+type FirmwareFile struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID string `gorm:"not null;index" json:"project_id"`
+	Path      string `gorm:"not null" json:"path"` // relative to the extraction tree
+	Name      string `gorm:"index" json:"name"`    // base name
+
+	Size     int64  `json:"size"`
+	SHA256   string `gorm:"index" json:"sha256"`
+	MIMEType string `gorm:"index" json:"mime_type"`
+	FileType string `gorm:"index" json:"file_type"` // elf, script, text, data or a container format
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+// BinaryHash holds the hashes of an ELF binary of the extracted firmware.
+// The ssdeep hash finds the same code in other firmware even when it was
+// rebuilt or patched; BlockSize limits the candidates to compare it with.
+type BinaryHash struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID string `gorm:"not null;index" json:"project_id"`
+	FilePath  string `gorm:"not null;index" json:"file_path"`
+
+	Size      int64  `json:"size"`
+	SHA256    string `gorm:"index" json:"sha256"`
+	SSDeep    string `json:"ssdeep"`
+	BlockSize uint64 `gorm:"index" json:"-"`
 
 	CreatedAt time.Time `json:"created_at"`
 
 	// Relationships
 	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
 }
+
+// Kinds of key material
+const (
+	KeyKindPrivateKey  = "private_key"
+	KeyKindPublicKey   = "public_key"
+	KeyKindCertificate = "certificate"
+)
+
+// KeyMaterial is a key or X.509 certificate found in the extracted
+// firmware. Fingerprint identifies the key pair (the SHA-256 of the public
+// key), so a certificate, its private key and the same key in other
+// firmware share it.
+type KeyMaterial struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID string `gorm:"not null;index" json:"project_id"`
+	FilePath  string `gorm:"not null" json:"file_path"`
+
+	Kind        string `gorm:"not null;index" json:"kind"`
+	Format      string `json:"format"`    // pem, der or openssh
+	Algorithm   string `json:"algorithm"` // RSA, ECDSA, Ed25519, DSA
+	KeySize     int    `json:"key_size"`  // bits
+	Fingerprint string `gorm:"index" json:"fingerprint"`
+	Encrypted   bool   `gorm:"default:false" json:"encrypted"` // passphrase protected private key
+
+	// Certificates only
+	Subject      string     `json:"subject,omitempty"`
+	Issuer       string     `json:"issuer,omitempty"`
+	SerialNumber string     `json:"serial_number,omitempty"`
+	NotBefore    *time.Time `json:"not_before,omitempty"`
+	NotAfter     *time.Time `json:"not_after,omitempty"`
+	SelfSigned   bool       `gorm:"default:false" json:"self_signed"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+// EmulatedService is a network service that came up in system emulation
+type EmulatedService struct {
+	Name     string `json:"name"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// PasswordHash is an account's password hash found in the firmware by
+// EMBA's password file checks (S45, S107), with the outcome of cracking it
+type PasswordHash struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID string `gorm:"not null;index" json:"project_id"`
+	FilePath  string `gorm:"not null" json:"file_path"` // e.g. /etc/shadow
+	Username  string `gorm:"index" json:"username"`
+	Hash      string `json:"hash"`
+	Algorithm string `gorm:"index" json:"algorithm"`
+
+	CrackStatus     CrackStatus `gorm:"index" json:"crack_status"` // empty when there is nothing to crack
+	CrackedPassword string      `json:"cracked_password,omitempty"`
+	CrackCheckedAt  *time.Time  `json:"crack_checked_at"`     // when cracking started or finished
+	FindingID       *uint       `json:"finding_id,omitempty"` // the critical finding of a cracked password
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+// CVE is the canonical record of a vulnerability, shared by the findings of
+// every project naming it (their CVEID), so it is stored once and
+// enrichment updates it for all of them: what the parsers, advisory
+// databases and NVD reported of it, and its EPSS score
+type CVE struct {
+	ID          string `gorm:"primaryKey" json:"id"` // CVE ID, or the GHSA ID of an advisory without one
+	Description string `gorm:"type:text;default:''" json:"description"`
+	References  string `gorm:"type:text;default:''" json:"references"` // JSON array
+
+	// From NVD's record: the CVSS v3 subscores, weaknesses and dates.
+	// NVDEnrichedAt is nil until NVD was asked.
+	CVSSVersion         string     `json:"cvss_version,omitempty"`
+	ExploitabilityScore float64    `json:"exploitability_score,omitempty"`
+	ImpactScore         float64    `json:"impact_score,omitempty"`
+	CWEIDs              string     `gorm:"default:'';index" json:"cwe_ids,omitempty"`
+	PublishedAt         *time.Time `json:"published_at,omitempty"`
+	LastModifiedAt      *time.Time `json:"last_modified_at,omitempty"`
+	NVDEnrichedAt       *time.Time `json:"nvd_enriched_at,omitempty"`
+
+	// NVD's CVSS v4.0 vector and base score, alongside the v3 one the
+	// findings are rated by
+	CVSSV4Vector string  `json:"cvss_v4_vector,omitempty"`
+	CVSSV4Score  float64 `json:"cvss_v4_score,omitempty"`
+
+	// EPSS: the probability the CVE is exploited in the wild within 30 days
+	// and its percentile among all CVEs. EPSSCheckedAt is nil until the
+	// first lookup.
+	EPSSScore      float64    `gorm:"index" json:"epss_score,omitempty"`
+	EPSSPercentile float64    `json:"epss_percentile,omitempty"`
+	EPSSCheckedAt  *time.Time `gorm:"index" json:"epss_checked_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CVESuppression accepts the risk of a CVE: the CVE findings it matches
+// are hidden from the default views and left out of the projects' risk
+// rating until it expires or is deleted
+type CVESuppression struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	OrgID     string `gorm:"default:default;index" json:"org_id"`
+	ProjectID string `gorm:"index" json:"project_id,omitempty"` // empty for every project of the organization
+	CVEID     string `gorm:"not null;index" json:"cve_id"`
+	Component string `json:"component,omitempty"` // software name the rule is limited to, empty for any
+
+	Justification string     `gorm:"type:text;not null" json:"justification"`
+	ExpiresAt     *time.Time `gorm:"index" json:"expires_at,omitempty"` // nil for never
+	CreatedBy     string     `json:"created_by"`
+	UpdatedBy     string     `json:"updated_by"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Active reports whether the rule is in force at the given time
+func (s *CVESuppression) Active(at time.Time) bool {
+	return s.ExpiresAt == nil || s.ExpiresAt.After(at)
+}
+
+// NVDRecord caches NVD's record of a CVE, shared by all projects and
+// workers
+type NVDRecord struct {
+	CVEID     string    `gorm:"primaryKey" json:"cve_id"`
+	Found     bool      `json:"found"`                  // false when NVD doesn't know the CVE
+	Data      string    `gorm:"type:text" json:"data"` // nvd.CVE as JSON
+	FetchedAt time.Time `gorm:"index" json:"fetched_at"`
+}
+
+// KEVEntry is a CVE of CISA's Known Exploited Vulnerabilities catalog,
+// imported into the local mirror for offline mode
+type KEVEntry struct {
+	CVEID             string `gorm:"primaryKey" json:"cve_id"`
+	VendorProject     string `json:"vendor_project"`
+	Product           string `json:"product"`
+	VulnerabilityName string `json:"vulnerability_name"`
+	DateAdded         string `json:"date_added"`     // 2006-01-02
+	RansomwareUse     string `json:"ransomware_use"` // Known or Unknown
+}
+
+// EPSSRecord is a CVE's score in the imported mirror of FIRST's daily EPSS
+// CSV
+type EPSSRecord struct {
+	CVEID      string  `gorm:"primaryKey" json:"cve_id"`
+	EPSS       float64 `json:"epss"`
+	Percentile float64 `json:"percentile"`
+	Date       string  `json:"date"` // score date of the CSV
+}
+
+// ExploitDBEntry is an exploit of the imported exploit-db index for a CVE;
+// an exploit of several CVEs has an entry for each
+type ExploitDBEntry struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	ExploitID   string `gorm:"not null;index" json:"exploit_id"`
+	CVEID       string `gorm:"not null;index" json:"cve_id"`
+	Description string `json:"description"`
+	Type        string `json:"type"`     // dos, local, remote, webapps
+	Platform    string `json:"platform"` // e.g. hardware, linux
+	Verified    bool   `json:"verified"`
+}
+
+// MirrorImport records the last import of a dataset into the local mirror
+type MirrorImport struct {
+	Dataset    string    `gorm:"primaryKey" json:"dataset"` // nvd, kev, epss, exploitdb
+	Source     string    `json:"source"`                    // the imported path
+	Records    int       `json:"records"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// CVEMonitorRun is a run of the CVE monitor, which matches the SBOM
+// components of completed analyses against the CVEs NVD published or
+// changed since the last successful run
+type CVEMonitorRun struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Slot        string     `gorm:"uniqueIndex" json:"slot"` // start of the interval it ran in, so it runs once per interval
+	Source      string     `json:"source"`                  // the NVD feed URL, or mirror offline
+	Since       time.Time  `json:"since"`                   // CVEs modified from then on were matched
+	CVEs        int        `gorm:"column:cves" json:"cves"` // CVEs modified since then
+	Findings    int        `json:"findings"`                // CVE findings added
+	Projects    int        `json:"projects"`                // projects they were added to
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt   time.Time  `gorm:"index" json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// VulnDBSync is a synchronization of the local vulnerability data, on
+// schedule or started by an admin: the datasets downloaded into the mirror
+// and the update of EMBA's CVE database
+type VulnDBSync struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Slot        string     `gorm:"uniqueIndex" json:"slot"` // start of the interval of a scheduled sync, so it runs once per interval
+	Trigger     string     `json:"trigger"`                 // schedule or admin
+	RequestedBy string     `json:"requested_by,omitempty"`
+	Datasets    string     `gorm:"type:text" json:"datasets"`              // JSON array of the datasets' sources, record counts and errors
+	Records     int        `json:"records"`                                // records imported, all datasets
+	EMBAUpdate  string     `gorm:"type:text" json:"emba_update,omitempty"` // end of the EMBA update's output
+	Error       string     `gorm:"type:text" json:"error,omitempty"`       // what failed, a line each
+	StartedAt   time.Time  `gorm:"index" json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// OSINTUsage counts the calls to an OSINT provider on a UTC day, shared by
+// all workers, against its daily quota
+type OSINTUsage struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Provider    string     `gorm:"not null;uniqueIndex:idx_osint_usage_day" json:"provider"`
+	Day         string     `gorm:"not null;uniqueIndex:idx_osint_usage_day" json:"day"` // 2006-01-02
+	Calls       int        `gorm:"default:0" json:"calls"`
+	LastCallAt  *time.Time `json:"last_call_at"`
+	ExhaustedAt *time.Time `json:"exhausted_at"` // when the provider answered its quota is used up
+}
+
+// Integration is a stored API key of an OSINT provider. The secret is
+// encrypted with INTEGRATIONS_KEY and never returned.
+type Integration struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	Provider   string `gorm:"not null;index" json:"provider"`
+	Name       string `json:"name"`
+	KeyID      string `json:"key_id,omitempty"` // public part of the credential, e.g. Censys' API ID
+	Secret     string `gorm:"type:text;not null" json:"-"`
+	SecretHint string `json:"secret_hint"` // last characters of the secret
+	Enabled    bool   `gorm:"default:true;index" json:"enabled"`
+
+	// Usage counters, updated by the workers using the key
+	UsageCount  int64      `gorm:"default:0" json:"usage_count"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ThreatFeed is a TAXII 2.1 collection whose STIX indicators are matched
+// against the analyzed firmware. The password is encrypted with
+// INTEGRATIONS_KEY and never returned.
+type ThreatFeed struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	Name         string `gorm:"not null" json:"name"`
+	APIRoot      string `gorm:"not null" json:"api_root"` // e.g. https://taxii.example.com/api1/
+	CollectionID string `gorm:"not null" json:"collection_id"`
+	Username     string `json:"username,omitempty"`
+	Password     string `gorm:"type:text" json:"-"`
+	Enabled      bool   `gorm:"default:true;index" json:"enabled"`
+
+	// Polling state: the date the server added the last ingested object,
+	// from which the next poll continues
+	AddedAfter     string     `json:"added_after,omitempty"`
+	LastPolledAt   *time.Time `json:"last_polled_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	IndicatorCount int64      `gorm:"default:0" json:"indicator_count"`
+
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ThreatIndicator is an observable of a STIX indicator of a threat feed: a
+// file hash, IP address, domain or URL
+type ThreatIndicator struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	FeedID     uint       `gorm:"not null;index" json:"feed_id"`
+	STIXID     string     `gorm:"not null;index" json:"stix_id"` // indicator--<uuid>
+	Type       string     `gorm:"not null" json:"type"`          // sha256, sha1, md5, ipv4, ipv6, domain or url
+	Value      string     `gorm:"not null;index" json:"value"`
+	Name       string     `json:"name"`
+	Labels     string     `json:"labels,omitempty"` // comma separated
+	Confidence int        `json:"confidence"`       // STIX confidence, 0-100
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// OSINTCacheEntry caches an OSINT provider's response to a query, shared by
+// all projects and workers
+type OSINTCacheEntry struct {
+	Key       string    `gorm:"primaryKey" json:"key"` // SHA-256 of provider and query
+	Provider  string    `gorm:"index" json:"provider"`
+	Query     string    `gorm:"type:text" json:"query"`
+	Data      string    `gorm:"type:text" json:"data"` // the response as JSON
+	FetchedAt time.Time `gorm:"index" json:"fetched_at"`
+}
+
+// DefaultCredential is a default username and password a vendor documents
+// for its devices, or for one model when Model is set
+type DefaultCredential struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Vendor   string `gorm:"not null;index" json:"vendor"`
+	Model    string `json:"model,omitempty"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Comment  string `json:"comment,omitempty"`
+	Source   string `gorm:"index" json:"source"` // shipped, or the URL of the dataset
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EngineVerdict records the verdict of a single antivirus/threat-intel engine
+type EngineVerdict struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID string `gorm:"not null;index" json:"project_id"`
+
+	Engine      string      `gorm:"not null" json:"engine"` // clamav, virustotal, sandbox
+	Disposition Disposition `gorm:"not null" json:"disposition"`
+	Signature   string      `json:"signature"`
+	Detail      string      `gorm:"type:text" json:"detail"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Relationships
+	Project Project `gorm:"foreignKey:ProjectID" json:"-"`
+}
+
+// OrgSettings holds runtime settings of an organization, editable via the admin API
+type OrgSettings struct {
+	OrgID               string `gorm:"primaryKey" json:"org_id"`
+	SupportedExtensions string `gorm:"type:text" json:"-"` // comma separated, e.g. ".bin,.img"
+	MaxFileSize         int64  `json:"max_file_size"`
+	MaxConcurrentScans  int    `json:"max_concurrent_scans"` // 0 uses the instance default
+	UpdatedBy           string `json:"updated_by"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Extensions returns the supported file extensions as a list
+func (s *OrgSettings) Extensions() []string {
+	var extensions []string
+	for _, ext := range strings.Split(s.SupportedExtensions, ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			extensions = append(extensions, ext)
+		}
+	}
+	return extensions
+}
+
+// AuditLog records administrative changes
+type AuditLog struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	Actor      string `gorm:"not null;index" json:"actor"`
+	Action     string `gorm:"not null;index" json:"action"`
+	Resource   string `gorm:"not null" json:"resource"`
+	ResourceID string `gorm:"index" json:"resource_id"`
+	Details    string `gorm:"type:text" json:"details"`
+
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// WorkerOfflineAfter is how long a worker may miss heartbeats before it is considered offline
+const WorkerOfflineAfter = 2 * time.Minute
+
+// Webhook event types a subscription can receive
+const (
+	WebhookEventAnalysis = "analysis" // analysis completed or failed
+	WebhookEventFinding  = "finding"  // findings of a completed analysis
+	WebhookEventCVE      = "cve"      // CVE findings of a completed analysis
+	WebhookEventExposure = "exposure" // material change found by a scheduled OSINT refresh
+	WebhookEventNewCVE   = "new_cve"  // CVE findings the CVE monitor added to a completed analysis
+)
+
+// WebhookSubscription delivers analysis results to an external endpoint
+type WebhookSubscription struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	OrgID   string `gorm:"default:default;index" json:"org_id"`
+	Name    string `gorm:"not null" json:"name"`
+	URL     string `gorm:"not null" json:"url"`
+	Secret  string `json:"-"` // HMAC key for the X-Odin-Signature header
+	Enabled bool   `json:"enabled"`
+
+	// Filters (comma separated lists, empty means no restriction)
+	EventTypes   string    `json:"event_types"`
+	MinSeverity  RiskLevel `json:"min_severity"`
+	FindingTypes string    `json:"finding_types"`
+	Fleets       string    `json:"fleets"`
+
+	PayloadTemplate string `gorm:"default:full" json:"payload_template"` // full or summary
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CVEAlertRule alerts a webhook subscription, its channel, when the CVE
+// monitor adds a CVE meeting any of its conditions to a monitored project,
+// e.g. a CVSS score of 9 or more or a CISA KEV listing. The subscription's
+// own filters don't apply to the alerts.
+type CVEAlertRule struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	OrgID     string `gorm:"default:default;index" json:"org_id"`
+	Name      string `gorm:"not null" json:"name"`
+	Enabled   bool   `json:"enabled"`
+	WebhookID uint   `gorm:"not null;index" json:"webhook_id"`
+
+	// Conditions, unset when zero
+	MinCVSS          float64 `json:"min_cvss"`          // CVSS base score
+	KnownExploited   bool    `json:"known_exploited"`   // listed in CISA KEV
+	ExploitAvailable bool    `json:"exploit_available"` // public exploit known
+
+	// Scope, empty for every project of the organization
+	ProjectID string `gorm:"index" json:"project_id,omitempty"`
+	Fleets    string `json:"fleets"` // comma separated
+
+	Alerts        int        `json:"alerts"` // CVEs alerted on
+	LastAlertedAt *time.Time `json:"last_alerted_at,omitempty"`
+	CreatedBy     string     `json:"created_by"`
+	UpdatedBy     string     `json:"updated_by"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// YaraRuleSet is a set of YARA rules, e.g. an organization's rules for
+// known vendor backdoors, that enabled sets scan every analysis with
+type YaraRuleSet struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	OrgID       string    `gorm:"default:default;index" json:"org_id"`
+	Name        string    `gorm:"not null" json:"name"`
+	Description string    `json:"description"`
+	Source      string    `gorm:"type:text;not null" json:"source"`
+	Tags        string    `json:"tags"`                         // comma separated
+	Severity    RiskLevel `gorm:"default:high" json:"severity"` // of the findings of its matches
+	Enabled     bool      `json:"enabled"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery records a single delivery attempt of a webhook
+type WebhookDelivery struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint   `gorm:"not null;index" json:"subscription_id"`
+	ProjectID      string `gorm:"index" json:"project_id"`
+	Event          string `json:"event"`
+	StatusCode     int    `json:"status_code"`
+	Success        bool   `json:"success"`
+	Error          string `json:"error"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Worker is a worker process registered through its heartbeats
+type Worker struct {
+	ID       string `gorm:"primaryKey" json:"id"` // hostname:pid
+	Hostname string `gorm:"not null" json:"hostname"`
+	PID      int    `json:"pid"`
+	Version  string `json:"version"`
+
+	CurrentProjectID string  `json:"current_project_id"`
+	Load             float64 `json:"load"` // 1 minute load average of the host
+	Draining         bool    `gorm:"default:false" json:"draining"`
+
+	StartedAt       time.Time `json:"started_at"`
+	LastHeartbeatAt time.Time `gorm:"index" json:"last_heartbeat_at"`
+}
+
+// Online reports whether the worker has sent a heartbeat recently
+func (w *Worker) Online() bool {
+	return time.Since(w.LastHeartbeatAt) < WorkerOfflineAfter
+}
+
+// BackfillState tracks progress of a resumable backfill task
+type BackfillState struct {
+	Task      string `gorm:"primaryKey" json:"task"` // fingerprints, risk, counters, techniques
+	Status    string `gorm:"default:pending" json:"status"`
+	Cursor    string `json:"cursor"` // last processed primary key
+	Processed int    `gorm:"default:0" json:"processed"`
+	Total     int    `gorm:"default:0" json:"total"`
+	Error     string `json:"error"`
+
+	StartedAt   time.Time  `json:"started_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// EMBA installation states
+const (
+	InstallPending   = "pending"
+	InstallRunning   = "running"
+	InstallCompleted = "completed"
+	InstallFailed    = "failed"
+)
+
+// EMBAInstall is an admin-requested installation or update of EMBA on one
+// worker host, carried out by a worker on that host
+type EMBAInstall struct {
+	ID          string `gorm:"primaryKey" json:"id"`
+	Hostname    string `gorm:"index;not null" json:"hostname"`
+	Version     string `json:"version"` // git tag, branch or commit
+	Mode        string `json:"mode"`    // installer mode, see emba.InstallMode*
+	Status      string `gorm:"index;default:pending" json:"status"`
+	Step        string `json:"step"`
+	Output      string `gorm:"type:text" json:"output"` // tail of the current step's output
+	Error       string `json:"error"`
+	RequestedBy string `json:"requested_by"`
+	WorkerID    string `json:"worker_id"` // worker that carried it out
+
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
@@ -0,0 +1,319 @@
+package nvd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// CPEMatch is a CPE of NVD's applicability statement of a CVE: a CPE 2.3
+// name, whose version may be a wildcard bounded by a version range
+type CPEMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	Criteria              string `json:"criteria"` // e.g. cpe:2.3:a:busybox:busybox:*:*:*:*:*:*:*:*
+	VersionStartIncluding string `json:"versionStartIncluding,omitempty"`
+	VersionStartExcluding string `json:"versionStartExcluding,omitempty"`
+	VersionEndIncluding   string `json:"versionEndIncluding,omitempty"`
+	VersionEndExcluding   string `json:"versionEndExcluding,omitempty"`
+
+	// Vulnerable only on a platform the configuration names as well, e.g.
+	// a firmware on some hardware, which an SBOM can't tell
+	Conditional bool `json:"conditional,omitempty"`
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Affects returns how the CVE was matched to an SBOM component: "cpe" when
+// the component's CPE names a vulnerable product and version, "name_version"
+// when its name and version do, "" when it isn't affected. Components
+// without a version match nothing, as no range can be told to include them.
+func (c *CVE) Affects(component *models.SBOMComponent) string {
+	vendor, product, version, match := identify(component)
+	if product == "" || version == "" {
+		return ""
+	}
+	for _, m := range c.Matches {
+		fields := m.names(vendor, product)
+		if fields != nil && m.includes(fields[5], version) {
+			return match
+		}
+	}
+	return ""
+}
+
+// Evidence returns the CPE of the CVE's applicability statements that
+// includes the component's version and the versions it covers, or "" when
+// none does. Statements without a platform condition come first.
+func (c *CVE) Evidence(component *models.SBOMComponent) (string, string) {
+	vendor, product, version, _ := identify(component)
+	if product == "" || version == "" {
+		return "", ""
+	}
+	criteria, versions := "", ""
+	for _, m := range c.Matches {
+		fields := m.names(vendor, product)
+		if fields == nil || !m.includes(fields[5], version) {
+			continue
+		}
+		if !m.Conditional {
+			return m.Criteria, m.versionRange(fields[5])
+		}
+		if criteria == "" {
+			criteria, versions = m.Criteria, m.versionRange(fields[5])
+		}
+	}
+	return criteria, versions
+}
+
+// Reasons Verify gives for a match status
+const (
+	ReasonInRange         = "version_in_range"
+	ReasonPlatform        = "platform_condition"
+	ReasonOutOfRange      = "version_out_of_range"
+	ReasonNoVersion       = "no_version"
+	ReasonNotListed       = "product_not_listed"
+	ReasonNoApplicability = "no_applicability_data"
+)
+
+// Verify checks a component a scanner reported the CVE for against the
+// CVE's applicability statements, and why: matched when its version is in
+// a vulnerable range, potential otherwise, for an analyst to confirm or
+// reject. NVD not having analyzed the CVE yet, a component without a
+// version and a version in range only on some platform are uncertain as
+// much as a product or version NVD doesn't list, which is how a scanner
+// matching names and versions loosely over-reports.
+func (c *CVE) Verify(component *models.SBOMComponent) (models.MatchStatus, string) {
+	if len(c.Matches) == 0 {
+		return models.MatchPotential, ReasonNoApplicability
+	}
+	vendor, product, version, _ := identify(component)
+	listed, conditional := false, false
+	for _, m := range c.Matches {
+		fields := m.names(vendor, product)
+		if fields == nil {
+			continue
+		}
+		listed = true
+		if version == "" || !m.includes(fields[5], version) {
+			continue
+		}
+		if !m.Conditional {
+			return models.MatchMatched, ReasonInRange
+		}
+		conditional = true
+	}
+	switch {
+	case !listed:
+		return models.MatchPotential, ReasonNotListed
+	case version == "":
+		return models.MatchPotential, ReasonNoVersion
+	case conditional:
+		return models.MatchPotential, ReasonPlatform
+	}
+	return models.MatchPotential, ReasonOutOfRange
+}
+
+// Products returns the normalized products of the CVE's vulnerable CPEs
+func (c *CVE) Products() []string {
+	seen := make(map[string]bool)
+	var products []string
+	for _, m := range c.Matches {
+		if fields := splitCPE(m.Criteria); fields != nil && !seen[normalize(fields[4])] {
+			seen[normalize(fields[4])] = true
+			products = append(products, normalize(fields[4]))
+		}
+	}
+	return products
+}
+
+// Product returns the normalized product of an SBOM component, which
+// Products of the CVEs affecting it include
+func Product(component *models.SBOMComponent) string {
+	_, product, _, _ := identify(component)
+	return product
+}
+
+// identify returns the normalized vendor and product and the version of a
+// component, from its CPE when it has one, and how they were told
+func identify(component *models.SBOMComponent) (vendor, product, version, match string) {
+	product, version, match = normalize(component.Name), component.Version, "name_version"
+	if fields := splitCPE(component.CPE); fields != nil {
+		vendor, product, match = normalize(fields[3]), normalize(fields[4]), "cpe"
+		if v := fields[5]; v != "*" && v != "-" && v != "" {
+			version = v
+		}
+	}
+	return vendor, product, version, match
+}
+
+// names returns the fields of the match's CPE when it names a product, and
+// the vendor unless either is unknown, or nil
+func (m *CPEMatch) names(vendor, product string) []string {
+	fields := splitCPE(m.Criteria)
+	if product == "" || fields == nil || normalize(fields[4]) != product {
+		return nil
+	}
+	if vendor != "" && fields[3] != "*" && normalize(fields[3]) != vendor {
+		return nil
+	}
+	return fields
+}
+
+// includes reports whether a version is the match's version, or in its
+// range when the version is a wildcard
+func (m *CPEMatch) includes(matchVersion, version string) bool {
+	switch matchVersion {
+	case "-", "":
+		return false
+	case "*":
+	default:
+		return CompareVersions(version, matchVersion) == 0
+	}
+	if m.VersionStartIncluding != "" && CompareVersions(version, m.VersionStartIncluding) < 0 {
+		return false
+	}
+	if m.VersionStartExcluding != "" && CompareVersions(version, m.VersionStartExcluding) <= 0 {
+		return false
+	}
+	if m.VersionEndIncluding != "" && CompareVersions(version, m.VersionEndIncluding) > 0 {
+		return false
+	}
+	if m.VersionEndExcluding != "" && CompareVersions(version, m.VersionEndExcluding) >= 0 {
+		return false
+	}
+	return true
+}
+
+// versionRange describes the versions the match covers: its version, or
+// the bounds of its range when the version is a wildcard
+func (m *CPEMatch) versionRange(matchVersion string) string {
+	if matchVersion != "*" {
+		return "=" + matchVersion
+	}
+	var bounds []string
+	if m.VersionStartIncluding != "" {
+		bounds = append(bounds, ">="+m.VersionStartIncluding)
+	}
+	if m.VersionStartExcluding != "" {
+		bounds = append(bounds, ">"+m.VersionStartExcluding)
+	}
+	if m.VersionEndIncluding != "" {
+		bounds = append(bounds, "<="+m.VersionEndIncluding)
+	}
+	if m.VersionEndExcluding != "" {
+		bounds = append(bounds, "<"+m.VersionEndExcluding)
+	}
+	if len(bounds) == 0 {
+		return "*"
+	}
+	return strings.Join(bounds, " ")
+}
+
+// splitCPE returns the fields of a CPE 2.3 formatted string, cpe and 2.3
+// included, or nil when it isn't one. Escaped colons stay in their field.
+func splitCPE(cpe string) []string {
+	if !strings.HasPrefix(cpe, "cpe:2.3:") {
+		return nil
+	}
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(cpe); i++ {
+		switch {
+		case cpe[i] == '\\' && i+1 < len(cpe):
+			i++
+			field.WriteByte(cpe[i])
+		case cpe[i] == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(cpe[i])
+		}
+	}
+	fields = append(fields, field.String())
+	if len(fields) < 6 {
+		return nil
+	}
+	return fields
+}
+
+// normalize lowercases a vendor or product name and drops what isn't a
+// letter or digit, so linux_kernel matches Linux Kernel
+func normalize(name string) string {
+	return nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "")
+}
+
+var versionTokenRegex = regexp.MustCompile(`[0-9]+|[a-z]+`)
+
+// prereleases ranks the tags of the versions before a release
+var prereleases = map[string]int{
+	"dev": 1, "snapshot": 1,
+	"alpha": 2,
+	"beta":  3,
+	"pre":   4, "preview": 4,
+	"rc": 5,
+}
+
+// CompareVersions compares versions token by token: -1, 0 or 1. Numbers
+// compare as numbers and letters as strings; numbers but 0 come after
+// letters. A missing token counts as 0, so 7 equals 7.0 and 1.0.2 comes
+// before 1.0.2k. Prerelease tags come before anything else, so 2.0-rc1
+// comes before 2.0.
+func CompareVersions(a, b string) int {
+	ta := versionTokenRegex.FindAllString(strings.ToLower(a), -1)
+	tb := versionTokenRegex.FindAllString(strings.ToLower(b), -1)
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		x, y := "0", "0"
+		if i < len(ta) {
+			x = ta[i]
+		}
+		if i < len(tb) {
+			y = tb[i]
+		}
+		if c := compareTokens(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareTokens(x, y string) int {
+	if px, py := prereleases[x], prereleases[y]; px > 0 || py > 0 {
+		switch {
+		case px == 0:
+			return 1
+		case py == 0:
+			return -1
+		case px < py:
+			return -1
+		case px > py:
+			return 1
+		}
+		return 0
+	}
+	nx, errX := strconv.Atoi(x)
+	ny, errY := strconv.Atoi(y)
+	switch {
+	case errX == nil && errY == nil:
+		if nx < ny {
+			return -1
+		}
+		if nx > ny {
+			return 1
+		}
+		return 0
+	case errX == nil:
+		// A missing token, 0, is below letters
+		if nx == 0 {
+			return -1
+		}
+		return 1
+	case errY == nil:
+		if ny == 0 {
+			return 1
+		}
+		return -1
+	}
+	return strings.Compare(x, y)
+}
@@ -0,0 +1,114 @@
+package nvd
+
+import (
+	"testing"
+
+	"odin-backend/internal/models"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.10", "1.9", 1},
+		{"2.6.36", "2.6.4", 1},
+
+		// Differing segment counts: a missing segment counts as 0
+		{"7", "7.0", 0},
+		{"7.0.0", "7", 0},
+		{"2.6.36", "2.6.36.4", -1},
+		{"1.2.3.1", "1.2.3", 1},
+
+		// Letter suffixes come after the release and in order
+		{"1.0.2", "1.0.2k", -1},
+		{"1.0.2k", "1.0.2l", -1},
+		{"1.0.2l", "1.0.2k", 1},
+		{"1.0.2K", "1.0.2k", 0},
+		{"1.0.2k", "1.0.3", -1},
+
+		// Prereleases come before the release and in order
+		{"2.0-rc1", "2.0", -1},
+		{"2.0", "2.0-rc1", 1},
+		{"2.0-rc1", "2.0-rc2", -1},
+		{"2.0-beta3", "2.0-rc1", -1},
+		{"2.0-alpha", "2.0-beta", -1},
+		{"1.0.0-dev", "1.0.0-alpha", -1},
+		{"2.0-pre1", "2.0-preview1", 0},
+		{"2.0-rc1", "1.9", 1},
+	}
+
+	for _, tc := range cases {
+		if got := CompareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareVersions(%q, %q): got %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	const wildcard = "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*"
+	cases := []struct {
+		name    string
+		match   CPEMatch
+		version string
+		want    bool
+	}{
+		{"exact version", CPEMatch{Criteria: "cpe:2.3:a:openssl:openssl:1.0.2k:*:*:*:*:*:*:*"}, "1.0.2k", true},
+		{"other version", CPEMatch{Criteria: "cpe:2.3:a:openssl:openssl:1.0.2k:*:*:*:*:*:*:*"}, "1.0.2l", false},
+		{"exact version with more segments", CPEMatch{Criteria: "cpe:2.3:a:busybox:busybox:1.30:*:*:*:*:*:*:*"}, "1.30.0", true},
+		{"any version", CPEMatch{Criteria: wildcard}, "3.0.7", true},
+		// "-" is NA: the product has no versions to be in range
+		{"no version", CPEMatch{Criteria: "cpe:2.3:a:openssl:openssl:-:*:*:*:*:*:*:*"}, "1.0.2k", false},
+
+		{"at inclusive start", CPEMatch{Criteria: wildcard, VersionStartIncluding: "1.0.2"}, "1.0.2", true},
+		{"below inclusive start", CPEMatch{Criteria: wildcard, VersionStartIncluding: "1.0.2"}, "1.0.1u", false},
+		{"at exclusive start", CPEMatch{Criteria: wildcard, VersionStartExcluding: "1.0.2"}, "1.0.2", false},
+		{"above exclusive start", CPEMatch{Criteria: wildcard, VersionStartExcluding: "1.0.2"}, "1.0.2a", true},
+		{"at inclusive end", CPEMatch{Criteria: wildcard, VersionEndIncluding: "1.0.2k"}, "1.0.2k", true},
+		{"above inclusive end", CPEMatch{Criteria: wildcard, VersionEndIncluding: "1.0.2k"}, "1.0.2l", false},
+		{"at exclusive end", CPEMatch{Criteria: wildcard, VersionEndExcluding: "1.0.2l"}, "1.0.2l", false},
+		{"below exclusive end", CPEMatch{Criteria: wildcard, VersionEndExcluding: "1.0.2l"}, "1.0.2k", true},
+		{"prerelease of exclusive end", CPEMatch{Criteria: wildcard, VersionEndExcluding: "3.0.0"}, "3.0.0-beta1", true},
+		{"in bounded range", CPEMatch{Criteria: wildcard, VersionStartIncluding: "3.0.0", VersionEndExcluding: "3.0.7"}, "3.0.6", true},
+		{"below bounded range", CPEMatch{Criteria: wildcard, VersionStartIncluding: "3.0.0", VersionEndExcluding: "3.0.7"}, "1.1.1t", false},
+		{"above bounded range", CPEMatch{Criteria: wildcard, VersionStartIncluding: "3.0.0", VersionEndExcluding: "3.0.7"}, "3.0.10", false},
+	}
+
+	for _, tc := range cases {
+		fields := splitCPE(tc.match.Criteria)
+		if got := tc.match.includes(fields[5], tc.version); got != tc.want {
+			t.Errorf("%s: includes(%q) got %v, want %v", tc.name, tc.version, got, tc.want)
+		}
+	}
+}
+
+func TestAffects(t *testing.T) {
+	cve := &CVE{ID: "CVE-2017-3735", Matches: []CPEMatch{{
+		Vulnerable:            true,
+		Criteria:              "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*",
+		VersionStartIncluding: "1.0.2",
+		VersionEndExcluding:   "1.0.2m",
+	}}}
+
+	cases := []struct {
+		name      string
+		component models.SBOMComponent
+		want      string
+	}{
+		{"cpe", models.SBOMComponent{Name: "libssl", CPE: "cpe:2.3:a:openssl:openssl:1.0.2k:*:*:*:*:*:*:*"}, "cpe"},
+		{"cpe out of range", models.SBOMComponent{Name: "libssl", CPE: "cpe:2.3:a:openssl:openssl:1.0.2m:*:*:*:*:*:*:*"}, ""},
+		{"other vendor", models.SBOMComponent{CPE: "cpe:2.3:a:fork:openssl:1.0.2k:*:*:*:*:*:*:*"}, ""},
+		// A CPE without a version leaves it to the component's
+		{"cpe version *", models.SBOMComponent{Version: "1.0.2k", CPE: "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*"}, "cpe"},
+		{"cpe version -", models.SBOMComponent{Version: "1.0.2k", CPE: "cpe:2.3:a:openssl:openssl:-:*:*:*:*:*:*:*"}, "cpe"},
+		{"name and version", models.SBOMComponent{Name: "OpenSSL", Version: "1.0.2l"}, "name_version"},
+		{"no version", models.SBOMComponent{Name: "openssl"}, ""},
+	}
+
+	for _, tc := range cases {
+		if got := cve.Affects(&tc.component); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+// Package nvd fetches CVE records from the NVD CVE API 2.0, or reads them
+// from NVD's JSON 2.0 data feeds, fills CVE findings in with their CVSS v3
+// metrics, weaknesses, references and dates, and matches SBOM components
+// against the CPEs they affect
+package nvd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+)
+
+const apiURL = "https://services.nvd.nist.gov/rest/json/cves/2.0"
+
+// NVD allows 5 requests in a rolling 30 second window without an API key
+// and 50 with one; requests are spaced to stay below that
+const (
+	intervalWithoutKey = 6 * time.Second
+	intervalWithKey    = 600 * time.Millisecond
+)
+
+// Client fetches CVE records. Its requests are spaced to stay within NVD's
+// rate limit.
+type Client struct {
+	apiKey   string
+	baseURL  string
+	client   *http.Client
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// New returns an NVD client, or nil when NVD_ENRICHMENT is off or in
+// offline mode, where only the imported mirror of NVD's feeds is read
+func New(cfg *config.Config) *Client {
+	if !cfg.NVDEnrichment || cfg.OfflineMode {
+		return nil
+	}
+	interval := intervalWithoutKey
+	if cfg.NVDAPIKey != "" {
+		interval = intervalWithKey
+	}
+	return &Client{
+		apiKey:   cfg.NVDAPIKey,
+		baseURL:  apiURL,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		interval: interval,
+	}
+}
+
+// CVE is what NVD knows of a CVE
+type CVE struct {
+	ID           string    `json:"id"`
+	Description  string    `json:"description"`
+	Published    time.Time `json:"published"`
+	LastModified time.Time `json:"last_modified"`
+
+	// The primary CVSS v3.1 metric, or v3.0 when NVD has no v3.1 one
+	CVSSVersion         string  `json:"cvss_version,omitempty"`
+	CVSSVector          string  `json:"cvss_vector,omitempty"`
+	BaseScore           float64 `json:"base_score,omitempty"`
+	BaseSeverity        string  `json:"base_severity,omitempty"`
+	ExploitabilityScore float64 `json:"exploitability_score,omitempty"`
+	ImpactScore         float64 `json:"impact_score,omitempty"`
+
+	// The primary CVSS v4.0 metric, when NVD has one
+	CVSSV4Vector string  `json:"cvss_v4_vector,omitempty"`
+	CVSSV4Score  float64 `json:"cvss_v4_score,omitempty"`
+
+	CWEs       []string `json:"cwes,omitempty"` // e.g. CWE-787
+	References []string `json:"references,omitempty"`
+
+	// The vulnerable CPEs of NVD's applicability statements
+	Matches []CPEMatch `json:"cpe_matches,omitempty"`
+}
+
+// metric is a CVSS metric of NVD's response
+type metric struct {
+	Source   string `json:"source"`
+	Type     string `json:"type"` // Primary or Secondary
+	CVSSData struct {
+		Version      string  `json:"version"`
+		VectorString string  `json:"vectorString"`
+		BaseScore    float64 `json:"baseScore"`
+		BaseSeverity string  `json:"baseSeverity"`
+	} `json:"cvssData"`
+	ExploitabilityScore float64 `json:"exploitabilityScore"`
+	ImpactScore         float64 `json:"impactScore"`
+}
+
+// Fetch returns NVD's record of a CVE, or nil when NVD doesn't know it
+func (c *Client) Fetch(ctx context.Context, cveID string) (*CVE, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	params := url.Values{"cveId": {cveID}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("apiKey", c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("NVD request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// NVD answers 404 to malformed IDs, and an empty result to unknown ones
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("NVD returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Vulnerabilities []struct {
+			CVE record `json:"cve"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode NVD response: %w", err)
+	}
+	if len(body.Vulnerabilities) == 0 {
+		return nil, nil
+	}
+	return body.Vulnerabilities[0].CVE.convert(), nil
+}
+
+// record is a CVE as NVD's API and its JSON 2.0 data feeds have it
+type record struct {
+	ID           string `json:"id"`
+	Published    string `json:"published"`
+	LastModified string `json:"lastModified"`
+	Descriptions []struct {
+		Lang  string `json:"lang"`
+		Value string `json:"value"`
+	} `json:"descriptions"`
+	Metrics struct {
+		V31 []metric `json:"cvssMetricV31"`
+		V30 []metric `json:"cvssMetricV30"`
+		V40 []metric `json:"cvssMetricV40"`
+	} `json:"metrics"`
+	Weaknesses []struct {
+		Description []struct {
+			Lang  string `json:"lang"`
+			Value string `json:"value"`
+		} `json:"description"`
+	} `json:"weaknesses"`
+	References []struct {
+		URL string `json:"url"`
+	} `json:"references"`
+	Configurations []struct {
+		Operator string `json:"operator"` // AND when the CPEs run on a platform
+		Nodes    []struct {
+			CPEMatch []CPEMatch `json:"cpeMatch"`
+		} `json:"nodes"`
+	} `json:"configurations"`
+}
+
+func (raw record) convert() *CVE {
+	cve := &CVE{
+		ID:           raw.ID,
+		Published:    parseTime(raw.Published),
+		LastModified: parseTime(raw.LastModified),
+	}
+	for _, description := range raw.Descriptions {
+		if description.Lang == "en" {
+			cve.Description = description.Value
+			break
+		}
+	}
+
+	metrics := raw.Metrics.V31
+	if len(metrics) == 0 {
+		metrics = raw.Metrics.V30
+	}
+	if m, ok := primary(metrics); ok {
+		cve.CVSSVersion = m.CVSSData.Version
+		cve.CVSSVector = m.CVSSData.VectorString
+		cve.BaseScore = m.CVSSData.BaseScore
+		cve.BaseSeverity = m.CVSSData.BaseSeverity
+		cve.ExploitabilityScore = m.ExploitabilityScore
+		cve.ImpactScore = m.ImpactScore
+	}
+	if m, ok := primary(raw.Metrics.V40); ok {
+		cve.CVSSV4Vector = m.CVSSData.VectorString
+		cve.CVSSV4Score = m.CVSSData.BaseScore
+	}
+
+	seen := make(map[string]bool)
+	for _, weakness := range raw.Weaknesses {
+		for _, description := range weakness.Description {
+			// NVD-CWE-Other and NVD-CWE-noinfo aren't weaknesses
+			if strings.HasPrefix(description.Value, "CWE-") && !seen[description.Value] {
+				seen[description.Value] = true
+				cve.CWEs = append(cve.CWEs, description.Value)
+			}
+		}
+	}
+	sort.Strings(cve.CWEs)
+
+	for _, reference := range raw.References {
+		if reference.URL != "" {
+			cve.References = append(cve.References, reference.URL)
+		}
+	}
+
+	// The platforms a configuration requires the vulnerable CPEs to run on
+	// aren't vulnerable themselves, but make them conditional
+	for _, configuration := range raw.Configurations {
+		conditional := false
+		if configuration.Operator == "AND" {
+			for _, node := range configuration.Nodes {
+				for _, match := range node.CPEMatch {
+					conditional = conditional || !match.Vulnerable
+				}
+			}
+		}
+		for _, node := range configuration.Nodes {
+			for _, match := range node.CPEMatch {
+				if match.Vulnerable {
+					match.Conditional = conditional
+					cve.Matches = append(cve.Matches, match)
+				}
+			}
+		}
+	}
+	return cve
+}
+
+// ReadFeed reads the CVEs of an NVD JSON 2.0 data feed (nvdcve-2.0-*.json),
+// calling fn with each as it is decoded, so a year's feed isn't held in
+// memory at once. It stops at fn's first error.
+func ReadFeed(r io.Reader, fn func(*CVE) error) error {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return fmt.Errorf("not an NVD JSON 2.0 feed")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("failed to read feed: %w", err)
+		}
+		if token != "vulnerabilities" {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return fmt.Errorf("failed to read feed: %w", err)
+			}
+			continue
+		}
+
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return fmt.Errorf("feed's vulnerabilities aren't a list")
+		}
+		for decoder.More() {
+			var item struct {
+				CVE record `json:"cve"`
+			}
+			if err := decoder.Decode(&item); err != nil {
+				return fmt.Errorf("failed to decode feed entry: %w", err)
+			}
+			if item.CVE.ID == "" {
+				continue
+			}
+			if err := fn(item.CVE.convert()); err != nil {
+				return err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("failed to read feed: %w", err)
+		}
+	}
+	return nil
+}
+
+// FetchFeed downloads an NVD JSON 2.0 data feed, gzipped or not, and reads
+// its CVEs as ReadFeed does
+func FetchFeed(ctx context.Context, feedURL string, fn func(*CVE) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download NVD feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("NVD feed download returned status %d", resp.StatusCode)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	var r io.Reader = body
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("failed to read NVD feed: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return ReadFeed(r, fn)
+}
+
+// primary returns NVD's own metric, or the first one when NVD didn't score
+// the CVE itself
+func primary(metrics []metric) (metric, bool) {
+	for _, m := range metrics {
+		if m.Type == "Primary" {
+			return m, true
+		}
+	}
+	if len(metrics) > 0 {
+		return metrics[0], true
+	}
+	return metric{}, false
+}
+
+// parseTime parses NVD's timestamps, which carry no zone and are UTC
+func parseTime(s string) time.Time {
+	t, err := time.Parse("2006-01-02T15:04:05.000", s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// wait blocks until the next request may be sent or the context is done
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	at := c.next
+	if at.Before(now) {
+		at = now
+	}
+	c.next = at.Add(c.interval)
+	c.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Apply rates a CVE finding by NVD's record: NVD's CVSS v3 score replaces
+// the one EMBA reported. An analyst's severity override stays, NVD's
+// rating becoming the original one.
+func Apply(finding *models.CVEFinding, cve *CVE) {
+	if cve.CVSSVector != "" {
+		finding.CVSSVector = cve.CVSSVector
+		finding.SeverityScore = cve.BaseScore
+		if level, ok := severities[cve.BaseSeverity]; ok {
+			if finding.SeverityOverrideAt != nil {
+				finding.OriginalSeverity = level
+			} else {
+				finding.SeverityLevel = level
+			}
+		}
+	}
+	if finding.Source == "" {
+		finding.Source = "NVD"
+	}
+}
+
+// ApplyRecord fills the shared record of a CVE in with NVD's: the CVSS v3
+// subscores, the v4 vector, weaknesses and dates. The description and references already
+// reported are kept, NVD's references added to them.
+func ApplyRecord(record *models.CVE, cve *CVE) {
+	if cve.Description != "" && record.Description == "" {
+		record.Description = cve.Description
+	}
+	if cve.CVSSVector != "" {
+		record.CVSSVersion = cve.CVSSVersion
+		record.ExploitabilityScore = cve.ExploitabilityScore
+		record.ImpactScore = cve.ImpactScore
+	}
+	if cve.CVSSV4Vector != "" {
+		record.CVSSV4Vector = cve.CVSSV4Vector
+		record.CVSSV4Score = cve.CVSSV4Score
+	}
+	record.CWEIDs = strings.Join(cve.CWEs, ",")
+	if !cve.Published.IsZero() {
+		published := cve.Published
+		record.PublishedAt = &published
+	}
+	if !cve.LastModified.IsZero() {
+		modified := cve.LastModified
+		record.LastModifiedAt = &modified
+	}
+	record.References = mergeReferences(record.References, cve.References)
+}
+
+// severities maps CVSS v3 qualitative ratings to risk levels
+var severities = map[string]models.RiskLevel{
+	"CRITICAL": models.RiskCritical,
+	"HIGH":     models.RiskHigh,
+	"MEDIUM":   models.RiskMedium,
+	"LOW":      models.RiskLow,
+	"NONE":     models.RiskInfo,
+}
+
+// mergeReferences adds URLs to a JSON array of references, keeping the
+// order and dropping duplicates
+func mergeReferences(list string, urls []string) string {
+	var merged []string
+	if list != "" {
+		_ = json.Unmarshal([]byte(list), &merged)
+	}
+	seen := make(map[string]bool, len(merged))
+	for _, u := range merged {
+		seen[u] = true
+	}
+	for _, u := range urls {
+		if !seen[u] {
+			seen[u] = true
+			merged = append(merged, u)
+		}
+	}
+	if len(merged) == 0 {
+		return list
+	}
+	data, _ := json.Marshal(merged)
+	return string(data)
+}
@@ -0,0 +1,439 @@
+// Package ocsf maps Odin findings to the Open Cybersecurity Schema Framework
+// (OCSF) Vulnerability Finding class, so they can be ingested by data lakes
+// and SIEMs without a custom translation.
+package ocsf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"odin-backend/internal/attack"
+	"odin-backend/internal/models"
+	"odin-backend/internal/version"
+)
+
+// Schema constants of the Vulnerability Finding class (Findings category)
+const (
+	SchemaVersion = "1.1.0"
+
+	CategoryUID  = 2
+	CategoryName = "Findings"
+	ClassUID     = 2002
+	ClassName    = "Vulnerability Finding"
+
+	ActivityCreate = 1
+	TypeUIDCreate  = ClassUID*100 + ActivityCreate
+
+	StatusNew = 1
+)
+
+// OCSF severity_id values
+const (
+	SeverityUnknown       = 0
+	SeverityInformational = 1
+	SeverityLow           = 2
+	SeverityMedium        = 3
+	SeverityHigh          = 4
+	SeverityCritical      = 5
+)
+
+// VulnerabilityFinding is a single OCSF Vulnerability Finding event
+type VulnerabilityFinding struct {
+	CategoryUID  int    `json:"category_uid"`
+	CategoryName string `json:"category_name"`
+	ClassUID     int    `json:"class_uid"`
+	ClassName    string `json:"class_name"`
+	ActivityID   int    `json:"activity_id"`
+	ActivityName string `json:"activity_name"`
+	TypeUID      int    `json:"type_uid"`
+	TypeName     string `json:"type_name"`
+	SeverityID   int    `json:"severity_id"`
+	Severity     string `json:"severity"`
+	StatusID     int    `json:"status_id"`
+	Status       string `json:"status"`
+	Time         int64  `json:"time"`
+	Message      string `json:"message,omitempty"`
+
+	Metadata        Metadata        `json:"metadata"`
+	FindingInfo     FindingInfo     `json:"finding_info"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	Resources       []Resource      `json:"resources"`
+	Device          *Device         `json:"device,omitempty"`
+
+	// Unmapped carries Odin fields without an OCSF equivalent
+	Unmapped map[string]interface{} `json:"unmapped,omitempty"`
+}
+
+// Metadata describes the event producer
+type Metadata struct {
+	Version string   `json:"version"`
+	Product Product  `json:"product"`
+	UID     string   `json:"uid"`
+	Labels  []string `json:"labels,omitempty"`
+}
+
+// Product identifies Odin as the reporting product
+type Product struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version"`
+}
+
+// FindingInfo identifies the finding itself
+type FindingInfo struct {
+	UID         string   `json:"uid"`
+	Title       string   `json:"title"`
+	Desc        string   `json:"desc,omitempty"`
+	Types       []string `json:"types,omitempty"`
+	CreatedTime int64    `json:"created_time"`
+	Attacks     []Attack `json:"attacks,omitempty"`
+}
+
+// Attack is an ATT&CK technique a finding enables, with its tactic
+type Attack struct {
+	Technique AttackObject  `json:"technique"`
+	Tactic    *AttackObject `json:"tactic,omitempty"`
+}
+
+// AttackObject is an ATT&CK technique or tactic
+type AttackObject struct {
+	UID  string `json:"uid,omitempty"`
+	Name string `json:"name"`
+}
+
+// Vulnerability describes the weakness a finding reports
+type Vulnerability struct {
+	Title            string         `json:"title"`
+	Desc             string         `json:"desc,omitempty"`
+	Severity         string         `json:"severity"`
+	CVE              *CVE           `json:"cve,omitempty"`
+	CWE              *CWE           `json:"cwe,omitempty"`
+	AffectedPackages []Package      `json:"affected_packages,omitempty"`
+	AffectedCode     []AffectedCode `json:"affected_code,omitempty"`
+	References       []string       `json:"references,omitempty"`
+}
+
+// CVE is the OCSF CVE object
+type CVE struct {
+	UID  string `json:"uid"`
+	Desc string `json:"desc,omitempty"`
+	CVSS []CVSS `json:"cvss,omitempty"`
+}
+
+// CWE is the OCSF CWE object, the weakness a finding cites
+type CWE struct {
+	UID    string `json:"uid"`
+	SrcURL string `json:"src_url,omitempty"`
+}
+
+// CVSS is a CVSS score of a CVE
+type CVSS struct {
+	BaseScore    float64 `json:"base_score"`
+	Version      string  `json:"version"`
+	VectorString string  `json:"vector_string,omitempty"`
+}
+
+// Package is a software package affected by a vulnerability
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// AffectedCode points at the file a finding was raised for
+type AffectedCode struct {
+	File      File `json:"file"`
+	StartLine int  `json:"start_line,omitempty"`
+}
+
+// File is the OCSF file object
+type File struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Resource is the analyzed firmware image
+type Resource struct {
+	UID   string                 `json:"uid"`
+	Name  string                 `json:"name"`
+	Type  string                 `json:"type"`
+	Group *Group                 `json:"group,omitempty"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+// Group is the fleet a firmware image belongs to
+type Group struct {
+	Name string `json:"name"`
+}
+
+// Device is the device the firmware runs on
+type Device struct {
+	Type       string `json:"type"`
+	TypeID     int    `json:"type_id"`
+	Name       string `json:"name,omitempty"`
+	Model      string `json:"model,omitempty"`
+	VendorName string `json:"vendor_name,omitempty"`
+}
+
+// Events maps a project's findings and CVEs to OCSF events. The project's
+// Findings and CVEFindings must be preloaded.
+func Events(project *models.Project) []VulnerabilityFinding {
+	return Map(project, project.Findings, project.CVEFindings)
+}
+
+// Map converts the given findings and CVEs of a project to OCSF events
+func Map(project *models.Project, findings []models.Finding, cves []models.CVEFinding) []VulnerabilityFinding {
+	events := make([]VulnerabilityFinding, 0, len(findings)+len(cves))
+	for i := range findings {
+		events = append(events, FromFinding(project, &findings[i]))
+	}
+	for i := range cves {
+		events = append(events, FromCVE(project, &cves[i]))
+	}
+	return events
+}
+
+// FromFinding maps a parser finding
+func FromFinding(project *models.Project, finding *models.Finding) VulnerabilityFinding {
+	event := newEvent(project, finding.Severity, finding.CreatedAt.UnixMilli())
+	event.Message = finding.Title
+	event.Metadata.UID = fmt.Sprintf("%s/finding/%d", project.ID, finding.ID)
+	event.FindingInfo = FindingInfo{
+		UID:         findingUID(project, finding),
+		Title:       finding.Title,
+		Desc:        finding.Description,
+		Types:       []string{string(finding.Type)},
+		CreatedTime: finding.CreatedAt.UnixMilli(),
+	}
+
+	vulnerability := Vulnerability{
+		Title:    finding.Title,
+		Desc:     finding.Description,
+		Severity: event.Severity,
+	}
+	if finding.FilePath != "" {
+		vulnerability.AffectedCode = []AffectedCode{{
+			File:      File{Name: baseName(finding.FilePath), Path: finding.FilePath},
+			StartLine: finding.LineNumber,
+		}}
+	}
+
+	metadata := decodeMetadata(finding.FindingMetadata)
+	score, vector := embaCVSS(metadata)
+	if cveID := stringValue(metadata, "cve_id"); cveID != "" {
+		vulnerability.CVE = &CVE{UID: cveID}
+		if score > 0 {
+			vulnerability.CVE.CVSS = []CVSS{{BaseScore: score, Version: cvssVersion(vector), VectorString: vector}}
+		}
+	} else if score > 0 {
+		// OCSF only carries CVSS scores on a CVE
+		event.Unmapped["emba_cvss"] = score
+		if vector != "" {
+			event.Unmapped["emba_cvss_vector"] = vector
+		}
+	}
+	if finding.CWE != "" {
+		vulnerability.CWE = &CWE{
+			UID:    finding.CWE,
+			SrcURL: fmt.Sprintf("https://cwe.mitre.org/data/definitions/%s.html", strings.TrimPrefix(finding.CWE, "CWE-")),
+		}
+	}
+	event.Vulnerabilities = []Vulnerability{vulnerability}
+
+	event.Unmapped["finding_type"] = finding.Type
+	if source := stringValue(metadata, "severity_source"); source != "" {
+		event.Unmapped["severity_source"] = source
+	}
+	if module := stringValue(metadata, "module"); module != "" {
+		event.Unmapped["emba_module"] = module
+	}
+	if finding.Fingerprint != "" {
+		event.Unmapped["fingerprint"] = finding.Fingerprint
+	}
+
+	// ATT&CK techniques map to OCSF attacks, EMB3D threats have no place
+	var threats []string
+	for _, id := range strings.Split(finding.Techniques, ",") {
+		technique, ok := attack.Techniques[id]
+		if !ok {
+			continue
+		}
+		if technique.Framework == attack.FrameworkEMB3D {
+			threats = append(threats, id)
+			continue
+		}
+		for _, tactic := range technique.Tactics {
+			event.FindingInfo.Attacks = append(event.FindingInfo.Attacks, Attack{
+				Technique: AttackObject{UID: technique.ID, Name: technique.Name},
+				Tactic:    &AttackObject{Name: tactic},
+			})
+		}
+	}
+	if len(threats) > 0 {
+		event.Unmapped["emb3d_threats"] = threats
+	}
+	return event
+}
+
+// FromCVE maps a CVE matched against the firmware's software
+func FromCVE(project *models.Project, cve *models.CVEFinding) VulnerabilityFinding {
+	event := newEvent(project, cve.SeverityLevel, cve.CreatedAt.UnixMilli())
+	event.Message = fmt.Sprintf("%s in %s %s", cve.CVEID, cve.SoftwareName, cve.SoftwareVersion)
+	event.Metadata.UID = fmt.Sprintf("%s/cve/%d", project.ID, cve.ID)
+	event.FindingInfo = FindingInfo{
+		UID:         fmt.Sprintf("%s:%s:%s", project.ID, cve.CVEID, cve.SoftwareName),
+		Title:       cve.CVEID,
+		Desc:        cve.Description,
+		Types:       []string{"cve"},
+		CreatedTime: cve.CreatedAt.UnixMilli(),
+	}
+
+	ocsfCVE := &CVE{UID: cve.CVEID, Desc: cve.Description}
+	if cve.SeverityScore > 0 {
+		ocsfCVE.CVSS = []CVSS{{BaseScore: cve.SeverityScore, Version: cvssVersion(cve.CVSSVector), VectorString: cve.CVSSVector}}
+	}
+
+	var references []string
+	if cve.References != "" {
+		json.Unmarshal([]byte(cve.References), &references)
+	}
+
+	event.Vulnerabilities = []Vulnerability{{
+		Title:            cve.CVEID,
+		Desc:             cve.Description,
+		Severity:         event.Severity,
+		CVE:              ocsfCVE,
+		AffectedPackages: []Package{{Name: cve.SoftwareName, Version: cve.SoftwareVersion}},
+		References:       references,
+	}}
+	return event
+}
+
+// newEvent fills in the class, severity, producer and resource fields shared by all events
+func newEvent(project *models.Project, severity models.RiskLevel, created int64) VulnerabilityFinding {
+	severityID, severityName := Severity(severity)
+
+	resource := Resource{
+		UID:  project.ID,
+		Name: project.Filename,
+		Type: "firmware",
+		Data: map[string]interface{}{
+			"sha256":     project.FileHash,
+			"file_size":  project.FileSize,
+			"risk_level": project.RiskLevel,
+		},
+	}
+	if project.Fleet != "" {
+		resource.Group = &Group{Name: project.Fleet}
+	}
+
+	event := VulnerabilityFinding{
+		CategoryUID:  CategoryUID,
+		CategoryName: CategoryName,
+		ClassUID:     ClassUID,
+		ClassName:    ClassName,
+		ActivityID:   ActivityCreate,
+		ActivityName: "Create",
+		TypeUID:      TypeUIDCreate,
+		TypeName:     ClassName + ": Create",
+		SeverityID:   severityID,
+		Severity:     severityName,
+		StatusID:     StatusNew,
+		Status:       "New",
+		Time:         created,
+		Metadata: Metadata{
+			Version: SchemaVersion,
+			Product: Product{Name: "Odin", VendorName: "Odin", Version: version.Version},
+		},
+		Resources: []Resource{resource},
+		Unmapped: map[string]interface{}{
+			"org_id":     project.OrgID,
+			"project_id": project.ID,
+		},
+	}
+	if project.OrgID != "" {
+		event.Metadata.Labels = []string{"org:" + project.OrgID}
+	}
+
+	if project.DeviceModel != "" || project.Manufacturer != "" || project.Name != "" {
+		event.Device = &Device{
+			Type:       "IOT",
+			TypeID:     7,
+			Name:       project.Name,
+			Model:      project.DeviceModel,
+			VendorName: project.Manufacturer,
+		}
+	}
+	return event
+}
+
+// Severity maps an Odin risk level to OCSF's severity_id and caption
+func Severity(level models.RiskLevel) (int, string) {
+	switch level {
+	case models.RiskCritical:
+		return SeverityCritical, "Critical"
+	case models.RiskHigh:
+		return SeverityHigh, "High"
+	case models.RiskMedium:
+		return SeverityMedium, "Medium"
+	case models.RiskLow:
+		return SeverityLow, "Low"
+	case models.RiskInfo:
+		return SeverityInformational, "Informational"
+	default:
+		return SeverityUnknown, "Unknown"
+	}
+}
+
+// findingUID stays stable across rescans of the same firmware thanks to the fingerprint
+func findingUID(project *models.Project, finding *models.Finding) string {
+	if finding.Fingerprint != "" {
+		return project.ID + ":" + finding.Fingerprint
+	}
+	return fmt.Sprintf("%s:%d", project.ID, finding.ID)
+}
+
+func decodeMetadata(raw string) map[string]interface{} {
+	metadata := make(map[string]interface{})
+	if raw != "" {
+		json.Unmarshal([]byte(raw), &metadata)
+	}
+	return metadata
+}
+
+func stringValue(metadata map[string]interface{}, key string) string {
+	value, _ := metadata[key].(string)
+	return value
+}
+
+// embaCVSS returns the CVSS score and vector EMBA reported for a finding, if any
+func embaCVSS(metadata map[string]interface{}) (float64, string) {
+	var score float64
+	switch value := metadata["emba_cvss"].(type) {
+	case string:
+		fmt.Sscanf(value, "%g", &score)
+	case float64:
+		score = value
+	}
+	return score, stringValue(metadata, "emba_cvss_vector")
+}
+
+// cvssVersion reads the version from a vector string, defaulting to 3.1
+func cvssVersion(vector string) string {
+	if strings.HasPrefix(vector, "CVSS:") {
+		if i := strings.IndexByte(vector, '/'); i > len("CVSS:") {
+			return vector[len("CVSS:"):i]
+		}
+	}
+	if vector != "" && !strings.HasPrefix(vector, "CVSS:") {
+		return "2.0"
+	}
+	return "3.1"
+}
+
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
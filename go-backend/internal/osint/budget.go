@@ -0,0 +1,260 @@
+package osint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrBudgetExhausted is returned for calls to a provider whose daily quota
+// is used up, until the next UTC day
+var ErrBudgetExhausted = errors.New("daily quota exhausted")
+
+// Budget spaces the outbound calls to every provider to its rate limit and
+// counts them against its daily quota. The counts are in the database,
+// shared by all workers; the rate limits are per process.
+type Budget struct {
+	db       *gorm.DB
+	quotas   map[string]int
+	rates    map[string]int
+	mu       sync.Mutex
+	limiters map[string]*limiter
+}
+
+// NewBudget returns the budget of the configured quotas and rate limits
+func NewBudget(db *gorm.DB, cfg *config.Config) *Budget {
+	return &Budget{
+		db:       db,
+		quotas:   cfg.OSINTDailyQuotas,
+		rates:    RateLimits(cfg),
+		limiters: make(map[string]*limiter),
+	}
+}
+
+// RateLimits returns the calls per minute allowed to each rate limited
+// provider: OSINT_RATE_LIMITS, and VIRUSTOTAL_RATE_LIMIT for VirusTotal
+// unless it names it
+func RateLimits(cfg *config.Config) map[string]int {
+	rates := map[string]int{"virustotal": cfg.VirusTotalRateLimit}
+	for provider, rate := range cfg.OSINTRateLimits {
+		rates[provider] = rate
+	}
+	return rates
+}
+
+// Spend waits for the provider's rate limit and counts a call against its
+// daily quota, or returns ErrBudgetExhausted when the quota is used up.
+// Calls answered from the cache don't spend. A nil budget allows every call.
+func (b *Budget) Spend(ctx context.Context, provider string) error {
+	if b == nil {
+		return nil
+	}
+	if err := b.reserve(provider); err != nil {
+		return err
+	}
+	return b.limiter(provider).wait(ctx)
+}
+
+// reserve counts a call of today against the provider's quota
+func (b *Budget) reserve(provider string) error {
+	day, now := today(), time.Now().UTC()
+	quota := b.quotas[provider]
+	for attempt := 0; attempt < 2; attempt++ {
+		update := b.db.Model(&models.OSINTUsage{}).
+			Where("provider = ? AND day = ? AND exhausted_at IS NULL", provider, day)
+		if quota > 0 {
+			update = update.Where("calls < ?", quota)
+		}
+		update = update.UpdateColumns(map[string]interface{}{
+			"calls":        gorm.Expr("calls + 1"),
+			"last_call_at": now,
+		})
+		if update.Error != nil {
+			return fmt.Errorf("failed to count %s call: %w", provider, update.Error)
+		}
+		if update.RowsAffected == 1 {
+			return nil
+		}
+
+		// The day's first call, unless another worker made it meanwhile or
+		// the quota is used up
+		created := b.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.OSINTUsage{
+			Provider:   provider,
+			Day:        day,
+			Calls:      1,
+			LastCallAt: &now,
+		})
+		if created.Error != nil {
+			return fmt.Errorf("failed to count %s call: %w", provider, created.Error)
+		}
+		if created.RowsAffected == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %w", provider, ErrBudgetExhausted)
+}
+
+// Exhaust marks the provider's quota used up for today, when the provider
+// answered so before the configured quota was reached
+func (b *Budget) Exhaust(provider string) {
+	if b == nil {
+		return
+	}
+	now := time.Now().UTC()
+	usage := models.OSINTUsage{Provider: provider, Day: today(), ExhaustedAt: &now}
+	if err := b.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"exhausted_at"}),
+	}).Create(&usage).Error; err != nil {
+		log.Printf("Failed to record exhausted %s quota: %v", provider, err)
+	}
+}
+
+// Exhausted reports whether the provider's quota is used up for today
+func (b *Budget) Exhausted(provider string) bool {
+	if b == nil {
+		return false
+	}
+	var usage models.OSINTUsage
+	if err := b.db.Where("provider = ? AND day = ?", provider, today()).First(&usage).Error; err != nil {
+		return false
+	}
+	quota := b.quotas[provider]
+	return usage.ExhaustedAt != nil || (quota > 0 && usage.Calls >= quota)
+}
+
+func (b *Budget) limiter(provider string) *limiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.limiters[provider]
+	if !ok {
+		l = newLimiter(b.rates[provider])
+		b.limiters[provider] = l
+	}
+	return l
+}
+
+// Quota is a provider's budget for today
+type Quota struct {
+	Provider    string     `json:"provider"`
+	DailyQuota  int        `json:"daily_quota"` // 0 for unlimited
+	RateLimit   int        `json:"rate_limit_per_minute"`
+	Used        int        `json:"used"`
+	Remaining   *int       `json:"remaining"` // nil for unlimited
+	Exhausted   bool       `json:"exhausted"`
+	ExhaustedAt *time.Time `json:"exhausted_at,omitempty"`
+	LastCallAt  *time.Time `json:"last_call_at,omitempty"`
+	ResetsAt    time.Time  `json:"resets_at"`
+}
+
+// Quotas returns today's budget of the providers: the named ones, and those
+// with a quota, a rate limit or calls today
+func Quotas(db *gorm.DB, cfg *config.Config, providers []string) ([]Quota, error) {
+	var usages []models.OSINTUsage
+	if err := db.Where("day = ?", today()).Find(&usages).Error; err != nil {
+		return nil, err
+	}
+	used := make(map[string]models.OSINTUsage, len(usages))
+	names := make(map[string]bool)
+	for _, usage := range usages {
+		used[usage.Provider] = usage
+		names[usage.Provider] = true
+	}
+	rates := RateLimits(cfg)
+	for _, provider := range providers {
+		names[provider] = true
+	}
+	for provider := range cfg.OSINTDailyQuotas {
+		names[provider] = true
+	}
+	for provider, rate := range rates {
+		if rate > 0 {
+			names[provider] = true
+		}
+	}
+
+	quotas := make([]Quota, 0, len(names))
+	for provider := range names {
+		usage := used[provider]
+		quota := Quota{
+			Provider:    provider,
+			DailyQuota:  cfg.OSINTDailyQuotas[provider],
+			RateLimit:   rates[provider],
+			Used:        usage.Calls,
+			ExhaustedAt: usage.ExhaustedAt,
+			LastCallAt:  usage.LastCallAt,
+			ResetsAt:    ResetsAt(),
+		}
+		if quota.DailyQuota > 0 {
+			remaining := max(quota.DailyQuota-usage.Calls, 0)
+			if usage.ExhaustedAt != nil {
+				remaining = 0
+			}
+			quota.Remaining = &remaining
+			quota.Exhausted = remaining == 0
+		} else {
+			quota.Exhausted = usage.ExhaustedAt != nil
+		}
+		quotas = append(quotas, quota)
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Provider < quotas[j].Provider })
+	return quotas, nil
+}
+
+// ResetsAt returns when the daily quotas start over: the next UTC midnight
+func ResetsAt() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+func today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// limiter spaces calls evenly to stay within a per-minute limit
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(perMinute int) *limiter {
+	if perMinute <= 0 {
+		return &limiter{}
+	}
+	return &limiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// wait blocks until the next call may be made or the context is done
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package osint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Cache keeps the responses of the providers in the database, shared by all
+// workers, so analyses of the same device model don't spend quota on the
+// same queries again. Responses are kept for the provider's TTL; failures
+// aren't kept.
+type Cache struct {
+	db      *gorm.DB
+	ttl     time.Duration
+	ttls    map[string]time.Duration
+	refresh bool
+}
+
+// NewCache returns the response cache, or nil when OSINT_CACHE_TTL is 0 and
+// OSINT_CACHE_TTLS sets none
+func NewCache(db *gorm.DB, cfg *config.Config) *Cache {
+	if cfg.OSINTCacheTTL <= 0 && len(cfg.OSINTCacheTTLs) == 0 {
+		return nil
+	}
+	return &Cache{db: db, ttl: cfg.OSINTCacheTTL, ttls: cfg.OSINTCacheTTLs}
+}
+
+// Refreshing returns a cache that queries the providers again and stores
+// their responses, for analyses forcing a refresh
+func (c *Cache) Refreshing() *Cache {
+	if c == nil {
+		return nil
+	}
+	refreshing := *c
+	refreshing.refresh = true
+	return &refreshing
+}
+
+// TTL returns how long a provider's responses are kept, 0 for not at all
+func (c *Cache) TTL(provider string) time.Duration {
+	if ttl, ok := c.ttls[provider]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+// Fetch returns the cached response of a provider to a query, or calls fetch
+// and caches what it returns. A nil cache always calls fetch.
+func Fetch[T any](c *Cache, provider, query string, fetch func() (T, error)) (T, error) {
+	if c == nil || c.TTL(provider) <= 0 {
+		return fetch()
+	}
+	key := cacheKey(provider, query)
+
+	if !c.refresh {
+		var entry models.OSINTCacheEntry
+		err := c.db.First(&entry, "key = ?", key).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load cached %s response: %v", provider, err)
+		}
+		if err == nil && time.Since(entry.FetchedAt) < c.TTL(provider) {
+			var cached T
+			if err := json.Unmarshal([]byte(entry.Data), &cached); err == nil {
+				return cached, nil
+			}
+		}
+	}
+
+	response, err := fetch()
+	if err != nil {
+		return response, err
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return response, nil
+	}
+	entry := models.OSINTCacheEntry{
+		Key:       key,
+		Provider:  provider,
+		Query:     query,
+		Data:      string(data),
+		FetchedAt: time.Now().UTC(),
+	}
+	if err := c.db.Save(&entry).Error; err != nil {
+		log.Printf("Failed to cache %s response: %v", provider, err)
+	}
+	return response, nil
+}
+
+func cacheKey(provider, query string) string {
+	sum := sha256.Sum256([]byte(provider + "\x00" + query))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,367 @@
+// Package censys measures the internet exposure of an analyzed firmware on
+// Censys: how many hosts serve its certificates, name the device model or
+// run its service versions, with a sample of them
+package censys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/integrations"
+	"odin-backend/internal/models"
+	"odin-backend/internal/osint"
+)
+
+const (
+	apiURL = "https://search.censys.io/api/v2"
+
+	// Source is the OSINT source of Censys' results
+	Source = "censys"
+
+	// maxQueries bounds the searches, which cost query credits, per
+	// analysis; sampleHosts the hosts kept of each
+	maxQueries  = 10
+	sampleHosts = 5
+)
+
+// Specificity of a search: how surely the hosts it found run the analyzed
+// firmware, by what matched them
+const (
+	specificityCertificate  = 90 // serve a certificate embedded in the firmware
+	specificityCertName     = 60 // serve a certificate for a name one embedded in the firmware is for
+	specificityModelService = 70 // run the firmware's service version and name the model
+	specificityModel        = 50 // name the device model
+	specificityService      = 20 // run the firmware's service version
+)
+
+// Client searches Censys' host index
+type Client struct {
+	apiID     string // from the environment, used without stored keys
+	apiSecret string
+	keys      *integrations.Keyring
+	baseURL   string
+	client    *http.Client
+}
+
+// New returns a Censys client, or nil in offline mode or unless
+// CENSYS_API_ID and CENSYS_API_SECRET are set or keys can be stored
+func New(cfg *config.Config, keys *integrations.Keyring) *Client {
+	if cfg.OfflineMode || ((cfg.CensysAPIID == "" || cfg.CensysAPISecret == "") && keys == nil) {
+		return nil
+	}
+	return &Client{
+		apiID:     cfg.CensysAPIID,
+		apiSecret: cfg.CensysAPISecret,
+		keys:      keys,
+		baseURL:   apiURL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Host is a host Censys scanned
+type Host struct {
+	IP       string `json:"ip"`
+	Services []struct {
+		Port                int    `json:"port"`
+		ServiceName         string `json:"service_name"`
+		ExtendedServiceName string `json:"extended_service_name"`
+		TransportProtocol   string `json:"transport_protocol"`
+	} `json:"services"`
+	Location struct {
+		Country string `json:"country"`
+		City    string `json:"city"`
+	} `json:"location"`
+	AutonomousSystem struct {
+		ASN  int    `json:"asn"`
+		Name string `json:"name"`
+	} `json:"autonomous_system"`
+	LastUpdatedAt string `json:"last_updated_at"`
+}
+
+// SearchResult is the first page of hosts matching a search and how many
+// match in all
+type SearchResult struct {
+	Total int    `json:"total"`
+	Hits  []Host `json:"hits"`
+}
+
+// Search runs a Censys host search and returns the first page of hosts
+func (c *Client) Search(ctx context.Context, query string) (*SearchResult, error) {
+	fallback := integrations.Key{KeyID: c.apiID, Secret: c.apiSecret}
+	if c.apiID == "" {
+		fallback = integrations.Key{}
+	}
+	key, err := c.keys.Key(Source, fallback)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{"q": {query}, "per_page": {strconv.Itoa(sampleHosts)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/hosts/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(key.KeyID, key.Secret)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Censys request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		err := fmt.Errorf("Censys returned status %d: %s", resp.StatusCode, failure.Error)
+		c.keys.Failed(key, err)
+		return nil, err
+	}
+	var body struct {
+		Result SearchResult `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode Censys response: %w", err)
+	}
+	return &body.Result, nil
+}
+
+// Target is what is known of an analyzed firmware to look it up by
+type Target struct {
+	Manufacturer string
+	Model        string
+	Certificates []models.KeyMaterial
+	Components   []models.SBOMComponent
+	// Cache keeps responses across analyses; nil queries every time
+	Cache *osint.Cache
+	// Budget spaces and counts the calls; nil doesn't
+	Budget *osint.Budget
+}
+
+// query is a search, what it looks for, how surely its hosts run the
+// firmware and the entity it looks for
+type query struct {
+	text        string
+	about       string
+	specificity int
+	entity      string
+}
+
+// Name returns the provider's name, the source of its results
+func (c *Client) Name() string {
+	return Source
+}
+
+// Enrich measures the exposure of the subject's firmware on Censys
+func (c *Client) Enrich(ctx context.Context, subject osint.Subject) osint.Enrichment {
+	found := c.Lookup(ctx, Target{
+		Manufacturer: subject.Project.Manufacturer,
+		Model:        subject.Project.DeviceModel,
+		Certificates: subject.Certificates,
+		Components:   subject.Components,
+		Cache:        subject.Cache,
+		Budget:       subject.Budget,
+	})
+	log.Printf("Censys found exposed hosts for %d searches of project %s", len(found), subject.Project.ID)
+	return osint.Enrichment{Results: found}
+}
+
+// Lookup searches Censys for hosts running the target's firmware and
+// returns an OSINT result per search that found any, with the number of
+// hosts and a sample of them. Searches stop at the context's deadline or
+// the first failure; the results until then are returned.
+func (c *Client) Lookup(ctx context.Context, target Target) []models.OSINTResult {
+	var results []models.OSINTResult
+	for _, q := range queries(target) {
+		found, err := osint.Fetch(target.Cache, Source, q.text, func() (*SearchResult, error) {
+			if err := target.Budget.Spend(ctx, Source); err != nil {
+				return nil, err
+			}
+			return c.Search(ctx, q.text)
+		})
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, integrations.ErrNoKey) {
+				log.Printf("Censys search %q failed: %v", q.text, err)
+			}
+			break
+		}
+		if found.Total == 0 {
+			continue
+		}
+		results = append(results, result(q, found))
+	}
+	return results
+}
+
+// queries builds the searches for a target, the most specific first
+func queries(target Target) []query {
+	var queries []query
+	names := make(map[string]bool)
+	for _, cert := range target.Certificates {
+		if cert.Kind != models.KeyKindCertificate || cert.Fingerprint == "" {
+			continue
+		}
+		queries = append(queries, query{
+			"services.tls.certificates.leaf_data.public_key.fingerprint: " + cert.Fingerprint,
+			"serve the certificate " + cert.Subject,
+			specificityCertificate,
+			osint.CertificateEntity(cert.Fingerprint),
+		})
+		if name := commonName(cert.Subject); strings.Contains(name, ".") && !names[name] {
+			names[name] = true
+			queries = append(queries, query{
+				"services.tls.certificates.leaf_data.names: " + quote(name),
+				"serve a certificate for " + name,
+				specificityCertName,
+				osint.CertificateEntity(cert.Fingerprint),
+			})
+		}
+	}
+
+	model := strings.TrimSpace(target.Model)
+	if model != "" {
+		device := model
+		if target.Manufacturer != "" && !strings.Contains(strings.ToLower(model), strings.ToLower(target.Manufacturer)) {
+			device = target.Manufacturer + " " + model
+		}
+		queries = append(queries, query{
+			"services.banner: " + quote(model) + " or services.http.response.html_title: " + quote(model),
+			"name the " + device,
+			specificityModel,
+			osint.DeviceEntity(target.Manufacturer, model),
+		})
+	}
+
+	seen := make(map[string]bool)
+	for _, component := range target.Components {
+		product, ok := products[strings.ToLower(component.Name)]
+		if !ok || component.Version == "" || seen[product+component.Version] {
+			continue
+		}
+		seen[product+component.Version] = true
+		text := fmt.Sprintf("services.software.product: %s and services.software.version: %s", quote(product), quote(component.Version))
+		about := fmt.Sprintf("run %s %s", component.Name, component.Version)
+		specificity := specificityService
+		if model != "" {
+			text = fmt.Sprintf("(%s) and services.banner: %s", text, quote(model))
+			about += " and name the " + model
+			specificity = specificityModelService
+		}
+		queries = append(queries, query{text, about, specificity, osint.ServiceEntity(component.Name, component.Version)})
+	}
+
+	if len(queries) > maxQueries {
+		queries = queries[:maxQueries]
+	}
+	return queries
+}
+
+// products maps the SBOM names of embedded network services to the product
+// names Censys' software fingerprints carry
+var products = map[string]string{
+	"dropbear":     "dropbear_ssh",
+	"openssh":      "openssh",
+	"lighttpd":     "lighttpd",
+	"boa":          "boa",
+	"goahead":      "goahead",
+	"thttpd":       "thttpd",
+	"mini_httpd":   "mini_httpd",
+	"uhttpd":       "uhttpd",
+	"nginx":        "nginx",
+	"dnsmasq":      "dnsmasq",
+	"miniupnpd":    "miniupnpd",
+	"proftpd":      "proftpd",
+	"vsftpd":       "vsftpd",
+	"pure-ftpd":    "pure-ftpd",
+	"samba":        "samba",
+	"net-snmp":     "net-snmp",
+	"openvpn":      "openvpn",
+	"mosquitto":    "mosquitto",
+	"micro_httpd":  "micro_httpd",
+	"apache":       "http_server",
+	"apache_httpd": "http_server",
+}
+
+// commonName returns the CN of a distinguished name
+func commonName(dn string) string {
+	for _, part := range strings.Split(dn, ",") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(part), "CN="); ok {
+			return strings.TrimPrefix(name, "*.")
+		}
+	}
+	return ""
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "") + `"`
+}
+
+// result converts a search into an OSINT result with the hosts found
+func result(q query, found *SearchResult) models.OSINTResult {
+	type sample struct {
+		IP       string   `json:"ip"`
+		Services []string `json:"services"`
+		Country  string   `json:"country,omitempty"`
+		City     string   `json:"city,omitempty"`
+		ASN      int      `json:"asn,omitempty"`
+		Network  string   `json:"network,omitempty"`
+		SeenAt   string   `json:"seen_at,omitempty"`
+	}
+	var hosts []sample
+	var countries []string
+	seenCountry := make(map[string]bool)
+	for i, host := range found.Hits {
+		if i == sampleHosts {
+			break
+		}
+		s := sample{
+			IP:      host.IP,
+			Country: host.Location.Country,
+			City:    host.Location.City,
+			ASN:     host.AutonomousSystem.ASN,
+			Network: host.AutonomousSystem.Name,
+			SeenAt:  host.LastUpdatedAt,
+		}
+		for _, service := range host.Services {
+			name := service.ExtendedServiceName
+			if name == "" {
+				name = service.ServiceName
+			}
+			s.Services = append(s.Services, fmt.Sprintf("%d/%s %s", service.Port, strings.ToLower(service.TransportProtocol), name))
+		}
+		hosts = append(hosts, s)
+		if host.Location.Country != "" && !seenCountry[host.Location.Country] {
+			seenCountry[host.Location.Country] = true
+			countries = append(countries, host.Location.Country)
+		}
+	}
+
+	description := fmt.Sprintf("%d hosts on the internet %s", found.Total, q.about)
+	if len(countries) > 0 {
+		description += "; sample in " + strings.Join(countries, ", ")
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"total":        found.Total,
+		"sample_hosts": hosts,
+	})
+	return models.OSINTResult{
+		Source:      Source,
+		Query:       q.text,
+		Title:       fmt.Sprintf("%d exposed hosts %s", found.Total, q.about),
+		Description: description,
+		URL:         "https://search.censys.io/search?resource=hosts&q=" + url.QueryEscape(q.text),
+		Data:        string(data),
+		Specificity: q.specificity,
+		Entity:      q.entity,
+	}
+}
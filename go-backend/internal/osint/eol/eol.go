@@ -0,0 +1,411 @@
+// Package eol finds what of an analyzed firmware is past its vendor's
+// support: the device model, by a curated table of vendor end-of-support
+// announcements, and the SBOM components, by endoflife.date's release cycles
+package eol
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+	"odin-backend/internal/osint"
+)
+
+const (
+	apiURL = "https://endoflife.date/api"
+
+	// Source is the OSINT source of the end-of-life results
+	Source = "endoflife"
+
+	// FindingEOL is the type of the findings of unsupported devices and
+	// components
+	FindingEOL = models.FindingType("eol")
+)
+
+// Specificity of a result: how surely it is about the analyzed firmware,
+// by what matched
+const (
+	specificityComponent = 100 // the component's version is in the cycle
+	specificityDevice    = 70  // the device model names match loosely
+)
+
+//go:embed devices.csv
+var devicesCSV string
+
+// Device is a device model its vendor announced the end of support of
+type Device struct {
+	Vendor       string
+	Model        string
+	EndOfSupport time.Time
+	Comment      string
+}
+
+// products maps the SBOM names of components to endoflife.date's products.
+// The Linux kernel is left out: the kernel analysis checks its branch.
+var products = map[string]string{
+	"openssl":    "openssl",
+	"python":     "python",
+	"python3":    "python",
+	"php":        "php",
+	"nginx":      "nginx",
+	"samba":      "samba",
+	"nodejs":     "nodejs",
+	"node":       "nodejs",
+	"openwrt":    "openwrt",
+	"busybox":    "busybox",
+	"sqlite":     "sqlite",
+	"postgresql": "postgresql",
+	"mysql":      "mysql",
+	"mariadb":    "mariadb",
+	"redis":      "redis",
+	"qt":         "qt",
+	"tomcat":     "tomcat",
+}
+
+// Cycle is a release cycle of a product on endoflife.date. EOL is a date or
+// a boolean.
+type Cycle struct {
+	Cycle  string          `json:"cycle"`
+	EOL    json.RawMessage `json:"eol"`
+	Latest string          `json:"latest"`
+	Link   string          `json:"link"`
+}
+
+// End returns whether the cycle reached its end of life by now, and the
+// date when endoflife.date has one
+func (c Cycle) End(now time.Time) (bool, string) {
+	var date string
+	if err := json.Unmarshal(c.EOL, &date); err == nil {
+		eol, err := time.Parse("2006-01-02", date)
+		return err == nil && !now.Before(eol), date
+	}
+	var ended bool
+	_ = json.Unmarshal(c.EOL, &ended)
+	return ended, ""
+}
+
+// Client looks up end-of-life dates
+type Client struct {
+	baseURL string
+	client  *http.Client
+	devices []Device
+
+	// offline leaves endoflife.date out: only the shipped table is searched
+	offline bool
+}
+
+// New returns the end-of-life lookup, or nil when EOL_LOOKUP is off. In
+// offline mode it only searches the shipped end-of-support table.
+func New(cfg *config.Config) *Client {
+	if !cfg.EOLLookup {
+		return nil
+	}
+	devices, err := parseDevices(strings.NewReader(devicesCSV))
+	if err != nil {
+		panic(fmt.Sprintf("shipped end-of-support table doesn't parse: %v", err))
+	}
+	return &Client{
+		baseURL: apiURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		devices: devices,
+		offline: cfg.OfflineMode,
+	}
+}
+
+// parseDevices reads the Vendor,Model,EndOfSupport,Comment table
+func parseDevices(r io.Reader) ([]Device, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var devices []Device
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		end, err := time.Parse("2006-01-02", record[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", record[0], record[1], err)
+		}
+		devices = append(devices, Device{Vendor: record[0], Model: record[1], EndOfSupport: end, Comment: record[3]})
+	}
+	return devices, nil
+}
+
+// Cycles returns the release cycles of an endoflife.date product, or nil
+// when endoflife.date doesn't track it
+func (c *Client) Cycles(ctx context.Context, product string) ([]Cycle, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/"+url.PathEscape(product)+".json", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("endoflife.date request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("endoflife.date returned status %d", resp.StatusCode)
+	}
+	var cycles []Cycle
+	if err := json.NewDecoder(resp.Body).Decode(&cycles); err != nil {
+		return nil, fmt.Errorf("failed to decode endoflife.date response: %w", err)
+	}
+	return cycles, nil
+}
+
+// Target is what is known of an analyzed firmware to look it up by
+type Target struct {
+	Manufacturer string
+	Model        string
+	Components   []models.SBOMComponent
+	// Cache keeps responses across analyses; nil queries every time
+	Cache *osint.Cache
+	// Budget spaces and counts the calls; nil doesn't
+	Budget *osint.Budget
+}
+
+// Name returns the provider's name, the source of its results
+func (c *Client) Name() string {
+	return Source
+}
+
+// Enrich finds what of the subject's firmware is past its vendor's
+// support, counted in the summary's eol_findings
+func (c *Client) Enrich(ctx context.Context, subject osint.Subject) osint.Enrichment {
+	found, unsupported := c.Lookup(ctx, Target{
+		Manufacturer: subject.Project.Manufacturer,
+		Model:        subject.Project.DeviceModel,
+		Components:   subject.Components,
+		Cache:        subject.Cache,
+		Budget:       subject.Budget,
+	})
+	log.Printf("%d end-of-life findings for project %s", len(unsupported), subject.Project.ID)
+	return osint.Enrichment{
+		Results:  found,
+		Findings: unsupported,
+		Summary:  map[string]interface{}{"eol_findings": len(unsupported)},
+	}
+}
+
+// Lookup returns an OSINT result and a finding for the device model when
+// its vendor no longer supports it, and for every component whose release
+// cycle reached its end of life, unless offline. Lookups stop at the context's deadline or
+// the first failure; what was found until then is returned.
+func (c *Client) Lookup(ctx context.Context, target Target) ([]models.OSINTResult, []models.Finding) {
+	now := time.Now()
+	var results []models.OSINTResult
+	var findings []models.Finding
+
+	if device, ok := c.device(target.Manufacturer, target.Model); ok && !now.Before(device.EndOfSupport) {
+		results = append(results, deviceResult(target, device))
+		findings = append(findings, deviceFinding(target, device))
+	}
+
+	if c.offline {
+		return results, findings
+	}
+
+	cycles := make(map[string][]Cycle)
+	seen := make(map[string]bool)
+	for _, component := range target.Components {
+		product, ok := products[strings.ToLower(component.Name)]
+		if !ok || component.Version == "" || seen[product+component.Version] {
+			continue
+		}
+		seen[product+component.Version] = true
+
+		known, looked := cycles[product]
+		if !looked {
+			var err error
+			known, err = osint.Fetch(target.Cache, Source, product, func() ([]Cycle, error) {
+				if err := target.Budget.Spend(ctx, Source); err != nil {
+					return nil, err
+				}
+				return c.Cycles(ctx, product)
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("endoflife.date lookup of %s failed: %v", product, err)
+				}
+				break
+			}
+			cycles[product] = known
+		}
+
+		cycle, ok := match(known, component.Version)
+		if !ok {
+			continue
+		}
+		if ended, date := cycle.End(now); ended {
+			results = append(results, componentResult(product, component, cycle, date))
+			findings = append(findings, componentFinding(product, component, cycle, date))
+		}
+	}
+	return results, findings
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normalize(s string) string {
+	return nonAlphanumeric.ReplaceAllString(strings.ToLower(s), "")
+}
+
+// device finds the device model in the table. Vendor names match loosely,
+// so "NETGEAR, Inc." finds Netgear's; models match exactly once
+// normalized, so the DIR-615 doesn't match the DIR-6150.
+func (c *Client) device(manufacturer, model string) (Device, bool) {
+	vendor := normalize(manufacturer)
+	name := normalize(model)
+	if vendor == "" || name == "" {
+		return Device{}, false
+	}
+	for _, device := range c.devices {
+		v := normalize(device.Vendor)
+		if (strings.Contains(vendor, v) || strings.Contains(v, vendor)) && normalize(device.Model) == name {
+			return device, true
+		}
+	}
+	return Device{}, false
+}
+
+// match returns the most specific release cycle a version belongs to:
+// 1.1.1w belongs to OpenSSL's 1.1.1, not a 1.1 cycle
+func match(cycles []Cycle, version string) (Cycle, bool) {
+	var best Cycle
+	found := false
+	for _, cycle := range cycles {
+		rest, ok := strings.CutPrefix(version, cycle.Cycle)
+		if !ok || cycle.Cycle == "" {
+			continue
+		}
+		if rest != "" && rest[0] >= '0' && rest[0] <= '9' {
+			continue
+		}
+		if !found || len(cycle.Cycle) > len(best.Cycle) {
+			best, found = cycle, true
+		}
+	}
+	return best, found
+}
+
+func deviceResult(target Target, device Device) models.OSINTResult {
+	data, _ := json.Marshal(map[string]interface{}{
+		"vendor":         device.Vendor,
+		"model":          device.Model,
+		"end_of_support": device.EndOfSupport.Format("2006-01-02"),
+		"comment":        device.Comment,
+	})
+	return models.OSINTResult{
+		Source:      Source,
+		Query:       device.Vendor + " " + device.Model,
+		Title:       fmt.Sprintf("%s %s is past its end of support", device.Vendor, device.Model),
+		Description: fmt.Sprintf("%s stopped supporting the %s on %s", device.Vendor, device.Model, device.EndOfSupport.Format("2006-01-02")),
+		Data:        string(data),
+		Specificity: specificityDevice,
+		Entity:      osint.DeviceEntity(target.Manufacturer, target.Model),
+	}
+}
+
+func deviceFinding(target Target, device Device) models.Finding {
+	description := fmt.Sprintf("%s stopped supporting the %s on %s. It gets no more firmware updates, so vulnerabilities found from then on stay unfixed",
+		device.Vendor, device.Model, device.EndOfSupport.Format("2006-01-02"))
+	if device.Comment != "" {
+		description += " (" + device.Comment + ")"
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"source":         Source,
+		"vendor":         device.Vendor,
+		"model":          device.Model,
+		"end_of_support": device.EndOfSupport.Format("2006-01-02"),
+	})
+	f := models.Finding{
+		Type:            FindingEOL,
+		Title:           fmt.Sprintf("Unsupported device %s %s", device.Vendor, device.Model),
+		Description:     description,
+		Severity:        models.RiskHigh,
+		Content:         strings.TrimSpace(target.Manufacturer + " " + target.Model),
+		FindingMetadata: string(metadata),
+		Confidence:      models.ConfidenceMedium,
+		OccurrenceCount: 1,
+	}
+	f.Fingerprint = f.ComputeFingerprint()
+	return f
+}
+
+func componentResult(product string, component models.SBOMComponent, cycle Cycle, date string) models.OSINTResult {
+	description := fmt.Sprintf("%s %s belongs to the %s cycle, which reached its end of life", component.Name, component.Version, cycle.Cycle)
+	if date != "" {
+		description += " on " + date
+	}
+	if cycle.Latest != "" {
+		description += "; its last release is " + cycle.Latest
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"product": product,
+		"version": component.Version,
+		"cycle":   cycle.Cycle,
+		"eol":     date,
+		"latest":  cycle.Latest,
+	})
+	return models.OSINTResult{
+		Source:      Source,
+		Query:       product + " " + component.Version,
+		Title:       fmt.Sprintf("%s %s is end-of-life", component.Name, component.Version),
+		Description: description,
+		URL:         "https://endoflife.date/" + product,
+		Data:        string(data),
+		Specificity: specificityComponent,
+		Entity:      osint.ServiceEntity(component.Name, component.Version),
+	}
+}
+
+func componentFinding(product string, component models.SBOMComponent, cycle Cycle, date string) models.Finding {
+	description := fmt.Sprintf("%s %s belongs to the %s release cycle, which no longer receives security fixes", component.Name, component.Version, cycle.Cycle)
+	if date != "" {
+		description = fmt.Sprintf("%s %s belongs to the %s release cycle, which stopped receiving security fixes on %s", component.Name, component.Version, cycle.Cycle, date)
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"source":  Source,
+		"product": product,
+		"cycle":   cycle.Cycle,
+		"eol":     date,
+		"purl":    component.PURL,
+	})
+	f := models.Finding{
+		Type:            FindingEOL,
+		Title:           fmt.Sprintf("End-of-life %s %s", component.Name, component.Version),
+		Description:     description,
+		Severity:        models.RiskHigh,
+		Content:         component.Name + " " + component.Version,
+		FindingMetadata: string(metadata),
+		Confidence:      models.ConfidenceHigh,
+		OccurrenceCount: 1,
+	}
+	f.Fingerprint = f.ComputeFingerprint()
+	return f
+}
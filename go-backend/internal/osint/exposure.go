@@ -0,0 +1,64 @@
+package osint
+
+import (
+	"encoding/json"
+
+	"odin-backend/internal/models"
+)
+
+// A change of exposure is material when a source's count moves by at least
+// materialShare of the larger count and materialCount in absolute terms, or
+// a source finds something where it found nothing
+const (
+	materialShare = 0.25
+	materialCount = 5
+)
+
+// Exposure sums a collection of OSINT results up per source: the number of
+// matching hosts a search engine reports when it does (Shodan's
+// total_matches, Censys' total), else the number of results
+func Exposure(results []models.OSINTResult) map[string]int {
+	exposure := make(map[string]int)
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Source]++
+		var data struct {
+			Total        int `json:"total"`
+			TotalMatches int `json:"total_matches"`
+		}
+		_ = json.Unmarshal([]byte(result.Data), &data)
+		if total := max(data.Total, data.TotalMatches); total > exposure[result.Source] {
+			exposure[result.Source] = total
+		}
+	}
+	for source, count := range counts {
+		exposure[source] = max(exposure[source], count)
+	}
+	return exposure
+}
+
+// MaterialChange reports whether the exposure of two collections differs
+// enough to tell subscribers
+func MaterialChange(before, after map[string]int) bool {
+	sources := make(map[string]bool)
+	for source := range before {
+		sources[source] = true
+	}
+	for source := range after {
+		sources[source] = true
+	}
+	for source := range sources {
+		was, now := before[source], after[source]
+		if (was == 0) != (now == 0) {
+			return true
+		}
+		diff := now - was
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff >= materialCount && float64(diff) >= materialShare*float64(max(was, now)) {
+			return true
+		}
+	}
+	return false
+}
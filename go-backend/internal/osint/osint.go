@@ -0,0 +1,122 @@
+// Package osint defines the external intelligence sources an analyzed
+// firmware is looked up in after its analysis. Every source is a Provider;
+// the worker runs the registered ones a project selects without knowing
+// what they are.
+package osint
+
+import (
+	"context"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// None as a project's selection runs no provider
+const None = "none"
+
+// Subject is what is known of an analyzed firmware to look it up by
+type Subject struct {
+	Project      *models.Project
+	Certificates []models.KeyMaterial
+	Components   []models.SBOMComponent
+	Files        []models.FirmwareFile
+
+	// Cache of the providers' responses, nil when caching is off
+	Cache *Cache
+
+	// Budget the providers' calls are spaced and counted by, nil for none
+	Budget *Budget
+}
+
+// Enrichment is what a provider found: OSINT results, findings, and
+// counters added to the analysis summary
+type Enrichment struct {
+	Results  []models.OSINTResult
+	Findings []models.Finding
+	Summary  map[string]interface{}
+}
+
+// Provider is an external intelligence source
+type Provider interface {
+	// Name identifies the provider in project selections and logs, and is
+	// the source of its OSINT results
+	Name() string
+
+	// Enrich looks the subject up until the context's deadline and returns
+	// what was found. Failures are logged and end the lookup early: OSINT
+	// is enrichment, not part of the analysis.
+	Enrich(ctx context.Context, subject Subject) Enrichment
+}
+
+// Registry holds the providers configured on this instance, in the order
+// they run
+type Registry struct {
+	providers []Provider
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a provider, replacing one of the same name
+func (r *Registry) Register(provider Provider) {
+	for i, registered := range r.providers {
+		if registered.Name() == provider.Name() {
+			r.providers[i] = provider
+			return
+		}
+	}
+	r.providers = append(r.providers, provider)
+}
+
+// Providers returns the registered providers
+func (r *Registry) Providers() []Provider {
+	return r.providers
+}
+
+// Names returns the names of the registered providers
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for _, provider := range r.providers {
+		names = append(names, provider.Name())
+	}
+	return names
+}
+
+// For returns the providers a project runs: those its OSINTProviders
+// selection names, every registered one when it names none, no provider
+// for "none"
+func (r *Registry) For(project *models.Project) []Provider {
+	selection := ParseSelection(project.OSINTProviders)
+	if len(selection) == 0 {
+		return r.providers
+	}
+	selected := make(map[string]bool, len(selection))
+	for _, name := range selection {
+		selected[name] = true
+	}
+	var providers []Provider
+	for _, provider := range r.providers {
+		if selected[provider.Name()] {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// ParseSelection splits a comma-separated provider selection into
+// lowercase names, each once
+func ParseSelection(s string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,61 @@
+// Package providers registers the OSINT providers configured on this
+// instance. A new intelligence source only needs registering here.
+package providers
+
+import (
+	"log"
+	"strings"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/integrations"
+	"odin-backend/internal/osint"
+	"odin-backend/internal/osint/censys"
+	"odin-backend/internal/osint/eol"
+	"odin-backend/internal/osint/shodan"
+	"odin-backend/internal/osint/virustotal"
+
+	"gorm.io/gorm"
+)
+
+// New returns a registry of the providers with their credentials or flags
+// configured, limited to OSINT_PROVIDERS when it names any. In offline mode
+// only the end-of-life lookup of the shipped table is left. With
+// INTEGRATIONS_KEY set, the providers taking API keys are registered
+// whether or not they have one yet, as keys can be stored at any time.
+func New(db *gorm.DB, cfg *config.Config) *osint.Registry {
+	keys := integrations.New(db, cfg)
+	registry := osint.NewRegistry()
+	// Typed nil clients must not become non-nil providers
+	if client := shodan.New(cfg, keys); client != nil {
+		registry.Register(client)
+	}
+	if client := censys.New(cfg, keys); client != nil {
+		registry.Register(client)
+	}
+	if client := virustotal.New(cfg, keys); client != nil {
+		registry.Register(client)
+	}
+	if client := eol.New(cfg); client != nil {
+		registry.Register(client)
+	}
+
+	if len(cfg.OSINTProviders) == 0 {
+		return registry
+	}
+	enabled := osint.NewRegistry()
+	for _, name := range osint.ParseSelection(strings.Join(cfg.OSINTProviders, ",")) {
+		found := false
+		for _, provider := range registry.Providers() {
+			if provider.Name() == name {
+				enabled.Register(provider)
+				found = true
+			}
+		}
+		if !found && cfg.OfflineMode {
+			log.Printf("OSINT provider %q enabled but unreachable in offline mode, skipping", name)
+		} else if !found {
+			log.Printf("OSINT provider %q enabled but not configured, skipping", name)
+		}
+	}
+	return enabled
+}
@@ -0,0 +1,163 @@
+package osint
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+)
+
+// Factors of a result's confidence score
+const (
+	FactorSpecificity   = "specificity"
+	FactorReliability   = "reliability"
+	FactorCorroboration = "corroboration"
+)
+
+// DefaultWeights weigh the factors when OSINT_SCORE_WEIGHTS doesn't
+var DefaultWeights = map[string]float64{
+	FactorSpecificity:   0.6,
+	FactorReliability:   0.25,
+	FactorCorroboration: 0.15,
+}
+
+// DefaultReliability is how far each source's answers are trusted (0-100)
+// when OSINT_SOURCE_RELIABILITY doesn't say: file hashes and release
+// cycles are exact, host indexes' banners can be stale or spoofed
+var DefaultReliability = map[string]int{
+	"virustotal": 95,
+	"endoflife":  90,
+	"censys":     80,
+	"shodan":     75,
+}
+
+// unknownReliability is the reliability of a source without one
+const unknownReliability = 50
+
+// corroborationPerSource is what each other source finding the same entity
+// adds to the corroboration factor
+const corroborationPerSource = 50
+
+// Entity keys name what a query looked for, the same for every provider,
+// so results of different providers about it corroborate each other
+
+// CertificateEntity is the entity of a certificate by its fingerprint
+func CertificateEntity(fingerprint string) string {
+	return "certificate:" + strings.ToLower(fingerprint)
+}
+
+// DeviceEntity is the entity of a device model
+func DeviceEntity(manufacturer, model string) string {
+	device := strings.ToLower(strings.TrimSpace(model))
+	if m := strings.ToLower(strings.TrimSpace(manufacturer)); m != "" && !strings.Contains(device, m) {
+		device = m + " " + device
+	}
+	return "device:" + device
+}
+
+// ServiceEntity is the entity of a version of an SBOM component
+func ServiceEntity(name, version string) string {
+	return "component:" + strings.ToLower(name) + "@" + version
+}
+
+// FileEntity is the entity of a file by its SHA-256
+func FileEntity(sha256 string) string {
+	return "file:" + strings.ToLower(sha256)
+}
+
+// Scorer computes the confidence scores of OSINT results
+type Scorer struct {
+	weights     map[string]float64
+	reliability map[string]int
+}
+
+// NewScorer returns a scorer with the configured weights and source
+// reliabilities on top of the defaults
+func NewScorer(cfg *config.Config) *Scorer {
+	s := &Scorer{weights: DefaultWeights, reliability: make(map[string]int)}
+	if len(cfg.OSINTScoreWeights) > 0 {
+		s.weights = cfg.OSINTScoreWeights
+	}
+	for source, reliability := range DefaultReliability {
+		s.reliability[source] = reliability
+	}
+	for source, reliability := range cfg.OSINTSourceReliability {
+		s.reliability[source] = reliability
+	}
+	return s
+}
+
+// Factor is a factor of a score: its value (0-100) and weight
+type Factor struct {
+	Value  int     `json:"value"`
+	Weight float64 `json:"weight"`
+}
+
+// Breakdown records how a result's score was computed
+type Breakdown struct {
+	Score          int               `json:"score"`
+	Factors        map[string]Factor `json:"factors"`
+	Entity         string            `json:"entity,omitempty"`
+	CorroboratedBy []string          `json:"corroborated_by,omitempty"`
+}
+
+// Score sets the confidence score of results collected together: the
+// weighted mean of the specificity of the query that found a result, the
+// reliability of its source and its corroboration by the other sources that
+// found something about the same entity. The breakdown is recorded with
+// each result.
+func (s *Scorer) Score(results []models.OSINTResult) {
+	sources := make(map[string]map[string]bool)
+	for _, result := range results {
+		if result.Entity == "" {
+			continue
+		}
+		if sources[result.Entity] == nil {
+			sources[result.Entity] = make(map[string]bool)
+		}
+		sources[result.Entity][result.Source] = true
+	}
+
+	for i := range results {
+		result := &results[i]
+		var corroborating []string
+		for source := range sources[result.Entity] {
+			if source != result.Source {
+				corroborating = append(corroborating, source)
+			}
+		}
+		sort.Strings(corroborating)
+
+		reliability, ok := s.reliability[result.Source]
+		if !ok {
+			reliability = unknownReliability
+		}
+		values := map[string]int{
+			FactorSpecificity:   clamp(result.Specificity),
+			FactorReliability:   clamp(reliability),
+			FactorCorroboration: clamp(len(corroborating) * corroborationPerSource),
+		}
+
+		breakdown := Breakdown{Factors: make(map[string]Factor), Entity: result.Entity, CorroboratedBy: corroborating}
+		var sum, total float64
+		for factor, value := range values {
+			weight := s.weights[factor]
+			breakdown.Factors[factor] = Factor{Value: value, Weight: weight}
+			sum += weight * float64(value)
+			total += weight
+		}
+		if total > 0 {
+			breakdown.Score = clamp(int(math.Round(sum / total)))
+		}
+		result.ConfidenceScore = breakdown.Score
+		data, _ := json.Marshal(breakdown)
+		result.ScoreBreakdown = string(data)
+	}
+}
+
+func clamp(value int) int {
+	return max(0, min(value, 100))
+}
@@ -0,0 +1,321 @@
+// Package shodan looks up internet-facing devices running an analyzed
+// firmware on Shodan: hosts serving the firmware's certificates, banners of
+// its network services and mentions of the device model
+package shodan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/integrations"
+	"odin-backend/internal/models"
+	"odin-backend/internal/osint"
+)
+
+const (
+	apiURL = "https://api.shodan.io"
+
+	// Source is the OSINT source of Shodan's results
+	Source = "shodan"
+
+	// maxQueries bounds the searches, which cost query credits, per
+	// analysis; hostsPerQuery the hosts kept of each
+	maxQueries    = 10
+	hostsPerQuery = 10
+)
+
+// Specificity of a search: how surely the hosts it finds run the analyzed
+// firmware, by what matched them
+const (
+	specificityCertificate  = 90 // serves a certificate embedded in the firmware
+	specificityModelService = 70 // runs the firmware's service version and names the model
+	specificityModel        = 50 // names the device model
+	specificityService      = 20 // runs the firmware's service version
+	modelInBanner           = 10 // added when the banner names the model
+)
+
+// Client searches Shodan's host index
+type Client struct {
+	apiKey  string // from the environment, used without stored keys
+	keys    *integrations.Keyring
+	baseURL string
+	client  *http.Client
+}
+
+// New returns a Shodan client, or nil in offline mode or when
+// SHODAN_API_KEY is empty and no keys can be stored
+func New(cfg *config.Config, keys *integrations.Keyring) *Client {
+	if cfg.OfflineMode || (cfg.ShodanAPIKey == "" && keys == nil) {
+		return nil
+	}
+	return &Client{
+		apiKey:  cfg.ShodanAPIKey,
+		keys:    keys,
+		baseURL: apiURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Host is a service banner Shodan collected
+type Host struct {
+	IP        string   `json:"ip_str"`
+	Port      int      `json:"port"`
+	Transport string   `json:"transport"`
+	Org       string   `json:"org"`
+	ISP       string   `json:"isp"`
+	Hostnames []string `json:"hostnames"`
+	Location  struct {
+		Country string `json:"country_name"`
+		City    string `json:"city"`
+	} `json:"location"`
+	Product   string `json:"product"`
+	Version   string `json:"version"`
+	Data      string `json:"data"` // the banner
+	Timestamp string `json:"timestamp"`
+}
+
+// SearchResult is a page of hosts matching a search
+type SearchResult struct {
+	Total   int    `json:"total"`
+	Matches []Host `json:"matches"`
+}
+
+// Search runs a Shodan search query and returns the first page of hosts
+func (c *Client) Search(ctx context.Context, query string) (*SearchResult, error) {
+	key, err := c.keys.Key(Source, integrations.Key{Secret: c.apiKey})
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{"key": {key.Secret}, "query": {query}, "minify": {"true"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/shodan/host/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Shodan request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		err := fmt.Errorf("Shodan returned status %d: %s", resp.StatusCode, failure.Error)
+		c.keys.Failed(key, err)
+		return nil, err
+	}
+	var result SearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode Shodan response: %w", err)
+	}
+	return &result, nil
+}
+
+// Target is what is known of an analyzed firmware to look it up by
+type Target struct {
+	Manufacturer string
+	Model        string
+	Certificates []models.KeyMaterial
+	Components   []models.SBOMComponent
+	// Cache keeps responses across analyses; nil queries every time
+	Cache *osint.Cache
+	// Budget spaces and counts the calls; nil doesn't
+	Budget *osint.Budget
+}
+
+// query is a search, its specificity and the entity it looks for
+type query struct {
+	text        string
+	specificity int
+	entity      string
+}
+
+// Name returns the provider's name, the source of its results
+func (c *Client) Name() string {
+	return Source
+}
+
+// Enrich looks the subject's firmware up on Shodan
+func (c *Client) Enrich(ctx context.Context, subject osint.Subject) osint.Enrichment {
+	found := c.Lookup(ctx, Target{
+		Manufacturer: subject.Project.Manufacturer,
+		Model:        subject.Project.DeviceModel,
+		Certificates: subject.Certificates,
+		Components:   subject.Components,
+		Cache:        subject.Cache,
+		Budget:       subject.Budget,
+	})
+	log.Printf("Shodan found %d hosts for project %s", len(found), subject.Project.ID)
+	return osint.Enrichment{Results: found}
+}
+
+// Lookup searches Shodan for hosts running the target's firmware and returns
+// them as OSINT results, scored by what matched. Searches stop at the
+// context's deadline or the first failure; the hosts found until then are
+// returned.
+func (c *Client) Lookup(ctx context.Context, target Target) []models.OSINTResult {
+	var results []models.OSINTResult
+	seen := make(map[string]int)
+	for _, q := range queries(target) {
+		found, err := osint.Fetch(target.Cache, Source, q.text, func() (*SearchResult, error) {
+			if err := target.Budget.Spend(ctx, Source); err != nil {
+				return nil, err
+			}
+			return c.Search(ctx, q.text)
+		})
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, integrations.ErrNoKey) {
+				log.Printf("Shodan search %q failed: %v", q.text, err)
+			}
+			break
+		}
+		for i, host := range found.Matches {
+			if i == hostsPerQuery {
+				break
+			}
+			specificity := q.specificity
+			if target.Model != "" && strings.Contains(strings.ToLower(host.Data), strings.ToLower(target.Model)) {
+				specificity = min(specificity+modelInBanner, 100)
+			}
+			// A host several queries found keeps its best match
+			key := fmt.Sprintf("%s:%d", host.IP, host.Port)
+			if i, ok := seen[key]; ok {
+				if specificity > results[i].Specificity {
+					results[i] = result(q, host, specificity, found.Total)
+				}
+				continue
+			}
+			seen[key] = len(results)
+			results = append(results, result(q, host, specificity, found.Total))
+		}
+	}
+	return results
+}
+
+// queries builds the searches for a target, the most specific first
+func queries(target Target) []query {
+	var queries []query
+	for _, cert := range target.Certificates {
+		if cert.Kind == models.KeyKindCertificate && cert.Fingerprint != "" {
+			queries = append(queries, query{"ssl.cert.fingerprint:" + cert.Fingerprint, specificityCertificate, osint.CertificateEntity(cert.Fingerprint)})
+		}
+	}
+
+	model := strings.TrimSpace(target.Model)
+	if model != "" {
+		device := model
+		if target.Manufacturer != "" && !strings.Contains(strings.ToLower(model), strings.ToLower(target.Manufacturer)) {
+			device = target.Manufacturer + " " + model
+		}
+		queries = append(queries, query{quote(device), specificityModel, osint.DeviceEntity(target.Manufacturer, model)})
+	}
+
+	seen := make(map[string]bool)
+	for _, component := range target.Components {
+		product, ok := products[strings.ToLower(component.Name)]
+		if !ok || component.Version == "" || seen[product+component.Version] {
+			continue
+		}
+		seen[product+component.Version] = true
+		text := fmt.Sprintf("product:%s version:%s", quote(product), quote(component.Version))
+		specificity := specificityService
+		if model != "" {
+			text = quote(model) + " " + text
+			specificity = specificityModelService
+		}
+		queries = append(queries, query{text, specificity, osint.ServiceEntity(component.Name, component.Version)})
+	}
+
+	if len(queries) > maxQueries {
+		queries = queries[:maxQueries]
+	}
+	return queries
+}
+
+// products maps the SBOM names of embedded network services to the product
+// names Shodan's banners carry
+var products = map[string]string{
+	"dropbear":     "Dropbear sshd",
+	"openssh":      "OpenSSH",
+	"lighttpd":     "lighttpd",
+	"boa":          "Boa HTTPd",
+	"goahead":      "GoAhead-Webs",
+	"thttpd":       "thttpd",
+	"mini_httpd":   "mini_httpd",
+	"uhttpd":       "uhttpd",
+	"nginx":        "nginx",
+	"dnsmasq":      "Dnsmasq",
+	"miniupnpd":    "MiniUPnPd",
+	"proftpd":      "ProFTPD",
+	"vsftpd":       "vsftpd",
+	"pure-ftpd":    "Pure-FTPd",
+	"samba":        "Samba",
+	"net-snmp":     "Net-SNMP",
+	"openvpn":      "OpenVPN",
+	"mosquitto":    "Mosquitto",
+	"micro_httpd":  "micro_httpd",
+	"apache":       "Apache httpd",
+	"apache_httpd": "Apache httpd",
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "") + `"`
+}
+
+// result converts a host into an OSINT result
+func result(q query, host Host, specificity, total int) models.OSINTResult {
+	title := fmt.Sprintf("%s:%d", host.IP, host.Port)
+	if product := strings.TrimSpace(host.Product + " " + host.Version); product != "" {
+		title += " " + product
+	}
+
+	var about []string
+	if host.Org != "" {
+		about = append(about, host.Org)
+	}
+	if location := strings.Trim(host.Location.City+", "+host.Location.Country, ", "); location != "" {
+		about = append(about, location)
+	}
+	if len(host.Hostnames) > 0 {
+		about = append(about, strings.Join(host.Hostnames, ", "))
+	}
+	if host.Timestamp != "" {
+		about = append(about, "seen "+host.Timestamp)
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"ip":            host.IP,
+		"port":          host.Port,
+		"transport":     host.Transport,
+		"org":           host.Org,
+		"isp":           host.ISP,
+		"hostnames":     host.Hostnames,
+		"country":       host.Location.Country,
+		"city":          host.Location.City,
+		"product":       host.Product,
+		"version":       host.Version,
+		"banner":        host.Data,
+		"timestamp":     host.Timestamp,
+		"total_matches": total,
+	})
+	return models.OSINTResult{
+		Source:      Source,
+		Query:       q.text,
+		Title:       title,
+		Description: strings.Join(about, "; "),
+		URL:         "https://www.shodan.io/host/" + host.IP,
+		Data:        string(data),
+		Specificity: specificity,
+		Entity:      q.entity,
+	}
+}
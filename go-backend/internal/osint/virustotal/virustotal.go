@@ -0,0 +1,320 @@
+// Package virustotal looks the hashes of an analyzed firmware and of its
+// extracted executables up in VirusTotal. Files are never uploaded.
+package virustotal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/integrations"
+	"odin-backend/internal/models"
+	"odin-backend/internal/osint"
+)
+
+const (
+	apiURL = "https://www.virustotal.com/api/v3"
+
+	// Source is the OSINT source of VirusTotal's results
+	Source = "virustotal"
+
+	// maxLookups bounds the hashes looked up per analysis, which count
+	// against the key's daily quota
+	maxLookups = 100
+)
+
+// errQuotaExceeded is returned once the key's quota is used up
+var errQuotaExceeded = errors.New("VirusTotal quota exceeded")
+
+// Client looks up file reports. Lookups spend from the OSINT budget, which
+// spaces them to VIRUSTOTAL_RATE_LIMIT across concurrent analyses.
+type Client struct {
+	apiKey  string // from the environment, used without stored keys
+	keys    *integrations.Keyring
+	baseURL string
+	client  *http.Client
+}
+
+// New returns a VirusTotal client, or nil in offline mode or when
+// VIRUSTOTAL_API_KEY is empty and no keys can be stored
+func New(cfg *config.Config, keys *integrations.Keyring) *Client {
+	if cfg.OfflineMode || (cfg.VirusTotalAPIKey == "" && keys == nil) {
+		return nil
+	}
+	return &Client{
+		apiKey:  cfg.VirusTotalAPIKey,
+		keys:    keys,
+		baseURL: apiURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Report is what VirusTotal knows of a file
+type Report struct {
+	SHA256            string   `json:"sha256"`
+	MeaningfulName    string   `json:"meaningful_name"`
+	TypeDescription   string   `json:"type_description"`
+	Reputation        int      `json:"reputation"`
+	Tags              []string `json:"tags"`
+	LastAnalysisDate  int64    `json:"last_analysis_date"`
+	LastAnalysisStats struct {
+		Malicious  int `json:"malicious"`
+		Suspicious int `json:"suspicious"`
+		Undetected int `json:"undetected"`
+		Harmless   int `json:"harmless"`
+	} `json:"last_analysis_stats"`
+	LastAnalysisResults map[string]struct {
+		Category string `json:"category"`
+		Result   string `json:"result"`
+	} `json:"last_analysis_results"`
+	PopularThreatClassification struct {
+		SuggestedThreatLabel string `json:"suggested_threat_label"`
+	} `json:"popular_threat_classification"`
+}
+
+// FileReport returns VirusTotal's report of a SHA-256, or nil when the hash
+// isn't known to it
+func (c *Client) FileReport(ctx context.Context, sha256 string) (*Report, error) {
+	key, err := c.keys.Key(Source, integrations.Key{Secret: c.apiKey})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/files/"+sha256, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-apikey", key.Secret)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("VirusTotal request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusTooManyRequests:
+		c.keys.Failed(key, errQuotaExceeded)
+		return nil, errQuotaExceeded
+	default:
+		err := fmt.Errorf("VirusTotal returned status %d", resp.StatusCode)
+		c.keys.Failed(key, err)
+		return nil, err
+	}
+
+	var body struct {
+		Data struct {
+			Attributes Report `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode VirusTotal response: %w", err)
+	}
+	report := body.Data.Attributes
+	if report.SHA256 == "" {
+		report.SHA256 = sha256
+	}
+	return &report, nil
+}
+
+// Target is what of an analyzed firmware is looked up: the upload and the
+// executables extracted from it
+type Target struct {
+	Filename string
+	SHA256   string
+	Files    []models.FirmwareFile
+	// Cache keeps responses across analyses; nil queries every time
+	Cache *osint.Cache
+	// Budget spaces and counts the calls; nil doesn't
+	Budget *osint.Budget
+}
+
+// lookup is a hash to look up and the file it is of
+type lookup struct {
+	sha256 string
+	path   string // in the extraction tree; empty for the upload
+}
+
+// Name returns the provider's name, the source of its results
+func (c *Client) Name() string {
+	return Source
+}
+
+// Enrich looks the upload and its executables up; the malicious ones are
+// findings, counted in the summary's virustotal_malicious
+func (c *Client) Enrich(ctx context.Context, subject osint.Subject) osint.Enrichment {
+	found, flagged := c.Lookup(ctx, Target{
+		Filename: subject.Project.Filename,
+		SHA256:   subject.Project.FileHash,
+		Files:    subject.Files,
+		Cache:    subject.Cache,
+		Budget:   subject.Budget,
+	})
+	log.Printf("VirusTotal knows %d files of project %s, %d flagged malicious", len(found), subject.Project.ID, len(flagged))
+	return osint.Enrichment{
+		Results:  found,
+		Findings: flagged,
+		Summary:  map[string]interface{}{"virustotal_malicious": len(flagged)},
+	}
+}
+
+// Lookup looks the target's hashes up and returns the reports of the known
+// ones as OSINT results, and a finding for every file flagged malicious.
+// Lookups stop at the context's deadline, when the quota is exceeded or at
+// the first failure; what was found until then is returned.
+func (c *Client) Lookup(ctx context.Context, target Target) ([]models.OSINTResult, []models.Finding) {
+	var results []models.OSINTResult
+	var findings []models.Finding
+	for _, l := range lookups(target) {
+		report, err := osint.Fetch(target.Cache, Source, l.sha256, func() (*Report, error) {
+			if err := target.Budget.Spend(ctx, Source); err != nil {
+				return nil, err
+			}
+			return c.FileReport(ctx, l.sha256)
+		})
+		if err != nil {
+			// With stored keys, the next lookup may use another one
+			if errors.Is(err, errQuotaExceeded) && c.keys == nil {
+				target.Budget.Exhaust(Source)
+			}
+			if ctx.Err() == nil && !errors.Is(err, integrations.ErrNoKey) {
+				log.Printf("VirusTotal lookup of %s failed: %v", l.sha256, err)
+			}
+			break
+		}
+		if report == nil {
+			continue
+		}
+		name := l.path
+		if name == "" {
+			name = target.Filename
+		}
+		results = append(results, result(name, report))
+		if report.LastAnalysisStats.Malicious > 0 {
+			findings = append(findings, finding(l, name, report))
+		}
+	}
+	return results, findings
+}
+
+// lookups lists the hashes of a target, the upload first, each once
+func lookups(target Target) []lookup {
+	var lookups []lookup
+	seen := make(map[string]bool)
+	if target.SHA256 != "" {
+		lookups = append(lookups, lookup{sha256: target.SHA256})
+		seen[target.SHA256] = true
+	}
+	for _, file := range target.Files {
+		if file.FileType != "elf" || file.SHA256 == "" || seen[file.SHA256] {
+			continue
+		}
+		seen[file.SHA256] = true
+		lookups = append(lookups, lookup{sha256: file.SHA256, path: file.Path})
+	}
+	if len(lookups) > maxLookups {
+		lookups = lookups[:maxLookups]
+	}
+	return lookups
+}
+
+// result converts a report into an OSINT result. The hash matches the file
+// exactly, so the result is as specific as can be.
+func result(name string, report *Report) models.OSINTResult {
+	stats := report.LastAnalysisStats
+	title := fmt.Sprintf("%s: %d/%d engines flag it malicious", name, stats.Malicious,
+		stats.Malicious+stats.Suspicious+stats.Undetected+stats.Harmless)
+
+	description := fmt.Sprintf("%d malicious, %d suspicious, %d undetected, %d harmless",
+		stats.Malicious, stats.Suspicious, stats.Undetected, stats.Harmless)
+	if label := report.PopularThreatClassification.SuggestedThreatLabel; label != "" {
+		description += "; " + label
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"sha256":             report.SHA256,
+		"file":               name,
+		"meaningful_name":    report.MeaningfulName,
+		"type_description":   report.TypeDescription,
+		"reputation":         report.Reputation,
+		"tags":               report.Tags,
+		"last_analysis_date": report.LastAnalysisDate,
+		"stats":              stats,
+		"threat_label":       report.PopularThreatClassification.SuggestedThreatLabel,
+		"detections":         detections(report),
+	})
+	return models.OSINTResult{
+		Source:      Source,
+		Query:       report.SHA256,
+		Title:       title,
+		Description: description,
+		URL:         "https://www.virustotal.com/gui/file/" + report.SHA256,
+		Data:        string(data),
+		Specificity: 100,
+		Entity:      osint.FileEntity(report.SHA256),
+	}
+}
+
+// finding raises a file flagged malicious
+func finding(l lookup, name string, report *Report) models.Finding {
+	stats := report.LastAnalysisStats
+	title := fmt.Sprintf("VirusTotal flags %s as malicious", name)
+	if label := report.PopularThreatClassification.SuggestedThreatLabel; label != "" {
+		title += " (" + label + ")"
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"source":       Source,
+		"sha256":       report.SHA256,
+		"stats":        stats,
+		"threat_label": report.PopularThreatClassification.SuggestedThreatLabel,
+		"detections":   detections(report),
+	})
+	f := models.Finding{
+		Type:  models.FindingSecurityIssue,
+		Title: title,
+		Description: fmt.Sprintf("%d of the antivirus engines on VirusTotal flag %s (SHA-256 %s) as malicious",
+			stats.Malicious, name, report.SHA256),
+		Severity:        models.RiskCritical,
+		FilePath:        l.path,
+		Content:         strings.Join(detectionNames(report), ", "),
+		FindingMetadata: string(metadata),
+		Confidence:      models.ConfidenceHigh,
+		OccurrenceCount: 1,
+	}
+	f.Fingerprint = f.ComputeFingerprint()
+	return f
+}
+
+// detections maps the engines flagging a file malicious to their signatures
+func detections(report *Report) map[string]string {
+	found := make(map[string]string)
+	for engine, verdict := range report.LastAnalysisResults {
+		if verdict.Category == "malicious" {
+			found[engine] = verdict.Result
+		}
+	}
+	return found
+}
+
+// detectionNames lists the distinct signatures of a file, sorted
+func detectionNames(report *Report) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, signature := range detections(report) {
+		if signature != "" && !seen[signature] {
+			seen[signature] = true
+			names = append(names, signature)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
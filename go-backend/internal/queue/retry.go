@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"odin-backend/internal/config"
+)
+
+// FailureClass tells whether a failed job is worth retrying
+type FailureClass string
+
+const (
+	FailureTransient FailureClass = "transient"
+	FailurePermanent FailureClass = "permanent"
+)
+
+// classifiedError carries an explicit failure class through error wrapping
+type classifiedError struct {
+	class FailureClass
+	err   error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+func (e *classifiedError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not retryable, e.g. unparseable EMBA output
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: FailurePermanent, err: err}
+}
+
+// Transient marks an error as retryable, e.g. EMBA setup or infrastructure failures
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: FailureTransient, err: err}
+}
+
+// RetryPolicy controls how often and how quickly failed jobs are retried
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	// RetryablePatterns classify errors that weren't explicitly marked:
+	// an error whose message contains one of them is transient, anything
+	// else is permanent
+	RetryablePatterns []string
+}
+
+// DefaultRetryablePatterns match infrastructure failures that usually go away on their own
+var DefaultRetryablePatterns = []string{
+	"timeout",
+	"connection refused",
+	"connection reset",
+	"temporarily unavailable",
+	"no space left on device",
+	"signal: killed",
+}
+
+// DefaultRetryPolicy is used when nothing is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:        3,
+		BaseDelay:         time.Minute,
+		MaxDelay:          30 * time.Minute,
+		RetryablePatterns: DefaultRetryablePatterns,
+	}
+}
+
+// NewRetryPolicy builds the retry policy from configuration
+func NewRetryPolicy(cfg *config.Config) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = cfg.JobMaxRetries
+	if cfg.JobRetryBaseDelay > 0 {
+		policy.BaseDelay = cfg.JobRetryBaseDelay
+	}
+	if cfg.JobRetryMaxDelay > 0 {
+		policy.MaxDelay = cfg.JobRetryMaxDelay
+	}
+	if len(cfg.JobRetryableErrors) > 0 {
+		policy.RetryablePatterns = cfg.JobRetryableErrors
+	}
+	return policy
+}
+
+// Classify returns the failure class of an error
+func (p RetryPolicy) Classify(err error) FailureClass {
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.class
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, pattern := range p.RetryablePatterns {
+		if pattern != "" && strings.Contains(message, strings.ToLower(pattern)) {
+			return FailureTransient
+		}
+	}
+	return FailurePermanent
+}
+
+// ShouldRetry reports whether a job that failed with err after the given
+// number of retries should be attempted again
+func (p RetryPolicy) ShouldRetry(err error, retried int) bool {
+	return err != nil && retried < p.MaxRetries && p.Classify(err) == FailureTransient
+}
+
+// Backoff returns the delay before retry number n (starting at 0), doubling
+// from BaseDelay and capped at MaxDelay
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < n && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
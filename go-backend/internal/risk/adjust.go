@@ -0,0 +1,108 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+
+	"odin-backend/internal/cvss"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Adjust sets the adjusted score of a CVE finding: the environmental score
+// of its CVSS vector for the project's deployment and the maturity of the
+// exploits known of it. Findings without a vector, or with one that can't
+// be scored (v2, v4), get none.
+func Adjust(finding *models.CVEFinding, project *models.Project) {
+	finding.AdjustedScore, finding.AdjustedSeverity, finding.AdjustedVector = 0, "", ""
+
+	vector, err := cvss.Parse(finding.CVSSVector)
+	if err != nil {
+		return
+	}
+	adjusted, err := vector.Adjust(cvss.Context{
+		Reachability: project.Reachability,
+		Requirements: project.SecurityRequirements,
+		Exploit:      exploitMaturity(finding),
+	})
+	if err != nil {
+		return
+	}
+	finding.AdjustedVector = adjusted.String()
+	if !adjusted.Scorable() {
+		return
+	}
+	score, err := adjusted.EnvironmentalScore()
+	if err != nil {
+		return
+	}
+	finding.AdjustedScore = score
+	finding.AdjustedSeverity = severityOf(score)
+}
+
+// exploitMaturity rates the exploits known of a CVE: exploited in the wild
+// (CISA KEV), a Metasploit module, a public exploit or proof of concept,
+// or none
+func exploitMaturity(finding *models.CVEFinding) string {
+	switch {
+	case finding.KnownExploited:
+		return cvss.ExploitAttacked
+	case listed(finding.MetasploitModules):
+		return cvss.ExploitFunctional
+	case finding.ExploitAvailable || listed(finding.ExploitDBIDs) || listed(finding.PoCURLs):
+		return cvss.ExploitPoC
+	}
+	return cvss.ExploitUnreported
+}
+
+// listed reports whether a JSON array has an element
+func listed(array string) bool {
+	array = strings.TrimSpace(array)
+	return array != "" && array != "[]" && array != "null"
+}
+
+// severityOf maps a CVSS score to a risk level
+func severityOf(score float64) models.RiskLevel {
+	if rating := cvss.Severity(score); rating != "None" {
+		return models.RiskLevel(strings.ToLower(rating))
+	}
+	return models.RiskInfo
+}
+
+// Rescore adjusts the scores of a project's CVE findings again, after its
+// deployment context, a CVE's vector or its exploits changed. Frozen
+// projects stay as delivered.
+func Rescore(db *gorm.DB, projectID string) error {
+	var project models.Project
+	if err := db.Select("id", "reachability", "security_requirements", "frozen_at").
+		First(&project, "id = ?", projectID).Error; err != nil {
+		return fmt.Errorf("failed to load project: %w", err)
+	}
+	if project.Frozen() {
+		return nil
+	}
+
+	var findings []models.CVEFinding
+	if err := db.Select("id", "cvss_vector", "known_exploited", "exploit_available", "metasploit_modules",
+		"exploit_db_ids", "po_c_urls", "adjusted_score", "adjusted_severity", "adjusted_vector").
+		Where("project_id = ?", projectID).Find(&findings).Error; err != nil {
+		return fmt.Errorf("failed to load CVE findings: %w", err)
+	}
+	for _, finding := range findings {
+		before := finding
+		Adjust(&finding, &project)
+		if finding.AdjustedScore == before.AdjustedScore && finding.AdjustedSeverity == before.AdjustedSeverity &&
+			finding.AdjustedVector == before.AdjustedVector {
+			continue
+		}
+		if err := db.Model(&models.CVEFinding{}).Where("id = ?", finding.ID).UpdateColumns(map[string]interface{}{
+			"adjusted_score":    finding.AdjustedScore,
+			"adjusted_severity": finding.AdjustedSeverity,
+			"adjusted_vector":   finding.AdjustedVector,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to rescore CVE finding: %w", err)
+		}
+	}
+	return nil
+}
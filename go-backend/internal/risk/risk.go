@@ -0,0 +1,132 @@
+package risk
+
+import (
+	"fmt"
+
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Counts holds finding totals for a project broken down by severity
+type Counts struct {
+	Findings int `json:"findings"`
+	CVEs     int `json:"cves"`
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Info     int `json:"info"`
+
+	// CVEs with a public exploit or known to be exploited in the wild, and
+	// those of them rated high or critical
+	Exploitable     int `json:"exploitable"`
+	ExploitableHigh int `json:"exploitable_high"`
+}
+
+// Add counts a single finding or CVE of the given severity
+func (c *Counts) Add(severity models.RiskLevel) {
+	switch severity {
+	case models.RiskCritical:
+		c.Critical++
+	case models.RiskHigh:
+		c.High++
+	case models.RiskMedium:
+		c.Medium++
+	case models.RiskLow:
+		c.Low++
+	case models.RiskInfo:
+		c.Info++
+	}
+}
+
+// AddCVE counts a CVE finding, noting whether it is exploitable
+func (c *Counts) AddCVE(cve models.CVEFinding) {
+	c.Add(cve.SeverityLevel)
+	if cve.Exploitable() {
+		c.Exploitable++
+		if cve.SeverityLevel.Rank() >= models.RiskHigh.Rank() {
+			c.ExploitableHigh++
+		}
+	}
+}
+
+// CountProject tallies the stored findings and CVE findings of a project.
+// Suppressed CVEs are accepted risks and aren't counted, nor are findings
+// merged into another as duplicates.
+func CountProject(db *gorm.DB, projectID string) (Counts, error) {
+	var counts Counts
+	var findings []models.Finding
+	var cveFindings []models.CVEFinding
+
+	if err := db.Select("severity").Where("project_id = ? AND merged_into_id IS NULL", projectID).Find(&findings).Error; err != nil {
+		return counts, fmt.Errorf("failed to load findings: %w", err)
+	}
+	if err := db.Select("severity_level", "exploit_available", "known_exploited").Where("project_id = ? AND suppression_id IS NULL AND match_status <> ?", projectID, models.MatchRejected).Find(&cveFindings).Error; err != nil {
+		return counts, fmt.Errorf("failed to load CVE findings: %w", err)
+	}
+
+	counts.Findings = len(findings)
+	counts.CVEs = len(cveFindings)
+	for _, finding := range findings {
+		counts.Add(finding.Severity)
+	}
+	for _, cve := range cveFindings {
+		counts.AddCVE(cve)
+	}
+
+	return counts, nil
+}
+
+// Level derives the overall project risk level from severity counts. An
+// exploitable CVE makes a project high risk whatever its rating, and
+// critical when rated high: attackers needn't write the exploit themselves.
+func Level(c Counts) models.RiskLevel {
+	if c.Critical > 0 {
+		return models.RiskCritical
+	} else if c.High >= 3 || c.ExploitableHigh > 0 {
+		return models.RiskCritical
+	} else if c.High > 0 || c.Exploitable > 0 {
+		return models.RiskHigh
+	} else if c.Medium >= 5 {
+		return models.RiskHigh
+	} else if c.Medium > 0 {
+		return models.RiskMedium
+	} else if c.Low == 0 && c.Info > 0 {
+		return models.RiskInfo
+	}
+
+	return models.RiskLow
+}
+
+// Recount updates a completed project's severity counts and risk level
+// from its stored findings
+func Recount(db *gorm.DB, projectID string) error {
+	counts, err := CountProject(db, projectID)
+	if err != nil {
+		return err
+	}
+	var project models.Project
+	ApplyCounts(&project, counts)
+	return db.Model(&models.Project{}).Where("id = ?", projectID).UpdateColumns(map[string]interface{}{
+		"risk_level":     Level(counts),
+		"finding_count":  project.FindingCount,
+		"cve_count":      project.CVECount,
+		"critical_count": project.CriticalCount,
+		"high_count":     project.HighCount,
+		"medium_count":   project.MediumCount,
+		"low_count":      project.LowCount,
+		"info_count":     project.InfoCount,
+	}).Error
+}
+
+// ApplyCounts copies severity counts onto the project's counter fields
+func ApplyCounts(project *models.Project, c Counts) {
+	project.FindingCount = c.Findings
+	project.CVECount = c.CVEs
+	project.CriticalCount = c.Critical
+	project.HighCount = c.High
+	project.MediumCount = c.Medium
+	project.LowCount = c.Low
+	project.InfoCount = c.Info
+}
@@ -0,0 +1,138 @@
+package rtos
+
+import (
+	"encoding/json"
+
+	"odin-backend/internal/models"
+)
+
+// Source is the CVE source of RTOS vulnerabilities
+const Source = "odin-rtos"
+
+// versionRange is a range of affected versions: from introduced up to, but
+// not including, fixed. An empty fixed means no release fixes it.
+type versionRange struct {
+	introduced string
+	fixed      string
+}
+
+type knownCVE struct {
+	id          string
+	rtos        string
+	affected    []versionRange
+	fixedIn     string // VxWorks 7 service release with the fix
+	score       float64
+	severity    models.RiskLevel
+	vector      string
+	description string
+	references  []string
+}
+
+// knownCVEs are RTOS vulnerabilities with public version ranges: the six
+// remote code execution flaws of URGENT/11 in VxWorks' IPnet stack, the
+// FreeRTOS kernel's BadAlloc allocation overflows and Zephyr's syscall
+// validation flaw
+var knownCVEs = []knownCVE{
+	{
+		id: "CVE-2019-12256", rtos: VxWorks, affected: []versionRange{{"6.9.4", ""}}, fixedIn: "SR0640",
+		score: 9.8, severity: models.RiskCritical, vector: "CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		description: "URGENT/11: stack overflow parsing IPv4 options in the IPnet TCP/IP stack",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2019-12256", "https://www.windriver.com/security/announcements/tcp-ip-network-stack-ipnet-urgent11"},
+	},
+	{
+		id: "CVE-2019-12255", rtos: VxWorks, affected: []versionRange{{"6.5", ""}}, fixedIn: "SR0640",
+		score: 9.8, severity: models.RiskCritical, vector: "CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		description: "URGENT/11: buffer overflow from a TCP urgent pointer of zero in the IPnet TCP/IP stack",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2019-12255"},
+	},
+	{
+		id: "CVE-2019-12260", rtos: VxWorks, affected: []versionRange{{"6.9.3", ""}}, fixedIn: "SR0640",
+		score: 9.8, severity: models.RiskCritical, vector: "CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		description: "URGENT/11: buffer overflow from a malformed TCP AO option in the IPnet TCP/IP stack",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2019-12260"},
+	},
+	{
+		id: "CVE-2019-12261", rtos: VxWorks, affected: []versionRange{{"6.7", ""}}, fixedIn: "SR0640",
+		score: 9.8, severity: models.RiskCritical, vector: "CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		description: "URGENT/11: buffer overflow from a TCP urgent pointer in outbound connections of the IPnet TCP/IP stack",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2019-12261"},
+	},
+	{
+		id: "CVE-2019-12263", rtos: VxWorks, affected: []versionRange{{"6.9.3", ""}}, fixedIn: "SR0640",
+		score: 8.1, severity: models.RiskHigh, vector: "CVSS:3.0/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		description: "URGENT/11: race condition on the TCP urgent pointer leading to a buffer overflow in the IPnet TCP/IP stack",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2019-12263"},
+	},
+	{
+		id: "CVE-2019-12257", rtos: VxWorks, affected: []versionRange{{"6.5", "6.9.5"}},
+		score: 8.8, severity: models.RiskHigh, vector: "CVSS:3.0/AV:A/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		description: "URGENT/11: heap overflow in the DHCP client of the IPnet TCP/IP stack",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2019-12257"},
+	},
+	{
+		id: "CVE-2021-31571", rtos: FreeRTOS, affected: []versionRange{{"0", "10.4.3"}},
+		score: 9.8, severity: models.RiskCritical, vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		description: "BadAlloc: integer overflow in xQueueGenericCreate of the FreeRTOS kernel",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2021-31571"},
+	},
+	{
+		id: "CVE-2021-31572", rtos: FreeRTOS, affected: []versionRange{{"0", "10.4.3"}},
+		score: 9.8, severity: models.RiskCritical, vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		description: "BadAlloc: integer overflow in the stream buffer of the FreeRTOS kernel",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2021-31572"},
+	},
+	{
+		id: "CVE-2021-43997", rtos: FreeRTOS, affected: []versionRange{{"0", "10.4.6"}},
+		score: 7.8, severity: models.RiskHigh, vector: "CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H",
+		description: "Privilege escalation in the ARMv7-M and ARMv8-M MPU ports of the FreeRTOS kernel (only images built with an MPU port are affected)",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2021-43997"},
+	},
+	{
+		id: "CVE-2020-10024", rtos: Zephyr, affected: []versionRange{{"1.14.0", "1.14.2"}, {"2.0.0", "2.2.0"}},
+		score: 7.8, severity: models.RiskHigh, vector: "CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H",
+		description: "Signed comparison when validating system call numbers on ARM lets user threads escalate privileges",
+		references:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2020-10024"},
+	},
+}
+
+// CVEs returns the known vulnerabilities of the detected RTOS version. An
+// RTOS without a version matches nothing: its range can't be told.
+func CVEs(d *Detection, binaryPath string) []models.CVEFinding {
+	if d == nil || d.Version == "" {
+		return nil
+	}
+	var findings []models.CVEFinding
+	for _, cve := range knownCVEs {
+		if cve.rtos != d.Name || !cve.affects(d) {
+			continue
+		}
+		references, _ := json.Marshal(cve.references)
+		findings = append(findings, models.CVEFinding{
+			CVEID:           cve.id,
+			SoftwareName:    d.Name,
+			SoftwareVersion: d.Version,
+			Description:     cve.description,
+			SeverityScore:   cve.score,
+			SeverityLevel:   cve.severity,
+			CVSSVector:      cve.vector,
+			Source:          Source,
+			BinaryPath:      binaryPath,
+			ComponentMatch:  "name_version",
+			References:      string(references),
+		})
+	}
+	return findings
+}
+
+func (c knownCVE) affects(d *Detection) bool {
+	// VxWorks 7 is fixed by a service release rather than a version
+	if c.fixedIn != "" && d.Release != "" && d.Release >= c.fixedIn {
+		return false
+	}
+	for _, r := range c.affected {
+		if compareVersions(d.Version, r.introduced) >= 0 && (r.fixed == "" || compareVersions(d.Version, r.fixed) < 0) {
+			return true
+		}
+	}
+	return false
+}
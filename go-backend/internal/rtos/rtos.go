@@ -0,0 +1,155 @@
+// Package rtos detects real-time operating systems in monolithic firmware
+// images from the banners and version strings they are linked with, and
+// matches them against known RTOS vulnerabilities. These images often have
+// no filesystem to extract, so EMBA finds neither the OS nor its CVEs.
+package rtos
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RTOS names
+const (
+	FreeRTOS = "FreeRTOS"
+	Zephyr   = "Zephyr"
+	VxWorks  = "VxWorks"
+	ThreadX  = "ThreadX"
+)
+
+const (
+	maxScanSize = 256 << 20
+	chunkSize   = 1 << 20
+	overlap     = 256 // longest banner a chunk boundary could split
+)
+
+// Detection is an RTOS found in an image
+type Detection struct {
+	Name     string `json:"name"`
+	Version  string `json:"version,omitempty"`
+	Release  string `json:"release,omitempty"` // VxWorks 7 service release, e.g. SR0620
+	Evidence string `json:"evidence"`          // the string it was recognized by
+	Matches  int    `json:"matches"`
+}
+
+type signature struct {
+	name    string
+	marker  []byte
+	version *regexp.Regexp // first group: version, optional second: release
+}
+
+var signatures = []signature{
+	{FreeRTOS, []byte("FreeRTOS"), regexp.MustCompile(`FreeRTOS(?: Kernel)? V(\d+\.\d+\.\d+)`)},
+	{Zephyr, []byte("Zephyr OS"), regexp.MustCompile(`Zephyr OS (?:build )?(?:zephyr-)?v(\d+\.\d+\.\d+)`)},
+	{VxWorks, []byte("VxWorks"), regexp.MustCompile(`VxWorks ?(\d+(?:\.\d+)*)(?: SR(\d{4}))?`)},
+	{ThreadX, []byte("ThreadX"), regexp.MustCompile(`ThreadX[^\x00]{0,80}?Version [A-Z]?(\d+\.\d+(?:\.\d+)*)`)},
+}
+
+// Detect scans an image for RTOS signatures and returns the RTOS it most
+// likely runs, or nil. An RTOS whose version string was found wins over one
+// that is only mentioned.
+func Detect(path string) (*Detection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	found := make(map[string]*Detection)
+	buf := make([]byte, chunkSize+overlap)
+	carried, scanned := 0, 0
+	for scanned < maxScanSize {
+		n, err := io.ReadFull(f, buf[carried:])
+		if n == 0 {
+			break
+		}
+		scan(buf[:carried+n], found)
+		scanned += n
+		if err != nil {
+			break
+		}
+		// Keep the tail so banners across the boundary are seen whole
+		carried = copy(buf, buf[carried+n-overlap:carried+n])
+	}
+
+	var best *Detection
+	for _, sig := range signatures {
+		if d := found[sig.name]; d != nil && better(d, best) {
+			best = d
+		}
+	}
+	return best, nil
+}
+
+// Summary describes a detection for logs and status messages
+func (d *Detection) Summary() string {
+	if d.Version == "" {
+		return d.Name
+	}
+	if d.Release != "" {
+		return fmt.Sprintf("%s %s %s", d.Name, d.Version, d.Release)
+	}
+	return fmt.Sprintf("%s %s", d.Name, d.Version)
+}
+
+func better(d, best *Detection) bool {
+	if best == nil {
+		return true
+	}
+	if (d.Version != "") != (best.Version != "") {
+		return d.Version != ""
+	}
+	return d.Matches > best.Matches
+}
+
+func scan(chunk []byte, found map[string]*Detection) {
+	for _, sig := range signatures {
+		count := bytes.Count(chunk, sig.marker)
+		if count == 0 {
+			continue
+		}
+		d := found[sig.name]
+		if d == nil {
+			d = &Detection{Name: sig.name, Evidence: sig.name}
+			found[sig.name] = d
+		}
+		d.Matches += count
+		if d.Version != "" {
+			continue
+		}
+		if match := sig.version.FindSubmatch(chunk); match != nil {
+			d.Version = string(match[1])
+			if len(match) > 2 && len(match[2]) > 0 {
+				d.Release = "SR" + string(match[2])
+			}
+			d.Evidence = strings.TrimSpace(string(match[0]))
+		}
+	}
+}
+
+// compareVersions compares dotted numeric versions: -1, 0 or 1. Missing
+// components count as zero, so 7 equals 7.0.
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,444 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+)
+
+// KeySource is the metadata source of the key analysis' findings
+const KeySource = "key_analysis"
+
+const (
+	maxKeyFileSize = 8 << 20 // keys and certificates also sit in binaries
+	minKeySize     = 2048    // RSA and DSA keys below this are weak
+)
+
+// Formats of key material
+const (
+	keyFormatPEM     = "pem"
+	keyFormatDER     = "der"
+	keyFormatOpenSSH = "openssh"
+)
+
+// derExtensions are the file extensions tried as DER when a file holds no PEM
+var derExtensions = map[string]bool{
+	".der": true, ".cer": true, ".crt": true, ".key": true, ".p8": true, ".pk8": true,
+}
+
+var pemBegin = []byte("-----BEGIN ")
+
+// ScanKeys walks root for PEM and DER encoded keys and X.509 certificates
+// and returns them along with findings for weak keys and expired or
+// self-signed certificates. Paths are relative to root.
+func (s *Scanner) ScanKeys(ctx context.Context, root string) ([]models.KeyMaterial, []models.Finding, error) {
+	keys := []models.KeyMaterial{}
+	var findings []models.Finding
+	now := time.Now()
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > maxKeyFileSize {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+
+		rel := "/" + filepath.ToSlash(strings.TrimPrefix(p, root+string(filepath.Separator)))
+		found := parsePEM(content)
+		if len(found) == 0 && derExtensions[strings.ToLower(filepath.Ext(p))] {
+			if key, ok := parseDER(content); ok {
+				found = append(found, key)
+			}
+		}
+		for _, key := range found {
+			key.FilePath = rel
+			keys = append(keys, key)
+			findings = append(findings, keyFindings(key, now)...)
+		}
+		return nil
+	})
+	return keys, findings, err
+}
+
+// parsePEM returns the keys and certificates of every PEM block in content,
+// which may be a binary with blocks embedded in it
+func parsePEM(content []byte) []models.KeyMaterial {
+	var keys []models.KeyMaterial
+	rest := content
+	for {
+		start := bytes.Index(rest, pemBegin)
+		if start < 0 {
+			return keys
+		}
+		block, remainder := pem.Decode(rest[start:])
+		if block == nil {
+			rest = rest[start+len(pemBegin):]
+			continue
+		}
+		rest = remainder
+		if key, ok := parseBlock(block); ok {
+			keys = append(keys, key)
+		}
+	}
+}
+
+// parseBlock parses a single PEM block
+func parseBlock(block *pem.Block) (models.KeyMaterial, bool) {
+	key := models.KeyMaterial{Format: keyFormatPEM}
+	switch block.Type {
+	case "CERTIFICATE", "X509 CERTIFICATE", "TRUSTED CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return key, false
+		}
+		describeCertificate(&key, cert)
+	case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY", "DSA PRIVATE KEY":
+		key.Kind = models.KeyKindPrivateKey
+		if block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+			// Legacy OpenSSL encryption hides everything but the type
+			key.Encrypted = true
+			key.Algorithm = strings.TrimSuffix(block.Type, " PRIVATE KEY")
+			if key.Algorithm == "EC" {
+				key.Algorithm = "ECDSA"
+			}
+			return key, true
+		}
+		public, ok := parsePrivateKey(block.Type, block.Bytes)
+		if !ok {
+			return key, false
+		}
+		describePublicKey(&key, public)
+	case "ENCRYPTED PRIVATE KEY":
+		key.Kind = models.KeyKindPrivateKey
+		key.Encrypted = true
+	case "OPENSSH PRIVATE KEY":
+		return parseOpenSSH(block.Bytes)
+	case "PUBLIC KEY", "RSA PUBLIC KEY":
+		key.Kind = models.KeyKindPublicKey
+		var public interface{}
+		var err error
+		if block.Type == "RSA PUBLIC KEY" {
+			public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		} else {
+			public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		}
+		if err != nil {
+			return key, false
+		}
+		describePublicKey(&key, public)
+	default:
+		return key, false
+	}
+	return key, true
+}
+
+// parseDER tries a DER file as a certificate, then as a private key
+func parseDER(content []byte) (models.KeyMaterial, bool) {
+	key := models.KeyMaterial{Format: keyFormatDER}
+	if cert, err := x509.ParseCertificate(content); err == nil {
+		describeCertificate(&key, cert)
+		return key, true
+	}
+	for _, blockType := range []string{"PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY"} {
+		if public, ok := parsePrivateKey(blockType, content); ok {
+			key.Kind = models.KeyKindPrivateKey
+			describePublicKey(&key, public)
+			return key, true
+		}
+	}
+	if public, err := x509.ParsePKIXPublicKey(content); err == nil {
+		key.Kind = models.KeyKindPublicKey
+		describePublicKey(&key, public)
+		return key, true
+	}
+	return key, false
+}
+
+// parsePrivateKey returns the public half of an unencrypted private key
+func parsePrivateKey(blockType string, der []byte) (interface{}, bool) {
+	switch blockType {
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			return nil, false
+		}
+		return &private.PublicKey, true
+	case "EC PRIVATE KEY":
+		private, err := x509.ParseECPrivateKey(der)
+		if err != nil {
+			return nil, false
+		}
+		return &private.PublicKey, true
+	case "DSA PRIVATE KEY":
+		public, err := parseDSAPrivateKey(der)
+		return public, err == nil
+	default:
+		private, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, false
+		}
+		switch private := private.(type) {
+		case *rsa.PrivateKey:
+			return &private.PublicKey, true
+		case *ecdsa.PrivateKey:
+			return &private.PublicKey, true
+		case ed25519.PrivateKey:
+			return private.Public(), true
+		}
+		return nil, false
+	}
+}
+
+// parseDSAPrivateKey parses OpenSSL's DSA private key format, which Go's
+// x509 package doesn't support
+func parseDSAPrivateKey(der []byte) (*dsa.PublicKey, error) {
+	var private struct {
+		Version       int
+		P, Q, G, Y, X *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &private); err != nil {
+		return nil, err
+	}
+	return &dsa.PublicKey{
+		Parameters: dsa.Parameters{P: private.P, Q: private.Q, G: private.G},
+		Y:          private.Y,
+	}, nil
+}
+
+// describeCertificate fills key with the details of a certificate
+func describeCertificate(key *models.KeyMaterial, cert *x509.Certificate) {
+	key.Kind = models.KeyKindCertificate
+	describePublicKey(key, cert.PublicKey)
+	key.Subject = cert.Subject.String()
+	key.Issuer = cert.Issuer.String()
+	key.SerialNumber = cert.SerialNumber.String()
+	notBefore, notAfter := cert.NotBefore, cert.NotAfter
+	key.NotBefore = &notBefore
+	key.NotAfter = &notAfter
+	key.SelfSigned = bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// describePublicKey fills in the algorithm, size and fingerprint of a key
+func describePublicKey(key *models.KeyMaterial, public interface{}) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		key.Algorithm = "RSA"
+		key.KeySize = public.N.BitLen()
+	case *ecdsa.PublicKey:
+		key.Algorithm = "ECDSA"
+		key.KeySize = public.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		key.Algorithm = "Ed25519"
+		key.KeySize = 256
+	case *dsa.PublicKey:
+		key.Algorithm = "DSA"
+		key.KeySize = public.P.BitLen()
+	}
+	if der, err := x509.MarshalPKIXPublicKey(public); err == nil {
+		key.Fingerprint = fingerprint(der)
+	}
+}
+
+// parseOpenSSH reads the header of an openssh-key-v1 private key, which
+// holds the cipher and the public key in the clear
+func parseOpenSSH(data []byte) (models.KeyMaterial, bool) {
+	key := models.KeyMaterial{Kind: models.KeyKindPrivateKey, Format: keyFormatOpenSSH}
+	const magic = "openssh-key-v1\x00"
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return key, false
+	}
+	rest := data[len(magic):]
+
+	cipher, rest, ok := sshString(rest)
+	if !ok {
+		return key, false
+	}
+	key.Encrypted = string(cipher) != "none"
+	if _, rest, ok = sshString(rest); !ok { // KDF name
+		return key, false
+	}
+	if _, rest, ok = sshString(rest); !ok { // KDF options
+		return key, false
+	}
+	if len(rest) < 4 {
+		return key, false
+	}
+	rest = rest[4:] // number of keys
+	blob, _, ok := sshString(rest)
+	if !ok {
+		return key, false
+	}
+
+	algorithm, fields, ok := sshString(blob)
+	if !ok {
+		return key, false
+	}
+	switch string(algorithm) {
+	case "ssh-rsa":
+		_, fields, _ = sshString(fields) // exponent
+		modulus, _, ok := sshString(fields)
+		if ok {
+			key.Algorithm = "RSA"
+			key.KeySize = bitLen(modulus)
+		}
+	case "ssh-dss":
+		p, _, ok := sshString(fields)
+		if ok {
+			key.Algorithm = "DSA"
+			key.KeySize = bitLen(p)
+		}
+	case "ssh-ed25519":
+		key.Algorithm = "Ed25519"
+		key.KeySize = 256
+	default:
+		if strings.HasPrefix(string(algorithm), "ecdsa-sha2-nistp") {
+			key.Algorithm = "ECDSA"
+			fmt.Sscanf(strings.TrimPrefix(string(algorithm), "ecdsa-sha2-nistp"), "%d", &key.KeySize)
+		} else {
+			key.Algorithm = string(algorithm)
+		}
+	}
+	// Not a PKIX fingerprint, but the same key always has the same blob
+	key.Fingerprint = fingerprint(blob)
+	return key, true
+}
+
+// sshString splits a length-prefixed string off an SSH wire format buffer
+func sshString(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(data)
+	if uint64(n) > uint64(len(data)-4) {
+		return nil, nil, false
+	}
+	return data[4 : 4+n], data[4+n:], true
+}
+
+// bitLen returns the bit length of an SSH mpint
+func bitLen(mpint []byte) int {
+	for len(mpint) > 0 && mpint[0] == 0 {
+		mpint = mpint[1:]
+	}
+	if len(mpint) == 0 {
+		return 0
+	}
+	bits := (len(mpint) - 1) * 8
+	for b := mpint[0]; b != 0; b >>= 1 {
+		bits++
+	}
+	return bits
+}
+
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// keyFindings returns the findings for a weak key or a certificate that is
+// expired or self-signed
+func keyFindings(key models.KeyMaterial, now time.Time) []models.Finding {
+	var findings []models.Finding
+	metadata := map[string]interface{}{
+		"kind":        key.Kind,
+		"algorithm":   key.Algorithm,
+		"key_size":    key.KeySize,
+		"fingerprint": key.Fingerprint,
+	}
+
+	if (key.Algorithm == "RSA" || key.Algorithm == "DSA") && key.KeySize > 0 && key.KeySize < minKeySize {
+		findings = append(findings, newKeyFinding(key, models.FindingSecurityIssue, models.RiskHigh,
+			fmt.Sprintf("Weak %d-bit %s key", key.KeySize, key.Algorithm),
+			fmt.Sprintf("%s holds a %d-bit %s %s; keys below %d bits can be factored",
+				key.FilePath, key.KeySize, key.Algorithm, strings.ReplaceAll(key.Kind, "_", " "), minKeySize),
+			withCheck(metadata, "weak_key")))
+	}
+	if key.Kind != models.KeyKindCertificate {
+		return findings
+	}
+	if key.NotAfter != nil && key.NotAfter.Before(now) {
+		findings = append(findings, newKeyFinding(key, models.FindingConfigIssue, models.RiskMedium,
+			fmt.Sprintf("Expired certificate: %s", key.Subject),
+			fmt.Sprintf("The certificate in %s expired on %s", key.FilePath, key.NotAfter.Format("2006-01-02")),
+			withCheck(metadata, "expired_certificate")))
+	}
+	if key.SelfSigned {
+		findings = append(findings, newKeyFinding(key, models.FindingConfigIssue, models.RiskLow,
+			fmt.Sprintf("Self-signed certificate: %s", key.Subject),
+			fmt.Sprintf("The certificate in %s is signed by its own key, so clients can't verify it", key.FilePath),
+			withCheck(metadata, "self_signed_certificate")))
+	}
+	return findings
+}
+
+func withCheck(metadata map[string]interface{}, check string) map[string]interface{} {
+	copied := map[string]interface{}{"check": check}
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+// newKeyFinding creates a finding about a key or certificate
+func newKeyFinding(key models.KeyMaterial, findingType models.FindingType, severity models.RiskLevel, title, description string, metadata map[string]interface{}) models.Finding {
+	metadata["source"] = KeySource
+	encoded, _ := json.Marshal(metadata)
+	finding := models.Finding{
+		Type:            findingType,
+		Title:           title,
+		Description:     description,
+		Severity:        severity,
+		FilePath:        key.FilePath,
+		FindingMetadata: string(encoded),
+		Confidence:      models.ConfidenceHigh,
+		OccurrenceCount: 1,
+	}
+	finding.Fingerprint = finding.ComputeFingerprint()
+	return finding
+}
+
+// ReusedKeyFinding reports a private key that also ships in the firmware of
+// other analyses
+func ReusedKeyFinding(key models.KeyMaterial, projects []string) models.Finding {
+	return newKeyFinding(key, models.FindingPrivateKey, models.RiskCritical,
+		"Private key reused across firmware",
+		fmt.Sprintf("The %s private key in %s (fingerprint %s) also ships in %d other analyzed firmware; anyone holding one image can impersonate or decrypt traffic of all devices using it",
+			key.Algorithm, key.FilePath, key.Fingerprint, len(projects)),
+		map[string]interface{}{
+			"check":       "reused_private_key",
+			"fingerprint": key.Fingerprint,
+			"projects":    projects,
+		})
+}
@@ -0,0 +1,156 @@
+package scanner
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// Rule finds one kind of secret. Group is the capture group holding the
+// secret itself; matches whose secret has less Shannon entropy than
+// MinEntropy (bits per character) are taken for placeholders and dropped.
+type Rule struct {
+	ID         string
+	Name       string
+	Type       models.FindingType
+	Severity   models.RiskLevel
+	Pattern    *regexp.Regexp
+	Group      int
+	MinEntropy float64
+	Plain      bool // the group names the secret (e.g. the key type) and isn't redacted
+}
+
+// DefaultRules are the rules Scan applies
+var DefaultRules = []Rule{
+	{
+		ID:       "private_key",
+		Name:     "Private key",
+		Type:     models.FindingPrivateKey,
+		Severity: models.RiskHigh,
+		Pattern:  regexp.MustCompile(`-----BEGIN ((?:RSA |DSA |EC |OPENSSH |ENCRYPTED )?PRIVATE KEY)-----`),
+		Group:    1,
+		Plain:    true,
+	},
+	{
+		ID:       "aws_access_key",
+		Name:     "AWS access key ID",
+		Type:     models.FindingCredential,
+		Severity: models.RiskHigh,
+		Pattern:  regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`),
+		Group:    1,
+	},
+	{
+		ID:         "aws_secret_key",
+		Name:       "AWS secret access key",
+		Type:       models.FindingCredential,
+		Severity:   models.RiskHigh,
+		Pattern:    regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?[=:\s'"]([A-Za-z0-9/+=]{40})\b`),
+		Group:      1,
+		MinEntropy: 4.0,
+	},
+	{
+		ID:       "github_token",
+		Name:     "GitHub token",
+		Type:     models.FindingCredential,
+		Severity: models.RiskHigh,
+		Pattern:  regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,255})\b`),
+		Group:    1,
+	},
+	{
+		ID:       "slack_token",
+		Name:     "Slack token",
+		Type:     models.FindingCredential,
+		Severity: models.RiskHigh,
+		Pattern:  regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9-]{10,})\b`),
+		Group:    1,
+	},
+	{
+		ID:       "google_api_key",
+		Name:     "Google API key",
+		Type:     models.FindingCredential,
+		Severity: models.RiskMedium,
+		Pattern:  regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`),
+		Group:    1,
+	},
+	{
+		ID:         "jwt",
+		Name:       "JSON Web Token",
+		Type:       models.FindingSensitiveInfo,
+		Severity:   models.RiskMedium,
+		Pattern:    regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`),
+		Group:      1,
+		MinEntropy: 3.5,
+	},
+	{
+		ID:         "api_token",
+		Name:       "API token",
+		Type:       models.FindingCredential,
+		Severity:   models.RiskMedium,
+		Pattern:    regexp.MustCompile(`(?i)(?:\b|_)(?:api[_-]?key|api[_-]?token|access[_-]?token|auth[_-]?token|secret[_-]?key|client[_-]?secret)\b['"]?\s*[:=]\s*['"]?([A-Za-z0-9_\-./+=]{16,})`),
+		Group:      1,
+		MinEntropy: 3.5,
+	},
+	{
+		ID:         "hardcoded_password",
+		Name:       "Hardcoded password",
+		Type:       models.FindingCredential,
+		Severity:   models.RiskMedium,
+		Pattern:    regexp.MustCompile(`(?i)(?:\b|_)(?:password|passwd|passwort|pwd|pass)\b['"]?\s*[:=]\s*['"]?([^\s'"#;,&|)]{4,64})`),
+		Group:      1,
+		MinEntropy: 2.0,
+	},
+}
+
+// placeholderValues are "secrets" of configuration templates and code
+var placeholderValues = map[string]bool{
+	"null": true, "none": true, "true": true, "false": true, "empty": true,
+	"required": true, "optional": true, "undefined": true, "string": true,
+	"password": true, "passwd": true, "xxxx": true, "****": true,
+}
+
+// placeholder reports whether a captured value is a variable, template or
+// stand-in rather than a secret
+func placeholder(value string) bool {
+	if placeholderValues[strings.ToLower(value)] {
+		return true
+	}
+	switch value[0] {
+	case '$', '%', '{', '<', '@', '[', '(':
+		return true
+	}
+	return strings.Trim(value, "*xX.") == ""
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	total := float64(len([]rune(s)))
+	bits := 0.0
+	for _, count := range counts {
+		p := float64(count) / total
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// redact keeps the first and last characters of a secret so analysts can
+// tell secrets apart without the finding exposing them (or their length)
+func redact(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 8 {
+		return "****"
+	}
+	keep := 4
+	if len(runes) < 16 {
+		keep = 2
+	}
+	return string(runes[:keep]) + "****" + string(runes[len(runes)-keep:])
+}
@@ -0,0 +1,169 @@
+// Package scanner searches extracted firmware for secrets with Odin's own
+// rules (a regular expression plus an entropy threshold each), so every
+// analysis reports hardcoded keys and passwords whatever EMBA modules ran
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+)
+
+// Source is the metadata source of the scanner's findings
+const Source = "secret_scan"
+
+const (
+	maxScannedFileSize = 1 << 20
+	maxLineLength      = 4096 // longer lines are minified data, not config
+	contextLines       = 2
+	binarySniffLength  = 8000
+)
+
+// Scanner applies secret rules to the files of an extracted filesystem
+type Scanner struct {
+	rules []Rule
+}
+
+// New creates a scanner with the default rules, or returns nil when secret
+// scanning is disabled
+func New(cfg *config.Config) *Scanner {
+	if !cfg.SecretScan {
+		return nil
+	}
+	return &Scanner{rules: DefaultRules}
+}
+
+// Scan walks root and returns a finding per secret, with paths relative to
+// root and the surrounding lines, secrets redacted, as context
+func (s *Scanner) Scan(ctx context.Context, root string) ([]models.Finding, error) {
+	findings := []models.Finding{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > maxScannedFileSize {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil || isBinary(content) {
+			return nil
+		}
+
+		rel := "/" + filepath.ToSlash(strings.TrimPrefix(p, root+string(filepath.Separator)))
+		findings = append(findings, s.scanFile(rel, content)...)
+		return nil
+	})
+	return findings, err
+}
+
+// scanFile applies every rule to every line of a text file
+func (s *Scanner) scanFile(rel string, content []byte) []models.Finding {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), maxScannedFileSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	var findings []models.Finding
+	seen := make(map[string]bool)
+	for i, line := range lines {
+		if len(line) > maxLineLength {
+			continue
+		}
+		for _, rule := range s.rules {
+			for _, match := range rule.Pattern.FindAllStringSubmatch(line, -1) {
+				secret := match[rule.Group]
+				if !rule.Plain && (placeholder(secret) || entropy(secret) < rule.MinEntropy) {
+					continue
+				}
+				key := rule.ID + "\x00" + secret
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				shown := secret
+				if !rule.Plain {
+					shown = redact(secret)
+				}
+				metadata, _ := json.Marshal(map[string]interface{}{
+					"source":  Source,
+					"rule":    rule.ID,
+					"entropy": fmt.Sprintf("%.2f", entropy(secret)),
+				})
+				finding := models.Finding{
+					Type:            rule.Type,
+					Title:           fmt.Sprintf("%s: %s", rule.Name, shown),
+					Description:     fmt.Sprintf("%s found in %s line %d", rule.Name, rel, i+1),
+					Severity:        rule.Severity,
+					FilePath:        rel,
+					LineNumber:      i + 1,
+					Content:         strings.TrimSpace(strings.ReplaceAll(line, secret, shown)),
+					Context:         s.context(lines, i),
+					FindingMetadata: string(metadata),
+					Confidence:      models.ConfidenceMedium,
+					OccurrenceCount: 1,
+				}
+				finding.Fingerprint = finding.ComputeFingerprint()
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// context returns the lines around line i, numbered, with every secret the
+// rules find in them redacted
+func (s *Scanner) context(lines []string, i int) string {
+	start, end := i-contextLines, i+contextLines+1
+	if start < 0 {
+		start = 0
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var buf strings.Builder
+	for n := start; n < end; n++ {
+		line := lines[n]
+		if len(line) > maxLineLength {
+			line = line[:maxLineLength]
+		}
+		for _, rule := range s.rules {
+			if rule.Plain {
+				continue
+			}
+			for _, match := range rule.Pattern.FindAllStringSubmatch(line, -1) {
+				line = strings.ReplaceAll(line, match[rule.Group], redact(match[rule.Group]))
+			}
+		}
+		fmt.Fprintf(&buf, "%d: %s\n", n+1, line)
+	}
+	return buf.String()
+}
+
+// isBinary reports whether content has a NUL byte near the start
+func isBinary(content []byte) bool {
+	sniff := content
+	if len(sniff) > binarySniffLength {
+		sniff = sniff[:binarySniffLength]
+	}
+	return bytes.IndexByte(sniff, 0) >= 0
+}
@@ -0,0 +1,106 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MaxAllowedFileSize caps what an admin can configure as the upload limit (64GB)
+const MaxAllowedFileSize int64 = 64 << 30
+
+// MaxAllowedConcurrentScans caps the per-organization concurrent scan limit
+const MaxAllowedConcurrentScans = 1000
+
+var extensionRegex = regexp.MustCompile(`^\.[a-z0-9][a-z0-9._-]{0,15}$`)
+
+// Load returns the effective settings of an organization, falling back to
+// the instance defaults from config when nothing has been stored
+func Load(db *gorm.DB, cfg *config.Config, orgID string) (models.OrgSettings, error) {
+	if orgID == "" {
+		orgID = models.DefaultOrgID
+	}
+
+	defaults := models.OrgSettings{
+		OrgID:               orgID,
+		SupportedExtensions: strings.Join(cfg.SupportedExtensions, ","),
+		MaxFileSize:         cfg.MaxFileSize,
+		MaxConcurrentScans:  cfg.OrgMaxConcurrentScans,
+	}
+
+	var stored models.OrgSettings
+	err := db.First(&stored, "org_id = ?", orgID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaults, nil
+	}
+	if err != nil {
+		return defaults, fmt.Errorf("failed to load settings for org %s: %w", orgID, err)
+	}
+
+	if stored.SupportedExtensions == "" {
+		stored.SupportedExtensions = defaults.SupportedExtensions
+	}
+	if stored.MaxFileSize <= 0 {
+		stored.MaxFileSize = defaults.MaxFileSize
+	}
+	if stored.MaxConcurrentScans <= 0 {
+		stored.MaxConcurrentScans = defaults.MaxConcurrentScans
+	}
+	return stored, nil
+}
+
+// NormalizeExtensions lowercases extensions, adds the leading dot and
+// rejects anything that doesn't look like a file extension
+func NormalizeExtensions(extensions []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if !extensionRegex.MatchString(ext) {
+			return nil, fmt.Errorf("invalid file extension %q", ext)
+		}
+		if !seen[ext] {
+			seen[ext] = true
+			normalized = append(normalized, ext)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("at least one file extension is required")
+	}
+	return normalized, nil
+}
+
+// ValidateMaxFileSize checks an upload size limit is within sane bounds
+func ValidateMaxFileSize(size int64) error {
+	if size <= 0 {
+		return fmt.Errorf("max_file_size must be positive")
+	}
+	if size > MaxAllowedFileSize {
+		return fmt.Errorf("max_file_size must not exceed %d bytes", MaxAllowedFileSize)
+	}
+	return nil
+}
+
+// ValidateMaxConcurrentScans checks a per-organization scan limit; 0 means
+// the instance default applies
+func ValidateMaxConcurrentScans(limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("max_concurrent_scans must not be negative")
+	}
+	if limit > MaxAllowedConcurrentScans {
+		return fmt.Errorf("max_concurrent_scans must not exceed %d", MaxAllowedConcurrentScans)
+	}
+	return nil
+}
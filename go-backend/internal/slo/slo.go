@@ -0,0 +1,161 @@
+// Package slo measures analysis turnaround against configured objectives
+package slo
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// maxBreaches bounds the breach list of a report
+const maxBreaches = 100
+
+// Breach is an analysis that missed its objective's turnaround threshold
+type Breach struct {
+	JobID       string               `json:"job_id"`
+	Name        string               `json:"name"`
+	OrgID       string               `json:"org_id"`
+	Status      models.ProjectStatus `json:"status"`
+	CreatedAt   time.Time            `json:"created_at"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	Turnaround  float64              `json:"turnaround_seconds"`
+}
+
+// Report is an objective's attainment over the measurement window
+type Report struct {
+	Name             string    `json:"name"`
+	ScanProfile      string    `json:"scan_profile"`
+	Target           float64   `json:"target"`
+	Threshold        string    `json:"threshold"`
+	ThresholdSeconds float64   `json:"threshold_seconds"`
+	WindowStart      time.Time `json:"window_start"`
+
+	// Analyses that completed in time, missed the threshold (late, failed or
+	// still running past it), and are still running within it
+	Total    int `json:"total"`
+	Met      int `json:"met"`
+	Breached int `json:"breached"`
+	Pending  int `json:"pending"`
+
+	Attainment float64 `json:"attainment"` // share of Total that met the threshold
+	Compliant  bool    `json:"compliant"`
+	// Share of the allowed breaches still left; negative once the objective is missed
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+
+	P50Turnaround float64 `json:"p50_turnaround_seconds"`
+	P95Turnaround float64 `json:"p95_turnaround_seconds"`
+
+	Breaches []Breach `json:"breaches"`
+}
+
+// Measure evaluates the objectives against the analyses uploaded within
+// window before now
+func Measure(db *gorm.DB, objectives []config.SLOObjective, window time.Duration, now time.Time) ([]Report, error) {
+	start := now.Add(-window)
+	reports := make([]Report, 0, len(objectives))
+	for _, objective := range objectives {
+		query := db.Select("id, name, org_id, status, created_at, completed_at").
+			Where("created_at >= ?", start)
+		if objective.ScanProfile != "*" {
+			query = query.Where("scan_profile = ?", objective.ScanProfile)
+		}
+		var projects []models.Project
+		if err := query.Order("created_at").Find(&projects).Error; err != nil {
+			return nil, fmt.Errorf("failed to load analyses for objective %s: %w", objective.Name, err)
+		}
+		reports = append(reports, evaluate(objective, projects, start, now))
+	}
+	return reports, nil
+}
+
+func evaluate(objective config.SLOObjective, projects []models.Project, start, now time.Time) Report {
+	report := Report{
+		Name:             objective.Name,
+		ScanProfile:      objective.ScanProfile,
+		Target:           objective.Target,
+		Threshold:        objective.Threshold.String(),
+		ThresholdSeconds: objective.Threshold.Seconds(),
+		WindowStart:      start,
+		Breaches:         []Breach{},
+	}
+
+	var turnarounds []float64
+	for _, project := range projects {
+		var turnaround time.Duration
+		breached := false
+		switch {
+		case project.Status == models.StatusCompleted && project.CompletedAt != nil:
+			turnaround = project.CompletedAt.Sub(project.CreatedAt)
+			turnarounds = append(turnarounds, turnaround.Seconds())
+			breached = turnaround > objective.Threshold
+		case project.Status == models.StatusFailed:
+			// A failed analysis never delivered its results
+			if project.CompletedAt != nil {
+				turnaround = project.CompletedAt.Sub(project.CreatedAt)
+			}
+			breached = true
+		default:
+			turnaround = now.Sub(project.CreatedAt)
+			if turnaround <= objective.Threshold {
+				report.Pending++
+				continue
+			}
+			breached = true
+		}
+
+		report.Total++
+		if !breached {
+			report.Met++
+			continue
+		}
+		report.Breached++
+		if len(report.Breaches) < maxBreaches {
+			report.Breaches = append(report.Breaches, Breach{
+				JobID:       project.ID,
+				Name:        project.Name,
+				OrgID:       project.OrgID,
+				Status:      project.Status,
+				CreatedAt:   project.CreatedAt,
+				CompletedAt: project.CompletedAt,
+				Turnaround:  turnaround.Seconds(),
+			})
+		}
+	}
+
+	report.Attainment = 1
+	if report.Total > 0 {
+		report.Attainment = float64(report.Met) / float64(report.Total)
+	}
+	report.Compliant = report.Attainment >= objective.Target
+	report.ErrorBudgetRemaining = 1
+	if allowed := (1 - objective.Target) * float64(report.Total); allowed > 0 {
+		report.ErrorBudgetRemaining = 1 - float64(report.Breached)/allowed
+	} else if report.Breached > 0 {
+		report.ErrorBudgetRemaining = -1
+	}
+
+	sort.Float64s(turnarounds)
+	report.P50Turnaround = percentile(turnarounds, 0.50)
+	report.P95Turnaround = percentile(turnarounds, 0.95)
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,131 @@
+// Package suppress applies the CVE suppression rules, the risks analysts
+// accepted, to the CVE findings of the projects they cover
+package suppress
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+
+	"gorm.io/gorm"
+)
+
+// Active returns the rules in force for a project: its own and the global
+// ones of its organization
+func Active(db *gorm.DB, orgID, projectID string) ([]models.CVESuppression, error) {
+	var rules []models.CVESuppression
+	if err := db.Where("org_id = ? AND (project_id = '' OR project_id = ?)", orgID, projectID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC()).
+		Order("id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to load CVE suppressions: %w", err)
+	}
+	return rules, nil
+}
+
+// Match returns the rule suppressing a CVE finding, nil when none does: a
+// rule names the finding's CVE, and its software unless it names none
+func Match(rules []models.CVESuppression, finding *models.CVEFinding) *models.CVESuppression {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.CVEID != finding.CVEID {
+			continue
+		}
+		if rule.Component != "" && !strings.EqualFold(rule.Component, finding.SoftwareName) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// Apply suppresses the CVE findings of a project the rules in force match,
+// lifts the suppression of the others and rates the project again when
+// any changed. Frozen projects stay as delivered.
+func Apply(db *gorm.DB, projectID string) error {
+	var project models.Project
+	if err := db.Select("id", "org_id", "frozen_at").First(&project, "id = ?", projectID).Error; err != nil {
+		return fmt.Errorf("failed to load project: %w", err)
+	}
+	if project.Frozen() {
+		return nil
+	}
+	rules, err := Active(db, project.OrgID, project.ID)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var findings []models.CVEFinding
+		if err := tx.Select("id", "cve_id", "software_name", "suppression_id").
+			Where("project_id = ?", project.ID).Find(&findings).Error; err != nil {
+			return fmt.Errorf("failed to load CVE findings: %w", err)
+		}
+
+		changed := false
+		for _, finding := range findings {
+			var id *uint
+			if rule := Match(rules, &finding); rule != nil {
+				id = &rule.ID
+			}
+			if ruleID(id) == ruleID(finding.SuppressionID) {
+				continue
+			}
+			if err := tx.Model(&models.CVEFinding{}).Where("id = ?", finding.ID).
+				UpdateColumn("suppression_id", id).Error; err != nil {
+				return fmt.Errorf("failed to suppress CVE finding: %w", err)
+			}
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+		return risk.Recount(tx, project.ID)
+	})
+}
+
+// ruleID returns the ID of a suppression, 0 for none
+func ruleID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// Projects returns the projects a rule covers that have a finding of its
+// CVE, or one it suppressed, which Apply has to be run again on when the
+// rule changes
+func Projects(db *gorm.DB, rule *models.CVESuppression) ([]string, error) {
+	query := db.Model(&models.CVEFinding{}).Where("cve_id = ? OR suppression_id = ?", rule.CVEID, rule.ID)
+	if rule.ProjectID != "" {
+		query = query.Where("project_id = ?", rule.ProjectID)
+	} else {
+		query = query.Where("project_id IN (?)", db.Model(&models.Project{}).Select("id").Where("org_id = ?", rule.OrgID))
+	}
+	var ids []string
+	if err := query.Distinct("project_id").Pluck("project_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to load projects of CVE suppression: %w", err)
+	}
+	return ids, nil
+}
+
+// Expire lifts the suppressions of the rules that expired and returns the
+// number of projects rated again
+func Expire(db *gorm.DB) (int, error) {
+	var ids []string
+	if err := db.Model(&models.CVEFinding{}).
+		Where("suppression_id IN (?)", db.Model(&models.CVESuppression{}).Select("id").
+			Where("expires_at <= ?", time.Now().UTC())).
+		Where("project_id IN (?)", db.Model(&models.Project{}).Select("id").Where("frozen_at IS NULL")).
+		Distinct("project_id").Pluck("project_id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to load projects of expired CVE suppressions: %w", err)
+	}
+	for _, id := range ids {
+		if err := Apply(db, id); err != nil {
+			return 0, fmt.Errorf("project %s: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
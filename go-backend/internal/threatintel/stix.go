@@ -0,0 +1,82 @@
+package threatintel
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Observable types indicators are stored with
+const (
+	TypeSHA256 = "sha256"
+	TypeSHA1   = "sha1"
+	TypeMD5    = "md5"
+	TypeIPv4   = "ipv4"
+	TypeIPv6   = "ipv6"
+	TypeDomain = "domain"
+	TypeURL    = "url"
+)
+
+// Observable is a value a STIX pattern compares an object with
+type Observable struct {
+	Type  string
+	Value string
+}
+
+// comparisonRegex matches the equality comparisons of a STIX pattern, e.g.
+// file:hashes.'SHA-256' = '...' or ipv4-addr:value = '198.51.100.1'
+var comparisonRegex = regexp.MustCompile(`([a-z0-9-]+):([^\s=!<>\[\]]+)\s*=\s*'((?:[^'\\]|\\.)*)'`)
+
+var hexRegex = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// ParsePattern returns the observables of a STIX 2.1 pattern. Every
+// equality comparison of a supported object counts on its own, so
+// [a AND b] yields both a and b; other comparison operators are ignored.
+func ParsePattern(pattern string) []Observable {
+	var observables []Observable
+	for _, match := range comparisonRegex.FindAllStringSubmatch(pattern, -1) {
+		object, path := strings.ToLower(match[1]), strings.ToLower(match[2])
+		value := strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(match[3])
+		if observable, ok := observable(object, path, value); ok {
+			observables = append(observables, observable)
+		}
+	}
+	return observables
+}
+
+func observable(object, path, value string) (Observable, bool) {
+	value = strings.TrimSpace(value)
+	switch object {
+	case "file":
+		// hashes.'SHA-256', hashes.SHA256, hashes.MD5, ...
+		algorithm := strings.NewReplacer("'", "", "-", "", "_", "").Replace(strings.TrimPrefix(path, "hashes."))
+		value = strings.ToLower(value)
+		lengths := map[string]int{TypeSHA256: 64, TypeSHA1: 40, TypeMD5: 32}
+		if length, ok := lengths[algorithm]; ok && strings.HasPrefix(path, "hashes.") && len(value) == length && hexRegex.MatchString(value) {
+			return Observable{Type: algorithm, Value: value}, true
+		}
+	case "ipv4-addr", "ipv6-addr":
+		if path != "value" {
+			break
+		}
+		// Only single addresses; a host CIDR is one
+		value = strings.TrimSuffix(strings.TrimSuffix(value, "/32"), "/128")
+		ip := net.ParseIP(value)
+		if ip == nil {
+			break
+		}
+		if ip.To4() != nil {
+			return Observable{Type: TypeIPv4, Value: ip.String()}, true
+		}
+		return Observable{Type: TypeIPv6, Value: ip.String()}, true
+	case "domain-name":
+		if path == "value" && value != "" {
+			return Observable{Type: TypeDomain, Value: strings.TrimSuffix(strings.ToLower(value), ".")}, true
+		}
+	case "url":
+		if path == "value" && value != "" {
+			return Observable{Type: TypeURL, Value: value}, true
+		}
+	}
+	return Observable{}, false
+}
@@ -0,0 +1,165 @@
+package threatintel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// taxiiMediaType is the media type of TAXII 2.1 requests and responses
+	taxiiMediaType = "application/taxii+json;version=2.1"
+
+	// pageSize is how many objects a poll asks the server for at a time
+	pageSize = 500
+
+	// maxPages bounds the pages of one poll; the next poll continues where
+	// it stopped
+	maxPages = 100
+)
+
+// Indicator is a STIX 2.1 indicator with the observables of its pattern
+type Indicator struct {
+	ID          string
+	Name        string
+	Labels      []string
+	Confidence  int
+	ValidUntil  *time.Time
+	Revoked     bool
+	Observables []Observable
+}
+
+// Page is the indicators a poll got, and the date the server added the
+// last of them, from which the next poll continues
+type Page struct {
+	Indicators []Indicator
+	AddedAfter string
+}
+
+// Collection is a TAXII collection to poll
+type Collection struct {
+	APIRoot  string
+	ID       string
+	Username string
+	Password string
+}
+
+// Client polls TAXII 2.1 collections
+type Client struct {
+	client *http.Client
+}
+
+// NewClient returns a TAXII client
+func NewClient() *Client {
+	return &Client{client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// stixObject is the part of a STIX object the client reads
+type stixObject struct {
+	Type        string     `json:"type"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Labels      []string   `json:"labels"`
+	Confidence  int        `json:"confidence"`
+	Pattern     string     `json:"pattern"`
+	PatternType string     `json:"pattern_type"`
+	ValidUntil  *time.Time `json:"valid_until"`
+	Revoked     bool       `json:"revoked"`
+}
+
+// Poll gets the indicators a collection added after addedAfter (all when
+// empty), following the server's pages
+func (c *Client) Poll(ctx context.Context, collection Collection, addedAfter string) (Page, error) {
+	page := Page{AddedAfter: addedAfter}
+	next := ""
+	for i := 0; i < maxPages; i++ {
+		params := url.Values{}
+		params.Set("match[type]", "indicator")
+		params.Set("limit", fmt.Sprint(pageSize))
+		if next != "" {
+			params.Set("next", next)
+		} else if page.AddedAfter != "" {
+			params.Set("added_after", page.AddedAfter)
+		}
+
+		envelope, lastAdded, err := c.objects(ctx, collection, params)
+		if err != nil {
+			return page, err
+		}
+		for _, object := range envelope.Objects {
+			if object.Type != "indicator" || (object.PatternType != "" && object.PatternType != "stix") {
+				continue
+			}
+			page.Indicators = append(page.Indicators, Indicator{
+				ID:          object.ID,
+				Name:        object.Name,
+				Labels:      object.Labels,
+				Confidence:  object.Confidence,
+				ValidUntil:  object.ValidUntil,
+				Revoked:     object.Revoked,
+				Observables: ParsePattern(object.Pattern),
+			})
+		}
+		if lastAdded != "" {
+			page.AddedAfter = lastAdded
+		}
+		if !envelope.More || (envelope.Next == "" && lastAdded == "") {
+			break
+		}
+		next = envelope.Next
+	}
+	return page, nil
+}
+
+type envelope struct {
+	More    bool         `json:"more"`
+	Next    string       `json:"next"`
+	Objects []stixObject `json:"objects"`
+}
+
+// objects gets a page of a collection's objects, and the date the server
+// added the last of them
+func (c *Client) objects(ctx context.Context, collection Collection, params url.Values) (envelope, string, error) {
+	endpoint := strings.TrimSuffix(collection.APIRoot, "/") + "/collections/" + url.PathEscape(collection.ID) + "/objects/?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return envelope{}, "", err
+	}
+	req.Header.Set("Accept", taxiiMediaType)
+	if collection.Username != "" || collection.Password != "" {
+		req.SetBasicAuth(collection.Username, collection.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return envelope{}, "", fmt.Errorf("TAXII request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// TAXII servers answer 404 for a collection without new objects too,
+		// but then with an error message
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if strings.Contains(strings.ToLower(string(body)), "no objects") {
+			return envelope{}, "", nil
+		}
+		return envelope{}, "", fmt.Errorf("TAXII collection %s not found", collection.ID)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return envelope{}, "", fmt.Errorf("TAXII server refused the credentials (status %d)", resp.StatusCode)
+	default:
+		return envelope{}, "", fmt.Errorf("TAXII server returned status %d", resp.StatusCode)
+	}
+
+	var body envelope
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return envelope{}, "", fmt.Errorf("failed to decode TAXII envelope: %w", err)
+	}
+	return body, resp.Header.Get("X-TAXII-Date-Added-Last"), nil
+}
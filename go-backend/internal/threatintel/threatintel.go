@@ -0,0 +1,244 @@
+// Package threatintel ingests the STIX indicators of TAXII threat feeds
+// (file hashes, IP addresses, domains and URLs) and matches them against
+// the hashes of the extracted files and the addresses hardcoded in the
+// firmware, raising a high-severity finding for every match.
+package threatintel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// FindingThreatIntel is the type of the findings of matched indicators
+const FindingThreatIntel models.FindingType = "threat_intel"
+
+// lookupChunk bounds the values of one lookup query
+const lookupChunk = 500
+
+// Ingest stores the indicators of a feed's page, replacing the earlier
+// versions of the same indicators. Revoked and expired indicators are
+// removed. It returns how many observables were stored.
+func Ingest(tx *gorm.DB, feedID uint, indicators []Indicator) (int, error) {
+	now := time.Now().UTC()
+	stored := 0
+	for _, indicator := range indicators {
+		if err := tx.Where("feed_id = ? AND stix_id = ?", feedID, indicator.ID).
+			Delete(&models.ThreatIndicator{}).Error; err != nil {
+			return stored, fmt.Errorf("failed to remove indicator %s: %w", indicator.ID, err)
+		}
+		if indicator.Revoked || (indicator.ValidUntil != nil && indicator.ValidUntil.Before(now)) {
+			continue
+		}
+		seen := make(map[Observable]bool)
+		for _, observable := range indicator.Observables {
+			if seen[observable] {
+				continue
+			}
+			seen[observable] = true
+			record := models.ThreatIndicator{
+				FeedID:     feedID,
+				STIXID:     indicator.ID,
+				Type:       observable.Type,
+				Value:      observable.Value,
+				Name:       indicator.Name,
+				Labels:     strings.Join(indicator.Labels, ","),
+				Confidence: indicator.Confidence,
+				ValidUntil: indicator.ValidUntil,
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return stored, fmt.Errorf("failed to save indicator %s: %w", indicator.ID, err)
+			}
+			stored++
+		}
+	}
+	return stored, nil
+}
+
+// Location is where the analyzed firmware has a value
+type Location struct {
+	Type     string // the observable type the value is
+	FilePath string
+}
+
+var (
+	ipv4Regex   = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)
+	urlRegex    = regexp.MustCompile(`(?i)\b(?:https?|ftp)://[^\s'"<>()\[\]{}]+`)
+	domainRegex = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{1,62}\b`)
+)
+
+// Candidates returns the values of an analysis that indicators can match,
+// with where they are: the SHA-256 of every extracted file, and the IP
+// addresses, URLs and domains the findings quote
+func Candidates(files []models.FirmwareFile, findings []models.Finding) map[string][]Location {
+	candidates := make(map[string][]Location)
+	add := func(value string, location Location) {
+		for _, known := range candidates[value] {
+			if known == location {
+				return
+			}
+		}
+		candidates[value] = append(candidates[value], location)
+	}
+
+	for _, file := range files {
+		if file.SHA256 != "" {
+			add(strings.ToLower(file.SHA256), Location{Type: TypeSHA256, FilePath: file.Path})
+		}
+	}
+	for _, finding := range findings {
+		text := finding.Title + "\n" + finding.Description + "\n" + finding.Content
+		for _, match := range urlRegex.FindAllString(text, -1) {
+			match = strings.TrimRight(match, ".,;:")
+			add(match, Location{Type: TypeURL, FilePath: finding.FilePath})
+			if parsed, err := url.Parse(match); err == nil && parsed.Hostname() != "" {
+				host := strings.ToLower(parsed.Hostname())
+				if net.ParseIP(host) == nil {
+					add(host, Location{Type: TypeDomain, FilePath: finding.FilePath})
+				}
+			}
+		}
+		for _, match := range ipv4Regex.FindAllString(text, -1) {
+			if ip := net.ParseIP(match); ip != nil && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsUnspecified() {
+				add(ip.String(), Location{Type: TypeIPv4, FilePath: finding.FilePath})
+			}
+		}
+		for _, match := range domainRegex.FindAllString(text, -1) {
+			add(strings.ToLower(match), Location{Type: TypeDomain, FilePath: finding.FilePath})
+		}
+	}
+	return candidates
+}
+
+// Match is an indicator of an enabled feed a candidate value matched
+type Match struct {
+	models.ThreatIndicator
+	Feed string
+}
+
+// Lookup returns the indicators of the enabled feeds matching candidate
+// values of the same type, leaving out expired ones
+func Lookup(db *gorm.DB, candidates map[string][]Location) ([]Match, error) {
+	values := make([]string, 0, len(candidates))
+	for value := range candidates {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	var matches []Match
+	now := time.Now().UTC()
+	for start := 0; start < len(values); start += lookupChunk {
+		end := min(start+lookupChunk, len(values))
+		var chunk []Match
+		if err := db.Table("threat_indicators").
+			Select("threat_indicators.*, threat_feeds.name AS feed").
+			Joins("JOIN threat_feeds ON threat_feeds.id = threat_indicators.feed_id").
+			Where("threat_feeds.enabled = ? AND threat_indicators.value IN ?", true, values[start:end]).
+			Where("threat_indicators.valid_until IS NULL OR threat_indicators.valid_until > ?", now).
+			Order("threat_indicators.id").Scan(&chunk).Error; err != nil {
+			return nil, fmt.Errorf("failed to look indicators up: %w", err)
+		}
+		for _, match := range chunk {
+			for _, location := range candidates[match.Value] {
+				if location.Type == match.Type {
+					matches = append(matches, match)
+					break
+				}
+			}
+		}
+	}
+	return matches, nil
+}
+
+// Findings returns a high-severity finding for every place of the firmware
+// an indicator matched. When several feeds list a value, the finding names
+// the first.
+func Findings(matches []Match, candidates map[string][]Location) []models.Finding {
+	var findings []models.Finding
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		for _, location := range candidates[match.Value] {
+			if location.Type != match.Type {
+				continue
+			}
+			f := finding(match, location)
+			if !seen[f.Fingerprint] {
+				seen[f.Fingerprint] = true
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+func finding(match Match, location Location) models.Finding {
+	var title, description string
+	confidence := models.ConfidenceMedium
+	switch match.Type {
+	case TypeSHA256, TypeSHA1, TypeMD5:
+		title = "Known malicious file " + location.FilePath
+		description = fmt.Sprintf("The %s hash of %s is an indicator of threat feed %s", hashLabel[match.Type], location.FilePath, match.Feed)
+		// The same bytes, not only the same address
+		confidence = models.ConfidenceHigh
+	default:
+		title = "Hardcoded known malicious " + typeLabel(match.Type) + " " + match.Value
+		where := "The firmware"
+		if location.FilePath != "" {
+			where = location.FilePath
+		}
+		description = fmt.Sprintf("%s refers to %s, an indicator of threat feed %s", where, match.Value, match.Feed)
+		if match.Confidence >= 70 {
+			confidence = models.ConfidenceHigh
+		}
+	}
+	if match.Name != "" {
+		description += " (" + match.Name + ")"
+	}
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"source":         "threat_intel",
+		"feed":           match.Feed,
+		"feed_id":        match.FeedID,
+		"stix_id":        match.STIXID,
+		"indicator_type": match.Type,
+		"indicator":      match.Value,
+		"labels":         match.Labels,
+		"confidence":     match.Confidence,
+	})
+	result := models.Finding{
+		Type:            FindingThreatIntel,
+		Title:           title,
+		Description:     description,
+		Severity:        models.RiskHigh,
+		FilePath:        location.FilePath,
+		Content:         match.Value,
+		Confidence:      confidence,
+		OccurrenceCount: 1,
+		FindingMetadata: string(metadata),
+	}
+	result.Fingerprint = result.ComputeFingerprint()
+	return result
+}
+
+var hashLabel = map[string]string{TypeSHA256: "SHA-256", TypeSHA1: "SHA-1", TypeMD5: "MD5"}
+
+func typeLabel(observableType string) string {
+	switch observableType {
+	case TypeIPv4, TypeIPv6:
+		return "IP address"
+	case TypeDomain:
+		return "domain"
+	case TypeURL:
+		return "URL"
+	}
+	return observableType
+}
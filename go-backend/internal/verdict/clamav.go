@@ -0,0 +1,66 @@
+package verdict
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"odin-backend/internal/models"
+)
+
+// ClamAV scans files with the clamscan (or clamdscan) command line tool
+type ClamAV struct {
+	binary string
+}
+
+// NewClamAV creates a ClamAV engine using the given scanner binary
+func NewClamAV(binary string) *ClamAV {
+	if binary == "" {
+		binary = "clamscan"
+	}
+	return &ClamAV{binary: binary}
+}
+
+// Name returns the engine name
+func (e *ClamAV) Name() string {
+	return "clamav"
+}
+
+// Scan runs the scanner on the file. clamscan exits 0 when clean, 1 when
+// a signature matched and 2 on errors.
+func (e *ClamAV) Scan(ctx context.Context, filePath, sha256 string) (EngineResult, error) {
+	cmd := exec.CommandContext(ctx, e.binary, "--no-summary", "--infected", filePath)
+	output, err := cmd.CombinedOutput()
+	outputStr := strings.TrimSpace(string(output))
+
+	if err == nil {
+		return EngineResult{Disposition: models.DispositionClean}, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return EngineResult{
+			Disposition: models.DispositionMalicious,
+			Signature:   parseClamSignature(outputStr),
+			Detail:      outputStr,
+		}, nil
+	}
+
+	return EngineResult{}, fmt.Errorf("clamscan failed: %v: %s", err, outputStr)
+}
+
+// parseClamSignature extracts the signature name from "path: Signature FOUND"
+func parseClamSignature(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasSuffix(line, " FOUND") {
+			continue
+		}
+		line = strings.TrimSuffix(line, " FOUND")
+		if idx := strings.LastIndex(line, ": "); idx >= 0 {
+			return line[idx+2:]
+		}
+	}
+	return ""
+}
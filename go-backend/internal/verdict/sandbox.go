@@ -0,0 +1,84 @@
+package verdict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+)
+
+// Sandbox queries an internal sandbox service for a verdict on the file hash.
+// The service is expected to answer GET <base>/api/v1/verdicts/<sha256> with
+// {"verdict": "clean|suspicious|malicious|unknown", "signature": "...", "detail": "..."}.
+type Sandbox struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewSandbox creates a sandbox API engine
+func NewSandbox(baseURL, apiKey string) *Sandbox {
+	return &Sandbox{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Name returns the engine name
+func (e *Sandbox) Name() string {
+	return "sandbox"
+}
+
+// Scan fetches the sandbox verdict for the file hash
+func (e *Sandbox) Scan(ctx context.Context, filePath, sha256 string) (EngineResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.baseURL+"/api/v1/verdicts/"+sha256, nil)
+	if err != nil {
+		return EngineResult{}, err
+	}
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return EngineResult{}, fmt.Errorf("sandbox request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return EngineResult{
+			Disposition: models.DispositionUnknown,
+			Detail:      "no sandbox verdict for this hash",
+		}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return EngineResult{}, fmt.Errorf("sandbox returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Verdict   string `json:"verdict"`
+		Signature string `json:"signature"`
+		Detail    string `json:"detail"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return EngineResult{}, fmt.Errorf("failed to decode sandbox response: %w", err)
+	}
+
+	disposition := models.Disposition(strings.ToLower(body.Verdict))
+	switch disposition {
+	case models.DispositionClean, models.DispositionSuspicious, models.DispositionMalicious:
+	default:
+		disposition = models.DispositionUnknown
+	}
+
+	return EngineResult{
+		Disposition: disposition,
+		Signature:   body.Signature,
+		Detail:      body.Detail,
+	}, nil
+}
@@ -0,0 +1,184 @@
+package verdict
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+)
+
+// Engine is a single antivirus or threat-intel source producing a verdict for a file
+type Engine interface {
+	Name() string
+	Scan(ctx context.Context, filePath, sha256 string) (EngineResult, error)
+}
+
+// EngineResult is the verdict of one engine
+type EngineResult struct {
+	Engine      string             `json:"engine"`
+	Disposition models.Disposition `json:"disposition"`
+	Signature   string             `json:"signature,omitempty"`
+	Detail      string             `json:"detail,omitempty"`
+}
+
+// Result is the aggregate of all engine verdicts for a file
+type Result struct {
+	Disposition models.Disposition `json:"disposition"`
+	Engines     []EngineResult     `json:"engines"`
+}
+
+// Action is what a policy decides to do with an analysis after the verdict
+type Action string
+
+const (
+	ActionAllow  Action = "allow"
+	ActionReview Action = "review"
+	ActionBlock  Action = "block"
+)
+
+// Policy maps an aggregate verdict to an action
+type Policy func(result Result) Action
+
+// Aggregator runs every configured engine and combines their verdicts
+type Aggregator struct {
+	engines []Engine
+	policy  Policy
+}
+
+// New builds an aggregator from the engines and policy enabled in config
+func New(cfg *config.Config) *Aggregator {
+	a := &Aggregator{policy: ConfiguredPolicy(cfg.VerdictPolicy)}
+
+	for _, name := range cfg.VerdictEngines {
+		switch strings.TrimSpace(name) {
+		case "clamav":
+			a.engines = append(a.engines, NewClamAV(cfg.ClamAVPath))
+		case "virustotal":
+			if cfg.OfflineMode {
+				log.Printf("VirusTotal verdict engine enabled but unreachable in offline mode, skipping")
+				continue
+			}
+			if cfg.VirusTotalAPIKey == "" {
+				log.Printf("VirusTotal verdict engine enabled but VIRUSTOTAL_API_KEY is empty, skipping")
+				continue
+			}
+			a.engines = append(a.engines, NewVirusTotal(cfg.VirusTotalAPIKey))
+		case "sandbox":
+			if cfg.SandboxAPIURL == "" {
+				log.Printf("Sandbox verdict engine enabled but SANDBOX_API_URL is empty, skipping")
+				continue
+			}
+			a.engines = append(a.engines, NewSandbox(cfg.SandboxAPIURL, cfg.SandboxAPIKey))
+		case "":
+		default:
+			log.Printf("Unknown verdict engine %q, skipping", name)
+		}
+	}
+
+	return a
+}
+
+// Register adds an engine to the aggregator
+func (a *Aggregator) Register(engine Engine) {
+	a.engines = append(a.engines, engine)
+}
+
+// SetPolicy replaces the policy hook deciding what happens after a verdict
+func (a *Aggregator) SetPolicy(policy Policy) {
+	a.policy = policy
+}
+
+// Enabled reports whether any engine is configured
+func (a *Aggregator) Enabled() bool {
+	return len(a.engines) > 0
+}
+
+// Check scans a file with every engine. Engine errors are recorded as
+// unknown verdicts rather than failing the whole check.
+func (a *Aggregator) Check(ctx context.Context, filePath, sha256 string) Result {
+	result := Result{Disposition: models.DispositionUnknown}
+
+	for _, engine := range a.engines {
+		engineResult, err := engine.Scan(ctx, filePath, sha256)
+		if err != nil {
+			log.Printf("Verdict engine %s failed: %v", engine.Name(), err)
+			engineResult = EngineResult{
+				Disposition: models.DispositionUnknown,
+				Detail:      err.Error(),
+			}
+		}
+		engineResult.Engine = engine.Name()
+		result.Engines = append(result.Engines, engineResult)
+
+		if rank(engineResult.Disposition) > rank(result.Disposition) {
+			result.Disposition = engineResult.Disposition
+		}
+	}
+
+	return result
+}
+
+// Decide applies the policy hook to a result
+func (a *Aggregator) Decide(result Result) Action {
+	if a.policy == nil {
+		return ActionAllow
+	}
+	return a.policy(result)
+}
+
+// ConfiguredPolicy returns the built-in policy for a VERDICT_POLICY value:
+// "block" stops analysis of malicious files, "review" flags them for manual
+// review, "none" records verdicts only. Suspicious files are flagged for
+// review under both block and review.
+func ConfiguredPolicy(mode string) Policy {
+	return func(result Result) Action {
+		switch result.Disposition {
+		case models.DispositionMalicious:
+			switch mode {
+			case "block":
+				return ActionBlock
+			case "none":
+				return ActionAllow
+			default:
+				return ActionReview
+			}
+		case models.DispositionSuspicious:
+			if mode == "none" {
+				return ActionAllow
+			}
+			return ActionReview
+		}
+		return ActionAllow
+	}
+}
+
+// Summary describes which engines flagged the file
+func (r Result) Summary() string {
+	var flagged []string
+	for _, engine := range r.Engines {
+		if engine.Disposition == models.DispositionMalicious || engine.Disposition == models.DispositionSuspicious {
+			flagged = append(flagged, fmt.Sprintf("%s (%s)", engine.Engine, engine.Disposition))
+		}
+	}
+	if len(flagged) == 0 {
+		return fmt.Sprintf("aggregate verdict %s", r.Disposition)
+	}
+	return fmt.Sprintf("flagged by %s", strings.Join(flagged, ", "))
+}
+
+// rank orders dispositions so the most severe verdict wins
+func rank(d models.Disposition) int {
+	switch d {
+	case models.DispositionMalicious:
+		return 3
+	case models.DispositionSuspicious:
+		return 2
+	case models.DispositionClean:
+		return 1
+	default:
+		return 0
+	}
+}
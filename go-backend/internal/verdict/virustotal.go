@@ -0,0 +1,91 @@
+package verdict
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"odin-backend/internal/models"
+)
+
+const virusTotalFilesURL = "https://www.virustotal.com/api/v3/files/"
+
+// VirusTotal looks up the file hash in VirusTotal. The file itself is never uploaded.
+type VirusTotal struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewVirusTotal creates a VirusTotal hash lookup engine
+func NewVirusTotal(apiKey string) *VirusTotal {
+	return &VirusTotal{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the engine name
+func (e *VirusTotal) Name() string {
+	return "virustotal"
+}
+
+// Scan looks up the SHA-256 and maps the last analysis stats to a disposition
+func (e *VirusTotal) Scan(ctx context.Context, filePath, sha256 string) (EngineResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, virusTotalFilesURL+sha256, nil)
+	if err != nil {
+		return EngineResult{}, err
+	}
+	req.Header.Set("x-apikey", e.apiKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return EngineResult{}, fmt.Errorf("virustotal request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return EngineResult{
+			Disposition: models.DispositionUnknown,
+			Detail:      "hash not known to VirusTotal",
+		}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return EngineResult{}, fmt.Errorf("virustotal returned status %d", resp.StatusCode)
+	}
+
+	var report struct {
+		Data struct {
+			Attributes struct {
+				LastAnalysisStats struct {
+					Malicious  int `json:"malicious"`
+					Suspicious int `json:"suspicious"`
+					Undetected int `json:"undetected"`
+					Harmless   int `json:"harmless"`
+				} `json:"last_analysis_stats"`
+				PopularThreatClassification struct {
+					SuggestedThreatLabel string `json:"suggested_threat_label"`
+				} `json:"popular_threat_classification"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return EngineResult{}, fmt.Errorf("failed to decode virustotal response: %w", err)
+	}
+
+	stats := report.Data.Attributes.LastAnalysisStats
+	result := EngineResult{
+		Disposition: models.DispositionClean,
+		Signature:   report.Data.Attributes.PopularThreatClassification.SuggestedThreatLabel,
+		Detail: fmt.Sprintf("%d malicious, %d suspicious, %d undetected, %d harmless",
+			stats.Malicious, stats.Suspicious, stats.Undetected, stats.Harmless),
+	}
+	if stats.Malicious > 0 {
+		result.Disposition = models.DispositionMalicious
+	} else if stats.Suspicious > 0 {
+		result.Disposition = models.DispositionSuspicious
+	}
+
+	return result, nil
+}
@@ -0,0 +1,5 @@
+package version
+
+// Version is the Odin backend version, overridable at build time with
+// -ldflags "-X odin-backend/internal/version.Version=..."
+var Version = "1.0.0"
@@ -0,0 +1,409 @@
+// Package vulndb keeps the local vulnerability data up to date: NVD's
+// feeds, CISA KEV, FIRST's EPSS scores and the exploit-db index are
+// downloaded into the mirror, on schedule or when an admin asks, EMBA's CVE
+// database is updated by a configured command, and every sync is recorded
+package vulndb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/mirror"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Triggers of a sync
+const (
+	TriggerSchedule = "schedule"
+	TriggerAdmin    = "admin"
+)
+
+// TargetEMBA selects the update of EMBA's CVE database, next to the
+// mirror's datasets
+const TargetEMBA = "emba"
+
+// syncTimeout bounds the downloads and imports of a sync; a sync started
+// longer ago that never completed is taken for dead
+const syncTimeout = 2 * time.Hour
+
+// outputTail is how much of the end of the EMBA update's output is kept
+const outputTail = 4096
+
+// ErrAlreadyRunning is returned when a sync is started while another one is in progress
+var ErrAlreadyRunning = errors.New("a vulnerability database sync is already running")
+
+var running sync.Mutex
+
+// DatasetResult is what a sync imported of a dataset
+type DatasetResult struct {
+	Dataset string `json:"dataset"`
+	Source  string `json:"source"`
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Sources returns the URLs the datasets are downloaded from by dataset,
+// without the datasets whose URL is empty
+func Sources(cfg *config.Config) map[string]string {
+	sources := make(map[string]string)
+	for dataset, source := range map[string]string{
+		mirror.DatasetNVD:       cfg.VulnDBNVDURL,
+		mirror.DatasetKEV:       cfg.VulnDBKEVURL,
+		mirror.DatasetEPSS:      cfg.VulnDBEPSSURL,
+		mirror.DatasetExploitDB: cfg.VulnDBExploitDBURL,
+	} {
+		if source != "" {
+			sources[dataset] = source
+		}
+	}
+	return sources
+}
+
+// ParseTargets validates what a sync is asked to update: datasets of the
+// mirror and emba. None means everything configured.
+func ParseTargets(targets []string) ([]string, error) {
+	var parsed []string
+	for _, target := range targets {
+		target = strings.ToLower(strings.TrimSpace(target))
+		if target == "" {
+			continue
+		}
+		known := target == TargetEMBA
+		for _, dataset := range mirror.Datasets {
+			known = known || target == dataset
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown dataset %q: use %s or %s", target, strings.Join(mirror.Datasets, ", "), TargetEMBA)
+		}
+		parsed = append(parsed, target)
+	}
+	return parsed, nil
+}
+
+// Start records a sync an admin asked for and runs it in the background.
+// It returns ErrAlreadyRunning while another sync is in progress.
+func Start(db *gorm.DB, cfg *config.Config, actor string, targets []string) (*models.VulnDBSync, error) {
+	if !running.TryLock() {
+		return nil, ErrAlreadyRunning
+	}
+	if busy, err := inProgress(db); err != nil || busy {
+		running.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrAlreadyRunning
+	}
+
+	now := time.Now().UTC()
+	record := &models.VulnDBSync{
+		Slot:        "admin:" + now.Format(time.RFC3339Nano),
+		Trigger:     TriggerAdmin,
+		RequestedBy: actor,
+		StartedAt:   now,
+	}
+	if err := db.Create(record).Error; err != nil {
+		running.Unlock()
+		return nil, fmt.Errorf("failed to record sync: %w", err)
+	}
+
+	started := *record
+	go func() {
+		defer running.Unlock()
+		if err := run(context.Background(), db, cfg, record, targets); err != nil {
+			log.Printf("Vulnerability database sync %d failed: %v", record.ID, err)
+		}
+	}()
+	return &started, nil
+}
+
+// SyncDue runs the scheduled sync unless it ran in the current
+// VULNDB_SYNC_INTERVAL, on this worker or another, or another sync is in
+// progress
+func SyncDue(ctx context.Context, db *gorm.DB, cfg *config.Config) error {
+	if !running.TryLock() {
+		return nil
+	}
+	defer running.Unlock()
+	if busy, err := inProgress(db); err != nil || busy {
+		return err
+	}
+
+	// Claim the interval's sync, so another worker doesn't run it too
+	now := time.Now().UTC()
+	record := &models.VulnDBSync{
+		Slot:      now.Truncate(cfg.VulnDBSyncInterval).Format(time.RFC3339),
+		Trigger:   TriggerSchedule,
+		StartedAt: now,
+	}
+	claimed := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if claimed.Error != nil {
+		return fmt.Errorf("failed to claim vulnerability database sync: %w", claimed.Error)
+	}
+	if claimed.RowsAffected == 0 {
+		return nil
+	}
+	return run(ctx, db, cfg, record, nil)
+}
+
+// inProgress reports whether a sync is in progress, of this process or
+// another one
+func inProgress(db *gorm.DB) (bool, error) {
+	var count int64
+	if err := db.Model(&models.VulnDBSync{}).
+		Where("completed_at IS NULL AND started_at > ?", time.Now().UTC().Add(-syncTimeout)).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check running syncs: %w", err)
+	}
+	return count > 0, nil
+}
+
+// run downloads and imports the datasets, updates EMBA's CVE database and
+// records the outcome. A dataset failing doesn't stop the others; the sync
+// records every failure and fails when any did.
+func run(ctx context.Context, db *gorm.DB, cfg *config.Config, record *models.VulnDBSync, targets []string) error {
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	sources := Sources(cfg)
+	results := []DatasetResult{}
+	var failures []string
+	for _, dataset := range mirror.Datasets {
+		source, ok := sources[dataset]
+		if !ok || !selected(targets, dataset) {
+			continue
+		}
+		result := DatasetResult{Dataset: dataset, Source: source}
+		count, err := syncDataset(ctx, db, dataset, source)
+		result.Records = count
+		if err != nil {
+			result.Error = err.Error()
+			failures = append(failures, dataset+": "+err.Error())
+		}
+		record.Records += count
+		results = append(results, result)
+	}
+
+	if cfg.VulnDBEMBAUpdateCommand != "" && selected(targets, TargetEMBA) {
+		output, err := updateEMBA(ctx, cfg)
+		record.EMBAUpdate = output
+		if err != nil {
+			failures = append(failures, TargetEMBA+": "+err.Error())
+		}
+	}
+
+	data, _ := json.Marshal(results)
+	completed := time.Now().UTC()
+	record.Datasets = string(data)
+	record.Error = strings.Join(failures, "\n")
+	record.CompletedAt = &completed
+	if err := db.Save(record).Error; err != nil {
+		return fmt.Errorf("failed to record sync: %w", err)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of the sync's updates failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+	log.Printf("Vulnerability database synced: %d records imported", record.Records)
+	return nil
+}
+
+// selected reports whether a sync asked to update the targets updates one:
+// no targets is everything
+func selected(targets []string, target string) bool {
+	if len(targets) == 0 {
+		return true
+	}
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// syncDataset downloads a dataset and imports it into the mirror, which
+// records the URL as the import's source
+func syncDataset(ctx context.Context, db *gorm.DB, dataset, source string) (int, error) {
+	dir, err := os.MkdirTemp("", "odin-vulndb-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	file, err := download(ctx, source, dir)
+	if err != nil {
+		return 0, err
+	}
+	count, err := mirror.Import(db, dataset, file)
+	if err != nil {
+		return count, err
+	}
+	if err := db.Model(&models.MirrorImport{}).Where("dataset = ?", dataset).
+		Update("source", source).Error; err != nil {
+		return count, fmt.Errorf("failed to record import: %w", err)
+	}
+	return count, nil
+}
+
+// download saves a URL into a directory under the name of its path, which
+// tells the mirror whether it is gzipped
+func download(ctx context.Context, source, dir string) (string, error) {
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", source, err)
+	}
+	name := path.Base(parsed.Path)
+	if name == "." || name == "/" {
+		name = "dataset"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	file := filepath.Join(dir, name)
+	f, err := os.Create(file)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	return file, f.Close()
+}
+
+// updateEMBA runs VULNDB_EMBA_UPDATE_COMMAND in EMBA's directory and
+// returns the end of its output
+func updateEMBA(ctx context.Context, cfg *config.Config) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.VulnDBEMBAUpdateTimeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", cfg.VulnDBEMBAUpdateCommand)
+	cmd.Dir = cfg.EMBAPath
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+
+	tail := output.String()
+	if len(tail) > outputTail {
+		tail = tail[len(tail)-outputTail:]
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return tail, fmt.Errorf("EMBA update timed out after %s", cfg.VulnDBEMBAUpdateTimeout)
+	}
+	if err != nil {
+		return tail, fmt.Errorf("EMBA update failed: %w", err)
+	}
+	return tail, nil
+}
+
+// Status is the state of the local vulnerability data
+type Status struct {
+	Running        bool                `json:"running"`
+	LastSync       *models.VulnDBSync  `json:"last_sync"`
+	LastSuccess    *models.VulnDBSync  `json:"last_success"`
+	Datasets       []DatasetStatus     `json:"datasets"`
+	RecentSyncs    []models.VulnDBSync `json:"recent_syncs"`
+	RecentFailures int                 `json:"recent_failures"`
+}
+
+// DatasetStatus is a dataset of the mirror: where it is synced from, how
+// many records are stored and its last import
+type DatasetStatus struct {
+	Dataset    string               `json:"dataset"`
+	Source     string               `json:"source,omitempty"` // empty when it isn't synced
+	Records    int64                `json:"records"`
+	LastImport *models.MirrorImport `json:"last_import,omitempty"`
+}
+
+// datasetTables are the models the datasets are stored in
+var datasetTables = map[string]interface{}{
+	mirror.DatasetNVD:       &models.NVDRecord{},
+	mirror.DatasetKEV:       &models.KEVEntry{},
+	mirror.DatasetEPSS:      &models.EPSSRecord{},
+	mirror.DatasetExploitDB: &models.ExploitDBEntry{},
+}
+
+// GetStatus returns the state of the local vulnerability data and the
+// last recent syncs
+func GetStatus(db *gorm.DB, cfg *config.Config, recent int) (*Status, error) {
+	busy, err := inProgress(db)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Running: busy}
+
+	if err := db.Order("started_at DESC, id DESC").Limit(recent).Find(&status.RecentSyncs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load syncs: %w", err)
+	}
+	for i := range status.RecentSyncs {
+		if status.RecentSyncs[i].Error != "" {
+			status.RecentFailures++
+		}
+	}
+	var last, success models.VulnDBSync
+	if err := db.Where("completed_at IS NOT NULL").Order("started_at DESC, id DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, fmt.Errorf("failed to load last sync: %w", err)
+	}
+	if last.ID != 0 {
+		status.LastSync = &last
+	}
+	if err := db.Where("completed_at IS NOT NULL AND error = ''").Order("started_at DESC, id DESC").Limit(1).Find(&success).Error; err != nil {
+		return nil, fmt.Errorf("failed to load last successful sync: %w", err)
+	}
+	if success.ID != 0 {
+		status.LastSuccess = &success
+	}
+
+	imports, err := mirror.Status(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mirror imports: %w", err)
+	}
+	sources := Sources(cfg)
+	for _, dataset := range mirror.Datasets {
+		entry := DatasetStatus{Dataset: dataset, Source: sources[dataset]}
+		query := db.Model(datasetTables[dataset])
+		if dataset == mirror.DatasetNVD {
+			// NVD records also cache CVEs NVD doesn't know
+			query = query.Where("found = ?", true)
+		}
+		if err := query.Count(&entry.Records).Error; err != nil {
+			return nil, fmt.Errorf("failed to count %s records: %w", dataset, err)
+		}
+		for i := range imports {
+			if imports[i].Dataset == dataset {
+				entry.LastImport = &imports[i]
+			}
+		}
+		status.Datasets = append(status.Datasets, entry)
+	}
+	return status, nil
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ValidateAlertRule checks a rule's name and conditions. A rule needs a
+// condition: alerting on every new CVE is what the new_cve event is for.
+func ValidateAlertRule(rule *models.CVEAlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if rule.MinCVSS < 0 || rule.MinCVSS > 10 {
+		return fmt.Errorf("min_cvss must be between 0 and 10")
+	}
+	if rule.MinCVSS == 0 && !rule.KnownExploited && !rule.ExploitAvailable {
+		return fmt.Errorf("one of min_cvss, known_exploited or exploit_available is required")
+	}
+	return nil
+}
+
+// NotifyAlerts evaluates the enabled alert rules of the project's
+// organization against the CVE findings the CVE monitor added to it and
+// alerts each matching rule's subscription of the CVEs it matched, even a
+// subscription whose filters leave them out. Disabled subscriptions aren't
+// alerted.
+func (d *Dispatcher) NotifyAlerts(projectID string, cves []models.CVEFinding) {
+	var project models.Project
+	if err := d.db.First(&project, "id = ?", projectID).Error; err != nil {
+		log.Printf("Webhook: failed to load project %s: %v", projectID, err)
+		return
+	}
+
+	var rules []models.CVEAlertRule
+	if err := d.db.Where("org_id = ? AND enabled = ?", project.OrgID, true).
+		Where("project_id = '' OR project_id = ?", project.ID).Order("id").Find(&rules).Error; err != nil {
+		log.Printf("Webhook: failed to load alert rules for org %s: %v", project.OrgID, err)
+		return
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if fleets := splitList(rule.Fleets); len(fleets) > 0 && !containsFold(fleets, project.Fleet) {
+			continue
+		}
+
+		var matching []models.CVEFinding
+		for _, cve := range cves {
+			if alertMatches(rule, &cve) {
+				matching = append(matching, cve)
+			}
+		}
+		if len(matching) == 0 {
+			continue
+		}
+
+		var sub models.WebhookSubscription
+		if err := d.db.Where("org_id = ?", project.OrgID).First(&sub, rule.WebhookID).Error; err != nil {
+			log.Printf("Webhook: failed to load subscription %d of alert rule %d: %v", rule.WebhookID, rule.ID, err)
+			continue
+		}
+		if !sub.Enabled {
+			continue
+		}
+
+		payload := &Payload{
+			Event:     EventCVEAlert,
+			Timestamp: time.Now().UTC(),
+			Project: ProjectSummary{
+				ID:           project.ID,
+				Name:         project.Name,
+				OrgID:        project.OrgID,
+				Fleet:        project.Fleet,
+				DeviceModel:  project.DeviceModel,
+				Manufacturer: project.Manufacturer,
+				Status:       project.Status,
+				RiskLevel:    project.RiskLevel,
+			},
+			Summary: map[string]int{"cves": len(matching)},
+			Alert:   &AlertSummary{RuleID: rule.ID, Name: rule.Name},
+		}
+		for _, cve := range matching {
+			payload.Summary[string(cve.SeverityLevel)]++
+		}
+		if sub.PayloadTemplate != TemplateSummary {
+			payload.CVEs = matching
+		}
+
+		body, ok, err := encodePayload(&sub, &project, payload)
+		if err != nil {
+			log.Printf("Webhook: failed to encode payload for subscription %d: %v", sub.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		d.deliver(&sub, project.ID, payload.Event, body)
+
+		if err := d.db.Model(&models.CVEAlertRule{}).Where("id = ?", rule.ID).UpdateColumns(map[string]interface{}{
+			"alerts":          gorm.Expr("alerts + ?", len(matching)),
+			"last_alerted_at": payload.Timestamp,
+		}).Error; err != nil {
+			log.Printf("Webhook: failed to record alert of rule %d: %v", rule.ID, err)
+		}
+	}
+}
+
+// alertMatches reports whether a CVE finding meets any of the rule's conditions
+func alertMatches(rule *models.CVEAlertRule, cve *models.CVEFinding) bool {
+	switch {
+	case rule.MinCVSS > 0 && cve.SeverityScore >= rule.MinCVSS:
+		return true
+	case rule.KnownExploited && cve.KnownExploited:
+		return true
+	case rule.ExploitAvailable && cve.ExploitAvailable:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,471 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/ocsf"
+
+	"gorm.io/gorm"
+)
+
+// Payload templates
+const (
+	TemplateFull    = "full"
+	TemplateSummary = "summary"
+	TemplateOCSF    = "ocsf" // OCSF Vulnerability Finding events, one per finding/CVE
+)
+
+// Event names sent in the payload
+const (
+	EventAnalysisCompleted = "analysis.completed"
+	EventAnalysisFailed    = "analysis.failed"
+	EventExposureChanged   = "osint.exposure_changed"
+	EventCVEsDetected      = "monitor.cves_detected"
+	EventCVEAlert          = "alert.cve_matched"
+)
+
+const (
+	maxAttempts  = 3
+	retryBackoff = 2 * time.Second
+)
+
+// Dispatcher delivers analysis events to matching webhook subscriptions
+type Dispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+}
+
+// New creates a webhook dispatcher
+func New(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Payload is the JSON body posted to a subscriber
+type Payload struct {
+	Event     string              `json:"event"`
+	Timestamp time.Time           `json:"timestamp"`
+	Project   ProjectSummary      `json:"project"`
+	Summary   map[string]int      `json:"summary"`
+	Findings  []models.Finding    `json:"findings,omitempty"`
+	CVEs      []models.CVEFinding `json:"cves,omitempty"`
+	Exposure  *ExposureChange     `json:"exposure,omitempty"`
+	Alert     *AlertSummary       `json:"alert,omitempty"`
+}
+
+// AlertSummary is the alert rule a payload's CVEs matched
+type AlertSummary struct {
+	RuleID uint   `json:"rule_id"`
+	Name   string `json:"name"`
+}
+
+// ExposureChange is the exposure per source (hosts found, or results) of
+// the previous and the new OSINT collection of a project
+type ExposureChange struct {
+	PreviousCollectedAt *time.Time     `json:"previous_collected_at,omitempty"`
+	CollectedAt         time.Time      `json:"collected_at"`
+	Before              map[string]int `json:"before"`
+	After               map[string]int `json:"after"`
+}
+
+// ProjectSummary is the project section of a payload
+type ProjectSummary struct {
+	ID           string               `json:"id"`
+	Name         string               `json:"name"`
+	OrgID        string               `json:"org_id"`
+	Fleet        string               `json:"fleet"`
+	DeviceModel  string               `json:"device_model"`
+	Manufacturer string               `json:"manufacturer"`
+	Status       models.ProjectStatus `json:"status"`
+	RiskLevel    models.RiskLevel     `json:"risk_level"`
+}
+
+// ValidateSubscription checks a subscription's URL, filters and template
+func ValidateSubscription(sub *models.WebhookSubscription) error {
+	if sub.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !strings.HasPrefix(sub.URL, "http://") && !strings.HasPrefix(sub.URL, "https://") {
+		return fmt.Errorf("url must be an http(s) URL")
+	}
+	for _, event := range splitList(sub.EventTypes) {
+		switch event {
+		case models.WebhookEventAnalysis, models.WebhookEventFinding, models.WebhookEventCVE, models.WebhookEventExposure, models.WebhookEventNewCVE:
+		default:
+			return fmt.Errorf("unknown event type %q", event)
+		}
+	}
+	if sub.MinSeverity != "" && sub.MinSeverity.Rank() == 0 {
+		return fmt.Errorf("unknown min_severity %q", sub.MinSeverity)
+	}
+	switch sub.PayloadTemplate {
+	case "":
+		sub.PayloadTemplate = TemplateFull
+	case TemplateFull, TemplateSummary, TemplateOCSF:
+	default:
+		return fmt.Errorf("payload_template must be %q, %q or %q", TemplateFull, TemplateSummary, TemplateOCSF)
+	}
+	return nil
+}
+
+// Notify sends the outcome of an analysis to all enabled subscriptions of the
+// project's organization whose filters match. Suppressed CVEs and merged
+// findings are left out.
+func (d *Dispatcher) Notify(projectID string) {
+	var project models.Project
+	if err := d.db.Preload("Findings", "merged_into_id IS NULL").Preload("CVEFindings", "suppression_id IS NULL AND match_status <> ?", models.MatchRejected).Preload("CVEFindings.CVE").
+		First(&project, "id = ?", projectID).Error; err != nil {
+		log.Printf("Webhook: failed to load project %s: %v", projectID, err)
+		return
+	}
+
+	var subs []models.WebhookSubscription
+	if err := d.db.Where("org_id = ? AND enabled = ?", project.OrgID, true).Find(&subs).Error; err != nil {
+		log.Printf("Webhook: failed to load subscriptions for org %s: %v", project.OrgID, err)
+		return
+	}
+
+	for i := range subs {
+		payload, ok := buildPayload(&subs[i], &project)
+		if !ok {
+			continue
+		}
+		body, ok, err := encodePayload(&subs[i], &project, payload)
+		if err != nil {
+			log.Printf("Webhook: failed to encode payload for subscription %d: %v", subs[i].ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		d.deliver(&subs[i], project.ID, payload.Event, body)
+	}
+}
+
+// NotifyExposure tells the subscriptions of the project's organization that
+// want exposure changes what a scheduled OSINT refresh found, with the new
+// findings at or above their minimum severity
+func (d *Dispatcher) NotifyExposure(projectID string, change ExposureChange, findings []models.Finding) {
+	var project models.Project
+	if err := d.db.First(&project, "id = ?", projectID).Error; err != nil {
+		log.Printf("Webhook: failed to load project %s: %v", projectID, err)
+		return
+	}
+
+	var subs []models.WebhookSubscription
+	if err := d.db.Where("org_id = ? AND enabled = ?", project.OrgID, true).Find(&subs).Error; err != nil {
+		log.Printf("Webhook: failed to load subscriptions for org %s: %v", project.OrgID, err)
+		return
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+		if events := splitList(sub.EventTypes); len(events) > 0 && !contains(events, models.WebhookEventExposure) {
+			continue
+		}
+		if fleets := splitList(sub.Fleets); len(fleets) > 0 && !containsFold(fleets, project.Fleet) {
+			continue
+		}
+
+		var matching []models.Finding
+		for _, finding := range findings {
+			if finding.Severity.Rank() >= sub.MinSeverity.Rank() {
+				matching = append(matching, finding)
+			}
+		}
+		payload := &Payload{
+			Event:     EventExposureChanged,
+			Timestamp: time.Now().UTC(),
+			Project: ProjectSummary{
+				ID:           project.ID,
+				Name:         project.Name,
+				OrgID:        project.OrgID,
+				Fleet:        project.Fleet,
+				DeviceModel:  project.DeviceModel,
+				Manufacturer: project.Manufacturer,
+				Status:       project.Status,
+				RiskLevel:    project.RiskLevel,
+			},
+			Summary:  map[string]int{"findings": len(matching)},
+			Exposure: &change,
+		}
+		if sub.PayloadTemplate != TemplateSummary {
+			payload.Findings = matching
+		}
+
+		body, ok, err := encodePayload(sub, &project, payload)
+		if err != nil {
+			log.Printf("Webhook: failed to encode payload for subscription %d: %v", sub.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		d.deliver(sub, project.ID, payload.Event, body)
+	}
+}
+
+// NotifyNewCVEs tells the subscriptions of the project's organization that
+// want new CVEs which CVE findings the CVE monitor added to the completed
+// analysis, those at or above their minimum severity
+func (d *Dispatcher) NotifyNewCVEs(projectID string, cves []models.CVEFinding) {
+	var project models.Project
+	if err := d.db.First(&project, "id = ?", projectID).Error; err != nil {
+		log.Printf("Webhook: failed to load project %s: %v", projectID, err)
+		return
+	}
+
+	var subs []models.WebhookSubscription
+	if err := d.db.Where("org_id = ? AND enabled = ?", project.OrgID, true).Find(&subs).Error; err != nil {
+		log.Printf("Webhook: failed to load subscriptions for org %s: %v", project.OrgID, err)
+		return
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+		if events := splitList(sub.EventTypes); len(events) > 0 && !contains(events, models.WebhookEventNewCVE) {
+			continue
+		}
+		if fleets := splitList(sub.Fleets); len(fleets) > 0 && !containsFold(fleets, project.Fleet) {
+			continue
+		}
+
+		var matching []models.CVEFinding
+		for _, cve := range cves {
+			if cve.SeverityLevel.Rank() >= sub.MinSeverity.Rank() {
+				matching = append(matching, cve)
+			}
+		}
+		if len(matching) == 0 {
+			continue
+		}
+		payload := &Payload{
+			Event:     EventCVEsDetected,
+			Timestamp: time.Now().UTC(),
+			Project: ProjectSummary{
+				ID:           project.ID,
+				Name:         project.Name,
+				OrgID:        project.OrgID,
+				Fleet:        project.Fleet,
+				DeviceModel:  project.DeviceModel,
+				Manufacturer: project.Manufacturer,
+				Status:       project.Status,
+				RiskLevel:    project.RiskLevel,
+			},
+			Summary: map[string]int{"cves": len(matching)},
+		}
+		for _, cve := range matching {
+			payload.Summary[string(cve.SeverityLevel)]++
+		}
+		if sub.PayloadTemplate != TemplateSummary {
+			payload.CVEs = matching
+		}
+
+		body, ok, err := encodePayload(sub, &project, payload)
+		if err != nil {
+			log.Printf("Webhook: failed to encode payload for subscription %d: %v", sub.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		d.deliver(sub, project.ID, payload.Event, body)
+	}
+}
+
+// buildPayload applies the subscription's filters and template. It returns
+// false when nothing in the analysis is of interest to the subscriber.
+func buildPayload(sub *models.WebhookSubscription, project *models.Project) (*Payload, bool) {
+	if fleets := splitList(sub.Fleets); len(fleets) > 0 && !containsFold(fleets, project.Fleet) {
+		return nil, false
+	}
+
+	events := splitList(sub.EventTypes)
+	wants := func(event string) bool { return len(events) == 0 || contains(events, event) }
+
+	event := EventAnalysisCompleted
+	if project.Status == models.StatusFailed {
+		event = EventAnalysisFailed
+	}
+
+	payload := &Payload{
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		Project: ProjectSummary{
+			ID:           project.ID,
+			Name:         project.Name,
+			OrgID:        project.OrgID,
+			Fleet:        project.Fleet,
+			DeviceModel:  project.DeviceModel,
+			Manufacturer: project.Manufacturer,
+			Status:       project.Status,
+			RiskLevel:    project.RiskLevel,
+		},
+		Summary: map[string]int{},
+	}
+
+	if event == EventAnalysisFailed {
+		return payload, wants(models.WebhookEventAnalysis)
+	}
+
+	var findings []models.Finding
+	if wants(models.WebhookEventFinding) {
+		types := splitList(sub.FindingTypes)
+		for _, finding := range project.Findings {
+			if finding.Severity.Rank() < sub.MinSeverity.Rank() {
+				continue
+			}
+			if len(types) > 0 && !contains(types, string(finding.Type)) {
+				continue
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	var cves []models.CVEFinding
+	if wants(models.WebhookEventCVE) {
+		for _, cve := range project.CVEFindings {
+			if cve.SeverityLevel.Rank() < sub.MinSeverity.Rank() {
+				continue
+			}
+			cves = append(cves, cve)
+		}
+	}
+
+	// Subscribers that only want findings/CVEs aren't notified of clean results
+	if !wants(models.WebhookEventAnalysis) && len(findings) == 0 && len(cves) == 0 {
+		return nil, false
+	}
+
+	payload.Summary["findings"] = len(findings)
+	payload.Summary["cves"] = len(cves)
+	for _, finding := range findings {
+		payload.Summary[string(finding.Severity)]++
+	}
+	for _, cve := range cves {
+		payload.Summary[string(cve.SeverityLevel)]++
+	}
+
+	if sub.PayloadTemplate != TemplateSummary {
+		payload.Findings = findings
+		payload.CVEs = cves
+	}
+
+	return payload, true
+}
+
+// encodePayload renders the payload in the subscription's template. OCSF
+// subscribers only receive events when there are findings or CVEs to report.
+func encodePayload(sub *models.WebhookSubscription, project *models.Project, payload *Payload) ([]byte, bool, error) {
+	if sub.PayloadTemplate != TemplateOCSF {
+		body, err := json.Marshal(payload)
+		return body, err == nil, err
+	}
+
+	events := ocsf.Map(project, payload.Findings, payload.CVEs)
+	if len(events) == 0 {
+		return nil, false, nil
+	}
+	body, err := json.Marshal(events)
+	return body, err == nil, err
+}
+
+// deliver posts the payload, retrying transient failures, and records the outcome
+func (d *Dispatcher) deliver(sub *models.WebhookSubscription, projectID, event string, body []byte) {
+	var err error
+	delivery := models.WebhookDelivery{
+		SubscriptionID: sub.ID,
+		ProjectID:      projectID,
+		Event:          event,
+	}
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		delivery.StatusCode, err = d.post(sub, event, body)
+		if err == nil && delivery.StatusCode < 300 {
+			delivery.Success = true
+			delivery.Error = ""
+			break
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		} else {
+			delivery.Error = fmt.Sprintf("unexpected status %d", delivery.StatusCode)
+			// Client errors won't succeed on retry
+			if delivery.StatusCode < 500 && delivery.StatusCode != http.StatusTooManyRequests {
+				break
+			}
+		}
+		if attempt < maxAttempts {
+			time.Sleep(retryBackoff * time.Duration(attempt))
+		}
+	}
+
+	if !delivery.Success {
+		log.Printf("Webhook: delivery to subscription %d failed: %s", sub.ID, delivery.Error)
+	}
+	if err := d.db.Create(&delivery).Error; err != nil {
+		log.Printf("Webhook: failed to record delivery: %v", err)
+	}
+}
+
+// post sends a single signed request
+func (d *Dispatcher) post(sub *models.WebhookSubscription, event string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Odin-Event", event)
+	if sub.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(sub.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Odin-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(items []string, value string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
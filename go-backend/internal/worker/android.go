@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"odin-backend/internal/extract"
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+)
+
+// unpackAndroid unpacks an Android boot image, sparse image or OTA zip and
+// returns the extracted tree, which EMBA analyzes instead of the image its
+// extractor doesn't understand
+func (w *Worker) unpackAndroid(ctx context.Context, project *models.Project) (string, *extract.AndroidInfo, error) {
+	if err := w.updateProjectStatus(project, models.StatusExtracting, "Unpacking Android image..."); err != nil {
+		return "", nil, queue.Transient(fmt.Errorf("failed to update project status: %w", err))
+	}
+
+	dir := filepath.Join(w.config.WorkDir, fmt.Sprintf("job_%s", project.ID), "android")
+	if err := clearWorkDir(dir); err != nil {
+		return "", nil, err
+	}
+
+	info, err := w.extractor.Android(ctx, project.FilePath, dir)
+	if err != nil {
+		return "", nil, unpackFailure(ctx, err, "nothing could be unpacked from the Android image", func(err error) error {
+			return queue.Permanent(fmt.Errorf("failed to unpack Android image: %w", err))
+		})
+	}
+	return dir, info, nil
+}
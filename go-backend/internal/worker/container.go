@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"odin-backend/internal/extract"
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+)
+
+// unpackContainer applies the layers of a docker save tarball or OCI image
+// layout and returns the resulting root filesystem for EMBA to analyze
+func (w *Worker) unpackContainer(ctx context.Context, project *models.Project) (string, *extract.ContainerInfo, error) {
+	if err := w.updateProjectStatus(project, models.StatusExtracting, "Unpacking container image layers..."); err != nil {
+		return "", nil, queue.Transient(fmt.Errorf("failed to update project status: %w", err))
+	}
+
+	dir := filepath.Join(w.config.WorkDir, fmt.Sprintf("job_%s", project.ID), "rootfs")
+	if err := clearWorkDir(dir); err != nil {
+		return "", nil, err
+	}
+
+	info, err := w.extractor.ContainerImage(ctx, project.FilePath, dir)
+	if err != nil {
+		return "", nil, unpackFailure(ctx, err, "the container image's layers hold no files", func(err error) error {
+			return queue.Permanent(fmt.Errorf("failed to unpack container image: %w", err))
+		})
+	}
+	return dir, info, nil
+}
@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"odin-backend/internal/decrypt"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/fwformat"
+	"odin-backend/internal/models"
+)
+
+// decryptFirmware replaces an upload no format was recognized in, or a
+// vendor encrypted one, with its decrypted payload when a decryptor knows
+// its scheme. The project is then analyzed, and its format and platform
+// described, from the payload; the upload is kept as EncryptedFilePath.
+func (w *Worker) decryptFirmware(ctx context.Context, project *models.Project) error {
+	known := project.FirmwareType != fwformat.Unknown && !fwformat.Encrypted(project.FirmwareType)
+	if known || project.EncryptedFilePath != "" {
+		return nil
+	}
+
+	encrypted := false
+	if threshold := w.config.EncryptedEntropyThreshold; threshold > 0 {
+		entropy, err := fwformat.Entropy(project.FilePath)
+		if err != nil {
+			log.Printf("Failed to measure entropy of project %s: %v", project.ID, err)
+		}
+		encrypted = err == nil && entropy >= threshold
+	}
+
+	ext := filepath.Ext(project.FilePath)
+	decrypted := strings.TrimSuffix(project.FilePath, ext) + ".decrypted" + ext
+	name, err := w.decryptors.Decrypt(ctx, project.FilePath, decrypted, encrypted)
+	if errors.Is(err, decrypt.ErrNotDecrypted) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decrypt firmware: %w", err)
+	}
+
+	format, err := fwformat.DescribeFile(decrypted)
+	if err != nil {
+		log.Printf("Failed to identify format of decrypted firmware %s: %v", decrypted, err)
+	}
+	project.EncryptedFilePath, project.FilePath = project.FilePath, decrypted
+	project.Decryptor = name
+	project.FirmwareType = format.Format
+	project.FirmwareEndianness = format.Endianness
+	project.FirmwareArch = format.Architecture
+	project.FormatDetails = ""
+	if format.Details != nil {
+		encoded, _ := json.Marshal(format.Details)
+		project.FormatDetails = string(encoded)
+	}
+	platform := emba.HeaderPlatform(format)
+	project.Architecture, project.OSFamily = platform.Architecture, platform.OSFamily
+
+	log.Printf("Decrypted firmware of project %s with %s: %s", project.ID, name, format.Format)
+	return w.db.Save(project).Error
+}
@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"odin-backend/internal/defaultcreds"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultCredentials returns the shipped default credentials and the last
+// downloaded dataset
+func (w *Worker) defaultCredentials() []models.DefaultCredential {
+	credentials := append([]models.DefaultCredential{}, defaultcreds.Shipped()...)
+	var downloaded []models.DefaultCredential
+	if err := w.db.Where("source <> ?", defaultcreds.SourceShipped).Find(&downloaded).Error; err != nil {
+		log.Printf("Failed to load downloaded default credentials: %v", err)
+	}
+	return append(credentials, downloaded...)
+}
+
+// checkDefaultCredentials raises a critical finding for the default
+// credentials documented for the device's manufacturer and model, and one
+// for every account of the firmware whose credential a vendor documents as
+// its default
+func (w *Worker) checkDefaultCredentials(project *models.Project, result *emba.AnalysisResult) {
+	credentials := w.defaultCredentials()
+	accounts := result.Results.PasswordHashes
+
+	if documented := defaultcreds.ForDevice(credentials, project.Manufacturer, project.DeviceModel); len(documented) > 0 {
+		result.Results.Findings = append(result.Results.Findings, deviceCredentialsFinding(project, documented, accounts))
+	}
+
+	// The only plaintext credentials of the accounts before cracking are
+	// empty passwords
+	for _, account := range accounts {
+		if account.Algorithm != models.HashNone {
+			continue
+		}
+		vendors := defaultcreds.Documenting(credentials, account.Username, "")
+		if len(vendors) == 0 {
+			continue
+		}
+		sort.Strings(vendors)
+		pair := defaultcreds.Pair(account.Username, "")
+		finding := models.Finding{
+			Type:  models.FindingCredential,
+			Title: fmt.Sprintf("Account %s has the documented default credential %s", account.Username, pair),
+			Description: fmt.Sprintf("%s in %s has no password, the default credential %s documents. Scanners try it on every device",
+				account.Username, account.FilePath, strings.Join(vendors, ", ")),
+			Severity:        models.RiskCritical,
+			FilePath:        account.FilePath,
+			Content:         pair,
+			Confidence:      models.ConfidenceHigh,
+			OccurrenceCount: 1,
+			FindingMetadata: encodeCredentialMetadata(map[string]interface{}{
+				"source":   "default_credentials",
+				"username": account.Username,
+				"vendors":  vendors,
+			}),
+		}
+		finding.Fingerprint = finding.ComputeFingerprint()
+		result.Results.Findings = append(result.Results.Findings, finding)
+	}
+}
+
+// deviceCredentialsFinding reports the default credentials documented for a
+// device, naming the firmware's accounts they are for
+func deviceCredentialsFinding(project *models.Project, documented []models.DefaultCredential, accounts []models.PasswordHash) models.Finding {
+	device := strings.TrimSpace(project.Manufacturer + " " + project.DeviceModel)
+
+	var pairs, lines []string
+	usernames := make(map[string]bool)
+	for _, credential := range documented {
+		pair := defaultcreds.Pair(credential.Username, credential.Password)
+		pairs = append(pairs, pair)
+		if credential.Comment != "" {
+			pair += " (" + credential.Comment + ")"
+		}
+		lines = append(lines, pair)
+		usernames[credential.Username] = true
+	}
+
+	var matched []string
+	for _, account := range accounts {
+		if usernames[account.Username] {
+			matched = append(matched, fmt.Sprintf("%s (%s)", account.Username, account.FilePath))
+		}
+	}
+
+	description := fmt.Sprintf("%s documents default credentials for the %s. Devices whose owners never changed them accept them", documented[0].Vendor, device)
+	confidence := models.ConfidenceMedium
+	if len(matched) > 0 {
+		description += "; the firmware has the accounts " + strings.Join(matched, ", ")
+		confidence = models.ConfidenceHigh
+	}
+
+	finding := models.Finding{
+		Type:            models.FindingCredential,
+		Title:           "Documented default credentials for " + device,
+		Description:     description,
+		Severity:        models.RiskCritical,
+		Content:         strings.Join(lines, "\n"),
+		Confidence:      confidence,
+		OccurrenceCount: 1,
+		FindingMetadata: encodeCredentialMetadata(map[string]interface{}{
+			"source":      "default_credentials",
+			"credentials": pairs,
+			"accounts":    matched,
+		}),
+	}
+	finding.Fingerprint = finding.ComputeFingerprint()
+	return finding
+}
+
+func encodeCredentialMetadata(fields map[string]interface{}) string {
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
+
+// RunDefaultCredentialUpdates downloads the default credentials dataset
+// every DEFAULT_CREDENTIALS_UPDATE_INTERVAL until the process exits. It does
+// nothing unless DEFAULT_CREDENTIALS_URL is set.
+func (w *Worker) RunDefaultCredentialUpdates() {
+	if w.config.DefaultCredentialsURL == "" {
+		return
+	}
+
+	log.Printf("Updating default credentials from %s every %s", w.config.DefaultCredentialsURL, w.config.DefaultCredentialsUpdateInterval)
+	for {
+		if err := w.UpdateDefaultCredentials(context.Background()); err != nil {
+			log.Printf("Error updating default credentials: %v", err)
+		}
+		time.Sleep(w.config.DefaultCredentialsUpdateInterval)
+	}
+}
+
+// UpdateDefaultCredentials replaces the downloaded default credentials with
+// the dataset at DEFAULT_CREDENTIALS_URL. The shipped ones stay.
+func (w *Worker) UpdateDefaultCredentials(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	source := w.config.DefaultCredentialsURL
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download default credentials: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("default credentials download returned status %d", resp.StatusCode)
+	}
+
+	credentials, err := defaultcreds.Parse(resp.Body, source)
+	if err != nil {
+		return fmt.Errorf("failed to parse default credentials: %w", err)
+	}
+	// A dataset that shrank to nothing is more likely broken than true
+	if len(credentials) == 0 {
+		return fmt.Errorf("default credentials dataset at %s is empty", source)
+	}
+
+	err = w.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source <> ?", defaultcreds.SourceShipped).Delete(&models.DefaultCredential{}).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(credentials, 500).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save default credentials: %w", err)
+	}
+	log.Printf("Updated %d default credentials from %s", len(credentials), source)
+	return nil
+}
@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+
+	"gorm.io/gorm"
+)
+
+// diffBasePath returns the firmware of the analysis a diff scan compares
+// its target with. A base that was deleted since won't come back.
+func (w *Worker) diffBasePath(project *models.Project) (string, error) {
+	var base models.Project
+	err := w.db.First(&base, "id = ?", project.DiffBaseID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", queue.Permanent(fmt.Errorf("base analysis %s of the diff scan no longer exists", project.DiffBaseID))
+	}
+	if err != nil {
+		return "", queue.Transient(fmt.Errorf("failed to load base analysis: %w", err))
+	}
+
+	for _, path := range []string{base.FilePath, project.FilePath} {
+		if _, err := os.Stat(path); err != nil {
+			return "", queue.Permanent(fmt.Errorf("firmware %s of the diff scan is gone: %w", path, err))
+		}
+	}
+	return base.FilePath, nil
+}
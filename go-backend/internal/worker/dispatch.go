@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/settings"
+)
+
+// activeStatuses are the statuses of projects a worker is currently scanning
+var activeStatuses = []models.ProjectStatus{models.StatusExtracting, models.StatusAnalyzing}
+
+// orgQueue is the pending work of one organization
+type orgQueue struct {
+	orgID   string
+	running int
+	pending []models.Project
+}
+
+// nextJob claims the next project to analyze, or returns nil when nothing can
+// start right now. Organizations take turns: the one with the fewest running
+// scans goes first (oldest pending upload breaks ties), jobs within an
+// organization are FIFO, and organizations at their concurrency cap wait.
+func (w *Worker) nextJob() (*models.Project, error) {
+	var projects []models.Project
+
+	// Find projects that are pending analysis and not waiting out a retry backoff
+	if err := w.db.Where("status = ?", models.StatusPending).
+		Where("next_retry_at IS NULL OR next_retry_at <= ?", time.Now().UTC()).
+		Order("created_at, id").
+		Find(&projects).Error; err != nil {
+		return nil, fmt.Errorf("failed to query pending projects: %w", err)
+	}
+	if len(projects) == 0 {
+		return nil, nil
+	}
+
+	running, err := w.runningScansByOrg()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, org := range fairOrder(projects, running) {
+		if limit := w.orgScanLimit(org.orgID); limit > 0 && org.running >= limit {
+			w.recordDeferral(&org.pending[0], SchedulingDecision{
+				Deferred:  true,
+				Reason:    fmt.Sprintf("organization has %d of %d concurrent scans running", org.running, limit),
+				CheckedAt: time.Now().UTC(),
+			})
+			continue
+		}
+
+		for i := range org.pending {
+			project := &org.pending[i]
+
+			// Leave the job queued while the host lacks memory, disk or CPU for it
+			if decision := w.checkResources(project); decision.Deferred {
+				w.recordDeferral(project, decision)
+				continue
+			}
+
+			// The projects table is the queue: claim the project so no other
+			// worker picks it up
+			claimed, err := w.claimProject(project)
+			if err != nil {
+				log.Printf("Failed to claim project %s: %v", project.ID, err)
+				continue
+			}
+			if claimed {
+				return project, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// fairOrder groups pending projects by organization and orders the groups
+// so the least served organization comes first
+func fairOrder(projects []models.Project, running map[string]int) []*orgQueue {
+	queues := make(map[string]*orgQueue)
+	var order []*orgQueue
+	for _, project := range projects {
+		orgID := project.OrgID
+		if orgID == "" {
+			orgID = models.DefaultOrgID
+		}
+		org, ok := queues[orgID]
+		if !ok {
+			org = &orgQueue{orgID: orgID, running: running[orgID]}
+			queues[orgID] = org
+			order = append(order, org)
+		}
+		org.pending = append(org.pending, project)
+	}
+
+	// Stable sort keeps oldest-first order among organizations with equal load
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].running < order[j].running
+	})
+	return order
+}
+
+// runningScansByOrg counts projects being scanned per organization
+func (w *Worker) runningScansByOrg() (map[string]int, error) {
+	var rows []struct {
+		OrgID string
+		Count int
+	}
+	if err := w.db.Model(&models.Project{}).
+		Select("org_id, COUNT(*) AS count").
+		Where("status IN ?", activeStatuses).
+		Group("org_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count running scans: %w", err)
+	}
+
+	running := make(map[string]int, len(rows))
+	for _, row := range rows {
+		running[row.OrgID] = row.Count
+	}
+	return running, nil
+}
+
+// orgScanLimit returns an organization's concurrent scan cap, 0 if unlimited
+func (w *Worker) orgScanLimit(orgID string) int {
+	orgSettings, err := settings.Load(w.db, w.config, orgID)
+	if err != nil {
+		log.Printf("Failed to load settings for org %s: %v", orgID, err)
+		return w.config.OrgMaxConcurrentScans
+	}
+	return orgSettings.MaxConcurrentScans
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/epss"
+	"odin-backend/internal/models"
+)
+
+const epssPollInterval = time.Minute
+
+// RunEPSSEnrichment keeps the EPSS scores of the CVE findings of completed
+// analyses current until the process exits, from the imported mirror in
+// offline mode. It does nothing unless EPSS_ENRICHMENT is on.
+func (w *Worker) RunEPSSEnrichment() {
+	if !w.config.EPSSEnrichment {
+		return
+	}
+	var c epss.Source = epss.New(w.config)
+	if w.mirror != nil {
+		c = w.mirror
+	}
+
+	log.Printf("EPSS enrichment checking for CVE findings to score every %s", epssPollInterval)
+	for {
+		if err := w.RefreshEPSSScores(context.Background(), c); err != nil {
+			log.Printf("Error refreshing EPSS scores: %v", err)
+		}
+		time.Sleep(epssPollInterval)
+	}
+}
+
+// RefreshEPSSScores looks up the scores of the CVEs of the CVE findings
+// never scored or last scored more than EPSS_REFRESH_INTERVAL ago, a batch
+// of CVEs at a time, until none are left. A score is stored once in the
+// CVE's shared record. CVEs EPSS doesn't score keep a score of 0.
+func (w *Worker) RefreshEPSSScores(ctx context.Context, c epss.Source) error {
+	for {
+		stale := time.Now().UTC().Add(-w.config.EPSSRefreshInterval)
+		var ids []string
+		if err := w.db.Model(&models.CVE{}).
+			Where("id IN (?)", w.enrichableCVEs(w.db.Model(&models.CVEFinding{})).Select("cve_id")).
+			Where("epss_checked_at IS NULL OR epss_checked_at < ?", stale).
+			Order("id").Limit(epss.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("failed to query CVE findings to score: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		scores, err := c.Scores(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, id := range ids {
+			score := scores[id]
+			if err := w.db.Model(&models.CVE{}).Where("id = ?", id).
+				UpdateColumns(map[string]interface{}{
+					"epss_score":      score.EPSS,
+					"epss_percentile": score.Percentile,
+					"epss_checked_at": now,
+				}).Error; err != nil {
+				return fmt.Errorf("failed to save EPSS score of %s: %w", id, err)
+			}
+		}
+		log.Printf("Refreshed EPSS scores of %d CVEs, %d scored", len(ids), len(scores))
+	}
+}
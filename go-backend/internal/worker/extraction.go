@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/extract"
+	"odin-backend/internal/fwformat"
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+)
+
+// rejectEncrypted stops images that look encrypted before EMBA spends hours
+// failing to unpack them: vendor encrypted containers no decryptor could
+// decrypt, or no known container signature and near random data
+func (w *Worker) rejectEncrypted(project *models.Project) error {
+	if fwformat.Encrypted(project.FirmwareType) {
+		project.ExtractionQuality = models.ExtractionEncrypted
+		extraction := project.ExtractionData()
+		extraction["extraction"] = map[string]interface{}{"quality": models.ExtractionEncrypted}
+		project.SetExtractionData(extraction)
+		return queue.Permanent(fmt.Errorf("firmware is a D-Link %s image encrypted with a key none of the decryptors knows. Upload the decrypted image or add a decryptor to DECRYPTOR_DIR", project.FirmwareType))
+	}
+
+	threshold := w.config.EncryptedEntropyThreshold
+	if threshold == 0 || project.FirmwareType != fwformat.Unknown {
+		return nil
+	}
+
+	entropy, err := fwformat.Entropy(project.FilePath)
+	if err != nil {
+		log.Printf("Failed to measure entropy of project %s: %v", project.ID, err)
+		return nil
+	}
+	if entropy < threshold {
+		return nil
+	}
+
+	project.ExtractionQuality = models.ExtractionEncrypted
+	extraction := project.ExtractionData()
+	extraction["extraction"] = map[string]interface{}{"quality": models.ExtractionEncrypted, "entropy": entropy}
+	project.SetExtractionData(extraction)
+	return queue.Permanent(fmt.Errorf("firmware appears to be encrypted: no known container format and an entropy of %.2f bits per byte, so EMBA could not extract anything to analyze. Upload the decrypted image", entropy))
+}
+
+// checkExtraction records how well EMBA unpacked the image. An image it
+// couldn't see into fails the analysis rather than being reported as a
+// clean, low-risk firmware.
+func checkExtraction(project *models.Project, results *emba.ParsedResults) error {
+	extraction, ok := results.ExtractionInfo["extraction"].(*emba.Extraction)
+	if !ok {
+		return nil
+	}
+	project.ExtractionQuality = extraction.Quality
+	if !extraction.Quality.Opaque() || len(results.CVEs) > 0 {
+		return nil
+	}
+	// Informational findings only describe the image itself
+	for _, finding := range results.Findings {
+		if finding.Severity != models.RiskInfo {
+			return nil
+		}
+	}
+
+	if extraction.Quality == models.ExtractionEncrypted {
+		detail := "EMBA reported it as encrypted"
+		if extraction.Entropy != nil {
+			detail = fmt.Sprintf("its entropy is %.2f bits per byte", *extraction.Entropy)
+		}
+		return queue.Permanent(fmt.Errorf("firmware appears to be encrypted (%s) and EMBA extracted no files, so there were no results to report. Upload the decrypted image", detail))
+	}
+	return queue.Permanent(fmt.Errorf("EMBA extracted no files from the firmware, so there were no results to report; the image format may be unsupported"))
+}
+
+// recordExtraction rates an extraction done outside EMBA and adds the file
+// inventory of the extracted tree to the results
+func recordExtraction(results *emba.ParsedResults, root, method string) error {
+	inventory, err := extract.TakeInventory(root)
+	if err != nil {
+		return fmt.Errorf("failed to inventory extracted files: %w", err)
+	}
+	results.ExtractionInfo["extraction"] = emba.RateExtraction(inventory.Files)
+	results.Summary["extraction_method"] = method
+	results.Summary["inventory"] = inventory
+	return nil
+}
+
+// clearWorkDir removes what an earlier attempt of the job left in dir, so a
+// retry starts from scratch
+func clearWorkDir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return queue.Transient(fmt.Errorf("failed to clear work directory: %w", err))
+	}
+	return nil
+}
+
+// unpackFailure classifies the error of an unpacker for the retry policy: a
+// cancelled job returns its cause, an image the unpacker found nothing in
+// fails permanently with the reason given, other errors as classify says
+func unpackFailure(ctx context.Context, err error, nothing string, classify func(error) error) error {
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+	if errors.Is(err, extract.ErrNothingExtracted) {
+		return queue.Permanent(errors.New(nothing))
+	}
+	return classify(err)
+}
@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/extract"
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+	"odin-backend/internal/rtos"
+)
+
+// extractOnly analyzes a project without EMBA: the filesystem is extracted
+// (with unblob if the project selected it) into the log directory EMBA would
+// have used, so the firmware browser finds it, then inventoried and checked
+// by Odin itself. firmwarePath is the payload of the upload's vendor
+// container, if it had one.
+func (w *Worker) extractOnly(ctx context.Context, project *models.Project, firmwarePath string, vendor *extract.VendorContainer) error {
+	if err := w.updateProjectStatus(project, models.StatusAnalyzing, "EMBA is not available, extracting firmware..."); err != nil {
+		return queue.Transient(fmt.Errorf("failed to update project status: %w", err))
+	}
+
+	logDir := filepath.Join(w.config.EMBALogDir, fmt.Sprintf("job_%s", project.ID))
+	root := filepath.Join(logDir, "firmware")
+	if err := clearWorkDir(root); err != nil {
+		return err
+	}
+
+	method := extract.MethodUnblob
+	var android *extract.AndroidInfo
+	var container *extract.ContainerInfo
+	var err error
+	if project.Extractor == extract.MethodUnblob {
+		err = w.extractor.Unblob(ctx, firmwarePath, root)
+	} else if extract.IsAndroid(project.FilePath, project.FirmwareType) {
+		method = extract.MethodAndroid
+		android, err = w.extractor.Android(ctx, project.FilePath, root)
+	} else if extract.IsContainerImage(project.FilePath, project.FirmwareType) {
+		method = extract.MethodContainer
+		container, err = w.extractor.ContainerImage(ctx, project.FilePath, root)
+	} else {
+		method, err = w.extractor.Extract(ctx, firmwarePath, root)
+	}
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		// A monolithic RTOS image has no filesystem to unpack, but its RTOS
+		// and the CVEs of its version are still worth reporting
+		detected, _ := rtos.Detect(firmwarePath)
+		if detected == nil {
+			return queue.Permanent(fmt.Errorf("extraction failed: %w", err))
+		}
+		log.Printf("Nothing extracted from project %s, analyzing it as a monolithic %s image: %v", project.Name, detected.Name, err)
+		method = extract.MethodNone
+		if err := os.MkdirAll(root, 0755); err != nil {
+			return queue.Transient(fmt.Errorf("failed to create extraction directory: %w", err))
+		}
+	}
+
+	findings, err := extract.Check(root)
+	if err != nil {
+		return queue.Transient(fmt.Errorf("failed to check extracted files: %w", err))
+	}
+
+	project.Extractor = method
+	project.ExtractionOnly = true
+	result := &emba.AnalysisResult{
+		Success:      true,
+		LogDir:       logDir,
+		AnalysisTime: time.Now().UTC().Format(time.RFC3339),
+		Results: emba.ParsedResults{
+			Findings:         findings,
+			CVEs:             []models.CVEFinding{},
+			OSINTResults:     []models.OSINTResult{},
+			Components:       []models.SBOMComponent{},
+			Binaries:         []models.BinaryAnalysis{},
+			PasswordHashes:   []models.PasswordHash{},
+			EmulationResults: []models.EmulationResult{},
+			KeyMaterials:     []models.KeyMaterial{},
+			BinaryHashes:     []models.BinaryHash{},
+			Files:            []models.FirmwareFile{},
+			FileInfo:         map[string]interface{}{},
+			ExtractionInfo:   map[string]interface{}{},
+			Summary:          map[string]interface{}{"result_source": "extraction_only"},
+		},
+	}
+	if err := recordExtraction(&result.Results, root, method); err != nil {
+		return queue.Transient(err)
+	}
+	if android != nil {
+		result.Results.FileInfo["android"] = android
+	}
+	if container != nil {
+		result.Results.FileInfo["container"] = container
+	}
+	if vendor != nil {
+		result.Results.ExtractionInfo["vendor_container"] = vendor
+	}
+
+	if err := w.completeAnalysis(project, result, "Extraction-only analysis completed: EMBA is not available"); err != nil {
+		return err
+	}
+	log.Printf("Extraction-only analysis completed for project %s (%s)", project.Name, method)
+	return nil
+}
@@ -0,0 +1,29 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/fuzzyhash"
+	"odin-backend/internal/models"
+)
+
+// hashBinaries adds the hashes of the extracted ELF binaries to the results
+// so they can be matched against the binaries of other projects
+func (w *Worker) hashBinaries(project *models.Project, result *emba.AnalysisResult) {
+	root := filepath.Join(result.LogDir, "firmware")
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return
+	}
+
+	hashes, err := fuzzyhash.HashBinaries(context.Background(), root)
+	if err != nil {
+		log.Printf("Hashing the binaries of project %s failed: %v", project.ID, err)
+		return
+	}
+	result.Results.BinaryHashes = hashes
+	result.Results.Summary["binaries_hashed"] = len(hashes)
+}
@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+)
+
+// Ingest parses the log directory of a past EMBA run into the project
+// instead of analyzing its firmware
+func (w *Worker) Ingest(project *models.Project) error {
+	if err := w.updateProjectStatus(project, models.StatusAnalyzing, "Parsing imported EMBA logs..."); err != nil {
+		return queue.Transient(fmt.Errorf("failed to update project status: %w", err))
+	}
+
+	result, err := w.emba.ParseLogDir(project.IngestLogDir, project.ParserLayout)
+	if err != nil {
+		return queue.Permanent(fmt.Errorf("failed to parse EMBA logs: %w", err))
+	}
+	if err := w.completeAnalysis(project, result, "Imported EMBA logs parsed successfully"); err != nil {
+		return err
+	}
+
+	log.Printf("Imported EMBA logs from %s into project %s", project.IngestLogDir, project.Name)
+	return nil
+}
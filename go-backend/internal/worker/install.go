@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"odin-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// installProgressInterval limits how often installation output is saved
+const installProgressInterval = 5 * time.Second
+
+// installPending reports whether an EMBA installation is waiting for or
+// running on this host; analyses must not start until it is done
+func (w *Worker) installPending() bool {
+	if w.id == "" {
+		return false
+	}
+	hostname, _ := os.Hostname()
+	var count int64
+	err := w.db.Model(&models.EMBAInstall{}).
+		Where("hostname = ? AND status IN ?", hostname, []string{models.InstallPending, models.InstallRunning}).
+		Count(&count).Error
+	return err == nil && count > 0
+}
+
+// runPendingInstall carries out the oldest EMBA installation requested for
+// this host once no worker on the host is running an analysis
+func (w *Worker) runPendingInstall(ctx context.Context) {
+	if w.id == "" {
+		return
+	}
+	hostname, _ := os.Hostname()
+	w.failAbandonedInstalls(hostname)
+
+	var install models.EMBAInstall
+	err := w.db.Where("hostname = ? AND status = ?", hostname, models.InstallPending).
+		Order("created_at").First(&install).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to check for EMBA installations: %v", err)
+		return
+	}
+
+	var busy int64
+	if err := w.db.Model(&models.Worker{}).
+		Where("hostname = ? AND current_project_id <> '' AND last_heartbeat_at > ?", hostname, time.Now().Add(-models.WorkerOfflineAfter)).
+		Count(&busy).Error; err != nil || busy > 0 {
+		return
+	}
+
+	// Claim it; another worker on this host may have been faster
+	now := time.Now().UTC()
+	claimed := w.db.Model(&models.EMBAInstall{}).
+		Where("id = ? AND status = ?", install.ID, models.InstallPending).
+		Updates(map[string]interface{}{"status": models.InstallRunning, "worker_id": w.id, "started_at": now})
+	if claimed.Error != nil || claimed.RowsAffected == 0 {
+		return
+	}
+
+	log.Printf("Installing EMBA %s (%s mode) for request %s", install.Version, install.Mode, install.ID)
+
+	var lastSaved time.Time
+	var lastStep, lastOutput string
+	err = w.emba.Install(ctx, install.Version, install.Mode, func(step, output string) {
+		lastOutput = output
+		if step == lastStep && time.Since(lastSaved) < installProgressInterval {
+			return
+		}
+		lastStep, lastSaved = step, time.Now()
+		w.db.Model(&models.EMBAInstall{}).Where("id = ?", install.ID).
+			Updates(map[string]interface{}{"step": step, "output": output})
+	})
+
+	completed := time.Now().UTC()
+	updates := map[string]interface{}{
+		"status":       models.InstallCompleted,
+		"output":       lastOutput,
+		"completed_at": completed,
+	}
+	if err != nil {
+		log.Printf("EMBA installation %s failed: %v", install.ID, err)
+		updates["status"] = models.InstallFailed
+		updates["error"] = err.Error()
+	} else {
+		log.Printf("EMBA %s installed for request %s", install.Version, install.ID)
+	}
+	if err := w.db.Model(&models.EMBAInstall{}).Where("id = ?", install.ID).Updates(updates).Error; err != nil {
+		log.Printf("Failed to record outcome of EMBA installation %s: %v", install.ID, err)
+	}
+}
+
+// failAbandonedInstalls fails installations whose worker stopped mid-way
+func (w *Worker) failAbandonedInstalls(hostname string) {
+	var running []models.EMBAInstall
+	if err := w.db.Where("hostname = ? AND status = ?", hostname, models.InstallRunning).Find(&running).Error; err != nil {
+		return
+	}
+	for _, install := range running {
+		var worker models.Worker
+		if err := w.db.First(&worker, "id = ?", install.WorkerID).Error; err == nil && worker.Online() {
+			continue
+		}
+		w.db.Model(&models.EMBAInstall{}).Where("id = ? AND status = ?", install.ID, models.InstallRunning).
+			Updates(map[string]interface{}{
+				"status": models.InstallFailed,
+				"error":  fmt.Sprintf("worker %s stopped during the installation", install.WorkerID),
+			})
+	}
+}
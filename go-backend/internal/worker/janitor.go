@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/suppress"
+)
+
+const heartbeatInterval = time.Minute
+
+// Recovery actions recorded in a project's extraction results
+const (
+	RecoveryRequeued = "requeued"
+	RecoveryFailed   = "failed"
+)
+
+// startHeartbeat periodically marks the project as alive while this worker
+// processes it, calling onDeleted if the project no longer exists. The
+// returned function stops the heartbeat.
+func (w *Worker) startHeartbeat(projectID string, onDeleted func()) func() {
+	beat := func() {
+		now := time.Now().UTC()
+		result := w.db.Model(&models.Project{}).Where("id = ?", projectID).
+			UpdateColumn("last_heartbeat_at", now)
+		if result.Error != nil {
+			log.Printf("Failed to record heartbeat for project %s: %v", projectID, result.Error)
+		} else if result.RowsAffected == 0 && onDeleted != nil {
+			onDeleted()
+		}
+	}
+	beat()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				beat()
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// RunJanitor periodically recovers stuck projects and lifts the expired
+// CVE suppressions until the process exits
+func (w *Worker) RunJanitor() {
+	log.Printf("Janitor checking for stuck jobs every %s (threshold %s)", w.config.JanitorInterval, w.config.StuckJobThreshold)
+	for {
+		if err := w.RecoverStuckJobs(); err != nil {
+			log.Printf("Error recovering stuck jobs: %v", err)
+		}
+		if n, err := suppress.Expire(w.db); err != nil {
+			log.Printf("Error lifting expired CVE suppressions: %v", err)
+		} else if n > 0 {
+			log.Printf("Lifted expired CVE suppressions of %d projects", n)
+		}
+		time.Sleep(w.config.JanitorInterval)
+	}
+}
+
+// RecoverStuckJobs finds projects stuck in analyzing/extracting whose worker
+// stopped sending heartbeats, and requeues or fails them
+func (w *Worker) RecoverStuckJobs() error {
+	cutoff := time.Now().UTC().Add(-w.config.StuckJobThreshold)
+
+	var projects []models.Project
+	err := w.db.Where("status IN ?", []models.ProjectStatus{models.StatusAnalyzing, models.StatusExtracting}).
+		Where("updated_at < ?", cutoff).
+		Where("last_heartbeat_at IS NULL OR last_heartbeat_at < ?", cutoff).
+		Find(&projects).Error
+	if err != nil {
+		return fmt.Errorf("failed to query stuck projects: %w", err)
+	}
+
+	for i := range projects {
+		if err := w.recoverProject(&projects[i]); err != nil {
+			log.Printf("Failed to recover project %s: %v", projects[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// recoverProject requeues a stuck project, or fails it once it has used up its requeues
+func (w *Worker) recoverProject(project *models.Project) error {
+	extraction := project.ExtractionData()
+	history, _ := extraction["recovery_actions"].([]interface{})
+
+	requeues := 0
+	for _, entry := range history {
+		if action, ok := entry.(map[string]interface{}); ok && action["action"] == RecoveryRequeued {
+			requeues++
+		}
+	}
+
+	action := RecoveryFailed
+	if w.config.StuckJobAction == "requeue" && requeues < w.config.StuckJobMaxRequeues {
+		action = RecoveryRequeued
+	}
+
+	lastSeen := project.UpdatedAt
+	if project.LastHeartbeatAt != nil && project.LastHeartbeatAt.After(lastSeen) {
+		lastSeen = *project.LastHeartbeatAt
+	}
+	reason := fmt.Sprintf("no worker heartbeat while %s since %s", project.Status, lastSeen.Format(time.RFC3339))
+
+	extraction["recovery_actions"] = append(history, map[string]interface{}{
+		"action":          action,
+		"previous_status": project.Status,
+		"reason":          reason,
+		"recovered_at":    time.Now().UTC(),
+	})
+
+	log.Printf("Recovering stuck project %s: %s (%s)", project.ID, action, reason)
+
+	// Guard on the status so a worker that resumed in the meantime isn't overridden
+	status := models.StatusFailed
+	message := "Analysis failed: " + reason
+	if action == RecoveryRequeued {
+		status = models.StatusPending
+		message = "Requeued after stuck job recovery"
+	}
+	extraction["status_message"] = message
+	extraction["last_updated"] = time.Now().UTC()
+	project.SetExtractionData(extraction)
+
+	return w.db.Model(&models.Project{}).
+		Where("id = ? AND status = ?", project.ID, project.Status).
+		Updates(map[string]interface{}{
+			"status":             status,
+			"extraction_results": project.ExtractionResults,
+		}).Error
+}
@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/firmwarefs"
+	"odin-backend/internal/models"
+)
+
+// recordManifest adds the manifest of the extracted files to the results
+func (w *Worker) recordManifest(project *models.Project, result *emba.AnalysisResult) {
+	root := filepath.Join(result.LogDir, "firmware")
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return
+	}
+
+	files, err := firmwarefs.Manifest(context.Background(), root)
+	if err != nil {
+		log.Printf("Failed to build the file manifest of project %s: %v", project.ID, err)
+		return
+	}
+	result.Results.Files = files
+}
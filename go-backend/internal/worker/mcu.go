@@ -0,0 +1,97 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/extract"
+	"odin-backend/internal/mcu"
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+)
+
+// mcuFlashImage is the file the flash image of a HEX file is written to
+const mcuFlashImage = "flash.bin"
+
+// analyzeMCU analyzes bare-metal firmware without EMBA, whose modules look
+// for a filesystem: the flash image, its strings and its symbols are written
+// where EMBA's extracted firmware would be, so the secret scanner, YARA and
+// the firmware browser work on them
+func (w *Worker) analyzeMCU(ctx context.Context, project *models.Project) error {
+	if err := w.updateProjectStatus(project, models.StatusAnalyzing, "Analyzing bare-metal MCU firmware..."); err != nil {
+		return queue.Transient(fmt.Errorf("failed to update project status: %w", err))
+	}
+
+	logDir := filepath.Join(w.config.EMBALogDir, fmt.Sprintf("job_%s", project.ID))
+	root := filepath.Join(logDir, "firmware")
+	if err := clearWorkDir(root); err != nil {
+		return err
+	}
+
+	image, err := mcu.Analyze(project.FilePath, project.FirmwareType)
+	if err != nil {
+		return queue.Permanent(fmt.Errorf("failed to read MCU firmware: %w", err))
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	name := mcuFlashImage
+	if image.Info.Format != mcu.FormatHex {
+		name = filepath.Base(project.Filename)
+	}
+	files := map[string]string{
+		name:          string(image.Data),
+		"strings.txt": strings.Join(image.Strings, "\n"),
+	}
+	if len(image.Symbols) > 0 {
+		files["symbols.txt"] = strings.Join(image.Symbols, "\n")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return queue.Transient(fmt.Errorf("failed to create firmware directory: %w", err))
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(root, file), []byte(content), 0644); err != nil {
+			return queue.Transient(fmt.Errorf("failed to write %s: %w", file, err))
+		}
+	}
+
+	project.Extractor = extract.MethodMCU
+	project.ExtractionOnly = true
+	result := &emba.AnalysisResult{
+		Success:      true,
+		LogDir:       logDir,
+		AnalysisTime: time.Now().UTC().Format(time.RFC3339),
+		Results: emba.ParsedResults{
+			Findings:         []models.Finding{},
+			CVEs:             []models.CVEFinding{},
+			OSINTResults:     []models.OSINTResult{},
+			Components:       []models.SBOMComponent{},
+			Binaries:         []models.BinaryAnalysis{},
+			PasswordHashes:   []models.PasswordHash{},
+			EmulationResults: []models.EmulationResult{},
+			KeyMaterials:     []models.KeyMaterial{},
+			BinaryHashes:     []models.BinaryHash{},
+			Files:            []models.FirmwareFile{},
+			FileInfo:         map[string]interface{}{"mcu": image.Info},
+			ExtractionInfo:   map[string]interface{}{},
+			Summary: map[string]interface{}{
+				"result_source":     "mcu",
+				"extraction_method": extract.MethodMCU,
+				"architecture":      image.Info.Architecture,
+			},
+		},
+	}
+
+	if err := w.completeAnalysis(project, result, "Bare-metal MCU firmware analysis completed"); err != nil {
+		return err
+	}
+	log.Printf("MCU firmware analysis completed for project %s (%s)", project.Name, image.Info.Architecture)
+	return nil
+}
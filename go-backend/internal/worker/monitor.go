@@ -0,0 +1,316 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/nvd"
+	"odin-backend/internal/risk"
+	"odin-backend/internal/suppress"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// monitorWindow is how far back the first run matches: the span of
+	// NVD's modified feed
+	monitorWindow = 8 * 24 * time.Hour
+
+	// monitorTimeout bounds a run
+	monitorTimeout = time.Hour
+
+	// monitorSourceMirror is the source of the runs of offline mode
+	monitorSourceMirror = "mirror"
+)
+
+// monitorMatch is a stored SBOM component a CVE affects
+type monitorMatch struct {
+	cve       *nvd.CVE
+	component *models.SBOMComponent
+	match     string // cpe or name_version
+}
+
+// RunCVEMonitoring matches the SBOM components of completed analyses against
+// the CVEs NVD published or changed once every CVE_MONITOR_INTERVAL until the
+// process exits, checking every minute whether the interval's run is due. It
+// does nothing unless CVE_MONITORING is on.
+func (w *Worker) RunCVEMonitoring() {
+	if !w.config.CVEMonitoring {
+		return
+	}
+
+	log.Printf("Monitoring stored SBOMs for new CVEs every %s", w.config.CVEMonitorInterval)
+	for {
+		if err := w.MonitorCVEs(context.Background()); err != nil {
+			log.Printf("Error monitoring CVEs: %v", err)
+		}
+		time.Sleep(time.Minute)
+	}
+}
+
+// MonitorCVEs runs the CVE monitor unless it ran in the current interval,
+// on this worker or another: the CVEs of NVD's modified feed (offline, the
+// NVD records imported) modified since the last successful run are matched
+// against the SBOM components of the completed analyses, which gain a CVE
+// finding for every CVE they didn't have. A failed run is recorded and the
+// next one matches from the same point, so no CVE is missed.
+func (w *Worker) MonitorCVEs(ctx context.Context) error {
+	now := time.Now().UTC()
+	source := w.config.NVDModifiedFeedURL
+	if w.config.OfflineMode {
+		source = monitorSourceMirror
+	}
+
+	var previous models.CVEMonitorRun
+	if err := w.db.Where("completed_at IS NOT NULL AND error = ''").
+		Order("started_at DESC").Limit(1).Find(&previous).Error; err != nil {
+		return fmt.Errorf("failed to load last CVE monitor run: %w", err)
+	}
+	since := now.Add(-monitorWindow)
+	if previous.ID != 0 {
+		since = previous.StartedAt
+	}
+
+	// Claim the interval's run, so another worker doesn't run it too
+	run := models.CVEMonitorRun{
+		Slot:      now.Truncate(w.config.CVEMonitorInterval).Format(time.RFC3339),
+		Source:    source,
+		Since:     since,
+		StartedAt: now,
+	}
+	claimed := w.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&run)
+	if claimed.Error != nil {
+		return fmt.Errorf("failed to claim CVE monitor run: %w", claimed.Error)
+	}
+	if claimed.RowsAffected == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, monitorTimeout)
+	defer cancel()
+	err := w.monitorCVEs(ctx, &run)
+
+	completed := time.Now().UTC()
+	run.CompletedAt = &completed
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if saveErr := w.db.Save(&run).Error; saveErr != nil {
+		log.Printf("Failed to record CVE monitor run: %v", saveErr)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("CVE monitor matched %d CVEs modified since %s: %d CVE findings added to %d projects",
+		run.CVEs, since.Format(time.RFC3339), run.Findings, run.Projects)
+	return nil
+}
+
+// monitorCVEs matches the CVEs modified since the run's start against the
+// monitored components and adds the new CVE findings, a project at a time
+func (w *Worker) monitorCVEs(ctx context.Context, run *models.CVEMonitorRun) error {
+	components, err := w.monitoredComponents()
+	if err != nil {
+		return err
+	}
+
+	matches := make(map[string][]monitorMatch)
+	match := func(cve *nvd.CVE) error {
+		if cve.LastModified.Before(run.Since) {
+			return nil
+		}
+		run.CVEs++
+		for _, product := range cve.Products() {
+			for _, component := range components[product] {
+				if how := cve.Affects(component); how != "" {
+					matches[component.ProjectID] = append(matches[component.ProjectID], monitorMatch{cve, component, how})
+				}
+			}
+		}
+		return nil
+	}
+	if run.Source == monitorSourceMirror {
+		err = w.readNVDRecords(run.Since, match)
+	} else {
+		err = nvd.FetchFeed(ctx, run.Source, match)
+	}
+	if err != nil {
+		return err
+	}
+
+	for projectID, projectMatches := range matches {
+		added, err := w.addMonitoredCVEs(projectID, projectMatches)
+		if err != nil {
+			return fmt.Errorf("project %s: %w", projectID, err)
+		}
+		if len(added) == 0 {
+			continue
+		}
+		run.Findings += len(added)
+		run.Projects++
+
+		// Accepted risks aren't news
+		var notify []models.CVEFinding
+		for _, finding := range added {
+			if finding.SuppressionID == nil {
+				notify = append(notify, finding)
+			}
+		}
+		if len(notify) > 0 {
+			w.webhooks.NotifyNewCVEs(projectID, notify)
+			w.webhooks.NotifyAlerts(projectID, notify)
+		}
+	}
+	return nil
+}
+
+// monitoredComponents returns the versioned SBOM components of the
+// completed analyses by normalized product. Frozen projects stay as
+// delivered; diff scans aren't monitored.
+func (w *Worker) monitoredComponents() (map[string][]*models.SBOMComponent, error) {
+	var components []models.SBOMComponent
+	if err := w.db.Where("version <> '' OR cpe <> ''").
+		Where("project_id IN (?)", w.db.Model(&models.Project{}).Select("id").
+			Where("status = ? AND frozen_at IS NULL AND diff_base_id = ''", models.StatusCompleted)).
+		Find(&components).Error; err != nil {
+		return nil, fmt.Errorf("failed to load SBOM components: %w", err)
+	}
+	byProduct := make(map[string][]*models.SBOMComponent)
+	for i := range components {
+		if product := nvd.Product(&components[i]); product != "" {
+			byProduct[product] = append(byProduct[product], &components[i])
+		}
+	}
+	return byProduct, nil
+}
+
+// readNVDRecords calls fn with every NVD record imported or fetched since
+// the given time, which the records modified since then are among
+func (w *Worker) readNVDRecords(since time.Time, fn func(*nvd.CVE) error) error {
+	var records []models.NVDRecord
+	return w.db.Where("found = ? AND fetched_at >= ?", true, since).
+		FindInBatches(&records, 500, func(tx *gorm.DB, batch int) error {
+			for _, record := range records {
+				var cve nvd.CVE
+				if err := json.Unmarshal([]byte(record.Data), &cve); err != nil {
+					continue
+				}
+				if err := fn(&cve); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// addMonitoredCVEs adds a CVE finding to the project for every matched
+// component of a CVE the project doesn't have yet, fills the CVEs' shared
+// records in with NVD's, adjusts their scores to the project and rates it
+// again. It returns the findings added.
+func (w *Worker) addMonitoredCVEs(projectID string, matches []monitorMatch) ([]models.CVEFinding, error) {
+	ids := make([]string, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.cve.ID)
+	}
+	var existing []string
+	if err := w.db.Model(&models.CVEFinding{}).Where("project_id = ? AND cve_id IN ?", projectID, ids).
+		Distinct("cve_id").Pluck("cve_id", &existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load CVE findings: %w", err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+
+	now := time.Now().UTC()
+	var records []*models.CVE
+	shared := make(map[string]*models.CVE)
+	var added []models.CVEFinding
+	for _, m := range matches {
+		if known[m.cve.ID] {
+			continue
+		}
+		record, ok := shared[m.cve.ID]
+		if !ok {
+			record = &models.CVE{ID: m.cve.ID}
+			if err := w.db.Limit(1).Find(record, "id = ?", m.cve.ID).Error; err != nil {
+				return nil, fmt.Errorf("failed to load %s: %w", m.cve.ID, err)
+			}
+			nvd.ApplyRecord(record, m.cve)
+			record.NVDEnrichedAt = &now
+			shared[m.cve.ID] = record
+			records = append(records, record)
+		}
+
+		componentID := m.component.ID
+		finding := models.CVEFinding{
+			ProjectID:       projectID,
+			CVEID:           m.cve.ID,
+			SoftwareName:    m.component.Name,
+			SoftwareVersion: m.component.Version,
+			ComponentID:     &componentID,
+			ComponentMatch:  m.match,
+			SeverityLevel:   models.RiskLow, // unless NVD scored it, as EMBA rates unscored CVEs
+			NVDEnrichedAt:   &now,
+			MonitoredAt:     &now,
+		}
+		finding.Fill(record)
+		nvd.Apply(&finding, m.cve)
+		finding.MatchStatus, finding.MatchReason = m.cve.Verify(m.component)
+		finding.MatchCriteria, finding.MatchVersionRange = m.cve.Evidence(m.component)
+		added = append(added, finding)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	var project models.Project
+	if err := w.db.Select("id", "org_id", "reachability", "security_requirements").First(&project, "id = ?", projectID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+	rules, err := suppress.Active(w.db, project.OrgID, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range added {
+		if rule := suppress.Match(rules, &added[i]); rule != nil {
+			added[i].SuppressionID = &rule.ID
+		}
+	}
+
+	// Public exploits and KEV listings, as for the analysis' own CVEs
+	if w.exploits != nil {
+		w.exploits.Enrich(added)
+	}
+	if w.mirror != nil {
+		if err := w.mirror.Enrich(added); err != nil {
+			log.Printf("Mirror lookup for project %s failed: %v", projectID, err)
+		}
+	}
+	for i := range added {
+		risk.Adjust(&added[i], &project)
+	}
+
+	err = w.db.Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			if err := tx.Save(record).Error; err != nil {
+				return fmt.Errorf("failed to save %s: %w", record.ID, err)
+			}
+		}
+		for i := range added {
+			if err := tx.Create(&added[i]).Error; err != nil {
+				return fmt.Errorf("failed to save CVE finding: %w", err)
+			}
+		}
+		return risk.Recount(tx, projectID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
@@ -0,0 +1,195 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/nvd"
+	"odin-backend/internal/risk"
+
+	"gorm.io/gorm"
+)
+
+const (
+	nvdPollInterval = time.Minute
+
+	// nvdBatchSize is the number of CVEs looked up per query of the pending ones
+	nvdBatchSize = 50
+)
+
+// RunNVDEnrichment fills the CVE findings of completed analyses in with
+// NVD's records until the process exits, only from the imported mirror in
+// offline mode. It does nothing unless NVD_ENRICHMENT is on.
+func (w *Worker) RunNVDEnrichment() {
+	if !w.config.NVDEnrichment {
+		return
+	}
+	c := nvd.New(w.config) // nil offline
+
+	log.Printf("NVD enrichment checking for pending CVE findings every %s", nvdPollInterval)
+	for {
+		if err := w.EnrichPendingCVEs(context.Background(), c); err != nil {
+			log.Printf("Error enriching CVE findings: %v", err)
+		}
+		time.Sleep(nvdPollInterval)
+	}
+}
+
+// enrichableCVEs scopes a query to the CVE findings of completed analyses
+// named by a CVE ID; advisories without one (GHSA-...) are unknown to NVD
+// and EPSS. Frozen results stay as delivered.
+func (w *Worker) enrichableCVEs(db *gorm.DB) *gorm.DB {
+	return db.Where("partial = ? AND cve_id LIKE ?", false, "CVE-%").
+		Where("project_id IN (?)", w.db.Model(&models.Project{}).Select("id").
+			Where("status = ? AND frozen_at IS NULL", models.StatusCompleted))
+}
+
+// pendingCVEs scopes a query to the CVE findings NVD wasn't asked about yet
+func (w *Worker) pendingCVEs(db *gorm.DB) *gorm.DB {
+	return w.enrichableCVEs(db).Where("nvd_enriched_at IS NULL")
+}
+
+// EnrichPendingCVEs enriches the pending CVE findings, a CVE at a time for
+// all projects that have it, until none are left. It stops at the first
+// failure to reach NVD; the CVEs not enriched by then are retried on the
+// next pass.
+func (w *Worker) EnrichPendingCVEs(ctx context.Context, c *nvd.Client) error {
+	for {
+		var ids []string
+		if err := w.pendingCVEs(w.db.Model(&models.CVEFinding{})).Distinct("cve_id").
+			Order("cve_id").Limit(nvdBatchSize).Pluck("cve_id", &ids).Error; err != nil {
+			return fmt.Errorf("failed to query pending CVE findings: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		for _, id := range ids {
+			record, err := w.nvdRecord(ctx, c, id)
+			if err != nil {
+				return fmt.Errorf("failed to fetch %s from NVD: %w", id, err)
+			}
+			if err := w.applyNVDRecord(id, record); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// nvdRecord returns NVD's record of a CVE from the cache, or from NVD when
+// it isn't cached or is older than NVD_CACHE_TTL. It returns nil when NVD
+// doesn't know the CVE. Without a client (offline) the cache, which the
+// mirror imports fill, is all there is, however old.
+func (w *Worker) nvdRecord(ctx context.Context, c *nvd.Client, cveID string) (*nvd.CVE, error) {
+	var cached models.NVDRecord
+	err := w.db.First(&cached, "cve_id = ?", cveID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load cached NVD record: %w", err)
+	}
+	if err == nil && (c == nil || time.Since(cached.FetchedAt) < w.config.NVDCacheTTL) {
+		if !cached.Found {
+			return nil, nil
+		}
+		var cve nvd.CVE
+		if err := json.Unmarshal([]byte(cached.Data), &cve); err == nil {
+			return &cve, nil
+		}
+	}
+	if c == nil {
+		return nil, nil
+	}
+
+	cve, err := c.Fetch(ctx, cveID)
+	if err != nil {
+		return nil, err
+	}
+	record := models.NVDRecord{CVEID: cveID, Found: cve != nil, FetchedAt: time.Now().UTC()}
+	if cve != nil {
+		data, _ := json.Marshal(cve)
+		record.Data = string(data)
+	}
+	if err := w.db.Save(&record).Error; err != nil {
+		log.Printf("Failed to cache NVD record of %s: %v", cveID, err)
+	}
+	return cve, nil
+}
+
+// applyNVDRecord fills the shared record of a CVE in with its NVD record,
+// rates its pending findings by NVD's score and rates and adjusts their
+// projects again, as the score may differ from the one EMBA reported. Findings of a CVE NVD
+// doesn't know are only marked enriched.
+func (w *Worker) applyNVDRecord(cveID string, record *nvd.CVE) error {
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		shared := models.CVE{ID: cveID}
+		if err := tx.Limit(1).Find(&shared, "id = ?", cveID).Error; err != nil {
+			return fmt.Errorf("failed to load %s: %w", cveID, err)
+		}
+		if record != nil {
+			nvd.ApplyRecord(&shared, record)
+		}
+		shared.NVDEnrichedAt = &now
+		if err := tx.Save(&shared).Error; err != nil {
+			return fmt.Errorf("failed to save %s: %w", cveID, err)
+		}
+
+		var findings []models.CVEFinding
+		if err := w.pendingCVEs(tx).Where("cve_id = ?", cveID).Find(&findings).Error; err != nil {
+			return fmt.Errorf("failed to load findings of %s: %w", cveID, err)
+		}
+
+		projects := make(map[string]bool)
+		for i := range findings {
+			finding := &findings[i]
+			if record != nil {
+				nvd.Apply(finding, record)
+				if finding.MatchStatus == models.MatchUnverified {
+					if err := verifyMatch(tx, finding, record); err != nil {
+						return err
+					}
+				}
+			}
+			finding.NVDEnrichedAt = &now
+			if err := tx.Save(finding).Error; err != nil {
+				return fmt.Errorf("failed to save finding of %s: %w", cveID, err)
+			}
+			projects[finding.ProjectID] = true
+		}
+		if record == nil {
+			return nil
+		}
+
+		for projectID := range projects {
+			if err := risk.Rescore(tx, projectID); err != nil {
+				return err
+			}
+			if err := risk.Recount(tx, projectID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// verifyMatch checks the component of a CVE finding against NVD's
+// applicability statements of the CVE: the SBOM component it was linked to,
+// or its software name and version
+func verifyMatch(tx *gorm.DB, finding *models.CVEFinding, record *nvd.CVE) error {
+	component := models.SBOMComponent{Name: finding.SoftwareName, Version: finding.SoftwareVersion}
+	if finding.ComponentID != nil {
+		if err := tx.Limit(1).Find(&component, "id = ?", *finding.ComponentID).Error; err != nil {
+			return fmt.Errorf("failed to load component of finding %d: %w", finding.ID, err)
+		}
+	}
+	finding.MatchStatus, finding.MatchReason = record.Verify(&component)
+	// NVD's applicability statement is better evidence than EMBA's match
+	if criteria, versions := record.Evidence(&component); criteria != "" {
+		finding.MatchCriteria, finding.MatchVersionRange = criteria, versions
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/attack"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/freeze"
+	"odin-backend/internal/models"
+	"odin-backend/internal/osint"
+	"odin-backend/internal/risk"
+	"odin-backend/internal/webhook"
+
+	"gorm.io/gorm"
+)
+
+// gatherOSINT looks the analyzed firmware up in the OSINT providers the
+// project selects and adds what they found to the results. Failures leave
+// the results as they are: OSINT is enrichment, not part of the analysis.
+func (w *Worker) gatherOSINT(project *models.Project, result *emba.AnalysisResult) {
+	providers := w.osint.For(project)
+	if len(providers) == 0 {
+		return
+	}
+	if err := w.updateProjectStatus(project, models.StatusOSINT, "Gathering OSINT intelligence..."); err != nil {
+		log.Printf("Failed to update status of project %s: %v", project.ID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.OSINTTimeout)
+	defer cancel()
+
+	collectedAt := time.Now().UTC()
+	project.OSINTCollectedAt = &collectedAt
+
+	cache := w.osintCache
+	if project.OSINTRefresh {
+		cache = cache.Refreshing()
+	}
+	subject := osint.Subject{
+		Project:      project,
+		Certificates: result.Results.KeyMaterials,
+		Components:   result.Results.Components,
+		Files:        result.Results.Files,
+		Cache:        cache,
+		Budget:       w.osintBudget,
+	}
+	for _, provider := range providers {
+		found := provider.Enrich(ctx, subject)
+		result.Results.OSINTResults = append(result.Results.OSINTResults, found.Results...)
+		result.Results.Findings = append(result.Results.Findings, found.Findings...)
+		for key, value := range found.Summary {
+			result.Results.Summary[key] = value
+		}
+	}
+	// Providers finding the same certificate, device or component
+	// corroborate each other
+	w.osintScorer.Score(result.Results.OSINTResults)
+
+	project.OSINTDeferredUntil = w.osintDeferral(providers)
+	if project.OSINTDeferredUntil != nil {
+		log.Printf("OSINT of project %s deferred to %s: a provider's daily quota is exhausted", project.ID, project.OSINTDeferredUntil.Format(time.RFC3339))
+	}
+}
+
+// osintDeferral returns when to collect OSINT again because one of the
+// providers ran out of its daily quota, nil when none did
+func (w *Worker) osintDeferral(providers []osint.Provider) *time.Time {
+	for _, provider := range providers {
+		if w.osintBudget.Exhausted(provider.Name()) {
+			resetsAt := osint.ResetsAt()
+			return &resetsAt
+		}
+	}
+	return nil
+}
+
+// osintRefreshBatch is how many projects a refresh pass collects OSINT for
+const osintRefreshBatch = 10
+
+// RunOSINTRefresh collects the OSINT of completed projects again every
+// OSINT_REFRESH_INTERVAL, and once their deferral ends for those a provider's
+// exhausted quota deferred, until the process exits, checking for due
+// projects every minute. It does nothing when no provider is enabled.
+func (w *Worker) RunOSINTRefresh() {
+	if len(w.osint.Providers()) == 0 {
+		return
+	}
+
+	if w.config.OSINTRefreshInterval > 0 {
+		log.Printf("Refreshing the OSINT of completed projects every %s", w.config.OSINTRefreshInterval)
+	}
+	for {
+		if w.config.OSINTRefreshInterval > 0 {
+			if err := w.RefreshOSINT(); err != nil {
+				log.Printf("Error refreshing OSINT: %v", err)
+			}
+		}
+		if err := w.CollectDeferredOSINT(); err != nil {
+			log.Printf("Error collecting deferred OSINT: %v", err)
+		}
+		time.Sleep(time.Minute)
+	}
+}
+
+// CollectDeferredOSINT collects the OSINT of the completed projects whose
+// deferral ended, a batch at a time, until none are due. A project whose
+// providers are still out of quota is deferred again.
+func (w *Worker) CollectDeferredOSINT() error {
+	for {
+		var projects []models.Project
+		err := w.db.Where("status = ? AND frozen_at IS NULL AND diff_base_id = ''", models.StatusCompleted).
+			Where("osint_deferred_until <= ?", time.Now().UTC()).
+			Order("osint_deferred_until").Limit(osintRefreshBatch).Find(&projects).Error
+		if err != nil {
+			return fmt.Errorf("failed to load projects with deferred OSINT: %w", err)
+		}
+		if len(projects) == 0 {
+			return nil
+		}
+		for i := range projects {
+			project := &projects[i]
+			// Claim the project, so another worker doesn't collect it too
+			claimed := w.db.Model(&models.Project{}).Where("id = ? AND osint_deferred_until = ?", project.ID, *project.OSINTDeferredUntil).
+				Update("osint_deferred_until", nil)
+			if claimed.Error != nil {
+				return fmt.Errorf("failed to claim project: %w", claimed.Error)
+			}
+			if claimed.RowsAffected == 0 {
+				continue
+			}
+			project.OSINTDeferredUntil = nil
+			if err := w.refreshProjectOSINT(project); err != nil {
+				log.Printf("Failed to collect deferred OSINT of project %s: %v", project.ID, err)
+			}
+		}
+	}
+}
+
+// RefreshOSINT collects the OSINT of the completed projects whose last
+// collection is older than OSINT_REFRESH_INTERVAL, a batch at a time, until
+// none are due. Frozen projects and diff scans are left alone.
+func (w *Worker) RefreshOSINT() error {
+	for {
+		due := time.Now().UTC().Add(-w.config.OSINTRefreshInterval)
+		var projects []models.Project
+		err := w.db.Where("status = ? AND frozen_at IS NULL AND diff_base_id = ''", models.StatusCompleted).
+			Where("osint_collected_at < ? OR (osint_collected_at IS NULL AND completed_at < ?)", due, due).
+			Order("osint_collected_at, completed_at").Limit(osintRefreshBatch).Find(&projects).Error
+		if err != nil {
+			return fmt.Errorf("failed to load projects due for OSINT: %w", err)
+		}
+		if len(projects) == 0 {
+			return nil
+		}
+		for i := range projects {
+			if err := w.refreshProjectOSINT(&projects[i]); err != nil {
+				log.Printf("Failed to refresh OSINT of project %s: %v", projects[i].ID, err)
+			}
+		}
+	}
+}
+
+// refreshProjectOSINT collects a project's OSINT again from what its
+// analysis stored, keeps the new results as a collection of their own, adds
+// the findings the project doesn't have yet and notifies subscribers when
+// the exposure changed materially
+func (w *Worker) refreshProjectOSINT(project *models.Project) error {
+	previous := project.OSINTCollectedAt
+	collectedAt := time.Now().UTC()
+
+	// Claim the project, so another worker doesn't collect it too
+	claim := w.db.Model(&models.Project{}).Where("id = ?", project.ID)
+	if previous == nil {
+		claim = claim.Where("osint_collected_at IS NULL")
+	} else {
+		claim = claim.Where("osint_collected_at = ?", *previous)
+	}
+	claimed := claim.Update("osint_collected_at", collectedAt)
+	if claimed.Error != nil {
+		return fmt.Errorf("failed to claim project: %w", claimed.Error)
+	}
+	if claimed.RowsAffected == 0 {
+		return nil
+	}
+	project.OSINTCollectedAt = &collectedAt
+
+	providers := w.osint.For(project)
+	if len(providers) == 0 {
+		return nil
+	}
+	subject := osint.Subject{Project: project, Cache: w.osintCache, Budget: w.osintBudget}
+	if err := w.db.Where("project_id = ?", project.ID).Find(&subject.Components).Error; err != nil {
+		return fmt.Errorf("failed to load SBOM components: %w", err)
+	}
+	if err := w.db.Where("project_id = ?", project.ID).Find(&subject.Certificates).Error; err != nil {
+		return fmt.Errorf("failed to load key material: %w", err)
+	}
+	if err := w.db.Where("project_id = ?", project.ID).Find(&subject.Files).Error; err != nil {
+		return fmt.Errorf("failed to load file manifest: %w", err)
+	}
+
+	var before []models.OSINTResult
+	query := w.db.Where("project_id = ?", project.ID)
+	if previous != nil {
+		query = query.Where("collected_at = ?", *previous)
+	} else {
+		query = query.Where("collected_at IS NULL")
+	}
+	if err := query.Find(&before).Error; err != nil {
+		return fmt.Errorf("failed to load previous OSINT results: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.OSINTTimeout)
+	defer cancel()
+	var results []models.OSINTResult
+	var findings []models.Finding
+	for _, provider := range providers {
+		found := provider.Enrich(ctx, subject)
+		results = append(results, found.Results...)
+		findings = append(findings, found.Findings...)
+	}
+	w.osintScorer.Score(results)
+	if deferredUntil := w.osintDeferral(providers); deferredUntil != nil {
+		if err := w.db.Model(&models.Project{}).Where("id = ?", project.ID).
+			Update("osint_deferred_until", deferredUntil).Error; err != nil {
+			return fmt.Errorf("failed to defer OSINT: %w", err)
+		}
+		log.Printf("OSINT of project %s deferred to %s: a provider's daily quota is exhausted", project.ID, deferredUntil.Format(time.RFC3339))
+	}
+
+	var added []models.Finding
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		// The project may have been frozen while the providers ran
+		if err := freeze.Check(tx, project.ID); err != nil {
+			return err
+		}
+
+		// Results from before collections were dated belong to the analysis
+		if previous == nil && project.CompletedAt != nil {
+			if err := tx.Model(&models.OSINTResult{}).Where("project_id = ? AND collected_at IS NULL", project.ID).
+				Update("collected_at", *project.CompletedAt).Error; err != nil {
+				return err
+			}
+		}
+		for i := range results {
+			results[i].ID = 0
+			results[i].ProjectID = project.ID
+			results[i].CollectedAt = &collectedAt
+			if err := tx.Create(&results[i]).Error; err != nil {
+				return fmt.Errorf("failed to save OSINT result: %w", err)
+			}
+		}
+
+		for _, finding := range findings {
+			var known int64
+			if err := tx.Model(&models.Finding{}).Where("project_id = ? AND fingerprint = ?", project.ID, finding.Fingerprint).
+				Count(&known).Error; err != nil {
+				return err
+			}
+			if known > 0 {
+				continue
+			}
+			finding.ProjectID = project.ID
+			finding.Techniques = attack.Tag(&finding)
+			if err := tx.Create(&finding).Error; err != nil {
+				return fmt.Errorf("failed to save finding: %w", err)
+			}
+			added = append(added, finding)
+		}
+		if len(added) == 0 {
+			return nil
+		}
+		return risk.Recount(tx, project.ID)
+	})
+	if errors.Is(err, freeze.ErrFrozen) {
+		log.Printf("Dropped the refreshed OSINT of project %s: its results were frozen meanwhile", project.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	exposureBefore, exposureAfter := osint.Exposure(before), osint.Exposure(results)
+	log.Printf("Refreshed OSINT of project %s: %d results, %d new findings", project.ID, len(results), len(added))
+	if len(added) > 0 || osint.MaterialChange(exposureBefore, exposureAfter) {
+		previousAt := previous
+		if previousAt == nil {
+			previousAt = project.CompletedAt
+		}
+		w.webhooks.NotifyExposure(project.ID, webhook.ExposureChange{
+			PreviousCollectedAt: previousAt,
+			CollectedAt:         collectedAt,
+			Before:              exposureBefore,
+			After:               exposureAfter,
+		}, added)
+	}
+	return nil
+}
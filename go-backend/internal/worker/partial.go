@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+
+	"gorm.io/gorm"
+)
+
+// partialSaver persists the results of modules as EMBA finishes them, so a
+// run that crashes late keeps what it found and the UI can show results
+// while the scan is live
+type partialSaver struct {
+	db        *gorm.DB
+	projectID string
+	modules   []string
+}
+
+// newPartialSaver removes partial results a previous attempt left behind
+func newPartialSaver(db *gorm.DB, projectID string) (*partialSaver, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := clearPartialResults(tx, projectID); err != nil {
+			return err
+		}
+		return tx.Model(&models.Project{}).Where("id = ?", projectID).UpdateColumn("finished_modules", "").Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &partialSaver{db: db, projectID: projectID}, nil
+}
+
+// save is the emba.AnalysisOptions.OnModulesFinished callback
+func (p *partialSaver) save(partial *emba.PartialResults) {
+	modules := append(p.modules, partial.Modules...)
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		for _, finding := range partial.Findings {
+			finding.ProjectID = p.projectID
+			finding.Partial = true
+			if err := tx.Create(&finding).Error; err != nil {
+				return fmt.Errorf("failed to save finding: %w", err)
+			}
+		}
+		for _, cve := range partial.CVEs {
+			cve.ProjectID = p.projectID
+			cve.Partial = true
+			if err := tx.Create(&cve).Error; err != nil {
+				return fmt.Errorf("failed to save CVE finding: %w", err)
+			}
+		}
+
+		var project models.Project
+		counts, err := risk.CountProject(tx, p.projectID)
+		if err != nil {
+			return err
+		}
+		risk.ApplyCounts(&project, counts)
+		return tx.Model(&models.Project{}).Where("id = ?", p.projectID).UpdateColumns(map[string]interface{}{
+			"finished_modules": strings.Join(modules, ","),
+			"finding_count":    project.FindingCount,
+			"cve_count":        project.CVECount,
+			"critical_count":   project.CriticalCount,
+			"high_count":       project.HighCount,
+			"medium_count":     project.MediumCount,
+			"low_count":        project.LowCount,
+			"info_count":       project.InfoCount,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("Failed to save partial results of %s for project %s: %v", strings.Join(partial.Modules, ", "), p.projectID, err)
+		return
+	}
+	p.modules = modules
+}
+
+// apply copies what was saved onto the project, whose later saves would
+// otherwise overwrite it
+func (p *partialSaver) apply(project *models.Project) {
+	project.FinishedModules = strings.Join(p.modules, ",")
+	if len(p.modules) == 0 {
+		return
+	}
+	counts, err := risk.CountProject(p.db, p.projectID)
+	if err != nil {
+		log.Printf("Failed to count partial results for project %s: %v", p.projectID, err)
+		return
+	}
+	risk.ApplyCounts(project, counts)
+}
+
+// clearPartialResults deletes the results saved while EMBA was running
+func clearPartialResults(tx *gorm.DB, projectID string) error {
+	if err := tx.Where("project_id = ? AND partial = ?", projectID, true).Delete(&models.Finding{}).Error; err != nil {
+		return fmt.Errorf("failed to clear partial findings: %w", err)
+	}
+	if err := tx.Where("project_id = ? AND partial = ?", projectID, true).Delete(&models.CVEFinding{}).Error; err != nil {
+		return fmt.Errorf("failed to clear partial CVE findings: %w", err)
+	}
+	return nil
+}
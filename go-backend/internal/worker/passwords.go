@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/attack"
+	"odin-backend/internal/cracker"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+
+	"gorm.io/gorm"
+)
+
+const crackPollInterval = time.Minute
+
+// RunPasswordCracker tries the pending password hashes of completed analyses
+// against the wordlist until the process exits. It does nothing unless
+// PASSWORD_CRACKER is set.
+func (w *Worker) RunPasswordCracker() {
+	c := cracker.New(w.config)
+	if c == nil {
+		return
+	}
+
+	log.Printf("Password cracker %s checking for pending hashes every %s", c.Name(), crackPollInterval)
+	for {
+		if err := w.CrackPendingHashes(c); err != nil {
+			log.Printf("Error cracking password hashes: %v", err)
+		}
+		time.Sleep(crackPollInterval)
+	}
+}
+
+// CrackPendingHashes cracks the pending hashes one project at a time until
+// none are left
+func (w *Worker) CrackPendingHashes(c cracker.Cracker) error {
+	for {
+		hashes, err := w.claimPasswordHashes()
+		if err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return nil
+		}
+		if err := w.crackProjectHashes(c, hashes); err != nil {
+			log.Printf("Failed to crack password hashes of project %s: %v", hashes[0].ProjectID, err)
+		}
+	}
+}
+
+// claimPasswordHashes marks the pending hashes of one completed project as
+// running so no other worker cracks them, and returns them. Hashes whose
+// cracker died are claimed again once they've been running for twice the
+// timeout.
+func (w *Worker) claimPasswordHashes() ([]models.PasswordHash, error) {
+	now := time.Now().UTC()
+	claimable := func(db *gorm.DB) *gorm.DB {
+		return db.Where("crack_status = ? OR (crack_status = ? AND crack_checked_at < ?)",
+			models.CrackPending, models.CrackRunning, now.Add(-2*w.config.PasswordCrackTimeout)).
+			Where("project_id IN (?)", w.db.Model(&models.Project{}).Select("id").Where("status = ?", models.StatusCompleted))
+	}
+
+	var next models.PasswordHash
+	err := claimable(w.db.Model(&models.PasswordHash{})).Order("id").First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending password hashes: %w", err)
+	}
+
+	result := claimable(w.db.Model(&models.PasswordHash{})).Where("project_id = ?", next.ProjectID).
+		UpdateColumns(map[string]interface{}{"crack_status": models.CrackRunning, "crack_checked_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to claim password hashes: %w", result.Error)
+	}
+
+	var hashes []models.PasswordHash
+	if err := w.db.Where("project_id = ? AND crack_status = ? AND crack_checked_at = ?", next.ProjectID, models.CrackRunning, now).
+		Order("id").Find(&hashes).Error; err != nil {
+		return nil, fmt.Errorf("failed to load claimed password hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// crackProjectHashes cracks a project's hashes algorithm by algorithm and
+// records every cracked password as a critical finding: it is a default
+// credential of every device running the firmware
+func (w *Worker) crackProjectHashes(c cracker.Cracker, hashes []models.PasswordHash) error {
+	projectID := hashes[0].ProjectID
+
+	byAlgorithm := make(map[string][]*models.PasswordHash)
+	var algorithms []string
+	for i := range hashes {
+		hash := &hashes[i]
+		if !c.Supports(hash.Algorithm) {
+			hash.CrackStatus = models.CrackUnsupported
+			continue
+		}
+		if _, ok := byAlgorithm[hash.Algorithm]; !ok {
+			algorithms = append(algorithms, hash.Algorithm)
+		}
+		byAlgorithm[hash.Algorithm] = append(byAlgorithm[hash.Algorithm], hash)
+	}
+
+	for _, algorithm := range algorithms {
+		group := byAlgorithm[algorithm]
+		var values []string
+		for _, hash := range group {
+			values = append(values, hash.Hash)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), w.config.PasswordCrackTimeout)
+		cracked, err := c.Crack(ctx, algorithm, values)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			// The wordlist wasn't exhausted; what john or hashcat found so far is lost
+			log.Printf("Cracking %d %s hashes of project %s timed out after %s", len(group), algorithm, projectID, w.config.PasswordCrackTimeout)
+		} else if err != nil {
+			log.Printf("Cracking %d %s hashes of project %s failed: %v", len(group), algorithm, projectID, err)
+		}
+
+		for _, hash := range group {
+			password, ok := cracked[hash.Hash]
+			switch {
+			case ok:
+				hash.CrackStatus = models.CrackCracked
+				hash.CrackedPassword = password
+			case err != nil:
+				hash.CrackStatus = models.CrackFailed
+			default:
+				hash.CrackStatus = models.CrackNotCracked
+			}
+		}
+	}
+
+	var project models.Project
+	if err := w.db.First(&project, "id = ?", projectID).Error; err != nil {
+		return fmt.Errorf("failed to load project: %w", err)
+	}
+
+	found := 0
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		for i := range hashes {
+			hash := &hashes[i]
+			hash.CrackCheckedAt = &now
+
+			// Frozen results stay as delivered
+			if hash.CrackStatus == models.CrackCracked && !project.Frozen() {
+				finding := emba.CrackedPasswordFinding(hash, c.Name())
+				finding.ProjectID = projectID
+				finding.Techniques = attack.Tag(&finding)
+				if err := tx.Create(&finding).Error; err != nil {
+					return fmt.Errorf("failed to save finding: %w", err)
+				}
+				hash.FindingID = &finding.ID
+				found++
+			}
+			if err := tx.Save(hash).Error; err != nil {
+				return fmt.Errorf("failed to save password hash: %w", err)
+			}
+		}
+		if found == 0 {
+			return nil
+		}
+
+		counts, err := risk.CountProject(tx, projectID)
+		if err != nil {
+			return err
+		}
+		risk.ApplyCounts(&project, counts)
+		return tx.Model(&models.Project{}).Where("id = ?", projectID).UpdateColumns(map[string]interface{}{
+			"risk_level":     risk.Level(counts),
+			"finding_count":  project.FindingCount,
+			"critical_count": project.CriticalCount,
+			"high_count":     project.HighCount,
+			"medium_count":   project.MediumCount,
+			"low_count":      project.LowCount,
+			"info_count":     project.InfoCount,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Cracked %d of %d password hashes of project %s", found, len(hashes), projectID)
+	return nil
+}
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"odin-backend/internal/emba"
+	"odin-backend/internal/extract"
+	"odin-backend/internal/mcu"
+	"odin-backend/internal/models"
+	"odin-backend/internal/rtos"
+)
+
+// projectPlatform is what is known of the project's platform before it is
+// analyzed: the header's hints, or what an earlier analysis found
+func projectPlatform(project *models.Project) emba.Platform {
+	return emba.Platform{Architecture: project.Architecture, OSFamily: project.OSFamily}
+}
+
+// recordPlatform sets the project's architecture and OS family from what
+// the analysis found. EMBA's detection, the executables of a tree Odin
+// extracted, an MCU image, a container image's config or a detected RTOS
+// replace the header's hints; where none of them tell, the hints stay.
+func recordPlatform(project *models.Project, results *emba.ParsedResults) {
+	fileInfo := results.FileInfo
+	platform := emba.PlatformOf(fileInfo)
+	// Extracted Linux filesystems are full of ELF executables
+	if inventory, ok := results.Summary["inventory"].(extract.Inventory); ok && platform.Architecture == "" && inventory.Architecture != "" {
+		platform.Architecture = emba.NormalizeArchitecture(inventory.Architecture, inventory.Endianness)
+		if platform.OSFamily == "" {
+			platform.OSFamily = "linux"
+		}
+	}
+	if info, ok := fileInfo["mcu"].(mcu.Info); ok {
+		platform.Architecture = emba.NormalizeArchitecture(info.Architecture, "")
+		platform.OSFamily = "bare-metal"
+	}
+	if container, ok := fileInfo["container"].(*extract.ContainerInfo); ok {
+		platform.Architecture = emba.NormalizeArchitecture(container.Architecture, "")
+		platform.OSFamily = emba.NormalizeOS(container.OS)
+	}
+	if _, ok := fileInfo["android"].(*extract.AndroidInfo); ok {
+		platform.OSFamily = "android"
+	}
+	if detected, ok := fileInfo["rtos"].(*rtos.Detection); ok {
+		platform.OSFamily = emba.NormalizeOS(detected.Name)
+	}
+
+	if platform.Architecture != "" {
+		project.Architecture = platform.Architecture
+	}
+	if platform.OSFamily != "" {
+		project.OSFamily = platform.OSFamily
+	}
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/version"
+)
+
+const registryHeartbeatInterval = 30 * time.Second
+
+// Register records this worker process in the workers table and keeps its
+// heartbeat, host load and drain state up to date in the background
+func (w *Worker) Register() error {
+	hostname, _ := os.Hostname()
+	now := time.Now().UTC()
+
+	w.id = fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	record := models.Worker{
+		ID:              w.id,
+		Hostname:        hostname,
+		PID:             os.Getpid(),
+		Version:         version.Version,
+		Load:            hostLoad(),
+		StartedAt:       now,
+		LastHeartbeatAt: now,
+	}
+	if err := w.db.Save(&record).Error; err != nil {
+		return fmt.Errorf("failed to register worker: %w", err)
+	}
+
+	log.Printf("Registered worker %s (version %s)", w.id, version.Version)
+
+	go func() {
+		ticker := time.NewTicker(registryHeartbeatInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			w.sendHeartbeat()
+		}
+	}()
+
+	return nil
+}
+
+// sendHeartbeat refreshes the worker's heartbeat and host load
+func (w *Worker) sendHeartbeat() {
+	err := w.db.Model(&models.Worker{}).Where("id = ?", w.id).Updates(map[string]interface{}{
+		"last_heartbeat_at": time.Now().UTC(),
+		"load":              hostLoad(),
+	}).Error
+	if err != nil {
+		log.Printf("Failed to send worker heartbeat: %v", err)
+	}
+}
+
+// setCurrentJob records which project this worker is processing
+func (w *Worker) setCurrentJob(projectID string) {
+	if w.id == "" {
+		return
+	}
+	if err := w.db.Model(&models.Worker{}).Where("id = ?", w.id).
+		Update("current_project_id", projectID).Error; err != nil {
+		log.Printf("Failed to record current job for worker %s: %v", w.id, err)
+	}
+}
+
+// draining reports whether an operator asked this worker to stop taking new jobs
+func (w *Worker) draining() bool {
+	if w.id == "" {
+		return false
+	}
+	var record models.Worker
+	if err := w.db.Select("draining").First(&record, "id = ?", w.id).Error; err != nil {
+		return false
+	}
+	return record.Draining
+}
+
+// hostLoad returns the 1 minute load average, or 0 where /proc/loadavg is unavailable
+func hostLoad() float64 {
+	content, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, _ := strconv.ParseFloat(fields[0], 64)
+	return load
+}
@@ -0,0 +1,152 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"odin-backend/internal/models"
+)
+
+// ResourceSnapshot is the host's free capacity when a job was considered
+type ResourceSnapshot struct {
+	FreeMemoryMB int64   `json:"free_memory_mb"`
+	FreeDiskMB   int64   `json:"free_disk_mb"`
+	LoadPerCPU   float64 `json:"load_per_cpu"`
+}
+
+// SchedulingDecision records why a job was started or deferred
+type SchedulingDecision struct {
+	Deferred  bool             `json:"deferred"`
+	Heavy     bool             `json:"heavy"`
+	Reason    string           `json:"reason,omitempty"`
+	Resources ResourceSnapshot `json:"resources"`
+	CheckedAt time.Time        `json:"checked_at"`
+}
+
+// isHeavy reports whether analyzing the project needs the heavy resource
+// thresholds, i.e. runs emulation or a profile configured as heavy
+func (w *Worker) isHeavy(project *models.Project) bool {
+	if w.config.EMBAEnableEmulation {
+		if emulatable, _ := projectPlatform(project).Emulatable(); emulatable {
+			return true
+		}
+	}
+	for _, profile := range w.config.HeavyScanProfiles {
+		if profile == w.config.EMBAScanProfile {
+			return true
+		}
+	}
+	return false
+}
+
+// checkResources decides whether the host currently has room for the project
+func (w *Worker) checkResources(project *models.Project) SchedulingDecision {
+	decision := SchedulingDecision{
+		Heavy:     w.isHeavy(project),
+		Resources: currentResources(w.config.EMBALogDir),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	minMemory := w.config.MinFreeMemoryMB
+	if decision.Heavy && w.config.HeavyMinFreeMemoryMB > minMemory {
+		minMemory = w.config.HeavyMinFreeMemoryMB
+	}
+
+	var reasons []string
+	res := decision.Resources
+	if minMemory > 0 && res.FreeMemoryMB >= 0 && res.FreeMemoryMB < minMemory {
+		reasons = append(reasons, fmt.Sprintf("%d MB memory free, %d MB required", res.FreeMemoryMB, minMemory))
+	}
+	if w.config.MinFreeDiskMB > 0 && res.FreeDiskMB >= 0 && res.FreeDiskMB < w.config.MinFreeDiskMB {
+		reasons = append(reasons, fmt.Sprintf("%d MB disk free, %d MB required", res.FreeDiskMB, w.config.MinFreeDiskMB))
+	}
+	if decision.Heavy && w.config.MaxLoadPerCPU > 0 && res.LoadPerCPU > w.config.MaxLoadPerCPU {
+		reasons = append(reasons, fmt.Sprintf("load %.2f per CPU, limit %.2f", res.LoadPerCPU, w.config.MaxLoadPerCPU))
+	}
+
+	if len(reasons) > 0 {
+		decision.Deferred = true
+		decision.Reason = strings.Join(reasons, "; ")
+	}
+	return decision
+}
+
+// recordDeferral stores a deferral in the pending project's status so users
+// can see why their job isn't starting
+func (w *Worker) recordDeferral(project *models.Project, decision SchedulingDecision) {
+	message := "Waiting for resources: " + decision.Reason
+
+	extraction := project.ExtractionData()
+	previous, _ := extraction["status_message"].(string)
+	extraction["status_message"] = message
+	extraction["scheduling"] = decision
+	project.SetExtractionData(extraction)
+
+	// Only log and touch the row when the reason changes, not on every poll
+	if previous == message {
+		return
+	}
+	log.Printf("Deferring project %s: %s", project.ID, decision.Reason)
+
+	if err := w.db.Model(&models.Project{}).
+		Where("id = ? AND status = ?", project.ID, models.StatusPending).
+		UpdateColumn("extraction_results", project.ExtractionResults).Error; err != nil {
+		log.Printf("Failed to record deferral for project %s: %v", project.ID, err)
+	}
+}
+
+// currentResources samples free memory, free disk under dir and load per CPU.
+// Values that can't be read are reported as -1 and don't block jobs.
+func currentResources(dir string) ResourceSnapshot {
+	snapshot := ResourceSnapshot{
+		FreeMemoryMB: availableMemoryMB(),
+		FreeDiskMB:   -1,
+		LoadPerCPU:   hostLoad() / float64(runtime.NumCPU()),
+	}
+
+	// The log directory may not exist yet; check the closest existing parent
+	for {
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(dir, &stat); err == nil {
+			snapshot.FreeDiskMB = int64(stat.Bavail) * int64(stat.Bsize) / (1 << 20)
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return snapshot
+}
+
+// availableMemoryMB reads MemAvailable from /proc/meminfo
+func availableMemoryMB() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return -1
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return -1
+			}
+			return kb / 1024
+		}
+	}
+	return -1
+}
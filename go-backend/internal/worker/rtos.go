@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/fwformat"
+	"odin-backend/internal/models"
+	"odin-backend/internal/rtos"
+)
+
+// detectRTOS looks for an RTOS in the uploaded image and adds the known CVEs
+// of its version to the results. The image itself is scanned, not the
+// extracted tree: Linux firmware often carries RTOS based blobs for its
+// radios, which aren't what the project runs.
+func (w *Worker) detectRTOS(project *models.Project, result *emba.AnalysisResult) {
+	image := project.FilePath
+	if project.FirmwareType == fwformat.IntelHex {
+		image = filepath.Join(result.LogDir, "firmware", mcuFlashImage)
+	}
+	if _, err := os.Stat(image); err != nil {
+		return
+	}
+
+	detected, err := rtos.Detect(image)
+	if err != nil {
+		log.Printf("RTOS detection for project %s failed: %v", project.ID, err)
+		return
+	}
+	if detected == nil {
+		return
+	}
+	result.Results.FileInfo["rtos"] = detected
+	result.Results.Summary["rtos"] = detected.Summary()
+
+	known := make(map[string]bool, len(result.Results.CVEs))
+	for _, cve := range result.Results.CVEs {
+		known[cve.CVEID] = true
+	}
+	for _, cve := range rtos.CVEs(detected, "/"+filepath.Base(project.Filename)) {
+		if !known[cve.CVEID] {
+			result.Results.CVEs = append(result.Results.CVEs, cve)
+		}
+	}
+	log.Printf("Detected %s in project %s", detected.Summary(), project.Name)
+}
@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+	"odin-backend/internal/scanner"
+)
+
+// scanSecrets adds the secrets Odin's own rules find in the extracted
+// firmware to the results, along with its keys and certificates. A failed
+// scan leaves EMBA's results as they are.
+func (w *Worker) scanSecrets(project *models.Project, result *emba.AnalysisResult) {
+	root := filepath.Join(result.LogDir, "firmware")
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return
+	}
+
+	ctx := context.Background()
+	if w.config.SecretScanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.config.SecretScanTimeout)
+		defer cancel()
+	}
+
+	findings, err := w.secrets.Scan(ctx, root)
+	if err != nil {
+		log.Printf("Secret scan of project %s failed: %v", project.ID, err)
+		return
+	}
+	result.Results.Findings = append(result.Results.Findings, findings...)
+	result.Results.Summary["secrets_found"] = len(findings)
+
+	keys, findings, err := w.secrets.ScanKeys(ctx, root)
+	if err != nil {
+		log.Printf("Key analysis of project %s failed: %v", project.ID, err)
+		return
+	}
+	findings = append(findings, w.reusedKeys(project, keys)...)
+	result.Results.KeyMaterials = keys
+	result.Results.Findings = append(result.Results.Findings, findings...)
+	result.Results.Summary["key_materials_found"] = len(keys)
+}
+
+// reusedKeys returns a finding for every private key that was also found in
+// the firmware of other projects
+func (w *Worker) reusedKeys(project *models.Project, keys []models.KeyMaterial) []models.Finding {
+	var findings []models.Finding
+	reported := make(map[string]bool)
+	for _, key := range keys {
+		if key.Kind != models.KeyKindPrivateKey || key.Fingerprint == "" || reported[key.Fingerprint] {
+			continue
+		}
+		var projects []string
+		if err := w.db.Model(&models.KeyMaterial{}).
+			Where("kind = ? AND fingerprint = ? AND project_id <> ?", models.KeyKindPrivateKey, key.Fingerprint, project.ID).
+			Distinct().Pluck("project_id", &projects).Error; err != nil {
+			log.Printf("Failed to look up reuse of key %s: %v", key.Fingerprint, err)
+			continue
+		}
+		if len(projects) > 0 {
+			reported[key.Fingerprint] = true
+			findings = append(findings, scanner.ReusedKeyFinding(key, projects))
+		}
+	}
+	return findings
+}
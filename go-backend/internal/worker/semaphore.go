@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	analysisSlotKey  = "odin:emba:slots"
+	analysisSlotTTL  = 5 * time.Minute
+	slotPollInterval = 15 * time.Second
+)
+
+// acquireScript drops expired leases, then grants a slot if the holder
+// already owns one or fewer than the limit are taken
+var acquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZSCORE', KEYS[1], ARGV[3]) then
+	redis.call('ZADD', KEYS[1], ARGV[4], ARGV[3])
+	return 1
+end
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('ZADD', KEYS[1], ARGV[4], ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// Semaphore is a Redis-backed counting semaphore shared by all workers.
+// Each holder owns a lease that expires unless refreshed, so slots held by
+// a crashed worker are reclaimed automatically.
+type Semaphore struct {
+	client *redis.Client
+	key    string
+	limit  int
+	ttl    time.Duration
+}
+
+// NewSemaphore creates a semaphore allowing at most limit concurrent holders
+func NewSemaphore(redisURL, key string, limit int, ttl time.Duration) *Semaphore {
+	return &Semaphore{
+		client: redis.NewClient(&redis.Options{Addr: redisURL}),
+		key:    key,
+		limit:  limit,
+		ttl:    ttl,
+	}
+}
+
+// TryAcquire attempts to take a slot for holder without blocking
+func (s *Semaphore) TryAcquire(ctx context.Context, holder string) (bool, error) {
+	now := time.Now()
+	granted, err := acquireScript.Run(ctx, s.client, []string{s.key},
+		now.UnixMilli(), s.limit, holder, now.Add(s.ttl).UnixMilli()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire slot: %w", err)
+	}
+	return granted == 1, nil
+}
+
+// Refresh extends the lease held by holder
+func (s *Semaphore) Refresh(ctx context.Context, holder string) error {
+	expiry := time.Now().Add(s.ttl).UnixMilli()
+	if err := s.client.ZAddXX(ctx, s.key, redis.Z{Score: float64(expiry), Member: holder}).Err(); err != nil {
+		return fmt.Errorf("failed to refresh slot: %w", err)
+	}
+	return nil
+}
+
+// Release gives up the slot held by holder
+func (s *Semaphore) Release(ctx context.Context, holder string) error {
+	if err := s.client.ZRem(ctx, s.key, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release slot: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying Redis connection
+func (s *Semaphore) Close() error {
+	return s.client.Close()
+}
+
+// slotLimiter caps how many EMBA runs may happen at once
+type slotLimiter interface {
+	TryAcquire(ctx context.Context, holder string) (bool, error)
+	Refresh(ctx context.Context, holder string) error
+	Release(ctx context.Context, holder string) error
+}
+
+// LocalSemaphore is an in-process counting semaphore for the embedded worker,
+// where all analyses run in a single process and Redis isn't available
+type LocalSemaphore struct {
+	mu      sync.Mutex
+	limit   int
+	holders map[string]bool
+}
+
+// NewLocalSemaphore creates a semaphore allowing at most limit concurrent holders
+func NewLocalSemaphore(limit int) *LocalSemaphore {
+	return &LocalSemaphore{limit: limit, holders: make(map[string]bool)}
+}
+
+// TryAcquire attempts to take a slot for holder without blocking
+func (s *LocalSemaphore) TryAcquire(_ context.Context, holder string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.holders[holder] {
+		return true, nil
+	}
+	if len(s.holders) >= s.limit {
+		return false, nil
+	}
+	s.holders[holder] = true
+	return true, nil
+}
+
+// Refresh is a no-op; local leases live as long as the process
+func (s *LocalSemaphore) Refresh(context.Context, string) error {
+	return nil
+}
+
+// Release gives up the slot held by holder
+func (s *LocalSemaphore) Release(_ context.Context, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.holders, holder)
+	return nil
+}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/firmwarefs"
+	"odin-backend/internal/models"
+)
+
+// labelSlots handles images with an A/B update layout, whose two copies of
+// the firmware EMBA and Odin analyze alike: every finding is labeled with
+// the slot it was found in, and a finding both copies have is reported once
+// for both instead of twice. The slots, how far they differ and their
+// finding counts go into the summary.
+func labelSlots(result *emba.AnalysisResult) {
+	layout := firmwarefs.Slots(result.Results.Files)
+	if layout == nil {
+		return
+	}
+	result.Results.Summary["slots"] = layout
+
+	kept := result.Results.Findings[:0]
+	seen := make(map[string]int)
+	for _, finding := range result.Results.Findings {
+		slot, rel := layout.SlotOf(finding.FilePath)
+		if slot == "" {
+			kept = append(kept, finding)
+			continue
+		}
+		key := strings.Join([]string{string(finding.Type), layout.Unslotted(finding.Title), rel, strconv.Itoa(finding.LineNumber)}, "|")
+		if i, ok := seen[key]; ok {
+			if !strings.Contains(","+kept[i].Slot+",", ","+slot+",") {
+				kept[i].Slot += "," + slot
+			}
+			continue
+		}
+		finding.Slot = slot
+		seen[key] = len(kept)
+		kept = append(kept, finding)
+	}
+	result.Results.Findings = kept
+	result.Results.Summary["slot_findings"] = slotFindings(kept)
+}
+
+// slotFindings counts the findings of each slot of an A/B image
+func slotFindings(findings []models.Finding) map[string]int {
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		for _, slot := range strings.Split(finding.Slot, ",") {
+			if slot != "" {
+				counts[slot]++
+			}
+		}
+	}
+	return counts
+}
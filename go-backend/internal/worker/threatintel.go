@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"odin-backend/internal/attack"
+	"odin-backend/internal/emba"
+	"odin-backend/internal/integrations"
+	"odin-backend/internal/models"
+	"odin-backend/internal/risk"
+	"odin-backend/internal/threatintel"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// threatFeedPollTimeout bounds a poll of one feed
+	threatFeedPollTimeout = 10 * time.Minute
+
+	// hashLookupChunk bounds the hashes of one file lookup query
+	hashLookupChunk = 500
+)
+
+// matchThreatIntel raises a finding for every indicator of the threat feeds
+// among the hashes of the extracted files and the addresses the findings
+// quote. Failures leave the results as they are.
+func (w *Worker) matchThreatIntel(project *models.Project, result *emba.AnalysisResult) {
+	candidates := threatintel.Candidates(result.Results.Files, result.Results.Findings)
+	if len(candidates) == 0 {
+		return
+	}
+	matches, err := threatintel.Lookup(w.db, candidates)
+	if err != nil {
+		log.Printf("Failed to match threat intel of project %s: %v", project.ID, err)
+		return
+	}
+	findings := threatintel.Findings(matches, candidates)
+	result.Results.Findings = append(result.Results.Findings, findings...)
+	result.Results.Summary["threat_intel_matches"] = len(findings)
+}
+
+// RunThreatFeeds polls the enabled threat feeds every
+// THREAT_FEED_POLL_INTERVAL until the process exits, checking for due feeds
+// every minute. It does nothing when the interval is 0.
+func (w *Worker) RunThreatFeeds() {
+	if w.config.ThreatFeedPollInterval <= 0 {
+		return
+	}
+
+	log.Printf("Polling threat feeds every %s", w.config.ThreatFeedPollInterval)
+	for {
+		if err := w.PollThreatFeeds(); err != nil {
+			log.Printf("Error polling threat feeds: %v", err)
+		}
+		time.Sleep(time.Minute)
+	}
+}
+
+// PollThreatFeeds polls the enabled feeds not polled for
+// THREAT_FEED_POLL_INTERVAL, and the ones never polled
+func (w *Worker) PollThreatFeeds() error {
+	due := time.Now().UTC().Add(-w.config.ThreatFeedPollInterval)
+	var feeds []models.ThreatFeed
+	if err := w.db.Where("enabled = ? AND (last_polled_at IS NULL OR last_polled_at < ?)", true, due).
+		Order("last_polled_at, id").Find(&feeds).Error; err != nil {
+		return fmt.Errorf("failed to load threat feeds: %w", err)
+	}
+	for i := range feeds {
+		if err := w.pollThreatFeed(&feeds[i]); err != nil {
+			log.Printf("Failed to poll threat feed %s: %v", feeds[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// pollThreatFeed ingests the indicators a feed's collection added since the
+// last poll and matches the new file hashes against the completed analyses
+func (w *Worker) pollThreatFeed(feed *models.ThreatFeed) error {
+	previous := feed.LastPolledAt
+	polledAt := time.Now().UTC()
+
+	// Claim the feed, so another worker doesn't poll it too
+	claim := w.db.Model(&models.ThreatFeed{}).Where("id = ?", feed.ID)
+	if previous == nil {
+		claim = claim.Where("last_polled_at IS NULL")
+	} else {
+		claim = claim.Where("last_polled_at = ?", *previous)
+	}
+	claimed := claim.Update("last_polled_at", polledAt)
+	if claimed.Error != nil {
+		return fmt.Errorf("failed to claim feed: %w", claimed.Error)
+	}
+	if claimed.RowsAffected == 0 {
+		return nil
+	}
+
+	page, err := w.fetchThreatFeed(feed)
+	if err != nil {
+		w.db.Model(&models.ThreatFeed{}).Where("id = ?", feed.ID).Update("last_error", err.Error())
+		return err
+	}
+
+	stored := 0
+	err = w.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if stored, err = threatintel.Ingest(tx, feed.ID, page.Indicators); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.ThreatIndicator{}).Where("feed_id = ?", feed.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ThreatFeed{}).Where("id = ?", feed.ID).UpdateColumns(map[string]interface{}{
+			"added_after":     page.AddedAfter,
+			"last_error":      "",
+			"indicator_count": count,
+		}).Error
+	})
+	if err != nil {
+		w.db.Model(&models.ThreatFeed{}).Where("id = ?", feed.ID).Update("last_error", err.Error())
+		return err
+	}
+	log.Printf("Polled threat feed %s: %d indicators, %d observables stored", feed.Name, len(page.Indicators), stored)
+
+	var hashes []string
+	for _, indicator := range page.Indicators {
+		for _, observable := range indicator.Observables {
+			if observable.Type == threatintel.TypeSHA256 {
+				hashes = append(hashes, observable.Value)
+			}
+		}
+	}
+	return w.matchStoredFiles(hashes)
+}
+
+// fetchThreatFeed polls a feed's collection for what it added since the
+// last poll
+func (w *Worker) fetchThreatFeed(feed *models.ThreatFeed) (threatintel.Page, error) {
+	password := ""
+	if feed.Password != "" {
+		var err error
+		if password, err = integrations.New(w.db, w.config).Open(feed.Password); err != nil {
+			return threatintel.Page{}, fmt.Errorf("failed to decrypt password: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), threatFeedPollTimeout)
+	defer cancel()
+	return threatintel.NewClient().Poll(ctx, threatintel.Collection{
+		APIRoot:  feed.APIRoot,
+		ID:       feed.CollectionID,
+		Username: feed.Username,
+		Password: password,
+	}, feed.AddedAfter)
+}
+
+// matchStoredFiles adds findings to the completed analyses whose extracted
+// files have one of the hashes a feed just listed. Frozen projects are left
+// alone.
+func (w *Worker) matchStoredFiles(hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	byProject := make(map[string][]models.FirmwareFile)
+	for start := 0; start < len(hashes); start += hashLookupChunk {
+		end := min(start+hashLookupChunk, len(hashes))
+		var files []models.FirmwareFile
+		if err := w.db.Joins("JOIN projects ON projects.id = firmware_files.project_id").
+			Where("projects.status = ? AND projects.frozen_at IS NULL", models.StatusCompleted).
+			Where("firmware_files.sha256 IN ?", hashes[start:end]).Find(&files).Error; err != nil {
+			return fmt.Errorf("failed to look up file hashes: %w", err)
+		}
+		for _, file := range files {
+			byProject[file.ProjectID] = append(byProject[file.ProjectID], file)
+		}
+	}
+
+	for projectID, files := range byProject {
+		candidates := threatintel.Candidates(files, nil)
+		matches, err := threatintel.Lookup(w.db, candidates)
+		if err != nil {
+			return err
+		}
+		findings := threatintel.Findings(matches, candidates)
+
+		added := 0
+		err = w.db.Transaction(func(tx *gorm.DB) error {
+			for _, finding := range findings {
+				var known int64
+				if err := tx.Model(&models.Finding{}).Where("project_id = ? AND fingerprint = ?", projectID, finding.Fingerprint).
+					Count(&known).Error; err != nil {
+					return err
+				}
+				if known > 0 {
+					continue
+				}
+				finding.ProjectID = projectID
+				finding.Techniques = attack.Tag(&finding)
+				if err := tx.Create(&finding).Error; err != nil {
+					return fmt.Errorf("failed to save finding: %w", err)
+				}
+				added++
+			}
+			if added == 0 {
+				return nil
+			}
+			return risk.Recount(tx, projectID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save threat intel findings of project %s: %w", projectID, err)
+		}
+		if added > 0 {
+			log.Printf("Threat feeds matched %d files of project %s", added, projectID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+)
+
+// unblobFirmware extracts a project's firmware with unblob and returns the
+// extracted tree, which EMBA analyzes instead of running its own extractor
+func (w *Worker) unblobFirmware(ctx context.Context, project *models.Project) (string, error) {
+	if err := w.updateProjectStatus(project, models.StatusExtracting, "Extracting firmware with unblob..."); err != nil {
+		return "", queue.Transient(fmt.Errorf("failed to update project status: %w", err))
+	}
+
+	dir := filepath.Join(w.config.WorkDir, fmt.Sprintf("job_%s", project.ID), "unblob")
+	if err := clearWorkDir(dir); err != nil {
+		return "", err
+	}
+
+	if err := w.extractor.Unblob(ctx, project.FilePath, dir); err != nil {
+		return "", unpackFailure(ctx, err, "unblob extracted no files from the firmware; the image format may be unsupported or encrypted", queue.Transient)
+	}
+	return dir, nil
+}
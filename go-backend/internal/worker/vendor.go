@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"odin-backend/internal/extract"
+	"odin-backend/internal/models"
+	"odin-backend/internal/queue"
+)
+
+// unwrapFirmware strips the vendor container (Netgear CHK, Broadcom TRX,
+// MikroTik NPK) of a project's firmware and returns the payload EMBA is
+// handed instead, and the containers. The header of an upload a decryptor
+// decrypted is recorded as the outer container.
+func (w *Worker) unwrapFirmware(project *models.Project) (string, *extract.VendorContainer, error) {
+	dir := filepath.Join(w.config.WorkDir, fmt.Sprintf("job_%s", project.ID), "payload")
+	if err := clearWorkDir(dir); err != nil {
+		return "", nil, err
+	}
+
+	var outer *extract.VendorContainer
+	if project.EncryptedFilePath != "" {
+		if _, encrypted, err := extract.Unwrap(project.EncryptedFilePath, dir); err == nil {
+			outer = encrypted
+		}
+	}
+	if !extract.IsVendorContainer(project.FirmwareType) {
+		return "", outer, nil
+	}
+
+	payload, container, err := extract.Unwrap(project.FilePath, dir)
+	if errors.Is(err, extract.ErrChecksumMismatch) {
+		return "", nil, queue.Permanent(fmt.Errorf("%v. The upload is truncated or corrupt", err))
+	}
+	// A header that doesn't parse may be a false match; EMBA gets the image
+	if err != nil {
+		log.Printf("Failed to unwrap firmware of project %s, analyzing it as uploaded: %v", project.ID, err)
+		return "", outer, nil
+	}
+	if outer != nil {
+		outer.Inner = container
+		container = outer
+	}
+	return payload, container, nil
+}
@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"odin-backend/internal/vulndb"
+)
+
+// RunVulnDBSync synchronizes the local vulnerability data once every
+// VULNDB_SYNC_INTERVAL until the process exits, checking every minute
+// whether the interval's sync is due. It does nothing unless VULNDB_SYNC is
+// on; admins can still start a sync.
+func (w *Worker) RunVulnDBSync() {
+	if !w.config.VulnDBSync {
+		return
+	}
+
+	log.Printf("Synchronizing the vulnerability database every %s", w.config.VulnDBSyncInterval)
+	for {
+		if err := vulndb.SyncDue(context.Background(), w.db, w.config); err != nil {
+			log.Printf("Error synchronizing the vulnerability database: %v", err)
+		}
+		time.Sleep(time.Minute)
+	}
+}
@@ -1,79 +1,431 @@
 package worker
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"odin-backend/internal/attack"
 	"odin-backend/internal/config"
+	"odin-backend/internal/decrypt"
 	"odin-backend/internal/emba"
+	"odin-backend/internal/exploit"
+	"odin-backend/internal/extract"
+	"odin-backend/internal/ghsa"
+	"odin-backend/internal/mcu"
+	"odin-backend/internal/mirror"
 	"odin-backend/internal/models"
+	"odin-backend/internal/osint"
+	"odin-backend/internal/osint/providers"
+	"odin-backend/internal/queue"
+	"odin-backend/internal/risk"
+	"odin-backend/internal/rtos"
+	"odin-backend/internal/scanner"
+	"odin-backend/internal/suppress"
+	"odin-backend/internal/verdict"
+	"odin-backend/internal/webhook"
+	"odin-backend/internal/yara"
+	"os"
+	"path/filepath"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+const pollInterval = 10 * time.Second
+
+// errJobCancelled stops an analysis whose project was deleted while it ran
+var errJobCancelled = errors.New("analysis cancelled: project was deleted")
+
 type Worker struct {
-	db     *gorm.DB
-	config *config.Config
-	emba   *emba.Service
+	id          string // registry ID, set by Register
+	db          *gorm.DB
+	config      *config.Config
+	emba        *emba.Service
+	verdicts    *verdict.Aggregator
+	exploits    *exploit.Lookup
+	mirror      *mirror.Mirror
+	advisories  *ghsa.Lookup
+	extractor   *extract.Extractor
+	secrets     *scanner.Scanner
+	yara        *yara.Scanner
+	decryptors  *decrypt.Registry
+	osint       *osint.Registry
+	osintCache  *osint.Cache
+	osintScorer *osint.Scorer
+	osintBudget *osint.Budget
+	slots       slotLimiter
+	webhooks    *webhook.Dispatcher
+	retries     queue.RetryPolicy
 }
 
 func New(db *gorm.DB, cfg *config.Config) *Worker {
 	embaService := emba.New(cfg)
-	return &Worker{
-		db:     db,
-		config: cfg,
-		emba:   embaService,
+	w := &Worker{
+		db:          db,
+		config:      cfg,
+		emba:        embaService,
+		verdicts:    verdict.New(cfg),
+		exploits:    exploit.New(cfg),
+		mirror:      mirror.New(db, cfg),
+		advisories:  ghsa.New(cfg),
+		extractor:   extract.New(cfg),
+		secrets:     scanner.New(cfg),
+		yara:        yara.New(cfg),
+		decryptors:  decrypt.New(cfg),
+		osint:       providers.New(db, cfg),
+		osintCache:  osint.NewCache(db, cfg),
+		osintScorer: osint.NewScorer(cfg),
+		osintBudget: osint.NewBudget(db, cfg),
+		webhooks:    webhook.New(db),
+		retries:     queue.NewRetryPolicy(cfg),
 	}
-}
 
-// ProcessPendingJobs polls for pending analysis jobs and processes them
-func (w *Worker) ProcessPendingJobs() error {
-	var projects []models.Project
-	
-	// Find projects that are pending analysis
-	if err := w.db.Where("status = ?", models.StatusPending).Find(&projects).Error; err != nil {
-		return fmt.Errorf("failed to query pending projects: %w", err)
+	// Limit concurrent EMBA runs across all workers sharing this Redis, or
+	// within this process when running embedded without Redis
+	if cfg.EMBAMaxConcurrent > 0 {
+		if cfg.EmbeddedWorker {
+			w.slots = NewLocalSemaphore(cfg.EMBAMaxConcurrent)
+		} else if cfg.RedisURL != "" {
+			w.slots = NewSemaphore(cfg.RedisURL, analysisSlotKey, cfg.EMBAMaxConcurrent, analysisSlotTTL)
+		}
 	}
 
-	for _, project := range projects {
-		log.Printf("Processing pending project: %s (ID: %d)", project.Name, project.ID)
-		if err := w.processProject(&project); err != nil {
-			log.Printf("Failed to process project %d: %v", project.ID, err)
-			w.updateProjectStatus(&project, models.StatusFailed, fmt.Sprintf("Processing failed: %v", err))
+	return w
+}
+
+// Run polls for pending jobs until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		w.runPendingInstall(ctx)
+		if err := w.ProcessPendingJobs(ctx); err != nil {
+			log.Printf("Error processing jobs: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
 	}
+}
 
-	return nil
+// ProcessPendingJobs processes pending analysis jobs until none can start.
+// Cancelling ctx stops the running EMBA analysis and requeues its project.
+func (w *Worker) ProcessPendingJobs(ctx context.Context) error {
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Stop picking up new work once an operator drains this worker
+		if w.draining() {
+			log.Printf("Worker %s is draining, not starting new jobs", w.id)
+			return nil
+		}
+
+		// Let a requested EMBA installation run before the next analysis
+		if w.installPending() {
+			log.Printf("EMBA installation pending on this host, not starting new jobs")
+			return nil
+		}
+
+		project, err := w.nextJob()
+		if err != nil {
+			return err
+		}
+		if project == nil {
+			return nil
+		}
+
+		log.Printf("Processing pending project: %s (ID: %s)", project.Name, project.ID)
+		if err := w.processProject(ctx, project); err != nil {
+			if ctx.Err() != nil {
+				// Worker shutdown: another worker picks the project up again
+				w.updateProjectStatus(project, models.StatusPending, "Requeued after worker shutdown")
+				return nil
+			}
+			if errors.Is(err, errJobCancelled) {
+				log.Printf("Analysis of project %s was cancelled", project.ID)
+				continue
+			}
+			log.Printf("Failed to process project %s: %v", project.ID, err)
+			if w.scheduleRetry(project, err) {
+				continue
+			}
+			w.updateProjectStatus(project, models.StatusFailed, fmt.Sprintf("Processing failed: %v", err))
+		}
+
+		// Notify subscribers of the outcome without holding up the queue
+		go w.webhooks.Notify(project.ID)
+	}
 }
 
 // processProject processes a single firmware analysis project
-func (w *Worker) processProject(project *models.Project) error {
+func (w *Worker) processProject(ctx context.Context, project *models.Project) error {
 	log.Printf("Starting firmware analysis for project %s", project.Name)
 
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Let the janitor know this project is being worked on; the heartbeat
+	// cancels the analysis if the project is deleted meanwhile
+	stopHeartbeat := w.startHeartbeat(project.ID, func() { cancel(errJobCancelled) })
+	defer stopHeartbeat()
+
+	w.setCurrentJob(project.ID)
+	defer w.setCurrentJob("")
+
+	// Imported logs of a past run only need parsing
+	if project.IngestLogDir != "" {
+		return w.Ingest(project)
+	}
+
+	// Diff scans compare two images that were checked when they were uploaded
+	firmwarePath, diffFirmware := project.FilePath, ""
+	var vendor *extract.VendorContainer
+	if project.DiffBaseID != "" {
+		basePath, err := w.diffBasePath(project)
+		if err != nil {
+			return err
+		}
+		firmwarePath, diffFirmware = basePath, project.FilePath
+	} else {
+		// Check the upload against antivirus/threat-intel engines before analyzing it
+		if err := w.runMalwareCheck(ctx, project); err != nil {
+			return err
+		}
+
+		// Analyze the payload of images encrypted with a scheme Odin knows
+		if err := w.decryptFirmware(ctx, project); err != nil {
+			return err
+		}
+		firmwarePath = project.FilePath
+
+		// Don't spend an EMBA run on an image that can't be unpacked
+		if err := w.rejectEncrypted(project); err != nil {
+			return err
+		}
+
+		// Vendor containers are stripped so EMBA sees the payload they wrap
+		payload, container, err := w.unwrapFirmware(project)
+		if err != nil {
+			return err
+		}
+		if payload != "" {
+			defer os.RemoveAll(filepath.Dir(payload))
+			firmwarePath = payload
+		}
+		vendor = container
+	}
+
+	// Bare-metal MCU images have no filesystem for EMBA's modules to look at
+	if diffFirmware == "" && mcu.IsMCU(project.FilePath, project.FirmwareType) {
+		return w.analyzeMCU(ctx, project)
+	}
+
+	// Without EMBA the filesystem is still worth unpacking and checking
+	if !w.emba.IsAvailable() {
+		if diffFirmware != "" {
+			return queue.Permanent(fmt.Errorf("diff scans need EMBA, which is not available"))
+		}
+		return w.extractOnly(ctx, project, firmwarePath, vendor)
+	}
+
+	// Wait for a free EMBA slot before starting the heavy part of the analysis
+	release, err := w.acquireAnalysisSlot(ctx, project)
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		return queue.Transient(fmt.Errorf("failed to acquire analysis slot: %w", err))
+	}
+	defer release()
+
+	// Projects extracted with unblob hand EMBA the extracted tree
+	extracted := ""
+	if project.Extractor == extract.MethodUnblob && diffFirmware == "" {
+		extracted, err = w.unblobFirmware(ctx, project)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(filepath.Dir(extracted))
+		firmwarePath = extracted
+	}
+
+	// EMBA doesn't know Android's image formats or container images: Odin
+	// unpacks them first
+	var android *extract.AndroidInfo
+	var container *extract.ContainerInfo
+	if extracted == "" && diffFirmware == "" {
+		switch {
+		case extract.IsAndroid(project.FilePath, project.FirmwareType):
+			extracted, android, err = w.unpackAndroid(ctx, project)
+			project.Extractor = extract.MethodAndroid
+		case extract.IsContainerImage(project.FilePath, project.FirmwareType):
+			extracted, container, err = w.unpackContainer(ctx, project)
+			project.Extractor = extract.MethodContainer
+		}
+		if err != nil {
+			return err
+		}
+		if extracted != "" {
+			defer os.RemoveAll(filepath.Dir(extracted))
+			firmwarePath = extracted
+		}
+	}
+
 	// Update status to analyzing
 	if err := w.updateProjectStatus(project, models.StatusAnalyzing, "Running EMBA firmware analysis..."); err != nil {
-		return fmt.Errorf("failed to update project status: %w", err)
+		return queue.Transient(fmt.Errorf("failed to update project status: %w", err))
 	}
 
-	// Run EMBA analysis
-	result, err := w.emba.AnalyzeFirmware(project.FilePath, fmt.Sprintf("job_%d", project.ID))
+	// Record what the results will depend on besides the firmware
+	w.recordEnvironment(project)
+
+	// Run EMBA analysis. Setup failures (EMBA missing, log dir not writable) are
+	// worth retrying; a failed EMBA run is classified by the retry policy.
+	modules, err := emba.ParseModules(project.Modules)
+	if err != nil {
+		return queue.Permanent(fmt.Errorf("invalid module selection: %w", err))
+	}
+	excluded, err := emba.ParseModules(project.ExcludedModules)
+	if err != nil {
+		return queue.Permanent(fmt.Errorf("invalid module exclusion: %w", err))
+	}
+	partial, err := newPartialSaver(w.db, project.ID)
+	if err != nil {
+		return queue.Transient(fmt.Errorf("failed to clear partial results: %w", err))
+	}
+	result, err := w.emba.AnalyzeFirmware(ctx, firmwarePath, fmt.Sprintf("job_%s", project.ID), emba.AnalysisOptions{
+		Modules:           modules,
+		ExcludedModules:   excluded,
+		DiffFirmware:      diffFirmware,
+		Platform:          projectPlatform(project),
+		OnModulesFinished: partial.save,
+	})
+	partial.apply(project)
 	if err != nil {
 		log.Printf("EMBA analysis failed for project %s: %v", project.Name, err)
-		w.updateProjectStatus(project, models.StatusFailed, fmt.Sprintf("EMBA analysis failed: %v", err))
-		return fmt.Errorf("EMBA analysis failed: %w", err)
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		// A run that hit EMBA_TIMEOUT would only time out again
+		if errors.Is(err, context.DeadlineExceeded) {
+			return queue.Permanent(err)
+		}
+		return queue.Transient(fmt.Errorf("EMBA analysis failed: %w", err))
 	}
 
 	if !result.Success {
 		log.Printf("EMBA analysis unsuccessful for project %s: %s", project.Name, result.Error)
-		w.updateProjectStatus(project, models.StatusFailed, fmt.Sprintf("EMBA analysis failed: %s", result.Error))
 		return fmt.Errorf("EMBA analysis failed: %s", result.Error)
 	}
 
-	// Parse and save EMBA results
+	// unblob or Odin, not EMBA, extracted the image
+	if extracted != "" {
+		if err := recordExtraction(&result.Results, extracted, project.Extractor); err != nil {
+			return queue.Transient(err)
+		}
+	}
+	if android != nil {
+		result.Results.FileInfo["android"] = android
+	}
+	if container != nil {
+		result.Results.FileInfo["container"] = container
+	}
+	if vendor != nil {
+		result.Results.ExtractionInfo["vendor_container"] = vendor
+	}
+
+	if err := w.completeAnalysis(project, result, "EMBA analysis completed successfully"); err != nil {
+		return err
+	}
+
+	log.Printf("EMBA analysis completed successfully for project %s", project.Name)
+	return nil
+}
+
+// completeAnalysis saves the parsed results, rates the project and marks it completed
+func (w *Worker) completeAnalysis(project *models.Project, result *emba.AnalysisResult, message string) error {
+	// Diff mode doesn't extract the images the way a scan does. An RTOS image
+	// with known CVEs is worth reporting even if nothing could be extracted.
+	if project.DiffBaseID == "" {
+		w.detectRTOS(project, result)
+		if err := checkExtraction(project, &result.Results); err != nil {
+			log.Printf("Analysis of project %s has nothing to report: %v", project.Name, err)
+			return err
+		}
+	}
+
+	// Odin's own analyses of the extracted firmware: the secret rules and key
+	// analysis supplement EMBA's grep-based modules, the default credentials
+	// vendors document are cross-referenced, YARA rule sets find
+	// known backdoors, and binary hashes and the file manifest serve
+	// searches across projects
+	if project.DiffBaseID == "" {
+		if w.secrets != nil {
+			w.scanSecrets(project, result)
+		}
+		w.checkDefaultCredentials(project, result)
+		if w.yara != nil {
+			w.scanYara(project, result)
+		}
+		if w.config.FuzzyHash {
+			w.hashBinaries(project, result)
+		}
+		w.recordManifest(project, result)
+		labelSlots(result)
+	}
+
+	// Indicators of the threat feeds among the extracted files and the
+	// addresses the firmware hardcodes
+	if project.DiffBaseID == "" {
+		w.matchThreatIntel(project, result)
+	}
+
+	// Advisories of the npm, PyPI, ... packages in the SBOM, which EMBA's
+	// CPE-based matching misses
+	if w.advisories != nil && project.DiffBaseID == "" {
+		found := w.advisories.Find(result.Results.Components, result.Results.CVEs)
+		result.Results.CVEs = append(result.Results.CVEs, found...)
+		result.Results.Summary["ghsa_findings"] = len(found)
+	}
+
+	// Public exploits EMBA's exploit aggregation doesn't know of
+	if w.exploits != nil {
+		w.exploits.Enrich(result.Results.CVEs)
+	}
+	// and, offline, the exploits and KEV listings of the imported mirrors
+	if w.mirror != nil {
+		if err := w.mirror.Enrich(result.Results.CVEs); err != nil {
+			log.Printf("Mirror lookup for project %s failed: %v", project.ID, err)
+		}
+	}
+
+	// Devices running the firmware on the internet, and what antivirus
+	// engines make of it
+	if project.DiffBaseID == "" {
+		w.gatherOSINT(project, result)
+	}
+
+	// Save the parsed EMBA results. Parsing is done; what fails here is the
+	// database (locked, connection lost), which a retry may get past.
 	if err := w.saveAnalysisResults(project, result); err != nil {
 		log.Printf("Failed to save analysis results for project %s: %v", project.Name, err)
-		w.updateProjectStatus(project, models.StatusFailed, fmt.Sprintf("Failed to save results: %v", err))
-		return fmt.Errorf("failed to save analysis results: %w", err)
+		return queue.Transient(fmt.Errorf("failed to save analysis results: %w", err))
+	}
+
+	// Accepted risks are left out of the rating
+	if err := suppress.Apply(w.db, project.ID); err != nil {
+		log.Printf("Failed to apply CVE suppressions to project %s: %v", project.ID, err)
+	}
+
+	// CVE scores adjusted to where the device is deployed
+	if err := risk.Rescore(w.db, project.ID); err != nil {
+		log.Printf("Failed to adjust CVE scores of project %s: %v", project.ID, err)
 	}
 
 	// Calculate risk level
@@ -83,24 +435,212 @@ func (w *Worker) processProject(project *models.Project) error {
 	// Mark as completed
 	now := time.Now()
 	project.CompletedAt = &now
-	if err := w.updateProjectStatus(project, models.StatusCompleted, "EMBA analysis completed successfully"); err != nil {
-		return fmt.Errorf("failed to update completion status: %w", err)
+	if err := w.updateProjectStatus(project, models.StatusCompleted, message); err != nil {
+		return queue.Transient(fmt.Errorf("failed to update completion status: %w", err))
 	}
-
-	log.Printf("EMBA analysis completed successfully for project %s", project.Name)
 	return nil
 }
 
+// recordEnvironment stores the fingerprint of the environment the project is analyzed in
+func (w *Worker) recordEnvironment(project *models.Project) {
+	env := w.emba.Environment()
+	if project.Modules != "" {
+		env.Options["selected_modules"] = project.Modules
+	}
+	if project.ExcludedModules != "" {
+		env.Options["excluded_modules"] = project.ExcludedModules
+	}
+	if project.Extractor == extract.MethodUnblob || project.Extractor == extract.MethodAndroid || project.Extractor == extract.MethodContainer {
+		env.Options["extractor"] = project.Extractor
+	}
+	encoded, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("Failed to encode environment for project %s: %v", project.ID, err)
+		return
+	}
+	project.Environment = string(encoded)
+	project.EnvironmentHash = env.Hash()
+	project.EMBAVersion = env.EMBAVersion
+	project.ParserLayout = env.ParserLayout
+	if err := w.db.Model(&models.Project{}).Where("id = ?", project.ID).UpdateColumns(map[string]interface{}{
+		"environment":      project.Environment,
+		"environment_hash": project.EnvironmentHash,
+	}).Error; err != nil {
+		log.Printf("Failed to record environment for project %s: %v", project.ID, err)
+	}
+}
+
+// claimProject atomically moves a pending project to extracting. It returns
+// false if another worker claimed it first.
+func (w *Worker) claimProject(project *models.Project) (bool, error) {
+	result := w.db.Model(&models.Project{}).
+		Where("id = ? AND status = ?", project.ID, models.StatusPending).
+		Update("status", models.StatusExtracting)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	project.Status = models.StatusExtracting
+	return true, nil
+}
+
+// scheduleRetry puts a project back into the queue after a transient failure,
+// delayed by the retry policy's backoff. It returns false when the failure is
+// permanent or the project has used up its retries.
+func (w *Worker) scheduleRetry(project *models.Project, err error) bool {
+	class := w.retries.Classify(err)
+
+	extraction := project.ExtractionData()
+	extraction["last_error"] = err.Error()
+	extraction["last_error_class"] = class
+	project.SetExtractionData(extraction)
+
+	if !w.retries.ShouldRetry(err, project.RetryCount) {
+		log.Printf("Not retrying project %s (%s failure, %d/%d retries used)", project.ID, class, project.RetryCount, w.retries.MaxRetries)
+		return false
+	}
+
+	delay := w.retries.Backoff(project.RetryCount)
+	next := time.Now().UTC().Add(delay)
+	project.RetryCount++
+	project.NextRetryAt = &next
+
+	log.Printf("Retrying project %s in %s (attempt %d/%d)", project.ID, delay, project.RetryCount, w.retries.MaxRetries)
+	message := fmt.Sprintf("Retry %d/%d scheduled in %s after transient failure: %v", project.RetryCount, w.retries.MaxRetries, delay, err)
+	if updateErr := w.updateProjectStatus(project, models.StatusPending, message); updateErr != nil {
+		log.Printf("Failed to schedule retry for project %s: %v", project.ID, updateErr)
+		return false
+	}
+	return true
+}
+
+// runMalwareCheck records per-engine verdicts and the aggregate disposition,
+// then applies the verdict policy. It returns an error when analysis is blocked.
+func (w *Worker) runMalwareCheck(ctx context.Context, project *models.Project) error {
+	if !w.verdicts.Enabled() {
+		return nil
+	}
+
+	if err := w.updateProjectStatus(project, project.Status, "Checking firmware with malware engines..."); err != nil {
+		return fmt.Errorf("failed to update project status: %w", err)
+	}
+
+	result := w.verdicts.Check(ctx, project.FilePath, project.FileHash)
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+
+	// A retried job checks again: its verdicts replace those of the last attempt
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", project.ID).Delete(&models.EngineVerdict{}).Error; err != nil {
+			return err
+		}
+		for _, engineResult := range result.Engines {
+			engineVerdict := models.EngineVerdict{
+				ProjectID:   project.ID,
+				Engine:      engineResult.Engine,
+				Disposition: engineResult.Disposition,
+				Signature:   engineResult.Signature,
+				Detail:      engineResult.Detail,
+			}
+			if err := tx.Create(&engineVerdict).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save engine verdicts: %w", err)
+	}
+	project.Disposition = result.Disposition
+
+	switch w.verdicts.Decide(result) {
+	case verdict.ActionBlock:
+		log.Printf("Analysis of project %s blocked by verdict policy: %s", project.ID, result.Summary())
+		return queue.Permanent(fmt.Errorf("analysis blocked: firmware %s", result.Summary()))
+	case verdict.ActionReview:
+		log.Printf("Project %s flagged for manual review: %s", project.ID, result.Summary())
+		project.NeedsReview = true
+	}
+
+	return w.db.Save(project).Error
+}
+
+// acquireAnalysisSlot blocks until this worker may start EMBA for the project.
+// The returned function releases the slot and must always be called. Waiting
+// stops with the cause of ctx when the job is cancelled or the worker stops.
+func (w *Worker) acquireAnalysisSlot(ctx context.Context, project *models.Project) (func(), error) {
+	if w.slots == nil {
+		return func() {}, nil
+	}
+
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), project.ID)
+	// The lease outlives a cancelled job long enough to be released
+	lease := context.Background()
+
+	ticker := time.NewTicker(slotPollInterval)
+	defer ticker.Stop()
+
+	waiting := false
+	for {
+		granted, err := w.slots.TryAcquire(ctx, holder)
+		if err != nil {
+			log.Printf("Analysis slot check failed for project %s: %v", project.ID, err)
+		} else if granted {
+			break
+		}
+
+		if !waiting {
+			waiting = true
+			log.Printf("Project %s waiting for analysis slot (limit %d)", project.ID, w.config.EMBAMaxConcurrent)
+			if err := w.updateProjectStatus(project, models.StatusAnalyzing, "Waiting for analysis slot"); err != nil {
+				return nil, err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-ticker.C:
+		}
+	}
+
+	// Keep the lease alive while EMBA runs
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(analysisSlotTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := w.slots.Refresh(lease, holder); err != nil {
+					log.Printf("Failed to refresh analysis slot for project %s: %v", project.ID, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		if err := w.slots.Release(lease, holder); err != nil {
+			log.Printf("Failed to release analysis slot for project %s: %v", project.ID, err)
+		}
+	}, nil
+}
+
 // updateProjectStatus updates the project status in database
 func (w *Worker) updateProjectStatus(project *models.Project, status models.ProjectStatus, message string) error {
 	project.Status = status
-	
+
 	// Update extraction results with status message
-	if project.ExtractionResults == nil {
-		project.ExtractionResults = make(map[string]interface{})
-	}
-	project.ExtractionResults["status_message"] = message
-	project.ExtractionResults["last_updated"] = time.Now().UTC()
+	extraction := project.ExtractionData()
+	extraction["status_message"] = message
+	extraction["last_updated"] = time.Now().UTC()
+	project.SetExtractionData(extraction)
 
 	return w.db.Save(project).Error
 }
@@ -115,37 +655,145 @@ func (w *Worker) saveAnalysisResults(project *models.Project, result *emba.Analy
 		}
 	}()
 
+	// The final results replace those saved while EMBA was running
+	if err := clearPartialResults(tx, project.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Save findings
 	for _, findingData := range result.Results.Findings {
 		finding := models.Finding{
-			ProjectID:       project.ID,
-			Type:            findingData.Type,
-			Title:           findingData.Title,
-			Description:     findingData.Description,
-			Severity:        findingData.Severity,
-			FilePath:        findingData.FilePath,
-			LineNumber:      findingData.LineNumber,
-			Content:         findingData.Content,
-			Context:         findingData.Context,
-			FindingMetadata: findingData.FindingMetadata,
+			ProjectID:        project.ID,
+			Type:             findingData.Type,
+			Title:            findingData.Title,
+			Description:      findingData.Description,
+			Severity:         findingData.Severity,
+			FilePath:         findingData.FilePath,
+			LineNumber:       findingData.LineNumber,
+			Content:          findingData.Content,
+			Context:          findingData.Context,
+			FindingMetadata:  findingData.FindingMetadata,
+			Fingerprint:      findingData.Fingerprint,
+			OccurrenceCount:  findingData.OccurrenceCount,
+			FileMode:         findingData.FileMode,
+			FileOwner:        findingData.FileOwner,
+			PermissionIssues: findingData.PermissionIssues,
+			Slot:             findingData.Slot,
+			Confidence:       findingData.Confidence,
+			Module:           findingData.Module,
+			SourceFile:       findingData.SourceFile,
+			SourceLine:       findingData.SourceLine,
+			CWE:              findingData.CWE,
 		}
+		finding.Techniques = attack.Tag(&finding)
 		if err := tx.Create(&finding).Error; err != nil {
 			tx.Rollback()
 			return fmt.Errorf("failed to save finding: %w", err)
 		}
 	}
 
+	// Save binary protections
+	for _, binary := range result.Results.Binaries {
+		binary.ID = 0
+		binary.ProjectID = project.ID
+		if err := tx.Create(&binary).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save binary analysis: %w", err)
+		}
+	}
+
+	// Save password hashes; the cracker picks up the pending ones
+	for _, hash := range result.Results.PasswordHashes {
+		hash.ID = 0
+		hash.ProjectID = project.ID
+		if err := tx.Create(&hash).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save password hash: %w", err)
+		}
+	}
+
+	// Save system emulation outcomes
+	for _, emulation := range result.Results.EmulationResults {
+		emulation.ID = 0
+		emulation.ProjectID = project.ID
+		if err := tx.Create(&emulation).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save emulation result: %w", err)
+		}
+	}
+
+	// Save keys and certificates
+	for _, key := range result.Results.KeyMaterials {
+		key.ID = 0
+		key.ProjectID = project.ID
+		if err := tx.Create(&key).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save key material: %w", err)
+		}
+	}
+
+	// Save binary hashes
+	for _, hash := range result.Results.BinaryHashes {
+		hash.ID = 0
+		hash.ProjectID = project.ID
+		if err := tx.Create(&hash).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save binary hash: %w", err)
+		}
+	}
+
+	// Save the file manifest, which can run to tens of thousands of files
+	for i := range result.Results.Files {
+		result.Results.Files[i].ID = 0
+		result.Results.Files[i].ProjectID = project.ID
+	}
+	if len(result.Results.Files) > 0 {
+		if err := tx.CreateInBatches(result.Results.Files, 500).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save file manifest: %w", err)
+		}
+	}
+
+	// Save SBOM components, then link the CVE findings to them
+	components := result.Results.Components
+	for i := range components {
+		components[i].ID = 0
+		components[i].ProjectID = project.ID
+		if err := tx.Create(&components[i]).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save SBOM component: %w", err)
+		}
+	}
+
 	// Save CVE findings
 	for _, cveData := range result.Results.CVEs {
 		cveFinding := models.CVEFinding{
-			ProjectID:       project.ID,
-			CVEID:          cveData.CVEID,
-			SoftwareName:   cveData.SoftwareName,
-			SoftwareVersion: cveData.SoftwareVersion,
-			Description:    cveData.Description,
-			SeverityScore:  cveData.SeverityScore,
-			SeverityLevel:  cveData.SeverityLevel,
-			References:     cveData.References,
+			ProjectID:         project.ID,
+			CVEID:             cveData.CVEID,
+			SoftwareName:      cveData.SoftwareName,
+			SoftwareVersion:   cveData.SoftwareVersion,
+			Description:       cveData.Description,
+			SeverityScore:     cveData.SeverityScore,
+			SeverityLevel:     cveData.SeverityLevel,
+			References:        cveData.References,
+			CVSSVector:        cveData.CVSSVector,
+			Source:            cveData.Source,
+			BinaryPath:        cveData.BinaryPath,
+			ExploitAvailable:  cveData.ExploitAvailable,
+			ExploitSources:    cveData.ExploitSources,
+			KnownExploited:    cveData.KnownExploited,
+			ExploitDBIDs:      cveData.ExploitDBIDs,
+			MetasploitModules: cveData.MetasploitModules,
+			PoCURLs:           cveData.PoCURLs,
+			CWEIDs:            cveData.CWEIDs,
+			PublishedAt:       cveData.PublishedAt,
+			LastModifiedAt:    cveData.LastModifiedAt,
+		}
+		if i, match := emba.MatchComponent(components, &cveFinding); i >= 0 {
+			cveFinding.ComponentID = &components[i].ID
+			cveFinding.ComponentMatch = match
+			cveFinding.MatchCriteria, cveFinding.MatchVersionRange = emba.MatchEvidence(&components[i], match, &cveFinding)
 		}
 		if err := tx.Create(&cveFinding).Error; err != nil {
 			tx.Rollback()
@@ -157,13 +805,17 @@ func (w *Worker) saveAnalysisResults(project *models.Project, result *emba.Analy
 	for _, osintData := range result.Results.OSINTResults {
 		osintResult := models.OSINTResult{
 			ProjectID:       project.ID,
-			Source:         osintData.Source,
-			Query:          osintData.Query,
-			Title:          osintData.Title,
-			Description:    osintData.Description,
-			URL:            osintData.URL,
-			Data:           osintData.Data,
+			Source:          osintData.Source,
+			Query:           osintData.Query,
+			Title:           osintData.Title,
+			Description:     osintData.Description,
+			URL:             osintData.URL,
+			Data:            osintData.Data,
 			ConfidenceScore: osintData.ConfidenceScore,
+			Specificity:     osintData.Specificity,
+			Entity:          osintData.Entity,
+			ScoreBreakdown:  osintData.ScoreBreakdown,
+			CollectedAt:     project.OSINTCollectedAt,
 		}
 		if err := tx.Create(&osintResult).Error; err != nil {
 			tx.Rollback()
@@ -172,18 +824,42 @@ func (w *Worker) saveAnalysisResults(project *models.Project, result *emba.Analy
 	}
 
 	// Update project with EMBA results
-	project.ExtractionResults = map[string]interface{}{
-		"emba_log_dir":    result.LogDir,
-		"analysis_time":   result.AnalysisTime,
-		"file_info":       result.Results.FileInfo,
-		"summary":         result.Results.Summary,
-		"emba_stdout":     result.Stdout,
-		"success":         result.Success,
+	project.EMBAVersion = result.EMBAVersion
+	project.ParserLayout = result.ParserLayout
+	project.SetExtractionData(map[string]interface{}{
+		"emba_log_dir":     result.LogDir,
+		"analysis_time":    result.AnalysisTime,
+		"file_info":        result.Results.FileInfo,
+		"summary":          result.Results.Summary,
+		"emba_stdout":      result.Stdout,
+		"emba_console_log": result.ConsoleLog,
+		"privilege_mode":   result.PrivilegeMode,
+		"skipped_modules":  result.SkippedModules,
+		"excluded_modules": result.ExcludedModules,
+		"extraction":       result.Results.ExtractionInfo["extraction"],
+		"vendor_container": result.Results.ExtractionInfo["vendor_container"],
+		"parser_layout":    result.ParserLayout,
+		"success":          result.Success,
+	})
+
+	if kernel, ok := result.Results.FileInfo["kernel"].(*emba.KernelInfo); ok {
+		project.KernelVersion = kernel.Version
+		project.KernelEOL = kernel.EOL
+		project.KernelEOLDate = kernel.EOLDate
+		project.KernelFailedChecks = kernel.FailedChecks
+		project.KernelVerifiedCVEs = len(kernel.VerifiedCVEs)
+	}
+	if detected, ok := result.Results.FileInfo["rtos"].(*rtos.Detection); ok {
+		project.RTOS = detected.Name
+		project.RTOSVersion = detected.Version
 	}
+	recordPlatform(project, &result.Results)
 
 	// Update firmware info if available
 	if result.Results.FileInfo != nil {
-		project.FirmwareInfo = result.Results.FileInfo
+		if fileInfo, err := json.Marshal(result.Results.FileInfo); err == nil {
+			project.FirmwareInfo = string(fileInfo)
+		}
 	}
 
 	if err := tx.Save(project).Error; err != nil {
@@ -195,54 +871,15 @@ func (w *Worker) saveAnalysisResults(project *models.Project, result *emba.Analy
 }
 
 // calculateRiskLevel calculates overall risk level based on findings
+// and refreshes the project's severity counters along the way
 func (w *Worker) calculateRiskLevel(project *models.Project) models.RiskLevel {
-	var findings []models.Finding
-	var cveFindings []models.CVEFinding
-
-	w.db.Where("project_id = ?", project.ID).Find(&findings)
-	w.db.Where("project_id = ?", project.ID).Find(&cveFindings)
-
-	// Count severity levels
-	criticalCount := 0
-	highCount := 0
-	mediumCount := 0
-
-	for _, finding := range findings {
-		switch finding.Severity {
-		case models.RiskCritical:
-			criticalCount++
-		case models.RiskHigh:
-			highCount++
-		case models.RiskMedium:
-			mediumCount++
-		}
-	}
-
-	for _, cve := range cveFindings {
-		switch cve.SeverityLevel {
-		case models.RiskCritical:
-			criticalCount++
-		case models.RiskHigh:
-			highCount++
-		case models.RiskMedium:
-			mediumCount++
-		}
-	}
-
-	// Determine overall risk
-	if criticalCount > 0 {
-		return models.RiskCritical
-	} else if highCount >= 3 {
-		return models.RiskCritical
-	} else if highCount > 0 {
-		return models.RiskHigh
-	} else if mediumCount >= 5 {
-		return models.RiskHigh
-	} else if mediumCount > 0 {
-		return models.RiskMedium
+	counts, err := risk.CountProject(w.db, project.ID)
+	if err != nil {
+		log.Printf("Failed to count findings for project %s: %v", project.ID, err)
 	}
+	risk.ApplyCounts(project, counts)
 
-	return models.RiskLow
+	return risk.Level(counts)
 }
 
 // mapFindingType maps EMBA finding types to our model types
@@ -278,6 +915,8 @@ func (w *Worker) mapRiskLevel(embaSeverity string) models.RiskLevel {
 		return models.RiskMedium
 	case "low":
 		return models.RiskLow
+	case "info", "informational":
+		return models.RiskInfo
 	default:
 		return models.RiskLow
 	}
@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+
+	"odin-backend/internal/emba"
+	"odin-backend/internal/models"
+	"odin-backend/internal/yara"
+)
+
+// scanYara adds the matches of the organization's enabled YARA rule sets
+// in the extracted firmware to the results. A failed scan leaves the
+// results as they are.
+func (w *Worker) scanYara(project *models.Project, result *emba.AnalysisResult) {
+	root := filepath.Join(result.LogDir, "firmware")
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return
+	}
+
+	var sets []models.YaraRuleSet
+	if err := w.db.Where("org_id = ? AND enabled = ?", project.OrgID, true).Order("id").Find(&sets).Error; err != nil {
+		log.Printf("Failed to load YARA rule sets for project %s: %v", project.ID, err)
+		return
+	}
+	if len(sets) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	if w.config.YaraScanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.config.YaraScanTimeout)
+		defer cancel()
+	}
+
+	matches, err := w.yara.Scan(ctx, root, sets)
+	if err != nil {
+		log.Printf("YARA scan of project %s failed: %v", project.ID, err)
+		return
+	}
+	result.Results.Findings = append(result.Results.Findings, yara.Findings(matches, sets)...)
+	result.Results.Summary["yara_matches"] = len(matches)
+}
@@ -0,0 +1,264 @@
+// Package yara scans extracted firmware with user supplied YARA rule sets,
+// running the yara command line tool so Odin needs no libyara to build
+package yara
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"odin-backend/internal/config"
+	"odin-backend/internal/models"
+)
+
+// Source is the metadata source of the findings of YARA matches
+const Source = "yara"
+
+const (
+	maxStringsPerMatch = 20  // offsets kept per match
+	maxMatchedData     = 128 // bytes of matched data shown per offset
+)
+
+// ErrUnavailable is returned when the yara tool isn't installed
+var ErrUnavailable = errors.New("yara is not installed")
+
+// Scanner runs the yara tool
+type Scanner struct {
+	path string
+}
+
+// New creates a scanner running the configured yara, or returns nil when
+// YARA scanning is disabled
+func New(cfg *config.Config) *Scanner {
+	if !cfg.YaraScan {
+		return nil
+	}
+	return &Scanner{path: cfg.YaraPath}
+}
+
+// StringMatch is a string of a rule that matched, at an offset of the file
+type StringMatch struct {
+	Offset     int64  `json:"offset"`
+	Identifier string `json:"identifier"`
+	Data       string `json:"data,omitempty"`
+}
+
+// Match is a rule of a rule set that matched a file
+type Match struct {
+	RuleSetID uint
+	Rule      string
+	Tags      []string
+	Path      string // relative to the scanned root
+	Strings   []StringMatch
+}
+
+// Validate compiles source and returns yara's error message if it doesn't
+// compile. It returns ErrUnavailable when yara isn't installed.
+func Validate(ctx context.Context, yaraPath, source string) error {
+	if _, err := exec.LookPath(yaraPath); err != nil {
+		return ErrUnavailable
+	}
+	dir, err := os.MkdirTemp("", "odin-yara-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	rules := filepath.Join(dir, "rules.yar")
+	if err := os.WriteFile(rules, []byte(source), 0600); err != nil {
+		return err
+	}
+
+	// Any file will do as a target, the rules only need to compile
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, yaraPath, "-w", rules, rules)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(strings.ReplaceAll(stderr.String(), rules, "rules"))
+		if message == "" {
+			message = err.Error()
+		}
+		return errors.New(message)
+	}
+	return nil
+}
+
+// Scan runs the rule sets over root in a single yara run and returns the
+// matches. Each set is compiled into its own namespace, which is how
+// matches are traced back to their set.
+func (s *Scanner) Scan(ctx context.Context, root string, sets []models.YaraRuleSet) ([]Match, error) {
+	if len(sets) == 0 {
+		return nil, nil
+	}
+	if _, err := exec.LookPath(s.path); err != nil {
+		return nil, ErrUnavailable
+	}
+
+	dir, err := os.MkdirTemp("", "odin-yara-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"-r", "-N", "-w", "-e", "-g", "-s"}
+	for _, set := range sets {
+		rules := filepath.Join(dir, fmt.Sprintf("set_%d.yar", set.ID))
+		if err := os.WriteFile(rules, []byte(set.Source), 0600); err != nil {
+			return nil, err
+		}
+		args = append(args, fmt.Sprintf("%s:%s", namespace(set.ID), rules))
+	}
+	args = append(args, root)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("yara failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseOutput(&stdout, root), nil
+}
+
+// namespacePrefix prefixes the ID of a rule set to name its namespace
+const namespacePrefix = "set_"
+
+func namespace(id uint) string {
+	return fmt.Sprintf("%s%d", namespacePrefix, id)
+}
+
+// parseOutput parses yara's output with namespaces, tags and strings:
+//
+//	set_3:Backdoor [vendor,telnet] /root/bin/httpd
+//	0x1a40:$magic: 6f64696e
+func parseOutput(output *bytes.Buffer, root string) []Match {
+	var matches []Match
+	var current *Match
+
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "0x") {
+			if current != nil && len(current.Strings) < maxStringsPerMatch {
+				if match, ok := parseString(line); ok {
+					current.Strings = append(current.Strings, match)
+				}
+			}
+			continue
+		}
+
+		match, ok := parseRule(line, root)
+		if !ok {
+			current = nil
+			continue
+		}
+		matches = append(matches, match)
+		current = &matches[len(matches)-1]
+	}
+	return matches
+}
+
+// parseRule parses the line naming a matching rule and file
+func parseRule(line, root string) (Match, bool) {
+	var match Match
+	open, end := strings.Index(line, " ["), strings.Index(line, "] ")
+	if open < 0 || end < open {
+		return match, false
+	}
+	ns, rule, ok := strings.Cut(line[:open], ":")
+	if !ok {
+		return match, false
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(ns, namespacePrefix), 10, 64)
+	if err != nil {
+		return match, false
+	}
+	match.RuleSetID = uint(id)
+	match.Rule = rule
+	for _, tag := range strings.Split(line[open+2:end], ",") {
+		if tag != "" {
+			match.Tags = append(match.Tags, tag)
+		}
+	}
+	path := line[end+2:]
+	match.Path = "/" + filepath.ToSlash(strings.TrimPrefix(strings.TrimPrefix(path, root), string(filepath.Separator)))
+	return match, true
+}
+
+// parseString parses a matched string line: offset, identifier and data
+func parseString(line string) (StringMatch, bool) {
+	parts := strings.SplitN(line, ":", 3)
+	if len(parts) < 2 {
+		return StringMatch{}, false
+	}
+	offset, err := strconv.ParseInt(strings.TrimPrefix(parts[0], "0x"), 16, 64)
+	if err != nil {
+		return StringMatch{}, false
+	}
+	match := StringMatch{Offset: offset, Identifier: parts[1]}
+	if len(parts) == 3 {
+		match.Data = strings.TrimPrefix(parts[2], " ")
+		if len(match.Data) > maxMatchedData {
+			match.Data = match.Data[:maxMatchedData]
+		}
+	}
+	return match, true
+}
+
+// Findings turns matches into findings carrying the rule, its set and the
+// offsets of the matched strings
+func Findings(matches []Match, sets []models.YaraRuleSet) []models.Finding {
+	byID := make(map[uint]models.YaraRuleSet, len(sets))
+	for _, set := range sets {
+		byID[set.ID] = set
+	}
+
+	findings := make([]models.Finding, 0, len(matches))
+	for _, match := range matches {
+		set := byID[match.RuleSetID]
+		severity := set.Severity
+		if severity == "" {
+			severity = models.RiskHigh
+		}
+
+		var offsets []string
+		for _, str := range match.Strings {
+			offsets = append(offsets, fmt.Sprintf("0x%x %s", str.Offset, str.Identifier))
+		}
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"source":        Source,
+			"rule":          match.Rule,
+			"rule_set":      set.Name,
+			"rule_set_id":   set.ID,
+			"rule_set_tags": set.Tags,
+			"tags":          match.Tags,
+			"strings":       match.Strings,
+		})
+		finding := models.Finding{
+			Type:            models.FindingSecurityIssue,
+			Title:           fmt.Sprintf("YARA rule %s matched", match.Rule),
+			Description:     fmt.Sprintf("Rule %s of rule set %s matched %s", match.Rule, set.Name, match.Path),
+			Severity:        severity,
+			FilePath:        match.Path,
+			Content:         strings.Join(offsets, ", "),
+			FindingMetadata: string(metadata),
+			Confidence:      models.ConfidenceHigh,
+			OccurrenceCount: 1,
+		}
+		finding.Fingerprint = finding.ComputeFingerprint()
+		findings = append(findings, finding)
+	}
+	return findings
+}